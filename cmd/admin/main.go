@@ -0,0 +1,452 @@
+// Command authentio-admin manages users from the command line, without going
+// through the HTTP API. It reads the same configuration as the server (the
+// environment, .env or AUTHENTIO_CONFIG_FILE) and calls the same service layer,
+// so every change is validated and audited exactly like an API request.
+//
+// Usage:
+//
+//	authentio-admin create-user --email jane@example.com --first-name Jane --last-name Doe
+//	authentio-admin reset-password --email jane@example.com
+//	authentio-admin list-users --search example.com
+//	authentio-admin revoke-tokens --email jane@example.com
+//	authentio-admin run-migrations
+//	authentio-admin rotate-encryption-key --old-key <base64 key>
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"authentio/internal/config"
+	"authentio/internal/constants"
+	dbpkg "authentio/internal/database"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/pkg/crypto"
+	"authentio/pkg/email"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+	"authentio/pkg/password"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// =============================================================================
+// Commands
+// =============================================================================
+
+// newRootCommand builds the authentio-admin command tree.
+func newRootCommand() *cobra.Command {
+	var tenantID int64
+
+	root := &cobra.Command{
+		Use:          "authentio-admin",
+		Short:        "Manage Authentio users from the command line",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().Int64Var(&tenantID, "tenant-id", 0, "act within this tenant (multi-tenancy); 0 for all tenants")
+
+	// tenantContext scopes repository calls to --tenant-id, like X-Tenant-ID does for the API
+	tenantContext := func(cmd *cobra.Command) context.Context {
+		ctx := cmd.Context()
+		if tenantID != 0 {
+			ctx = repository.WithTenantID(ctx, tenantID)
+		}
+		return ctx
+	}
+
+	root.AddCommand(
+		newCreateUserCommand(tenantContext),
+		newResetPasswordCommand(tenantContext),
+		newListUsersCommand(tenantContext),
+		newRevokeTokensCommand(tenantContext),
+		newRunMigrationsCommand(),
+		newRotateEncryptionKeyCommand(),
+	)
+	return root
+}
+
+// newCreateUserCommand creates a user with a verified email.
+func newCreateUserCommand(tenantContext func(*cobra.Command) context.Context) *cobra.Command {
+	var userEmail, firstName, lastName, plainPassword, role string
+
+	cmd := &cobra.Command{
+		Use:   "create-user",
+		Short: "Create a user with a verified email",
+		Long: "Create a user with a verified email. Without --password the user signs in through\n" +
+			"SSO, a magic link or a password reset (see reset-password).",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			userRole := constants.Role(strings.ToLower(role))
+			if userRole != constants.RoleUser && userRole != constants.RoleAdmin {
+				return fmt.Errorf("invalid --role %q: expected user or admin", role)
+			}
+
+			return withApp(func(a *app) error {
+				ctx := tenantContext(cmd)
+				user := &models.User{
+					Email:     strings.ToLower(strings.TrimSpace(userEmail)),
+					FirstName: firstName,
+					LastName:  lastName,
+					IsActive:  true,
+					Role:      userRole,
+					Provider:  "email",
+				}
+				if tenantID, ok := repository.TenantIDFromContext(ctx); ok {
+					user.TenantID = &tenantID
+				}
+				if err := a.authSrv.ProvisionUser(ctx, user, plainPassword); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "created user %d <%s>\n", user.ID, user.Email)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&userEmail, "email", "", "email address (required)")
+	cmd.Flags().StringVar(&firstName, "first-name", "", "first name (required)")
+	cmd.Flags().StringVar(&lastName, "last-name", "", "last name")
+	cmd.Flags().StringVar(&plainPassword, "password", "", "initial password; must meet the password policy")
+	cmd.Flags().StringVar(&role, "role", string(constants.RoleUser), "user or admin")
+	_ = cmd.MarkFlagRequired("email")
+	_ = cmd.MarkFlagRequired("first-name")
+	return cmd
+}
+
+// newResetPasswordCommand prints a one-time password reset link.
+func newResetPasswordCommand(tenantContext func(*cobra.Command) context.Context) *cobra.Command {
+	var userEmail string
+
+	cmd := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Print a one-time password reset link for a user",
+		Long: "Print a one-time password reset link for a user. The link points to PASSWORD_RESET_URL\n" +
+			"with a signed token valid for PASSWORD_RESET_TTL; nothing is emailed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withApp(func(a *app) error {
+				link, err := a.authSrv.IssuePasswordResetLink(tenantContext(cmd), strings.TrimSpace(userEmail), a.cfg.PasswordResetURL)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), link)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&userEmail, "email", "", "email address of the user (required)")
+	_ = cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+// newListUsersCommand prints users as a table, newest last.
+func newListUsersCommand(tenantContext func(*cobra.Command) context.Context) *cobra.Command {
+	var search string
+	var limit int
+	var includeDeleted bool
+
+	cmd := &cobra.Command{
+		Use:   "list-users",
+		Short: "List users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if limit < 1 {
+				return fmt.Errorf("invalid --limit %d: must be at least 1", limit)
+			}
+
+			return withApp(func(a *app) error {
+				page, err := a.authSrv.ListUsers(tenantContext(cmd), repository.UserFilter{
+					EmailLike:      search,
+					IncludeDeleted: includeDeleted,
+					Limit:          limit,
+				}, nil)
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tEMAIL\tNAME\tROLE\tSTATUS\tVERIFIED\tCREATED")
+				for _, user := range page.Users {
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\t%s\n",
+						user.ID, user.Email, strings.TrimSpace(user.FirstName+" "+user.LastName), user.Role,
+						userStatus(&user), user.EmailVerifiedAt != nil, user.CreatedAt.Format(time.DateOnly))
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+				if page.Total > len(page.Users) {
+					fmt.Fprintf(cmd.ErrOrStderr(), "showing %d of %d users; raise --limit or narrow --search\n", len(page.Users), page.Total)
+				}
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&search, "search", "", "only users whose email contains this text")
+	cmd.Flags().IntVar(&limit, "limit", 50, "maximum number of users to list")
+	cmd.Flags().BoolVar(&includeDeleted, "include-deleted", false, "include soft-deleted users")
+	return cmd
+}
+
+// newRevokeTokensCommand signs a user out everywhere.
+func newRevokeTokensCommand(tenantContext func(*cobra.Command) context.Context) *cobra.Command {
+	var userEmail string
+
+	cmd := &cobra.Command{
+		Use:   "revoke-tokens",
+		Short: "Revoke every token of a user",
+		Long: "Revoke every refresh token of a user and reject the access tokens issued to them\n" +
+			"until now, signing them out on every device immediately.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withApp(func(a *app) error {
+				ctx := tenantContext(cmd)
+				user, err := a.findUserByEmail(ctx, userEmail)
+				if err != nil {
+					return err
+				}
+				if err := a.authSrv.LogoutAll(ctx, user.ID); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "revoked tokens of user %d <%s>\n", user.ID, user.Email)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&userEmail, "email", "", "email address of the user (required)")
+	_ = cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+// newRunMigrationsCommand applies pending database migrations, like the server
+// does on startup. It only needs the database.
+func newRunMigrationsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "run-migrations",
+		Short: "Apply pending database migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			db, err := openDB(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := dbpkg.EnsureSchema(cmd.Context(), cfg.DBDriver, db); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "migrations applied")
+			return nil
+		},
+	}
+}
+
+// newRotateEncryptionKeyCommand re-encrypts TOTP secrets, OTP codes and linked
+// OAuth provider tokens from --old-key to --new-key, which defaults to
+// DB_ENCRYPTION_KEY. Run it with the new key configured, right before
+// restarting the servers with it.
+func newRotateEncryptionKeyCommand() *cobra.Command {
+	var oldKey, newKey string
+
+	cmd := &cobra.Command{
+		Use:   "rotate-encryption-key",
+		Short: "Re-encrypt secrets stored at rest with a new DB_ENCRYPTION_KEY",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			if newKey == "" {
+				newKey = cfg.DBEncryptionKey
+			}
+			if newKey == "" {
+				return errors.New("--new-key is required when DB_ENCRYPTION_KEY is not set")
+			}
+			from, err := crypto.ParseKey(oldKey)
+			if err != nil {
+				return fmt.Errorf("invalid --old-key: %w", err)
+			}
+			to, err := crypto.ParseKey(newKey)
+			if err != nil {
+				return fmt.Errorf("invalid new key: %w", err)
+			}
+			if from == to {
+				return errors.New("the old and new keys are the same")
+			}
+
+			db, err := openDB(cmd.Context(), cfg)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := dbpkg.MigrateEncryption(cmd.Context(), db, from, to); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "encryption key rotated")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&oldKey, "old-key", "", "base64-encoded key the secrets are encrypted with now (required)")
+	cmd.Flags().StringVar(&newKey, "new-key", "", "base64-encoded key to re-encrypt with (default DB_ENCRYPTION_KEY)")
+	_ = cmd.MarkFlagRequired("old-key")
+	return cmd
+}
+
+// userStatus describes whether the user can sign in.
+func userStatus(user *models.User) string {
+	switch {
+	case user.DeletedAt != nil:
+		return "deleted"
+	case user.DeactivatedAt != nil:
+		return "deactivated"
+	case !user.IsActive:
+		return "disabled"
+	default:
+		return "active"
+	}
+}
+
+// =============================================================================
+// Application Wiring
+// =============================================================================
+
+// app holds the connections and the service used by a command.
+type app struct {
+	cfg     *config.Config
+	db      *sql.DB
+	redis   *redis.Client
+	authSrv *service.AuthService
+}
+
+// withApp connects to Postgres and Redis, runs fn and closes the connections.
+func withApp(fn func(*app) error) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	db, err := openDB(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	redisClient := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr, Password: cfg.RedisPass})
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer redisClient.Close()
+
+	return fn(&app{cfg: cfg, db: db, redis: redisClient, authSrv: newAuthService(cfg, db, redisClient)})
+}
+
+// newAuthService builds the AuthService with the features the commands rely on:
+// audit logging, password history, reset links and token revocation.
+func newAuthService(cfg *config.Config, db *sql.DB, redisClient *redis.Client) *service.AuthService {
+	encryptionKey, _ := cfg.EncryptionKey()
+	userRepo, _ := dbpkg.NewUserStorer(cfg.DBDriver, db, nil) // DB_DRIVER is validated by LoadConfig
+	jwtManager := jwt.NewRotatingManager(cfg.JWTSecret, cfg.JWTPreviousSecret).WithRevocationStore(redisClient, cfg.TokenRevocationStrict)
+	var emailOpts []email.ClientOption
+	if sender := cfg.EmailSender(); sender != nil {
+		emailOpts = append(emailOpts, email.WithSender(sender))
+	}
+	emailClient := email.NewClient(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom, emailOpts...)
+
+	authSrv := service.NewAuthService(
+		userRepo,
+		dbpkg.NewTwoFARepository(db, encryptionKey, nil),
+		dbpkg.NewOTPRepository(db, encryptionKey, nil),
+		dbpkg.NewTokenRepository(db, nil),
+		jwtManager,
+		emailClient,
+		config.GoogleOAuthConfig,
+		nil,
+	)
+	authSrv.WithAuditLog(dbpkg.NewAuditRepository(db, nil))
+	authSrv.WithPasswordHistory(dbpkg.NewPasswordHistoryRepository(db, nil), cfg.PasswordHistoryLen)
+	authSrv.WithOTPLength(cfg.OTPLength)
+	authSrv.WithPasswordResetLinks(service.PasswordResetLinkConfig{
+		Secret: cfg.PasswordResetSigningKey(),
+		URL:    cfg.PasswordResetURL,
+		TTL:    cfg.PasswordResetTTL,
+		Redis:  redisClient,
+	})
+	return authSrv
+}
+
+// findUserByEmail returns the user with the given email, or service.ErrUserNotFound.
+func (a *app) findUserByEmail(ctx context.Context, userEmail string) (*models.User, error) {
+	page, err := a.authSrv.ListUsers(ctx, repository.UserFilter{Email: strings.TrimSpace(userEmail), Limit: 2}, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch len(page.Users) {
+	case 0:
+		return nil, service.ErrUserNotFound
+	case 1:
+		return &page.Users[0], nil
+	default:
+		return nil, errors.New("email belongs to users of several tenants; pass --tenant-id")
+	}
+}
+
+// loadConfig loads and validates the server configuration and initializes logging.
+// Logs go to stderr, so stdout only carries command output.
+func loadConfig() (*config.Config, error) {
+	cfg, cfgErrs := config.LoadConfig()
+	if len(cfgErrs) > 0 {
+		msgs := make([]string, len(cfgErrs))
+		for i, e := range cfgErrs {
+			msgs[i] = "  - " + e.Error()
+		}
+		return nil, fmt.Errorf("failed to load config:\n%s", strings.Join(msgs, "\n"))
+	}
+
+	if err := logger.InitLogger(cfg.Env == "production"); err != nil {
+		return nil, fmt.Errorf("failed to init logger: %w", err)
+	}
+	if cfg.LogBackend == "zap" {
+		logger.SetBackend(logger.NewZapBackend(logger.Sugar))
+	}
+	if err := password.SetDefaultCost(cfg.BcryptCost); err != nil {
+		return nil, fmt.Errorf("invalid bcrypt cost: %w", err)
+	}
+	return cfg, nil
+}
+
+// openDB connects to Postgres.
+func openDB(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := dbpkg.Open(cfg.DBDriver, cfg.PostgresDSN, cfg.DBQueryLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	dbpkg.SetStatementTimeout(cfg.DBStatementTimeout)
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"slices"
+
+	"authentio/internal/config"
+	"authentio/pkg/logger"
+
+	"golang.org/x/net/http2"
+)
+
+// =============================================================================
+// HTTP/2
+// =============================================================================
+
+// http2MaxConcurrentStreams bounds the requests a single client connection may
+// have in flight
+const http2MaxConcurrentStreams = 250
+
+// authMetadataPath is the JWKS document clients verify access tokens with
+const authMetadataPath = "/api/v1/auth/.well-known/jwks.json"
+
+// authMetadataPushRoutes are the requests answered with new access tokens; over
+// HTTP/2 the JWKS document is pushed along with them
+var authMetadataPushRoutes = []string{
+	"/api/v1/auth/login",
+	"/api/v1/auth/refresh",
+}
+
+// configureHTTP2 enables or disables HTTP/2 on srv, which configureTLS must
+// have prepared. Browsers only speak HTTP/2 over TLS, so it is served only when
+// HTTPS is enabled; plain HTTP stays HTTP/1.1.
+//
+// Shutting srv down sends every HTTP/2 connection a GOAWAY frame: clients stop
+// opening streams on it, streams in flight finish and the connection closes.
+// http.Server.Shutdown waits for that like it waits for HTTP/1.1 requests.
+//
+// With HTTP/2, the responses to logins and refreshes come with a server push
+// of the JWKS document (see pushAuthMetadata).
+func configureHTTP2(cfg *config.Config, srv *http.Server) error {
+	if tlsModeFor(cfg) == tlsDisabled {
+		if cfg.EnableHTTP2 {
+			logger.Warn("HTTP/2 requires HTTPS - serving HTTP/1.1")
+		}
+		return nil
+	}
+
+	if !cfg.EnableHTTP2 {
+		// A non-nil, empty map turns off net/http's built-in HTTP/2, and clients
+		// must not be offered h2 during the TLS handshake
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		srv.TLSConfig.NextProtos = slices.DeleteFunc(srv.TLSConfig.NextProtos, func(proto string) bool {
+			return proto == http2.NextProtoTLS
+		})
+		logger.Info("HTTP/2 disabled - serving HTTP/1.1")
+		return nil
+	}
+
+	if err := http2.ConfigureServer(srv, &http2.Server{
+		MaxConcurrentStreams: http2MaxConcurrentStreams,
+		IdleTimeout:          srv.IdleTimeout,
+	}); err != nil {
+		return err
+	}
+	srv.Handler = pushAuthMetadata(srv.Handler)
+	logger.Info("HTTP/2 enabled", "max_concurrent_streams", http2MaxConcurrentStreams)
+	return nil
+}
+
+// pushAuthMetadata wraps next so HTTP/2 requests to authMetadataPushRoutes
+// push the JWKS document before they are served: a client verifying the tokens
+// it is issued then has the keys without a round trip of its own. Clients that
+// disabled push, and HTTP/1.1 requests, are served as usual.
+func pushAuthMetadata(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pusher, ok := w.(http.Pusher); ok && r.ProtoMajor == 2 && slices.Contains(authMetadataPushRoutes, r.URL.Path) {
+			err := pusher.Push(authMetadataPath, &http.PushOptions{
+				Header: http.Header{"Accept": []string{"application/json"}},
+			})
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Debug("failed to push auth metadata", "error", err, "path", r.URL.Path)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
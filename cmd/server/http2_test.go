@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"authentio/internal/config"
+	"authentio/internal/middleware"
+	"authentio/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// startServer serves h over HTTPS with a self-signed certificate, configured by
+// configureTLS and configureHTTP2 as cmd/server configures its server.
+func startServer(tb testing.TB, enableHTTP2 bool, h http.Handler) *httptest.Server {
+	tb.Helper()
+
+	cfg := &config.Config{TLSCertFile: "cert.pem", TLSKeyFile: "key.pem", EnableHTTP2: enableHTTP2}
+	srv := &http.Server{Handler: h, IdleTimeout: time.Minute}
+	configureTLS(cfg, srv)
+	if err := configureHTTP2(cfg, srv); err != nil {
+		tb.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(nil)
+	ts.Config = srv
+	ts.TLS = srv.TLSConfig
+	ts.EnableHTTP2 = enableHTTP2
+	ts.StartTLS()
+	tb.Cleanup(ts.Close)
+	return ts
+}
+
+// recordingPusher is a ResponseWriter supporting server push that records the
+// pushed targets
+type recordingPusher struct {
+	http.ResponseWriter
+	pushed []string
+	err    error
+}
+
+func (p *recordingPusher) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return p.err
+}
+
+func TestPushAuthMetadata(t *testing.T) {
+	served := false
+	h := pushAuthMetadata(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { served = true }))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		protoMajor int
+		pushErr    error
+		wantPush   bool
+	}{
+		{"HTTP/2 login", http.MethodPost, "/api/v1/auth/login", 2, nil, true},
+		{"HTTP/2 refresh", http.MethodPost, "/api/v1/auth/refresh", 2, nil, true},
+		{"push disabled by client", http.MethodPost, "/api/v1/auth/login", 2, http.ErrNotSupported, true},
+		{"HTTP/1.1 login", http.MethodPost, "/api/v1/auth/login", 1, nil, false},
+		{"HTTP/2 other route", http.MethodGet, "/api/v1/user/profile", 2, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			served = false
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.ProtoMajor = tt.protoMajor
+			w := &recordingPusher{ResponseWriter: httptest.NewRecorder(), err: tt.pushErr}
+
+			h.ServeHTTP(w, req)
+
+			if !served {
+				t.Fatal("request not served")
+			}
+			if got := len(w.pushed) == 1 && w.pushed[0] == authMetadataPath; got != tt.wantPush {
+				t.Fatalf("pushed %v, want push of %s: %v", w.pushed, authMetadataPath, tt.wantPush)
+			}
+		})
+	}
+}
+
+// TestHTTP2ServerPushesAuthMetadata speaks HTTP/2 frames directly: Go's HTTP
+// client never enables server push.
+func TestHTTP2ServerPushesAuthMetadata(t *testing.T) {
+	ts := startServer(t, true, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	conn, err := tls.Dial("tcp", ts.Listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		NextProtos:         []string{http2.NextProtoTLS},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if proto := conn.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+		t.Fatalf("negotiated %q, want h2", proto)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		t.Fatal(err)
+	}
+	framer := http2.NewFramer(conn, conn)
+	if err := framer.WriteSettings(http2.Setting{ID: http2.SettingEnablePush, Val: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	for _, field := range []hpack.HeaderField{
+		{Name: ":method", Value: http.MethodPost},
+		{Name: ":scheme", Value: "https"},
+		{Name: ":authority", Value: ts.Listener.Addr().String()},
+		{Name: ":path", Value: "/api/v1/auth/login"},
+	} {
+		enc.WriteField(field)
+	}
+	if err := framer.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: block.Bytes(), EndStream: true, EndHeaders: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := hpack.NewDecoder(4096, nil)
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("no PUSH_PROMISE before %v", err)
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.PushPromiseFrame:
+			fields, err := dec.DecodeFull(f.HeaderBlockFragment())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, field := range fields {
+				if field.Name == ":path" {
+					if field.Value != authMetadataPath {
+						t.Fatalf("pushed %s, want %s", field.Value, authMetadataPath)
+					}
+					return
+				}
+			}
+			t.Fatalf("PUSH_PROMISE without :path: %v", fields)
+		case *http2.HeadersFrame:
+			if f.StreamID == 1 && f.StreamEnded() {
+				t.Fatal("login response finished without a PUSH_PROMISE")
+			}
+		}
+	}
+}
+
+func TestConfigureHTTP2Protocols(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, enable := range []bool{true, false} {
+		ts := startServer(t, enable, h)
+		resp, err := ts.Client().Get(ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		want := 1
+		if enable {
+			want = 2
+		}
+		if resp.ProtoMajor != want {
+			t.Errorf("EnableHTTP2=%v served %s, want HTTP/%d", enable, resp.Proto, want)
+		}
+	}
+}
+
+// benchmarkTokenValidation sends concurrent requests with an access token to an
+// AuthRequired route, over HTTP/2 or HTTP/1.1 with TLS, and reports requests per
+// second. Run with:
+//
+//	go test -run '^$' -bench TokenValidation ./cmd/server
+func benchmarkTokenValidation(b *testing.B, enableHTTP2 bool) {
+	gin.SetMode(gin.ReleaseMode)
+	manager := jwt.NewManager("http2-benchmark-secret-at-least-32-bytes")
+	token, err := manager.GenerateToken(1, "jane@example.com", "Jane", "Doe")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/api/v1/user/profile", middleware.AuthRequired(manager, nil, nil, nil, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	ts := startServer(b, enableHTTP2, r)
+
+	client := ts.Client()
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		// Let HTTP/1.1 keep a connection per concurrent request, as HTTP/2
+		// multiplexes them over one
+		transport.MaxIdleConnsPerHost = 256
+	}
+
+	b.SetParallelism(8)
+	b.ResetTimer()
+	start := time.Now()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/user/profile", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			resp, err := client.Do(req)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNoContent {
+				b.Errorf("status %s, want 204", resp.Status)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "req/s")
+}
+
+func BenchmarkTokenValidationHTTP1(b *testing.B) { benchmarkTokenValidation(b, false) }
+
+func BenchmarkTokenValidationHTTP2(b *testing.B) { benchmarkTokenValidation(b, true) }
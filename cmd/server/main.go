@@ -2,30 +2,53 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"authentio/internal/config"
 	dbpkg "authentio/internal/database"
+	grpcapi "authentio/internal/grpc"
 	"authentio/internal/handler"
+	"authentio/internal/middleware"
+	"authentio/internal/repository"
 	"authentio/internal/router"
 	"authentio/internal/service"
-	"authentio/pkg/email" 
+	"authentio/pkg/email"
+	"authentio/pkg/events"
+	"authentio/pkg/flags"
+	"authentio/pkg/geoip"
 	"authentio/pkg/jwt"
+	"authentio/pkg/ldap"
+	"authentio/pkg/lock"
 	"authentio/pkg/logger"
-	
+	"authentio/pkg/oauth"
+	"authentio/pkg/password"
+	"authentio/pkg/queue"
+	"authentio/pkg/sms"
+	"authentio/pkg/tracing"
+
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
+	"github.com/go-webauthn/webauthn/webauthn"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	// Swagger imports
 	_ "authentio/docs" // This imports your generated docs
 )
 
+// The OpenAPI spec in docs/ is generated from the swag annotations of this file
+// and the handlers; run go generate ./cmd/server after changing them.
+//go:generate swag init --dir ../.. --generalInfo cmd/server/main.go --output ../../docs
+
 // @title Authentio API
 // @version 1.0
 // @description Secure authentication service with JWT, OAuth2, 2FA, and password reset functionality
@@ -44,7 +67,7 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description JWT Bearer token. Format: "Bearer {your_jwt_token}"
+// @description JWT Bearer token. Format: "Bearer {your_jwt_token}", or "DPoP {your_jwt_token}" with a DPoP header for DPoP-bound tokens
 
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
@@ -53,33 +76,83 @@ import (
 // It initializes configuration, logging, database connections, Redis, email client,
 // JWT manager, repositories, services, handlers, and starts the HTTP server with graceful shutdown.
 func main() {
+	// --migrate-only applies pending database migrations and exits
+	migrateOnly := flag.Bool("migrate-only", false, "run database migrations and exit")
+	flag.Parse()
+
 	// Load configuration from environment or .env file
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+	cfg, cfgErrs := config.LoadConfig()
+	if len(cfgErrs) > 0 {
+		fmt.Fprintln(os.Stderr, "failed to load config:")
+		for _, e := range cfgErrs {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
 		os.Exit(1)
 	}
 
 	googleOAuthConfig := config.GoogleOAuthConfig
 
-	// Initialize email client for sending OTPs and notifications
-	emailClient := email.NewClient(
-		cfg.SMTPHost,
-		cfg.SMTPPort,
-		cfg.SMTPUsername,
-		cfg.SMTPPassword,
-		cfg.SMTPFrom,
-	)
-
 	// Initialize structured logger (JSON in production, console in dev)
 	if err := logger.InitLogger(cfg.Env == "production"); err != nil {
 		fmt.Fprintf(os.Stderr, "failed to init logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync() // Ensure all logs are flushed on exit
+	if cfg.LogBackend == "zap" {
+		logger.SetBackend(logger.NewZapBackend(logger.Sugar))
+	}
 
 	logger.Info("Starting Authentio service", "env", cfg.Env, "port", cfg.ServerPort)
 
+	// bcrypt cost for new password hashes
+	if err := password.SetDefaultCost(cfg.BcryptCost); err != nil {
+		logger.Fatal("invalid bcrypt cost", "error", err)
+	}
+
+	// OpenTelemetry tracing: spans are exported over OTLP when an endpoint is configured
+	tracerProvider, shutdownTracing, err := tracing.NewProvider(context.Background(), tracing.Config{
+		Enabled:     cfg.OTelExporterEndpoint != "",
+		ServiceName: cfg.OTelServiceName,
+		SampleRatio: cfg.OTelSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", "error", err)
+	}
+	if cfg.OTelExporterEndpoint != "" {
+		logger.Info("Tracing enabled", "endpoint", cfg.OTelExporterEndpoint, "sampleRatio", cfg.OTelSampleRatio)
+	}
+
+	// Secondary SMTP servers, tried in order when the primary cannot deliver
+	var smtpFailover []email.SMTPConfig
+	for _, raw := range cfg.SMTPFailoverServers {
+		server, err := email.ParseSMTPURL(strings.TrimSpace(raw))
+		if err != nil {
+			logger.Fatal("invalid SMTP failover server", "error", err)
+		}
+		smtpFailover = append(smtpFailover, server)
+	}
+
+	emailOpts := []email.ClientOption{
+		email.WithRetryPolicy(cfg.SMTPMaxAttempts, cfg.SMTPInitialBackoff, cfg.SMTPMaxBackoff),
+		email.WithTracerProvider(tracerProvider),
+		email.WithFailover(smtpFailover...),
+	}
+	// EMAIL_PROVIDER=sendgrid or mailgun delivers through the provider's API instead of SMTP
+	if sender := cfg.EmailSender(); sender != nil {
+		emailOpts = append(emailOpts, email.WithSender(sender))
+		logger.Info("Email delivery through provider API", "provider", cfg.EmailProvider)
+	}
+
+	// Initialize email client for sending OTPs and notifications
+	emailClient := email.NewClient(
+		cfg.SMTPHost,
+		cfg.SMTPPort,
+		cfg.SMTPUsername,
+		cfg.SMTPPassword,
+		cfg.SMTPFrom,
+		emailOpts...,
+	)
+
 	// Set Gin runtime mode
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -87,11 +160,20 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
-	// Initialize PostgreSQL connection
-	db, err := sql.Open("pgx", cfg.PostgresDSN)
+	// Initialize the database connection (DB_DRIVER, PostgreSQL by default);
+	// DB_QUERY_LOG logs every statement
+	db, err := dbpkg.Open(cfg.DBDriver, cfg.PostgresDSN, cfg.DBQueryLog)
 	if err != nil {
 		logger.Fatal("failed to open database connection", "error", err)
 	}
+	dbpkg.SetStatementTimeout(cfg.DBStatementTimeout)
+
+	// Bound the pool so load spikes queue for a connection instead of
+	// exhausting Postgres max_connections
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime)
 	defer func() {
 		if err := db.Close(); err != nil {
 			logger.Error("error closing database", "error", err)
@@ -106,6 +188,17 @@ func main() {
 	}
 	logger.Info("Database connection established")
 
+	// Bring the schema up to date before anything touches it
+	if err := dbpkg.EnsureSchema(context.Background(), cfg.DBDriver, db); err != nil {
+		logger.Fatal("failed to run database migrations", "error", err)
+	}
+	if cfg.DBDriver != dbpkg.DriverPostgres {
+		logger.Warn("only the user repository supports this DB_DRIVER; tokens, OTPs, 2FA and the other stores still need PostgreSQL", "driver", cfg.DBDriver)
+	}
+	if *migrateOnly {
+		return
+	}
+
 	// Initialize Redis client for rate limiting, caching, and session management
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
@@ -122,30 +215,344 @@ func main() {
 		}
 	}()
 
+	// Load branded email templates if a directory is configured
+	if cfg.EmailTemplatesDir != "" {
+		if err := emailClient.LoadTemplates(cfg.EmailTemplatesDir); err != nil {
+			logger.Warn("failed to load email templates, using built-in bodies", "dir", cfg.EmailTemplatesDir, "error", err)
+		} else {
+			logger.Info("Email templates loaded", "dir", cfg.EmailTemplatesDir)
+		}
+	}
+
 	// Test email service (non-fatal in production, but warn)
-	if err := emailClient.Send([]string{"test@example.com"}, "Authentio Email Test", "Email service is working!"); err != nil {
+	if err := emailClient.Send(context.Background(), []string{"test@example.com"}, "Authentio Email Test", "Email service is working!"); err != nil {
 		logger.Warn("Email service test failed - check SMTP settings", "error", err)
 	} else {
 		logger.Info("Email service initialized and tested successfully")
 	}
 
-	// Initialize JWT manager for token signing and verification
-	jwtManager := jwt.NewManager(cfg.JWTSecret)
+	// Initialize JWT manager for token signing and verification. Tokens signed with
+	// JWT_PREVIOUS_SECRET keep verifying, so the secret can be rotated without
+	// signing everyone out.
+	jwtManager := jwt.NewRotatingManager(cfg.JWTSecret, cfg.JWTPreviousSecret).WithRevocationStore(redisClient, cfg.TokenRevocationStrict)
+	// DPoP proofs (RFC 9449) are single-use; their IDs are tracked in Redis
+	jwtManager.WithDPoPReplayStore(redisClient)
+
+	// Per-user feature flags, snapshotted into the feature_flags claim of new tokens
+	flagStore := flags.NewStore(redisClient).WithCacheTTL(cfg.FlagsCacheTTL)
+	jwtManager.WithClaimTransformer(flagStore.ClaimTransformer())
+
+	// Decode the at-rest encryption key (validated in LoadConfig); without it TOTP is
+	// unavailable and OTP codes are stored in plain text
+	encryptionKey, _ := cfg.EncryptionKey()
+	if encryptionKey == nil {
+		logger.Warn("DB_ENCRYPTION_KEY not set - TOTP enrollment is disabled and OTP codes are stored unencrypted")
+	}
 
 	// Initialize data repositories
-	userRepo := dbpkg.NewUserRepository(db)
-	tokenRepo := dbpkg.NewTokenRepository(db)
-	otpRepo := dbpkg.NewOTPRepository(db)
-	twoFARepo := dbpkg.NewTwoFARepository(db)
+	userRepo, err := dbpkg.NewUserStorer(cfg.DBDriver, db, tracerProvider)
+	if err != nil {
+		logger.Fatal("failed to create user repository", "error", err)
+	}
+	if cfg.UserCacheSize > 0 {
+		userRepo = dbpkg.NewCachedUserRepository(userRepo, cfg.UserCacheSize, cfg.UserCacheTTL)
+	}
+	tokenRepo := dbpkg.NewTokenRepository(db, tracerProvider)
+	otpRepo := dbpkg.NewOTPRepository(db, encryptionKey, tracerProvider)
+	twoFARepo := dbpkg.NewTwoFARepository(db, encryptionKey, tracerProvider)
 
 	// Initialize authentication service
-	authSrv := service.NewAuthService(userRepo, twoFARepo, otpRepo, tokenRepo, jwtManager, emailClient, googleOAuthConfig)
+	authSrv := service.NewAuthService(userRepo, twoFARepo, otpRepo, tokenRepo, jwtManager, emailClient, googleOAuthConfig, tracerProvider)
+
+	// Never email addresses that bounced or unsubscribed (recorded by /webhooks/email)
+	authSrv.WithEmailSuppression(dbpkg.NewEmailSuppressionRepository(db, tracerProvider))
+
+	// Send emails from background workers instead of the request path
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if cfg.EmailQueueEnabled {
+		authSrv.WithEmailQueue(queue.NewProducer(redisClient, cfg.EmailQueueStream))
+
+		hostname, _ := os.Hostname()
+		consumer := queue.NewConsumer(redisClient, cfg.EmailQueueStream, "email-senders", hostname, queue.EmailHandler(emailClient))
+		if err := queue.StartWorker(workerCtx, consumer, cfg.EmailQueueConcurrency); err != nil {
+			logger.Fatal("failed to start email queue workers", "error", err)
+		}
+	}
+
+	// Delete expired OTPs and refresh tokens so the tables do not grow forever
+	if cfg.DBDriver == dbpkg.DriverPostgres && cfg.DBJanitorInterval > 0 {
+		dbpkg.StartJanitor(workerCtx, db, cfg.DBJanitorInterval)
+	}
+
+	// Deliver auth events to the webhooks registered by admins
+	authSrv.WithWebhooks(dbpkg.NewWebhookRepository(db, tracerProvider))
+	webhookPublisher := events.NewWebhookPublisher(authSrv, cfg.WebhookWorkers)
+	defer webhookPublisher.Close()
+	publishers := events.Multi{webhookPublisher}
+
+	// Publish auth events to NATS for other services; topics left empty are not published
+	if cfg.EventsNATSURL != "" {
+		publisher, err := events.NewNATSPublisher(cfg.EventsNATSURL, events.Topics{
+			events.UserRegistered:  cfg.EventsTopicUserRegistered,
+			events.UserLoggedIn:    cfg.EventsTopicUserLoggedIn,
+			events.PasswordChanged: cfg.EventsTopicPasswordChanged,
+			events.TwoFAEnabled:    cfg.EventsTopicTwoFAEnabled,
+		})
+		if err != nil {
+			logger.Fatal("failed to connect to event broker", "error", err)
+		}
+		defer publisher.Close()
+		publishers = append(publishers, publisher)
+		logger.Info("Event publishing enabled", "broker", "nats")
+	}
+	authSrv.WithEventPublisher(publishers)
+
+	// Email verification links are signed with the JWT secret and tracked in Redis
+	authSrv.WithEmailVerification(service.EmailVerificationConfig{
+		Redis:    redisClient,
+		Secret:   []byte(cfg.JWTSecret),
+		URL:      cfg.EmailVerificationURL,
+		TTL:      cfg.EmailVerificationTTL,
+		Required: cfg.RequireEmailVerification,
+	})
+
+	// SMS delivery of OTP codes when Twilio is configured
+	if cfg.TwilioAccountSID != "" {
+		authSrv.WithSMS(sms.NewClient(cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFromNumber))
+	}
+
+	// LDAP / Active Directory login, tried before local passwords
+	if cfg.LDAPEnabled {
+		ldapClient := ldap.NewClient(cfg.LDAPURL, cfg.LDAPBindDN, cfg.LDAPBindPassword, cfg.LDAPUserBaseDN, cfg.LDAPUserFilter)
+		ldapClient.Mechanism = cfg.LDAPBindMechanism
+		authSrv.WithLDAP(ldapClient)
+	}
+
+	// Single-use magic login links live in Redis for MAGIC_LINK_TTL
+	authSrv.WithMagicLink(service.MagicLinkConfig{
+		Redis: redisClient,
+		URL:   cfg.MagicLinkURL,
+		TTL:   cfg.MagicLinkTTL,
+	})
+
+	// OAuth2 authorization server for registered third-party clients; codes,
+	// device codes and refresh tokens live in Redis until used or expired
+	if cfg.OAuthServerEnabled {
+		authSrv.WithOAuthServer(service.OAuthServerConfig{
+			Clients:         dbpkg.NewOAuthClientRepository(db, tracerProvider),
+			Redis:           redisClient,
+			CodeTTL:         cfg.OAuthCodeTTL,
+			AccessTokenTTL:  cfg.OAuthAccessTokenTTL,
+			RefreshTokenTTL: cfg.OAuthRefreshTokenTTL,
+
+			DeviceCodeTTL:         cfg.OAuthDeviceCodeTTL,
+			DevicePollInterval:    cfg.OAuthDevicePollInterval,
+			DeviceVerificationURI: cfg.OAuthDeviceVerificationURI,
+		})
+	}
+
+	// Password resets email signed links valid for PASSWORD_RESET_TTL; Redis
+	// records redeemed links so each works once
+	authSrv.WithPasswordResetLinks(service.PasswordResetLinkConfig{
+		Secret: cfg.PasswordResetSigningKey(),
+		URL:    cfg.PasswordResetURL,
+		TTL:    cfg.PasswordResetTTL,
+		Redis:  redisClient,
+	})
+
+	// Record logins, logouts, password and 2FA changes in the append-only audit log
+	authSrv.WithAuditLog(dbpkg.NewAuditRepository(db, tracerProvider))
+
+	// Locate login attempts with the GeoIP database when it is installed
+	if cfg.GeoIPDatabasePath != "" {
+		if _, err := os.Stat(cfg.GeoIPDatabasePath); err != nil {
+			logger.Info("GeoIP database not found, login geolocation disabled", "path", cfg.GeoIPDatabasePath)
+		} else if locator, err := geoip.Open(cfg.GeoIPDatabasePath); err != nil {
+			logger.Warn("failed to open GeoIP database, login geolocation disabled", "error", err)
+		} else {
+			defer locator.Close()
+			authSrv.WithGeoIP(locator)
+		}
+	}
+
+	// Reject reuse of the last PASSWORD_HISTORY_LEN passwords
+	authSrv.WithPasswordHistory(dbpkg.NewPasswordHistoryRepository(db, tracerProvider), cfg.PasswordHistoryLen)
+
+	// Reject new passwords estimated below MIN_PASSWORD_ENTROPY bits
+	authSrv.WithMinPasswordEntropy(cfg.MinPasswordEntropy)
+
+	// Only ALLOWED_EMAIL_DOMAINS may register, when set, and never BLOCKED_EMAIL_DOMAINS
+	// or disposable providers; both lists are reloaded on SIGHUP
+	authSrv.WithEmailDomains(cfg.AllowedEmailDomains, cfg.BlockedEmailDomains)
+
+	// Emailed and texted OTP codes have OTP_LENGTH digits
+	authSrv.WithOTPLength(cfg.OTPLength)
+
+	// Lock accounts after repeated failed logins
+	authSrv.WithLockout(service.LockoutConfig{
+		Redis:           redisClient,
+		MaxFailedLogins: cfg.MaxFailedLogins,
+		Window:          cfg.LockoutWindow,
+	})
+
+	// Manage the IP blocklist checked when IP_FILTER_MODE=blocklist
+	authSrv.WithIPFilter(redisClient)
+
+	// Serialize registrations of the same email and redemptions of the same OTP across instances
+	authSrv.WithLocker(lock.New(redisClient))
+
+	// Admins (role=admin) and holders of the users:impersonate permission may
+	// impersonate users with tokens valid for IMPERSONATION_TTL
+	authSrv.WithImpersonationTTL(cfg.ImpersonationTTL)
+	authSrv.WithFeatureFlags(flagStore)
+
+	// Refresh tokens expire after REFRESH_TOKEN_TTL, or REMEMBER_ME_TTL for
+	// logins with remember_me
+	authSrv.WithRefreshTokenTTL(cfg.RefreshTokenTTL, cfg.RememberMeTTL)
+
+	// RBAC roles grant permissions, issued as the "permissions" claim of access tokens
+	roleRepo := dbpkg.NewRoleRepository(db, tracerProvider)
+	authSrv.WithRoles(roleRepo)
+
+	// GET /me/export downloads the user's personal data, once a day per user
+	authSrv.WithDataExport(dbpkg.NewExportRepository(db, tracerProvider), redisClient)
+
+	// PKCE code challenges for public OAuth clients live in Redis for 10 minutes
+	authSrv.WithPKCEStore(redisClient, 10*time.Minute)
+
+	// Password logins of accounts with an authenticator app wait 5 minutes for its code
+	authSrv.WithTOTPLogin(redisClient, 5*time.Minute)
+
+	// Passkeys (WebAuthn) when a relying party ID is configured
+	if cfg.WebAuthnRPID != "" {
+		timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.WebAuthnTimeout, TimeoutUVD: cfg.WebAuthnTimeout}
+		rp, err := webauthn.New(&webauthn.Config{
+			RPID:          cfg.WebAuthnRPID,
+			RPDisplayName: cfg.WebAuthnRPDisplayName,
+			RPOrigins:     cfg.WebAuthnRPOrigins,
+			Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+		})
+		if err != nil {
+			logger.Fatal("invalid WebAuthn configuration", "error", err)
+		}
+		authSrv.WithWebAuthn(service.WebAuthnConfig{
+			WebAuthn:   rp,
+			Repo:       dbpkg.NewWebAuthnRepository(db, tracerProvider),
+			Redis:      redisClient,
+			SessionTTL: cfg.WebAuthnTimeout,
+		})
+	}
+
+	// Register OAuth2 social login providers that have credentials configured
+	if cfg.GoogleClientID != "" {
+		authSrv.WithOAuthProviders(oauth.NewGoogleProvider(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURL))
+	}
+	if cfg.GitHubClientID != "" {
+		authSrv.WithOAuthProviders(oauth.NewGitHubProvider(cfg.GitHubClientID, cfg.GitHubClientSecret, cfg.GitHubRedirectURL))
+	}
+
+	// Users can link several provider accounts (/me/linked-accounts); provider
+	// tokens are only kept when DB_ENCRYPTION_KEY is set
+	authSrv.WithOAuthIdentities(dbpkg.NewOAuthIdentityRepository(db, encryptionKey, tracerProvider))
+
+	// Accept access tokens of an external identity provider when its JWKS URI is set
+	var externalTokens middleware.ExternalTokenAuthenticator
+	var externalJWTVerifier jwt.Verifier
+	if cfg.ExternalJWKSURI != "" {
+		verifier, err := jwt.NewJWKSVerifier(cfg.ExternalJWKSURI, cfg.ExternalJWKSCacheTTL)
+		if err != nil {
+			logger.Fatal("failed to load external JWKS", "error", err, "uri", cfg.ExternalJWKSURI)
+		}
+		authSrv.WithExternalTokens(verifier.WithIssuer(cfg.ExternalJWTIssuer).WithAudience(cfg.ExternalJWTAudience))
+		externalTokens = authSrv
+
+		// With federation the provider's tokens are the only ones accepted, and
+		// their subjects are provisioned as local users
+		if cfg.ExternalJWTFederation {
+			externalJWTVerifier = verifier
+		}
+	}
+
+	// Active sessions are cached in Redis for SESSION_CACHE_TTL when session validation is on
+	if cfg.SessionValidation {
+		authSrv.WithSessionCache(redisClient, cfg.SessionCacheTTL)
+	}
 
 	// Initialize HTTP handlers
-	h := handler.NewHandler(*authSrv)
+	h := handler.NewHandler(*authSrv, handler.NewHealthHandler(db, redisClient).WithCircuitBreakers(authSrv.OAuthBreakers()...))
+
+	// Optionally check every access token's session against the sessions table,
+	// through the session cache, and cache the claims of validated tokens
+	var sessionChecker middleware.SessionChecker
+	var claimsCache middleware.ClaimsCache
+	if cfg.SessionValidation {
+		sessionChecker = authSrv
+		claimsCache = authSrv
+	}
+
+	// Optionally extend the session of every access token in use, so active
+	// users are not logged out at the end of the refresh token lifetime
+	var sessionSlider middleware.SessionSlider
+	if cfg.SlidingSessionEnabled {
+		sessionSlider = authSrv
+	}
+
+	// Tenant resolution (X-Tenant-ID header or subdomain) when multi-tenancy is enabled
+	var tenantRepo repository.TenantRepository
+	if cfg.MultiTenancy {
+		tenantRepo = dbpkg.NewTenantRepository(db, tracerProvider)
+	}
 
 	// Setup Gin router with middleware and routes
-	r := router.SetupRouter(h, redisClient, jwtManager)
+	// Counts in-flight requests, drained on shutdown after the listeners close
+	inFlight := middleware.NewInFlight()
+
+	r := router.SetupRouter(h, redisClient, jwtManager, router.Options{
+		InFlight: inFlight,
+		RateLimits: router.RouteRateLimits{
+			Login:    router.RateLimitConfig{Name: "login", Window: cfg.LoginRateLimitWindow, MaxRequests: cfg.LoginRateLimitMax},
+			Register: router.RateLimitConfig{Name: "register", Window: cfg.RegisterRateLimitWindow, MaxRequests: cfg.RegisterRateLimitMax},
+			User:     router.UserRateLimitConfig{Window: cfg.UserRateLimitWindow, MaxRequests: cfg.UserRateLimitMax},
+		},
+		Metrics:          router.MetricsConfig{Enabled: cfg.MetricsEnabled, Token: cfg.MetricsToken},
+		AdminToken:       cfg.AdminAPIToken,
+		SCIMToken:        cfg.SCIMToken,
+		AdminServices:    cfg.AdminServices(),
+		SCIMServices:     cfg.SCIMServices(),
+		IdempotencyTTL:   cfg.IdempotencyTTL,
+		SwaggerEnabled:   cfg.SwaggerEnabled,
+		SessionChecker:   sessionChecker,
+		ClaimsCache:      claimsCache,
+		SessionSlider:    sessionSlider,
+		ExternalTokens:   externalTokens,
+		Tenants:          tenantRepo,
+		TenantBaseDomain: cfg.TenantBaseDomain,
+		TracerProvider:   tracerProvider,
+		MaxBodyBytes:     cfg.MaxBodyBytes,
+		IPFilterMode:     cfg.IPFilterMode,
+		TrustedProxies:   cfg.TrustedProxies,
+
+		UserImportMaxBytes:  int64(cfg.MaxImportFileSizeMB) << 20,
+		IntrospectionSecret: cfg.IntrospectionSecret,
+		CSRFSecret:          []byte(cfg.CSRFSecret),
+		EmailWebhookSecret:  cfg.EmailWebhookSecret,
+
+		EmailWebhookSigningKey:      cfg.EmailWebhookSigningKey,
+		EmailWebhookSignatureHeader: cfg.EmailWebhookSignatureHeader,
+		EmailWebhookSignatureScheme: cfg.EmailWebhookSignatureScheme,
+
+		ExternalJWTVerifier:     externalJWTVerifier,
+		ExternalUsers:           userRepo,
+		ExternalUserPermissions: roleRepo,
+
+		CORS: middleware.CORSConfig{
+			AllowedOrigins:   cfg.CORSAllowedOrigins,
+			AllowCredentials: cfg.CORSAllowCredentials,
+			MaxAge:           cfg.CORSMaxAge,
+		},
+		SecurityHeaders: cfg.SecurityHeadersConfig(),
+	})
 
 	// Create HTTP server instance
 	srv := &http.Server{
@@ -156,28 +563,148 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Enable HTTPS when certificate files or an ACME domain are configured.
+	// redirectSrv listens on HTTP_REDIRECT_PORT and redirects plain HTTP to HTTPS.
+	redirectSrv := configureTLS(cfg, srv)
+	logTLSMode(cfg)
+
+	// Authenticate internal services by client certificate when MTLS_CLIENT_CA_FILE is set
+	if err := configureClientAuth(cfg, srv); err != nil {
+		logger.Fatal("failed to load client CA certificates", "error", err)
+	}
+
+	// Serve HTTP/2 over HTTPS when ENABLE_HTTP2 is set
+	if err := configureHTTP2(cfg, srv); err != nil {
+		logger.Fatal("failed to configure HTTP/2", "error", err)
+	}
+
 	// Start server in a goroutine
 	go func() {
 		logger.Info("HTTP server starting", "port", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listenAndServe(cfg, srv); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("server failed", "error", err)
 		}
 	}()
 
+	// Start the HTTP -> HTTPS redirect server
+	if redirectSrv != nil {
+		go func() {
+			logger.Info("HTTPS redirect server starting", "port", cfg.HTTPRedirectPort)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("redirect server failed", "error", err)
+			}
+		}()
+	}
+
+	// Start the gRPC API next to HTTP, sharing the same AuthService
+	var grpcSrv *grpc.Server
+	if cfg.GRPCPort != 0 {
+		var grpcOpts []grpc.ServerOption
+		if cfg.TLSCertFile != "" {
+			creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertFile, cfg.TLSKeyFile)
+			if err != nil {
+				logger.Fatal("failed to load gRPC TLS certificate", "error", err)
+			}
+			grpcOpts = append(grpcOpts, grpc.Creds(creds))
+		}
+		grpcSrv = grpcapi.NewServer(authSrv, tenantRepo, grpcOpts...)
+
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			logger.Fatal("failed to listen for gRPC", "error", err, "port", cfg.GRPCPort)
+		}
+		go func() {
+			logger.Info("gRPC server starting", "port", cfg.GRPCPort)
+			if err := grpcSrv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+				logger.Fatal("gRPC server failed", "error", err)
+			}
+		}()
+	}
+
+	// SIGHUP reloads the config and applies what can change while serving: the
+	// registration email domains. An invalid config is logged and ignored
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-workerCtx.Done():
+				return
+			case <-reload:
+				reloaded, errs := config.Reload()
+				if len(errs) > 0 {
+					for _, e := range errs {
+						logger.Error("config reload failed, keeping current settings", "error", e.Error())
+					}
+					continue
+				}
+				authSrv.SetEmailDomains(reloaded.AllowedEmailDomains, reloaded.BlockedEmailDomains)
+			}
+		}
+	}()
+
 	// Wait for interrupt signal (SIGINT) to trigger graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	logger.Info("Shutdown signal received...")
+	stopWorkers()
 
-	// Create shutdown context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Create shutdown context with the SHUTDOWN_TIMEOUT grace period
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	// Perform graceful shutdown
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(ctx); err != nil {
+			logger.Error("Redirect server forced to shutdown", "error", err)
+		}
+	}
+	if grpcSrv != nil {
+		stopGRPC(ctx, grpcSrv)
+	}
+	// HTTP/2 connections get a GOAWAY and are drained like HTTP/1.1 requests
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", "error", err)
 	} else {
 		logger.Info("Server stopped gracefully")
 	}
-}
\ No newline at end of file
+
+	// Wait for requests Shutdown no longer tracks (hijacked connections, or all of
+	// them when it timed out), then for the emails sent in the background
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+	if remaining := inFlight.Drain(drainCtx); remaining > 0 {
+		logger.Error("requests did not drain before exiting", "count", remaining)
+	}
+	if err := authSrv.FlushBackground(drainCtx); err != nil {
+		logger.Error("background emails did not finish before exiting", "error", err)
+	}
+
+	// Flush spans still buffered by the exporter
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+}
+
+// drainTimeout bounds the wait for in-flight requests and background emails
+// once the servers have shut down
+const drainTimeout = 5 * time.Second
+
+// stopGRPC stops the gRPC server gracefully, letting in-flight calls finish, and
+// closes remaining connections forcibly once ctx expires.
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("gRPC server stopped gracefully")
+	case <-ctx.Done():
+		server.Stop()
+		logger.Error("gRPC server forced to shutdown", "error", ctx.Err())
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/config"
+	"authentio/pkg/logger"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// =============================================================================
+// HTTPS Support
+// =============================================================================
+
+// tlsMode reports how the server should serve HTTPS.
+type tlsMode int
+
+const (
+	tlsDisabled tlsMode = iota // plain HTTP
+	tlsStatic                  // certificate and key files from TLS_CERT_FILE / TLS_KEY_FILE
+	tlsACME                    // certificates obtained automatically for ACME_DOMAIN
+)
+
+// tlsModeFor picks the HTTPS mode from the config. ACME takes precedence over static files.
+func tlsModeFor(cfg *config.Config) tlsMode {
+	switch {
+	case cfg.ACMEDomain != "":
+		return tlsACME
+	case cfg.TLSCertFile != "" && cfg.TLSKeyFile != "":
+		return tlsStatic
+	default:
+		return tlsDisabled
+	}
+}
+
+// configureTLS prepares srv for HTTPS and returns the companion plain-HTTP server that
+// redirects to HTTPS (and answers ACME HTTP-01 challenges). It returns nil when TLS is disabled.
+func configureTLS(cfg *config.Config, srv *http.Server) *http.Server {
+	mode := tlsModeFor(cfg)
+	if mode == tlsDisabled {
+		return nil
+	}
+
+	redirect := redirectToHTTPS(cfg.ServerPort)
+
+	if mode == tlsACME {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(splitDomains(cfg.ACMEDomain)...),
+			Cache:      autocert.DirCache(cfg.ACMECacheDir),
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.HTTPRedirectPort),
+		Handler:           redirect,
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+}
+
+// configureClientAuth makes srv verify TLS client certificates against the CAs
+// in MTLS_CLIENT_CA_FILE. Certificates are verified when presented but not
+// required: the listener also serves browsers and apps, and the admin and SCIM
+// routes decide whether a verified certificate authenticates the request.
+func configureClientAuth(cfg *config.Config, srv *http.Server) error {
+	if cfg.MTLSClientCAFile == "" || srv.TLSConfig == nil {
+		return nil
+	}
+
+	pem, err := os.ReadFile(cfg.MTLSClientCAFile)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return fmt.Errorf("no PEM certificates in %s", cfg.MTLSClientCAFile)
+	}
+
+	srv.TLSConfig.ClientCAs = pool
+	srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	logger.Info("TLS client certificate authentication enabled", "ca_file", cfg.MTLSClientCAFile)
+	return nil
+}
+
+// listenAndServe starts srv with plain HTTP, static TLS files, or ACME certificates.
+func listenAndServe(cfg *config.Config, srv *http.Server) error {
+	switch tlsModeFor(cfg) {
+	case tlsACME:
+		// Certificates come from srv.TLSConfig.GetCertificate
+		return srv.ListenAndServeTLS("", "")
+	case tlsStatic:
+		return srv.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	default:
+		return srv.ListenAndServe()
+	}
+}
+
+// redirectToHTTPS sends every request to the same host and path over HTTPS.
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
+
+// splitDomains parses a comma-separated ACME_DOMAIN value.
+func splitDomains(value string) []string {
+	var domains []string
+	for _, d := range strings.Split(value, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+// logTLSMode logs which HTTPS mode is active.
+func logTLSMode(cfg *config.Config) {
+	switch tlsModeFor(cfg) {
+	case tlsACME:
+		logger.Info("HTTPS enabled with automatic certificates", "domains", cfg.ACMEDomain, "cache_dir", cfg.ACMECacheDir)
+	case tlsStatic:
+		logger.Info("HTTPS enabled with certificate files", "cert", cfg.TLSCertFile)
+	default:
+		logger.Info("HTTPS disabled - serving plain HTTP")
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable pointing at an optional config file
+const ConfigFileEnv = "AUTHENTIO_CONFIG_FILE"
+
+// loadConfigFile reads a flat YAML or TOML file whose keys are the same names as the
+// environment variables (e.g. "POSTGRES_DSN: ..."; keys are case-insensitive). Each value
+// is exported into the process environment unless that variable is already set, so the
+// regular env parsing picks it up and real environment variables keep precedence.
+func loadConfigFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unsupported config file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for key, value := range values {
+		key = strings.ToUpper(key)
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, stringify(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stringify renders a decoded YAML/TOML value the way the env parser expects it.
+// Lists become comma-separated strings.
+func stringify(value any) string {
+	if list, ok := value.([]any); ok {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
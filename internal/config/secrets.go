@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"authentio/pkg/kms"
+
+	"filippo.io/age"
+	"github.com/joho/godotenv"
+)
+
+// =============================================================================
+// Secret Sources (.env files, age-encrypted .env files, HashiCorp Vault, KMS)
+// =============================================================================
+
+const (
+	// EnvFileEnv names the environment variable pointing at the .env file to load
+	// (default ".env"). A path ending in ".age" is decrypted first.
+	EnvFileEnv = "AUTHENTIO_ENV_FILE"
+
+	// AgeIdentityEnv names the environment variable pointing at the age identity
+	// (private key) file used to decrypt an encrypted .env file
+	AgeIdentityEnv = "AUTHENTIO_AGE_IDENTITY"
+
+	// defaultEnvFile is loaded when AUTHENTIO_ENV_FILE is not set
+	defaultEnvFile = ".env"
+
+	// vaultTimeout bounds the request reading secrets from Vault
+	vaultTimeout = 10 * time.Second
+
+	// kmsTimeout bounds creating the KMS client and decrypting the secrets with it
+	kmsTimeout = 10 * time.Second
+)
+
+// exportedKeys are the variables exportUnset set, which Reload unsets again
+var exportedKeys = map[string]bool{}
+
+// exportUnset exports every value into the process environment unless that
+// variable is already set, so sources loaded earlier keep precedence.
+func exportUnset(values map[string]string) error {
+	for key, value := range values {
+		if _, exists := os.LookupEnv(key); exists {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		exportedKeys[key] = true
+	}
+	return nil
+}
+
+// forgetExported unsets the variables exported from secret sources, so the
+// next LoadConfig reads them from their source again
+func forgetExported() {
+	for key := range exportedKeys {
+		os.Unsetenv(key)
+		delete(exportedKeys, key)
+	}
+}
+
+// loadAgeEnvFile decrypts an age-encrypted .env file with the identities in
+// identityPath (as written by age-keygen) and exports its variables.
+func loadAgeEnvFile(path, identityPath string) error {
+	if identityPath == "" {
+		return fmt.Errorf("%s must point at an age identity file to decrypt %s", AgeIdentityEnv, path)
+	}
+
+	keyFile, err := os.Open(identityPath)
+	if err != nil {
+		return err
+	}
+	defer keyFile.Close()
+
+	identities, err := age.ParseIdentities(keyFile)
+	if err != nil {
+		return fmt.Errorf("parse age identity %s: %w", identityPath, err)
+	}
+
+	encrypted, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer encrypted.Close()
+
+	plaintext, err := age.Decrypt(encrypted, identities...)
+	if err != nil {
+		return fmt.Errorf("decrypt %s: %w", path, err)
+	}
+
+	values, err := godotenv.Parse(plaintext)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return exportUnset(values)
+}
+
+// loadVaultSecrets reads the secret at kvPath (the API path below /v1/, e.g.
+// "secret/data/authentio") and exports its keys, which are the names of the
+// environment variables ("JWT_SECRET", ...). Both KV version 1 and version 2
+// engines are supported; for version 2 the latest version is read.
+func loadVaultSecrets(addr, token, kvPath string) error {
+	if kvPath == "" {
+		return errors.New("VAULT_KV_PATH is required when VAULT_ADDR and VAULT_TOKEN are set")
+	}
+
+	url := strings.TrimRight(addr, "/") + "/v1/" + strings.TrimLeft(kvPath, "/")
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := (&http.Client{Timeout: vaultTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("read vault secret: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("read vault secret %s: status %d: %s", kvPath, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// KV v1 returns {"data": {...}}, KV v2 returns {"data": {"data": {...}, "metadata": {...}}}
+	var payload struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return fmt.Errorf("decode vault response: %w", err)
+	}
+	secrets := payload.Data
+	if nested, ok := secrets["data"].(map[string]any); ok {
+		if _, v2 := secrets["metadata"]; v2 {
+			secrets = nested
+		}
+	}
+
+	values := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		values[strings.ToUpper(key)] = stringify(value)
+	}
+	return exportUnset(values)
+}
+
+// decryptKMSSecrets replaces JWTSecret and DBEncryptionKey, which hold base64
+// KMS ciphertext, with their plaintext. Empty values are left alone, so a
+// missing secret is still reported by Validate. The plaintext of
+// DBEncryptionKey is itself the base64 AES key.
+func (c *Config) decryptKMSSecrets() []ConfigError {
+	ctx, cancel := context.WithTimeout(context.Background(), kmsTimeout)
+	defer cancel()
+
+	decrypter, err := kms.New(ctx, c.KMSProvider, c.KMSKeyID)
+	if err != nil {
+		configErr := newConfigError("KMSProvider", "aws, gcp or noop, with KMS_KEY_ID set for gcp", c.KMSProvider)
+		configErr.Reason = err.Error()
+		return []ConfigError{configErr}
+	}
+	if closer, ok := decrypter.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var errs []ConfigError
+	for _, secret := range []struct {
+		field string
+		value *string
+	}{
+		{"JWTSecret", &c.JWTSecret},
+		{"DBEncryptionKey", &c.DBEncryptionKey},
+	} {
+		if *secret.value == "" {
+			continue
+		}
+		plaintext, err := decrypter.Decrypt(ctx, []byte(*secret.value))
+		if err != nil {
+			// Never echo the ciphertext back, as with any other secret
+			errs = append(errs, ConfigError{
+				Field:  secret.field,
+				Env:    envKey(secret.field),
+				Format: "base64 ciphertext decryptable with the " + c.KMSProvider + " KMS",
+				Value:  "<redacted>",
+				Reason: err.Error(),
+			})
+			continue
+		}
+		*secret.value = string(plaintext)
+	}
+	return errs
+}
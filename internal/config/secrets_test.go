@@ -0,0 +1,61 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"authentio/pkg/kms"
+)
+
+func TestDecryptKMSSecretsNoop(t *testing.T) {
+	jwtSecret := "jwt-secret-that-is-at-least-32-bytes"
+	dbKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	cfg := &Config{
+		KMSProvider:     kms.ProviderNoop,
+		JWTSecret:       base64.StdEncoding.EncodeToString([]byte(jwtSecret)),
+		DBEncryptionKey: base64.StdEncoding.EncodeToString([]byte(dbKey)),
+	}
+	if errs := cfg.decryptKMSSecrets(); len(errs) > 0 {
+		t.Fatalf("decryptKMSSecrets: %v", errs)
+	}
+	if cfg.JWTSecret != jwtSecret {
+		t.Errorf("JWTSecret = %q, want %q", cfg.JWTSecret, jwtSecret)
+	}
+	if cfg.DBEncryptionKey != dbKey {
+		t.Errorf("DBEncryptionKey = %q, want %q", cfg.DBEncryptionKey, dbKey)
+	}
+}
+
+func TestDecryptKMSSecretsLeavesEmptyValues(t *testing.T) {
+	cfg := &Config{KMSProvider: kms.ProviderNoop}
+	if errs := cfg.decryptKMSSecrets(); len(errs) > 0 {
+		t.Fatalf("decryptKMSSecrets: %v", errs)
+	}
+	if cfg.JWTSecret != "" || cfg.DBEncryptionKey != "" {
+		t.Fatal("empty secrets were changed")
+	}
+}
+
+func TestDecryptKMSSecretsRedactsBadCiphertext(t *testing.T) {
+	cfg := &Config{KMSProvider: kms.ProviderNoop, JWTSecret: "plaintext-not-base64!"}
+	errs := cfg.decryptKMSSecrets()
+	if len(errs) != 1 || errs[0].Field != "JWTSecret" {
+		t.Fatalf("decryptKMSSecrets = %v, want one JWTSecret error", errs)
+	}
+	if strings.Contains(errs[0].Error(), "plaintext-not-base64") {
+		t.Fatalf("error echoes the secret: %v", errs[0])
+	}
+}
+
+func TestDecryptKMSSecretsUnknownProvider(t *testing.T) {
+	cfg := &Config{KMSProvider: "vault", JWTSecret: "c2VjcmV0"}
+	errs := cfg.decryptKMSSecrets()
+	if len(errs) != 1 || errs[0].Field != "KMSProvider" {
+		t.Fatalf("decryptKMSSecrets = %v, want one KMSProvider error", errs)
+	}
+	if cfg.JWTSecret != "c2VjcmV0" {
+		t.Fatal("JWTSecret changed despite the provider error")
+	}
+}
@@ -0,0 +1,421 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"authentio/internal/middleware"
+	"authentio/pkg/otp"
+)
+
+// ConfigError describes a single configuration setting that failed to load or validate
+type ConfigError struct {
+	Field  string // Go field name on Config, e.g. "ServerPort"
+	Env    string // environment variable the field is read from, e.g. "SERVER_PORT"
+	Format string // human-readable description of the accepted format
+	Value  string // the raw value that failed
+	Reason string // optional underlying parse error
+}
+
+func (e ConfigError) Error() string {
+	if e.Env == "" {
+		return e.Reason
+	}
+	msg := fmt.Sprintf("%s (%s): expected %s, got %q", e.Env, e.Field, e.Format, e.Value)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// Validate checks the config for missing or out-of-range values and returns one
+// ConfigError per problem. It does not read the environment, so it can be used on
+// configs built by hand.
+func (c *Config) Validate() []ConfigError {
+	var errs []ConfigError
+
+	// Required settings
+	required := []struct{ field, value string }{
+		{"PostgresDSN", c.PostgresDSN},
+		{"JWTSecret", c.JWTSecret},
+		{"SMTPFrom", c.SMTPFrom},
+	}
+	switch c.EmailProvider {
+	case "smtp":
+		required = append(required, struct{ field, value string }{"SMTPPassword", c.SMTPPassword})
+	case "sendgrid":
+		required = append(required, struct{ field, value string }{"SendGridAPIKey", c.SendGridAPIKey})
+	case "mailgun":
+		required = append(required,
+			struct{ field, value string }{"MailgunDomain", c.MailgunDomain},
+			struct{ field, value string }{"MailgunAPIKey", c.MailgunAPIKey},
+		)
+	default:
+		errs = append(errs, newConfigError("EmailProvider", "smtp, sendgrid or mailgun", c.EmailProvider))
+	}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			errs = append(errs, newConfigError(r.field, "non-empty string", r.value))
+		}
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, newConfigError("ShutdownTimeout", "positive duration (e.g. 30s)", c.ShutdownTimeout))
+	}
+
+	// Ports
+	if c.ServerPort <= 0 || c.ServerPort > 65535 {
+		errs = append(errs, newConfigError("ServerPort", "integer between 1 and 65535", c.ServerPort))
+	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 || (c.GRPCPort != 0 && c.GRPCPort == c.ServerPort) {
+		errs = append(errs, newConfigError("GRPCPort", "integer between 0 and 65535, different from SERVER_PORT", c.GRPCPort))
+	}
+	if c.DBMaxOpenConns < 1 {
+		errs = append(errs, newConfigError("DBMaxOpenConns", "integer >= 1", c.DBMaxOpenConns))
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		errs = append(errs, newConfigError("DBMaxIdleConns", "integer between 0 and DB_MAX_OPEN_CONNS", c.DBMaxIdleConns))
+	}
+	if c.DBConnMaxLifetime < 0 {
+		errs = append(errs, newConfigError("DBConnMaxLifetime", "non-negative duration (e.g. 30m)", c.DBConnMaxLifetime))
+	}
+	if c.DBConnMaxIdleTime < 0 {
+		errs = append(errs, newConfigError("DBConnMaxIdleTime", "non-negative duration (e.g. 5m)", c.DBConnMaxIdleTime))
+	}
+	if c.DBStatementTimeout < 0 {
+		errs = append(errs, newConfigError("DBStatementTimeout", "non-negative duration (e.g. 10s, 0 disables the timeout)", c.DBStatementTimeout))
+	}
+	if c.DBJanitorInterval < 0 {
+		errs = append(errs, newConfigError("DBJanitorInterval", "non-negative duration (e.g. 10m, 0 disables the janitor)", c.DBJanitorInterval))
+	}
+	if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+		errs = append(errs, newConfigError("SMTPPort", "integer between 1 and 65535", c.SMTPPort))
+	}
+
+	// HTTPS
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, newConfigError("TLSKeyFile", "set together with TLS_CERT_FILE", c.TLSKeyFile))
+	}
+	if c.ACMEDomain != "" && c.TLSCertFile != "" {
+		errs = append(errs, newConfigError("ACMEDomain", "empty when TLS_CERT_FILE is set (use one or the other)", c.ACMEDomain))
+	}
+	if c.ACMEDomain != "" && c.ACMECacheDir == "" {
+		errs = append(errs, newConfigError("ACMECacheDir", "writable directory for cached certificates", c.ACMECacheDir))
+	}
+	if (c.ACMEDomain != "" || c.TLSCertFile != "") && (c.HTTPRedirectPort <= 0 || c.HTTPRedirectPort > 65535 || c.HTTPRedirectPort == c.ServerPort) {
+		errs = append(errs, newConfigError("HTTPRedirectPort", "integer between 1 and 65535, different from SERVER_PORT", c.HTTPRedirectPort))
+	}
+	if c.MTLSClientCAFile != "" && c.ACMEDomain == "" && c.TLSCertFile == "" {
+		errs = append(errs, newConfigError("MTLSClientCAFile", "empty unless HTTPS is enabled (TLS_CERT_FILE or ACME_DOMAIN)", c.MTLSClientCAFile))
+	}
+
+	// Token lifetimes
+	if c.AccessTokenTTL <= 0 {
+		errs = append(errs, newConfigError("AccessTokenTTL", "positive duration (e.g. 15m)", c.AccessTokenTTL))
+	}
+	if c.RefreshTokenTTL <= 0 {
+		errs = append(errs, newConfigError("RefreshTokenTTL", "positive duration (e.g. 24h)", c.RefreshTokenTTL))
+	}
+
+	if c.EmailVerificationTTL <= 0 {
+		errs = append(errs, newConfigError("EmailVerificationTTL", "positive duration (e.g. 24h)", c.EmailVerificationTTL))
+	}
+
+	// Rate limits
+	if c.LoginRateLimitMax <= 0 {
+		errs = append(errs, newConfigError("LoginRateLimitMax", "positive integer", c.LoginRateLimitMax))
+	}
+	if c.LoginRateLimitWindow <= 0 {
+		errs = append(errs, newConfigError("LoginRateLimitWindow", "positive duration (e.g. 1m)", c.LoginRateLimitWindow))
+	}
+	if c.RegisterRateLimitMax <= 0 {
+		errs = append(errs, newConfigError("RegisterRateLimitMax", "positive integer", c.RegisterRateLimitMax))
+	}
+	if c.RegisterRateLimitWindow <= 0 {
+		errs = append(errs, newConfigError("RegisterRateLimitWindow", "positive duration (e.g. 1h)", c.RegisterRateLimitWindow))
+	}
+	if c.UserRateLimitMax < 0 {
+		errs = append(errs, newConfigError("UserRateLimitMax", "integer >= 0", c.UserRateLimitMax))
+	}
+	if c.UserRateLimitWindow <= 0 {
+		errs = append(errs, newConfigError("UserRateLimitWindow", "positive duration (e.g. 1m)", c.UserRateLimitWindow))
+	}
+
+	// Account lockout
+	if c.MaxFailedLogins <= 0 {
+		errs = append(errs, newConfigError("MaxFailedLogins", "positive integer", c.MaxFailedLogins))
+	}
+	if c.LockoutWindow <= 0 {
+		errs = append(errs, newConfigError("LockoutWindow", "positive duration (e.g. 15m)", c.LockoutWindow))
+	}
+
+	if c.PasswordHistoryLen < 0 {
+		errs = append(errs, newConfigError("PasswordHistoryLen", "integer >= 0", c.PasswordHistoryLen))
+	}
+	if c.MinPasswordEntropy < 0 {
+		errs = append(errs, newConfigError("MinPasswordEntropy", "number >= 0", c.MinPasswordEntropy))
+	}
+	if c.OTPLength < otp.MinLength || c.OTPLength > otp.MaxLength {
+		errs = append(errs, newConfigError("OTPLength", fmt.Sprintf("integer between %d and %d", otp.MinLength, otp.MaxLength), c.OTPLength))
+	}
+	if c.BcryptCost < 4 || c.BcryptCost > 31 {
+		errs = append(errs, newConfigError("BcryptCost", "integer between 4 and 31", c.BcryptCost))
+	}
+
+	for _, server := range c.SMTPFailoverServers {
+		u, err := url.Parse(strings.TrimSpace(server))
+		if err != nil || u.Hostname() == "" || (u.Scheme != "smtp" && u.Scheme != "smtps") {
+			// The URLs carry passwords, so only the offending scheme and host are shown
+			shown := "(unparsable URL)"
+			if err == nil {
+				shown = u.Scheme + "://" + u.Host
+			}
+			errs = append(errs, newConfigError("SMTPFailoverServers", "comma-separated smtp:// or smtps:// URLs", shown))
+			break
+		}
+	}
+
+	if c.EmailQueueEnabled && c.EmailQueueConcurrency < 1 {
+		errs = append(errs, newConfigError("EmailQueueConcurrency", "integer >= 1", c.EmailQueueConcurrency))
+	}
+
+	// Event publishing
+	if c.EventsNATSURL != "" {
+		for _, server := range strings.Split(c.EventsNATSURL, ",") {
+			u, err := url.Parse(strings.TrimSpace(server))
+			if err != nil || u.Host == "" || (u.Scheme != "nats" && u.Scheme != "tls" && u.Scheme != "ws" && u.Scheme != "wss") {
+				errs = append(errs, newConfigError("EventsNATSURL", "comma-separated nats://, tls://, ws:// or wss:// URLs", c.EventsNATSURL))
+				break
+			}
+		}
+	}
+
+	if c.WebhookWorkers < 1 {
+		errs = append(errs, newConfigError("WebhookWorkers", "integer >= 1", c.WebhookWorkers))
+	}
+
+	// CORS: the spec forbids credentials with a wildcard origin
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			if c.CORSAllowCredentials {
+				errs = append(errs, newConfigError("CORSAllowCredentials", "false when CORS_ALLOWED_ORIGINS is * (list explicit origins to allow credentials)", c.CORSAllowCredentials))
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			errs = append(errs, newConfigError("CORSAllowedOrigins", "* or comma-separated origins with scheme (e.g. https://app.example.com)", origin))
+		}
+	}
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, newConfigError("CORSMaxAge", "integer >= 0 (seconds)", c.CORSMaxAge))
+	}
+	switch c.DBDriver {
+	case "postgres", "mysql", "sqlite":
+	default:
+		errs = append(errs, newConfigError("DBDriver", "postgres, mysql or sqlite", c.DBDriver))
+	}
+	switch c.LogBackend {
+	case "slog", "zap":
+	default:
+		errs = append(errs, newConfigError("LogBackend", "slog or zap", c.LogBackend))
+	}
+	switch c.IPFilterMode {
+	case "", "allowlist", "blocklist":
+	default:
+		errs = append(errs, newConfigError("IPFilterMode", "allowlist, blocklist or empty", c.IPFilterMode))
+	}
+	for _, proxy := range c.TrustedProxies {
+		if !validProxy(proxy) {
+			errs = append(errs, newConfigError("TrustedProxies", "comma-separated IP addresses or CIDRs", strings.Join(c.TrustedProxies, ",")))
+			break
+		}
+	}
+	if c.MaxBodyBytes < 0 {
+		errs = append(errs, newConfigError("MaxBodyBytes", "integer >= 0 (bytes)", c.MaxBodyBytes))
+	}
+	if c.MaxImportFileSizeMB < 1 {
+		errs = append(errs, newConfigError("MaxImportFileSizeMB", "positive integer (megabytes)", c.MaxImportFileSizeMB))
+	}
+
+	if c.ImpersonationTTL <= 0 || c.ImpersonationTTL > 24*time.Hour {
+		errs = append(errs, newConfigError("ImpersonationTTL", "duration between 1s and 24h (e.g. 1h)", c.ImpersonationTTL))
+	}
+	if c.ExternalJWKSURI != "" {
+		if u, err := url.Parse(c.ExternalJWKSURI); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, newConfigError("ExternalJWKSURI", "http(s) URL of a JWKS (e.g. https://example.auth0.com/.well-known/jwks.json)", c.ExternalJWKSURI))
+		}
+		if c.ExternalJWKSCacheTTL <= 0 {
+			errs = append(errs, newConfigError("ExternalJWKSCacheTTL", "positive duration (e.g. 1h)", c.ExternalJWKSCacheTTL))
+		}
+	}
+	if c.ExternalJWTFederation && c.ExternalJWKSURI == "" {
+		errs = append(errs, newConfigError("ExternalJWKSURI", "JWKS URL of the identity provider when EXTERNAL_JWT_FEDERATION is true", c.ExternalJWKSURI))
+	}
+	if c.SessionCacheTTL < 0 {
+		errs = append(errs, newConfigError("SessionCacheTTL", "non-negative duration (e.g. 30s, 0 disables the cache)", c.SessionCacheTTL))
+	}
+	if c.UserCacheSize < 0 {
+		errs = append(errs, newConfigError("UserCacheSize", "non-negative integer (0 disables the cache)", c.UserCacheSize))
+	}
+	if c.UserCacheSize > 0 && c.UserCacheTTL <= 0 {
+		errs = append(errs, newConfigError("UserCacheTTL", "positive duration (e.g. 30s)", c.UserCacheTTL))
+	}
+	if c.OAuthServerEnabled {
+		if c.OAuthCodeTTL <= 0 || c.OAuthCodeTTL > 10*time.Minute {
+			errs = append(errs, newConfigError("OAuthCodeTTL", "positive duration of at most 10m (e.g. 1m)", c.OAuthCodeTTL))
+		}
+		if c.OAuthAccessTokenTTL <= 0 {
+			errs = append(errs, newConfigError("OAuthAccessTokenTTL", "positive duration (e.g. 1h)", c.OAuthAccessTokenTTL))
+		}
+		if c.OAuthRefreshTokenTTL <= 0 {
+			errs = append(errs, newConfigError("OAuthRefreshTokenTTL", "positive duration (e.g. 720h)", c.OAuthRefreshTokenTTL))
+		}
+		if c.OAuthDeviceCodeTTL <= 0 {
+			errs = append(errs, newConfigError("OAuthDeviceCodeTTL", "positive duration (e.g. 10m)", c.OAuthDeviceCodeTTL))
+		}
+		if c.OAuthDevicePollInterval < time.Second || c.OAuthDevicePollInterval%time.Second != 0 {
+			errs = append(errs, newConfigError("OAuthDevicePollInterval", "whole number of seconds, at least 1s (e.g. 5s)", c.OAuthDevicePollInterval))
+		}
+		if u, err := url.Parse(c.OAuthDeviceVerificationURI); err != nil || !u.IsAbs() {
+			errs = append(errs, newConfigError("OAuthDeviceVerificationURI", "absolute URL (e.g. https://example.com/activate)", c.OAuthDeviceVerificationURI))
+		}
+	}
+	if c.EmailWebhookSigningKey != "" && !middleware.IsWebhookSignatureScheme(c.EmailWebhookSignatureScheme) {
+		errs = append(errs, newConfigError("EmailWebhookSignatureScheme", "hmac-sha256, hmac-sha1, mailgun or sendgrid", c.EmailWebhookSignatureScheme))
+	}
+	if c.PasswordResetTTL <= 0 {
+		errs = append(errs, newConfigError("PasswordResetTTL", "positive duration (e.g. 1h)", c.PasswordResetTTL))
+	}
+	if c.IdempotencyTTL < 0 {
+		errs = append(errs, newConfigError("IdempotencyTTL", "non-negative duration (e.g. 24h, 0 disables idempotency keys)", c.IdempotencyTTL))
+	}
+	if c.FlagsCacheTTL < 0 {
+		errs = append(errs, newConfigError("FlagsCacheTTL", "non-negative duration (e.g. 1m, 0 disables the cache)", c.FlagsCacheTTL))
+	}
+	if c.RememberMeTTL < c.RefreshTokenTTL {
+		errs = append(errs, newConfigError("RememberMeTTL", "duration at least REFRESH_TOKEN_TTL (e.g. 720h for 30 days)", c.RememberMeTTL))
+	}
+
+	// Tracing
+	if c.OTelExporterEndpoint != "" {
+		if u, err := url.Parse(c.OTelExporterEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, newConfigError("OTelExporterEndpoint", "http(s) URL of an OTLP collector (e.g. http://otel-collector:4318)", c.OTelExporterEndpoint))
+		}
+	}
+	if c.OTelSampleRatio < 0 || c.OTelSampleRatio > 1 {
+		errs = append(errs, newConfigError("OTelSampleRatio", "number between 0 and 1", c.OTelSampleRatio))
+	}
+
+	// Passkeys
+	if c.WebAuthnRPID != "" && len(c.WebAuthnRPOrigins) == 0 {
+		errs = append(errs, newConfigError("WebAuthnRPOrigins", "comma-separated origins (e.g. https://example.com) when WEBAUTHN_RP_ID is set", ""))
+	}
+	if c.WebAuthnTimeout <= 0 {
+		errs = append(errs, newConfigError("WebAuthnTimeout", "positive duration (e.g. 5m)", c.WebAuthnTimeout))
+	}
+
+	// LDAP needs a server and a place to search for users
+	if c.LDAPEnabled {
+		if c.LDAPURL == "" {
+			errs = append(errs, newConfigError("LDAPURL", "ldap:// or ldaps:// URL when LDAP_ENABLED is true", ""))
+		}
+		if c.LDAPUserBaseDN == "" {
+			errs = append(errs, newConfigError("LDAPUserBaseDN", "base DN (e.g. ou=people,dc=example,dc=com) when LDAP_ENABLED is true", ""))
+		}
+		if !strings.Contains(c.LDAPUserFilter, "%s") {
+			errs = append(errs, newConfigError("LDAPUserFilter", "LDAP filter containing %s for the username", c.LDAPUserFilter))
+		}
+		switch c.LDAPBindMechanism {
+		case "simple", "DIGEST-MD5":
+		default:
+			errs = append(errs, newConfigError("LDAPBindMechanism", "simple or DIGEST-MD5", c.LDAPBindMechanism))
+		}
+	}
+
+	// Twilio needs a token and sender number once an account SID is set
+	if c.TwilioAccountSID != "" {
+		if c.TwilioAuthToken == "" {
+			errs = append(errs, newConfigError("TwilioAuthToken", "non-empty string when TWILIO_ACCOUNT_SID is set", ""))
+		}
+		if c.TwilioFromNumber == "" {
+			errs = append(errs, newConfigError("TwilioFromNumber", "E.164 phone number (e.g. +15005550006) when TWILIO_ACCOUNT_SID is set", ""))
+		}
+	}
+
+	// OAuth providers need a secret and redirect URL once a client ID is set
+	oauthProviders := []struct{ id, secretField, secret, redirectField, redirect string }{
+		{c.GoogleClientID, "GoogleClientSecret", c.GoogleClientSecret, "GoogleRedirectURL", c.GoogleRedirectURL},
+		{c.GitHubClientID, "GitHubClientSecret", c.GitHubClientSecret, "GitHubRedirectURL", c.GitHubRedirectURL},
+	}
+	for _, p := range oauthProviders {
+		if p.id == "" {
+			continue
+		}
+		if p.secret == "" {
+			errs = append(errs, newConfigError(p.secretField, "non-empty string when the client ID is set", ""))
+		}
+		if p.redirect == "" {
+			errs = append(errs, newConfigError(p.redirectField, "callback URL when the client ID is set", ""))
+		}
+	}
+
+	// Encryption key is optional, but must be well-formed when set
+	if _, err := c.EncryptionKey(); err != nil {
+		e := newConfigError("DBEncryptionKey", "base64-encoded 32-byte key", "<redacted>")
+		e.Reason = err.Error()
+		errs = append(errs, e)
+	}
+
+	return errs
+}
+
+// newConfigError builds a ConfigError for a Config field, resolving its env var name
+func newConfigError(field, format string, value any) ConfigError {
+	return ConfigError{
+		Field:  field,
+		Env:    envKey(field),
+		Format: format,
+		Value:  fmt.Sprint(value),
+	}
+}
+
+// envKey returns the environment variable name declared in the env tag of a Config field
+func envKey(field string) string {
+	f, ok := reflect.TypeOf(Config{}).FieldByName(field)
+	if !ok {
+		return ""
+	}
+	return strings.Split(f.Tag.Get("env"), ",")[0]
+}
+
+// formatFor describes the accepted format for a field type
+func formatFor(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "duration (e.g. 30s, 15m, 1h)"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "integer"
+	case reflect.Float64, reflect.Float32:
+		return "number"
+	case reflect.Bool:
+		return "boolean (true or false)"
+	case reflect.Slice:
+		return "comma-separated list"
+	default:
+		return t.String()
+	}
+}
+
+// validProxy reports whether proxy is an IP address or CIDR, as gin's
+// SetTrustedProxies accepts
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
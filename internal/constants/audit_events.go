@@ -0,0 +1,43 @@
+package constants
+
+// AuditEvent is the event_type of an audit log entry
+type AuditEvent string
+
+const (
+    AuditRegister             AuditEvent = "register"
+    AuditLogin                AuditEvent = "login"
+    AuditLoginFailed          AuditEvent = "login_failed"
+    AuditLogout               AuditEvent = "logout"
+    AuditLogoutAll            AuditEvent = "logout_all"
+    AuditTokensRevoked        AuditEvent = "tokens_revoked"
+    AuditAllTokensRevoked     AuditEvent = "all_tokens_revoked"
+    AuditSessionRevoked       AuditEvent = "session_revoked"
+    AuditTokenReuse           AuditEvent = "refresh_token_reuse"
+    AuditAccountLocked        AuditEvent = "account_locked"
+    AuditAccountUnlocked      AuditEvent = "account_unlocked"
+    AuditPasswordReset        AuditEvent = "password_reset"
+    AuditPasswordResetIssued  AuditEvent = "password_reset_issued"
+    AuditPasswordChanged      AuditEvent = "password_changed"
+    AuditEmailVerified        AuditEvent = "email_verified"
+    AuditEmailChangeRequested AuditEvent = "email_change_requested"
+    AuditEmailChanged         AuditEvent = "email_changed"
+    AuditProfileUpdated       AuditEvent = "profile_updated"
+    Audit2FAEnabled           AuditEvent = "2fa_enabled"
+    Audit2FADisabled          AuditEvent = "2fa_disabled"
+    AuditPasskeyRegistered    AuditEvent = "passkey_registered"
+    AuditSecurityKeyRegistered AuditEvent = "security_key_registered"
+    AuditAccountLinked        AuditEvent = "account_linked"
+    AuditAccountUnlinked      AuditEvent = "account_unlinked"
+    AuditUserProvisioned      AuditEvent = "user_provisioned"
+    AuditUserUpdated          AuditEvent = "user_updated"
+    AuditUserDeprovisioned    AuditEvent = "user_deprovisioned"
+    AuditImpersonationStarted AuditEvent = "impersonation_started"
+    AuditImpersonationEnded   AuditEvent = "impersonation_ended"
+    AuditRoleAssigned         AuditEvent = "role_assigned"
+    AuditRoleRemoved          AuditEvent = "role_removed"
+    AuditUserDeactivated      AuditEvent = "user_deactivated"
+    AuditUserDeleted          AuditEvent = "user_deleted"
+    AuditOTPLocked            AuditEvent = "otp_locked"
+    AuditFeatureFlagSet       AuditEvent = "feature_flag_set"
+    AuditDataExported         AuditEvent = "data_exported"
+)
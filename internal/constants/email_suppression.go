@@ -0,0 +1,8 @@
+package constants
+
+// Reasons an address is on the email suppression list
+const (
+    SuppressionBounce      = "bounce"      // permanent delivery failure
+    SuppressionUnsubscribe = "unsubscribe" // the recipient opted out
+    SuppressionComplaint   = "complaint"   // the recipient reported a message as spam
+)
@@ -0,0 +1,14 @@
+package constants
+
+// IP filter modes (IP_FILTER_MODE)
+const (
+    IPFilterAllowlist = "allowlist"
+    IPFilterBlocklist = "blocklist"
+)
+
+// Redis keys of the IP filter. The sets hold permanent entries; temporary
+// blocklist entries are separate keys (IPBlocklistKey + ":" + ip) that expire.
+const (
+    IPAllowlistKey = "auth:ip:allowlist"
+    IPBlocklistKey = "auth:ip:blocklist"
+)
@@ -6,4 +6,24 @@ const (
     Type2FA           Type = "2fa"
     TypePasswordReset Type = "password_reset"
     TypeEmailVerify   Type = "email_verify"
-)
\ No newline at end of file
+    TypeEmailChange   Type = "email_change"
+)
+
+// DeliveryChannel is how a one-time code reaches the user
+type DeliveryChannel string
+
+const (
+    DeliveryEmail DeliveryChannel = "email"
+    DeliverySMS   DeliveryChannel = "sms"
+)
+
+// WebAuthnKind is what a registered WebAuthn credential is used for
+type WebAuthnKind string
+
+const (
+    // WebAuthnPasskey signs the user in without a password
+    WebAuthnPasskey WebAuthnKind = "passkey"
+
+    // WebAuthnSecurityKey is a hardware second factor challenged after the password
+    WebAuthnSecurityKey WebAuthnKind = "security_key"
+)
@@ -0,0 +1,15 @@
+package constants
+
+// Role is a user's role, issued as the "role" claim of their access tokens
+type Role string
+
+const (
+    RoleUser  Role = "user"
+    RoleAdmin Role = "admin"
+)
+
+// Permissions checked by the API itself. RBAC roles can grant any permission name;
+// these are the ones route guards refer to.
+const (
+    PermissionImpersonateUsers = "users:impersonate"
+)
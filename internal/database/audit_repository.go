@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type auditRepository struct {
+	db *tracedDB
+}
+
+// NewAuditRepository creates a new PostgreSQL audit log repository
+func NewAuditRepository(db *sql.DB, tp trace.TracerProvider) repository.AuditRepository {
+	return &auditRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *auditRepository) Log(ctx context.Context, entry models.AuditEntry) error {
+	ctx, span := r.db.startSpan(ctx, "AuditRepository.Log")
+	defer span.End()
+
+	metadata := entry.Metadata
+	if len(metadata) == 0 {
+		metadata = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_logs (user_id, tenant_id, event_type, ip, user_agent, metadata, login_country, login_city)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))`
+
+	_, err := r.db.ExecContext(ctx, query,
+		entry.UserID,
+		entry.TenantID,
+		entry.EventType,
+		entry.IP,
+		entry.UserAgent,
+		[]byte(metadata),
+		entry.LoginCountry,
+		entry.LoginCity,
+	)
+	return err
+}
+
+func (r *auditRepository) Query(ctx context.Context, filter repository.AuditFilter, page repository.Page) ([]models.AuditEntry, int, error) {
+	ctx, span := r.db.startSpan(ctx, "AuditRepository.Query")
+	defer span.End()
+
+	where := `
+		WHERE ($1::BIGINT IS NULL OR user_id = $1)
+		AND ($2 = '' OR event_type = $2)
+		AND ($3::TIMESTAMPTZ IS NULL OR created_at >= $3)
+		AND ($4::TIMESTAMPTZ IS NULL OR created_at < $4)`
+	args := []interface{}{filter.UserID, filter.EventType, nullTime(filter.From), nullTime(filter.To)}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_logs `+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, user_id, tenant_id, event_type, COALESCE(ip, ''), COALESCE(user_agent, ''), metadata, created_at,
+			COALESCE(login_country, ''), COALESCE(login_city, '')
+		FROM audit_logs ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5 OFFSET $6`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, page.Limit, page.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var metadata []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.TenantID,
+			&entry.EventType,
+			&entry.IP,
+			&entry.UserAgent,
+			&metadata,
+			&entry.CreatedAt,
+			&entry.LoginCountry,
+			&entry.LoginCity,
+		); err != nil {
+			return nil, 0, err
+		}
+		entry.Metadata = metadata
+		entries = append(entries, entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+func (r *auditRepository) LoginCountries(ctx context.Context, userID int64, since time.Time) ([]string, error) {
+	ctx, span := r.db.startSpan(ctx, "AuditRepository.LoginCountries")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT login_country
+		FROM audit_logs
+		WHERE user_id = $1 AND event_type = $2 AND login_country IS NOT NULL AND created_at >= $3`,
+		userID, string(constants.AuditLogin), since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var countries []string
+	for rows.Next() {
+		var country string
+		if err := rows.Scan(&country); err != nil {
+			return nil, err
+		}
+		countries = append(countries, country)
+	}
+	return countries, rows.Err()
+}
+
+// nullTime maps the zero time to NULL so optional bounds can be disabled in SQL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+// =============================================================================
+// Database Drivers
+// =============================================================================
+
+// Supported values of DB_DRIVER
+const (
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+	DriverSQLite   = "sqlite"
+)
+
+var (
+	//go:embed schema/mysql.sql
+	mysqlSchema string
+
+	//go:embed schema/sqlite.sql
+	sqliteSchema string
+)
+
+// Open opens a connection pool for dsn with the given driver. Query logging
+// (see openPostgres) is only available for PostgreSQL. MySQL connections
+// always parse DATETIME columns into time.Time in UTC. An SQLite pool is
+// limited to one connection, since SQLite allows a single writer and every
+// connection to ":memory:" would otherwise see its own empty database.
+func Open(driver, dsn string, queryLog bool) (*sql.DB, error) {
+	if queryLog && driver != DriverPostgres {
+		logger.Warn("DB_QUERY_LOG is only supported for PostgreSQL", "driver", driver)
+	}
+
+	switch driver {
+	case DriverPostgres:
+		return openPostgres(dsn, queryLog)
+	case DriverMySQL:
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ParseTime = true
+		cfg.Loc = time.UTC
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(connector), nil
+	case DriverSQLite:
+		db, err := sql.Open("sqlite", dsn)
+		if err != nil {
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
+		db.SetConnMaxLifetime(0)
+		return db, nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
+
+// EnsureSchema brings the schema up to date: RunMigrations for PostgreSQL,
+// and for MySQL and SQLite the idempotent CREATE statements of the tables
+// their repositories use.
+func EnsureSchema(ctx context.Context, driver string, db *sql.DB) error {
+	var schema string
+	switch driver {
+	case DriverPostgres:
+		return RunMigrations(db)
+	case DriverMySQL:
+		schema = mysqlSchema
+	case DriverSQLite:
+		schema = sqliteSchema
+	default:
+		return fmt.Errorf("unsupported database driver %q", driver)
+	}
+
+	// Neither driver runs several statements in one Exec by default
+	for _, statement := range strings.Split(stripSQLComments(schema), ";") {
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create %s schema: %w", driver, err)
+		}
+	}
+	return nil
+}
+
+// stripSQLComments removes -- comments, which may contain semicolons.
+func stripSQLComments(schema string) string {
+	lines := strings.Split(schema, "\n")
+	for i, line := range lines {
+		if before, _, found := strings.Cut(line, "--"); found {
+			lines[i] = before
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewUserStorer creates the user repository for driver.
+func NewUserStorer(driver string, db *sql.DB, tp trace.TracerProvider) (repository.UserRepository, error) {
+	switch driver {
+	case DriverPostgres:
+		return NewUserRepository(db, tp), nil
+	case DriverMySQL:
+		return NewMySQLUserRepository(db, tp), nil
+	case DriverSQLite:
+		return NewSQLiteUserRepository(db, tp), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+}
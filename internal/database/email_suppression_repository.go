@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type emailSuppressionRepository struct {
+	db *tracedDB
+}
+
+// NewEmailSuppressionRepository creates a new PostgreSQL email suppression repository.
+// Addresses are compared case-insensitively.
+func NewEmailSuppressionRepository(db *sql.DB, tp trace.TracerProvider) repository.EmailSuppressionRepository {
+	return &emailSuppressionRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *emailSuppressionRepository) Add(ctx context.Context, email, reason string) error {
+	ctx, span := r.db.startSpan(ctx, "EmailSuppressionRepository.Add")
+	defer span.End()
+
+	query := `
+		INSERT INTO email_suppressions (email, reason)
+		VALUES ($1, $2)
+		ON CONFLICT (email) DO UPDATE
+		SET reason = EXCLUDED.reason, created_at = CURRENT_TIMESTAMP`
+	_, err := r.db.ExecContext(ctx, query, normalizeSuppressedEmail(email), reason)
+	return err
+}
+
+func (r *emailSuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "EmailSuppressionRepository.IsSuppressed")
+	defer span.End()
+
+	var suppressed bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM email_suppressions WHERE email = $1)`,
+		normalizeSuppressedEmail(email),
+	).Scan(&suppressed)
+	return suppressed, err
+}
+
+func normalizeSuppressedEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"authentio/pkg/crypto"
+	"authentio/pkg/logger"
+)
+
+// =============================================================================
+// Encryption Key Rotation
+// =============================================================================
+
+// MigrateEncryption re-encrypts every TOTP secret, active or pending, OTP code and linked OAuth
+// provider token from oldKey to newKey, in one transaction, so a failure leaves
+// all rows on oldKey. Rows that already decrypt with newKey are skipped, so an
+// interrupted rotation can be run again. OTP codes stored hashed before a key
+// was configured are left as they are, since they cannot be decrypted and still
+// verify. A TOTP secret or provider token that decrypts with neither key aborts
+// the rotation, since re-encrypting it would lose it for good.
+//
+// Deploy newKey as DB_ENCRYPTION_KEY right after the rotation commits: until
+// then, running servers cannot read the rotated rows.
+func MigrateEncryption(ctx context.Context, db *sql.DB, oldKey, newKey [crypto.KeySize]byte) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	secrets := 0
+	for _, column := range []string{"secret", "pending_secret"} {
+		n, err := reencryptColumn(ctx, tx, "two_fa_configs", column, oldKey, newKey, false)
+		if err != nil {
+			return err
+		}
+		secrets += n
+	}
+	codes, err := reencryptColumn(ctx, tx, "otps", "code", oldKey, newKey, true)
+	if err != nil {
+		return err
+	}
+	providerTokens := 0
+	for _, column := range []string{"access_token_enc", "refresh_token_enc"} {
+		n, err := reencryptColumn(ctx, tx, "oauth_identities", column, oldKey, newKey, false)
+		if err != nil {
+			return err
+		}
+		providerTokens += n
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	logger.Info("encryption key rotated", "totp_secrets", secrets, "otp_codes", codes, "provider_tokens", providerTokens)
+	return nil
+}
+
+// reencryptColumn rewrites the non-empty values of table.column and returns how
+// many changed. With skipUnencrypted, values that decrypt with neither key are
+// left alone; otherwise they are an error.
+func reencryptColumn(ctx context.Context, tx *sql.Tx, table, column string, oldKey, newKey [crypto.KeySize]byte, skipUnencrypted bool) (int, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT id, %s FROM %s WHERE COALESCE(%s, '') <> '' FOR UPDATE`, column, table, column))
+	if err != nil {
+		return 0, err
+	}
+
+	updates := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var stored string
+		if err := rows.Scan(&id, &stored); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		plaintext, err := crypto.DecryptString(stored, oldKey)
+		if err != nil {
+			if _, errNew := crypto.DecryptString(stored, newKey); errNew == nil {
+				continue // Already rotated
+			}
+			if skipUnencrypted {
+				continue // Hashed or plain text code from before the key was set
+			}
+			rows.Close()
+			return 0, fmt.Errorf("%s.%s of row %d does not decrypt with the old key: %w", table, column, id, err)
+		}
+
+		sealed, err := crypto.EncryptString(plaintext, newKey)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		updates[id] = sealed
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, sealed := range updates {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE id = $1`, table, column), id, sealed); err != nil {
+			return 0, err
+		}
+	}
+	return len(updates), nil
+}
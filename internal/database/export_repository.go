@@ -0,0 +1,213 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type exportRepository struct {
+	db *tracedDB
+}
+
+// NewExportRepository creates a new PostgreSQL repository for user data exports
+func NewExportRepository(db *sql.DB, tp trace.TracerProvider) repository.ExportRepository {
+	return &exportRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *exportRepository) ExportUser(ctx context.Context, userID int64) (*models.UserExport, error) {
+	ctx, span := r.db.startSpan(ctx, "ExportRepository.ExportUser")
+	defer span.End()
+
+	// A read-only repeatable-read transaction sees one snapshot for all queries
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export := &models.UserExport{ExportedAt: time.Now().UTC()}
+	user := &export.Profile
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, first_name, last_name, email, COALESCE(provider, ''), is_active, email_verified_at, tenant_id,
+			COALESCE(phone_number, ''), COALESCE(avatar_url, ''), role, deactivated_at, COALESCE(deactivation_reason, ''),
+			created_at, updated_at
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL AND `+tenantScope("tenant_id", 2),
+		userID, tenantArg(ctx),
+	).Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.Provider,
+		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.AvatarURL,
+		&user.Role,
+		&user.DeactivatedAt,
+		&user.DeactivationReason,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if export.Roles, err = queryStrings(ctx, tx, `
+		SELECT r.name FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1 ORDER BY r.name`, userID); err != nil {
+		return nil, err
+	}
+	if export.ExternalGroups, err = queryStrings(ctx, tx, `
+		SELECT group_name FROM user_external_groups WHERE user_id = $1 ORDER BY group_name`, userID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = exportSessions(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.TwoFactor, err = exportTwoFactor(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.Credentials, err = exportCredentials(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+	if export.AuditLog, err = exportAuditLog(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	return export, tx.Commit()
+}
+
+// queryStrings returns the single text column of every row of query.
+func queryStrings(ctx context.Context, tx *tracedTx, query string, args ...any) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func exportSessions(ctx context.Context, tx *tracedTx, userID int64) ([]models.ExportedSession, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT session_id, COALESCE(user_agent, ''), COALESCE(ip, ''), remember_me, created_at, last_seen_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.ExportedSession{}
+	for rows.Next() {
+		var session models.ExportedSession
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserAgent,
+			&session.IP,
+			&session.RememberMe,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func exportTwoFactor(ctx context.Context, tx *tracedTx, userID int64) (*models.ExportedTwoFactor, error) {
+	twoFA := &models.ExportedTwoFactor{}
+	err := tx.QueryRowContext(ctx, `
+		SELECT method, COALESCE(enabled, FALSE), created_at, updated_at
+		FROM two_fa_configs
+		WHERE user_id = $1 AND deleted_at IS NULL`, userID,
+	).Scan(&twoFA.Method, &twoFA.Enabled, &twoFA.CreatedAt, &twoFA.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return twoFA, nil
+}
+
+func exportCredentials(ctx context.Context, tx *tracedTx, userID int64) ([]models.ExportedCredential, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT kind, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	credentials := []models.ExportedCredential{}
+	for rows.Next() {
+		var credential models.ExportedCredential
+		if err := rows.Scan(&credential.Kind, &credential.CreatedAt, &credential.LastUsedAt); err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+	return credentials, rows.Err()
+}
+
+func exportAuditLog(ctx context.Context, tx *tracedTx, userID int64) ([]models.AuditEntry, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, user_id, tenant_id, event_type, COALESCE(ip, ''), COALESCE(user_agent, ''), metadata, created_at,
+			COALESCE(login_country, ''), COALESCE(login_city, '')
+		FROM audit_logs
+		WHERE user_id = $1
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var metadata []byte
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.TenantID,
+			&entry.EventType,
+			&entry.IP,
+			&entry.UserAgent,
+			&metadata,
+			&entry.CreatedAt,
+			&entry.LoginCountry,
+			&entry.LoginCity,
+		); err != nil {
+			return nil, err
+		}
+		entry.Metadata = metadata
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
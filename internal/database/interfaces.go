@@ -0,0 +1,27 @@
+package database
+
+import "authentio/internal/repository"
+
+// =============================================================================
+// Storage Interfaces
+// =============================================================================
+
+// The storer interfaces are what the service layer needs from a database
+// backend. They are the repository interfaces under their storage names, so
+// an implementation for another database only has to satisfy the
+// corresponding repository interface.
+type (
+	UserStorer  = repository.UserRepository
+	TokenStorer = repository.TokenRepository
+	OTPStorer   = repository.OTPRepository
+	TwoFAStorer = repository.TwoFARepository
+)
+
+// Compile-time checks that every backend implements its storers
+var (
+	_ UserStorer  = (*userRepository)(nil)
+	_ UserStorer  = (*sqlUserRepository)(nil)
+	_ TokenStorer = (*tokenRepository)(nil)
+	_ OTPStorer   = (*otpRepository)(nil)
+	_ TwoFAStorer = (*twoFARepository)(nil)
+)
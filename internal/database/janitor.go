@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// janitorBatchSize caps the rows the janitor deletes from each table per run,
+// so a large backlog is worked off in short transactions instead of one long
+// delete that holds row locks and bloats the WAL.
+const janitorBatchSize = 1000
+
+// janitorTables are the tables whose expired rows the janitor deletes
+var janitorTables = []string{"otps", "refresh_tokens"}
+
+// StartJanitor deletes expired OTPs and refresh tokens from PostgreSQL every
+// interval, in the background, until ctx is cancelled. Each run deletes at most
+// janitorBatchSize rows per table in its own transaction; rows locked by another
+// instance's janitor are skipped. Failures are logged and retried next run.
+func StartJanitor(ctx context.Context, db *sql.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, table := range janitorTables {
+					deleted, err := deleteExpiredBatch(ctx, db, table)
+					if err != nil {
+						if ctx.Err() == nil {
+							logger.Warn("janitor failed to delete expired rows", "error", err, "table", table)
+						}
+						continue
+					}
+					logger.Debug("janitor deleted expired rows", "table", table, "rows", deleted)
+				}
+			}
+		}
+	}()
+}
+
+// deleteExpiredBatch deletes up to janitorBatchSize rows of table whose
+// expires_at has passed, in one transaction, and returns how many it deleted.
+// Like repository calls it is bounded by the statement timeout.
+func deleteExpiredBatch(ctx context.Context, db *sql.DB, table string) (int64, error) {
+	if timeout := time.Duration(statementTimeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// table comes from janitorTables, never from input
+	query := `
+		DELETE FROM ` + table + `
+		WHERE id IN (
+			SELECT id FROM ` + table + `
+			WHERE expires_at < NOW()
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)`
+	result, err := tx.ExecContext(ctx, query, janitorBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
@@ -2,28 +2,64 @@ package database
 
 
 import (
+	"context"
 	"database/sql"
+	"embed"
+	"errors"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+
+	"authentio/pkg/logger"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-// RunMigrations executes the complete schema
+// migrationFiles holds the versioned NNN_name.up.sql / NNN_name.down.sql files.
+// Every up migration is idempotent (CREATE TABLE IF NOT EXISTS and friends), so
+// databases created before migrations were tracked are brought up to date
+// without errors.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// RunMigrations applies every pending up migration. Applied versions are recorded
+// in the schema_migrations table, and a Postgres advisory lock keeps concurrently
+// starting instances from migrating at the same time.
 func RunMigrations(db *sql.DB) error {
-	// Read the schema file
-	schemaPath := filepath.Join("database", "schema.sql")
-	schemaSQL, err := os.ReadFile(schemaPath)
+	ctx := context.Background()
+
+	source, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	// A dedicated connection, so closing the migrator leaves the pool open
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get migration connection: %w", err)
+	}
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to read schema file: %w", err)
+		conn.Close()
+		return fmt.Errorf("failed to init migration driver: %w", err)
 	}
 
-	// Execute the schema
-	_, err = db.Exec(string(schemaSQL))
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
+		driver.Close()
+		return fmt.Errorf("failed to init migrations: %w", err)
 	}
+	defer m.Close()
 
-	log.Println("Database schema created successfully")
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	version, _, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	logger.Info("Database migrations applied", "version", version)
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"errors"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationName is the NNN_description.(up|down).sql naming RunMigrations relies on
+var migrationName = regexp.MustCompile(`^(\d{3})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// TestMigrationFilesArePaired checks that every embedded migration has an up
+// and a down file with the same description, and that the versions run from
+// 001 without gaps, so Down can undo every Up.
+func TestMigrationFilesArePaired(t *testing.T) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type pair struct {
+		name     string
+		up, down bool
+	}
+	pairs := map[int]*pair{}
+	for _, entry := range entries {
+		m := migrationName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			t.Errorf("%s does not match NNN_description.up.sql / NNN_description.down.sql", entry.Name())
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		p := pairs[version]
+		if p == nil {
+			p = &pair{name: m[2]}
+			pairs[version] = p
+		}
+		if p.name != m[2] {
+			t.Errorf("version %03d has files named %s and %s", version, p.name, m[2])
+		}
+		if m[3] == "up" {
+			p.up = true
+		} else {
+			p.down = true
+		}
+	}
+
+	for version := 1; version <= len(pairs); version++ {
+		p := pairs[version]
+		switch {
+		case p == nil:
+			t.Errorf("migration %03d is missing; versions must not skip numbers", version)
+		case !p.up:
+			t.Errorf("migration %03d_%s has no up file", version, p.name)
+		case !p.down:
+			t.Errorf("migration %03d_%s has no down file", version, p.name)
+		}
+	}
+}
+
+// TestMigrationSourceReadsEveryVersion opens the embedded files the way
+// RunMigrations does and reads the up and down body of every version
+// golang-migrate sees.
+func TestMigrationSourceReadsEveryVersion(t *testing.T) {
+	source, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	for want := uint(1); err == nil; want++ {
+		if version != want {
+			t.Fatalf("migration %d follows %d", version, want-1)
+		}
+		up, _, readErr := source.ReadUp(version)
+		if readErr != nil {
+			t.Fatalf("migration %d: up: %v", version, readErr)
+		}
+		up.Close()
+		down, _, readErr := source.ReadDown(version)
+		if readErr != nil {
+			t.Fatalf("migration %d: down: %v", version, readErr)
+		}
+		down.Close()
+
+		version, err = source.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+}
@@ -1,9 +1,10 @@
 -- Rollback all changes in REVERSE order of creation
 
 -- ===== 1. DROP TRIGGERS =====
-DROP TRIGGER IF EXISTS update_users_updated_at ON users;
-DROP FUNCTION IF EXISTS update_updated_at_column();
+DROP TRIGGER IF EXISTS update_refresh_tokens_updated_at ON refresh_tokens;
+DROP TRIGGER IF EXISTS update_otps_updated_at ON otps;
 DROP TRIGGER IF EXISTS update_two_fa_configs_updated_at ON two_fa_configs;
+DROP FUNCTION IF EXISTS update_updated_at_column();
 
 -- (refresh token indexes removed via their own names)
 
@@ -10,7 +10,7 @@
 -- Stores user accounts with support for both local and OAuth authentication
 -- Password is nullable to support OAuth-only users
 -- =============================================================================
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
     first_name VARCHAR(100) NOT NULL,                   -- User's first name
     last_name VARCHAR(100) NOT NULL,                    -- User's last name
@@ -32,7 +32,7 @@ CREATE TABLE users (
 -- Stores refresh tokens for maintaining user sessions
 -- Supports token revocation and expiration management
 -- =============================================================================
-CREATE TABLE refresh_tokens (
+CREATE TABLE IF NOT EXISTS refresh_tokens (
     id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
     user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- Foreign key to users
     token VARCHAR(255) UNIQUE NOT NULL,                 -- JWT refresh token
@@ -49,7 +49,7 @@ CREATE TABLE refresh_tokens (
 -- Stores One-Time Passwords for email verification, password reset, etc.
 -- Supports multiple OTP types with expiration tracking
 -- =============================================================================
-CREATE TABLE otps (
+CREATE TABLE IF NOT EXISTS otps (
     id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
     user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,  -- Foreign key to users (nullable for pre-registration)
     email VARCHAR(255) NOT NULL,                        -- Email address the OTP was sent to
@@ -68,7 +68,7 @@ CREATE TABLE otps (
 -- Stores two-factor authentication settings for users
 -- Supports multiple 2FA methods (TOTP, SMS, etc.)
 -- =============================================================================
-CREATE TABLE two_fa_configs (
+CREATE TABLE IF NOT EXISTS two_fa_configs (
     id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
     user_id BIGINT UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- One-to-one with users
     method VARCHAR(50) NOT NULL,                        -- 2FA method: 'totp', 'sms', 'email'
@@ -84,30 +84,36 @@ CREATE TABLE two_fa_configs (
 -- =============================================================================
 -- Optimize queries for OAuth provider lookups and general provider queries
 -- =============================================================================
-CREATE INDEX idx_users_provider_provider_id ON users(provider, provider_id);  -- Composite index for OAuth lookups
-CREATE INDEX idx_users_provider ON users(provider);                           -- Index for provider-based queries
+CREATE INDEX IF NOT EXISTS idx_users_provider_provider_id ON users(provider, provider_id);  -- Composite index for OAuth lookups
+CREATE INDEX IF NOT EXISTS idx_users_provider ON users(provider);                           -- Index for provider-based queries
 
 -- =============================================================================
 -- ADD TRIGGERS FOR UPDATED_AT TIMESTAMPS
 -- =============================================================================
 -- Automatically update the updated_at timestamp on record modification
--- Assumes update_updated_at_column() function exists in the database
 -- =============================================================================
+CREATE OR REPLACE FUNCTION update_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
 
 -- Trigger for refresh_tokens table
-CREATE TRIGGER update_refresh_tokens_updated_at
+CREATE OR REPLACE TRIGGER update_refresh_tokens_updated_at
     BEFORE UPDATE ON refresh_tokens
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
 -- Trigger for otps table
-CREATE TRIGGER update_otps_updated_at
+CREATE OR REPLACE TRIGGER update_otps_updated_at
     BEFORE UPDATE ON otps
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
 -- Trigger for two_fa_configs table
-CREATE TRIGGER update_two_fa_configs_updated_at
+CREATE OR REPLACE TRIGGER update_two_fa_configs_updated_at
     BEFORE UPDATE ON two_fa_configs
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
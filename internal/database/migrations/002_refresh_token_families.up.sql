@@ -0,0 +1,12 @@
+-- =============================================================================
+-- REFRESH TOKEN FAMILIES
+-- =============================================================================
+-- Every refresh token belongs to a family (one per login). A token may only be
+-- rotated once: rotation stamps used_at and issues a successor in the same
+-- family. Presenting a token that already has used_at set is treated as theft
+-- and revokes every token in its family.
+-- =============================================================================
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP WITH TIME ZONE NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
@@ -0,0 +1 @@
+ALTER TABLE two_fa_configs DROP COLUMN IF EXISTS last_used_step;
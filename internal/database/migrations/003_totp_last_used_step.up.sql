@@ -0,0 +1,8 @@
+-- =============================================================================
+-- TOTP REPLAY PROTECTION
+-- =============================================================================
+-- Records the most recent TOTP time step accepted for a user. A code is only
+-- accepted if its step is strictly greater than last_used_step, so a code
+-- cannot be replayed within its validity window.
+-- =============================================================================
+ALTER TABLE two_fa_configs ADD COLUMN IF NOT EXISTS last_used_step BIGINT NULL;
@@ -0,0 +1,10 @@
+-- =============================================================================
+-- EMAIL VERIFICATION
+-- =============================================================================
+-- Records when a user proved ownership of their email address. NULL means the
+-- address has not been verified. Existing accounts are treated as verified so
+-- enabling REQUIRE_EMAIL_VERIFICATION does not lock them out.
+-- =============================================================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE NULL;
+
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
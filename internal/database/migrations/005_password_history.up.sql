@@ -0,0 +1,14 @@
+-- =============================================================================
+-- PASSWORD HISTORY
+-- =============================================================================
+-- Keeps the hashes of a user's recent passwords so a password change can
+-- reject reuse of any of the last N passwords (PASSWORD_HISTORY_LEN).
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- Foreign key to users
+    hash VARCHAR(255) NOT NULL,                         -- bcrypt or argon2id hash of a previous password
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id_created_at ON password_history(user_id, created_at DESC);
@@ -0,0 +1,20 @@
+-- =============================================================================
+-- SESSIONS
+-- =============================================================================
+-- One row per login (device). session_id is the refresh token family ID, so
+-- every rotated refresh token of a login belongs to the same session.
+-- Revoking a session revokes its refresh token family and, when
+-- SESSION_VALIDATION is enabled, every access token carrying its session_id.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id VARCHAR(64) PRIMARY KEY,                 -- Refresh token family ID
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- Foreign key to users
+    refresh_token_hash VARCHAR(64) NOT NULL,            -- SHA-256 (hex) of the current refresh token
+    user_agent TEXT,                                    -- User-Agent of the client that last used the session
+    ip VARCHAR(64),                                     -- Client IP that last used the session
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,  -- Updated on every token refresh
+    revoked_at TIMESTAMP WITH TIME ZONE                 -- Set on logout or explicit revocation
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webauthn_credentials_user_id;
+
+DROP TABLE IF EXISTS webauthn_credentials;
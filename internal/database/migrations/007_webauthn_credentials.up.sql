@@ -0,0 +1,17 @@
+-- =============================================================================
+-- WEBAUTHN CREDENTIALS (passkeys)
+-- =============================================================================
+-- One row per registered authenticator. The full credential record
+-- (public key, sign count, flags, transports, attestation) is stored as JSON
+-- so new fields from the WebAuthn library need no schema change.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- Foreign key to users
+    credential_id BYTEA NOT NULL UNIQUE,                -- Credential ID chosen by the authenticator
+    credential JSONB NOT NULL,                          -- webauthn.Credential record
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP WITH TIME ZONE               -- Updated on every successful passkey login
+);
+
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_users_tenant_id;
+DROP INDEX IF EXISTS idx_users_tenant_email;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+
+DROP TABLE IF EXISTS tenants;
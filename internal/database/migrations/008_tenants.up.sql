@@ -0,0 +1,20 @@
+-- =============================================================================
+-- TENANTS (multi-tenancy)
+-- =============================================================================
+-- Each tenant is an isolated set of users. Users created without a tenant
+-- (tenant_id NULL) belong to the default, single-tenant installation.
+-- Email addresses are unique per tenant instead of globally.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS tenants (
+    id BIGSERIAL PRIMARY KEY,                           -- Tenant ID (tenant_id claim / X-Tenant-ID header)
+    slug VARCHAR(63) UNIQUE NOT NULL,                   -- Subdomain / X-Tenant-ID alias, e.g. 'acme'
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb,        -- Tenant-specific settings
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id BIGINT NULL REFERENCES tenants(id) ON DELETE CASCADE;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users(COALESCE(tenant_id, 0), email);
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
@@ -0,0 +1,3 @@
+ALTER TABLE otps DROP COLUMN IF EXISTS channel;
+
+ALTER TABLE users DROP COLUMN IF EXISTS phone_number;
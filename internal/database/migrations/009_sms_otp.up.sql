@@ -0,0 +1,9 @@
+-- =============================================================================
+-- SMS OTP DELIVERY
+-- =============================================================================
+-- Stores the phone number SMS codes are sent to, and records on each OTP the
+-- channel ('email' or 'sms') it was delivered through.
+-- =============================================================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_number VARCHAR(32) NULL;  -- E.164 number for SMS 2FA
+
+ALTER TABLE otps ADD COLUMN IF NOT EXISTS channel VARCHAR(10) NOT NULL DEFAULT 'email';  -- Delivery channel: 'email' or 'sms'
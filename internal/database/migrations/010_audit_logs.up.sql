@@ -0,0 +1,34 @@
+-- =============================================================================
+-- AUDIT LOG
+-- =============================================================================
+-- Append-only record of security-relevant events (logins, logouts, password
+-- and 2FA changes, ...). A trigger rejects UPDATE and DELETE so entries cannot
+-- be altered or removed through the application's database role.
+-- user_id is not a foreign key: entries must outlive the users they describe.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    user_id BIGINT NULL,                                -- Acting or affected user (NULL if unknown)
+    tenant_id BIGINT NULL,                              -- Tenant of the request (NULL when unscoped)
+    event_type VARCHAR(64) NOT NULL,                    -- e.g. 'login', 'logout', 'password_changed'
+    ip VARCHAR(64),                                     -- Client IP
+    user_agent TEXT,                                    -- Client User-Agent
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb,        -- Event-specific details
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id_created_at ON audit_logs(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_event_type_created_at ON audit_logs(event_type, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at DESC);
+
+CREATE OR REPLACE FUNCTION reject_audit_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW
+    EXECUTE FUNCTION reject_audit_log_change();
@@ -0,0 +1,7 @@
+-- =============================================================================
+-- USER ROLES
+-- =============================================================================
+-- Every user has a role, issued as the "role" claim of their access tokens.
+-- 'admin' users may use the JWT-protected admin endpoints (e.g. impersonation).
+-- =============================================================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user';  -- 'user' or 'admin'
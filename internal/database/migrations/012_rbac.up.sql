@@ -0,0 +1,33 @@
+-- =============================================================================
+-- ROLE-BASED ACCESS CONTROL
+-- =============================================================================
+-- Named roles grant sets of permissions (e.g. 'users:read'); users get the
+-- union of the permissions of their roles, issued as the "permissions" claim
+-- of their access tokens. Roles are shared by all tenants.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS roles (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    name VARCHAR(64) UNIQUE NOT NULL,                   -- Role name, e.g. 'support'
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    name VARCHAR(128) UNIQUE NOT NULL                   -- Permission name, e.g. 'users:impersonate'
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id BIGINT NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id BIGINT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles(role_id);
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deactivation_reason,
+    DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,9 @@
+-- =============================================================================
+-- ACCOUNT DEACTIVATION
+-- =============================================================================
+-- Deactivated accounts keep their data and audit history but cannot sign in.
+-- Deleting an account stays a soft delete (users.deleted_at).
+-- =============================================================================
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE NULL,  -- When the account was deactivated
+    ADD COLUMN IF NOT EXISTS deactivation_reason TEXT NULL;                 -- Why, as given by the operator
@@ -0,0 +1,4 @@
+ALTER TABLE otps
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS locked,
+    DROP COLUMN IF EXISTS attempts;
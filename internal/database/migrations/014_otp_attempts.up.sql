@@ -0,0 +1,10 @@
+-- =============================================================================
+-- OTP BRUTE-FORCE PROTECTION
+-- =============================================================================
+-- Counts failed verification attempts per OTP. Every 5 failures lock the code
+-- for an increasing period (1, 5, then 30 minutes).
+-- =============================================================================
+ALTER TABLE otps
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,         -- Failed verification attempts
+    ADD COLUMN IF NOT EXISTS locked BOOLEAN NOT NULL DEFAULT FALSE,       -- Whether the code has ever been locked
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE NULL;  -- Verification refused until this time
@@ -0,0 +1,13 @@
+-- =============================================================================
+-- EXTERNAL DIRECTORY GROUPS
+-- =============================================================================
+-- Groups a user belongs to in an external directory (LDAP / Active Directory),
+-- replaced on every directory login. Roles named after a group are assigned
+-- to its members while they stay in the group.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS user_external_groups (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_name VARCHAR(255) NOT NULL,                  -- Group common name, e.g. 'engineering'
+    synced_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, group_name)
+);
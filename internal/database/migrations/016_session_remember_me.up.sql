@@ -0,0 +1,7 @@
+-- =============================================================================
+-- REMEMBER-ME SESSIONS
+-- =============================================================================
+-- Sessions started with remember_me get refresh tokens valid for
+-- REMEMBER_ME_TTL instead of REFRESH_TOKEN_TTL, on login and on every rotation.
+-- =============================================================================
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember_me BOOLEAN NOT NULL DEFAULT FALSE;  -- Long-lived session
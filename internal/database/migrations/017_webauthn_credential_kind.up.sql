@@ -0,0 +1,7 @@
+-- =============================================================================
+-- WEBAUTHN CREDENTIAL KIND
+-- =============================================================================
+-- Passkeys sign a user in on their own; security keys (YubiKey, etc.) are a
+-- second factor challenged after the password. Existing credentials are passkeys.
+-- =============================================================================
+ALTER TABLE webauthn_credentials ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'passkey';  -- passkey or security_key
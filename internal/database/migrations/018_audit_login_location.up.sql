@@ -0,0 +1,8 @@
+-- =============================================================================
+-- AUDIT LOGIN LOCATION
+-- =============================================================================
+-- Where a login attempt came from, resolved from the client IP with the GeoIP
+-- database. NULL when geolocation is disabled or the IP is unknown.
+-- =============================================================================
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS login_country VARCHAR(2);     -- ISO 3166-1 alpha-2 code
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS login_city VARCHAR(255);      -- English city name
@@ -0,0 +1 @@
+ALTER TABLE otps ALTER COLUMN code TYPE VARCHAR(64) USING LEFT(code, 64);
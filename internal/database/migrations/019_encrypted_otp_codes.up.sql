@@ -0,0 +1,7 @@
+-- =============================================================================
+-- ENCRYPTED OTP CODES
+-- =============================================================================
+-- OTP codes are stored AES-256-GCM encrypted (base64 nonce + ciphertext + tag)
+-- when DB_ENCRYPTION_KEY is set, which no longer fits a short VARCHAR.
+-- =============================================================================
+ALTER TABLE otps ALTER COLUMN code TYPE TEXT;
@@ -0,0 +1,12 @@
+-- =============================================================================
+-- EMAIL SUPPRESSIONS
+-- =============================================================================
+-- Addresses no email is sent to, because mail to them bounced or their owner
+-- unsubscribed or reported it as spam. Filled from the email provider's
+-- webhooks (/webhooks/email/bounce, /webhooks/email/unsubscribe).
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS email_suppressions (
+    email VARCHAR(255) PRIMARY KEY,                     -- Lowercased address
+    reason VARCHAR(32) NOT NULL,                        -- bounce, unsubscribe or complaint
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP  -- When the latest event was recorded
+);
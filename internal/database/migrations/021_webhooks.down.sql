@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhooks_tenant_id;
+
+DROP TABLE IF EXISTS webhooks;
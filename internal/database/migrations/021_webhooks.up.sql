@@ -0,0 +1,18 @@
+-- =============================================================================
+-- WEBHOOKS
+-- =============================================================================
+-- Callback URLs that auth events (see pkg/events) are POSTed to, signed with
+-- the webhook's secret. A webhook receives the events of its tenant, or of
+-- users without a tenant when tenant_id is NULL.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS webhooks (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    tenant_id BIGINT NULL REFERENCES tenants(id) ON DELETE CASCADE,  -- Tenant whose events are delivered
+    url TEXT NOT NULL,                                  -- Callback URL
+    secret VARCHAR(128) NOT NULL,                       -- HMAC-SHA256 key for X-Webhook-Signature
+    events JSONB NOT NULL DEFAULT '[]'::jsonb,          -- Subscribed event types; empty subscribes to all
+    active BOOLEAN NOT NULL DEFAULT TRUE,               -- Inactive webhooks receive nothing
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id);
@@ -0,0 +1,7 @@
+-- =============================================================================
+-- EMAIL CHANGES
+-- =============================================================================
+-- A requested new address waits in pending_email until the user confirms it
+-- with the code sent there; only then does it replace users.email.
+-- =============================================================================
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255) NULL;  -- Address awaiting confirmation
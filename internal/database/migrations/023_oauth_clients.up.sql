@@ -0,0 +1,15 @@
+-- =============================================================================
+-- OAUTH CLIENTS
+-- =============================================================================
+-- Third-party applications allowed to obtain tokens from /oauth/token. Public
+-- clients (native and browser apps) have no secret and rely on PKCE alone.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    client_id VARCHAR(64) NOT NULL UNIQUE,              -- Public identifier sent by the client
+    client_secret_hash VARCHAR(64) NULL,                -- Hex SHA-256 of the secret; NULL for public clients
+    name VARCHAR(255) NOT NULL,                         -- Application name shown to operators
+    redirect_uris JSONB NOT NULL DEFAULT '[]'::jsonb,   -- Exact redirect URIs the client may use
+    allowed_scopes JSONB NOT NULL DEFAULT '[]'::jsonb,  -- Scopes the client may request
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
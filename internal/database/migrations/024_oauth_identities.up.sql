@@ -0,0 +1,29 @@
+-- =============================================================================
+-- OAUTH IDENTITIES
+-- =============================================================================
+-- Social provider accounts linked to a user, so one user can sign in with
+-- several providers. A user has at most one account per provider. Provider
+-- tokens are encrypted with DB_ENCRYPTION_KEY and NULL without one.
+--
+-- users.provider and users.provider_id keep the provider the account was
+-- created or last linked with; existing links are copied over here.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS oauth_identities (
+    id BIGSERIAL PRIMARY KEY,                           -- Auto-incrementing primary key
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,  -- Linked user
+    provider VARCHAR(50) NOT NULL,                      -- Provider name: 'google', 'github', etc.
+    provider_user_id VARCHAR(255) NOT NULL,             -- Provider's stable user identifier
+    email VARCHAR(255) NOT NULL DEFAULT '',             -- Email reported by the provider when linked
+    access_token_enc TEXT NULL,                         -- Encrypted provider access token
+    refresh_token_enc TEXT NULL,                        -- Encrypted provider refresh token
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, provider)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_identities_provider_user_id ON oauth_identities(provider, provider_user_id);
+
+INSERT INTO oauth_identities (user_id, provider, provider_user_id, email)
+SELECT id, provider, provider_id, email
+FROM users
+WHERE provider_id IS NOT NULL AND provider NOT IN ('email', 'ldap') AND deleted_at IS NULL
+ON CONFLICT (user_id, provider) DO NOTHING;
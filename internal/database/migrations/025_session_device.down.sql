@@ -0,0 +1,3 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_type;
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_browser;
+ALTER TABLE sessions DROP COLUMN IF EXISTS device_os;
@@ -0,0 +1,10 @@
+-- =============================================================================
+-- SESSION DEVICES
+-- =============================================================================
+-- The device of a session, parsed from its User-Agent when the session is
+-- saved: operating system, browser and device type (desktop, mobile, tablet).
+-- Sessions saved before this migration are parsed when they are listed.
+-- =============================================================================
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_os VARCHAR(100);       -- e.g. "Windows 10"
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_browser VARCHAR(100);  -- e.g. "Chrome 120.0.0.0"
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_type VARCHAR(16);      -- desktop, mobile or tablet
@@ -0,0 +1,5 @@
+UPDATE two_fa_configs
+SET secret = pending_secret
+WHERE method = 'totp' AND enabled = FALSE AND pending_secret IS NOT NULL;
+
+ALTER TABLE two_fa_configs DROP COLUMN IF EXISTS pending_secret;
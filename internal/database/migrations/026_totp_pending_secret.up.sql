@@ -0,0 +1,13 @@
+-- =============================================================================
+-- PENDING TOTP SECRETS
+-- =============================================================================
+-- A TOTP secret from an enrollment that has not been verified yet. It replaces
+-- secret only once a code generated from it is accepted, so re-enrolling
+-- leaves the user's current 2FA in force until the new app is confirmed.
+-- =============================================================================
+ALTER TABLE two_fa_configs ADD COLUMN IF NOT EXISTS pending_secret TEXT;  -- TOTP secret awaiting verification (encrypted)
+
+-- Enrollments started but not verified before this migration become pending
+UPDATE two_fa_configs
+SET pending_secret = secret, secret = NULL
+WHERE method = 'totp' AND enabled = FALSE AND secret IS NOT NULL AND secret <> '';
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type oauthClientRepository struct {
+	db *tracedDB
+}
+
+// NewOAuthClientRepository creates a new PostgreSQL OAuth client repository
+func NewOAuthClientRepository(db *sql.DB, tp trace.TracerProvider) repository.OAuthClientRepository {
+	return &oauthClientRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *oauthClientRepository) Create(ctx context.Context, client *models.OAuthClient) error {
+	ctx, span := r.db.startSpan(ctx, "OAuthClientRepository.Create")
+	defer span.End()
+
+	redirectURIs, err := json.Marshal(nonNil(client.RedirectURIs))
+	if err != nil {
+		return err
+	}
+	scopes, err := json.Marshal(nonNil(client.AllowedScopes))
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, allowed_scopes)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		client.ClientID,
+		client.ClientSecretHash,
+		client.Name,
+		redirectURIs,
+		scopes,
+	).Scan(&client.ID, &client.CreatedAt)
+}
+
+func (r *oauthClientRepository) FindByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	ctx, span := r.db.startSpan(ctx, "OAuthClientRepository.FindByClientID")
+	defer span.End()
+
+	query := `
+		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, allowed_scopes, created_at
+		FROM oauth_clients
+		WHERE client_id = $1`
+	clients, err := r.query(ctx, query, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(clients) == 0 {
+		return nil, repository.ErrOAuthClientNotFound
+	}
+	return &clients[0], nil
+}
+
+func (r *oauthClientRepository) List(ctx context.Context) ([]models.OAuthClient, error) {
+	ctx, span := r.db.startSpan(ctx, "OAuthClientRepository.List")
+	defer span.End()
+
+	query := `
+		SELECT id, client_id, COALESCE(client_secret_hash, ''), name, redirect_uris, allowed_scopes, created_at
+		FROM oauth_clients
+		ORDER BY created_at, id`
+	clients, err := r.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range clients {
+		clients[i].ClientSecretHash = ""
+	}
+	return clients, nil
+}
+
+func (r *oauthClientRepository) Delete(ctx context.Context, clientID string) error {
+	ctx, span := r.db.startSpan(ctx, "OAuthClientRepository.Delete")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM oauth_clients WHERE client_id = $1`, clientID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func (r *oauthClientRepository) query(ctx context.Context, query string, args ...any) ([]models.OAuthClient, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		var c models.OAuthClient
+		var redirectURIs, scopes []byte
+		if err := rows.Scan(&c.ID, &c.ClientID, &c.ClientSecretHash, &c.Name, &redirectURIs, &scopes, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := errors.Join(json.Unmarshal(redirectURIs, &c.RedirectURIs), json.Unmarshal(scopes, &c.AllowedScopes)); err != nil {
+			return nil, err
+		}
+		c.Public = c.ClientSecretHash == ""
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// nonNil returns list, or an empty list for nil, so it encodes as [] rather than null
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/crypto"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type oauthIdentityRepository struct {
+	db *tracedDB
+	// encryptionKey encrypts provider tokens at rest; nil does not store them
+	encryptionKey *[crypto.KeySize]byte
+}
+
+// NewOAuthIdentityRepository creates a new PostgreSQL repository of linked
+// OAuth identities
+func NewOAuthIdentityRepository(db *sql.DB, encryptionKey *[crypto.KeySize]byte, tp trace.TracerProvider) repository.OAuthIdentityRepository {
+	return &oauthIdentityRepository{db: newTracedDB(db, tp), encryptionKey: encryptionKey}
+}
+
+func (r *oauthIdentityRepository) Create(ctx context.Context, identity *models.OAuthIdentity) error {
+	ctx, span := r.db.startSpan(ctx, "OAuthIdentityRepository.Create")
+	defer span.End()
+
+	accessToken, err := r.sealToken(identity.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := r.sealToken(identity.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO oauth_identities (user_id, provider, provider_user_id, email, access_token_enc, refresh_token_enc)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		identity.UserID,
+		identity.Provider,
+		identity.ProviderUserID,
+		identity.Email,
+		accessToken,
+		refreshToken,
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+func (r *oauthIdentityRepository) FindByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthIdentity, error) {
+	ctx, span := r.db.startSpan(ctx, "OAuthIdentityRepository.FindByProviderUserID")
+	defer span.End()
+
+	// Identities of soft-deleted users stay in the table but no longer sign anyone in
+	query := `
+		SELECT i.id, i.user_id, i.provider, i.provider_user_id, i.email, i.created_at
+		FROM oauth_identities i
+		JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.provider_user_id = $2 AND u.deleted_at IS NULL AND ` + tenantScope("u.tenant_id", 3) + `
+		ORDER BY i.id
+		LIMIT 1`
+
+	var identity models.OAuthIdentity
+	err := r.db.QueryRowContext(ctx, query, provider, providerUserID, tenantArg(ctx)).Scan(
+		&identity.ID,
+		&identity.UserID,
+		&identity.Provider,
+		&identity.ProviderUserID,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, repository.ErrOAuthIdentityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *oauthIdentityRepository) ListByUser(ctx context.Context, userID int64) ([]models.OAuthIdentity, error) {
+	ctx, span := r.db.startSpan(ctx, "OAuthIdentityRepository.ListByUser")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, provider, provider_user_id, email, created_at
+		FROM oauth_identities
+		WHERE user_id = $1 AND ` + userTenantScope("user_id", 2) + `
+		ORDER BY provider`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.OAuthIdentity{}
+	for rows.Next() {
+		var identity models.OAuthIdentity
+		if err := rows.Scan(
+			&identity.ID,
+			&identity.UserID,
+			&identity.Provider,
+			&identity.ProviderUserID,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (r *oauthIdentityRepository) Delete(ctx context.Context, userID int64, provider string) error {
+	ctx, span := r.db.startSpan(ctx, "OAuthIdentityRepository.Delete")
+	defer span.End()
+
+	query := `DELETE FROM oauth_identities WHERE user_id = $1 AND provider = $2 AND ` + userTenantScope("user_id", 3)
+	result, err := r.db.ExecContext(ctx, query, userID, provider, tenantArg(ctx))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrOAuthIdentityNotFound
+	}
+	return nil
+}
+
+// sealToken encrypts a provider token for storage. Without an encryption key,
+// or for an empty token, it returns NULL: provider tokens are never stored in
+// plain text.
+func (r *oauthIdentityRepository) sealToken(token string) (sql.NullString, error) {
+	if r.encryptionKey == nil || token == "" {
+		return sql.NullString{}, nil
+	}
+	sealed, err := crypto.EncryptString(token, *r.encryptionKey)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: sealed, Valid: true}, nil
+}
@@ -6,50 +6,97 @@ import (
 	"time"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/crypto"
+	otpcode "authentio/pkg/otp"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type otpRepository struct {
-	db *sql.DB
+	db *tracedDB
+
+	// encryptionKey encrypts OTP codes at rest; nil stores them in plain text
+	encryptionKey *[crypto.KeySize]byte
 }
 
-func NewOTPRepository(db *sql.DB) repository.OTPRepository {
-	return &otpRepository{db: db}
+func NewOTPRepository(db *sql.DB, encryptionKey *[crypto.KeySize]byte, tp trace.TracerProvider) repository.OTPRepository {
+	return &otpRepository{db: newTracedDB(db, tp), encryptionKey: encryptionKey}
 }
 
 func (r *otpRepository) CreateOTP(ctx context.Context, otp *models.OTP) error {
+	ctx, span := r.db.startSpan(ctx, "OtpRepository.CreateOTP")
+	defer span.End()
+
 	// Set expiration to 10 minutes
 	expiredAt := time.Now().Add(10 * time.Minute)
 	otp.ExpiredAt = &expiredAt
 
+	code, err := r.sealCode(otp.Code)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO otps (user_id, email, code, type, expires_at) 
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO otps (user_id, email, code, type, expires_at, channel) 
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), 'email'))
 		RETURNING id, created_at`
 	
-	err := r.db.QueryRowContext(ctx, query,
+	err = r.db.QueryRowContext(ctx, query,
 		otp.UserID,
 		otp.Email,
-		otp.Code,
+		code,
 		otp.Type,
 		otp.ExpiredAt,
+		otp.Channel,
 	).Scan(&otp.ID, &otp.CreatedAt)
 	
 	return err
 }
 
+// VerifyOTP marks the matching code as used. Stored codes cannot be compared
+// in SQL (every ciphertext has its own nonce), so the active codes sent to email
+// are checked here with otp.Verify; the conditional update then ensures two
+// concurrent requests with the same code cannot both succeed.
 func (r *otpRepository) VerifyOTP(ctx context.Context, email, code, otpType string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "OtpRepository.VerifyOTP")
+	defer span.End()
+
+	now := time.Now()
 	query := `
-		UPDATE otps 
-		SET used = TRUE 
-		WHERE email = $1 AND code = $2 AND type = $3 
-		AND used = FALSE AND expires_at > $4
-		RETURNING id`
-	
+		SELECT id, code, created_at, expires_at FROM otps
+		WHERE email = $1 AND type = $2
+		AND used = FALSE AND expires_at > $3
+		AND (locked_until IS NULL OR locked_until <= $3) AND ` + userTenantScope("user_id", 4)
+
+	rows, err := r.db.QueryContext(ctx, query, email, otpType, now, tenantArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var matchID int64
+	for rows.Next() {
+		var id int64
+		var stored string
+		var createdAt, expiresAt time.Time
+		if err := rows.Scan(&id, &stored, &createdAt, &expiresAt); err != nil {
+			return false, err
+		}
+		if otpcode.Verify(code, r.codeHash(stored), createdAt, expiresAt.Sub(createdAt)) == nil && matchID == 0 {
+			matchID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	if matchID == 0 {
+		return false, nil // Code not found or expired
+	}
+
 	var id int64
-	err := r.db.QueryRowContext(ctx, query, email, code, otpType, time.Now()).Scan(&id)
-	
+	err = r.db.QueryRowContext(ctx, `UPDATE otps SET used = TRUE WHERE id = $1 AND used = FALSE RETURNING id`, matchID).Scan(&id)
 	if err == sql.ErrNoRows {
-		return false, nil // Code not found or expired
+		return false, nil // Used by a concurrent request
 	}
 	if err != nil {
 		return false, err
@@ -58,8 +105,112 @@ func (r *otpRepository) VerifyOTP(ctx context.Context, email, code, otpType stri
 	return true, nil
 }
 
+func (r *otpRepository) FindActiveOTP(ctx context.Context, email, otpType string) (*models.OTP, error) {
+	ctx, span := r.db.startSpan(ctx, "OtpRepository.FindActiveOTP")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, email, type, channel, expires_at, attempts, locked, locked_until, created_at
+		FROM otps
+		WHERE email = $1 AND type = $2 AND used = FALSE AND expires_at > $3
+		AND ` + userTenantScope("user_id", 4) + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`
+
+	var otp models.OTP
+	err := r.db.QueryRowContext(ctx, query, email, otpType, time.Now(), tenantArg(ctx)).Scan(
+		&otp.ID,
+		&otp.UserID,
+		&otp.Email,
+		&otp.Type,
+		&otp.Channel,
+		&otp.ExpiredAt,
+		&otp.Attempts,
+		&otp.Locked,
+		&otp.LockedUntil,
+		&otp.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+func (r *otpRepository) RecordVerificationAttempt(ctx context.Context, otpID int64) (int, error) {
+	ctx, span := r.db.startSpan(ctx, "OtpRepository.RecordVerificationAttempt")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Lock the row so concurrent wrong guesses are all counted
+	var attempts int
+	err = tx.QueryRowContext(ctx, `SELECT attempts FROM otps WHERE id = $1 FOR UPDATE`, otpID).Scan(&attempts)
+	if err != nil {
+		return 0, err
+	}
+	attempts++
+
+	var lockedUntil *time.Time
+	attemptsLeft := repository.OTPMaxAttempts - attempts%repository.OTPMaxAttempts
+	if attemptsLeft == repository.OTPMaxAttempts {
+		lockout := attempts/repository.OTPMaxAttempts - 1
+		backoff := repository.OTPLockoutBackoff[min(lockout, len(repository.OTPLockoutBackoff)-1)]
+		until := time.Now().Add(backoff)
+		lockedUntil = &until
+		attemptsLeft = 0
+	}
+
+	query := `
+		UPDATE otps
+		SET attempts = $2,
+		    locked = locked OR $3::timestamptz IS NOT NULL,
+		    locked_until = COALESCE($3, locked_until)
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, otpID, attempts, lockedUntil); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return attemptsLeft, nil
+}
+
 func (r *otpRepository) CleanupExpiredOTPs(ctx context.Context) error {
+	ctx, span := r.db.startSpan(ctx, "OtpRepository.CleanupExpiredOTPs")
+	defer span.End()
+
 	query := `DELETE FROM otps WHERE expires_at < $1`
 	_, err := r.db.ExecContext(ctx, query, time.Now())
 	return err
-}
\ No newline at end of file
+}
+
+// sealCode prepares an OTP code for storage: encrypted when an encryption key
+// is configured, otherwise as its otp.Hash.
+func (r *otpRepository) sealCode(code string) (string, error) {
+	if r.encryptionKey == nil {
+		return otpcode.Hash(code), nil
+	}
+	return crypto.EncryptString(code, *r.encryptionKey)
+}
+
+// codeHash returns the otp.Hash of a stored code, whichever way it was stored:
+// encrypted, hashed, or in plain text by releases before codes were hashed
+// (those expire within minutes).
+func (r *otpRepository) codeHash(stored string) string {
+	if r.encryptionKey != nil {
+		if code, err := crypto.DecryptString(stored, *r.encryptionKey); err == nil {
+			return otpcode.Hash(code)
+		}
+	}
+	if otpcode.IsHash(stored) {
+		return stored
+	}
+	return otpcode.Hash(stored)
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type passwordHistoryRepository struct {
+	db *tracedDB
+}
+
+// NewPasswordHistoryRepository creates a new PostgreSQL password history repository
+func NewPasswordHistoryRepository(db *sql.DB, tp trace.TracerProvider) repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *passwordHistoryRepository) Add(ctx context.Context, userID int64, hash string, keep int) error {
+	ctx, span := r.db.startSpan(ctx, "PasswordHistoryRepository.Add")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO password_history (user_id, hash) VALUES ($1, $2)`,
+		userID, hash,
+	); err != nil {
+		return err
+	}
+
+	// Prune everything older than the newest keep entries
+	pruneQuery := `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`
+	if _, err := tx.ExecContext(ctx, pruneQuery, userID, keep); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *passwordHistoryRepository) Recent(ctx context.Context, userID int64, limit int) ([]string, error) {
+	ctx, span := r.db.startSpan(ctx, "PasswordHistoryRepository.Recent")
+	defer span.End()
+
+	query := `
+		SELECT hash FROM password_history
+		WHERE user_id = $1 AND ` + userTenantScope("user_id", 3) + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5/tracelog"
+)
+
+// =============================================================================
+// Connection and Query Logging
+// =============================================================================
+
+// openPostgres opens a pgx connection pool for dsn. With queryLog, every
+// statement is logged at info level once it completes, with its SQL, duration
+// and row count; failed statements are logged at error level. Bound argument
+// values are never logged, only how many there were, since they include
+// password hashes, tokens and email addresses.
+func openPostgres(dsn string, queryLog bool) (*sql.DB, error) {
+	if !queryLog {
+		return sql.Open("pgx", dsn)
+	}
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connConfig.Tracer = &tracelog.TraceLog{
+		Logger:   tracelog.LoggerFunc(logQuery),
+		LogLevel: tracelog.LogLevelInfo,
+	}
+	return stdlib.OpenDB(*connConfig), nil
+}
+
+// logQuery writes a pgx trace event to the structured logger. Like a
+// sqldblogger Logger it receives a level, message and key/value data; only
+// completed queries are logged at info level.
+func logQuery(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
+	attrs := []any{"db.system", "postgresql"}
+	if statement, ok := data["sql"].(string); ok {
+		attrs = append(attrs, "sql", sanitizeSQL(statement))
+	}
+	if args, ok := data["args"].([]any); ok {
+		attrs = append(attrs, "args", redactArgs(args))
+	}
+	if duration, ok := data["time"].(time.Duration); ok {
+		attrs = append(attrs, "duration", duration)
+	}
+	if tag, ok := data["commandTag"].(string); ok {
+		attrs = append(attrs, "rows", pgconn.NewCommandTag(tag).RowsAffected())
+	}
+	if err, ok := data["err"].(error); ok {
+		attrs = append(attrs, "error", err)
+	}
+
+	l := logger.FromContext(ctx)
+	switch {
+	case level == tracelog.LogLevelError:
+		l.ErrorContext(ctx, "db "+msg+" failed", attrs...)
+	case msg == "Query" && level == tracelog.LogLevelInfo:
+		l.InfoContext(ctx, "db query", attrs...)
+	default:
+		// Connects, prepares and batches are noise next to the statements
+		l.Log(ctx, slog.LevelDebug, "db "+msg, attrs...)
+	}
+}
+
+// redactArgs replaces the values bound to a statement's placeholders with
+// their position, so "$1" logs as "$1=[redacted]".
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i := range args {
+		redacted[i] = fmt.Sprintf("$%d=[redacted]", i+1)
+	}
+	return redacted
+}
@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// roleColumns selects a role together with its permissions, comma-joined in name
+// order. Permission names cannot contain commas (the service validates them).
+const roleColumns = `
+		r.id, r.name, r.created_at, r.updated_at,
+		COALESCE((
+			SELECT string_agg(p.name, ',' ORDER BY p.name)
+			FROM role_permissions rp
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE rp.role_id = r.id
+		), '')`
+
+type roleRepository struct {
+	db *tracedDB
+}
+
+// NewRoleRepository creates a new PostgreSQL role repository
+func NewRoleRepository(db *sql.DB, tp trace.TracerProvider) repository.RoleRepository {
+	return &roleRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.Create")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	query := `
+		INSERT INTO roles (name, created_at, updated_at)
+		VALUES ($1, $2, $2)
+		RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, role.Name, now).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := setRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *roleRepository) FindByID(ctx context.Context, id int64) (*models.Role, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.FindByID")
+	defer span.End()
+
+	query := `SELECT ` + roleColumns + ` FROM roles r WHERE r.id = $1`
+	return scanRole(r.db.QueryRowContext(ctx, query, id))
+}
+
+func (r *roleRepository) FindByName(ctx context.Context, name string) (*models.Role, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.FindByName")
+	defer span.End()
+
+	query := `SELECT ` + roleColumns + ` FROM roles r WHERE r.name = $1`
+	return scanRole(r.db.QueryRowContext(ctx, query, name))
+}
+
+func (r *roleRepository) List(ctx context.Context) ([]models.Role, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.List")
+	defer span.End()
+
+	query := `SELECT ` + roleColumns + ` FROM roles r ORDER BY r.name`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRoles(rows)
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.Update")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE roles SET name = $2, updated_at = $3 WHERE id = $1 RETURNING created_at, updated_at`
+	err = tx.QueryRowContext(ctx, query, role.ID, role.Name, time.Now()).Scan(&role.CreatedAt, &role.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = $1`, role.ID); err != nil {
+		return err
+	}
+	if err := setRolePermissions(ctx, tx, role.ID, role.Permissions); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.Delete")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) AssignToUser(ctx context.Context, userID, roleID int64) error {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.AssignToUser")
+	defer span.End()
+
+	query := `
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT $1, id FROM roles WHERE id = $2
+		ON CONFLICT (user_id, role_id) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, userID, roleID); err != nil {
+		return err
+	}
+
+	// Distinguish "already assigned" (fine) from "no such role"
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM roles WHERE id = $1)`, roleID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) RemoveFromUser(ctx context.Context, userID, roleID int64) error {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.RemoveFromUser")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrRoleNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) ListForUser(ctx context.Context, userID int64) ([]models.Role, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.ListForUser")
+	defer span.End()
+
+	query := `
+		SELECT ` + roleColumns + `
+		FROM roles r
+		JOIN user_roles ur ON ur.role_id = r.id
+		WHERE ur.user_id = $1 AND ` + userTenantScope("ur.user_id", 2) + `
+		ORDER BY r.name`
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanRoles(rows)
+}
+
+func (r *roleRepository) PermissionsForUser(ctx context.Context, userID int64) ([]string, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.PermissionsForUser")
+	defer span.End()
+
+	query := `
+		SELECT DISTINCT p.name
+		FROM user_roles ur
+		JOIN role_permissions rp ON rp.role_id = ur.role_id
+		JOIN permissions p ON p.id = rp.permission_id
+		WHERE ur.user_id = $1 AND ` + userTenantScope("ur.user_id", 2) + `
+		ORDER BY p.name`
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+	return permissions, rows.Err()
+}
+
+func (r *roleRepository) UserHasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "RoleRepository.UserHasPermission")
+	defer span.End()
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_roles ur
+			JOIN role_permissions rp ON rp.role_id = ur.role_id
+			JOIN permissions p ON p.id = rp.permission_id
+			WHERE ur.user_id = $1 AND p.name = $2 AND ` + userTenantScope("ur.user_id", 3) + `
+		)`
+	var has bool
+	err := r.db.QueryRowContext(ctx, query, userID, permission, tenantArg(ctx)).Scan(&has)
+	return has, err
+}
+
+// setRolePermissions grants permissions to a role inside tx, creating permission
+// rows that do not exist yet.
+func setRolePermissions(ctx context.Context, tx *tracedTx, roleID int64, permissions []string) error {
+	for _, name := range permissions {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO permissions (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`,
+			name,
+		); err != nil {
+			return err
+		}
+		query := `
+			INSERT INTO role_permissions (role_id, permission_id)
+			SELECT $1, id FROM permissions WHERE name = $2
+			ON CONFLICT (role_id, permission_id) DO NOTHING`
+		if _, err := tx.ExecContext(ctx, query, roleID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanRole scans one row selected with roleColumns, returning nil if there is none.
+func scanRole(row rowScanner) (*models.Role, error) {
+	var role models.Role
+	var permissions string
+	err := row.Scan(&role.ID, &role.Name, &role.CreatedAt, &role.UpdatedAt, &permissions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role.Permissions = []string{}
+	if permissions != "" {
+		role.Permissions = strings.Split(permissions, ",")
+	}
+	return &role, nil
+}
+
+// scanRoles scans every row selected with roleColumns.
+func scanRoles(rows *sql.Rows) ([]models.Role, error) {
+	roles := []models.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
@@ -0,0 +1,39 @@
+-- =============================================================================
+-- MYSQL SCHEMA (DB_DRIVER=mysql)
+-- =============================================================================
+-- The tables used by the MySQL user repository, matching the PostgreSQL
+-- migrations. Every statement is idempotent; EnsureSchema runs them in order.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    password VARCHAR(255) NULL,                         -- Hashed password (nullable for OAuth users)
+    is_active BOOLEAN DEFAULT TRUE,
+    provider VARCHAR(50) DEFAULT 'email',
+    provider_id VARCHAR(255) NULL,
+    avatar_url TEXT NULL,
+    phone_number VARCHAR(32) NULL,
+    role VARCHAR(32) NOT NULL DEFAULT 'user',
+    tenant_id BIGINT NULL,
+    email_verified_at DATETIME(6) NULL,
+    deactivated_at DATETIME(6) NULL,
+    deactivation_reason TEXT NULL,
+    pending_email VARCHAR(255) NULL,                    -- Address awaiting confirmation
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    deleted_at DATETIME(6) NULL,
+    last_login_at DATETIME(6) NULL,
+    UNIQUE KEY idx_users_tenant_email ((COALESCE(tenant_id, 0)), email),
+    KEY idx_users_tenant_id (tenant_id),
+    KEY idx_users_provider (provider, provider_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_external_groups (
+    user_id BIGINT NOT NULL,
+    group_name VARCHAR(255) NOT NULL,
+    synced_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (user_id, group_name),
+    CONSTRAINT fk_user_external_groups_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,41 @@
+-- =============================================================================
+-- SQLITE SCHEMA (DB_DRIVER=sqlite)
+-- =============================================================================
+-- The tables used by the SQLite user repository, matching the PostgreSQL
+-- migrations. Every statement is idempotent; EnsureSchema runs them in order.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL,
+    email TEXT NOT NULL,
+    password TEXT NULL,                                 -- Hashed password (nullable for OAuth users)
+    is_active BOOLEAN DEFAULT TRUE,
+    provider TEXT DEFAULT 'email',
+    provider_id TEXT NULL,
+    avatar_url TEXT NULL,
+    phone_number TEXT NULL,
+    role TEXT NOT NULL DEFAULT 'user',
+    tenant_id INTEGER NULL,
+    email_verified_at DATETIME NULL,
+    deactivated_at DATETIME NULL,
+    deactivation_reason TEXT NULL,
+    pending_email TEXT NULL,                            -- Address awaiting confirmation
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME NULL,
+    last_login_at DATETIME NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users(COALESCE(tenant_id, 0), email);
+
+CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id);
+
+CREATE INDEX IF NOT EXISTS idx_users_provider ON users(provider, provider_id);
+
+CREATE TABLE IF NOT EXISTS user_external_groups (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    group_name TEXT NOT NULL,
+    synced_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, group_name)
+);
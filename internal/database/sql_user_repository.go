@@ -0,0 +1,507 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// MySQL and SQLite User Repository
+// =============================================================================
+
+// sqlDialect holds what differs between the databases sqlUserRepository runs
+// on. Everything else is SQL both MySQL and SQLite understand: ? placeholders,
+// no RETURNING, and timestamps written from Go in UTC rather than NOW().
+type sqlDialect struct {
+	// system is the db.system attribute of query spans
+	system string
+
+	// insertIgnore starts an INSERT that skips rows violating a unique key
+	insertIgnore string
+
+	// likeEscape makes backslash the LIKE escape character
+	likeEscape string
+}
+
+var (
+	mysqlDialect = sqlDialect{
+		system:       "mysql",
+		insertIgnore: "INSERT IGNORE",
+		likeEscape:   `ESCAPE '\\'`, // backslash escapes in MySQL string literals
+	}
+	sqliteDialect = sqlDialect{
+		system:       "sqlite",
+		insertIgnore: "INSERT OR IGNORE",
+		likeEscape:   `ESCAPE '\'`,
+	}
+)
+
+// sqlUserRepository is the UserRepository for MySQL and SQLite. The schema it
+// expects is created by EnsureSchema.
+type sqlUserRepository struct {
+	db      *tracedDB
+	dialect sqlDialect
+}
+
+// NewMySQLUserRepository creates a new MySQL user repository. The DSN must set
+// parseTime=true (Open does).
+func NewMySQLUserRepository(db *sql.DB, tp trace.TracerProvider) repository.UserRepository {
+	return &sqlUserRepository{db: newTracedDBSystem(db, tp, mysqlDialect.system), dialect: mysqlDialect}
+}
+
+// NewSQLiteUserRepository creates a new SQLite user repository, e.g. over an
+// in-memory database for running without a database server.
+func NewSQLiteUserRepository(db *sql.DB, tp trace.TracerProvider) repository.UserRepository {
+	return &sqlUserRepository{db: newTracedDBSystem(db, tp, sqliteDialect.system), dialect: sqliteDialect}
+}
+
+// sqlTenantScope is tenantScope for ? placeholders; bind tenantArg(ctx) twice.
+func sqlTenantScope(column string) string {
+	return "(? IS NULL OR " + column + " = ?)"
+}
+
+// sqlUserColumns are the columns scanned by scanSQLUser
+const sqlUserColumns = `id, first_name, last_name, email, COALESCE(password, ''), COALESCE(provider, ''), COALESCE(provider_id, ''),
+	is_active, email_verified_at, tenant_id, COALESCE(phone_number, ''), COALESCE(avatar_url, ''), role,
+	deactivated_at, COALESCE(deactivation_reason, ''), created_at, updated_at, deleted_at`
+
+// scanSQLUser scans a row of sqlUserColumns.
+func scanSQLUser(row interface{ Scan(dest ...any) error }, user *models.User) error {
+	return row.Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.Password,
+		&user.Provider,
+		&user.ProviderID,
+		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.AvatarURL,
+		&user.Role,
+		&user.DeactivatedAt,
+		&user.DeactivationReason,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+		&user.DeletedAt,
+	)
+}
+
+// findOne returns the user matching where, or nil.
+func (r *sqlUserRepository) findOne(ctx context.Context, q rowQuerier, where string, args ...any) (*models.User, error) {
+	tenant := tenantArg(ctx)
+	query := `SELECT ` + sqlUserColumns + ` FROM users WHERE ` + where + ` AND deleted_at IS NULL AND ` + sqlTenantScope("tenant_id")
+
+	user := &models.User{}
+	err := scanSQLUser(q.QueryRowContext(ctx, query, append(args, tenant, tenant)...), user)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateUser runs an UPDATE of a live user of the request's tenant; set binds
+// its ? placeholders to args.
+func (r *sqlUserRepository) updateUser(ctx context.Context, set string, userID int64, args ...any) error {
+	tenant := tenantArg(ctx)
+	query := `UPDATE users SET ` + set + ` WHERE id = ? AND deleted_at IS NULL AND ` + sqlTenantScope("tenant_id")
+	_, err := r.db.ExecContext(ctx, query, append(args, userID, tenant, tenant)...)
+	return err
+}
+
+func (r *sqlUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByEmail")
+	defer span.End()
+
+	return r.findOne(ctx, r.db, `email = ?`, email)
+}
+
+func (r *sqlUserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByID")
+	defer span.End()
+
+	return r.findOne(ctx, r.db, `id = ?`, id)
+}
+
+func (r *sqlUserRepository) FindByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByProvider")
+	defer span.End()
+
+	return r.findOne(ctx, r.db, `provider = ? AND provider_id = ?`, provider, providerID)
+}
+
+func (r *sqlUserRepository) LinkProvider(ctx context.Context, userID int64, provider, providerID, avatarURL string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.LinkProvider")
+	defer span.End()
+
+	return r.updateUser(ctx, `provider = ?, provider_id = ?, avatar_url = COALESCE(NULLIF(?, ''), avatar_url), updated_at = ?`,
+		userID, provider, providerID, avatarURL, time.Now().UTC())
+}
+
+func (r *sqlUserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.MarkEmailVerified")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return r.updateUser(ctx, `email_verified_at = COALESCE(email_verified_at, ?), updated_at = ?`, userID, now, now)
+}
+
+func (r *sqlUserRepository) UpdatePassword(ctx context.Context, userID int64, hash string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.UpdatePassword")
+	defer span.End()
+
+	return r.updateUser(ctx, `password = ?, updated_at = ?`, userID, hash, time.Now().UTC())
+}
+
+func (r *sqlUserRepository) SetPendingEmail(ctx context.Context, userID int64, email string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.SetPendingEmail")
+	defer span.End()
+
+	return r.updateUser(ctx, `pending_email = NULLIF(?, ''), updated_at = ?`, userID, email, time.Now().UTC())
+}
+
+func (r *sqlUserRepository) FindPendingEmail(ctx context.Context, userID int64) (string, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindPendingEmail")
+	defer span.End()
+
+	tenant := tenantArg(ctx)
+	query := `SELECT COALESCE(pending_email, '') FROM users WHERE id = ? AND deleted_at IS NULL AND ` + sqlTenantScope("tenant_id")
+	var email string
+	err := r.db.QueryRowContext(ctx, query, userID, tenant, tenant).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return email, err
+}
+
+func (r *sqlUserRepository) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.ConfirmPendingEmail")
+	defer span.End()
+
+	now := time.Now().UTC()
+	tenant := tenantArg(ctx)
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, email_verified_at = ?, updated_at = ?
+		WHERE id = ? AND pending_email = ? AND deleted_at IS NULL AND ` + sqlTenantScope("tenant_id")
+
+	result, err := r.db.ExecContext(ctx, query, now, now, userID, email, tenant, tenant)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *sqlUserRepository) UpdatePhoneNumber(ctx context.Context, userID int64, phoneNumber string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.UpdatePhoneNumber")
+	defer span.End()
+
+	return r.updateUser(ctx, `phone_number = NULLIF(?, ''), updated_at = ?`, userID, phoneNumber, time.Now().UTC())
+}
+
+func (r *sqlUserRepository) List(ctx context.Context, filter repository.UserFilter, cursor *repository.Cursor) (*repository.UserPage, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.List")
+	defer span.End()
+
+	createdAfter := nullTimePtr(utcPtr(filter.CreatedAfter))
+	isVerified := nullBool(filter.IsVerified)
+	tenant := tenantArg(ctx)
+
+	where := `WHERE (? = '' OR LOWER(email) = LOWER(?))
+		AND (? = '' OR LOWER(email) LIKE LOWER(?) ` + r.dialect.likeEscape + `)
+		AND (? IS NULL OR created_at > ?)
+		AND (? IS NULL OR (email_verified_at IS NOT NULL) = ?)
+		AND ` + sqlTenantScope("tenant_id") + `
+		AND (? OR deleted_at IS NULL)`
+	args := []any{
+		filter.Email, filter.Email,
+		filter.EmailLike, "%" + escapeLike(filter.EmailLike) + "%",
+		createdAfter, createdAfter,
+		isVerified, isVerified,
+		tenant, tenant,
+		filter.IncludeDeleted,
+	}
+
+	page := &repository.UserPage{Users: []models.User{}}
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	var after sql.NullTime
+	var afterID int64
+	if cursor != nil {
+		after = sql.NullTime{Time: cursor.CreatedAt.UTC(), Valid: true}
+		afterID = cursor.ID
+	}
+
+	// One extra row tells whether another page follows
+	query := `SELECT ` + sqlUserColumns + ` FROM users ` + where + `
+		AND (? IS NULL OR created_at > ? OR (created_at = ? AND id > ?))
+		ORDER BY created_at, id
+		LIMIT ? OFFSET ?`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, after, after, after, afterID, filter.Limit+1, filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := scanSQLUser(rows, &user); err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > filter.Limit {
+		page.Users = page.Users[:filter.Limit]
+		if filter.Limit > 0 {
+			last := page.Users[len(page.Users)-1]
+			page.NextCursor = &repository.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	return page, nil
+}
+
+// utcPtr returns t in UTC, so SQLite compares timestamps stored as text correctly.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func (r *sqlUserRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Create")
+	defer span.End()
+
+	return r.insertUser(ctx, r.db, user)
+}
+
+func (r *sqlUserRepository) FindOrCreate(ctx context.Context, user *models.User) (*models.User, bool, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindOrCreate")
+	defer span.End()
+
+	where := `provider = ? AND provider_id = ?`
+	existing, err := r.findOne(ctx, r.db, where, user.Provider, user.ProviderID)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+	if err := r.insertUser(ctx, r.db, user); err != nil {
+		// A concurrent request may have created the user first, failing this
+		// insert on the unique email
+		existing, findErr := r.findOne(ctx, r.db, where, user.Provider, user.ProviderID)
+		if findErr == nil && existing != nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+func (r *sqlUserRepository) CreateBatch(ctx context.Context, users []*models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.CreateBatch")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		if err := r.insertUser(ctx, tx, user); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// execer is implemented by both tracedDB and tracedTx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertUser inserts a user and sets its ID from the auto-increment key. New
+// users join the tenant the request is scoped to.
+func (r *sqlUserRepository) insertUser(ctx context.Context, q execer, user *models.User) error {
+	query := `
+		INSERT INTO users (first_name, last_name, email, password, is_active, created_at, updated_at, provider, provider_id, avatar_url, email_verified_at, tenant_id, role)
+		VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'email'), NULLIF(?, ''), NULLIF(?, ''), ?, ?, COALESCE(NULLIF(?, ''), 'user'))`
+
+	tenant := tenantArg(ctx)
+	if tenant.Valid {
+		user.TenantID = &tenant.Int64
+	}
+
+	result, err := q.ExecContext(ctx, query,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.Password,
+		user.IsActive,
+		user.CreatedAt.UTC(),
+		user.UpdatedAt.UTC(),
+		user.Provider,
+		user.ProviderID,
+		user.AvatarURL,
+		utcPtr(user.EmailVerifiedAt),
+		tenant,
+		string(user.Role),
+	)
+	if err != nil {
+		return err
+	}
+	user.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *sqlUserRepository) Update(ctx context.Context, user *models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Update")
+	defer span.End()
+
+	tenant := tenantArg(ctx)
+	query := `
+		UPDATE users
+		SET first_name = ?, last_name = ?, email = ?, is_active = ?, updated_at = ?,
+			deactivated_at = CASE WHEN ? THEN NULL ELSE deactivated_at END,
+			deactivation_reason = CASE WHEN ? THEN NULL ELSE deactivation_reason END
+		WHERE id = ? AND ` + sqlTenantScope("tenant_id")
+
+	_, err := r.db.ExecContext(ctx, query,
+		user.FirstName,
+		user.LastName,
+		user.Email,
+		user.IsActive,
+		user.UpdatedAt.UTC(),
+		user.IsActive,
+		user.IsActive,
+		user.ID,
+		tenant, tenant,
+	)
+	return err
+}
+
+// Patch updates only the fields set in patch, like the PostgreSQL repository.
+// Without RETURNING, the updated user is read back in the same transaction.
+func (r *sqlUserRepository) Patch(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Patch")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tenant := tenantArg(ctx)
+	query := `
+		UPDATE users SET
+			first_name = COALESCE(?, first_name),
+			last_name = COALESCE(?, last_name),
+			avatar_url = NULLIF(COALESCE(?, avatar_url), ''),
+			phone_number = NULLIF(COALESCE(?, phone_number), ''),
+			updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL AND ` + sqlTenantScope("tenant_id")
+
+	if _, err := tx.ExecContext(ctx, query,
+		nullStringPtr(patch.FirstName),
+		nullStringPtr(patch.LastName),
+		nullStringPtr(patch.AvatarURL),
+		nullStringPtr(patch.PhoneNumber),
+		time.Now().UTC(),
+		userID,
+		tenant, tenant,
+	); err != nil {
+		return nil, err
+	}
+
+	user, err := r.findOne(ctx, tx, `id = ?`, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+func (r *sqlUserRepository) Deactivate(ctx context.Context, id int64, reason string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Deactivate")
+	defer span.End()
+
+	now := time.Now().UTC()
+	return r.updateUser(ctx, `is_active = FALSE, deactivated_at = ?, deactivation_reason = NULLIF(?, ''), updated_at = ?`,
+		id, now, reason, now)
+}
+
+func (r *sqlUserRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Delete")
+	defer span.End()
+
+	tenant := tenantArg(ctx)
+	query := `UPDATE users SET deleted_at = ? WHERE id = ? AND ` + sqlTenantScope("tenant_id")
+	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id, tenant, tenant)
+	return err
+}
+
+// ReplaceExternalGroups reads the previous groups before deleting them, as
+// there is no DELETE ... RETURNING.
+func (r *sqlUserRepository) ReplaceExternalGroups(ctx context.Context, userID int64, groups []string) ([]string, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.ReplaceExternalGroups")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tenant := tenantArg(ctx)
+	userScope := `user_id = ? AND (? IS NULL OR user_id IN (SELECT id FROM users WHERE tenant_id = ?))`
+
+	rows, err := tx.QueryContext(ctx, `SELECT group_name FROM user_external_groups WHERE `+userScope, userID, tenant, tenant)
+	if err != nil {
+		return nil, err
+	}
+	var previous []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous = append(previous, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_external_groups WHERE `+userScope, userID, tenant, tenant); err != nil {
+		return nil, err
+	}
+	for _, name := range groups {
+		if _, err := tx.ExecContext(ctx,
+			r.dialect.insertIgnore+` INTO user_external_groups (user_id, group_name) VALUES (?, ?)`,
+			userID, name,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return previous, tx.Commit()
+}
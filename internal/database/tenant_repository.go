@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tenantRepository struct {
+	db *tracedDB
+}
+
+// NewTenantRepository creates a new PostgreSQL tenant repository
+func NewTenantRepository(db *sql.DB, tp trace.TracerProvider) repository.TenantRepository {
+	return &tenantRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *tenantRepository) FindByID(ctx context.Context, id int64) (*models.Tenant, error) {
+	ctx, span := r.db.startSpan(ctx, "TenantRepository.FindByID")
+	defer span.End()
+
+	return r.findOne(ctx, `WHERE id = $1`, id)
+}
+
+func (r *tenantRepository) FindBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	ctx, span := r.db.startSpan(ctx, "TenantRepository.FindBySlug")
+	defer span.End()
+
+	return r.findOne(ctx, `WHERE slug = $1`, slug)
+}
+
+func (r *tenantRepository) Create(ctx context.Context, tenant *models.Tenant) error {
+	ctx, span := r.db.startSpan(ctx, "TenantRepository.Create")
+	defer span.End()
+
+	settings := tenant.Settings
+	if len(settings) == 0 {
+		settings = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO tenants (slug, settings)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at`
+
+	return r.db.QueryRowContext(ctx, query, tenant.Slug, []byte(settings)).
+		Scan(&tenant.ID, &tenant.CreatedAt, &tenant.UpdatedAt)
+}
+
+func (r *tenantRepository) findOne(ctx context.Context, where string, arg interface{}) (*models.Tenant, error) {
+	ctx, span := r.db.startSpan(ctx, "TenantRepository.findOne")
+	defer span.End()
+
+	query := fmt.Sprintf(`SELECT id, slug, settings, created_at, updated_at FROM tenants %s`, where)
+
+	tenant := &models.Tenant{}
+	var settings []byte
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&tenant.ID,
+		&tenant.Slug,
+		&settings,
+		&tenant.CreatedAt,
+		&tenant.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tenant.Settings = settings
+	return tenant, nil
+}
+
+// =============================================================================
+// Tenant Scoping Helpers
+// =============================================================================
+
+// tenantArg returns the tenant the request is scoped to as a query argument,
+// or NULL when it is not tenant-scoped.
+func tenantArg(ctx context.Context) sql.NullInt64 {
+	tenantID, ok := repository.TenantIDFromContext(ctx)
+	return sql.NullInt64{Int64: tenantID, Valid: ok}
+}
+
+// tenantScope returns a condition restricting a tenant_id column to the tenant
+// bound to placeholder $n. A NULL argument disables the restriction.
+func tenantScope(column string, n int) string {
+	return fmt.Sprintf("($%d::BIGINT IS NULL OR %s = $%d)", n, column, n)
+}
+
+// userTenantScope returns a condition restricting a user_id column to users of
+// the tenant bound to placeholder $n. A NULL argument disables the restriction.
+func userTenantScope(column string, n int) string {
+	return fmt.Sprintf("($%d::BIGINT IS NULL OR %s IN (SELECT id FROM users WHERE tenant_id = $%d))", n, column, n)
+}
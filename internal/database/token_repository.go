@@ -8,27 +8,33 @@ import (
 
 	"authentio/internal/models"
 	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type tokenRepository struct {
-	db *sql.DB
+	db *tracedDB
 }
 
 // NewTokenRepository creates a new TokenRepository instance
-func NewTokenRepository(db *sql.DB) repository.TokenRepository {
-	return &tokenRepository{db: db}
+func NewTokenRepository(db *sql.DB, tp trace.TracerProvider) repository.TokenRepository {
+	return &tokenRepository{db: newTracedDB(db, tp)}
 }
 
 // SaveRefreshToken stores a new refresh token
 func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.SaveRefreshToken")
+	defer span.End()
+
 	query := `
-		INSERT INTO refresh_tokens (user_id, token, expires_at, created_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_tokens (user_id, token, family_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id`
 
 	err := r.db.QueryRowContext(ctx, query,
 		token.UserID,
 		token.Token,
+		token.FamilyID,
 		token.ExpiredAt,
 		time.Now(),
 	).Scan(&token.ID)
@@ -42,22 +48,26 @@ func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *models.Re
 
 // GetRefreshToken retrieves a refresh token by its token string
 func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenStr string) (*models.RefreshToken, error) {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.GetRefreshToken")
+	defer span.End()
+
 	query := `
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT id, user_id, token, family_id, expires_at, created_at
 		FROM refresh_tokens
-		WHERE token = $1 AND expires_at > $2`
+		WHERE token = $1 AND expires_at > $2 AND revoked = FALSE AND used_at IS NULL AND ` + userTenantScope("user_id", 3)
 
 	token := &models.RefreshToken{}
-	err := r.db.QueryRowContext(ctx, query, tokenStr, time.Now()).Scan(
+	err := r.db.QueryRowContext(ctx, query, tokenStr, time.Now(), tenantArg(ctx)).Scan(
 		&token.ID,
 		&token.UserID,
 		&token.Token,
+		&token.FamilyID,
 		&token.ExpiredAt,
 		&token.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("token not found or expired")
+		return nil, repository.ErrRefreshTokenNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -66,36 +76,393 @@ func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenStr string)
 	return token, nil
 }
 
-// DeleteRefreshToken removes a refresh token
-func (r *tokenRepository) DeleteRefreshToken(ctx context.Context, token string) error {
-	query := `DELETE FROM refresh_tokens WHERE token = $1`
-	result, err := r.db.ExecContext(ctx, query, token)
+// RotateRefreshToken marks oldToken as used and stores newToken as its successor in one transaction.
+//
+// The conditional UPDATE is what makes rotation single-use: Postgres row locking guarantees
+// that when two requests race with the same token, exactly one of them sees used_at IS NULL.
+// The loser falls through to reuse detection and revokes the whole family, which also
+// invalidates the successor the winner just issued.
+func (r *tokenRepository) RotateRefreshToken(ctx context.Context, oldToken string, newToken *models.RefreshToken) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.RotateRefreshToken")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	now := time.Now()
+	tenant := tenantArg(ctx)
+
+	var userID int64
+	var familyID string
+	err = tx.QueryRowContext(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = $2
+		WHERE token = $1 AND used_at IS NULL AND revoked = FALSE AND expires_at > $2 AND `+userTenantScope("user_id", 3)+`
+		RETURNING user_id, family_id`,
+		oldToken, now, tenant,
+	).Scan(&userID, &familyID)
+
+	if err == sql.ErrNoRows {
+		// The token could not be consumed. Find out whether it was already used.
+		var usedAt sql.NullTime
+		err = tx.QueryRowContext(ctx,
+			`SELECT family_id, used_at FROM refresh_tokens WHERE token = $1 AND `+userTenantScope("user_id", 2),
+			oldToken, tenant,
+		).Scan(&familyID, &usedAt)
+		if err == sql.ErrNoRows || (err == nil && !usedAt.Valid) {
+			return repository.ErrRefreshTokenNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		// Tokens issued before families existed have no family to revoke
+		if familyID == "" {
+			return repository.ErrRefreshTokenReused
+		}
+
+		// Reuse detected: revoke the entire family and its session, and commit before reporting it
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1`,
+			familyID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at = $2 WHERE session_id = $1 AND revoked_at IS NULL`,
+			familyID, now,
+		); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		return repository.ErrRefreshTokenReused
+	}
 	if err != nil {
 		return err
 	}
 
-	if rows == 0 {
+	// Legacy tokens without a family adopt the one proposed by the caller
+	newToken.UserID = userID
+	if familyID != "" {
+		newToken.FamilyID = familyID
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, token, family_id, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`,
+		newToken.UserID,
+		newToken.Token,
+		newToken.FamilyID,
+		newToken.ExpiredAt,
+		now,
+	).Scan(&newToken.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeTokenFamily revokes every refresh token in a family and the session it belongs to
+func (r *tokenRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.RevokeTokenFamily")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1`,
+		familyID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $2 WHERE session_id = $1 AND revoked_at IS NULL`,
+		familyID, time.Now(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRefreshToken removes a refresh token and ends the session it belongs to
+func (r *tokenRepository) DeleteRefreshToken(ctx context.Context, token string) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.DeleteRefreshToken")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var familyID string
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM refresh_tokens WHERE token = $1 AND `+userTenantScope("user_id", 2)+` RETURNING family_id`,
+		token, tenantArg(ctx),
+	).Scan(&familyID)
+	if err == sql.ErrNoRows {
 		return errors.New("token not found")
 	}
+	if err != nil {
+		return err
+	}
 
-	return nil
+	if familyID != "" {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE sessions SET revoked_at = $2 WHERE session_id = $1 AND revoked_at IS NULL`,
+			familyID, time.Now(),
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// DeleteUserRefreshTokens removes all refresh tokens for a specific user
+// DeleteUserRefreshTokens removes all refresh tokens for a specific user and ends all of their sessions
 func (r *tokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
-	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
-	_, err := r.db.ExecContext(ctx, query, userID)
-	return err
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.DeleteUserRefreshTokens")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID, time.Now(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteAllRefreshTokens removes the refresh tokens of every user and ends every session
+func (r *tokenRepository) DeleteAllRefreshTokens(ctx context.Context) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.DeleteAllRefreshTokens")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM refresh_tokens`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $1 WHERE revoked_at IS NULL`,
+		time.Now(),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // CleanupExpiredTokens removes all expired refresh tokens
 func (r *tokenRepository) CleanupExpiredTokens(ctx context.Context) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.CleanupExpiredTokens")
+	defer span.End()
+
 	query := `DELETE FROM refresh_tokens WHERE expires_at <= $1`
 	_, err := r.db.ExecContext(ctx, query, time.Now())
 	return err
-}
\ No newline at end of file
+}
+// SaveSession upserts a session. A revoked session is never revived: the ON CONFLICT
+// update is skipped so a late refresh cannot resurrect a session the user ended.
+func (r *tokenRepository) SaveSession(ctx context.Context, session *models.Session) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.SaveSession")
+	defer span.End()
+
+	now := time.Now()
+	query := `
+		INSERT INTO sessions (session_id, user_id, refresh_token_hash, user_agent, ip, remember_me, created_at, last_seen_at, device_os, device_browser, device_type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''))
+		ON CONFLICT (session_id) DO UPDATE
+		SET refresh_token_hash = EXCLUDED.refresh_token_hash,
+		    user_agent = EXCLUDED.user_agent,
+		    ip = EXCLUDED.ip,
+		    last_seen_at = EXCLUDED.last_seen_at,
+		    device_os = EXCLUDED.device_os,
+		    device_browser = EXCLUDED.device_browser,
+		    device_type = EXCLUDED.device_type
+		WHERE sessions.revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IP,
+		session.RememberMe,
+		now,
+		session.Device.OS,
+		session.Device.Browser,
+		session.Device.DeviceType,
+	)
+	return err
+}
+
+// ListSessions returns the active sessions of a user, most recently used first
+func (r *tokenRepository) ListSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.ListSessions")
+	defer span.End()
+
+	query := `
+		SELECT session_id, user_id, COALESCE(user_agent, ''), COALESCE(ip, ''), remember_me, created_at, last_seen_at,
+		       COALESCE(device_os, ''), COALESCE(device_browser, ''), COALESCE(device_type, '')
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND ` + userTenantScope("user_id", 2) + `
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var s models.Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.RememberMe, &s.CreatedAt, &s.LastSeenAt,
+			&s.Device.OS, &s.Device.Browser, &s.Device.DeviceType); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// GetSession returns an active session by ID
+func (r *tokenRepository) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.GetSession")
+	defer span.End()
+
+	query := `
+		SELECT session_id, user_id, COALESCE(user_agent, ''), COALESCE(ip, ''), remember_me, created_at, last_seen_at,
+		       COALESCE(device_os, ''), COALESCE(device_browser, ''), COALESCE(device_type, '')
+		FROM sessions
+		WHERE session_id = $1 AND revoked_at IS NULL AND ` + userTenantScope("user_id", 2)
+
+	s := &models.Session{}
+	err := r.db.QueryRowContext(ctx, query, sessionID, tenantArg(ctx)).Scan(
+		&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.RememberMe, &s.CreatedAt, &s.LastSeenAt,
+		&s.Device.OS, &s.Device.Browser, &s.Device.DeviceType,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RevokeSession marks a user's session as revoked and revokes its refresh token family in one transaction
+func (r *tokenRepository) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.RevokeSession")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $3 WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL AND `+userTenantScope("user_id", 4),
+		sessionID, userID, time.Now(), tenantArg(ctx),
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrSessionNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked = TRUE WHERE family_id = $1 AND user_id = $2`,
+		sessionID, userID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// IsSessionActive reports whether the session exists and has not been revoked
+func (r *tokenRepository) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.IsSessionActive")
+	defer span.End()
+
+	var active bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM sessions WHERE session_id = $1 AND revoked_at IS NULL AND `+userTenantScope("user_id", 2)+`)`,
+		sessionID, tenantArg(ctx),
+	).Scan(&active)
+	return active, err
+}
+
+// ExtendSession slides an active session: last_seen_at becomes now and the
+// family's current (unused, unrevoked, unexpired) refresh token expires at
+// expiresAt, unless it already expires later. Both happen in one transaction.
+func (r *tokenRepository) ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	ctx, span := r.db.startSpan(ctx, "TokenRepository.ExtendSession")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	result, err := tx.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at = $2 WHERE session_id = $1 AND revoked_at IS NULL AND `+userTenantScope("user_id", 3),
+		sessionID, now, tenantArg(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return repository.ErrSessionNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET expires_at = $2
+		WHERE family_id = $1 AND used_at IS NULL AND revoked = FALSE AND expires_at > $3 AND expires_at < $2`,
+		sessionID, expiresAt, now,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
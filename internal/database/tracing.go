@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"authentio/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Query Tracing
+// =============================================================================
+
+// tracerName identifies spans created by the repositories
+const tracerName = "authentio/internal/database"
+
+// tracedDB wraps a connection pool so every query runs in its own child span
+// carrying the (sanitized) SQL statement. Repositories call it exactly like *sql.DB.
+type tracedDB struct {
+	*sql.DB
+	tracer trace.Tracer
+
+	// system is the db.system attribute of query spans, e.g. "postgresql"
+	system string
+}
+
+// newTracedDB wraps a PostgreSQL db; a nil tp disables tracing.
+func newTracedDB(db *sql.DB, tp trace.TracerProvider) *tracedDB {
+	return newTracedDBSystem(db, tp, "postgresql")
+}
+
+// newTracedDBSystem wraps a db of another database system, e.g. "mysql".
+func newTracedDBSystem(db *sql.DB, tp trace.TracerProvider, system string) *tracedDB {
+	return &tracedDB{DB: db, tracer: tracing.Tracer(tp, tracerName), system: system}
+}
+
+// statementTimeout bounds every repository method; see SetStatementTimeout
+var statementTimeout atomic.Int64
+
+// SetStatementTimeout bounds how long a repository method may spend on its
+// queries: statements still running after d are canceled and the method
+// returns context.DeadlineExceeded. Zero disables the timeout. It applies to
+// every repository and should be set once at startup.
+func SetStatementTimeout(d time.Duration) {
+	statementTimeout.Store(int64(d))
+}
+
+// startSpan starts the span of a repository method, e.g. "UserRepository.FindByID".
+// Queries issued with the returned context become its children. The context
+// carries the statement timeout, which ends with the span.
+func (db *tracedDB) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := db.tracer.Start(ctx, name)
+
+	timeout := time.Duration(statementTimeout.Load())
+	if timeout <= 0 {
+		return ctx, span
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, &timeoutSpan{Span: span, cancel: cancel}
+}
+
+// timeoutSpan releases the statement timeout of a repository method when the
+// method's span ends, as every method does with defer span.End().
+type timeoutSpan struct {
+	trace.Span
+	cancel context.CancelFunc
+}
+
+// End cancels the method's context and ends the span.
+func (s *timeoutSpan) End(options ...trace.SpanEndOption) {
+	s.cancel()
+	s.Span.End(options...)
+}
+
+// ExecContext runs a statement in a child span.
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, db.tracer, db.system, query)
+	defer span.End()
+
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return result, err
+}
+
+// QueryContext runs a query in a child span. The span ends when the query returns,
+// not when the rows are closed.
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, db.tracer, db.system, query)
+	defer span.End()
+
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query in a child span.
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, db.tracer, db.system, query)
+	defer span.End()
+
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+// BeginTx starts a transaction whose statements are traced like those of the pool.
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, tracer: db.tracer, system: db.system}, nil
+}
+
+// tracedTx is the transaction counterpart of tracedDB.
+type tracedTx struct {
+	*sql.Tx
+	tracer trace.Tracer
+	system string
+}
+
+// ExecContext runs a statement of the transaction in a child span.
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, tx.tracer, tx.system, query)
+	defer span.End()
+
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return result, err
+}
+
+// QueryContext runs a query of the transaction in a child span.
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, tx.tracer, tx.system, query)
+	defer span.End()
+
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	recordQueryError(span, err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row query of the transaction in a child span.
+func (tx *tracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuerySpan(ctx, tx.tracer, tx.system, query)
+	defer span.End()
+
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	recordQueryError(span, row.Err())
+	return row
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// startQuerySpan starts a client span named after the statement's operation.
+func startQuerySpan(ctx context.Context, tracer trace.Tracer, system, query string) (context.Context, trace.Span) {
+	statement := sanitizeSQL(query)
+	operation, _, _ := strings.Cut(statement, " ")
+	operation = strings.ToUpper(operation)
+
+	return tracer.Start(ctx, "db."+strings.ToLower(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", system),
+			attribute.String("db.operation", operation),
+			attribute.String("db.statement", statement),
+		),
+	)
+}
+
+// recordQueryError marks the span failed. sql.ErrNoRows is an expected outcome, not an error.
+func recordQueryError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+var (
+	// sqlStringLiteral matches single-quoted literals, including '' escapes
+	sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+	// sqlNumberLiteral matches numeric literals but not $n placeholders or identifiers
+	sqlNumberLiteral = regexp.MustCompile(`([^$\w.])\d+(?:\.\d+)?\b`)
+
+	// sqlWhitespace matches runs of whitespace, collapsed to a single space
+	sqlWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL replaces literal values in a statement with "?" and collapses its
+// whitespace. Arguments bound to $n placeholders never appear in the statement,
+// so only values written inline (e.g. INTERVAL '1 day', LIMIT 100) are affected.
+func sanitizeSQL(query string) string {
+	query = sqlStringLiteral.ReplaceAllString(query, "?")
+	query = sqlNumberLiteral.ReplaceAllString(query, "${1}?")
+	return strings.TrimSpace(sqlWhitespace.ReplaceAllString(query, " "))
+}
@@ -3,20 +3,29 @@ package database
 import (
 	_ "authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/crypto"
 	"context"
 	"database/sql"
 	"errors"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type twoFARepository struct {
-	db *sql.DB
+	db *tracedDB
+
+	// encryptionKey encrypts TOTP secrets at rest; nil disables TOTP storage
+	encryptionKey *[crypto.KeySize]byte
 }
 
-func NewTwoFARepository(db *sql.DB) repository.TwoFARepository {
-	return &twoFARepository{db: db}
+func NewTwoFARepository(db *sql.DB, encryptionKey *[crypto.KeySize]byte, tp trace.TracerProvider) repository.TwoFARepository {
+	return &twoFARepository{db: newTracedDB(db, tp), encryptionKey: encryptionKey}
 }
 
 func (r *twoFARepository) EnableEmail2FA(ctx context.Context, userID int64) error {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.EnableEmail2FA")
+	defer span.End()
+
 	// For email OTP, secret is not used
 	query := `
 		INSERT INTO two_fa_configs (user_id, method, secret, enabled) 
@@ -28,17 +37,38 @@ func (r *twoFARepository) EnableEmail2FA(ctx context.Context, userID int64) erro
 	return err
 }
 
-func (r *twoFARepository) Disable2FA(ctx context.Context, userID int64) error {
-	query := `UPDATE two_fa_configs SET enabled = FALSE WHERE user_id = $1`
+func (r *twoFARepository) EnableSMS2FA(ctx context.Context, userID int64) error {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.EnableSMS2FA")
+	defer span.End()
+
+	// For SMS OTP, codes go to users.phone_number and the secret is not used
+	query := `
+		INSERT INTO two_fa_configs (user_id, method, secret, enabled)
+		VALUES ($1, 'sms', '', TRUE)
+		ON CONFLICT (user_id)
+		DO UPDATE SET method = 'sms', enabled = TRUE, updated_at = CURRENT_TIMESTAMP`
+
 	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
 
+func (r *twoFARepository) Disable2FA(ctx context.Context, userID int64) error {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.Disable2FA")
+	defer span.End()
+
+	query := `UPDATE two_fa_configs SET enabled = FALSE WHERE user_id = $1 AND ` + userTenantScope("user_id", 2)
+	_, err := r.db.ExecContext(ctx, query, userID, tenantArg(ctx))
+	return err
+}
+
 func (r *twoFARepository) Is2FAEnabled(ctx context.Context, userID int64) (bool, error) {
-	query := `SELECT enabled FROM two_fa_configs WHERE user_id = $1`
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.Is2FAEnabled")
+	defer span.End()
+
+	query := `SELECT enabled FROM two_fa_configs WHERE user_id = $1 AND ` + userTenantScope("user_id", 2)
 	
 	var enabled bool
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&enabled)
+	err := r.db.QueryRowContext(ctx, query, userID, tenantArg(ctx)).Scan(&enabled)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -49,12 +79,121 @@ func (r *twoFARepository) Is2FAEnabled(ctx context.Context, userID int64) (bool,
 	return enabled, nil
 }
 
+// SaveTOTPSecret encrypts and stores a TOTP secret as pending. Re-enrolling only
+// replaces the pending secret: method, enabled and the active secret are left
+// unchanged until EnableTOTP promotes the new secret.
+func (r *twoFARepository) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.SaveTOTPSecret")
+	defer span.End()
+
+	if r.encryptionKey == nil {
+		return repository.ErrEncryptionKeyMissing
+	}
+
+	encrypted, err := crypto.EncryptString(secret, *r.encryptionKey)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO two_fa_configs (user_id, method, pending_secret, enabled, last_used_step)
+		VALUES ($1, 'totp', $2, FALSE, NULL)
+		ON CONFLICT (user_id)
+		DO UPDATE SET pending_secret = $2, updated_at = CURRENT_TIMESTAMP`
+
+	_, err = r.db.ExecContext(ctx, query, userID, encrypted)
+	return err
+}
+
+// GetPendingTOTPSecret returns the decrypted TOTP secret awaiting verification
+func (r *twoFARepository) GetPendingTOTPSecret(ctx context.Context, userID int64) (string, error) {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.GetPendingTOTPSecret")
+	defer span.End()
+
+	query := `SELECT pending_secret FROM two_fa_configs WHERE user_id = $1 AND ` + userTenantScope("user_id", 2)
+	return r.scanTOTPSecret(ctx, query, userID)
+}
+
+// Get2FASecret returns the decrypted TOTP secret of a user with TOTP enabled
 func (r *twoFARepository) Get2FASecret(ctx context.Context, userID int64) (string, error) {
-	// Not used for email OTP
-	return "", errors.New("not applicable for email OTP")
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.Get2FASecret")
+	defer span.End()
+
+	query := `SELECT secret FROM two_fa_configs WHERE user_id = $1 AND method = 'totp' AND enabled = TRUE AND ` + userTenantScope("user_id", 2)
+	return r.scanTOTPSecret(ctx, query, userID)
+}
+
+// scanTOTPSecret runs a query selecting one encrypted secret column and decrypts
+// it. A missing row or an empty secret means no such TOTP secret is on file.
+func (r *twoFARepository) scanTOTPSecret(ctx context.Context, query string, userID int64) (string, error) {
+	if r.encryptionKey == nil {
+		return "", repository.ErrEncryptionKeyMissing
+	}
+
+	var encrypted sql.NullString
+	err := r.db.QueryRowContext(ctx, query, userID, tenantArg(ctx)).Scan(&encrypted)
+	if err == sql.ErrNoRows || (err == nil && encrypted.String == "") {
+		return "", repository.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return crypto.DecryptString(encrypted.String, *r.encryptionKey)
+}
+
+// EnableTOTP activates TOTP once the user has proven they can generate valid codes
+// from the pending secret. The pending secret becomes the active one and step the
+// last accepted time step, so the verifying code cannot be replayed at login.
+func (r *twoFARepository) EnableTOTP(ctx context.Context, userID int64, step int64) error {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.EnableTOTP")
+	defer span.End()
+
+	query := `
+		UPDATE two_fa_configs
+		SET method = 'totp', secret = pending_secret, pending_secret = NULL, enabled = TRUE,
+			last_used_step = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND pending_secret IS NOT NULL AND pending_secret <> '' AND ` + userTenantScope("user_id", 3)
+
+	result, err := r.db.ExecContext(ctx, query, userID, step, tenantArg(ctx))
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrTOTPNotEnrolled
+	}
+	return nil
+}
+
+// RecordTOTPStep atomically advances last_used_step. The conditional update means two
+// concurrent requests with the same code cannot both succeed.
+func (r *twoFARepository) RecordTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.RecordTOTPStep")
+	defer span.End()
+
+	query := `
+		UPDATE two_fa_configs SET last_used_step = $2
+		WHERE user_id = $1 AND method = 'totp' AND (last_used_step IS NULL OR last_used_step < $2) AND ` + userTenantScope("user_id", 3)
+
+	result, err := r.db.ExecContext(ctx, query, userID, step, tenantArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
 }
 
 func (r *twoFARepository) VerifyOTP(ctx context.Context, userID int64,email, code, otpType string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.VerifyOTP")
+	defer span.End()
+
 	// This method is not used for email OTP
 	// Email OTP verification is handled by OTPRepository
 	return false, errors.New("use OTPRepository for email OTP verification")
@@ -62,9 +201,12 @@ func (r *twoFARepository) VerifyOTP(ctx context.Context, userID int64,email, cod
 
 // Get2FAMethod returns the 2FA method (e.g., "email", "sms", "totp") for a user
 func (r *twoFARepository) Get2FAMethod(ctx context.Context, userID int64) (string, error) {
-	query := `SELECT method FROM two_fa_configs WHERE user_id = $1`
+	ctx, span := r.db.startSpan(ctx, "TwoFARepository.Get2FAMethod")
+	defer span.End()
+
+	query := `SELECT method FROM two_fa_configs WHERE user_id = $1 AND ` + userTenantScope("user_id", 2)
 	var method string
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&method)
+	err := r.db.QueryRowContext(ctx, query, userID, tenantArg(ctx)).Scan(&method)
 	if err == sql.ErrNoRows {
 		return "", nil // No 2FA method set
 	}
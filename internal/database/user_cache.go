@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	userCacheHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "user_cache_hits_total",
+		Help: "Number of user lookups by ID answered from the in-process cache.",
+	})
+
+	userCacheMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "user_cache_misses_total",
+		Help: "Number of user lookups by ID that went to the database.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(userCacheHits, userCacheMisses)
+}
+
+// cachedUserRepository keeps the users returned by FindByID in an in-process
+// LRU cache with a TTL. Every write through the repository evicts the user it
+// changes; the TTL bounds how long other instances, and writes that bypass
+// this repository, can serve a stale user.
+type cachedUserRepository struct {
+	repository.UserRepository
+	cache *expirable.LRU[int64, models.User]
+}
+
+// NewCachedUserRepository caches up to size users read by FindByID for ttl in
+// front of repo. The cache is safe for concurrent use.
+func NewCachedUserRepository(repo repository.UserRepository, size int, ttl time.Duration) repository.UserRepository {
+	return &cachedUserRepository{
+		UserRepository: repo,
+		cache:          expirable.NewLRU[int64, models.User](size, nil, ttl),
+	}
+}
+
+// FindByID answers from the cache when the cached user belongs to the tenant
+// of ctx, and caches the users it reads otherwise. Callers get their own copy.
+func (r *cachedUserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	if user, ok := r.cache.Get(id); ok && inTenantScope(ctx, user.TenantID) {
+		userCacheHits.Inc()
+		return &user, nil
+	}
+	userCacheMisses.Inc()
+
+	user, err := r.UserRepository.FindByID(ctx, id)
+	if err != nil || user == nil {
+		return user, err
+	}
+	r.cache.Add(id, *user)
+	return user, nil
+}
+
+// inTenantScope reports whether a user of tenantID is visible to the tenant of
+// ctx, mirroring tenantScope: without a tenant every user is visible.
+func inTenantScope(ctx context.Context, tenantID *int64) bool {
+	scope := tenantArg(ctx)
+	return !scope.Valid || (tenantID != nil && *tenantID == scope.Int64)
+}
+
+func (r *cachedUserRepository) LinkProvider(ctx context.Context, userID int64, provider, providerID, avatarURL string) error {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.LinkProvider(ctx, userID, provider, providerID, avatarURL)
+}
+
+func (r *cachedUserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.MarkEmailVerified(ctx, userID)
+}
+
+func (r *cachedUserRepository) UpdatePassword(ctx context.Context, userID int64, hash string) error {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.UpdatePassword(ctx, userID, hash)
+}
+
+func (r *cachedUserRepository) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.ConfirmPendingEmail(ctx, userID, email)
+}
+
+func (r *cachedUserRepository) UpdatePhoneNumber(ctx context.Context, userID int64, phoneNumber string) error {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.UpdatePhoneNumber(ctx, userID, phoneNumber)
+}
+
+func (r *cachedUserRepository) Update(ctx context.Context, user *models.User) error {
+	defer r.cache.Remove(user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *cachedUserRepository) Patch(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error) {
+	defer r.cache.Remove(userID)
+	return r.UserRepository.Patch(ctx, userID, patch)
+}
+
+func (r *cachedUserRepository) Deactivate(ctx context.Context, id int64, reason string) error {
+	defer r.cache.Remove(id)
+	return r.UserRepository.Deactivate(ctx, id, reason)
+}
+
+func (r *cachedUserRepository) Delete(ctx context.Context, id int64) error {
+	defer r.cache.Remove(id)
+	return r.UserRepository.Delete(ctx, id)
+}
@@ -5,33 +5,46 @@ package database
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type userRepository struct {
-	db *sql.DB
+	db *tracedDB
 }
 
 // NewUserRepository creates a new PostgreSQL user repository
-func NewUserRepository(db *sql.DB) repository.UserRepository {
-	return &userRepository{db: db}
+func NewUserRepository(db *sql.DB, tp trace.TracerProvider) repository.UserRepository {
+	return &userRepository{db: newTracedDB(db, tp)}
 }
 
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByEmail")
+	defer span.End()
+
 	query := `
-		SELECT id, first_name, last_name, email, password, is_active, created_at, updated_at 
+		SELECT id, first_name, last_name, email, password, is_active, email_verified_at, tenant_id, COALESCE(phone_number, ''), role, deactivated_at, COALESCE(deactivation_reason, ''), created_at, updated_at 
 		FROM users 
-		WHERE email = $1 AND deleted_at IS NULL`
+		WHERE email = $1 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 2)
 	
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, email, tenantArg(ctx)).Scan(
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
 		&user.Password,
 		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.Role,
+		&user.DeactivatedAt,
+		&user.DeactivationReason,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -47,19 +60,29 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 }
 
 func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByID")
+	defer span.End()
+
 	query := `
-		SELECT id, first_name, last_name, email, password, is_active, created_at, updated_at 
+		SELECT id, first_name, last_name, email, password, is_active, email_verified_at, tenant_id, COALESCE(phone_number, ''), COALESCE(avatar_url, ''), role, deactivated_at, COALESCE(deactivation_reason, ''), created_at, updated_at 
 		FROM users 
-		WHERE id = $1 AND deleted_at IS NULL`
+		WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 2)
 	
 	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(ctx, query, id, tenantArg(ctx)).Scan(
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
 		&user.Email,
 		&user.Password,
 		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.AvatarURL,
+		&user.Role,
+		&user.DeactivatedAt,
+		&user.DeactivationReason,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -74,13 +97,300 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User,
 	return user, nil
 }
 
+func (r *userRepository) FindByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindByProvider")
+	defer span.End()
+
+	query := `
+		SELECT id, first_name, last_name, email, COALESCE(password, ''), is_active, email_verified_at, tenant_id, COALESCE(phone_number, ''), role, deactivated_at, COALESCE(deactivation_reason, ''), created_at, updated_at
+		FROM users
+		WHERE provider = $1 AND provider_id = $2 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+
+	user := &models.User{Provider: provider, ProviderID: providerID}
+	err := r.db.QueryRowContext(ctx, query, provider, providerID, tenantArg(ctx)).Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.Password,
+		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.Role,
+		&user.DeactivatedAt,
+		&user.DeactivationReason,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) LinkProvider(ctx context.Context, userID int64, provider, providerID, avatarURL string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.LinkProvider")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET provider = $1, provider_id = $2, avatar_url = COALESCE(NULLIF($3, ''), avatar_url), updated_at = NOW()
+		WHERE id = $4 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 5)
+
+	_, err := r.db.ExecContext(ctx, query, provider, providerID, avatarURL, userID, tenantArg(ctx))
+	return err
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.MarkEmailVerified")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 2)
+
+	_, err := r.db.ExecContext(ctx, query, userID, tenantArg(ctx))
+	return err
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, userID int64, hash string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.UpdatePassword")
+	defer span.End()
+
+	query := `UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+	_, err := r.db.ExecContext(ctx, query, hash, userID, tenantArg(ctx))
+	return err
+}
+
+func (r *userRepository) SetPendingEmail(ctx context.Context, userID int64, email string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.SetPendingEmail")
+	defer span.End()
+
+	query := `UPDATE users SET pending_email = NULLIF($1, ''), updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+	_, err := r.db.ExecContext(ctx, query, email, userID, tenantArg(ctx))
+	return err
+}
+
+func (r *userRepository) FindPendingEmail(ctx context.Context, userID int64) (string, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindPendingEmail")
+	defer span.End()
+
+	query := `SELECT COALESCE(pending_email, '') FROM users WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 2)
+	var email string
+	err := r.db.QueryRowContext(ctx, query, userID, tenantArg(ctx)).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return email, err
+}
+
+func (r *userRepository) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.ConfirmPendingEmail")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET email = pending_email, pending_email = NULL, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND pending_email = $2 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+
+	result, err := r.db.ExecContext(ctx, query, userID, email, tenantArg(ctx))
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (r *userRepository) UpdatePhoneNumber(ctx context.Context, userID int64, phoneNumber string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.UpdatePhoneNumber")
+	defer span.End()
+
+	query := `UPDATE users SET phone_number = NULLIF($1, ''), updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+	_, err := r.db.ExecContext(ctx, query, phoneNumber, userID, tenantArg(ctx))
+	return err
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter, cursor *repository.Cursor) (*repository.UserPage, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.List")
+	defer span.End()
+
+	where := `WHERE ($1 = '' OR LOWER(email) = LOWER($1))
+		AND ($2 = '' OR email ILIKE '%' || $2 || '%')
+		AND ($3::TIMESTAMPTZ IS NULL OR created_at > $3)
+		AND ($4::BOOLEAN IS NULL OR (email_verified_at IS NOT NULL) = $4)
+		AND ` + tenantScope("tenant_id", 5) + `
+		AND ($6 OR deleted_at IS NULL)`
+	args := []any{
+		filter.Email,
+		escapeLike(filter.EmailLike),
+		nullTimePtr(filter.CreatedAfter),
+		nullBool(filter.IsVerified),
+		tenantArg(ctx),
+		filter.IncludeDeleted,
+	}
+
+	page := &repository.UserPage{Users: []models.User{}}
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, args...).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+
+	var after sql.NullTime
+	var afterID int64
+	if cursor != nil {
+		after = sql.NullTime{Time: cursor.CreatedAt, Valid: true}
+		afterID = cursor.ID
+	}
+
+	// One extra row tells whether another page follows
+	query := `
+		SELECT id, first_name, last_name, email, is_active, email_verified_at, tenant_id, role,
+			deactivated_at, COALESCE(deactivation_reason, ''), created_at, updated_at, deleted_at
+		FROM users ` + where + `
+		AND ($7::TIMESTAMPTZ IS NULL OR (created_at, id) > ($7, $8))
+		ORDER BY created_at, id
+		LIMIT $9 OFFSET $10`
+
+	rows, err := r.db.QueryContext(ctx, query, append(args, after, afterID, filter.Limit+1, filter.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.Email,
+			&user.IsActive,
+			&user.EmailVerifiedAt,
+			&user.TenantID,
+			&user.Role,
+			&user.DeactivatedAt,
+			&user.DeactivationReason,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Users) > filter.Limit {
+		page.Users = page.Users[:filter.Limit]
+		if filter.Limit > 0 {
+			last := page.Users[len(page.Users)-1]
+			page.NextCursor = &repository.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+	}
+	return page, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nullTimePtr converts an optional time to a nullable query argument.
+func nullTimePtr(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+// nullStringPtr converts an optional string to a nullable query argument.
+func nullStringPtr(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+// nullBool converts an optional bool to a nullable query argument.
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
+
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Create")
+	defer span.End()
+
+	return insertUser(ctx, r.db, user)
+}
+
+func (r *userRepository) FindOrCreate(ctx context.Context, user *models.User) (*models.User, bool, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.FindOrCreate")
+	defer span.End()
+
+	existing, err := r.FindByProvider(ctx, user.Provider, user.ProviderID)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+	if err := insertUser(ctx, r.db, user); err != nil {
+		// A concurrent request may have created the user first, failing this
+		// insert on the unique email
+		existing, findErr := r.FindByProvider(ctx, user.Provider, user.ProviderID)
+		if findErr == nil && existing != nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+func (r *userRepository) CreateBatch(ctx context.Context, users []*models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.CreateBatch")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, user := range users {
+		if err := insertUser(ctx, tx, user); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// rowQuerier is implemented by both tracedDB and tracedTx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertUser inserts a user and sets its ID. New users join the tenant the
+// request is scoped to.
+func insertUser(ctx context.Context, q rowQuerier, user *models.User) error {
 	query := `
-		INSERT INTO users (first_name, last_name, email, password, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (first_name, last_name, email, password, is_active, created_at, updated_at, provider, provider_id, avatar_url, email_verified_at, tenant_id, role)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), 'email'), NULLIF($9, ''), NULLIF($10, ''), $11, $12, COALESCE(NULLIF($13, ''), 'user'))
 		RETURNING id`
-	
-	err := r.db.QueryRowContext(ctx, query,
+
+	tenant := tenantArg(ctx)
+	if tenant.Valid {
+		user.TenantID = &tenant.Int64
+	}
+
+	return q.QueryRowContext(ctx, query,
 		user.FirstName,
 		user.LastName,
 		user.Email,
@@ -88,16 +398,25 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		user.IsActive,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.Provider,
+		user.ProviderID,
+		user.AvatarURL,
+		user.EmailVerifiedAt,
+		tenant,
+		string(user.Role),
 	).Scan(&user.ID)
-	
-	return err
 }
 
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Update")
+	defer span.End()
+
 	query := `
 		UPDATE users 
-		SET first_name = $1, last_name = $2, email = $3, is_active = $4, updated_at = $5
-		WHERE id = $6`
+		SET first_name = $1, last_name = $2, email = $3, is_active = $4, updated_at = $5,
+			deactivated_at = CASE WHEN $4 THEN NULL ELSE deactivated_at END,
+			deactivation_reason = CASE WHEN $4 THEN NULL ELSE deactivation_reason END
+		WHERE id = $6 AND ` + tenantScope("tenant_id", 7)
 	
 	_, err := r.db.ExecContext(ctx, query,
 		user.FirstName,
@@ -106,13 +425,118 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 		user.IsActive,
 		user.UpdatedAt,
 		user.ID,
+		tenantArg(ctx),
 	)
 	
 	return err
 }
 
+// Patch updates only the fields set in patch: COALESCE keeps the current value
+// for NULL parameters, and NULLIF stores cleared avatar URLs and phone numbers as NULL.
+func (r *userRepository) Patch(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Patch")
+	defer span.End()
+
+	query := `
+		UPDATE users SET
+			first_name = COALESCE($1, first_name),
+			last_name = COALESCE($2, last_name),
+			avatar_url = NULLIF(COALESCE($3, avatar_url), ''),
+			phone_number = NULLIF(COALESCE($4, phone_number), ''),
+			updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 6) + `
+		RETURNING id, first_name, last_name, email, is_active, email_verified_at, tenant_id, COALESCE(phone_number, ''), COALESCE(avatar_url, ''), role, created_at, updated_at`
+
+	user := &models.User{}
+	err := r.db.QueryRowContext(ctx, query,
+		nullStringPtr(patch.FirstName),
+		nullStringPtr(patch.LastName),
+		nullStringPtr(patch.AvatarURL),
+		nullStringPtr(patch.PhoneNumber),
+		userID,
+		tenantArg(ctx),
+	).Scan(
+		&user.ID,
+		&user.FirstName,
+		&user.LastName,
+		&user.Email,
+		&user.IsActive,
+		&user.EmailVerifiedAt,
+		&user.TenantID,
+		&user.PhoneNumber,
+		&user.AvatarURL,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) Deactivate(ctx context.Context, id int64, reason string) error {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Deactivate")
+	defer span.End()
+
+	query := `
+		UPDATE users
+		SET is_active = FALSE, deactivated_at = NOW(), deactivation_reason = NULLIF($2, ''), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ` + tenantScope("tenant_id", 3)
+	_, err := r.db.ExecContext(ctx, query, id, reason, tenantArg(ctx))
+	return err
+}
+
 func (r *userRepository) Delete(ctx context.Context, id int64) error {
-	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	ctx, span := r.db.startSpan(ctx, "UserRepository.Delete")
+	defer span.End()
+
+	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND ` + tenantScope("tenant_id", 2)
+	_, err := r.db.ExecContext(ctx, query, id, tenantArg(ctx))
 	return err
-}
\ No newline at end of file
+}
+
+func (r *userRepository) ReplaceExternalGroups(ctx context.Context, userID int64, groups []string) ([]string, error) {
+	ctx, span := r.db.startSpan(ctx, "UserRepository.ReplaceExternalGroups")
+	defer span.End()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`DELETE FROM user_external_groups WHERE user_id = $1 AND `+userTenantScope("user_id", 2)+` RETURNING group_name`,
+		userID, tenantArg(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var previous []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous = append(previous, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range groups {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO user_external_groups (user_id, group_name) VALUES ($1, $2) ON CONFLICT (user_id, group_name) DO NOTHING`,
+			userID, name,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return previous, tx.Commit()
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type webAuthnRepository struct {
+	db *tracedDB
+}
+
+// NewWebAuthnRepository creates a new PostgreSQL WebAuthn credential repository
+func NewWebAuthnRepository(db *sql.DB, tp trace.TracerProvider) repository.WebAuthnRepository {
+	return &webAuthnRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *webAuthnRepository) Create(ctx context.Context, credential *models.WebAuthnCredential) error {
+	ctx, span := r.db.startSpan(ctx, "WebAuthnRepository.Create")
+	defer span.End()
+
+	record, err := json.Marshal(credential.Credential)
+	if err != nil {
+		return err
+	}
+
+	if credential.Kind == "" {
+		credential.Kind = constants.WebAuthnPasskey
+	}
+
+	query := `
+		INSERT INTO webauthn_credentials (user_id, credential_id, credential, kind, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		credential.UserID,
+		credential.Credential.ID,
+		record,
+		credential.Kind,
+		time.Now(),
+	).Scan(&credential.ID, &credential.CreatedAt)
+}
+
+func (r *webAuthnRepository) FindByUserID(ctx context.Context, userID int64) ([]models.WebAuthnCredential, error) {
+	ctx, span := r.db.startSpan(ctx, "WebAuthnRepository.FindByUserID")
+	defer span.End()
+
+	query := `
+		SELECT id, user_id, credential, kind, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1 AND ` + userTenantScope("user_id", 2) + `
+		ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, tenantArg(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var credentials []models.WebAuthnCredential
+	for rows.Next() {
+		var c models.WebAuthnCredential
+		var record []byte
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.UserID, &record, &c.Kind, &c.CreatedAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(record, &c.Credential); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			c.LastUsedAt = &lastUsedAt.Time
+		}
+		credentials = append(credentials, c)
+	}
+	return credentials, rows.Err()
+}
+
+func (r *webAuthnRepository) UpdateAfterLogin(ctx context.Context, credential *models.WebAuthnCredential) error {
+	ctx, span := r.db.startSpan(ctx, "WebAuthnRepository.UpdateAfterLogin")
+	defer span.End()
+
+	record, err := json.Marshal(credential.Credential)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `UPDATE webauthn_credentials SET credential = $2, last_used_at = $3 WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, credential.ID, record, now); err != nil {
+		return err
+	}
+
+	credential.LastUsedAt = &now
+	return nil
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type webhookRepository struct {
+	db *tracedDB
+}
+
+// NewWebhookRepository creates a new PostgreSQL webhook repository
+func NewWebhookRepository(db *sql.DB, tp trace.TracerProvider) repository.WebhookRepository {
+	return &webhookRepository{db: newTracedDB(db, tp)}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	ctx, span := r.db.startSpan(ctx, "WebhookRepository.Create")
+	defer span.End()
+
+	events := webhook.Events
+	if events == nil {
+		events = []string{}
+	}
+	eventsJSON, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO webhooks (tenant_id, url, secret, events, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	return r.db.QueryRowContext(ctx, query,
+		webhook.TenantID,
+		webhook.URL,
+		webhook.Secret,
+		eventsJSON,
+		webhook.Active,
+	).Scan(&webhook.ID, &webhook.CreatedAt)
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := r.db.startSpan(ctx, "WebhookRepository.List")
+	defer span.End()
+
+	query := `
+		SELECT id, tenant_id, url, '', events, active, created_at
+		FROM webhooks
+		ORDER BY created_at, id`
+	return r.query(ctx, query)
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id int64) error {
+	ctx, span := r.db.startSpan(ctx, "WebhookRepository.Delete")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) ListForEvent(ctx context.Context, tenantID *int64, eventType string) ([]models.Webhook, error) {
+	ctx, span := r.db.startSpan(ctx, "WebhookRepository.ListForEvent")
+	defer span.End()
+
+	query := `
+		SELECT id, tenant_id, url, secret, events, active, created_at
+		FROM webhooks
+		WHERE active AND tenant_id IS NOT DISTINCT FROM $1
+		AND (events = '[]'::jsonb OR events @> jsonb_build_array($2::text))
+		ORDER BY id`
+	return r.query(ctx, query, tenantID, eventType)
+}
+
+func (r *webhookRepository) query(ctx context.Context, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		var events []byte
+		if err := rows.Scan(&w.ID, &w.TenantID, &w.URL, &w.Secret, &events, &w.Active, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(events, &w.Events); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
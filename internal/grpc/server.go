@@ -0,0 +1,298 @@
+// Package grpc serves the authentication API over gRPC, next to the HTTP API.
+// Its handlers delegate to service.AuthService exactly like the Gin handlers do.
+package grpc
+
+//go:generate protoc --proto_path=../../proto --go_out=../../proto --go_opt=paths=source_relative --go-grpc_out=../../proto --go-grpc_opt=paths=source_relative auth/v1/auth.proto
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"authentio/internal/handler"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/pkg/logger"
+	"authentio/pkg/password"
+	"authentio/pkg/response"
+	authv1 "authentio/proto/auth/v1"
+
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// =============================================================================
+// Server Construction
+// =============================================================================
+
+// tenantMetadataKey selects the tenant of a call by ID or slug, like the
+// X-Tenant-ID header of the HTTP API
+const tenantMetadataKey = "x-tenant-id"
+
+// authorizationMetadataKey carries the caller's access token, like the
+// Authorization header of the HTTP API
+const authorizationMetadataKey = "authorization"
+
+// NewServer creates a gRPC server exposing AuthServer, with server reflection
+// enabled for tools such as grpcurl. A nil tenants repository serves every call
+// as the default, single-tenant installation. opts are passed to grpc.NewServer
+// (e.g. transport credentials).
+func NewServer(authService *service.AuthService, tenants repository.TenantRepository, opts ...gogrpc.ServerOption) *gogrpc.Server {
+	opts = append(opts, gogrpc.ChainUnaryInterceptor(
+		clientInfoInterceptor,
+		tenantInterceptor(tenants),
+	))
+	server := gogrpc.NewServer(opts...)
+	authv1.RegisterAuthServiceServer(server, NewAuthServer(authService))
+	reflection.Register(server)
+	return server
+}
+
+// =============================================================================
+// AuthServer Structure and Constructor
+// =============================================================================
+
+// AuthServer implements authv1.AuthServiceServer on top of service.AuthService
+type AuthServer struct {
+	authv1.UnimplementedAuthServiceServer
+
+	authService *service.AuthService
+}
+
+// NewAuthServer creates a new AuthServer instance
+func NewAuthServer(authService *service.AuthService) *AuthServer {
+	return &AuthServer{authService: authService}
+}
+
+// =============================================================================
+// Authentication RPCs
+// =============================================================================
+
+// Register creates a new account, validated like POST /auth/register.
+func (s *AuthServer) Register(ctx context.Context, req *authv1.RegisterRequest) (*authv1.RegisterResponse, error) {
+	registerReq := models.RegisterRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+		Password:  req.GetPassword(),
+	}
+	if err := handler.Validate.Struct(&registerReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, validationMessage(err))
+	}
+
+	resp, err := s.authService.Register(ctx, registerReq)
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			return nil, status.Error(codes.InvalidArgument, policyErr.Error())
+		}
+		if errors.Is(err, service.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, service.ErrLockConflict) {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &authv1.RegisterResponse{
+		User:    toUser(resp.User),
+		Message: resp.Message,
+	}, nil
+}
+
+// Login exchanges an email and password for a token pair.
+func (s *AuthServer) Login(ctx context.Context, req *authv1.LoginRequest) (*authv1.LoginResponse, error) {
+	loginReq := models.LoginRequest{
+		Email:    req.GetEmail(),
+		Password: req.GetPassword(),
+	}
+	if err := handler.Validate.Struct(&loginReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, validationMessage(err))
+	}
+
+	resp, err := s.authService.Login(ctx, loginReq)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmailNotVerified), errors.Is(err, service.ErrAccountDisabled),
+			errors.Is(err, service.ErrAccountDeactivated):
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		case errors.Is(err, service.ErrAccountLocked):
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		default:
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	}
+	// Second factors are only finished over HTTP: the security key ceremony
+	// needs a browser, and LoginResponse has no two-factor token field
+	if resp.TwoFactorRequired {
+		return nil, status.Error(codes.FailedPrecondition, "second factor required: sign in over the HTTP API")
+	}
+
+	return &authv1.LoginResponse{
+		User:         toUser(resp.User),
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    int32(resp.ExpiresIn),
+	}, nil
+}
+
+// Logout revokes a refresh token, and the access token sent as "authorization:
+// Bearer <token>" metadata, if any.
+func (s *AuthServer) Logout(ctx context.Context, req *authv1.LogoutRequest) (*authv1.LogoutResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	if err := s.authService.Logout(ctx, req.GetRefreshToken(), bearerToken(ctx)); err != nil {
+		logger.Warn("gRPC logout failed", "error", err)
+		return nil, status.Error(codes.InvalidArgument, "invalid refresh token")
+	}
+	return &authv1.LogoutResponse{}, nil
+}
+
+// RefreshToken rotates a refresh token and issues a new access token.
+func (s *AuthServer) RefreshToken(ctx context.Context, req *authv1.RefreshTokenRequest) (*authv1.RefreshTokenResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+
+	resp, err := s.authService.RefreshToken(ctx, req.GetRefreshToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return &authv1.RefreshTokenResponse{
+		User:         toUser(resp.User),
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    int32(resp.ExpiresIn),
+	}, nil
+}
+
+// VerifyOTP checks a 2FA code sent to the user.
+func (s *AuthServer) VerifyOTP(ctx context.Context, req *authv1.VerifyOTPRequest) (*authv1.VerifyOTPResponse, error) {
+	if req.GetEmail() == "" || req.GetCode() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email and code are required")
+	}
+
+	if err := s.authService.Verify2FA(ctx, req.GetEmail(), req.GetCode()); err != nil {
+		var lockedErr *service.ErrOTPLocked
+		if errors.As(err, &lockedErr) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &authv1.VerifyOTPResponse{Message: "2FA verification successful"}, nil
+}
+
+// =============================================================================
+// Interceptors
+// =============================================================================
+
+// clientInfoInterceptor records the caller's user agent and address on the
+// context, like middleware.ClientInfoMiddleware does for HTTP requests.
+func clientInfoInterceptor(ctx context.Context, req any, _ *gogrpc.UnaryServerInfo, next gogrpc.UnaryHandler) (any, error) {
+	var info models.ClientInfo
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			info.UserAgent = ua[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(info.IP); err == nil {
+			info.IP = host
+		}
+	}
+	return next(models.WithClientInfo(ctx, info), req)
+}
+
+// tenantInterceptor scopes a call to the tenant named by its x-tenant-id metadata
+// (numeric ID or slug). Calls without it are served as the default installation.
+func tenantInterceptor(tenants repository.TenantRepository) gogrpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *gogrpc.UnaryServerInfo, next gogrpc.UnaryHandler) (any, error) {
+		if tenants == nil {
+			return next(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(tenantMetadataKey)
+		if len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			return next(ctx, req)
+		}
+		ref := strings.TrimSpace(values[0])
+
+		var tenant *models.Tenant
+		var err error
+		if id, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+			tenant, err = tenants.FindByID(ctx, id)
+		} else {
+			tenant, err = tenants.FindBySlug(ctx, strings.ToLower(ref))
+		}
+		if err != nil {
+			logger.Error("failed to resolve tenant", "tenant", ref, "error", err)
+			return nil, status.Error(codes.Internal, "failed to resolve tenant")
+		}
+		if tenant == nil {
+			return nil, status.Error(codes.NotFound, "unknown tenant")
+		}
+		return next(repository.WithTenantID(ctx, tenant.ID), req)
+	}
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// bearerToken returns the token of the call's "authorization: Bearer <token>"
+// metadata, or "" without one.
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// toUser converts a REST user response to its protobuf message.
+func toUser(u response.UserResponse) *authv1.User {
+	user := &authv1.User{
+		Id:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		IsActive:  u.IsActive,
+	}
+	if !u.CreatedAt.IsZero() {
+		user.CreatedAt = timestamppb.New(u.CreatedAt)
+	}
+	return user
+}
+
+// validationMessage flattens validator errors into a single status message.
+func validationMessage(err error) string {
+	var parts []string
+	for field, msg := range handler.FormatValidationError(err) {
+		parts = append(parts, field+": "+msg)
+	}
+	if len(parts) == 0 {
+		return err.Error()
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
+}
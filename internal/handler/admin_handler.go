@@ -0,0 +1,590 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// AdminHandler Structure and Constructor
+// =============================================================================
+
+// AdminHandler handles operator-only HTTP requests
+type AdminHandler struct {
+	authService service.AuthService
+}
+
+// NewAdminHandler creates a new AdminHandler instance
+func NewAdminHandler(authService service.AuthService) *AdminHandler {
+	return &AdminHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// User Administration Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// UnlockUser godoc
+// @Summary Unlock a user account
+// @Description Clear the failed-login counter so a locked-out user can sign in again before the lockout window expires
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string "Account unlocked"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.authService.UnlockAccount(c.Request.Context(), userID); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account unlocked"})
+}
+
+// RevokeUserTokens godoc
+// @Summary Revoke all tokens of a user
+// @Description Sign a user out everywhere at once: access tokens issued until now are rejected before they expire, and every refresh token and OAuth refresh grant of the user stops working
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string "Tokens revoked"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 503 {object} ErrorResponse "Token revocation not configured"
+// @Router /admin/users/{id}/revoke-tokens [post]
+func (h *AdminHandler) RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.authService.RevokeAllUserTokens(c.Request.Context(), userID); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "tokens revoked"})
+}
+
+// RevokeAllTokens godoc
+// @Summary Revoke every token
+// @Description Sign every user of every tenant out after a security incident: access tokens issued until now are rejected, and all refresh tokens and OAuth refresh grants stop working. Users and clients have to authenticate again.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "All tokens revoked"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 503 {object} ErrorResponse "Token revocation not configured"
+// @Router /admin/revoke-all [post]
+func (h *AdminHandler) RevokeAllTokens(c *gin.Context) {
+	if err := h.authService.RevokeAllTokens(c.Request.Context()); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "all tokens revoked"})
+}
+
+const (
+	// userListDefaultLimit is the page size when no limit is given
+	userListDefaultLimit = 50
+
+	// userListMaxLimit caps the page size of a user listing
+	userListMaxLimit = 200
+)
+
+// UserListPage is one page of users
+type UserListPage struct {
+	Users []models.User `json:"users"`
+
+	// NextCursor is passed as after_cursor to fetch the next page; omitted on the last page
+	NextCursor string `json:"next_cursor,omitempty" example:"eyJpZCI6NDJ9"`
+
+	Total int `json:"total" example:"120"`
+	Limit int `json:"limit" example:"20"`
+}
+
+// ListUsers godoc
+// @Summary List users
+// @Description List users ordered by creation time, with keyset (cursor) pagination that stays stable while users sign up
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "Only users whose email contains this text (case-insensitive)"
+// @Param created_after query string false "Only users created after this time (RFC 3339)"
+// @Param verified query bool false "Only users with (true) or without (false) a verified email"
+// @Param include_deleted query bool false "Also list soft-deleted users"
+// @Param after_cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Maximum number of users (max 200)" default(50)
+// @Success 200 {object} UserListPage "Users"
+// @Failure 400 {object} map[string]string "Invalid filter or cursor"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users [get]
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	filter := repository.UserFilter{
+		EmailLike: c.Query("email"),
+		Limit:     userListDefaultLimit,
+	}
+
+	if raw := c.Query("created_after"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid created_after: expected RFC 3339 time"})
+			return
+		}
+		filter.CreatedAfter = &t
+	}
+	if raw := c.Query("verified"); raw != "" {
+		verified, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid verified: expected true or false"})
+			return
+		}
+		filter.IsVerified = &verified
+	}
+	if raw := c.Query("include_deleted"); raw != "" {
+		includeDeleted, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid include_deleted: expected true or false"})
+			return
+		}
+		filter.IncludeDeleted = includeDeleted
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = min(limit, userListMaxLimit)
+	}
+
+	var cursor *repository.Cursor
+	if raw := c.Query("after_cursor"); raw != "" {
+		var err error
+		if cursor, err = repository.DecodeCursor(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	page, err := h.authService.ListUsers(c.Request.Context(), filter, cursor)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	resp := UserListPage{Users: page.Users, Total: page.Total, Limit: filter.Limit}
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeactivateUser godoc
+// @Summary Deactivate a user account
+// @Description Disable an account without deleting it. The user is signed out everywhere and cannot sign in until reactivated; the reason is kept with the account and in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body DeactivateUserRequest false "Deactivation reason"
+// @Success 200 {object} map[string]string "Account deactivated"
+// @Failure 400 {object} map[string]string "Invalid user ID or body"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users/{id}/deactivate [post]
+func (h *AdminHandler) DeactivateUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req DeactivateUserRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.authService.DeactivateUser(c.Request.Context(), userID, req.Reason); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account deactivated"})
+}
+
+// DeleteUser godoc
+// @Summary Delete a user account
+// @Description Soft-delete an account and sign it out everywhere; its access tokens are rejected at once. The row and its audit history are kept; deleted users are hidden from lookups and only listed with include_deleted=true.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 204 "Account deleted"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users/{id} [delete]
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.authService.DeleteUser(c.Request.Context(), userID); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// =============================================================================
+// IP Blocklist Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// BlockIP godoc
+// @Summary Block an IP address
+// @Description Add an IP address to the blocklist, permanently or for ttl_seconds. Blocked addresses receive 403 on every /api/v1 route while IP_FILTER_MODE=blocklist.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BlockIPRequest true "Address and optional lifetime"
+// @Success 200 {object} map[string]string "IP address blocked"
+// @Failure 400 {object} map[string]string "Invalid IP address or TTL"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "IP filtering is not enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ip-blocklist [post]
+func (h *AdminHandler) BlockIP(c *gin.Context) {
+	var req BlockIPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if err := h.authService.AddToBlocklist(c.Request.Context(), req.IP, ttl); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "IP address blocked"})
+}
+
+// UnblockIP godoc
+// @Summary Unblock an IP address
+// @Description Remove an IP address from the blocklist, including temporary blocks
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param ip path string true "Blocked IP address"
+// @Success 204 "IP address unblocked"
+// @Failure 400 {object} map[string]string "Invalid IP address"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "IP filtering is not enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/ip-blocklist/{ip} [delete]
+func (h *AdminHandler) UnblockIP(c *gin.Context) {
+	if err := h.authService.RemoveFromBlocklist(c.Request.Context(), c.Param("ip")); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// =============================================================================
+// Impersonation Endpoints (Protected - Require Admin Role)
+// =============================================================================
+
+// ImpersonateUser godoc
+// @Summary Impersonate a user
+// @Description Issue a short-lived access token for another user, for support and debugging. The token carries an impersonated_by claim, has no refresh token and is recorded in the audit log. Requires an access token with role=admin.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID of the user to impersonate"
+// @Success 200 {object} models.TokenPair "Impersonation token (refresh_token is empty)"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Not an admin, or the user cannot be impersonated"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
+	adminID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	targetID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	tokens, err := h.authService.ImpersonateUser(c.Request.Context(), adminID.(int64), targetID)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// EndImpersonation godoc
+// @Summary End an impersonation
+// @Description Revoke the impersonation token the request is made with and close its session, before the token expires
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string "Impersonation ended"
+// @Failure 400 {object} map[string]string "Not an impersonation token"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/impersonation/end [post]
+func (h *AdminHandler) EndImpersonation(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	err := h.authService.EndImpersonation(c.Request.Context(), userID.(int64), c.GetInt64("impersonatedBy"), c.GetString("jti"), c.GetString("sessionID"))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		c.JSON(http.StatusOK, gin.H{"message": "impersonation already ended"})
+		return
+	}
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "impersonation ended"})
+}
+
+// =============================================================================
+// Audit Log Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+const (
+	// auditLogDefaultLimit is the page size when no limit is given
+	auditLogDefaultLimit = 50
+
+	// auditLogMaxLimit caps the page size of an audit log query
+	auditLogMaxLimit = 200
+)
+
+// AuditLogPage is one page of audit log entries
+type AuditLogPage struct {
+	Entries []models.AuditEntry `json:"entries"`
+	Total   int                 `json:"total" example:"120"`
+	Offset  int                 `json:"offset" example:"0"`
+	Limit   int                 `json:"limit" example:"20"`
+}
+
+// ListAuditLogs godoc
+// @Summary Query the audit log
+// @Description List audit log entries, newest first, filtered by user, event type and time range
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "Only entries of this user"
+// @Param event_type query string false "Only entries of this event type (e.g. login, login_failed, password_changed)"
+// @Param from query string false "Only entries at or after this time (RFC 3339)"
+// @Param to query string false "Only entries before this time (RFC 3339)"
+// @Param offset query int false "Number of entries to skip" default(0)
+// @Param limit query int false "Maximum number of entries (max 200)" default(50)
+// @Success 200 {object} AuditLogPage "Audit log entries"
+// @Failure 400 {object} map[string]string "Invalid filter"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/audit-logs [get]
+func (h *AdminHandler) ListAuditLogs(c *gin.Context) {
+	var filter repository.AuditFilter
+	filter.EventType = c.Query("event_type")
+
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	for _, bound := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if raw := c.Query(bound.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + bound.name + ": expected RFC 3339 time"})
+				return
+			}
+			*bound.dst = t
+		}
+	}
+
+	page := repository.Page{Limit: auditLogDefaultLimit}
+	if raw := c.Query("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+		page.Offset = offset
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		page.Limit = min(limit, auditLogMaxLimit)
+	}
+
+	entries, total, err := h.authService.QueryAuditLogs(c.Request.Context(), filter, page)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, AuditLogPage{
+		Entries: entries,
+		Total:   total,
+		Offset:  page.Offset,
+		Limit:   page.Limit,
+	})
+}
+
+// =============================================================================
+// Feature Flag Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// SetUserFlag godoc
+// @Summary Set a user's feature flag
+// @Description Enable or disable a feature flag for a user, permanently or for ttl_seconds. Enabled flags are listed in the feature_flags claim of access tokens issued afterwards.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param flag path string true "Flag name (1-64 lowercase letters, digits, '.', '_' or '-')"
+// @Param request body SetFlagRequest true "Flag state and optional lifetime"
+// @Success 200 {object} map[string]string "Feature flag set"
+// @Failure 400 {object} map[string]string "Invalid user ID, flag name or body"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found, or feature flags are not enabled"
+// @Router /admin/users/{id}/flags/{flag} [put]
+func (h *AdminHandler) SetUserFlag(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req SetFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if err := h.authService.SetUserFlag(c.Request.Context(), userID, c.Param("flag"), *req.Enabled, ttl); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "feature flag set"})
+}
+
+// ListUserFlags godoc
+// @Summary List a user's feature flags
+// @Description List every feature flag set for a user, enabled or not. Expired flags are omitted.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "Flags by name, e.g. {\"flags\":{\"beta\":true}}"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found, or feature flags are not enabled"
+// @Router /admin/users/{id}/flags [get]
+func (h *AdminHandler) ListUserFlags(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	userFlags, err := h.authService.ListUserFlags(c.Request.Context(), userID)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flags": userFlags})
+}
+
+// ListUserSessions godoc
+// @Summary List a user's sessions
+// @Description List every active session (logged-in device) of a user, most recently used first, with IP, User-Agent and the operating system, browser and device type parsed from it. Revoke them all with /admin/users/{id}/revoke-tokens.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} SessionsResponse "Active sessions"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /admin/users/{id}/sessions [get]
+func (h *AdminHandler) ListUserSessions(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	sessions, err := h.authService.ListUserSessions(c.Request.Context(), userID)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SessionsResponse{Sessions: sessions})
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"net/http"
-	
+	"strings"
+
 	"authentio/internal/config"
 	"authentio/internal/models"
 	"authentio/internal/service"
@@ -37,7 +41,9 @@ func NewAuthHandler(authService service.AuthService) *AuthHandler {
 // @Produce json
 // @Param request body RefreshTokenRequest true "Refresh token request"
 // @Success 200 {object} response.LoginResponse "New tokens generated successfully"
-// @Failure 400 {object} map[string]string "Invalid or expired refresh token"
+// @Failure 400 {object} map[string]string "Missing refresh token"
+// @Failure 401 {object} ErrorResponse "Invalid, expired or reused refresh token"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
 // @Router /auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var req struct {
@@ -50,25 +56,151 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 
 	result, err := h.authService.RefreshToken(c.Request.Context(), req.RefreshToken)
 	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Log out the current device: the refresh token stops working, and the access token sent in the Authorization header, if any, is revoked right away instead of when it expires.
+// @Description The access token is optional, so a client whose access token already expired can still end its session.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body RefreshTokenRequest true "Refresh token of the session to end"
+// @Success 200 {object} map[string]string "Logged out"
+// @Failure 400 {object} map[string]string "Missing refresh token"
+// @Failure 401 {object} ErrorResponse "Invalid refresh token"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+
+	// Bearer and DPoP-bound tokens alike; the service ignores invalid ones
+	_, accessToken, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken, strings.TrimSpace(accessToken)); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// Introspect godoc
+// @Summary Introspect a token
+// @Description Report whether an access or refresh token is active and who it belongs to (RFC 7662).
+// @Description Authenticated with the INTROSPECTION_SECRET bearer token, not a user token. Unknown, expired
+// @Description and revoked tokens return 200 with {"active": false}.
+// @Tags authentication
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Security BearerAuth
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} service.TokenIntrospection "Token status"
+// @Failure 400 {object} map[string]string "Missing token"
+// @Failure 401 {object} map[string]string "Invalid or missing introspection secret"
+// @Failure 404 {object} map[string]string "Token introspection is not enabled"
+// @Router /auth/introspect [post]
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	token := c.PostForm("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "token is required"})
+		return
+	}
+
+	result, err := h.authService.IntrospectToken(c.Request.Context(), token, c.PostForm("token_type_hint"))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
 	c.JSON(http.StatusOK, result)
 }
 
+// =============================================================================
+// Session Management Endpoints
+// =============================================================================
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the authenticated user's logged-in devices. The session the request was made with is flagged as current.
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Active sessions"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	currentID := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = currentID != "" && sessions[i].ID == currentID
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Log out a single device. Its refresh token stops working immediately; its access token is rejected when session validation is enabled.
+// @Tags authentication
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]string "Session revoked"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Session not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := h.authService.RevokeSession(c.Request.Context(), userID.(int64), c.Param("id")); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
 // =============================================================================
 // Password Reset Flow Endpoints
 // =============================================================================
 
 // ForgotPassword godoc
 // @Summary Request password reset
-// @Description Send a password reset code to the user's email address
+// @Description Email the user a signed, single-use password reset link
 // @Tags authentication
 // @Accept json
 // @Produce json
 // @Param request body ForgotPasswordRequest true "Password reset request"
+// @Param Idempotency-Key header string false "Client-chosen key; retries with the same key replay the first response"
 // @Success 200 {object} map[string]string "Password reset email sent successfully"
 // @Failure 400 {object} map[string]string "Invalid email format"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
 // @Router /auth/forgot-password [post]
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req struct {
@@ -79,7 +211,7 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 		return
 	}
 	if err := h.authService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset email sent"})
@@ -87,26 +219,37 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 
 // ResetPassword godoc
 // @Summary Reset user password
-// @Description Reset user password using verification code received via email
+// @Description Reset user password with the token of an emailed reset link, or with the email and verification code
 // @Tags authentication
 // @Accept json
 // @Produce json
 // @Param request body ResetPasswordRequest true "Password reset confirmation"
+// @Param Idempotency-Key header string false "Client-chosen key; retries with the same key replay the first response"
 // @Success 200 {object} map[string]string "Password reset successful"
-// @Failure 400 {object} map[string]string "Invalid code, email, or password requirements not met"
+// @Failure 400 {object} map[string]string "Invalid or used reset link, invalid code or email, or password requirements not met"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Failure 429 {object} map[string]string "Code locked after too many incorrect attempts (see Retry-After)"
 // @Router /auth/reset-password [post]
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req struct {
-		Email       string `json:"email" binding:"required,email"`
-		Code        string `json:"code" binding:"required"`
+		Token       string `json:"token"`
+		Email       string `json:"email" binding:"required_without=Token,omitempty,email"`
+		Code        string `json:"code" binding:"required_without=Token"`
 		NewPassword string `json:"new_password" binding:"required,min=8"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if err := h.authService.ResetPassword(c.Request.Context(), req.Email, req.Code, req.NewPassword); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+
+	var err error
+	if req.Token != "" {
+		err = h.authService.RedeemPasswordReset(c.Request.Context(), req.Token, req.NewPassword)
+	} else {
+		err = h.authService.ResetPassword(c.Request.Context(), req.Email, req.Code, req.NewPassword)
+	}
+	if err != nil {
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Password reset successful"})
@@ -125,6 +268,8 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 // @Param request body Verify2FARequest true "2FA verification request"
 // @Success 200 {object} map[string]string "2FA verification successful"
 // @Failure 400 {object} map[string]string "Invalid or expired 2FA code"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Failure 429 {object} map[string]string "Code locked after too many incorrect attempts (see Retry-After)"
 // @Router /auth/2fa/verify [post]
 func (h *AuthHandler) Verify2FA(c *gin.Context) {
 	var req struct {
@@ -136,7 +281,7 @@ func (h *AuthHandler) Verify2FA(c *gin.Context) {
 		return
 	}
 	if err := h.authService.Verify2FA(c.Request.Context(), req.Email, req.Code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "2FA verification successful"})
@@ -153,9 +298,12 @@ func (h *AuthHandler) Verify2FA(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body models.RegisterRequest true "User registration data"
+// @Param Idempotency-Key header string false "Client-chosen key; retries with the same key replay the first response"
 // @Success 201 {object} response.RegisterResponse "User registered successfully"
 // @Failure 400 {object} map[string]string "Invalid input data or validation failed"
+// @Failure 403 {object} ErrorResponse "Email domain is not allowed to register"
 // @Failure 409 {object} map[string]string "Email already exists"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
@@ -172,7 +320,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	resp, err := h.authService.Register(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, resp)
@@ -180,14 +328,18 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user with email and password, returns JWT tokens
+// @Description Authenticate user with email and password, returns JWT tokens.
+// @Description With remember_me the refresh token is valid for REMEMBER_ME_TTL instead of REFRESH_TOKEN_TTL.
 // @Tags authentication
 // @Accept json
 // @Produce json
 // @Param request body models.LoginRequest true "User login credentials"
-// @Success 200 {object} response.LoginResponse "Login successful with JWT tokens"
+// @Success 200 {object} response.LoginResponse "Login successful with JWT tokens, or two_factor_required with a security_key_challenge"
 // @Failure 400 {object} map[string]string "Invalid input data"
 // @Failure 401 {object} map[string]string "Invalid email or password"
+// @Failure 403 {object} map[string]string "Email address not verified (a new verification link is sent) or account disabled"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Failure 423 {object} map[string]string "Account locked after too many failed attempts"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -202,12 +354,94 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	resp, err := h.authService.Login(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
 }
 
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Redeem the signed, single-use token from the verification email and mark the user's email as verified
+// @Tags authentication
+// @Produce json
+// @Param token query string true "Verification token from the email link"
+// @Success 200 {object} map[string]string "Email verified successfully"
+// @Failure 400 {object} map[string]string "Missing, invalid, expired or already-used token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/verify-email [get]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing verification token"})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(c.Request.Context(), token); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+// =============================================================================
+// Magic-Link (Passwordless) Login Endpoints
+// =============================================================================
+
+// RequestMagicLink godoc
+// @Summary Request a magic login link
+// @Description Email a single-use login link to the account. Always succeeds for well-formed emails to prevent account enumeration.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param request body MagicLinkRequest true "Account email"
+// @Success 200 {object} map[string]string "Login link sent if the account exists"
+// @Failure 400 {object} map[string]string "Invalid email format"
+// @Failure 404 {object} map[string]string "Magic-link login is not enabled"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Failure 500 {object} map[string]string "Failed to send email"
+// @Router /auth/magic-link [post]
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var req MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.SendMagicLink(c.Request.Context(), req.Email); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "if the account exists, a login link has been sent"})
+}
+
+// VerifyMagicLink godoc
+// @Summary Sign in with a magic link
+// @Description Redeem the single-use token from the login email and return JWT tokens
+// @Tags authentication
+// @Produce json
+// @Param token query string true "Token from the login link"
+// @Success 200 {object} models.TokenPair "Login successful"
+// @Failure 400 {object} map[string]string "Missing, invalid, expired or already-used token"
+// @Failure 403 {object} map[string]string "Account disabled"
+// @Failure 404 {object} map[string]string "Magic-link login is not enabled"
+// @Failure 423 {object} map[string]string "Account locked after too many failed attempts"
+// @Router /auth/magic-link/verify [get]
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing magic link token"})
+		return
+	}
+
+	tokens, err := h.authService.RedeemMagicLink(c.Request.Context(), token)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
 // =============================================================================
 // Google OAuth2 Authentication Endpoints
 // =============================================================================
@@ -246,7 +480,7 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 
 	resp, err := h.authService.GoogleAuth(c.Request.Context(), req.IDToken, config.GoogleOAuthConfig.ClientID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
@@ -272,8 +506,125 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 	// Exchange code for tokens + verify ID token
 	resp, err := h.authService.GoogleCallback(c.Request.Context(), code, config.GoogleOAuthConfig)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, resp)
-}
\ No newline at end of file
+}
+
+// =============================================================================
+// OAuth2 Social Login Endpoints (Google, GitHub)
+// =============================================================================
+
+// oauthStateCookie holds the CSRF state issued with the provider redirect
+const oauthStateCookie = "oauth_state"
+
+// oauthStateMaxAge is how long (in seconds) the user has to complete the consent screen
+const oauthStateMaxAge = 600
+
+// OAuthRedirect godoc
+// @Summary Initiate OAuth2 login
+// @Description Redirects the user to the provider's consent screen. A CSRF state value is stored in a short-lived cookie and checked on callback.
+// @Tags authentication
+// @Param provider path string true "OAuth provider" Enums(google, github)
+// @Success 302 "Redirect to provider consent screen"
+// @Failure 404 {object} map[string]string "Unknown or unconfigured provider"
+// @Router /auth/oauth/{provider} [get]
+func (h *AuthHandler) OAuthRedirect(c *gin.Context) {
+	provider, err := h.authService.OAuthProvider(c.Param("provider"))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	state, err := newOAuthState()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start oauth flow"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, oauthStateMaxAge, "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state))
+}
+
+// OAuthAuthorize godoc
+// @Summary Start a PKCE OAuth2 flow (public clients)
+// @Description Generates state and a PKCE code_verifier, stores the S256 code_challenge server-side, and returns the provider URL. The client opens auth_url and sends code, state and code_verifier to the callback.
+// @Tags authentication
+// @Produce json
+// @Param provider path string true "OAuth provider" Enums(google, github)
+// @Success 200 {object} models.OAuthAuthorization "Authorization URL, state and code verifier"
+// @Failure 404 {object} map[string]string "Unknown or unconfigured provider"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/oauth/{provider}/authorize [get]
+func (h *AuthHandler) OAuthAuthorize(c *gin.Context) {
+	authz, err := h.authService.StartOAuthAuthorization(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, authz)
+}
+
+// OAuthCallback godoc
+// @Summary OAuth2 callback handler
+// @Description Handle the provider redirect: verifies the state (cookie flow) or PKCE code_verifier (public clients), exchanges the authorization code, and logs in (or registers) the linked user. Public clients should POST code, state and code_verifier as JSON.
+// @Tags authentication
+// @Accept json
+// @Produce json
+// @Param provider path string true "OAuth provider" Enums(google, github)
+// @Param code query string false "Authorization code from the provider (GET)"
+// @Param state query string false "CSRF state issued by /auth/oauth/{provider} (GET)"
+// @Param request body OAuthCallbackBody false "Callback parameters for PKCE clients (POST)"
+// @Success 200 {object} models.TokenPair "OAuth authentication successful"
+// @Failure 400 {object} map[string]string "Missing code, invalid state, or PKCE verifier mismatch"
+// @Failure 401 {object} map[string]string "Code exchange failed or email not verified"
+// @Failure 404 {object} map[string]string "Unknown or unconfigured provider"
+// @Router /auth/oauth/{provider}/callback [get]
+// @Router /auth/oauth/{provider}/callback [post]
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	req := models.OAuthCallbackRequest{
+		Provider: c.Param("provider"),
+		Code:     c.Query("code"),
+		State:    c.Query("state"),
+	}
+	if c.Request.Method == http.MethodPost {
+		var body OAuthCallbackBody
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Code, req.State, req.CodeVerifier = body.Code, body.State, body.CodeVerifier
+	}
+	if req.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing authorization code"})
+		return
+	}
+
+	// CSRF check for the browser (cookie) flow; PKCE flows are checked by the service
+	if req.CodeVerifier == "" {
+		expected, err := c.Cookie(oauthStateCookie)
+		if err != nil || req.State == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(req.State)) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+			return
+		}
+		c.SetCookie(oauthStateCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	}
+
+	tokens, err := h.authService.HandleOAuthCallback(c.Request.Context(), req)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// newOAuthState returns a random, URL-safe CSRF state value
+func newOAuthState() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"authentio/internal/constants"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// EmailWebhookHandler Structure and Constructor
+// =============================================================================
+
+// emailWebhookMaxBytes caps a webhook payload; SendGrid batches events but
+// stays well below this
+const emailWebhookMaxBytes = 1 << 20
+
+// EmailWebhookHandler handles delivery event webhooks of email providers
+type EmailWebhookHandler struct {
+	authService service.AuthService
+}
+
+// NewEmailWebhookHandler creates a new EmailWebhookHandler instance
+func NewEmailWebhookHandler(authService service.AuthService) *EmailWebhookHandler {
+	return &EmailWebhookHandler{
+		authService: authService,
+	}
+}
+
+// EmailWebhookResponse reports how many addresses a webhook added to the suppression list
+type EmailWebhookResponse struct {
+	Suppressed int `json:"suppressed" example:"1"`
+}
+
+// =============================================================================
+// Webhook Endpoints (Protected - Require EMAIL_WEBHOOK_SECRET)
+// =============================================================================
+
+// EmailBounceWebhook godoc
+// @Summary Record email bounces
+// @Description Adds the recipients of permanent bounces to the email suppression list, so no more email is sent to them.
+// @Description Accepts SendGrid Event Webhook batches (events of type bounce; blocked messages are ignored), Mailgun webhooks (event failed with severity permanent) and {"email": "..."}; other events are ignored.
+// @Description Authenticated with EMAIL_WEBHOOK_SECRET as bearer token or token query parameter, and with a provider signature when EMAIL_WEBHOOK_SIGNING_KEY is set.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token query string false "EMAIL_WEBHOOK_SECRET, for providers that cannot send an Authorization header"
+// @Success 200 {object} EmailWebhookResponse
+// @Failure 400 {object} map[string]string "Malformed payload"
+// @Failure 401 {object} map[string]string "Missing or invalid webhook secret or signature"
+// @Failure 404 {object} map[string]string "Email webhooks disabled"
+// @Router /webhooks/email/bounce [post]
+func (h *EmailWebhookHandler) EmailBounceWebhook(c *gin.Context) {
+	h.suppress(c, func(e emailWebhookEvent) string {
+		switch {
+		case e.Event == "":
+			return constants.SuppressionBounce
+		case e.Event == "bounce" && e.Type != "blocked": // SendGrid
+			return constants.SuppressionBounce
+		case e.Event == "failed" && e.Severity == "permanent": // Mailgun
+			return constants.SuppressionBounce
+		}
+		return ""
+	})
+}
+
+// EmailUnsubscribeWebhook godoc
+// @Summary Record email unsubscribes
+// @Description Adds recipients who unsubscribed or reported a message as spam to the email suppression list, so no more email is sent to them.
+// @Description Accepts SendGrid Event Webhook batches (unsubscribe, group_unsubscribe, spamreport), Mailgun webhooks (unsubscribed, complained) and {"email": "..."}; other events are ignored.
+// @Description Authenticated with EMAIL_WEBHOOK_SECRET as bearer token or token query parameter, and with a provider signature when EMAIL_WEBHOOK_SIGNING_KEY is set.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param token query string false "EMAIL_WEBHOOK_SECRET, for providers that cannot send an Authorization header"
+// @Success 200 {object} EmailWebhookResponse
+// @Failure 400 {object} map[string]string "Malformed payload"
+// @Failure 401 {object} map[string]string "Missing or invalid webhook secret or signature"
+// @Failure 404 {object} map[string]string "Email webhooks disabled"
+// @Router /webhooks/email/unsubscribe [post]
+func (h *EmailWebhookHandler) EmailUnsubscribeWebhook(c *gin.Context) {
+	h.suppress(c, func(e emailWebhookEvent) string {
+		switch e.Event {
+		case "", "unsubscribe", "group_unsubscribe", "unsubscribed":
+			return constants.SuppressionUnsubscribe
+		case "spamreport", "complained":
+			return constants.SuppressionComplaint
+		}
+		return ""
+	})
+}
+
+// =============================================================================
+// Payload Parsing
+// =============================================================================
+
+// emailWebhookEvent is one delivery event, whichever provider sent it
+type emailWebhookEvent struct {
+	Email    string
+	Event    string // provider event name; empty for the plain {"email": "..."} form
+	Type     string // SendGrid bounce type ("bounce" or "blocked")
+	Severity string // Mailgun failure severity ("permanent" or "temporary")
+}
+
+// emailWebhookPayload covers a SendGrid event, a Mailgun webhook and the plain form
+type emailWebhookPayload struct {
+	Email string `json:"email"`
+	Event string `json:"event"`
+	Type  string `json:"type"`
+
+	EventData *struct {
+		Event     string `json:"event"`
+		Severity  string `json:"severity"`
+		Recipient string `json:"recipient"`
+	} `json:"event-data"`
+}
+
+// parseEmailWebhook reads the events of a webhook body: a JSON array for
+// SendGrid, an object otherwise.
+func parseEmailWebhook(body []byte) ([]emailWebhookEvent, error) {
+	var payloads []emailWebhookPayload
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &payloads); err != nil {
+			return nil, err
+		}
+	} else {
+		var payload emailWebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+	}
+
+	events := make([]emailWebhookEvent, 0, len(payloads))
+	for _, p := range payloads {
+		if p.EventData != nil {
+			events = append(events, emailWebhookEvent{
+				Email:    p.EventData.Recipient,
+				Event:    p.EventData.Event,
+				Severity: p.EventData.Severity,
+			})
+			continue
+		}
+		events = append(events, emailWebhookEvent{Email: p.Email, Event: p.Event, Type: p.Type})
+	}
+	return events, nil
+}
+
+// suppress adds the address of every event reasonFor gives a reason to the
+// suppression list. Events without a reason are acknowledged and ignored, so
+// providers do not retry them.
+func (h *EmailWebhookHandler) suppress(c *gin.Context, reasonFor func(emailWebhookEvent) string) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, emailWebhookMaxBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read payload"})
+		return
+	}
+	events, err := parseEmailWebhook(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload: " + err.Error()})
+		return
+	}
+
+	suppressed := 0
+	for _, event := range events {
+		reason := reasonFor(event)
+		if reason == "" || event.Email == "" {
+			continue
+		}
+		added, err := h.authService.SuppressEmail(c.Request.Context(), event.Email, reason)
+		if err != nil {
+			WriteError(c, err)
+			return
+		}
+		if added {
+			suppressed++
+		}
+	}
+
+	c.JSON(http.StatusOK, EmailWebhookResponse{Suppressed: suppressed})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/pkg/logger"
+	"authentio/pkg/oauth"
+	"authentio/pkg/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Error Responses
+// =============================================================================
+
+// ErrorResponse is the body of every error returned for a failed service call
+type ErrorResponse struct {
+	// Error is a human-readable message; it may change between releases
+	Error string `json:"error" example:"invalid email or password"`
+
+	// Code identifies the error case, see the Code constants in internal/service/errors.go
+	Code service.ErrorCode `json:"code" example:"invalid_credentials"`
+
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// errorStatus is the HTTP status of each error code. Codes not listed here are
+// served as 500.
+var errorStatus = map[service.ErrorCode]int{
+	service.CodeInvalidCredentials: http.StatusUnauthorized,
+	service.CodeAccountLocked:      http.StatusLocked,
+	service.CodeAccountDisabled:    http.StatusForbidden,
+	service.CodeAccountDeactivated: http.StatusForbidden,
+	service.CodeEmailNotVerified:   http.StatusForbidden,
+	service.CodeIncorrectPassword:  http.StatusUnauthorized,
+	service.CodePasswordReused:     http.StatusBadRequest,
+	service.CodePasswordPolicy:     http.StatusBadRequest,
+
+	service.CodeUserNotFound:      http.StatusNotFound,
+	service.CodeEmailTaken:        http.StatusConflict,
+	service.CodeLockConflict:      http.StatusConflict,
+	service.CodeExportRateLimited: http.StatusTooManyRequests,
+	service.CodeDomainNotAllowed:  http.StatusForbidden,
+
+	service.CodeInvalidRefreshToken:      http.StatusUnauthorized,
+	service.CodeInvalidOTP:               http.StatusBadRequest,
+	service.CodeOTPLocked:                http.StatusTooManyRequests,
+	service.CodeInvalidTOTPCode:          http.StatusBadRequest,
+	service.CodeTOTPCodeReused:           http.StatusBadRequest,
+	service.CodeTOTPNotEnrolled:          http.StatusBadRequest,
+	service.CodeTOTPLoginNotFound:        http.StatusBadRequest,
+	service.CodeInvalidVerificationToken: http.StatusBadRequest,
+	service.CodeInvalidMagicLink:         http.StatusBadRequest,
+	service.CodeInvalidResetLink:         http.StatusBadRequest,
+	service.CodeSessionNotFound:          http.StatusNotFound,
+
+	service.CodeUnknownProvider:         http.StatusNotFound,
+	service.CodeInvalidOAuthCallback:    http.StatusBadRequest,
+	service.CodeOAuthExchangeFailed:     http.StatusUnauthorized,
+	service.CodeInvalidOAuthToken:       http.StatusUnauthorized,
+	service.CodeProviderEmailUnverified: http.StatusForbidden,
+	service.CodePKCEMismatch:            http.StatusBadRequest,
+	service.CodeAccountAlreadyLinked:    http.StatusConflict,
+	service.CodeProviderAlreadyLinked:   http.StatusConflict,
+	service.CodeLinkedAccountNotFound:   http.StatusNotFound,
+	service.CodeLastSignInMethod:        http.StatusConflict,
+
+	service.CodeOAuthInvalidRequest:          http.StatusBadRequest,
+	service.CodeOAuthInvalidClient:           http.StatusUnauthorized,
+	service.CodeOAuthInvalidGrant:            http.StatusBadRequest,
+	service.CodeOAuthUnauthorizedClient:      http.StatusBadRequest,
+	service.CodeOAuthUnsupportedGrantType:    http.StatusBadRequest,
+	service.CodeOAuthUnsupportedResponseType: http.StatusBadRequest,
+	service.CodeOAuthInvalidScope:            http.StatusBadRequest,
+	service.CodeOAuthAuthorizationPending:    http.StatusBadRequest,
+	service.CodeOAuthSlowDown:                http.StatusBadRequest,
+	service.CodeOAuthAccessDenied:            http.StatusBadRequest,
+	service.CodeOAuthExpiredToken:            http.StatusBadRequest,
+	service.CodeInvalidUserCode:              http.StatusBadRequest,
+
+	service.CodeNoPhoneNumber:          http.StatusBadRequest,
+	service.CodeInvalidPhoneNumber:     http.StatusBadRequest,
+	service.CodeInvalidDeliveryChannel: http.StatusBadRequest,
+
+	service.CodeWebAuthnSessionNotFound:    http.StatusBadRequest,
+	service.CodeNoWebAuthnCredentials:      http.StatusBadRequest,
+	service.CodeWebAuthnVerificationFailed: http.StatusUnauthorized,
+
+	service.CodeCannotImpersonate:   http.StatusForbidden,
+	service.CodeNotImpersonating:    http.StatusBadRequest,
+	service.CodeRoleNotFound:        http.StatusNotFound,
+	service.CodeRoleExists:          http.StatusConflict,
+	service.CodeInvalidRoleName:     http.StatusBadRequest,
+	service.CodeInvalidPermission:   http.StatusBadRequest,
+	service.CodeInvalidIP:           http.StatusBadRequest,
+	service.CodeInvalidFlag:         http.StatusBadRequest,
+	service.CodeWebhookNotFound:     http.StatusNotFound,
+	service.CodeInvalidWebhook:      http.StatusBadRequest,
+	service.CodeOAuthClientNotFound: http.StatusNotFound,
+	service.CodeInvalidOAuthClient:  http.StatusBadRequest,
+
+	// Disabled features look like missing endpoints, except SMS delivery, which
+	// is a choice the client made in an otherwise valid request
+	service.CodeEmailVerificationDisabled: http.StatusNotFound,
+	service.CodeMagicLinkDisabled:         http.StatusNotFound,
+	service.CodeWebAuthnDisabled:          http.StatusNotFound,
+	service.CodeSMSDisabled:               http.StatusBadRequest,
+	service.CodePKCEDisabled:              http.StatusNotFound,
+	service.CodeRBACDisabled:              http.StatusNotFound,
+	service.CodeIPFilterDisabled:          http.StatusNotFound,
+	service.CodeFeatureFlagsDisabled:      http.StatusNotFound,
+	service.CodeDataExportDisabled:        http.StatusNotFound,
+	service.CodeWebhooksDisabled:          http.StatusNotFound,
+	service.CodeOAuthServerDisabled:       http.StatusNotFound,
+	service.CodeLinkedAccountsDisabled:    http.StatusNotFound,
+
+	service.CodeDeliveryFailed:     http.StatusBadGateway,
+	service.CodeServiceUnavailable: http.StatusServiceUnavailable,
+	service.CodeInternal:           http.StatusInternalServerError,
+}
+
+// foreignErrors gives codes to errors that reach handlers from packages other
+// than service, which returns them unchanged.
+var foreignErrors = []struct {
+	err  error
+	code service.ErrorCode
+}{
+	{repository.ErrSessionNotFound, service.CodeSessionNotFound},
+	{repository.ErrRoleNotFound, service.CodeRoleNotFound},
+	{repository.ErrWebhookNotFound, service.CodeWebhookNotFound},
+	{repository.ErrOAuthClientNotFound, service.CodeOAuthClientNotFound},
+	{repository.ErrOAuthIdentityNotFound, service.CodeLinkedAccountNotFound},
+	{repository.ErrTOTPNotEnrolled, service.CodeTOTPNotEnrolled},
+	{repository.ErrEncryptionKeyMissing, service.CodeServiceUnavailable},
+	{oauth.ErrUnknownProvider, service.CodeUnknownProvider},
+}
+
+// WriteError writes the ErrorResponse for an error returned by the service
+// layer, with the HTTP status of its code.
+//
+// *service.AuthError values are written as they are. Password policy violations
+// get details.violations, and OTP lockouts and export rate limits a Retry-After
+// header. Errors without a code are logged and reported as a generic 500, so
+// internal details such as database errors never reach the client; so is the
+// message of internal_error.
+//
+// Parameters:
+//   - c: Gin context to write the response to
+//   - err: Error returned by an AuthService method
+func WriteError(c *gin.Context, err error) {
+	resp := ErrorResponse{Error: "internal server error", Code: service.CodeInternal}
+
+	var authErr *service.AuthError
+	var policyErr *password.PolicyError
+	var lockedErr *service.ErrOTPLocked
+	var exportLimitErr *service.ErrExportRateLimited
+	switch {
+	case errors.As(err, &authErr):
+		resp.Code = authErr.Code
+		resp.Details = authErr.Details
+		if authErr.Code != service.CodeInternal {
+			resp.Error = authErr.Message
+		}
+
+	case errors.As(err, &policyErr):
+		resp.Error = "password does not meet policy"
+		resp.Code = service.CodePasswordPolicy
+		resp.Details = map[string]any{"violations": policyErr.Violations}
+
+	case errors.As(err, &lockedErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+		resp.Error = lockedErr.Error()
+		resp.Code = service.CodeOTPLocked
+
+	case errors.As(err, &exportLimitErr):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(exportLimitErr.RetryAfter.Seconds()))))
+		resp.Error = exportLimitErr.Error()
+		resp.Code = service.CodeExportRateLimited
+
+	default:
+		for _, foreign := range foreignErrors {
+			if errors.Is(err, foreign.err) {
+				resp.Error = foreign.err.Error()
+				resp.Code = foreign.code
+				break
+			}
+		}
+	}
+
+	status, ok := errorStatus[resp.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	if status >= http.StatusInternalServerError {
+		logger.Error("request failed", "error", err, "code", string(resp.Code), "path", c.FullPath(), "request_id", logger.RequestIDFromContext(c))
+	}
+	c.JSON(status, resp)
+}
@@ -7,9 +7,9 @@ import "authentio/internal/service"
 // =============================================================================
 
 // Handler aggregates all sub-handlers into a single struct.
-// 
+//
 // Embedding the concrete handlers automatically promotes their methods,
-// allowing the router to call methods like `h.GoogleCallback`, `h.Login`, 
+// allowing the router to call methods like `h.GoogleCallback`, `h.Login`,
 // `h.GetProfile`, etc. directly on the main Handler instance.
 //
 // This pattern provides:
@@ -18,9 +18,17 @@ import "authentio/internal/service"
 // - Method promotion without explicit delegation
 // - Maintainable and testable structure
 type Handler struct {
-	*AuthHandler   // Handles authentication endpoints (login, register, OAuth)
-	*TwoFAHandler  // Handles two-factor authentication endpoints
-	*UserHandler   // Handles user profile management endpoints
+	*AuthHandler     // Handles authentication endpoints (login, register, OAuth)
+	*TwoFAHandler    // Handles two-factor authentication endpoints
+	*UserHandler     // Handles user profile management endpoints
+	*HealthHandler   // Handles liveness and readiness probes
+	*AdminHandler    // Handles operator-only endpoints (account unlock, ...)
+	*WebAuthnHandler // Handles passkey registration and login
+	*SCIMHandler     // Handles SCIM 2.0 user provisioning
+
+	*OAuthServerHandler // Handles the OAuth2 authorization server for third-party clients
+
+	*EmailWebhookHandler // Handles bounce and unsubscribe webhooks of email providers
 }
 
 // =============================================================================
@@ -33,13 +41,22 @@ type Handler struct {
 //
 // Parameters:
 //   - authService: The core service containing business logic for all handlers
+//   - health: Probe handler built with NewHealthHandler (kept separate so it does not depend on AuthService)
 //
 // Returns:
 //   - *Handler: Fully initialized handler aggregator ready for router setup
-func NewHandler(authService service.AuthService) *Handler {
+func NewHandler(authService service.AuthService, health *HealthHandler) *Handler {
 	return &Handler{
-		AuthHandler:  NewAuthHandler(authService),
-		TwoFAHandler: NewTwoFAHandler(authService),
-		UserHandler:  NewUserHandler(authService),
+		AuthHandler:     NewAuthHandler(authService),
+		TwoFAHandler:    NewTwoFAHandler(authService),
+		UserHandler:     NewUserHandler(authService),
+		HealthHandler:   health,
+		AdminHandler:    NewAdminHandler(authService),
+		WebAuthnHandler: NewWebAuthnHandler(authService),
+		SCIMHandler:     NewSCIMHandler(authService),
+
+		OAuthServerHandler: NewOAuthServerHandler(authService),
+
+		EmailWebhookHandler: NewEmailWebhookHandler(authService),
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"authentio/pkg/circuit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// HealthHandler Structure and Constructor
+// =============================================================================
+
+// probeTimeout bounds each dependency check so a hung dependency can't stall readiness
+const probeTimeout = 2 * time.Second
+
+// errNotConfigured is reported for a dependency that was never wired in
+var errNotConfigured = errors.New("not configured")
+
+// HealthHandler serves liveness and readiness probes for orchestrators and load balancers
+type HealthHandler struct {
+	db  *sql.DB
+	rdb *redis.Client
+
+	// breakers are the circuit breakers whose state Liveness reports
+	breakers []*circuit.Breaker
+}
+
+// NewHealthHandler creates a new HealthHandler that probes the given Postgres and Redis clients
+func NewHealthHandler(db *sql.DB, rdb *redis.Client) *HealthHandler {
+	return &HealthHandler{db: db, rdb: rdb}
+}
+
+// WithCircuitBreakers reports the state of breakers, e.g. those of the OAuth providers, in Liveness.
+func (h *HealthHandler) WithCircuitBreakers(breakers ...*circuit.Breaker) *HealthHandler {
+	h.breakers = append(h.breakers, breakers...)
+	return h
+}
+
+// =============================================================================
+// Probe Endpoints (Public)
+// =============================================================================
+
+// Liveness godoc
+// @Summary Liveness probe
+// @Description Reports that the process is running. Does not check dependencies.
+// @Description circuits reports the state (closed, open or half-open) of the circuit breakers guarding external calls; an open breaker does not affect the status.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Service is alive, e.g. {\"status\":\"ok\",\"circuits\":{\"oauth_google\":\"closed\"}}"
+// @Router /healthz [get]
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	if len(h.breakers) == 0 {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		return
+	}
+
+	circuits := make(map[string]string, len(h.breakers))
+	for _, b := range h.breakers {
+		circuits[b.Name()] = b.State().String()
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "circuits": circuits})
+}
+
+// Readiness godoc
+// @Summary Readiness probe
+// @Description Pings Postgres and Redis (2s timeout each). Returns 503 with the failing component's error if any dependency is unavailable.
+// @Description postgres_pool reports connection pool usage and does not affect the status.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string "All dependencies reachable, e.g. {\"postgres\":\"ok\",\"postgres_pool\":\"3/25 in use, 0 waiting\",\"redis\":\"ok\"}"
+// @Failure 503 {object} map[string]string "At least one dependency is unavailable"
+// @Router /readyz [get]
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	probes := map[string]func(context.Context) error{
+		"postgres": h.pingPostgres,
+		"redis":    h.pingRedis,
+	}
+
+	// Run probes concurrently so the worst case is one timeout, not the sum
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]string, len(probes))
+		healthy = true
+	)
+	for name, probe := range probes {
+		wg.Add(1)
+		go func(name string, probe func(context.Context) error) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), probeTimeout)
+			defer cancel()
+
+			status := "ok"
+			if err := probe(ctx); err != nil {
+				status = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = status
+			if status != "ok" {
+				healthy = false
+			}
+			mu.Unlock()
+		}(name, probe)
+	}
+	wg.Wait()
+
+	// Informational only: a busy pool still serves requests, just more slowly
+	if h.db != nil {
+		stats := h.db.Stats()
+		results["postgres_pool"] = fmt.Sprintf("%d/%d in use, %d waiting", stats.InUse, stats.MaxOpenConnections, stats.WaitCount)
+	}
+
+	code := http.StatusOK
+	if !healthy {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, results)
+}
+
+// =============================================================================
+// Debug Endpoints (Admin)
+// =============================================================================
+
+// DBStatsResponse is the Postgres connection pool utilisation reported by DBStats
+type DBStatsResponse struct {
+	MaxOpenConnections int `json:"max_open_connections" example:"25"`
+
+	// Connections currently open, split into those in use and idle ones
+	OpenConnections int `json:"open_connections" example:"12"`
+	InUse           int `json:"in_use" example:"3"`
+	Idle            int `json:"idle" example:"9"`
+
+	// Requests that had to wait for a free connection, and their total wait
+	WaitCount        int64   `json:"wait_count" example:"0"`
+	WaitDurationSecs float64 `json:"wait_duration_seconds" example:"0"`
+
+	// Connections closed by DB_MAX_IDLE_CONNS, DB_CONN_MAX_IDLE_TIME and DB_CONN_MAX_LIFETIME
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// DBStats godoc
+// @Summary Database pool statistics
+// @Description Reports Postgres connection pool utilisation. Counters are cumulative since startup. Requires the ADMIN_API_TOKEN bearer token.
+// @Tags health
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} DBStatsResponse
+// @Failure 401 {object} map[string]string "Missing or invalid admin token"
+// @Failure 404 {object} map[string]string "Admin API disabled"
+// @Failure 503 {object} map[string]string "Database not configured"
+// @Router /debug/db-stats [get]
+func (h *HealthHandler) DBStats(c *gin.Context) {
+	if h.db == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errNotConfigured.Error()})
+		return
+	}
+
+	stats := h.db.Stats()
+	c.JSON(http.StatusOK, DBStatsResponse{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationSecs:   stats.WaitDuration.Seconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	})
+}
+
+// =============================================================================
+// Dependency Probes
+// =============================================================================
+
+func (h *HealthHandler) pingPostgres(ctx context.Context) error {
+	if h.db == nil {
+		return errNotConfigured
+	}
+	return h.db.PingContext(ctx)
+}
+
+func (h *HealthHandler) pingRedis(ctx context.Context) error {
+	if h.rdb == nil {
+		return errNotConfigured
+	}
+	return h.rdb.Ping(ctx).Err()
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"net/http"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Linked Social Accounts Endpoints (Protected - Require JWT)
+// =============================================================================
+
+// ListLinkedAccounts godoc
+// @Summary List linked social accounts
+// @Description List the OAuth provider accounts the current user can sign in with, ordered by provider. Provider tokens are never returned.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.OAuthIdentity "Linked accounts"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} ErrorResponse "Linked accounts are not enabled"
+// @Router /me/linked-accounts [get]
+func (h *UserHandler) ListLinkedAccounts(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	identities, err := h.authService.ListLinkedAccounts(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+// LinkAccount godoc
+// @Summary Link a social account
+// @Description Link a provider account to the current user, who can then sign in with it too. Start the flow with /auth/oauth/{provider}/authorize and post the code, state and code_verifier of the provider redirect here instead of to the callback.
+// @Description A provider account links to one user, and a user links one account per provider. Linking an already linked account returns it unchanged. Not allowed with impersonation tokens.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "OAuth provider" Enums(google, github)
+// @Param request body OAuthCallbackBody true "Callback parameters of the PKCE flow"
+// @Success 200 {object} models.OAuthIdentity "Linked account"
+// @Failure 400 {object} ErrorResponse "Missing code or PKCE verifier mismatch"
+// @Failure 401 {object} ErrorResponse "Invalid JWT token or code exchange failed"
+// @Failure 403 {object} ErrorResponse "Token was issued by impersonation"
+// @Failure 404 {object} ErrorResponse "Unknown provider, or linked accounts or PKCE not enabled"
+// @Failure 409 {object} ErrorResponse "Account is linked to another user, or another account of the provider is linked"
+// @Router /me/linked-accounts/{provider} [post]
+func (h *UserHandler) LinkAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var body OAuthCallbackBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Support staff acting as the user must not be able to add a way to sign in as them
+	if _, impersonated := c.Get("impersonatedBy"); impersonated {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "accounts cannot be linked while impersonating", Code: service.CodeCannotImpersonate})
+		return
+	}
+
+	identity, err := h.authService.LinkOAuthIdentity(c.Request.Context(), userID.(int64), models.OAuthCallbackRequest{
+		Provider:     c.Param("provider"),
+		Code:         body.Code,
+		State:        body.State,
+		CodeVerifier: body.CodeVerifier,
+	})
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, identity)
+}
+
+// UnlinkAccount godoc
+// @Summary Unlink a social account
+// @Description Remove the linked account of a provider from the current user. The only linked account of a user without a password cannot be removed. Not allowed with impersonation tokens.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "OAuth provider"
+// @Success 204 "Account unlinked"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} ErrorResponse "Token was issued by impersonation"
+// @Failure 404 {object} ErrorResponse "No account of the provider is linked, or linked accounts are not enabled"
+// @Failure 409 {object} ErrorResponse "Last way to sign in; set a password first"
+// @Router /me/linked-accounts/{provider} [delete]
+func (h *UserHandler) UnlinkAccount(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if _, impersonated := c.Get("impersonatedBy"); impersonated {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "accounts cannot be unlinked while impersonating", Code: service.CodeCannotImpersonate})
+		return
+	}
+
+	if err := h.authService.UnlinkOAuthIdentity(c.Request.Context(), userID.(int64), c.Param("provider")); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
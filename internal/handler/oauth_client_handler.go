@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// OAuth Client Administration Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// ListOAuthClients godoc
+// @Summary List OAuth clients
+// @Description List the third-party applications registered with the OAuth2 authorization server, oldest first. Secrets are not returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.OAuthClient "OAuth clients"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Authorization server not enabled"
+// @Router /admin/oauth-clients [get]
+func (h *AdminHandler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.authService.ListOAuthClients(c.Request.Context())
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, clients)
+}
+
+// CreateOAuthClient godoc
+// @Summary Register an OAuth client
+// @Description Register a third-party application that may obtain tokens from /oauth/token. Redirect URIs are matched exactly.
+// @Description Confidential clients get a client secret, only returned in this response. Public clients get none and may only use the authorization code grant with PKCE.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body OAuthClientRequest true "Name, redirect URIs, allowed scopes and client type"
+// @Success 201 {object} models.OAuthClient "Client registered"
+// @Failure 400 {object} map[string]string "Invalid redirect URI or scope"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Authorization server not enabled"
+// @Router /admin/oauth-clients [post]
+func (h *AdminHandler) CreateOAuthClient(c *gin.Context) {
+	var req OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := h.authService.CreateOAuthClient(c.Request.Context(), req.Name, req.RedirectURIs, req.Scopes, req.Public)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, client)
+}
+
+// DeleteOAuthClient godoc
+// @Summary Delete an OAuth client
+// @Description Remove a client. Its codes and refresh tokens stop working at once; issued access tokens stay valid until they expire.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 204 "Client deleted"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Client not found"
+// @Router /admin/oauth-clients/{client_id} [delete]
+func (h *AdminHandler) DeleteOAuthClient(c *gin.Context) {
+	if err := h.authService.DeleteOAuthClient(c.Request.Context(), c.Param("client_id")); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
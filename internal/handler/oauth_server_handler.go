@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+
+	"authentio/internal/models"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// OAuthServerHandler Structure and Constructor
+// =============================================================================
+
+// OAuthServerHandler handles the OAuth2 authorization server endpoints used by
+// registered third-party clients
+type OAuthServerHandler struct {
+	authService service.AuthService
+}
+
+// NewOAuthServerHandler creates a new OAuthServerHandler instance
+func NewOAuthServerHandler(authService service.AuthService) *OAuthServerHandler {
+	return &OAuthServerHandler{
+		authService: authService,
+	}
+}
+
+// OAuthErrorResponse is the RFC 6749 section 5.2 error response of the token endpoint
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty" example:"invalid, expired or already used grant"`
+}
+
+// =============================================================================
+// Authorization Server Endpoints
+// =============================================================================
+
+// OAuthServerAuthorize godoc
+// @Summary Authorize an OAuth client
+// @Description Issues an authorization code to a registered client on behalf of the signed-in user, whose consent screen calls this endpoint with their access token.
+// @Description The client must use PKCE with code_challenge_method S256. The response names the client redirect URI, with code and state (or error and error_description) appended, to send the browser to.
+// @Description An unknown client_id or unregistered redirect_uri is answered with 400 instead, so the user is never sent to an unverified URI.
+// @Tags oauth-server
+// @Produce json
+// @Security BearerAuth
+// @Param response_type query string true "Must be code" Enums(code)
+// @Param client_id query string true "Registered client ID"
+// @Param redirect_uri query string false "One of the client's redirect URIs; optional if it has only one"
+// @Param scope query string false "Space-separated scopes; defaults to every scope the client is allowed"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "Base64url SHA-256 of the code_verifier"
+// @Param code_challenge_method query string true "Must be S256" Enums(S256)
+// @Success 200 {object} models.OAuthAuthorizeResponse "Client redirect URI with the code or error"
+// @Failure 400 {object} OAuthErrorResponse "Unknown client or unregistered redirect URI"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} ErrorResponse "Authorization server not enabled"
+// @Router /oauth/authorize [get]
+func (h *OAuthServerHandler) OAuthServerAuthorize(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		writeOAuthError(c, service.CodeOAuthInvalidRequest, err.Error())
+		return
+	}
+
+	authz, err := h.authService.AuthorizeOAuthClient(c.Request.Context(), userID.(int64), req)
+	if err != nil {
+		writeOAuthServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, authz)
+}
+
+// OAuthServerToken godoc
+// @Summary Obtain OAuth tokens
+// @Description Token endpoint (RFC 6749) for the authorization_code (with code_verifier), refresh_token, client_credentials and RFC 8628 device_code grants.
+// @Description Confidential clients authenticate with HTTP Basic or client_id and client_secret form fields; public clients send only client_id and cannot use client_credentials.
+// @Description Codes and refresh tokens work once; each refresh returns a new refresh token. Access tokens carry client_id and scope claims and are not accepted by this API's own endpoints.
+// @Description Devices poll with their device_code no more often than the interval and get authorization_pending until the user answers, slow_down (wait 5 seconds longer from now on) when too fast, then tokens, access_denied or expired_token.
+// @Tags oauth-server
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Grant type" Enums(authorization_code, refresh_token, client_credentials, urn:ietf:params:oauth:grant-type:device_code)
+// @Param code formData string false "Authorization code (authorization_code)"
+// @Param redirect_uri formData string false "Redirect URI sent to /oauth/authorize, if any (authorization_code)"
+// @Param code_verifier formData string false "PKCE code verifier (authorization_code)"
+// @Param refresh_token formData string false "Refresh token (refresh_token)"
+// @Param device_code formData string false "Device code from /oauth/device/code (device_code)"
+// @Param scope formData string false "Space-separated scopes (refresh_token: a subset of the original grant; client_credentials)"
+// @Param client_id formData string false "Client ID, when not sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret of confidential clients, when not sent with HTTP Basic"
+// @Success 200 {object} models.OAuthTokenResponse "Issued tokens"
+// @Failure 400 {object} OAuthErrorResponse "invalid_request, invalid_grant, invalid_scope, unauthorized_client, unsupported_grant_type, or authorization_pending, slow_down, access_denied or expired_token (device_code)"
+// @Failure 401 {object} OAuthErrorResponse "invalid_client"
+// @Failure 404 {object} ErrorResponse "Authorization server not enabled"
+// @Router /oauth/token [post]
+func (h *OAuthServerHandler) OAuthServerToken(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req models.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, service.CodeOAuthInvalidRequest, err.Error())
+		return
+	}
+	basicAuth := false
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret, basicAuth = id, secret, true
+	}
+
+	tokens, err := h.authService.ExchangeOAuthToken(c.Request.Context(), req)
+	if err != nil {
+		if basicAuth && service.Code(err) == service.CodeOAuthInvalidClient {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// OAuthDeviceAuthorization godoc
+// @Summary Start the device flow
+// @Description Device authorization endpoint (RFC 8628) for clients on devices without a browser or keyboard, such as CLIs and smart TVs.
+// @Description The device shows user_code and verification_uri (or verification_uri_complete as a QR code) and polls /oauth/token with grant_type urn:ietf:params:oauth:grant-type:device_code and the device_code every interval seconds, until the user approves or denies the request with /activate.
+// @Description Confidential clients authenticate with HTTP Basic or client_id and client_secret form fields; public clients send only client_id.
+// @Tags oauth-server
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param client_id formData string false "Client ID, when not sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret of confidential clients, when not sent with HTTP Basic"
+// @Param scope formData string false "Space-separated scopes; defaults to every scope the client is allowed"
+// @Success 200 {object} models.DeviceAuthorizationResponse "Device and user codes"
+// @Failure 400 {object} OAuthErrorResponse "invalid_request or invalid_scope"
+// @Failure 401 {object} OAuthErrorResponse "invalid_client"
+// @Failure 404 {object} ErrorResponse "Authorization server not enabled"
+// @Router /oauth/device/code [post]
+func (h *OAuthServerHandler) OAuthDeviceAuthorization(c *gin.Context) {
+	// The device code is a credential, like the tokens it stands for
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var req models.DeviceAuthorizationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, service.CodeOAuthInvalidRequest, err.Error())
+		return
+	}
+	basicAuth := false
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret, basicAuth = id, secret, true
+	}
+
+	authz, err := h.authService.RequestDeviceAuthorization(c.Request.Context(), req)
+	if err != nil {
+		if basicAuth && service.Code(err) == service.CodeOAuthInvalidClient {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, authz)
+}
+
+// ActivateDevice godoc
+// @Summary Approve or deny a device
+// @Description Answers the device flow request of a user code on behalf of the signed-in user. The verification page (OAUTH_DEVICE_VERIFICATION_URI), where the user enters the code shown on the device, calls this endpoint with their access token.
+// @Description On approval the device's next poll of /oauth/token receives tokens for the user; on denial it receives access_denied. Each user code can be answered once. Not allowed with impersonation tokens.
+// @Tags oauth-server
+// @Produce json
+// @Security BearerAuth
+// @Param user_code query string true "User code shown on the device; case, spaces and dashes are ignored"
+// @Param action query string false "approve (default) or deny" Enums(approve, deny)
+// @Success 200 {object} models.DeviceActivationResponse "The answered request"
+// @Failure 400 {object} ErrorResponse "Unknown, expired or already answered user code, or unknown action"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} ErrorResponse "Token was issued by impersonation"
+// @Failure 404 {object} ErrorResponse "Authorization server not enabled"
+// @Router /activate [get]
+func (h *OAuthServerHandler) ActivateDevice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Support staff acting as the user must not be able to sign a device in as them
+	if _, impersonated := c.Get("impersonatedBy"); impersonated {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "devices cannot be activated while impersonating", Code: service.CodeCannotImpersonate})
+		return
+	}
+
+	approve := true
+	switch c.DefaultQuery("action", "approve") {
+	case "approve":
+	case "deny":
+		approve = false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be approve or deny"})
+		return
+	}
+
+	activation, err := h.authService.ActivateDevice(c.Request.Context(), userID.(int64), c.Query("user_code"), approve)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, activation)
+}
+
+// =============================================================================
+// Error Responses
+// =============================================================================
+
+// oauthErrorCodes are the service codes that are RFC 6749 error codes
+var oauthErrorCodes = map[service.ErrorCode]bool{
+	service.CodeOAuthInvalidRequest:          true,
+	service.CodeOAuthInvalidClient:           true,
+	service.CodeOAuthInvalidGrant:            true,
+	service.CodeOAuthUnauthorizedClient:      true,
+	service.CodeOAuthUnsupportedGrantType:    true,
+	service.CodeOAuthUnsupportedResponseType: true,
+	service.CodeOAuthInvalidScope:            true,
+	service.CodeOAuthAuthorizationPending:    true,
+	service.CodeOAuthSlowDown:                true,
+	service.CodeOAuthAccessDenied:            true,
+	service.CodeOAuthExpiredToken:            true,
+}
+
+// writeOAuthServiceError writes errors with an RFC 6749 code as an
+// OAuthErrorResponse and everything else with WriteError.
+func writeOAuthServiceError(c *gin.Context, err error) {
+	code := service.Code(err)
+	if !oauthErrorCodes[code] {
+		WriteError(c, err)
+		return
+	}
+	writeOAuthError(c, code, err.Error())
+}
+
+// writeOAuthError writes an OAuthErrorResponse with the status of code.
+func writeOAuthError(c *gin.Context, code service.ErrorCode, description string) {
+	status, ok := errorStatus[code]
+	if !ok {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, OAuthErrorResponse{Error: string(code), ErrorDescription: description})
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Role Administration Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// ListRoles godoc
+// @Summary List roles
+// @Description List every RBAC role with its permissions, ordered by name
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Role "Roles"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "RBAC is not enabled"
+// @Router /admin/roles [get]
+func (h *AdminHandler) ListRoles(c *gin.Context) {
+	roles, err := h.authService.ListRoles(c.Request.Context())
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Create an RBAC role granting a set of permissions
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body RoleRequest true "Role name and permissions"
+// @Success 201 {object} models.Role "Role created"
+// @Failure 400 {object} map[string]string "Invalid role name or permission"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 409 {object} map[string]string "Role name already taken"
+// @Router /admin/roles [post]
+func (h *AdminHandler) CreateRole(c *gin.Context) {
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.authService.CreateRole(c.Request.Context(), req.Name, req.Permissions)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, role)
+}
+
+// GetRole godoc
+// @Summary Get a role
+// @Description Get an RBAC role with its permissions
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 200 {object} models.Role "Role"
+// @Failure 400 {object} map[string]string "Invalid role ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Router /admin/roles/{id} [get]
+func (h *AdminHandler) GetRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	role, err := h.authService.GetRole(c.Request.Context(), roleID)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// UpdateRole godoc
+// @Summary Update a role
+// @Description Rename an RBAC role and replace its permissions. Holders get the new permissions with their next access token.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Param request body RoleRequest true "New role name and permissions"
+// @Success 200 {object} models.Role "Role updated"
+// @Failure 400 {object} map[string]string "Invalid role ID, name or permission"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Failure 409 {object} map[string]string "Role name already taken"
+// @Router /admin/roles/{id} [put]
+func (h *AdminHandler) UpdateRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.authService.UpdateRole(c.Request.Context(), roleID, req.Name, req.Permissions)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Delete an RBAC role and unassign it from every user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Role ID"
+// @Success 204 "Role deleted"
+// @Failure 400 {object} map[string]string "Invalid role ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Router /admin/roles/{id} [delete]
+func (h *AdminHandler) DeleteRole(c *gin.Context) {
+	roleID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	if err := h.authService.DeleteRole(c.Request.Context(), roleID); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// =============================================================================
+// User Role Assignment Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// ListUserRoles godoc
+// @Summary List a user's roles
+// @Description List the RBAC roles assigned to a user
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} models.Role "Roles of the user"
+// @Failure 400 {object} map[string]string "Invalid user ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found"
+// @Router /admin/users/{id}/roles [get]
+func (h *AdminHandler) ListUserRoles(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	roles, err := h.authService.ListUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// AssignRole godoc
+// @Summary Assign a role to a user
+// @Description Give a user an RBAC role; its permissions appear in the user's next access token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body AssignRoleRequest true "Role to assign"
+// @Success 200 {object} map[string]string "Role assigned"
+// @Failure 400 {object} map[string]string "Invalid user ID or request"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User or role not found"
+// @Router /admin/users/{id}/roles [post]
+func (h *AdminHandler) AssignRole(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req AssignRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.AssignRole(c.Request.Context(), userID, req.RoleID); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role assigned"})
+}
+
+// RemoveRole godoc
+// @Summary Remove a role from a user
+// @Description Take an RBAC role away from a user. Access tokens issued before keep its permissions until they expire or are refreshed.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param roleId path int true "Role ID"
+// @Success 204 "Role removed"
+// @Failure 400 {object} map[string]string "Invalid user or role ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "User not found or role not assigned"
+// @Router /admin/users/{id}/roles/{roleId} [delete]
+func (h *AdminHandler) RemoveRole(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	roleID, err := strconv.ParseInt(c.Param("roleId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	if err := h.authService.RemoveRole(c.Request.Context(), userID, roleID); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,563 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/pkg/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// SCIM 2.0 Resources (RFC 7643 / RFC 7644)
+// =============================================================================
+
+const (
+	scimContentType = "application/scim+json"
+
+	scimUserSchema  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchSchema = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimMaxCount caps the page size of a list request
+	scimMaxCount = 100
+)
+
+// SCIMUser is the SCIM core User resource. userName is the user's email address.
+type SCIMUser struct {
+	Schemas  []string    `json:"schemas"`
+	ID       string      `json:"id,omitempty" example:"42"`
+	UserName string      `json:"userName" example:"jane.doe@example.com"`
+	Name     SCIMName    `json:"name"`
+	Emails   []SCIMEmail `json:"emails,omitempty"`
+	Active   *bool       `json:"active,omitempty" example:"true"`
+	Password string      `json:"password,omitempty" example:"Str0ng!Passw0rd"` // write-only, never returned
+	Meta     *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMName is the name sub-attribute of a SCIM User
+type SCIMName struct {
+	GivenName  string `json:"givenName" example:"Jane"`
+	FamilyName string `json:"familyName" example:"Doe"`
+	Formatted  string `json:"formatted,omitempty" example:"Jane Doe"`
+}
+
+// SCIMEmail is one entry of the emails attribute of a SCIM User
+type SCIMEmail struct {
+	Value   string `json:"value" example:"jane.doe@example.com"`
+	Type    string `json:"type,omitempty" example:"work"`
+	Primary bool   `json:"primary,omitempty" example:"true"`
+}
+
+// SCIMMeta is the resource metadata returned with every SCIM User
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType" example:"User"`
+	Created      time.Time `json:"created" example:"2024-05-01T12:00:00Z"`
+	LastModified time.Time `json:"lastModified" example:"2024-05-02T08:30:00Z"`
+	Location     string    `json:"location" example:"https://auth.example.com/scim/v2/Users/42"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults" example:"1"`
+	StartIndex   int        `json:"startIndex" example:"1"`
+	ItemsPerPage int        `json:"itemsPerPage" example:"100"`
+	Resources    []SCIMUser `json:"Resources"`
+}
+
+// SCIMPatchRequest is a SCIM PatchOp message
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is a single add, replace or remove operation
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// scimFilterPattern matches the equality filters identity providers use to look users up
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*(userName|emails(?:\.value)?)\s+eq\s+"([^"]*)"\s*$`)
+
+// errSCIMInvalidValue marks request payloads that cannot be applied to a user
+var errSCIMInvalidValue = errors.New("invalid value")
+
+// =============================================================================
+// SCIMHandler Structure and Constructor
+// =============================================================================
+
+// SCIMHandler handles SCIM 2.0 user provisioning requests
+type SCIMHandler struct {
+	authService service.AuthService
+}
+
+// NewSCIMHandler creates a new SCIMHandler instance
+func NewSCIMHandler(authService service.AuthService) *SCIMHandler {
+	return &SCIMHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// SCIM User Endpoints (Protected - Require SCIM Token)
+// =============================================================================
+
+// SCIMListUsers godoc
+// @Summary List users (SCIM)
+// @Description List provisioned users. Supports the filters userName eq "..." and emails.value eq "..." and 1-based startIndex/count pagination.
+// @Tags scim
+// @Produce json
+// @Param filter query string false "SCIM filter"
+// @Param startIndex query int false "1-based index of the first result" default(1)
+// @Param count query int false "Maximum number of results (max 100)" default(100)
+// @Success 200 {object} SCIMListResponse
+// @Failure 400 {object} map[string]interface{} "Invalid filter"
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Router /scim/v2/Users [get]
+func (h *SCIMHandler) SCIMListUsers(c *gin.Context) {
+	filter := repository.UserFilter{Limit: scimMaxCount}
+
+	if raw := c.Query("filter"); raw != "" {
+		m := scimFilterPattern.FindStringSubmatch(raw)
+		if m == nil {
+			writeSCIMError(c, http.StatusBadRequest, "invalidFilter", "only userName eq and emails.value eq filters are supported")
+			return
+		}
+		filter.Email = m[2]
+	}
+
+	startIndex := scimIntQuery(c, "startIndex", 1)
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	filter.Offset = startIndex - 1
+
+	if count := scimIntQuery(c, "count", scimMaxCount); count < 0 {
+		filter.Limit = 0
+	} else if count < scimMaxCount {
+		filter.Limit = count
+	}
+
+	page, err := h.authService.ListUsers(c.Request.Context(), filter, nil)
+	if err != nil {
+		writeSCIMServiceError(c, err)
+		return
+	}
+
+	resources := make([]SCIMUser, len(page.Users))
+	for i := range page.Users {
+		resources[i] = toSCIMUser(c, &page.Users[i])
+	}
+
+	writeSCIM(c, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: page.Total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// SCIMGetUser godoc
+// @Summary Get a user (SCIM)
+// @Tags scim
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} SCIMUser
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /scim/v2/Users/{id} [get]
+func (h *SCIMHandler) SCIMGetUser(c *gin.Context) {
+	user, ok := h.loadSCIMUser(c)
+	if !ok {
+		return
+	}
+	writeSCIM(c, http.StatusOK, toSCIMUser(c, user))
+}
+
+// SCIMCreateUser godoc
+// @Summary Provision a user (SCIM)
+// @Description Create a user from a SCIM User resource. The password is optional.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param request body SCIMUser true "SCIM User"
+// @Success 201 {object} SCIMUser
+// @Failure 400 {object} map[string]interface{} "Invalid resource or password policy violation"
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Failure 409 {object} map[string]interface{} "User already exists"
+// @Router /scim/v2/Users [post]
+func (h *SCIMHandler) SCIMCreateUser(c *gin.Context) {
+	var req SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	user := &models.User{IsActive: true, Provider: "scim"}
+	if err := applySCIMUser(user, &req); err != nil {
+		writeSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	if err := h.authService.ProvisionUser(c.Request.Context(), user, req.Password); err != nil {
+		writeSCIMServiceError(c, err)
+		return
+	}
+
+	resource := toSCIMUser(c, user)
+	c.Header("Location", resource.Meta.Location)
+	writeSCIM(c, http.StatusCreated, resource)
+}
+
+// SCIMReplaceUser godoc
+// @Summary Replace a user (SCIM)
+// @Description Replace the user's name, email and active flag with the given SCIM User resource
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body SCIMUser true "SCIM User"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} map[string]interface{} "Invalid resource"
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 409 {object} map[string]interface{} "Email belongs to another user"
+// @Router /scim/v2/Users/{id} [put]
+func (h *SCIMHandler) SCIMReplaceUser(c *gin.Context) {
+	user, ok := h.loadSCIMUser(c)
+	if !ok {
+		return
+	}
+
+	var req SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	// PUT replaces the resource: an omitted active attribute means active
+	user.IsActive = true
+	if err := applySCIMUser(user, &req); err != nil {
+		writeSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+		return
+	}
+
+	if err := h.authService.UpdateProvisionedUser(c.Request.Context(), user); err != nil {
+		writeSCIMServiceError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, toSCIMUser(c, user))
+}
+
+// SCIMPatchUser godoc
+// @Summary Update a user (SCIM)
+// @Description Apply a SCIM PatchOp to a user. Supported paths: active, userName, emails, name.givenName, name.familyName.
+// @Tags scim
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body SCIMPatchRequest true "SCIM PatchOp"
+// @Success 200 {object} SCIMUser
+// @Failure 400 {object} map[string]interface{} "Invalid operation"
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 409 {object} map[string]interface{} "Email belongs to another user"
+// @Router /scim/v2/Users/{id} [patch]
+func (h *SCIMHandler) SCIMPatchUser(c *gin.Context) {
+	user, ok := h.loadSCIMUser(c)
+	if !ok {
+		return
+	}
+
+	var req SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeSCIMError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+
+	for _, op := range req.Operations {
+		if err := applySCIMPatch(user, op); err != nil {
+			writeSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+
+	if err := h.authService.UpdateProvisionedUser(c.Request.Context(), user); err != nil {
+		writeSCIMServiceError(c, err)
+		return
+	}
+	writeSCIM(c, http.StatusOK, toSCIMUser(c, user))
+}
+
+// SCIMDeleteUser godoc
+// @Summary Deprovision a user (SCIM)
+// @Description Delete the user and revoke all of their sessions
+// @Tags scim
+// @Param id path string true "User ID"
+// @Success 204 "User deprovisioned"
+// @Failure 401 {object} map[string]interface{} "Invalid SCIM token"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Router /scim/v2/Users/{id} [delete]
+func (h *SCIMHandler) SCIMDeleteUser(c *gin.Context) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeSCIMError(c, http.StatusNotFound, "", "user not found")
+		return
+	}
+
+	if err := h.authService.DeprovisionUser(c.Request.Context(), userID); err != nil {
+		writeSCIMServiceError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// =============================================================================
+// SCIM Helper Functions
+// =============================================================================
+
+// loadSCIMUser loads the user named by the :id parameter, writing a SCIM error if it does not exist.
+func (h *SCIMHandler) loadSCIMUser(c *gin.Context) (*models.User, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		writeSCIMError(c, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+
+	user, err := h.authService.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		writeSCIMServiceError(c, err)
+		return nil, false
+	}
+	return user, true
+}
+
+// toSCIMUser converts a user to a SCIM User resource.
+func toSCIMUser(c *gin.Context, user *models.User) SCIMUser {
+	id := strconv.FormatInt(user.ID, 10)
+	active := user.IsActive
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+
+	return SCIMUser{
+		Schemas:  []string{scimUserSchema},
+		ID:       id,
+		UserName: user.Email,
+		Name: SCIMName{
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+			Formatted:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+		},
+		Emails: []SCIMEmail{{Value: user.Email, Type: "work", Primary: true}},
+		Active: &active,
+		Meta: &SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     fmt.Sprintf("%s://%s/scim/v2/Users/%s", scheme, c.Request.Host, id),
+		},
+	}
+}
+
+// applySCIMUser copies the attributes of a SCIM User resource onto user. The email
+// is userName when it is an address, otherwise the primary (or first) email.
+func applySCIMUser(user *models.User, req *SCIMUser) error {
+	email := req.UserName
+	if !strings.Contains(email, "@") {
+		email = primarySCIMEmail(req.Emails)
+	}
+	if email == "" {
+		return fmt.Errorf("%w: userName or emails must contain an email address", errSCIMInvalidValue)
+	}
+
+	user.Email = strings.TrimSpace(email)
+	user.FirstName = req.Name.GivenName
+	user.LastName = req.Name.FamilyName
+	if req.Active != nil {
+		user.IsActive = *req.Active
+	}
+	return nil
+}
+
+// applySCIMPatch applies one PatchOp operation to user. Attributes this service does
+// not store (externalId, displayName, ...) are ignored.
+func applySCIMPatch(user *models.User, op SCIMPatchOperation) error {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+	case "remove":
+		switch strings.ToLower(op.Path) {
+		case "name.givenname":
+			user.FirstName = ""
+		case "name.familyname":
+			user.LastName = ""
+		case "username", "emails", "active":
+			return fmt.Errorf("%w: %s cannot be removed", errSCIMInvalidValue, op.Path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported op %q", errSCIMInvalidValue, op.Op)
+	}
+
+	// Without a path the value is an object of attributes to set
+	if op.Path == "" {
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return fmt.Errorf("%w: value must be an object when path is omitted", errSCIMInvalidValue)
+		}
+		for path, value := range attrs {
+			if err := setSCIMAttribute(user, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setSCIMAttribute(user, op.Path, op.Value)
+}
+
+// setSCIMAttribute sets a single attribute addressed by a (case-insensitive) SCIM path.
+func setSCIMAttribute(user *models.User, path string, value json.RawMessage) error {
+	path = strings.ToLower(path)
+	switch {
+	case path == "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		user.IsActive = active
+
+	case path == "username":
+		var userName string
+		if err := json.Unmarshal(value, &userName); err != nil || !strings.Contains(userName, "@") {
+			return fmt.Errorf("%w: userName must be an email address", errSCIMInvalidValue)
+		}
+		user.Email = userName
+
+	case path == "name":
+		var name SCIMName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return fmt.Errorf("%w: name must be an object", errSCIMInvalidValue)
+		}
+		if name.GivenName != "" {
+			user.FirstName = name.GivenName
+		}
+		if name.FamilyName != "" {
+			user.LastName = name.FamilyName
+		}
+
+	case path == "name.givenname", path == "name.familyname":
+		var part string
+		if err := json.Unmarshal(value, &part); err != nil {
+			return fmt.Errorf("%w: %s must be a string", errSCIMInvalidValue, path)
+		}
+		if path == "name.givenname" {
+			user.FirstName = part
+		} else {
+			user.LastName = part
+		}
+
+	case strings.HasPrefix(path, "emails"):
+		// Either emails (an array) or a value path such as emails[type eq "work"].value
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			var emails []SCIMEmail
+			if err := json.Unmarshal(value, &emails); err != nil {
+				return fmt.Errorf("%w: emails must be a string or an array", errSCIMInvalidValue)
+			}
+			email = primarySCIMEmail(emails)
+		}
+		if !strings.Contains(email, "@") {
+			return fmt.Errorf("%w: email must be an email address", errSCIMInvalidValue)
+		}
+		user.Email = email
+	}
+	return nil
+}
+
+// primarySCIMEmail returns the primary email, or the first one if none is primary.
+func primarySCIMEmail(emails []SCIMEmail) string {
+	for _, e := range emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// scimBool decodes a boolean that some identity providers send as the string "True"/"False".
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if parsed, err := strconv.ParseBool(s); err == nil {
+			return parsed, nil
+		}
+	}
+	return false, fmt.Errorf("%w: active must be a boolean", errSCIMInvalidValue)
+}
+
+// scimIntQuery returns an integer query parameter, or def when it is absent or malformed.
+func scimIntQuery(c *gin.Context, name string, def int) int {
+	n, err := strconv.Atoi(c.Query(name))
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// writeSCIM writes a SCIM resource with the application/scim+json content type.
+func writeSCIM(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", scimContentType)
+	c.JSON(status, body)
+}
+
+// writeSCIMError writes a SCIM error response (RFC 7644 section 3.12).
+func writeSCIMError(c *gin.Context, status int, scimType, detail string) {
+	body := gin.H{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(c, status, body)
+}
+
+// writeSCIMServiceError maps provisioning service errors to SCIM error responses.
+func writeSCIMServiceError(c *gin.Context, err error) {
+	var policyErr *password.PolicyError
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		writeSCIMError(c, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, service.ErrEmailTaken):
+		writeSCIMError(c, http.StatusConflict, "uniqueness", err.Error())
+	case errors.As(err, &policyErr):
+		writeSCIMError(c, http.StatusBadRequest, "invalidValue", err.Error())
+	default:
+		writeSCIMError(c, http.StatusInternalServerError, "", "provisioning request failed")
+	}
+}
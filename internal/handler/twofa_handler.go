@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	// _"authentio/internal/handler"
+	"authentio/internal/constants"
 	"authentio/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -48,10 +49,45 @@ func (h *TwoFAHandler) EnableEmail2FA(c *gin.Context) {
 	}
 
 	if err := h.authService.EnableEmail2FA(c.Request.Context(), userID.(int64)); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "2FA enabled successfully"})
+}
+
+// EnableSMS2FA godoc
+// @Summary Enable SMS-based 2FA
+// @Description Store the phone number and make SMS the authenticated user's two-factor authentication method
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body EnableSMS2FARequest true "Phone number in E.164 format"
+// @Success 200 {object} map[string]string "2FA enabled successfully"
+// @Failure 400 {object} map[string]string "Invalid phone number or SMS delivery not enabled"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/enableSms [post]
+func (h *TwoFAHandler) EnableSMS2FA(c *gin.Context) {
+	// Get userID from JWT token (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req EnableSMS2FARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if err := h.authService.EnableSMS2FA(c.Request.Context(), userID.(int64), req.PhoneNumber); err != nil {
+		WriteError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "2FA enabled successfully"})
 }
 
@@ -76,42 +112,132 @@ func (h *TwoFAHandler) Disable2FA(c *gin.Context) {
 	}
 
 	if err := h.authService.Disable2FA(c.Request.Context(), userID.(int64)); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "2FA disabled successfully"})
 }
 
+// =============================================================================
+// TOTP (Authenticator App) Endpoints (Protected - Require Authentication)
+// =============================================================================
+
+// EnrollTOTP godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a new authenticator-app secret for the authenticated user and return its otpauth:// URI and a QR code PNG (base64). TOTP is enabled after the first successful verification.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TOTPEnrollment "TOTP secret, provisioning URI and QR code"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Failure 503 {object} map[string]string "TOTP not available - encryption key not configured"
+// @Router /2fa/totp/enroll [post]
+func (h *TwoFAHandler) EnrollTOTP(c *gin.Context) {
+	// Get userID from JWT token (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTOTP(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// VerifyTOTP godoc
+// @Summary Verify TOTP code
+// @Description Verify a 6-digit code from the user's authenticator app. A code from the app of a pending enrollment confirms it: TOTP is enabled with the new secret, replacing any previous 2FA method. Each code can only be used once.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body VerifyTOTPRequest true "TOTP code"
+// @Success 200 {object} map[string]string "TOTP code verified successfully"
+// @Failure 400 {object} map[string]string "Invalid, reused, or malformed code, or TOTP not enrolled"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/totp/verify [post]
+func (h *TwoFAHandler) VerifyTOTP(c *gin.Context) {
+	// Get userID from JWT token (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req VerifyTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.VerifyTOTP(c.Request.Context(), userID.(int64), req.Code); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "TOTP code verified successfully"})
+}
+
 // =============================================================================
 // OTP Management Endpoints (Public - Used during login flow)
 // =============================================================================
 
+// VerifyTOTPLogin godoc
+// @Summary Finish a login with an authenticator app
+// @Description Verifies a 6-digit code from the authenticator app of an account whose /auth/login response asked for a second factor without a security_key_challenge, and returns JWT tokens. The two_factor_token can only be used once.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param request body TOTPLoginRequest true "Two-factor token and TOTP code"
+// @Success 200 {object} response.LoginResponse "Login successful"
+// @Failure 400 {object} map[string]string "Invalid, reused, or malformed code, or expired or already used token"
+// @Failure 403 {object} map[string]string "Account is deactivated"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/2fa/totp/verify [post]
+func (h *TwoFAHandler) VerifyTOTPLogin(c *gin.Context) {
+	var req TOTPLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.FinishTOTPLogin(c.Request.Context(), req.TwoFactorToken, req.Code)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // SendOTP godoc
 // @Summary Send 2FA OTP code
-// @Description Send a one-time password to the user's email for two-factor authentication
+// @Description Send a one-time password by email or SMS for two-factor authentication. Without a channel, SMS is used when it is the user's 2FA method.
 // @Tags 2fa
 // @Accept json
 // @Produce json
-// @Param request body SendOTPRequest true "Email address to send OTP"
+// @Param request body SendOTPRequest true "Email address and optional delivery channel"
 // @Success 200 {object} map[string]string "OTP sent successfully"
-// @Failure 400 {object} map[string]string "Invalid email format or user not found"
-// @Failure 500 {object} map[string]string "Failed to send OTP email"
+// @Failure 400 {object} map[string]string "Invalid email format or channel, SMS unavailable, or no phone number on file"
+// @Failure 500 {object} map[string]string "Failed to send OTP"
 // @Router /2fa/sendOtp [post]
 func (h *TwoFAHandler) SendOTP(c *gin.Context) {
-	var req struct {
-		Email string `json:"email" binding:"required,email"`
-	}
-
-	// var req SendOTPRequest
-
+	var req SendOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.authService.Send2FAOTP(c.Request.Context(), req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.authService.Send2FAOTP(c.Request.Context(), req.Email, constants.DeliveryChannel(req.Channel)); err != nil {
+		WriteError(c, err)
 		return
 	}
 
@@ -127,6 +253,7 @@ func (h *TwoFAHandler) SendOTP(c *gin.Context) {
 // @Param request body VerifyOTPRequest true "OTP verification data"
 // @Success 200 {object} map[string]string "OTP verified successfully"
 // @Failure 400 {object} map[string]string "Invalid OTP code, expired code, or invalid email"
+// @Failure 429 {object} map[string]string "Code locked after too many incorrect attempts (see Retry-After)"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /2fa/verifyOtp [post]
 func (h *TwoFAHandler) VerifyOTP(c *gin.Context) {
@@ -143,7 +270,7 @@ func (h *TwoFAHandler) VerifyOTP(c *gin.Context) {
 	}
 
 	if err := h.authService.Verify2FA(c.Request.Context(), req.Email, req.Code); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 
@@ -1,9 +1,18 @@
 package handler
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"authentio/internal/models"
 	"authentio/internal/service"
+	"authentio/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
@@ -50,7 +59,7 @@ func (h *UserHandler) GetProfile(c *gin.Context) {
 
 	profile, err := h.authService.GetUserProfile(c.Request.Context(), userID.(int64))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 
@@ -92,9 +101,391 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	if err := h.authService.UpdateProfile(c.Request.Context(), userID.(int64), req.FirstName, req.LastName, req.Email); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		WriteError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profile updated successfully"})
-}
\ No newline at end of file
+}
+// =============================================================================
+// Current User (/me) Endpoints
+// =============================================================================
+
+// MeResponse is the authenticated user's own profile
+type MeResponse struct {
+	ID    int64  `json:"id" example:"42"`
+	Email string `json:"email" example:"jane.doe@example.com"`
+
+	// Name is FirstName and LastName joined by a space
+	Name      string `json:"name" example:"Jane Doe"`
+	FirstName string `json:"first_name" example:"Jane"`
+	LastName  string `json:"last_name" example:"Doe"`
+
+	AvatarURL       string     `json:"avatar_url,omitempty" example:"https://cdn.example.com/avatars/42.png"`
+	PhoneNumber     string     `json:"phone_number,omitempty" example:"+15551234567"`
+	CreatedAt       time.Time  `json:"created_at" example:"2024-05-01T12:00:00Z"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" example:"2024-05-01T12:05:00Z"`
+}
+
+// newMeResponse converts a user to the /me response
+func newMeResponse(user *models.User) MeResponse {
+	return MeResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Name:            strings.TrimSpace(user.FirstName + " " + user.LastName),
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		AvatarURL:       user.AvatarURL,
+		PhoneNumber:     user.PhoneNumber,
+		CreatedAt:       user.CreatedAt,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	}
+}
+
+// GetMe godoc
+// @Summary Get the current user
+// @Description Return the profile of the user the access token was issued to
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MeResponse
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /me [get]
+func (h *UserHandler) GetMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	user, err := h.authService.GetUser(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newMeResponse(user))
+}
+
+// PatchMe godoc
+// @Summary Update the current user
+// @Description Partially update the profile of the user the access token was issued to. Omitted fields are unchanged; an empty avatar_url or phone_number clears it.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PatchMeRequest true "Fields to change"
+// @Success 200 {object} MeResponse "Updated profile"
+// @Failure 400 {object} map[string]string "Invalid input, avatar URL or phone number"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /me [patch]
+func (h *UserHandler) PatchMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req PatchMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.AvatarURL != nil && *req.AvatarURL != "" && !isHTTPURL(*req.AvatarURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "avatar_url must be an http or https URL"})
+		return
+	}
+
+	user, err := h.authService.PatchProfile(c.Request.Context(), userID.(int64), models.UserPatch{
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		AvatarURL:   req.AvatarURL,
+		PhoneNumber: req.PhoneNumber,
+	})
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newMeResponse(user))
+}
+
+// DeleteMe godoc
+// @Summary Delete the current user
+// @Description Soft-delete the account the access token was issued to and sign it out everywhere. Not allowed with impersonation tokens.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 204 "Account deleted"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} ErrorResponse "Token was issued by impersonation"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /me [delete]
+func (h *UserHandler) DeleteMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Support staff acting as the user must not be able to close their account
+	if _, impersonated := c.Get("impersonatedBy"); impersonated {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "accounts cannot be deleted while impersonating", Code: service.CodeCannotImpersonate})
+		return
+	}
+
+	if err := h.authService.DeleteUser(c.Request.Context(), userID.(int64)); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SessionsResponse lists the active sessions of a user, most recently used first
+type SessionsResponse struct {
+	Sessions []models.Session `json:"sessions"`
+}
+
+// ListMySessions godoc
+// @Summary List the current user's sessions
+// @Description List the devices the user the access token was issued to is logged in on, with the operating system, browser and device type parsed from each User-Agent. The session the request was made with is flagged as current. Revoke sessions with DELETE /auth/sessions/{id}.
+// @Tags user
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} SessionsResponse "Active sessions"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /me/sessions [get]
+func (h *UserHandler) ListMySessions(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	currentID := c.GetString("sessionID")
+	for i := range sessions {
+		sessions[i].Current = currentID != "" && sessions[i].ID == currentID
+	}
+	c.JSON(http.StatusOK, SessionsResponse{Sessions: sessions})
+}
+
+// ExportMe godoc
+// @Summary Export the current user's data
+// @Description Download all personal data stored about the user the access token was issued to (GDPR data portability): profile, roles, sessions, 2FA configuration, WebAuthn credentials and audit log. Secrets are never included. format=zip returns one JSON file per section. Allowed once per 24 hours; not allowed with impersonation tokens.
+// @Tags user
+// @Produce json
+// @Produce application/zip
+// @Security BearerAuth
+// @Param format query string false "json (default) or zip" Enums(json, zip)
+// @Success 200 {object} models.UserExport "Export file (Content-Disposition: attachment)"
+// @Failure 400 {object} map[string]string "Unknown format"
+// @Failure 401 {object} map[string]string "Unauthorized - Invalid or missing JWT token"
+// @Failure 403 {object} ErrorResponse "Token was issued by impersonation"
+// @Failure 429 {object} ErrorResponse "Already exported in the last 24 hours (see Retry-After)"
+// @Router /me/export [get]
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	// Support staff acting as the user must not be able to take their data
+	if _, impersonated := c.Get("impersonatedBy"); impersonated {
+		c.JSON(http.StatusForbidden, ErrorResponse{Error: "data cannot be exported while impersonating", Code: service.CodeCannotImpersonate})
+		return
+	}
+
+	export, err := h.authService.ExportUserData(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("authentio-export-%d-%s.%s", export.Profile.ID, export.ExportedAt.Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Cache-Control", "no-store")
+
+	if format == "json" {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		c.Status(http.StatusOK)
+		encoder := json.NewEncoder(c.Writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			logger.Warn("failed to write data export", "error", err, "userID", export.Profile.ID)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := writeExportZip(c.Writer, export); err != nil {
+		logger.Warn("failed to write data export", "error", err, "userID", export.Profile.ID)
+	}
+}
+
+// writeExportZip streams a data export as a ZIP archive with one JSON file per section
+func writeExportZip(w io.Writer, export *models.UserExport) error {
+	archive := zip.NewWriter(w)
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"profile.json", gin.H{
+			"exported_at":     export.ExportedAt,
+			"profile":         export.Profile,
+			"roles":           export.Roles,
+			"external_groups": export.ExternalGroups,
+		}},
+		{"sessions.json", export.Sessions},
+		{"two_factor.json", export.TwoFactor},
+		{"webauthn_credentials.json", export.Credentials},
+		{"audit_log.json", export.AuditLog},
+	}
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// =============================================================================
+// Password Management Endpoints
+// =============================================================================
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the authenticated user's password. The new password must satisfy the password policy and must not match any recently used password.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string "Password changed successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid input, policy violations, or recently used password"
+// @Failure 401 {object} map[string]string "Unauthorized or incorrect current password"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /user/change-password [post]
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangePassword(c.Request.Context(), userID.(int64), req.CurrentPassword, req.NewPassword); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// =============================================================================
+// Email Change Endpoints
+// =============================================================================
+
+// ChangeEmail godoc
+// @Summary Request an email change
+// @Description Start changing the authenticated user's email. After the password is verified, a one-time code is sent to the new address; the email only changes once the code is confirmed.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangeEmailRequest true "New email and current password"
+// @Success 200 {object} map[string]string "Confirmation code sent"
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 401 {object} map[string]string "Unauthorized or incorrect password"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 502 {object} map[string]string "Confirmation code could not be sent"
+// @Router /user/change-email [post]
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ChangeEmail(c.Request.Context(), userID.(int64), req.NewEmail, req.Password); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Confirmation code sent to the new email address"})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm an email change
+// @Description Confirm a pending email change with the code sent to the new address. The new address becomes the account's verified email, all sessions are signed out, and the old address is notified.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ConfirmEmailChangeRequest true "Confirmation code"
+// @Success 200 {object} map[string]string "Email changed"
+// @Failure 400 {object} map[string]string "Invalid or expired code, or no change pending"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 429 {object} map[string]string "Too many wrong codes"
+// @Router /user/change-email/confirm [post]
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(c.Request.Context(), userID.(int64), req.Code); err != nil {
+		WriteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email changed successfully; please sign in again"})
+}
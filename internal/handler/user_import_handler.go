@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/middleware"
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// =============================================================================
+// Bulk User Import
+// =============================================================================
+
+// userImportHeader is the expected header row of an import file
+var userImportHeader = []string{"email", "name", "role"}
+
+// UserImportResponse reports the outcome of a user import
+type UserImportResponse struct {
+	Results []service.UserImportResult `json:"results"`
+
+	Total   int  `json:"total" example:"120"`
+	Created int  `json:"created" example:"18"`
+	Failed  int  `json:"failed" example:"1"`
+	Skipped int  `json:"skipped" example:"1"`
+	Atomic  bool `json:"atomic" example:"false"`
+}
+
+// ImportUsers godoc
+// @Summary Import users from CSV
+// @Description Create users from an uploaded CSV file with the columns email,name,role (a header row is optional; role defaults to user).
+// @Description Imported users have a verified email and no password. By default every valid row is created on its own;
+// @Description with atomic=true nothing is created unless every row can be.
+// @Tags admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file"
+// @Param atomic query bool false "Create all rows in one transaction, or none"
+// @Success 200 {object} UserImportResponse "Per-row results and counts"
+// @Failure 400 {object} map[string]string "Missing file or malformed CSV"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 413 {object} map[string]string "File larger than MAX_IMPORT_FILE_SIZE_MB"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /admin/users/import [post]
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	atomic := false
+	if raw := c.Query("atomic"); raw != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid atomic: expected true or false"})
+			return
+		}
+	}
+
+	file, err := importFile(c)
+	if err != nil {
+		writeImportReadError(c, err)
+		return
+	}
+
+	rows, invalid, err := parseUserImportCSV(file)
+	if err != nil {
+		writeImportReadError(c, err)
+		return
+	}
+
+	// An atomic import with invalid rows is abandoned before touching the database
+	var results []service.UserImportResult
+	switch {
+	case atomic && len(invalid) > 0:
+		for _, row := range rows {
+			results = append(results, service.UserImportResult{Line: row.Line, Email: row.Email, Status: service.ImportSkipped})
+		}
+	case len(rows) > 0:
+		if results, err = h.authService.ImportUsers(c.Request.Context(), rows, atomic); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import users"})
+			return
+		}
+	}
+	results = mergeImportResults(results, invalid)
+
+	resp := UserImportResponse{Results: results, Total: len(results), Atomic: atomic}
+	for _, result := range results {
+		switch result.Status {
+		case service.ImportCreated:
+			resp.Created++
+		case service.ImportFailed:
+			resp.Failed++
+		case service.ImportSkipped:
+			resp.Skipped++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// importFile returns the "file" part of a multipart upload. The body is streamed,
+// not buffered to disk, so the only size limit is the route's MaxBodySizeMiddleware.
+func importFile(c *gin.Context) (io.Reader, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, errors.New("expected a multipart/form-data upload")
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("missing file")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+	}
+}
+
+// parseUserImportCSV reads import rows from r. Rows that fail validation are
+// returned as failed results instead of rows; an error means the file itself
+// could not be read.
+func parseUserImportCSV(r io.Reader) ([]service.UserImportRow, []service.UserImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []service.UserImportRow
+	var invalid []service.UserImportResult
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if line == 1 && isUserImportHeader(record) {
+			continue
+		}
+
+		row, err := userImportRow(line, record)
+		if err != nil {
+			invalid = append(invalid, service.UserImportResult{Line: line, Email: row.Email, Status: service.ImportFailed, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, invalid, nil
+}
+
+// isUserImportHeader reports whether record is the email,name,role header row
+func isUserImportHeader(record []string) bool {
+	if len(record) > len(userImportHeader) {
+		return false
+	}
+	for i, field := range record {
+		if !strings.EqualFold(strings.TrimSpace(field), userImportHeader[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// userImportRow validates one CSV record, checking the email with the same
+// validator as the binding:"email" tags on JSON requests. The name is split at
+// its first space into a first and last name.
+func userImportRow(line int, record []string) (service.UserImportRow, error) {
+	field := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	row := service.UserImportRow{Line: line, Email: strings.ToLower(field(0))}
+	if len(record) > len(userImportHeader) {
+		return row, errors.New("expected the columns email,name,role")
+	}
+	if err := binding.Validator.Engine().(*validator.Validate).Var(row.Email, "required,email"); err != nil {
+		return row, errors.New("invalid email")
+	}
+
+	name := field(1)
+	if name == "" {
+		return row, errors.New("name is required")
+	}
+	row.FirstName, row.LastName, _ = strings.Cut(name, " ")
+	row.LastName = strings.TrimSpace(row.LastName)
+
+	switch role := constants.Role(strings.ToLower(field(2))); role {
+	case "", constants.RoleUser, constants.RoleAdmin:
+		row.Role = role
+	default:
+		return row, errors.New("invalid role: expected user or admin")
+	}
+	return row, nil
+}
+
+// mergeImportResults combines service results with rows rejected while parsing,
+// in file order.
+func mergeImportResults(results, invalid []service.UserImportResult) []service.UserImportResult {
+	merged := make([]service.UserImportResult, 0, len(results)+len(invalid))
+	i, j := 0, 0
+	for i < len(results) || j < len(invalid) {
+		if j == len(invalid) || (i < len(results) && results[i].Line < invalid[j].Line) {
+			merged = append(merged, results[i])
+			i++
+		} else {
+			merged = append(merged, invalid[j])
+			j++
+		}
+	}
+	return merged
+}
+
+// writeImportReadError responds to an upload that could not be read.
+func writeImportReadError(c *gin.Context, err error) {
+	if middleware.IsBodyTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file too large"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
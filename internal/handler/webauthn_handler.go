@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"authentio/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+)
+
+// =============================================================================
+// WebAuthnHandler Structure and Constructor
+// =============================================================================
+
+// WebAuthnHandler handles passkey (WebAuthn) HTTP requests
+type WebAuthnHandler struct {
+	authService service.AuthService
+}
+
+// NewWebAuthnHandler creates a new WebAuthnHandler instance
+func NewWebAuthnHandler(authService service.AuthService) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		authService: authService,
+	}
+}
+
+// =============================================================================
+// Passkey Registration Endpoints (Protected - Require Authentication)
+// =============================================================================
+
+// BeginWebAuthnRegistration godoc
+// @Summary Start passkey registration
+// @Description Returns the PublicKeyCredentialCreationOptions to pass to navigator.credentials.create()
+// @Tags webauthn
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Credential creation options"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Passkeys are not enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /auth/webauthn/register/begin [post]
+func (h *WebAuthnHandler) BeginWebAuthnRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	creation, err := h.authService.BeginWebAuthnRegistration(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishWebAuthnRegistration godoc
+// @Summary Finish passkey registration
+// @Description Verifies the authenticator's attestation (the JSON-encoded result of navigator.credentials.create()) and stores the passkey
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} map[string]string "Passkey registered"
+// @Failure 400 {object} map[string]string "Malformed or unverifiable response, or expired challenge"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Passkeys are not enabled"
+// @Router /auth/webauthn/register/finish [post]
+func (h *WebAuthnHandler) FinishWebAuthnRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential response"})
+		return
+	}
+
+	if err := h.authService.FinishWebAuthnRegistration(c.Request.Context(), userID.(int64), parsed); err != nil {
+		// The user is signed in: a rejected passkey is a bad request, not a failed login
+		if errors.Is(err, service.ErrWebAuthnVerificationFailed) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error(), Code: service.CodeWebAuthnVerificationFailed})
+			return
+		}
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Passkey registered successfully"})
+}
+
+// =============================================================================
+// Passkey Login Endpoints (Public)
+// =============================================================================
+
+// BeginWebAuthnLogin godoc
+// @Summary Start passkey login
+// @Description Returns the PublicKeyCredentialRequestOptions to pass to navigator.credentials.get()
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param request body WebAuthnLoginBeginRequest true "Account email"
+// @Success 200 {object} map[string]interface{} "Credential request options"
+// @Failure 400 {object} map[string]string "Invalid input or no passkeys registered"
+// @Failure 404 {object} map[string]string "Passkeys are not enabled"
+// @Failure 422 {object} map[string]interface{} "Request body failed schema validation"
+// @Failure 423 {object} map[string]string "Account locked after too many failed attempts"
+// @Router /auth/webauthn/login/begin [post]
+func (h *WebAuthnHandler) BeginWebAuthnLogin(c *gin.Context) {
+	var req WebAuthnLoginBeginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assertion, err := h.authService.BeginWebAuthnLogin(c.Request.Context(), req.Email)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, assertion)
+}
+
+// FinishWebAuthnLogin godoc
+// @Summary Finish passkey login
+// @Description Verifies the authenticator's assertion (the JSON-encoded result of navigator.credentials.get()) and returns JWT tokens
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param email query string true "Account email used to start the login"
+// @Success 200 {object} response.LoginResponse "Login successful"
+// @Failure 400 {object} map[string]string "Malformed response or expired challenge"
+// @Failure 401 {object} map[string]string "Passkey verification failed"
+// @Failure 404 {object} map[string]string "Passkeys are not enabled"
+// @Router /auth/webauthn/login/finish [post]
+func (h *WebAuthnHandler) FinishWebAuthnLogin(c *gin.Context) {
+	email := c.Query("email")
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email query parameter is required"})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential response"})
+		return
+	}
+
+	resp, err := h.authService.FinishWebAuthnLogin(c.Request.Context(), email, parsed)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// =============================================================================
+// Security Key Registration Endpoints (Protected - Require Authentication)
+// =============================================================================
+
+// BeginSecurityKeyRegistration godoc
+// @Summary Start security key registration
+// @Description Returns the PublicKeyCredentialCreationOptions to pass to navigator.credentials.create() to register a hardware security key as second factor
+// @Tags 2fa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Credential creation options"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "WebAuthn is not enabled"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /2fa/security-keys/register/begin [post]
+func (h *WebAuthnHandler) BeginSecurityKeyRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	creation, err := h.authService.BeginSecurityKeyRegistration(c.Request.Context(), userID.(int64))
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishSecurityKeyRegistration godoc
+// @Summary Finish security key registration
+// @Description Verifies the authenticator's attestation (the JSON-encoded result of navigator.credentials.create()) and stores the security key. Later password logins are challenged for it.
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} map[string]string "Security key registered"
+// @Failure 400 {object} map[string]string "Malformed or unverifiable response, or expired challenge"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "WebAuthn is not enabled"
+// @Router /2fa/security-keys/register/finish [post]
+func (h *WebAuthnHandler) FinishSecurityKeyRegistration(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential response"})
+		return
+	}
+
+	if err := h.authService.FinishSecurityKeyRegistration(c.Request.Context(), userID.(int64), parsed); err != nil {
+		// The user is signed in: a rejected key is a bad request, not a failed login
+		if errors.Is(err, service.ErrWebAuthnVerificationFailed) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error(), Code: service.CodeWebAuthnVerificationFailed})
+			return
+		}
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Security key registered successfully"})
+}
+
+// =============================================================================
+// Security Key Login Endpoint (Public)
+// =============================================================================
+
+// VerifySecurityKey godoc
+// @Summary Finish a login with a security key
+// @Description Verifies the security key assertion (the JSON-encoded result of navigator.credentials.get() for the security_key_challenge of /auth/login) and returns JWT tokens
+// @Tags 2fa
+// @Accept json
+// @Produce json
+// @Param token query string true "two_factor_token returned by /auth/login"
+// @Success 200 {object} response.LoginResponse "Login successful"
+// @Failure 400 {object} map[string]string "Malformed response, or expired or already used token"
+// @Failure 401 {object} map[string]string "Security key verification failed"
+// @Failure 404 {object} map[string]string "WebAuthn is not enabled"
+// @Router /auth/2fa/security-key/verify [post]
+func (h *WebAuthnHandler) VerifySecurityKey(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token query parameter is required"})
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid credential response"})
+		return
+	}
+
+	resp, err := h.authService.FinishSecurityKeyLogin(c.Request.Context(), token, parsed)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Webhook Administration Endpoints (Protected - Require Admin Token)
+// =============================================================================
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List the callback URLs that receive auth events, oldest first. Secrets are not returned.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Webhook "Webhooks"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Webhooks are not enabled"
+// @Router /admin/webhooks [get]
+func (h *AdminHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.authService.ListWebhooks(c.Request.Context())
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Register a callback URL that auth events of a tenant (user_registered, user_logged_in, password_changed, two_fa_enabled) are POSTed to as JSON.
+// @Description Each delivery carries X-Webhook-Event with the event type and X-Webhook-Signature: "sha256=" + hex HMAC-SHA256 of the body with the webhook secret.
+// @Description Failed deliveries (network errors, 429, 5xx) are retried twice with exponential backoff.
+// @Description The secret is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WebhookRequest true "Callback URL, event types and optional tenant and secret"
+// @Success 201 {object} models.Webhook "Webhook registered"
+// @Failure 400 {object} map[string]string "Invalid URL or event type"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Webhooks are not enabled"
+// @Router /admin/webhooks [post]
+func (h *AdminHandler) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.authService.CreateWebhook(c.Request.Context(), req.TenantID, req.URL, req.Secret, req.Events)
+	if err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Stop delivering events to a webhook
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Success 204 "Webhook deleted"
+// @Failure 400 {object} map[string]string "Invalid webhook ID"
+// @Failure 401 {object} map[string]string "Invalid or missing admin token"
+// @Failure 404 {object} map[string]string "Webhook not found"
+// @Router /admin/webhooks/{id} [delete]
+func (h *AdminHandler) DeleteWebhook(c *gin.Context) {
+	webhookID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+		return
+	}
+
+	if err := h.authService.DeleteWebhook(c.Request.Context(), webhookID); err != nil {
+		WriteError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
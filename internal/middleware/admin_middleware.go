@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Admin Authentication Middleware
+// =============================================================================
+
+// AdminTokenRequired protects operator-only routes with a static bearer token
+// (ADMIN_API_TOKEN). When no token is configured the admin API is disabled and
+// every request is rejected, so it can never be exposed by accident. Services
+// authenticated with a client certificate (MTLSMiddleware) on the services ACL
+// need no token; other certificates are refused with 403.
+func AdminTokenRequired(token string, services ServiceACL) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity, ok := GetServiceIdentity(c); ok {
+			if !services.Allows(identity) {
+				logger.Warn("rejected admin request from unauthorized service", "service", identity.CommonName, "ous", identity.OrganizationalUnits, "path", c.Request.URL.Path)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "service not authorized for the admin API"})
+				return
+			}
+			logger.Debug("admin request authenticated by client certificate", "service", identity.CommonName, "path", c.Request.URL.Path)
+			c.Next()
+			return
+		}
+
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "admin API is disabled"})
+			return
+		}
+
+		if !hasBearerToken(c, token) {
+			logger.Warn("rejected admin request", "ip", c.ClientIP(), "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hasBearerToken reports whether the request carries "Authorization: Bearer <token>".
+// The comparison is constant-time so the token cannot be guessed byte by byte.
+func hasBearerToken(c *gin.Context, token string) bool {
+	presented, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1
+}
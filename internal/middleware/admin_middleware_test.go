@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminTokenRequiredChecksServiceACL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	acl := ServiceACL{CommonNames: []string{"ops-console"}, OrganizationalUnits: []string{"platform"}}
+
+	tests := []struct {
+		name     string
+		identity *ServiceIdentity
+		bearer   string
+		want     int
+	}{
+		{"allowed CN", &ServiceIdentity{CommonName: "ops-console"}, "", http.StatusOK},
+		{"allowed OU", &ServiceIdentity{CommonName: "billing", OrganizationalUnits: []string{"finance", "platform"}}, "", http.StatusOK},
+		{"unlisted certificate", &ServiceIdentity{CommonName: "billing", OrganizationalUnits: []string{"finance"}}, "", http.StatusForbidden},
+		{"unlisted certificate with token", &ServiceIdentity{CommonName: "billing"}, "admin-token", http.StatusForbidden},
+		{"no certificate, token", nil, "admin-token", http.StatusOK},
+		{"no certificate, no token", nil, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				if tt.identity != nil {
+					c.Set("serviceIdentity", tt.identity)
+				}
+			}, AdminTokenRequired("admin-token", acl), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSCIMTokenRequiredRejectsServiceNotOnACL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", func(c *gin.Context) {
+		c.Set("serviceIdentity", &ServiceIdentity{CommonName: "ops-console"})
+	}, SCIMTokenRequired("scim-token", ServiceACL{OrganizationalUnits: []string{"identity-sync"}}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("got %d, want 403", w.Code)
+	}
+}
@@ -1,12 +1,14 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"authentio/internal/repository"
 	"authentio/pkg/jwt"
 	"authentio/pkg/logger"
 
@@ -85,21 +87,61 @@ func loadCountries(envVar string) map[string]bool {
 // Authentication Middleware
 // =============================================================================
 
+// SessionChecker reports whether a login session is still active. It is
+// implemented by repository.TokenRepository.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// SessionSlider extends a login session its access tokens are being used for,
+// so active users stay logged in. It is implemented by service.AuthService.
+type SessionSlider interface {
+	SlideSession(ctx context.Context, sessionID string) error
+}
+
+// ClaimsCache caches the claims of access tokens that passed validation, so
+// their signature and session are not checked again on every request. It is
+// implemented by service.AuthService.
+type ClaimsCache interface {
+	CachedClaims(ctx context.Context, token string) (jwt.Claims, bool)
+	CacheClaims(ctx context.Context, token string, claims jwt.Claims)
+}
+
+// ExternalTokenAuthenticator maps an access token issued by an external
+// identity provider to the claims of a local user. It is implemented by
+// service.AuthService when EXTERNAL_JWKS_URI is set.
+type ExternalTokenAuthenticator interface {
+	AuthenticateExternalToken(ctx context.Context, token string) (jwt.Claims, error)
+}
+
 // AuthRequired creates a Gin middleware that validates JWT tokens and enforces
 // geographical access restrictions. This is the main authentication guard for protected routes.
 //
 // Features:
 // - JWT token validation
+// - Tenant isolation (tenant_id claim must match the request's tenant)
+// - Optional session validation (rejects tokens whose session was revoked)
+// - Optional claims cache (skips signature and session checks of recently validated tokens)
+// - Optional sliding sessions (extends the session of every accepted token)
 // - GeoIP-based access control
 // - Request context enrichment with user and location data
 // - Security monitoring for suspicious locations
 //
 // Parameters:
 //   - jwtManager: JWT manager instance for token verification
+//   - sessions: optional session checker; when set, every token must carry the
+//     session_id claim of an active session
+//   - external: optional; tokens that are not ours are tried as external
+//     identity provider tokens. They have no session and skip session validation
+//   - slider: optional; when set, the session of every accepted token is passed
+//     to it. Failures are logged and do not fail the request
+//   - claimsCache: optional; tokens found in it skip signature verification and
+//     session validation, but are still checked against the revocation list.
+//     Tokens that pass both are added to it
 //
 // Returns:
 //   - gin.HandlerFunc: Authentication middleware function
-func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
+func AuthRequired(jwtManager *jwt.Manager, sessions SessionChecker, external ExternalTokenAuthenticator, slider SessionSlider, claimsCache ClaimsCache) gin.HandlerFunc {
 	httpClient := &http.Client{Timeout: 3 * time.Second} // GeoIP API client with timeout
 	
 	return func(c *gin.Context) {
@@ -112,9 +154,9 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 			return
 		}
 
-		// Parse Bearer token format: "Bearer <token>"
+		// Parse "Bearer <token>", or "DPoP <token>" for DPoP-bound tokens (see DPoP)
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "DPoP") {
 			logger.Debug("invalid authorization header format")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization format"})
 			c.Abort()
@@ -123,8 +165,25 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 
 		token := parts[1]
 		
-		// Verify JWT token signature and expiration
-		claims, err := jwtManager.VerifyToken(token)
+		// Verify JWT token signature and expiration, falling back to the external
+		// identity provider for tokens we did not issue. Claims cached after an
+		// earlier validation are used as they are
+		var claims jwt.Claims
+		var err error
+		isExternal, isCached := false, false
+		if claimsCache != nil {
+			claims, isCached = claimsCache.CachedClaims(c.Request.Context(), token)
+		}
+		if !isCached {
+			var verified map[string]any
+			verified, err = jwtManager.VerifyToken(token)
+			if err == nil {
+				claims = jwt.Claims(verified)
+			} else if external != nil {
+				claims, err = external.AuthenticateExternalToken(c.Request.Context(), token)
+				isExternal = err == nil
+			}
+		}
 		if err != nil {
 			logger.Debug("invalid token", zap.Error(err))
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -132,6 +191,87 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 			return
 		}
 
+		// Tokens issued to third-party OAuth clients (/oauth/token) are meant for the
+		// resource servers that honor their scope, never for this API
+		if _, isClientToken := claims["client_id"]; isClientToken && !isExternal {
+			logger.Debug("oauth client token rejected")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.Abort()
+			return
+		}
+
+		// Reject tokens that were revoked before their natural expiry (e.g. logout),
+		// individually or by an admin revoking every token issued before a cutoff
+		jti, _ := claims["jti"].(string)
+		revoked, err := jwtManager.IsRevoked(c.Request.Context(), jti)
+		if err == nil && !revoked {
+			revoked, err = jwtManager.IssuedBeforeRevocation(c.Request.Context(), claims)
+		}
+		if err != nil {
+			if jwtManager.StrictRevocation() {
+				logger.Error("token revocation check failed", zap.Error(err))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "unable to verify token status"})
+				c.Abort()
+				return
+			}
+			logger.Warn("token revocation check failed, allowing request", zap.Error(err))
+		} else if revoked {
+			logger.Debug("revoked token used", zap.String("jti", jti))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Tokens are only valid for the tenant they were issued for. A tenant token used
+		// without X-Tenant-ID or a tenant subdomain scopes the request to its tenant.
+		tokenTenant, hasTenant := claims["tenant_id"].(float64)
+		if requestTenant, scoped := repository.TenantIDFromContext(c.Request.Context()); scoped {
+			if !hasTenant || int64(tokenTenant) != requestTenant {
+				logger.Debug("token used for another tenant", zap.Int64("tenantID", requestTenant))
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "token is not valid for this tenant"})
+				c.Abort()
+				return
+			}
+		} else if hasTenant {
+			c.Set("tenantID", int64(tokenTenant))
+			c.Request = c.Request.WithContext(repository.WithTenantID(c.Request.Context(), int64(tokenTenant)))
+		}
+
+		// Reject tokens whose session was logged out or revoked; cached claims
+		// belong to a session that was active when they were cached
+		sessionID, _ := claims["session_id"].(string)
+		if sessions != nil && !isExternal && !isCached {
+			if sessionID == "" {
+				logger.Debug("token without session_id rejected")
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+				c.Abort()
+				return
+			}
+			active, err := sessions.IsSessionActive(c.Request.Context(), sessionID)
+			if err != nil {
+				logger.Error("session validation failed", zap.Error(err))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "unable to verify session status"})
+				c.Abort()
+				return
+			}
+			if !active {
+				logger.Debug("revoked session used", zap.String("sessionID", sessionID))
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+		if claimsCache != nil && !isExternal && !isCached {
+			claimsCache.CacheClaims(c.Request.Context(), token, claims)
+		}
+
+		// Keep the session of an active user from expiring
+		if slider != nil && !isExternal && sessionID != "" {
+			if err := slider.SlideSession(c.Request.Context(), sessionID); err != nil {
+				logger.Warn("failed to extend session", zap.Error(err), zap.String("sessionID", sessionID))
+			}
+		}
+
 		// Extract user information from token claims
 		userID, ok := claims["user_id"].(float64)
 		if !ok {
@@ -145,6 +285,15 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		firstName, _ := claims["first_name"].(string)
 		lastName, _ := claims["last_name"].(string)
 		fullName, _ := claims["name"].(string)
+		role, _ := claims["role"].(string)
+		var permissions []string
+		if list, ok := claims["permissions"].([]interface{}); ok {
+			for _, p := range list {
+				if name, ok := p.(string); ok {
+					permissions = append(permissions, name)
+				}
+			}
+		}
 
 		// Perform GeoIP lookup for geographical restrictions
 		countryCode, countryName := getGeoIPInfo(c, httpClient)
@@ -169,6 +318,17 @@ func AuthRequired(jwtManager *jwt.Manager) gin.HandlerFunc {
 		c.Set("firstName", firstName)
 		c.Set("lastName", lastName)
 		c.Set("fullName", fullName)
+		c.Set("jti", jti)
+		c.Set("sessionID", sessionID)
+		c.Set("role", role)
+		c.Set("permissions", permissions)
+		c.Set("claims", claims) // all claims, for custom claims read with jwt.ExtractClaim
+		if adminID, ok := claims["impersonated_by"].(float64); ok {
+			c.Set("impersonatedBy", int64(adminID))
+		}
+		if isExternal {
+			c.Set("externalToken", true)
+		}
 		c.Set("country", countryCode)
 		c.Set("countryName", countryName)
 		c.Set("clientIP", c.ClientIP())
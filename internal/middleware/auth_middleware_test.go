@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"authentio/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// fakeSessions is a SessionChecker counting its lookups
+type fakeSessions struct {
+	mu      sync.Mutex
+	active  bool
+	lookups int
+}
+
+func (f *fakeSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	return f.active, nil
+}
+
+// fakeClaimsCache is an in-memory ClaimsCache
+type fakeClaimsCache struct {
+	mu     sync.Mutex
+	claims map[string]jwt.Claims
+}
+
+func (f *fakeClaimsCache) CachedClaims(ctx context.Context, token string) (jwt.Claims, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	claims, ok := f.claims[token]
+	return claims, ok
+}
+
+func (f *fakeClaimsCache) CacheClaims(ctx context.Context, token string, claims jwt.Claims) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.claims[token] = claims
+}
+
+func (f *fakeClaimsCache) evict(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.claims, token)
+}
+
+func newClaimsCacheEngine(t *testing.T) (*gin.Engine, *jwt.Manager, *fakeSessions, *fakeClaimsCache) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	manager := jwt.NewManager("auth-middleware-test-secret-32-bytes").WithRevocationStore(rdb, true)
+	sessions := &fakeSessions{active: true}
+	cache := &fakeClaimsCache{claims: map[string]jwt.Claims{}}
+
+	r := gin.New()
+	r.GET("/", AuthRequired(manager, sessions, nil, nil, cache), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, manager, sessions, cache
+}
+
+func serveWithToken(r *gin.Engine, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "127.0.0.1:4000" // no GeoIP lookup
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthRequiredCachesValidatedClaims(t *testing.T) {
+	r, manager, sessions, cache := newClaimsCacheEngine(t)
+	token, err := manager.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 3 {
+		if code := serveWithToken(r, token); code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, code)
+		}
+	}
+	if sessions.lookups != 1 {
+		t.Fatalf("session looked up %d times for 3 requests, want once", sessions.lookups)
+	}
+	if claims, ok := cache.CachedClaims(context.Background(), token); !ok || claims["session_id"] != "session-1" {
+		t.Fatalf("cached claims %v, %v, want the token's claims", claims, ok)
+	}
+}
+
+func TestAuthRequiredRejectsRevokedSessionOnceEvicted(t *testing.T) {
+	r, manager, sessions, cache := newClaimsCacheEngine(t)
+	token, err := manager.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := serveWithToken(r, token); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+
+	// Revoking the session evicts its tokens' claims, so the session is checked again
+	sessions.active = false
+	cache.evict(token)
+	if code := serveWithToken(r, token); code != http.StatusUnauthorized {
+		t.Fatalf("token of a revoked session: status %d, want 401", code)
+	}
+	if _, ok := cache.CachedClaims(context.Background(), token); ok {
+		t.Fatal("claims of a revoked session were cached")
+	}
+}
+
+func TestAuthRequiredChecksRevocationOfCachedClaims(t *testing.T) {
+	r, manager, _, cache := newClaimsCacheEngine(t)
+	token, err := manager.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := serveWithToken(r, token); code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+
+	claims, ok := cache.CachedClaims(context.Background(), token)
+	if !ok {
+		t.Fatal("claims not cached")
+	}
+	jti, _ := claims["jti"].(string)
+	if err := manager.RevokeToken(context.Background(), jti, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if code := serveWithToken(r, token); code != http.StatusUnauthorized {
+		t.Fatalf("revoked token with cached claims: status %d, want 401", code)
+	}
+}
+
+func TestAuthRequiredDoesNotCacheInvalidTokens(t *testing.T) {
+	r, _, sessions, cache := newClaimsCacheEngine(t)
+
+	if code := serveWithToken(r, "not-a-jwt"); code != http.StatusUnauthorized {
+		t.Fatalf("status %d, want 401", code)
+	}
+	if len(cache.claims) != 0 || sessions.lookups != 0 {
+		t.Fatalf("invalid token cached (%d entries) or its session looked up (%d)", len(cache.claims), sessions.lookups)
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"authentio/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientInfoMiddleware attaches the client's User-Agent and IP to the request
+// context so services can record them (e.g. on sessions) without importing Gin.
+func ClientInfoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := models.WithClientInfo(c.Request.Context(), models.ClientInfo{
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
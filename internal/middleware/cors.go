@@ -1,9 +1,10 @@
 package middleware
 
 import (
-	"os"
-	"strings"
+	"slices"
+	"time"
 
+	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
@@ -11,6 +12,20 @@ import (
 // CORS Middleware
 // =============================================================================
 
+// CORSConfig configures Cross-Origin Resource Sharing.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins (scheme://host[:port]) allowed to call the
+	// API from a browser; ["*"] allows any origin
+	AllowedOrigins []string
+
+	// AllowCredentials lets browsers send cookies and Authorization headers on
+	// cross-origin requests. The CORS spec forbids it together with "*".
+	AllowCredentials bool
+
+	// MaxAge is how long (in seconds) browsers may cache a preflight response
+	MaxAge int
+}
+
 // CORSMiddleware creates a Gin middleware that handles Cross-Origin Resource Sharing (CORS).
 // This middleware enables secure cross-origin requests for web applications.
 //
@@ -18,133 +33,66 @@ import (
 // from another domain outside the domain from which the first resource was served.
 //
 // Features:
-// - Configurable allowed origins (environment variable support)
-// - Preflight request handling
-// - Secure headers for credentialed requests
-// - Flexible HTTP methods and headers
+// - Configurable allowed origins, credentials and preflight cache duration
+// - Preflight (OPTIONS) requests are answered with 204 and never reach handlers
+// - Disallowed origins receive no CORS headers, so browsers block the response
+//
+// The configuration must be valid (see config.Validate): cors.New panics on origins
+// without a scheme and on credentials combined with a wildcard origin.
+//
+// Parameters:
+//   - cfg: Allowed origins, credentials flag and preflight max age
 //
 // Returns:
 //   - gin.HandlerFunc: CORS middleware function
-func CORSMiddleware() gin.HandlerFunc {
-	// Get allowed origins from environment variable or default to all
-	allowedOrigins := getCORSAllowedOrigins()
-
-	return func(c *gin.Context) {
-		origin := c.Request.Header.Get("Origin")
-		
-		// Set Access-Control-Allow-Origin header
-		// In production, you should specify exact domains instead of "*" for security
-		if isOriginAllowed(origin, allowedOrigins) {
-			c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
-		} else {
-			// Fallback to wildcard if no specific origins configured
-			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		}
+func CORSMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		// Define which HTTP methods are allowed for cross-origin requests
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
 
-		// Allow credentials (cookies, authorization headers) in cross-origin requests
-		// Important: Cannot use wildcard "*" origin when credentials are allowed
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		
 		// Define which headers are allowed in actual requests
-		c.Writer.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{
+		AllowHeaders: []string{
 			"Content-Type",
 			"Content-Length",
 			"Accept-Encoding",
 			"X-CSRF-Token",
 			"Authorization",
-			"accept",
-			"origin",
+			"Accept",
+			"Origin",
 			"Cache-Control",
 			"X-Requested-With",
-			"X-API-Key",           // Custom API key header
-			"X-Client-Version",    // Client version header
-			"X-Request-ID",        // Request tracing
-		}, ", "))
-
-		// Define which HTTP methods are allowed for cross-origin requests
-		c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join([]string{
-			"POST",
-			"OPTIONS",
-			"GET",
-			"PUT",
-			"DELETE",
-			"PATCH",              // Added for partial updates
-			"HEAD",               // Added for header-only requests
-		}, ", "))
+			"X-API-Key",        // Custom API key header
+			"X-Client-Version", // Client version header
+			"X-Request-ID",     // Request tracing
+			"X-Correlation-ID", // Correlation ID propagated across services
+			"X-Tenant-ID",      // Tenant selection (multi-tenancy)
+			"DPoP",             // DPoP proof (RFC 9449)
+			"Idempotency-Key",  // Safe retries of registration and password reset
+		},
 
 		// Define which response headers can be exposed to the client
-		c.Writer.Header().Set("Access-Control-Expose-Headers", strings.Join([]string{
+		ExposeHeaders: []string{
 			"Content-Length",
 			"Content-Type",
 			"X-Request-ID",
+			"X-Correlation-ID",
 			"X-RateLimit-Limit",
 			"X-RateLimit-Remaining",
 			"X-RateLimit-Reset",
-		}, ", "))
-
-		// Handle preflight requests (OPTIONS)
-		// Preflight requests are sent by browsers before actual requests to check CORS permissions
-		if c.Request.Method == "OPTIONS" {
-			// Set cache duration for preflight responses (in seconds)
-			// Browsers will cache the preflight response for this duration
-			c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
-			
-			// Return 204 No Content for successful preflight requests
-			c.AbortWithStatus(204)
-			return
-		}
+			"WWW-Authenticate",
+			"Idempotency-Key-Replayed",
+			"X-CSRF-Token", // CSRF token for cookie-authenticated requests
+		},
 
-		// Continue to next middleware/handler for actual requests
-		c.Next()
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           time.Duration(cfg.MaxAge) * time.Second,
 	}
-}
 
-// =============================================================================
-// CORS Configuration Helpers
-// =============================================================================
-
-// getCORSAllowedOrigins retrieves and parses allowed origins from environment variable
-// Format: "https://app.example.com,https://admin.example.com,http://localhost:3000"
-//
-// Returns:
-//   - []string: List of allowed origins, empty slice means all origins allowed
-func getCORSAllowedOrigins() []string {
-	originsEnv := os.Getenv("CORS_ALLOWED_ORIGINS")
-	if originsEnv == "" {
-		return []string{} // Empty means allow all origins
+	if slices.Contains(cfg.AllowedOrigins, "*") {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.AllowedOrigins
 	}
 
-	origins := strings.Split(originsEnv, ",")
-	allowedOrigins := make([]string, 0, len(origins))
-	
-	for _, origin := range origins {
-		trimmedOrigin := strings.TrimSpace(origin)
-		if trimmedOrigin != "" {
-			allowedOrigins = append(allowedOrigins, trimmedOrigin)
-		}
-	}
-
-	return allowedOrigins
+	return cors.New(corsConfig)
 }
-
-// isOriginAllowed checks if a request origin is in the list of allowed origins
-//
-// Parameters:
-//   - origin: The Origin header value from the request
-//   - allowedOrigins: List of allowed origins from configuration
-//
-// Returns:
-//   - bool: True if origin is allowed, false otherwise
-func isOriginAllowed(origin string, allowedOrigins []string) bool {
-	if len(allowedOrigins) == 0 {
-		return true // Allow all origins if none specified
-	}
-
-	for _, allowedOrigin := range allowedOrigins {
-		if origin == allowedOrigin {
-			return true
-		}
-	}
-
-	return false
-}
\ No newline at end of file
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// DPoP Middleware (RFC 9449)
+// =============================================================================
+
+// DPoPHeader carries the DPoP proof of a request
+const DPoPHeader = "DPoP"
+
+// dpopConfirmedKey is set in the Gin context (c.Get) to whether the request
+// proved possession of the key its access token is bound to
+const dpopConfirmedKey = "dpop_confirmed"
+
+// DPoP enforces token binding on routes behind AuthRequired. Access tokens
+// with a cnf.jkt claim must be sent with the DPoP authorization scheme and a
+// DPoP proof for the request signed by the bound key; plain bearer tokens pass
+// through. dpop_confirmed is set in the Gin context either way.
+func DPoP(jwtManager *jwt.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+
+		claims, _ := c.Get("claims")
+		tokenClaims, _ := claims.(jwt.Claims)
+		if _, bound := tokenClaims["cnf"]; !bound {
+			c.Set(dpopConfirmedKey, false)
+			c.Next()
+			return
+		}
+
+		// A bound token sent as a bearer token is what a thief would do
+		if scheme != "DPoP" {
+			rejectDPoP(c, http.StatusUnauthorized, jwt.ErrDPoPProofMissing)
+			return
+		}
+
+		err := jwtManager.VerifyDPoP(token, c.GetHeader(DPoPHeader), c.Request.Method, requestURL(c))
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, jwt.ErrDPoPStoreUnavailable) {
+				status = http.StatusServiceUnavailable
+			}
+			rejectDPoP(c, status, err)
+			return
+		}
+
+		c.Set(dpopConfirmedKey, true)
+		c.Next()
+	}
+}
+
+// DPoPBinding binds the access tokens issued by a token endpoint (login,
+// refresh) to the client's key when the request carries a DPoP proof: the
+// proof is verified and its key thumbprint added to the request context with
+// jwt.WithDPoPKey. Requests without a proof get plain bearer tokens.
+func DPoPBinding(jwtManager *jwt.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		proof := c.GetHeader(DPoPHeader)
+		if proof == "" {
+			c.Set(dpopConfirmedKey, false)
+			c.Next()
+			return
+		}
+
+		jkt, err := jwtManager.DPoPThumbprint(proof, c.Request.Method, requestURL(c))
+		if err != nil {
+			// Token endpoints answer invalid proofs with 400 (RFC 9449 section 5)
+			status := http.StatusBadRequest
+			if errors.Is(err, jwt.ErrDPoPStoreUnavailable) {
+				status = http.StatusServiceUnavailable
+			}
+			logger.Debug("DPoP proof rejected", zap.Error(err))
+			c.AbortWithStatusJSON(status, gin.H{"error": "invalid_dpop_proof", "error_description": err.Error()})
+			return
+		}
+
+		c.Set(dpopConfirmedKey, true)
+		c.Request = c.Request.WithContext(jwt.WithDPoPKey(c.Request.Context(), jkt))
+		c.Next()
+	}
+}
+
+// rejectDPoP answers a request whose proof failed verification with the DPoP
+// challenge of RFC 9449 section 7.1.
+func rejectDPoP(c *gin.Context, status int, err error) {
+	logger.Debug("DPoP proof rejected", zap.Error(err))
+	if status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof", algs="RS256 PS256 ES256"`)
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": "invalid_dpop_proof", "error_description": err.Error()})
+}
+
+// requestURL returns the URI the client sent the request to, without query:
+// the htu a DPoP proof must name. Behind a TLS-terminating proxy the scheme is
+// taken from X-Forwarded-Proto.
+func requestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	} else if proto, _, _ := strings.Cut(c.GetHeader("X-Forwarded-Proto"), ","); strings.TrimSpace(proto) == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.EscapedPath()
+}
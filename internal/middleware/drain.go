@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// In-Flight Request Draining
+// =============================================================================
+
+// InFlight counts the requests being handled, so shutdown can wait for them
+// after http.Server.Shutdown has returned. Shutdown stops waiting for requests
+// on hijacked connections and gives up on the rest once its context expires;
+// InFlight still sees those requests until their handlers return.
+type InFlight struct {
+	mu       sync.RWMutex // guards draining against Middleware racing Drain
+	draining bool
+	wg       sync.WaitGroup
+	active   atomic.Int64
+}
+
+// NewInFlight creates an InFlight counter; mount its Middleware first.
+func NewInFlight() *InFlight {
+	return &InFlight{}
+}
+
+// Middleware counts every request from start to completion. Once Drain has
+// been called, new requests are rejected with 503 and Connection: close, so
+// clients retry them on another instance.
+//
+// Returns:
+//   - gin.HandlerFunc: Request counting middleware function
+func (f *InFlight) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f.mu.RLock()
+		if f.draining {
+			f.mu.RUnlock()
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "server is shutting down"})
+			return
+		}
+		f.wg.Add(1)
+		f.active.Add(1)
+		f.mu.RUnlock()
+
+		defer func() {
+			f.active.Add(-1)
+			f.wg.Done()
+		}()
+		c.Next()
+	}
+}
+
+// Drain stops accepting requests and waits until the ones in flight complete
+// or ctx is done. It returns the number of requests still running, zero when
+// every request drained.
+func (f *InFlight) Drain(ctx context.Context) int64 {
+	f.mu.Lock()
+	f.draining = true
+	f.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return 0
+	case <-ctx.Done():
+		return f.active.Load()
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Email Webhook Authentication Middleware
+// =============================================================================
+
+// EmailWebhookTokenRequired protects the email provider webhooks with a static
+// secret (EMAIL_WEBHOOK_SECRET). Providers that cannot send an Authorization
+// header, such as Mailgun, pass it in the token query parameter of the webhook
+// URL instead. When no secret is configured the webhooks are disabled.
+func EmailWebhookTokenRequired(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "email webhooks are disabled"})
+			return
+		}
+
+		if !hasBearerToken(c, token) && subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) != 1 {
+			logger.Warn("rejected email webhook", "ip", c.ClientIP(), "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
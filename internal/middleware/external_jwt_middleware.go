@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Externally Managed Identities (Federation)
+// =============================================================================
+
+// ExternalProvider is the provider of local users created for subjects of an
+// external identity provider; their provider ID is the token's sub claim
+const ExternalProvider = "external"
+
+// externalEmailDomain is the domain of the placeholder email given to external
+// subjects whose token has no email claim. .invalid never resolves (RFC 2606),
+// so nothing is ever delivered to it.
+const externalEmailDomain = "external.invalid"
+
+// unsafeEmailChars are replaced in a subject used as the local part of a placeholder email
+var unsafeEmailChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ExternalUserProvisioner finds or creates the local user of an external
+// subject. It is implemented by repository.UserRepository.
+type ExternalUserProvisioner interface {
+	FindOrCreate(ctx context.Context, user *models.User) (*models.User, bool, error)
+}
+
+// PermissionLister lists the permissions granted by a user's roles. It is
+// implemented by repository.RoleRepository.
+type PermissionLister interface {
+	PermissionsForUser(ctx context.Context, userID int64) ([]string, error)
+}
+
+// ExternalJWTMiddleware authenticates requests with tokens of an external
+// identity provider (Clerk, Firebase, ...) verified by verifier, for deployments
+// that leave authentication to that provider and use Authentio for
+// authorization only. The token's sub claim identifies a local user with
+// provider ExternalProvider, which is created on its first request, so roles,
+// permissions and everything else keyed by user ID work as for local users.
+//
+// New users get the token's email, or a placeholder at external.invalid
+// without one, and its name claims; their email is verified when the token
+// says so (email_verified). A new subject whose email belongs to another user
+// is rejected rather than merged into that account.
+//
+// It sets the same context keys as AuthRequired, with claims holding the
+// external token's claims and externalToken true. The permissions, which local
+// tokens carry as a claim, are looked up with roles on every request; nil
+// grants none. Local tokens are not accepted.
+func ExternalJWTMiddleware(verifier jwt.Verifier, users ExternalUserProvisioner, roles PermissionLister) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || scheme != "Bearer" || token == "" {
+			logger.Debug("missing or invalid authorization header")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization required"})
+			c.Abort()
+			return
+		}
+
+		verified, err := verifier.Verify(token)
+		if err != nil {
+			logger.Debug("invalid external token", zap.Error(err))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			c.Abort()
+			return
+		}
+		claims := *verified
+
+		subject, _ := claims["sub"].(string)
+		if subject == "" {
+			logger.Debug("external token without sub claim")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+			c.Abort()
+			return
+		}
+
+		user, created, err := users.FindOrCreate(c.Request.Context(), externalUser(subject, claims))
+		if err != nil {
+			// Most likely the email is already taken by a local account
+			logger.Warn("failed to provision external user", zap.Error(err), zap.String("subject", subject))
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "external identity cannot be mapped to a user"})
+			c.Abort()
+			return
+		}
+		if created {
+			logger.Info("external user provisioned", zap.Int64("userID", user.ID), zap.String("subject", subject))
+		}
+		if !user.IsActive {
+			logger.Debug("deactivated external user", zap.Int64("userID", user.ID))
+			c.JSON(http.StatusForbidden, gin.H{"error": "account is deactivated"})
+			c.Abort()
+			return
+		}
+
+		var permissions []string
+		if roles != nil {
+			permissions, err = roles.PermissionsForUser(c.Request.Context(), user.ID)
+			if err != nil {
+				logger.Error("failed to load permissions of external user", zap.Error(err), zap.Int64("userID", user.ID))
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "unable to load permissions"})
+				c.Abort()
+				return
+			}
+		}
+
+		jti, _ := claims["jti"].(string)
+		c.Set("userID", user.ID)
+		c.Set("email", user.Email)
+		c.Set("firstName", user.FirstName)
+		c.Set("lastName", user.LastName)
+		c.Set("fullName", strings.TrimSpace(user.FirstName+" "+user.LastName))
+		c.Set("jti", jti)
+		c.Set("sessionID", "")
+		c.Set("role", string(user.Role))
+		c.Set("permissions", permissions)
+		c.Set("claims", jwt.Claims(claims))
+		c.Set("externalToken", true)
+		c.Set("clientIP", c.ClientIP())
+		if user.TenantID != nil {
+			c.Set("tenantID", *user.TenantID)
+		}
+
+		c.Next()
+	}
+}
+
+// externalUser returns the local user to create for an external subject
+func externalUser(subject string, claims jwt.Claims) *models.User {
+	now := time.Now()
+	user := &models.User{
+		Provider:   ExternalProvider,
+		ProviderID: subject,
+		IsActive:   true,
+		Role:       constants.RoleUser,
+		BaseModel:  models.BaseModel{CreatedAt: now, UpdatedAt: now},
+	}
+
+	user.Email, _ = claims["email"].(string)
+	user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+	if user.Email == "" {
+		user.Email = unsafeEmailChars.ReplaceAllString(subject, "-") + "@" + externalEmailDomain
+	} else if verified, _ := claims["email_verified"].(bool); verified || claims["email_verified"] == "true" {
+		user.EmailVerifiedAt = &now
+	}
+
+	user.FirstName, _ = claims["given_name"].(string)
+	user.LastName, _ = claims["family_name"].(string)
+	if user.FirstName == "" && user.LastName == "" {
+		name, _ := claims["name"].(string)
+		user.FirstName, user.LastName, _ = strings.Cut(strings.TrimSpace(name), " ")
+	}
+	return user
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Idempotency Keys
+// =============================================================================
+
+const (
+	// IdempotencyKeyHeader carries the client-chosen key identifying a request
+	// across retries
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotencyReplayedHeader is set to "true" on responses replayed from Redis
+	IdempotencyReplayedHeader = "Idempotency-Key-Replayed"
+
+	// idempotencyKeyPrefix namespaces stored responses: idem:<method>:<key>
+	idempotencyKeyPrefix = "idem:"
+
+	// idempotencyMaxKeyLen bounds the keys clients may send
+	idempotencyMaxKeyLen = 255
+
+	// idempotencyLockTTL is how long a request in flight holds its key; a
+	// request that never completes (crashed instance) frees it after this long
+	idempotencyLockTTL = 30 * time.Second
+)
+
+// idempotencyRecord is the value stored under an idempotency key. Status is
+// zero while the first request is still being handled.
+type idempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// IdempotencyMiddleware makes retried requests safe: the response to a request
+// sent with an Idempotency-Key header is stored in Redis for ttl, and later
+// requests with the same method and key get that response back, with an
+// Idempotency-Key-Replayed: true header, instead of running the handler again.
+//
+// A key is bound to the route, tenant and body of its first request; reusing
+// it for a different request is rejected with 422. A duplicate sent while the
+// first request is still in flight is rejected with 409. Server errors (5xx)
+// are not stored, so the client can retry them with the same key.
+//
+// Requests without the header are handled normally. If Redis is unavailable
+// requests are handled without idempotency rather than rejected. Run
+// MaxBodySizeMiddleware first to bound the buffered body.
+//
+// Parameters:
+//   - rdb: Redis client storing the responses
+//   - ttl: How long a response is replayed for
+//
+// Returns:
+//   - gin.HandlerFunc: Idempotency middleware function
+func IdempotencyMiddleware(rdb *redis.Client, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idemKey := c.GetHeader(IdempotencyKeyHeader)
+		if idemKey == "" || rdb == nil || ttl <= 0 {
+			c.Next()
+			return
+		}
+		if len(idemKey) > idempotencyMaxKeyLen {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most " + strconv.Itoa(idempotencyMaxKeyLen) + " characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			if IsBodyTooLarge(err) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := idempotencyKeyPrefix + c.Request.Method + ":" + idemKey
+		fingerprint := requestFingerprint(c, body)
+
+		pending, _ := json.Marshal(idempotencyRecord{Fingerprint: fingerprint})
+		acquired, err := rdb.SetNX(ctx, key, pending, idempotencyLockTTL).Result()
+		if err != nil {
+			logger.Warn("idempotency store unavailable, handling request without it", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			replayIdempotent(c, rdb, key, fingerprint)
+			return
+		}
+
+		writer := &responseCaptureWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+
+		// Request contexts are canceled once the client goes away; the outcome
+		// must still be stored (or the key released) for its retry
+		storeCtx := context.WithoutCancel(ctx)
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			if err := rdb.Del(storeCtx, key).Err(); err != nil {
+				logger.Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+
+		record, _ := json.Marshal(idempotencyRecord{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		})
+		if err := rdb.Set(storeCtx, key, record, ttl).Err(); err != nil {
+			logger.Warn("failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// replayIdempotent answers a request whose key is already taken: with the
+// stored response, or with an error when the key belongs to another request
+// or the first request has not finished.
+func replayIdempotent(c *gin.Context, rdb *redis.Client, key, fingerprint string) {
+	data, err := rdb.Get(c.Request.Context(), key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// The first request failed and released the key in the meantime
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is being processed; retry later"})
+		return
+	}
+	if err != nil {
+		logger.Warn("failed to load idempotent response", zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "idempotency store unavailable"})
+		return
+	}
+
+	var record idempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		logger.Warn("malformed idempotent response", zap.Error(err))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "idempotency store unavailable"})
+		return
+	}
+
+	switch {
+	case record.Fingerprint != fingerprint:
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+	case record.Status == 0:
+		c.Header("Retry-After", strconv.Itoa(int(idempotencyLockTTL.Seconds())))
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is being processed; retry later"})
+	default:
+		c.Header(IdempotencyReplayedHeader, "true")
+		c.Data(record.Status, record.ContentType, record.Body)
+		c.Abort()
+	}
+}
+
+// requestFingerprint identifies what a request asks for: its route, tenant
+// and body. Requests sharing an idempotency key must share the fingerprint.
+func requestFingerprint(c *gin.Context, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(c.Request.URL.Path + "\n"))
+	if tenantID, ok := repository.TenantIDFromContext(c.Request.Context()); ok {
+		h.Write([]byte(strconv.FormatInt(tenantID, 10)))
+	}
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCaptureWriter is a response writer that keeps a copy of the body
+type responseCaptureWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write copies the response body while writing it
+func (w *responseCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString copies the response body while writing it
+func (w *responseCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Token Introspection Authentication Middleware
+// =============================================================================
+
+// IntrospectionTokenRequired protects the token introspection endpoint with a
+// static bearer token (INTROSPECTION_SECRET) shared with resource servers, so the
+// endpoint cannot be used to probe for valid tokens. When no token is configured
+// introspection is disabled.
+func IntrospectionTokenRequired(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "token introspection is disabled"})
+			return
+		}
+
+		if !hasBearerToken(c, token) {
+			logger.Warn("rejected introspection request", "ip", c.ClientIP())
+			c.Header("WWW-Authenticate", `Bearer realm="introspection"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// =============================================================================
+// JSON Schema Validation
+// =============================================================================
+
+// schemaFS holds the request body schemas, one JSON Schema (draft 2020-12) file
+// per route, named after the route, e.g. schemas/login.json.
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// schemaPrinter renders validation messages in English.
+var schemaPrinter = message.NewPrinter(language.English)
+
+// JSONSchemaMiddleware creates a Gin middleware that validates the JSON request
+// body against the embedded schema with the given name (schemas/<name>.json)
+// before the handler binds it.
+//
+// Malformed JSON and bodies that violate the schema are rejected with 422
+// Unprocessable Entity and a per-field breakdown:
+//
+//	{"error": "request body failed validation", "validation_error": {"email": "..."}}
+//
+// Nested fields are reported by their dotted path ("address.city"); problems with
+// the body as a whole use the key "body". The body is buffered and restored, so
+// the handler can still bind it. Run MaxBodySizeMiddleware first to bound the
+// buffer.
+//
+// The schema is compiled when the middleware is created; an unknown or invalid
+// schema panics at router setup rather than on the first request.
+//
+// Parameters:
+//   - name: Schema file name without the .json extension
+//
+// Returns:
+//   - gin.HandlerFunc: JSON schema validation middleware function
+func JSONSchemaMiddleware(name string) gin.HandlerFunc {
+	schema := mustCompileSchema(name)
+
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			if IsBodyTooLarge(err) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+		if err != nil {
+			abortWithFieldErrors(c, map[string]string{"body": "Malformed JSON"})
+			return
+		}
+
+		if err := schema.Validate(doc); err != nil {
+			var validationErr *jsonschema.ValidationError
+			if !errors.As(err, &validationErr) {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to validate request body"})
+				return
+			}
+			fields := make(map[string]string)
+			collectFieldErrors(validationErr, fields)
+			abortWithFieldErrors(c, fields)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// mustCompileSchema compiles schemas/<name>.json from schemaFS.
+func mustCompileSchema(name string) *jsonschema.Schema {
+	path := "schemas/" + name + ".json"
+	data, err := schemaFS.ReadFile(path)
+	if err != nil {
+		panic(fmt.Sprintf("json schema %q not found", name))
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		panic(fmt.Sprintf("json schema %q is not valid JSON: %v", name, err))
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	if err := compiler.AddResource(path, doc); err != nil {
+		panic(fmt.Sprintf("json schema %q: %v", name, err))
+	}
+	return compiler.MustCompile(path)
+}
+
+// collectFieldErrors flattens a validation error tree into one message per field.
+// Only leaf errors are reported; the first message for a field wins.
+func collectFieldErrors(err *jsonschema.ValidationError, fields map[string]string) {
+	if len(err.Causes) > 0 {
+		for _, cause := range err.Causes {
+			collectFieldErrors(cause, fields)
+		}
+		return
+	}
+
+	// A missing property is reported on its parent object; attribute it to the property
+	if required, ok := err.ErrorKind.(*kind.Required); ok {
+		for _, property := range required.Missing {
+			location := append(slices.Clone(err.InstanceLocation), property)
+			addFieldError(fields, fieldPath(location), "This field is required")
+		}
+		return
+	}
+
+	addFieldError(fields, fieldPath(err.InstanceLocation), err.ErrorKind.LocalizedString(schemaPrinter))
+}
+
+func addFieldError(fields map[string]string, field, msg string) {
+	if _, exists := fields[field]; !exists {
+		fields[field] = msg
+	}
+}
+
+// fieldPath renders an instance location as a dotted field path.
+func fieldPath(location []string) string {
+	if len(location) == 0 {
+		return "body"
+	}
+	return strings.Join(location, ".")
+}
+
+// abortWithFieldErrors writes the 422 response shared by all schema failures.
+func abortWithFieldErrors(c *gin.Context, fields map[string]string) {
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+		"error":            "request body failed validation",
+		"validation_error": fields,
+	})
+}
@@ -74,8 +74,8 @@ func RequestLogger() gin.HandlerFunc {
 		// Add response size
 		fields = append(fields, zap.Int("response_size", c.Writer.Size()))
 
-		// Add request ID if present
-		if requestID := c.GetHeader("X-Request-ID"); requestID != "" {
+		// Add request ID if present (set by RequestID)
+		if requestID := logger.RequestIDFromContext(c.Request.Context()); requestID != "" {
 			fields = append(fields, zap.String("request_id", requestID))
 		}
 
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Mutual TLS Middleware
+// =============================================================================
+
+// ServiceIdentity is the subject of a verified TLS client certificate: the
+// internal service calling the API
+type ServiceIdentity struct {
+	CommonName          string
+	OrganizationalUnits []string
+}
+
+// MTLSMiddleware stores the identity of a verified client certificate in the
+// context as "serviceIdentity". Only certificates the TLS handshake verified
+// against MTLS_CLIENT_CA_FILE count; requests without one pass through
+// unchanged, so routes decide for themselves whether an identity is required.
+// AdminTokenRequired and SCIMTokenRequired accept it instead of their bearer token
+// when it is on their ServiceACL.
+func MTLSMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if state := c.Request.TLS; state != nil && len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+			subject := state.VerifiedChains[0][0].Subject
+			c.Set("serviceIdentity", &ServiceIdentity{
+				CommonName:          subject.CommonName,
+				OrganizationalUnits: subject.OrganizationalUnit,
+			})
+		}
+		c.Next()
+	}
+}
+
+// ServiceACL lists the client certificates allowed in place of a route group's
+// token: a certificate matches by its CN or by any of its OUs. An empty ACL
+// admits no certificate.
+type ServiceACL struct {
+	CommonNames         []string
+	OrganizationalUnits []string
+}
+
+// Allows reports whether the identity matches the ACL.
+func (a ServiceACL) Allows(identity *ServiceIdentity) bool {
+	if slices.Contains(a.CommonNames, identity.CommonName) {
+		return true
+	}
+	for _, ou := range identity.OrganizationalUnits {
+		if slices.Contains(a.OrganizationalUnits, ou) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetServiceIdentity returns the identity MTLSMiddleware found, if any.
+func GetServiceIdentity(c *gin.Context) (*ServiceIdentity, bool) {
+	v, ok := c.Get("serviceIdentity")
+	if !ok {
+		return nil, false
+	}
+	identity, ok := v.(*ServiceIdentity)
+	return identity, ok
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"time"
@@ -44,6 +45,16 @@ func NewRedisRateLimiter(redis *redis.Client, limit int, window time.Duration) *
 	}
 }
 
+// NewRouteRateLimiter creates a RedisRateLimiter whose counters are scoped to a
+// named route, so a per-route limit is tracked independently of the global one.
+//
+// Example key: "ratelimit:login:192.168.1.100:/api/v1/auth/login"
+func NewRouteRateLimiter(redis *redis.Client, route string, limit int, window time.Duration) *RedisRateLimiter {
+	rl := NewRedisRateLimiter(redis, limit, window)
+	rl.keyPrefix = "ratelimit:" + route + ":"
+	return rl
+}
+
 // =============================================================================
 // Middleware Factory Function
 // =============================================================================
@@ -67,48 +78,37 @@ func RateLimiterMiddlewareRedis(redis *redis.Client) gin.HandlerFunc {
 // =============================================================================
 
 // Handle is the main rate limiting middleware function that processes each request.
-// It uses Redis pipelines for atomic operations to ensure accurate counting.
+// It implements a sliding-window log: every request is stored in a Redis sorted set
+// scored by its timestamp, so the limit applies to any window-long interval rather
+// than resetting at fixed boundaries (which lets a client burst 2x the limit).
 //
-// The rate limiting algorithm:
-// 1. Generates a unique key based on client IP and request path
-// 2. Increments the counter in Redis atomically
-// 3. Sets expiration on the key if it's new
-// 4. Checks if the request count exceeds the limit
-// 5. Returns appropriate headers and responses
+// The rate limiting algorithm (run in a single MULTI/EXEC transaction):
+// 1. ZREMRANGEBYSCORE drops entries older than now - window
+// 2. ZADD records the current request
+// 3. ZCARD counts requests inside the window
+// 4. ZRANGE fetches the oldest entry to compute when the window frees up
+// 5. PEXPIRE lets idle keys expire on their own
+//
+// Rejected requests are removed again so they do not extend the lockout.
 func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 	key := rl.getKey(c)
 	ctx := context.Background()
 
-	// Use Redis pipeline for atomic operations to prevent race conditions
-	pipe := rl.redis.Pipeline()
-	
-	// Increment the counter and set expiration in a single atomic operation
-	incrCmd := pipe.Incr(ctx, key)           // Increment the counter
-	pipe.Expire(ctx, key, rl.window)         // Set expiration (resets if key exists)
-	
-	// Execute the pipeline atomically
-	_, err := pipe.Exec(ctx)
-
-	// Handle case where key doesn't exist yet (first request)
-	if err == redis.Nil {
-		// Create new pipeline for initial key setup
-		pipe := rl.redis.Pipeline()
-		pipe.Set(ctx, key, 1, rl.window) // Set initial value with expiration
-		if _, err := pipe.Exec(ctx); err != nil {
-			logger.Logger.Error("redis rate limiter error - failed to set initial key", 
-				zap.Error(err),
-				zap.String("key", key),
-				zap.String("ip", c.ClientIP()),
-			)
-			c.Next() // Allow request on Redis error (fail-open strategy)
-			return
-		}
-		c.Next() // Allow the request
-		return
-	}
-
-	// Handle other Redis errors
-	if err != nil && err != redis.Nil {
+	now := time.Now()
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatUint(rand.Uint64(), 36)
+	windowStart := now.Add(-rl.window).UnixMicro()
+
+	var countCmd *redis.IntCmd
+	var oldestCmd *redis.ZSliceCmd
+	_, err := rl.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(windowStart, 10))
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMicro()), Member: member})
+		countCmd = pipe.ZCard(ctx, key)
+		oldestCmd = pipe.ZRangeWithScores(ctx, key, 0, 0)
+		pipe.PExpire(ctx, key, rl.window)
+		return nil
+	})
+	if err != nil {
 		logger.Logger.Error("redis rate limiter error - pipeline execution failed",
 			zap.Error(err),
 			zap.String("key", key),
@@ -118,16 +118,12 @@ func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 		return
 	}
 
-	// Get the incremented count result
-	count, err := incrCmd.Result()
-	if err != nil {
-		logger.Logger.Error("redis rate limiter error - failed to get increment result",
-			zap.Error(err),
-			zap.String("key", key),
-			zap.String("ip", c.ClientIP()),
-		)
-		c.Next() // Allow request on error
-		return
+	count := countCmd.Val()
+
+	// The window frees a slot when the oldest request in it ages out
+	reset := now.Add(rl.window)
+	if oldest := oldestCmd.Val(); len(oldest) > 0 {
+		reset = time.UnixMicro(int64(oldest[0].Score)).Add(rl.window)
 	}
 
 	// Add rate limit headers for client information
@@ -137,10 +133,20 @@ func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 		remaining = 0
 	}
 	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
-	c.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(rl.window).Unix(), 10))
-	
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
 	// Check if request count exceeds the limit
 	if count > int64(rl.limit) {
+		// Don't let rejected requests count against the window
+		if err := rl.redis.ZRem(ctx, key, member).Err(); err != nil {
+			logger.Logger.Warn("redis rate limiter error - failed to remove rejected request", zap.Error(err), zap.String("key", key))
+		}
+
+		retryAfter := time.Until(reset).Seconds()
+		if retryAfter < 0 {
+			retryAfter = 0
+		}
+
 		logger.Logger.Warn("rate limit exceeded",
 			zap.String("ip", c.ClientIP()),
 			zap.String("path", c.Request.URL.Path),
@@ -148,9 +154,10 @@ func (rl *RedisRateLimiter) Handle(c *gin.Context) {
 			zap.Int("limit", rl.limit),
 			zap.String("window", rl.window.String()),
 		)
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter+0.999)))
 		c.JSON(http.StatusTooManyRequests, gin.H{
 			"error": "rate limit exceeded",
-			"retry_after": rl.window.Seconds(),
+			"retry_after": retryAfter,
 			"limit": rl.limit,
 			"window_seconds": rl.window.Seconds(),
 		})
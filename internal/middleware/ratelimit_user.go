@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// =============================================================================
+// Per-User Rate Limiting
+// =============================================================================
+
+// userRateLimitedKey marks requests whose quota was charged to the user (see UserRateLimited)
+const userRateLimitedKey = "userRateLimited"
+
+// tokenBucketScript refills the bucket for the time since its last request,
+// then takes one token if there is one. State is a hash of the token count and
+// the time of the last refill (microseconds). It returns whether the request
+// is allowed and the tokens left, as a string since Redis truncates Lua
+// numbers to integers.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`)
+
+// UserRateLimiter limits requests per user and route with a token bucket in
+// Redis. A bucket holds up to limit tokens and refills at limit per window, so
+// a user can burst limit requests and then sustain limit per window. Users are
+// identified by an access token that AuthRequired would accept; other requests
+// are left to the IP-based limiters.
+type UserRateLimiter struct {
+	redis      *redis.Client
+	jwtManager *jwt.Manager
+	sessions   SessionChecker
+	limit      int
+	window     time.Duration
+	keyPrefix  string
+}
+
+// NewUserRateLimiter creates a UserRateLimiter verifying access tokens with
+// jwtManager and, when sessions is set, requiring their session to be active.
+// Both limit and window must be positive.
+//
+// Example key: "ratelimit:user:42:/api/v1/me"
+func NewUserRateLimiter(redis *redis.Client, jwtManager *jwt.Manager, sessions SessionChecker, limit int, window time.Duration) (*UserRateLimiter, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("user rate limit must be positive, got %d", limit)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("user rate limit window must be positive, got %s", window)
+	}
+	return &UserRateLimiter{
+		redis:      redis,
+		jwtManager: jwtManager,
+		sessions:   sessions,
+		limit:      limit,
+		window:     window,
+		keyPrefix:  "ratelimit:user:",
+	}, nil
+}
+
+// Handle charges the request to its user's bucket for the route and rejects it
+// with 429 and Retry-After when the bucket is empty. Requests without a valid
+// access token pass through unchanged. On Redis errors the request is allowed
+// and left to the IP-based limiter.
+func (rl *UserRateLimiter) Handle(c *gin.Context) {
+	userID, ok := rl.userID(c)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	key := rl.keyPrefix + strconv.FormatInt(userID, 10) + ":" + route
+
+	rate := float64(rl.limit) / float64(rl.window.Microseconds()) // tokens per microsecond
+	result, err := tokenBucketScript.Run(context.Background(), rl.redis, []string{key},
+		rl.limit, rate, time.Now().UnixMicro(), rl.window.Milliseconds(),
+	).Slice()
+	if err != nil || len(result) != 2 {
+		logger.Logger.Error("redis user rate limiter error - script failed",
+			zap.Error(err),
+			zap.String("key", key),
+		)
+		c.Next()
+		return
+	}
+	allowed, _ := result[0].(int64)
+	tokensLeft, _ := strconv.ParseFloat(result[1].(string), 64)
+
+	c.Set(userRateLimitedKey, true)
+	c.Header("X-RateLimit-Limit", strconv.Itoa(rl.limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(int(tokensLeft)))
+
+	if allowed != 1 {
+		// Time until the bucket refills to one token
+		retryAfter := (1 - tokensLeft) / rate / float64(time.Second/time.Microsecond)
+
+		logger.Logger.Warn("user rate limit exceeded",
+			zap.Int64("userID", userID),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("limit", rl.limit),
+			zap.String("window", rl.window.String()),
+		)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":          "rate limit exceeded",
+			"retry_after":    retryAfter,
+			"limit":          rl.limit,
+			"window_seconds": rl.window.Seconds(),
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// userID returns the user of a "Bearer" or "DPoP" access token that passes the
+// checks of AuthRequired: signature and expiry, not an OAuth client token, not
+// revoked, and with SESSION_VALIDATION an active session. This middleware runs
+// before AuthRequired, so a token that fails any check, or cannot be checked,
+// is charged to the IP-based limiter instead.
+func (rl *UserRateLimiter) userID(c *gin.Context) (int64, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || (scheme != "Bearer" && scheme != "DPoP") || rl.jwtManager == nil {
+		return 0, false
+	}
+	verified, err := rl.jwtManager.VerifyToken(token)
+	if err != nil {
+		return 0, false
+	}
+	claims := jwt.Claims(verified)
+	if _, isClientToken := claims["client_id"]; isClientToken {
+		return 0, false
+	}
+
+	ctx := c.Request.Context()
+	jti, _ := claims["jti"].(string)
+	revoked, err := rl.jwtManager.IsRevoked(ctx, jti)
+	if err == nil && !revoked {
+		revoked, err = rl.jwtManager.IssuedBeforeRevocation(ctx, claims)
+	}
+	if err != nil || revoked {
+		return 0, false
+	}
+
+	if rl.sessions != nil {
+		sessionID, _ := claims["session_id"].(string)
+		if sessionID == "" {
+			return 0, false
+		}
+		if active, err := rl.sessions.IsSessionActive(ctx, sessionID); err != nil || !active {
+			return 0, false
+		}
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok || userID <= 0 {
+		return 0, false
+	}
+	return int64(userID), true
+}
+
+// UserRateLimited reports whether the request's quota was charged to its user
+// by a UserRateLimiter.
+func UserRateLimited(c *gin.Context) bool {
+	return c.GetBool(userRateLimitedKey)
+}
+
+// SkipIfUserRateLimited runs limiter only for requests not already charged to
+// their user, so authenticated traffic does not use up the quota of an IP that
+// is shared (e.g. behind NAT) with other users.
+func SkipIfUserRateLimited(limiter gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if UserRateLimited(c) {
+			c.Next()
+			return
+		}
+		limiter(c)
+	}
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"authentio/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+type stubSessions map[string]bool
+
+func (s stubSessions) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	return s[sessionID], nil
+}
+
+func TestNewUserRateLimiterRejectsNonPositiveSettings(t *testing.T) {
+	if _, err := NewUserRateLimiter(nil, nil, nil, 10, 0); err == nil {
+		t.Fatal("zero window accepted")
+	}
+	if _, err := NewUserRateLimiter(nil, nil, nil, 10, -time.Second); err == nil {
+		t.Fatal("negative window accepted")
+	}
+	if _, err := NewUserRateLimiter(nil, nil, nil, 0, time.Minute); err == nil {
+		t.Fatal("zero limit accepted")
+	}
+}
+
+func TestUserRateLimiterOnlyChargesTokensAuthRequiredAccepts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	ctx := context.Background()
+
+	manager := jwt.NewManager("user-rate-limit-secret-of-32-bytes!").WithRevocationStore(rdb, false)
+	sessions := stubSessions{"active": true, "revoked": false}
+	limiter, err := NewUserRateLimiter(rdb, manager, sessions, 5, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := func(claims jwt.UserClaims) string {
+		t.Helper()
+		signed, err := manager.GenerateTokenWithClaims(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := token(jwt.UserClaims{UserID: 1, SessionID: "active"})
+	revoked := token(jwt.UserClaims{UserID: 2, SessionID: "active"})
+	claims, _ := manager.VerifyToken(revoked)
+	if err := manager.RevokeToken(ctx, claims["jti"].(string), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	signedOut := token(jwt.UserClaims{UserID: 3, SessionID: "active"})
+	if err := manager.RevokeUserTokensIssuedBefore(ctx, 3, time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	loggedOut := token(jwt.UserClaims{UserID: 4, SessionID: "revoked"})
+	noSession := token(jwt.UserClaims{UserID: 5})
+	client, err := manager.GenerateClientToken("reporting", []string{"users:read"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"valid token", valid, true},
+		{"revoked token", revoked, false},
+		{"token issued before sign-out everywhere", signedOut, false},
+		{"token of a revoked session", loggedOut, false},
+		{"token without session", noSession, false},
+		{"client credentials token", client, false},
+		{"malformed token", "not-a-token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var charged bool
+			r := gin.New()
+			r.GET("/me", limiter.Handle, func(c *gin.Context) {
+				charged = UserRateLimited(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("got %d, want 200", w.Code)
+			}
+			if charged != tt.want {
+				t.Fatalf("charged to user = %v, want %v", charged, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// =============================================================================
+// Request ID Middleware
+// =============================================================================
+
+// RequestIDHeader carries the request ID clients send for distributed tracing
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request ID in the Gin context (c.Get)
+const requestIDKey = "requestID"
+
+// maxRequestIDLength caps incoming IDs so clients can't inflate every log line
+const maxRequestIDLength = 128
+
+// RequestID passes the client's X-Request-ID through, or generates a UUID when
+// the header is absent or malformed. The ID is echoed on the response, stored
+// in the Gin context and added to the request context with logger.WithRequestID,
+// so request logs carry it as request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(requestIDKey, id)
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+
+		c.Next()
+	}
+}
+
+// validRequestID accepts non-empty, reasonably short IDs of printable ASCII.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// SCIM Authentication Middleware
+// =============================================================================
+
+// SCIMTokenRequired protects the SCIM provisioning API with a static bearer token
+// (SCIM_TOKEN) shared with the identity provider. It is independent of user JWTs.
+// When no token is configured the SCIM API is disabled. Errors use the SCIM error
+// format (RFC 7644 section 3.12) so identity providers can report them.
+// Services authenticated with a client certificate (MTLSMiddleware) on the
+// services ACL need no token; other certificates are refused with 403.
+func SCIMTokenRequired(token string, services ServiceACL) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity, ok := GetServiceIdentity(c); ok {
+			if !services.Allows(identity) {
+				logger.Warn("rejected SCIM request from unauthorized service", "service", identity.CommonName, "ous", identity.OrganizationalUnits, "path", c.Request.URL.Path)
+				abortSCIM(c, http.StatusForbidden, "service not authorized for SCIM provisioning")
+				return
+			}
+			logger.Debug("SCIM request authenticated by client certificate", "service", identity.CommonName, "path", c.Request.URL.Path)
+			c.Next()
+			return
+		}
+
+		if token == "" {
+			abortSCIM(c, http.StatusNotFound, "SCIM provisioning is disabled")
+			return
+		}
+
+		if !hasBearerToken(c, token) {
+			logger.Warn("rejected SCIM request", "ip", c.ClientIP(), "path", c.Request.URL.Path)
+			abortSCIM(c, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// abortSCIM aborts the request with a SCIM error response.
+func abortSCIM(c *gin.Context, status int, detail string) {
+	c.Header("Content-Type", "application/scim+json")
+	c.AbortWithStatusJSON(status, gin.H{
+		"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	})
+}
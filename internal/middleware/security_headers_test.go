@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveSecurityHeaders runs one request for path through SecurityHeaders and
+// returns the response headers.
+func serveSecurityHeaders(cfg SecurityHeadersConfig, path string, skipCSPRoutes ...string) http.Header {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeaders(cfg, skipCSPRoutes...))
+	r.GET("/api/v1/me", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/swagger/*any", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w.Header()
+}
+
+func TestSecurityHeadersDefaults(t *testing.T) {
+	h := serveSecurityHeaders(SecurityHeadersConfig{}, "/api/v1/me")
+
+	want := map[string]string{
+		"Strict-Transport-Security": DefaultStrictTransportSecurity,
+		"Content-Security-Policy":   DefaultContentSecurityPolicy,
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Permissions-Policy":        DefaultPermissionsPolicy,
+	}
+	for name, value := range want {
+		if got := h.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestSecurityHeadersOverrides(t *testing.T) {
+	h := serveSecurityHeaders(SecurityHeadersConfig{
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains; preload",
+		XFrameOptions:           "SAMEORIGIN",
+		ReferrerPolicy:          DisableHeader,
+		ContentSecurityPolicy:   DisableHeader,
+	}, "/api/v1/me")
+
+	if got := h.Get("Strict-Transport-Security"); got != "max-age=63072000; includeSubDomains; preload" {
+		t.Errorf("Strict-Transport-Security = %q, want the override", got)
+	}
+	if got := h.Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, want SAMEORIGIN", got)
+	}
+	if got := h.Get("X-Content-Type-Options"); got != DefaultXContentTypeOptions {
+		t.Errorf("X-Content-Type-Options = %q, want the default", got)
+	}
+	for _, name := range []string{"Referrer-Policy", "Content-Security-Policy"} {
+		if _, ok := h[name]; ok {
+			t.Errorf("disabled header %s was sent", name)
+		}
+	}
+}
+
+func TestSecurityHeadersSkipCSPRoutes(t *testing.T) {
+	h := serveSecurityHeaders(SecurityHeadersConfig{}, "/swagger/index.html", "/swagger/*any")
+
+	if _, ok := h["Content-Security-Policy"]; ok {
+		t.Error("Content-Security-Policy sent on a skipped route")
+	}
+	if got := h.Get("X-Frame-Options"); got != DefaultXFrameOptions {
+		t.Errorf("X-Frame-Options = %q, want the default on a skipped route", got)
+	}
+
+	if h := serveSecurityHeaders(SecurityHeadersConfig{}, "/api/v1/me", "/swagger/*any"); h.Get("Content-Security-Policy") == "" {
+		t.Error("Content-Security-Policy missing on a route that is not skipped")
+	}
+}
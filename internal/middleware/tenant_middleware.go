@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Tenant Resolution Middleware
+// =============================================================================
+
+// TenantIDHeader selects the tenant of a request by ID or slug
+const TenantIDHeader = "X-Tenant-ID"
+
+// TenantMiddleware resolves the tenant a request targets and scopes every
+// repository query made while handling it to that tenant.
+//
+// The tenant is taken from the X-Tenant-ID header (numeric ID or slug) or, when
+// baseDomain is set, from the subdomain (acme.<baseDomain> selects slug "acme").
+// Requests naming neither are served as the default, single-tenant installation.
+// Naming a tenant that does not exist is rejected.
+func TenantMiddleware(tenants repository.TenantRepository, baseDomain string) gin.HandlerFunc {
+	baseDomain = strings.ToLower(strings.TrimPrefix(baseDomain, "."))
+
+	return func(c *gin.Context) {
+		ref := strings.TrimSpace(c.GetHeader(TenantIDHeader))
+		if ref == "" && baseDomain != "" {
+			ref = subdomainSlug(c.Request.Host, baseDomain)
+		}
+		if ref == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		var tenant *models.Tenant
+		var err error
+		if id, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+			tenant, err = tenants.FindByID(ctx, id)
+		} else {
+			tenant, err = tenants.FindBySlug(ctx, strings.ToLower(ref))
+		}
+		if err != nil {
+			logger.Error("failed to resolve tenant", "tenant", ref, "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve tenant"})
+			return
+		}
+		if tenant == nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown tenant"})
+			return
+		}
+
+		c.Set("tenantID", tenant.ID)
+		c.Request = c.Request.WithContext(repository.WithTenantID(ctx, tenant.ID))
+		c.Next()
+	}
+}
+
+// subdomainSlug returns the single label in front of baseDomain in host, or ""
+// when host is baseDomain itself or not one of its subdomains.
+func subdomainSlug(host, baseDomain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+
+	slug, ok := strings.CutSuffix(host, "."+baseDomain)
+	if !ok || slug == "" || strings.Contains(slug, ".") {
+		return ""
+	}
+	return slug
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"authentio/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// =============================================================================
+// Tracing Middleware
+// =============================================================================
+
+// TracingMiddleware starts a server span for every request. A W3C traceparent (and
+// tracestate) header on the request makes the span a child of the caller's span,
+// so the trace continues across services. The span travels on the request context,
+// making service, repository and email spans its children.
+//
+// Spans are named "<METHOD> <route>", using the route template (e.g. /api/v1/users/:id)
+// rather than the raw path to keep span names low-cardinality. 5xx responses mark
+// the span as failed.
+//
+// Parameters:
+//   - tp: Tracer provider; nil disables tracing
+//
+// Returns:
+//   - gin.HandlerFunc: Tracing middleware function
+func TracingMiddleware(tp trace.TracerProvider) gin.HandlerFunc {
+	tracer := tracing.Tracer(tp, "authentio/internal/middleware")
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", c.Request.URL.Path),
+				attribute.String("client.address", c.ClientIP()),
+				attribute.String("user_agent.original", c.Request.UserAgent()),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		if userID, ok := c.Get("userID"); ok {
+			span.SetAttributes(attribute.String("enduser.id", fmt.Sprint(userID)))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Webhook Signature Verification
+// =============================================================================
+
+// Built-in webhook signature schemes, selected by name in VerifyWebhookSignature
+const (
+	// WebhookSignatureHMACSHA256 is a hex or base64 HMAC-SHA256 of the raw body,
+	// optionally prefixed with "sha256=" (default header X-Webhook-Signature)
+	WebhookSignatureHMACSHA256 = "hmac-sha256"
+
+	// WebhookSignatureHMACSHA1 is the same with HMAC-SHA1, for legacy providers
+	// (optional "sha1=" prefix, default header X-Webhook-Signature)
+	WebhookSignatureHMACSHA1 = "hmac-sha1"
+
+	// WebhookSignatureMailgun checks the signature object of Mailgun webhook
+	// payloads: a hex HMAC-SHA256 of timestamp and token with the signing key
+	WebhookSignatureMailgun = "mailgun"
+
+	// WebhookSignatureSendGrid checks the ECDSA signature of SendGrid's Signed
+	// Event Webhook over timestamp and body; the secret is the base64 verification key
+	WebhookSignatureSendGrid = "sendgrid"
+)
+
+// webhookSignatureMaxAge is how old the timestamp of a Mailgun or SendGrid
+// signature may be, so a captured request cannot be replayed later
+const webhookSignatureMaxAge = 5 * time.Minute
+
+// WebhookSignatureScheme reports whether a webhook request carries a valid
+// signature. It receives the request, its raw body, the configured secret and
+// header name; an empty headerName means the scheme's default header.
+type WebhookSignatureScheme func(r *http.Request, body []byte, secret, headerName string) bool
+
+// webhookSignatureSchemes are the schemes VerifyWebhookSignature can use by name
+var webhookSignatureSchemes = map[string]WebhookSignatureScheme{
+	WebhookSignatureHMACSHA256: hmacSignature(sha256.New, "sha256="),
+	WebhookSignatureHMACSHA1:   hmacSignature(sha1.New, "sha1="),
+	WebhookSignatureMailgun:    mailgunSignature,
+	WebhookSignatureSendGrid:   sendGridSignature,
+}
+
+// RegisterWebhookSignatureScheme adds a scheme for another provider, or replaces
+// a built-in one. Register schemes at startup, before the router is built.
+func RegisterWebhookSignatureScheme(name string, scheme WebhookSignatureScheme) {
+	webhookSignatureSchemes[name] = scheme
+}
+
+// IsWebhookSignatureScheme reports whether name is a registered scheme
+func IsWebhookSignatureScheme(name string) bool {
+	_, ok := webhookSignatureSchemes[name]
+	return ok
+}
+
+// VerifyWebhookSignature creates a Gin middleware that authenticates webhook
+// requests by the signature their provider computed over the raw body. The body
+// is read once and restored, so handlers can still bind it. Requests with a
+// missing or invalid signature are rejected with 401.
+//
+// The request body limit of MaxBodySizeMiddleware bounds how much is buffered.
+//
+// Parameters:
+//   - secret: Signing key shared with the provider (a public key for SendGrid)
+//   - headerName: Header holding the signature; empty uses the scheme's default
+//   - algo: Registered scheme name, e.g. WebhookSignatureHMACSHA256
+//
+// Returns:
+//   - gin.HandlerFunc: Signature verification middleware function
+func VerifyWebhookSignature(secret, headerName, algo string) gin.HandlerFunc {
+	scheme, ok := webhookSignatureSchemes[algo]
+
+	return func(c *gin.Context) {
+		if !ok {
+			logger.Error("unknown webhook signature scheme", "scheme", algo)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "webhook signature verification misconfigured"})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				if IsBodyTooLarge(err) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		if !scheme(c.Request, body, secret, headerName) {
+			logger.Warn("rejected webhook with invalid signature", "scheme", algo, "ip", c.ClientIP(), "path", c.Request.URL.Path)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// hmacSignature verifies an HMAC of the raw body, sent hex or base64 encoded
+// with an optional algorithm prefix such as "sha256=".
+func hmacSignature(newHash func() hash.Hash, prefix string) WebhookSignatureScheme {
+	return func(r *http.Request, body []byte, secret, headerName string) bool {
+		signature := strings.TrimPrefix(r.Header.Get(headerOrDefault(headerName, "X-Webhook-Signature")), prefix)
+		if signature == "" {
+			return false
+		}
+
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write(body)
+		return signatureMatches(mac.Sum(nil), signature)
+	}
+}
+
+// mailgunSignature verifies the signature object Mailgun puts in the JSON body
+// of its webhooks; it does not use a header.
+func mailgunSignature(_ *http.Request, body []byte, secret, _ string) bool {
+	var payload struct {
+		Signature struct {
+			Timestamp string `json:"timestamp"`
+			Token     string `json:"token"`
+			Signature string `json:"signature"`
+		} `json:"signature"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return false
+	}
+	sig := payload.Signature
+	if sig.Signature == "" || !recentTimestamp(sig.Timestamp) {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(sig.Timestamp + sig.Token))
+	expected, err := hex.DecodeString(sig.Signature)
+	return err == nil && hmac.Equal(mac.Sum(nil), expected)
+}
+
+// sendGridSignature verifies the base64 ECDSA signature of SendGrid's Signed
+// Event Webhook over the timestamp header followed by the raw body. The secret
+// is the base64 DER-encoded verification key shown in the SendGrid settings.
+func sendGridSignature(r *http.Request, body []byte, secret, headerName string) bool {
+	timestamp := r.Header.Get("X-Twilio-Email-Event-Webhook-Timestamp")
+	if !recentTimestamp(timestamp) {
+		return false
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Header.Get(headerOrDefault(headerName, "X-Twilio-Email-Event-Webhook-Signature")))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	der, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return false
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return false
+	}
+	publicKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+
+	digest := sha256.Sum256(append([]byte(timestamp), body...))
+	return ecdsa.VerifyASN1(publicKey, digest[:], signature)
+}
+
+// signatureMatches compares a computed MAC with a hex or base64 encoded
+// signature in constant time.
+func signatureMatches(mac []byte, signature string) bool {
+	if decoded, err := hex.DecodeString(signature); err == nil {
+		return hmac.Equal(mac, decoded)
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil {
+		return hmac.Equal(mac, decoded)
+	}
+	return false
+}
+
+// recentTimestamp reports whether a Unix timestamp in seconds is within
+// webhookSignatureMaxAge of now.
+func recentTimestamp(value string) bool {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(seconds, 0))
+	return age <= webhookSignatureMaxAge && age >= -webhookSignatureMaxAge
+}
+
+// headerOrDefault returns name, or def when no header name is configured
+func headerOrDefault(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
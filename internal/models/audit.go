@@ -0,0 +1,22 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry is one row of the append-only audit log.
+type AuditEntry struct {
+	ID        int64           `db:"id" json:"id" example:"42"`
+	UserID    *int64          `db:"user_id" json:"user_id,omitempty" example:"42"`
+	TenantID  *int64          `db:"tenant_id" json:"tenant_id,omitempty" example:"1"`
+	EventType string          `db:"event_type" json:"event_type" example:"login"`
+	IP        string          `db:"ip" json:"ip" example:"203.0.113.7"`
+	UserAgent string          `db:"user_agent" json:"user_agent" example:"Mozilla/5.0"`
+	Metadata  json.RawMessage `db:"metadata" json:"metadata,omitempty" swaggertype:"object"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at" example:"2024-05-01T12:00:00Z"`
+
+	// Location of the client IP of login attempts, when GeoIP is configured
+	LoginCountry string `db:"login_country" json:"login_country,omitempty" example:"NG"`
+	LoginCity    string `db:"login_city" json:"login_city,omitempty" example:"Lagos"`
+}
@@ -1,15 +1,29 @@
 package models
 
 type RegisterRequest struct {
-	FirstName string `json:"first_name" db:"first_name" validate:"required,alphaSpace,min=2,max=50"`
-	LastName  string `json:"last_name" db:"last_name" validate:"required,alphaSpace,min=2,max=50"`
-	Email     string `json:"email" db:"email" validate:"required,email,max=50"`
-	Password  string `json:"password" db:"password" validate:"required,password"`
+	FirstName string `json:"first_name" db:"first_name" validate:"required,alphaSpace,min=2,max=50" example:"Jane"`
+	LastName  string `json:"last_name" db:"last_name" validate:"required,alphaSpace,min=2,max=50" example:"Doe"`
+	Email     string `json:"email" db:"email" validate:"required,email,max=50" example:"jane.doe@example.com"`
+	Password  string `json:"password" db:"password" validate:"required,password" example:"Str0ng!Passw0rd"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email,max=100"`
-	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"required,email,max=100" example:"jane.doe@example.com"`
+	Password string `json:"password" validate:"required" example:"Str0ng!Passw0rd"`
+
+	// RememberMe requests a long-lived session (REMEMBER_ME_TTL instead of REFRESH_TOKEN_TTL)
+	RememberMe bool `json:"remember_me" example:"true"`
 }
 
 
+
+// OAuthCallbackRequest carries the parameters an OAuth provider redirects back with.
+// For the cookie-based flow, State must already have been checked against the value
+// issued with the redirect. For the PKCE flow, CodeVerifier is the value returned by
+// the authorize endpoint.
+type OAuthCallbackRequest struct {
+	Provider     string `json:"provider" validate:"required"`
+	Code         string `json:"code" validate:"required"`
+	State        string `json:"state" validate:"required"`
+	CodeVerifier string `json:"code_verifier,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// UserExport is everything stored about a user, as served by GET /me/export
+// for data portability (GDPR Art. 20). Secrets (password hash, TOTP secret,
+// refresh token hashes, one-time codes and passkey key material) are never
+// exported.
+type UserExport struct {
+	ExportedAt time.Time `json:"exported_at" example:"2024-06-01T09:00:00Z"`
+	Profile    User      `json:"profile"`
+
+	// Roles are the RBAC roles assigned to the user; ExternalGroups the
+	// directory groups synced from LDAP
+	Roles          []string `json:"roles"`
+	ExternalGroups []string `json:"external_groups"`
+
+	Sessions    []ExportedSession    `json:"sessions"`
+	TwoFactor   *ExportedTwoFactor   `json:"two_factor,omitempty"`
+	Credentials []ExportedCredential `json:"webauthn_credentials"`
+	AuditLog    []AuditEntry         `json:"audit_log"`
+}
+
+// ExportedSession is a session of a user export, including revoked ones
+type ExportedSession struct {
+	ID         string     `json:"id" example:"4f8c2b1e9a7d6c5b"`
+	UserAgent  string     `json:"user_agent" example:"Mozilla/5.0"`
+	IP         string     `json:"ip" example:"203.0.113.7"`
+	RememberMe bool       `json:"remember_me" example:"false"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-05-01T12:00:00Z"`
+	LastSeenAt time.Time  `json:"last_seen_at" example:"2024-05-02T08:30:00Z"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" example:"2024-05-03T10:00:00Z"`
+}
+
+// ExportedTwoFactor is the 2FA configuration of a user export, without the TOTP secret
+type ExportedTwoFactor struct {
+	Method    string    `json:"method" example:"totp"`
+	Enabled   bool      `json:"enabled" example:"true"`
+	CreatedAt time.Time `json:"created_at" example:"2024-05-01T12:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-05-01T12:00:00Z"`
+}
+
+// ExportedCredential is a passkey or security key of a user export, without its public key
+type ExportedCredential struct {
+	Kind       string     `json:"kind" example:"passkey"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-05-01T12:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-05-02T08:30:00Z"`
+}
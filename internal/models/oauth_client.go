@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// OAuthClient is a third-party application registered with the OAuth2
+// authorization server.
+type OAuthClient struct {
+	ID       int64  `db:"id" json:"id" example:"4"`
+	ClientID string `db:"client_id" json:"client_id" example:"9b1f0c6e2d4a4e8f"`
+
+	// ClientSecret is only returned when a confidential client is created
+	ClientSecret     string `db:"-" json:"client_secret,omitempty" example:"Zq3vY9k2mWc7T1xR5nB8aL0eH4jF6uD2sG9pK1oI3wE"`
+	ClientSecretHash string `db:"client_secret_hash" json:"-"`
+
+	Name          string    `db:"name" json:"name" example:"Acme Dashboard"`
+	RedirectURIs  []string  `db:"redirect_uris" json:"redirect_uris" example:"https://acme.example.com/callback"`
+	AllowedScopes []string  `db:"allowed_scopes" json:"allowed_scopes" example:"profile,email"`
+	Public        bool      `db:"-" json:"public" example:"false"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at" example:"2024-05-01T12:00:00Z"`
+}
+
+// OAuthAuthorizeRequest holds the query parameters of GET /oauth/authorize.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+}
+
+// OAuthAuthorizeResponse is the answer of GET /oauth/authorize: the redirect URI
+// of the client with either the authorization code or an error appended.
+type OAuthAuthorizeResponse struct {
+	RedirectTo string `json:"redirect_to" example:"https://acme.example.com/callback?code=f3a9c2&state=xyz"`
+}
+
+// OAuthTokenRequest holds the form parameters of POST /oauth/token. The client
+// credentials may come from HTTP Basic authentication instead.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	DeviceCode   string `form:"device_code"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse is the RFC 6749 section 5.1 access token response.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"3600"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"kR2vX9pL4mQ8wT1zB6nC3yH7jF5dS0aG2uE9iO4xV1c"`
+	Scope        string `json:"scope,omitempty" example:"profile email"`
+}
+
+// DeviceAuthorizationRequest holds the form parameters of POST
+// /oauth/device/code. The client credentials may come from HTTP Basic
+// authentication instead.
+type DeviceAuthorizationRequest struct {
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// DeviceAuthorizationResponse is the RFC 8628 section 3.2 device authorization
+// response. The device shows user_code and verification_uri (or a QR code of
+// verification_uri_complete) and polls /oauth/token with device_code.
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code" example:"GmRhmhcxhwAzkoEqiMEg_DnyEysNkuNhszIySk9eS"`
+	UserCode                string `json:"user_code" example:"WDJB-MJHT"`
+	VerificationURI         string `json:"verification_uri" example:"https://acme.example.com/activate"`
+	VerificationURIComplete string `json:"verification_uri_complete" example:"https://acme.example.com/activate?user_code=WDJB-MJHT"`
+	ExpiresIn               int    `json:"expires_in" example:"600"`
+	Interval                int    `json:"interval" example:"5"`
+}
+
+// DeviceActivationResponse is the answer of GET /activate: the device request
+// the user approved or denied.
+type DeviceActivationResponse struct {
+	ClientID   string `json:"client_id" example:"9b1f0c6e2d4a4e8f"`
+	ClientName string `json:"client_name" example:"Acme TV"`
+	Scope      string `json:"scope" example:"profile email"`
+	Status     string `json:"status" example:"approved" enums:"approved,denied"`
+}
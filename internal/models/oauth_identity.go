@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// OAuthIdentity is a social provider account linked to a user. A user has at
+// most one identity per provider.
+type OAuthIdentity struct {
+	ID             int64  `db:"id" json:"id" example:"12"`
+	UserID         int64  `db:"user_id" json:"user_id" example:"42"`
+	Provider       string `db:"provider" json:"provider" example:"github"`
+	ProviderUserID string `db:"provider_user_id" json:"provider_user_id" example:"583231"`
+	Email          string `db:"email" json:"email" example:"jane@example.com"`
+
+	// AccessToken and RefreshToken are the provider's tokens, stored encrypted
+	// and never returned by the API
+	AccessToken  string `db:"access_token_enc" json:"-"`
+	RefreshToken string `db:"refresh_token_enc" json:"-"`
+
+	CreatedAt time.Time `db:"created_at" json:"created_at" example:"2024-05-01T12:00:00Z"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type OTPType string
 
 type OTP struct {
@@ -8,4 +10,12 @@ type OTP struct {
 	Email     string    `json:"email"`
 	Code      string    `json:"code"`
 	Type string `db:"type" json:"type"`
+	// Channel is how the code was delivered ("email" or "sms"); empty means email
+	Channel string `db:"channel" json:"channel"`
+
+	// Attempts counts failed verifications; every repository.OTPMaxAttempts of them
+	// lock the code until LockedUntil
+	Attempts    int        `db:"attempts" json:"-"`
+	Locked      bool       `db:"locked" json:"-"`
+	LockedUntil *time.Time `db:"locked_until" json:"-"`
 }
\ No newline at end of file
@@ -1,7 +1,7 @@
 package models
 
 import (
-	_"time"
+	"time"
 )
 
 type RefreshToken struct {
@@ -9,4 +9,9 @@ type RefreshToken struct {
 	UserID    int64     `db:"user_id" json:"user_id"`
 	Token     string    `db:"token" json:"token"`
 	Revoked   bool      `db:"revoked" json:"revoked"`
-}
\ No newline at end of file
+
+	// FamilyID groups every token issued from the same login. Rotating a token
+	// keeps the family; reusing an already-rotated token revokes the whole family.
+	FamilyID string     `db:"family_id" json:"-"`
+	UsedAt   *time.Time `db:"used_at" json:"-"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Role is a named set of permissions that can be assigned to users (RBAC).
+type Role struct {
+	ID          int64     `db:"id" json:"id" example:"42"`
+	Name        string    `db:"name" json:"name" example:"support"`
+	Permissions []string  `db:"-" json:"permissions" example:"users:read,users:write"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at" example:"2024-05-01T12:00:00Z"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at" example:"2024-05-02T08:30:00Z"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mssola/useragent"
+)
+
+// Device types of a DeviceFingerprint
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+)
+
+// Session is a single login (device) of a user. Its ID is the refresh token
+// family ID, so it survives refresh token rotation.
+type Session struct {
+	ID               string     `db:"session_id" json:"id"`
+	UserID           int64      `db:"user_id" json:"-"`
+	RefreshTokenHash string     `db:"refresh_token_hash" json:"-"`
+	UserAgent        string     `db:"user_agent" json:"user_agent"`
+	IP               string     `db:"ip" json:"ip"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	LastSeenAt       time.Time  `db:"last_seen_at" json:"last_seen_at"`
+	RevokedAt        *time.Time `db:"revoked_at" json:"-"`
+
+	// Device is parsed from UserAgent when the session is saved
+	Device DeviceFingerprint `json:"device"`
+
+	// RememberMe marks a long-lived session, whose refresh tokens are valid for
+	// REMEMBER_ME_TTL instead of REFRESH_TOKEN_TTL
+	RememberMe bool `db:"remember_me" json:"remember_me"`
+
+	// Current is set by the handler for the session the request was made with
+	Current bool `db:"-" json:"current"`
+}
+
+// DeviceFingerprint is what a User-Agent tells about the device of a session,
+// e.g. {"os": "iOS 17.1", "browser": "Safari 17.1", "device_type": "mobile"}.
+// OS and Browser are empty when the User-Agent does not name them.
+type DeviceFingerprint struct {
+	OS         string `db:"device_os" json:"os" example:"Windows 10"`
+	Browser    string `db:"device_browser" json:"browser" example:"Chrome 120.0.0.0"`
+	DeviceType string `db:"device_type" json:"device_type" enums:"desktop,mobile,tablet" example:"desktop"`
+}
+
+// NewDeviceFingerprint parses a User-Agent header. The device type is desktop
+// unless the User-Agent identifies a tablet (iPad, Android without "Mobile",
+// Kindle) or a phone. An empty User-Agent returns the zero value.
+func NewDeviceFingerprint(userAgent string) DeviceFingerprint {
+	if userAgent == "" {
+		return DeviceFingerprint{}
+	}
+	ua := useragent.New(userAgent)
+
+	device := DeviceFingerprint{DeviceType: DeviceDesktop}
+	os := ua.OSInfo()
+	if os.Name == "OS" && strings.Contains(userAgent, "iPad") {
+		os.Name = "iPadOS" // "CPU OS 17_1 like Mac OS X" names no OS
+	}
+	device.OS = strings.TrimSpace(os.Name + " " + os.Version)
+	if name, version := ua.Browser(); name != "" {
+		device.Browser = strings.TrimSpace(name + " " + version)
+	}
+
+	switch {
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet") || strings.Contains(userAgent, "Kindle") ||
+		(strings.Contains(userAgent, "Android") && !strings.Contains(userAgent, "Mobile")):
+		device.DeviceType = DeviceTablet
+	case ua.Mobile():
+		device.DeviceType = DeviceMobile
+	}
+	// Crafted User-Agents can name anything; keep within the sessions columns
+	device.OS, device.Browser = truncate(device.OS, maxDeviceFieldLen), truncate(device.Browser, maxDeviceFieldLen)
+	return device
+}
+
+// maxDeviceFieldLen is the length of the device_os and device_browser columns
+const maxDeviceFieldLen = 100
+
+// truncate returns s cut to at most n runes
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// ClientInfo describes the client a request came from. It is attached to the
+// request context so services can record it without depending on Gin.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a copy of ctx carrying the given client info.
+func WithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext returns the client info stored in ctx, or the zero
+// value when none was attached.
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
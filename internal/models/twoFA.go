@@ -19,3 +19,11 @@ type TwoFA struct {
 	// Enabled indicates if the user has completed 2FA setup and it is active.
 	Enabled bool `db:"enabled" json:"enabled"`
 }
+
+// TOTPEnrollment is returned when a user starts TOTP setup. The secret is shown
+// once so it can be entered manually if the QR code cannot be scanned.
+type TOTPEnrollment struct {
+	Secret    string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URI       string `json:"otpauth_uri" example:"otpauth://totp/Authentio:jane.doe@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Authentio"`
+	QRCodePNG []byte `json:"qr_code_png"` // base64-encoded in JSON
+}
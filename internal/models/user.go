@@ -1,11 +1,40 @@
 package models
 
+import (
+	"time"
+
+	"authentio/internal/constants"
+)
+
 type User struct {
 	BaseModel
-	FirstName string `json:"first_name" db:"first_name"`
-      LastName  string `json:"last_name" db:"last_name"`
-	Email    string `json:"email" db:"email"`
+	FirstName string `json:"first_name" db:"first_name" example:"Jane"`
+      LastName  string `json:"last_name" db:"last_name" example:"Doe"`
+	Email    string `json:"email" db:"email" example:"jane.doe@example.com"`
 	Password string `json:"-" db:"password"`
-	Provider string `json:"provider" db:"provider"`
-	IsActive bool   `json:"is_active" db:"is_active"`
+	Provider string `json:"provider" db:"provider" example:"local"`
+	// ProviderID is the user's ID at the OAuth provider (empty for email/password accounts)
+	ProviderID string `json:"-" db:"provider_id"`
+	AvatarURL  string `json:"avatar_url,omitempty" db:"avatar_url" example:"https://cdn.example.com/avatars/42.png"`
+	// PhoneNumber is the E.164 number SMS one-time codes are sent to (empty if none)
+	PhoneNumber string `json:"phone_number,omitempty" db:"phone_number" example:"+15551234567"`
+	IsActive bool   `json:"is_active" db:"is_active" example:"true"`
+	// Role is issued as the "role" claim of the user's access tokens
+	Role constants.Role `json:"role" db:"role"`
+	// TenantID is the tenant the user belongs to; nil for the default (single-tenant) installation
+	TenantID *int64 `json:"tenant_id,omitempty" db:"tenant_id" example:"1"`
+	// EmailVerifiedAt is set once the user confirms their email address; nil means unverified
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at" example:"2024-05-01T12:05:00Z"`
+	// DeactivatedAt is set while an operator has deactivated the account; such users cannot sign in
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at" example:"2024-06-01T09:00:00Z"`
+	DeactivationReason string     `json:"deactivation_reason,omitempty" db:"deactivation_reason" example:"Left the company"`
+}
+
+// UserPatch is a partial update of a user's own profile: nil fields are left
+// unchanged, and an empty AvatarURL or PhoneNumber clears it.
+type UserPatch struct {
+	FirstName   *string
+	LastName    *string
+	AvatarURL   *string
+	PhoneNumber *string
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"authentio/internal/constants"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// WebAuthnCredential is a passkey or security key registered by a user.
+type WebAuthnCredential struct {
+	ID         int64                  `db:"id" json:"id"`
+	UserID     int64                  `db:"user_id" json:"-"`
+	Kind       constants.WebAuthnKind `db:"kind" json:"kind"`
+	Credential webauthn.Credential    `db:"credential" json:"-"`
+	CreatedAt  time.Time              `db:"created_at" json:"created_at"`
+	LastUsedAt *time.Time             `db:"last_used_at" json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Webhook is a callback URL that receives auth events, signed with Secret.
+type Webhook struct {
+	ID       int64  `db:"id" json:"id" example:"7"`
+	TenantID *int64 `db:"tenant_id" json:"tenant_id,omitempty" example:"3"`
+	URL      string `db:"url" json:"url" example:"https://hooks.example.com/authentio"`
+
+	// Secret is only returned when the webhook is created
+	Secret string `db:"secret" json:"secret,omitempty" example:"4f9c2a7be1d04c58a3e6b0f1d2c3e4f5"`
+
+	// Events lists the subscribed event types; empty subscribes to every event
+	Events    []string  `db:"events" json:"events" example:"user_registered,user_logged_in"`
+	Active    bool      `db:"active" json:"active" example:"true"`
+	CreatedAt time.Time `db:"created_at" json:"created_at" example:"2024-05-01T12:00:00Z"`
+}
@@ -3,13 +3,24 @@ package repository
 import (
 	"context"
 	_"authentio/internal/models"
-	_"errors"
+	"errors"
+)
+
+var (
+	// ErrTOTPNotEnrolled is returned when a user has no TOTP secret on file
+	ErrTOTPNotEnrolled = errors.New("totp not enrolled")
+
+	// ErrEncryptionKeyMissing is returned when a secret must be stored or read but no encryption key is configured
+	ErrEncryptionKeyMissing = errors.New("database encryption key not configured")
 )
 
 type TwoFARepository interface {
 
 // EnableEmail2FA enables email-based 2FA for a user
 	EnableEmail2FA(ctx context.Context, userID int64) error
+
+	// EnableSMS2FA enables SMS-based 2FA for a user
+	EnableSMS2FA(ctx context.Context, userID int64) error
 	
 	// Disable2FA disables 2FA for a user
 	Disable2FA(ctx context.Context, userID int64) error
@@ -22,4 +33,23 @@ type TwoFARepository interface {
 
 	// VerifyOTP verifies an OTP code for 2FA
 	VerifyOTP(ctx context.Context, userID int64, email, code, otpType string) (bool, error)
+
+	// SaveTOTPSecret stores a new (encrypted) TOTP secret for a user as pending.
+	// The user's current 2FA method and secret stay in force until EnableTOTP is
+	// called after the first successful verification of the new secret.
+	SaveTOTPSecret(ctx context.Context, userID int64, secret string) error
+
+	// GetPendingTOTPSecret returns the decrypted TOTP secret awaiting verification
+	GetPendingTOTPSecret(ctx context.Context, userID int64) (string, error)
+
+	// Get2FASecret returns the decrypted TOTP secret of a user with TOTP enabled
+	Get2FASecret(ctx context.Context, userID int64) (string, error)
+
+	// EnableTOTP replaces the TOTP secret with the pending one and marks TOTP as
+	// the user's active 2FA method, recording step as the last accepted one.
+	EnableTOTP(ctx context.Context, userID int64, step int64) error
+
+	// RecordTOTPStep stores the time step of an accepted TOTP code. It returns false
+	// if the step is not newer than the last recorded one (i.e. the code is a replay).
+	RecordTOTPStep(ctx context.Context, userID int64, step int64) (bool, error)
 }
\ No newline at end of file
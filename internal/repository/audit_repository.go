@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/models"
+)
+
+// AuditFilter selects audit log entries. Zero values match everything.
+type AuditFilter struct {
+	UserID    *int64
+	EventType string
+	From      time.Time // inclusive
+	To        time.Time // exclusive
+}
+
+// Page selects a window of a result set
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+type AuditRepository interface {
+	// Log appends an entry to the audit log
+	Log(ctx context.Context, entry models.AuditEntry) error
+
+	// Query returns one page of entries matching filter, newest first, and the total number of matches
+	Query(ctx context.Context, filter AuditFilter, page Page) ([]models.AuditEntry, int, error)
+
+	// LoginCountries returns the distinct countries the user logged in from since the given time
+	LoginCountries(ctx context.Context, userID int64, since time.Time) ([]string, error)
+}
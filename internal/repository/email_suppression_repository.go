@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+)
+
+type EmailSuppressionRepository interface {
+	// Add puts email on the suppression list, replacing the reason if it is already there
+	Add(ctx context.Context, email, reason string) error
+
+	// IsSuppressed reports whether email is on the suppression list
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"authentio/internal/models"
+)
+
+// ExportRepository collects the personal data stored about a user across all
+// tables, for data portability requests.
+type ExportRepository interface {
+	// ExportUser returns the user's profile, roles, groups, sessions, 2FA
+	// configuration, WebAuthn credentials and audit log, read from a single
+	// transaction snapshot so the parts are consistent with each other.
+	// It returns nil if the user does not exist.
+	ExportUser(ctx context.Context, userID int64) (*models.UserExport, error)
+}
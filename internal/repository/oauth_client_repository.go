@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"authentio/internal/models"
+)
+
+// ErrOAuthClientNotFound is returned when an OAuth client does not exist
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+// OAuthClientRepository stores the third-party applications of the OAuth2
+// authorization server.
+type OAuthClientRepository interface {
+	// Create inserts a new client and sets its ID and creation time
+	Create(ctx context.Context, client *models.OAuthClient) error
+
+	// FindByClientID returns the client with the given client_id, secret hash
+	// included. Returns ErrOAuthClientNotFound.
+	FindByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+
+	// List returns every client, oldest first, without secret hashes
+	List(ctx context.Context) ([]models.OAuthClient, error)
+
+	// Delete removes a client by client_id. Returns ErrOAuthClientNotFound.
+	Delete(ctx context.Context, clientID string) error
+}
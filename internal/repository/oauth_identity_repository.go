@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"authentio/internal/models"
+)
+
+// ErrOAuthIdentityNotFound is returned when a user has no identity of a provider
+var ErrOAuthIdentityNotFound = errors.New("linked account not found")
+
+// OAuthIdentityRepository stores the social provider accounts linked to users.
+// Lookups are restricted to the tenant in the context, if any.
+type OAuthIdentityRepository interface {
+	// Create links an identity to its user and sets its ID and creation time.
+	// Provider tokens are encrypted at rest, or not stored without a key.
+	Create(ctx context.Context, identity *models.OAuthIdentity) error
+
+	// FindByProviderUserID returns the identity of a provider account linked to
+	// a user that is not deleted, tokens excluded. Returns ErrOAuthIdentityNotFound.
+	FindByProviderUserID(ctx context.Context, provider, providerUserID string) (*models.OAuthIdentity, error)
+
+	// ListByUser returns the identities of a user ordered by provider, tokens excluded
+	ListByUser(ctx context.Context, userID int64) ([]models.OAuthIdentity, error)
+
+	// Delete unlinks the identity of provider from a user. Returns ErrOAuthIdentityNotFound.
+	Delete(ctx context.Context, userID int64, provider string) error
+}
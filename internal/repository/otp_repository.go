@@ -2,16 +2,35 @@ package repository
 
 import (
 	"context"
+	"time"
+
 	"authentio/internal/models"
 )
 
+// OTPMaxAttempts is how many wrong codes lock an OTP. Each further run of
+// OTPMaxAttempts failures locks it again, for the next period of OTPLockoutBackoff.
+const OTPMaxAttempts = 5
+
+// OTPLockoutBackoff is how long successive lockouts of an OTP last; lockouts past
+// the last entry repeat it.
+var OTPLockoutBackoff = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute}
+
 type OTPRepository interface {
 	// CreateOTP creates a new OTP code
 	CreateOTP(ctx context.Context, otp *models.OTP) error
 	
-	// VerifyOTP verifies an OTP code and marks it as used
+	// VerifyOTP verifies an OTP code and marks it as used. Locked codes never verify.
 	VerifyOTP(ctx context.Context, email, code, otpType string) (bool, error)
+
+	// FindActiveOTP returns the newest unused, unexpired OTP of the given type sent
+	// to email, or nil if there is none
+	FindActiveOTP(ctx context.Context, email, otpType string) (*models.OTP, error)
+
+	// RecordVerificationAttempt counts a failed verification of the OTP and locks it
+	// after every OTPMaxAttempts failures. It returns the attempts left before the
+	// next lockout, which is 0 when this attempt locked the code.
+	RecordVerificationAttempt(ctx context.Context, otpID int64) (attemptsLeft int, err error)
 	
 	// CleanupExpiredOTPs removes expired OTP codes
 	CleanupExpiredOTPs(ctx context.Context) error
-}
\ No newline at end of file
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+)
+
+type PasswordHistoryRepository interface {
+	// Add records a password hash for a user and keeps only the newest keep entries
+	Add(ctx context.Context, userID int64, hash string, keep int) error
+
+	// Recent returns the user's most recent password hashes, newest first
+	Recent(ctx context.Context, userID int64, limit int) ([]string, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"authentio/internal/models"
+)
+
+// ErrRoleNotFound is returned when a role does not exist or is not assigned to the user
+var ErrRoleNotFound = errors.New("role not found")
+
+type RoleRepository interface {
+	// Create inserts a role with its permissions, creating unknown permissions,
+	// and sets role.ID and its timestamps
+	Create(ctx context.Context, role *models.Role) error
+
+	// FindByID returns a role with its permissions, or nil if it does not exist
+	FindByID(ctx context.Context, id int64) (*models.Role, error)
+
+	// FindByName returns a role with its permissions, or nil if it does not exist
+	FindByName(ctx context.Context, name string) (*models.Role, error)
+
+	// List returns every role with its permissions, ordered by name
+	List(ctx context.Context) ([]models.Role, error)
+
+	// Update renames a role and replaces its permissions. Returns ErrRoleNotFound.
+	Update(ctx context.Context, role *models.Role) error
+
+	// Delete removes a role and unassigns it from every user. Returns ErrRoleNotFound.
+	Delete(ctx context.Context, id int64) error
+
+	// AssignToUser gives a user a role; assigning a role twice is not an error.
+	// Returns ErrRoleNotFound if the role does not exist.
+	AssignToUser(ctx context.Context, userID, roleID int64) error
+
+	// RemoveFromUser takes a role away from a user. Returns ErrRoleNotFound if the
+	// user does not have the role.
+	RemoveFromUser(ctx context.Context, userID, roleID int64) error
+
+	// ListForUser returns the roles of a user, ordered by name
+	ListForUser(ctx context.Context, userID int64) ([]models.Role, error)
+
+	// PermissionsForUser returns the distinct permissions granted by a user's roles, sorted
+	PermissionsForUser(ctx context.Context, userID int64) ([]string, error)
+
+	// UserHasPermission reports whether any of a user's roles grants the permission
+	UserHasPermission(ctx context.Context, userID int64, permission string) (bool, error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+
+	"authentio/internal/models"
+)
+
+type tenantIDKey struct{}
+
+// WithTenantID returns a copy of ctx scoped to a tenant. Every repository
+// restricts its queries to that tenant's users when the context carries one.
+func WithTenantID(ctx context.Context, tenantID int64) context.Context {
+	return context.WithValue(ctx, tenantIDKey{}, tenantID)
+}
+
+// TenantIDFromContext returns the tenant ctx is scoped to, if any.
+func TenantIDFromContext(ctx context.Context) (int64, bool) {
+	tenantID, ok := ctx.Value(tenantIDKey{}).(int64)
+	return tenantID, ok
+}
+
+// TenantRepository defines the interface for tenant-related database operations
+type TenantRepository interface {
+	// FindByID finds a tenant by ID; returns nil, nil when it does not exist
+	FindByID(ctx context.Context, id int64) (*models.Tenant, error)
+
+	// FindBySlug finds a tenant by slug; returns nil, nil when it does not exist
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+
+	// Create inserts a new tenant
+	Create(ctx context.Context, tenant *models.Tenant) error
+}
@@ -3,6 +3,20 @@ package repository
 import (
 	"authentio/internal/models"
 	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrRefreshTokenNotFound is returned when a refresh token does not exist, is expired or was revoked
+	ErrRefreshTokenNotFound = errors.New("token not found or expired")
+
+	// ErrRefreshTokenReused is returned when an already-rotated refresh token is presented again.
+	// By the time it is returned, every token in the same family has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+	// ErrSessionNotFound is returned when a session does not exist, belongs to another user or was already revoked
+	ErrSessionNotFound = errors.New("session not found")
 )
 
 // TokenRepository defines the interface for token-related database operations
@@ -13,12 +27,43 @@ type TokenRepository interface {
 	// GetRefreshToken retrieves a refresh token by its token string
 	GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error)
 
+	// RotateRefreshToken atomically marks oldToken as used and stores newToken in the same family.
+	// If oldToken was already used, the whole family is revoked and ErrRefreshTokenReused is returned.
+	RotateRefreshToken(ctx context.Context, oldToken string, newToken *models.RefreshToken) error
+
+	// RevokeTokenFamily revokes every refresh token that belongs to the given family
+	RevokeTokenFamily(ctx context.Context, familyID string) error
+
 	// DeleteRefreshToken removes a refresh token (used during logout or token rotation)
 	DeleteRefreshToken(ctx context.Context, token string) error
 
 	// DeleteUserRefreshTokens removes all refresh tokens for a specific user
 	DeleteUserRefreshTokens(ctx context.Context, userID int64) error
 
+	// DeleteAllRefreshTokens removes the refresh tokens of every user and ends every session
+	DeleteAllRefreshTokens(ctx context.Context) error
+
 	// CleanupExpiredTokens removes all expired refresh tokens
 	CleanupExpiredTokens(ctx context.Context) error
-}
\ No newline at end of file
+
+	// SaveSession creates the session or, if it already exists and is not revoked,
+	// updates its refresh token hash, client info and last_seen_at
+	SaveSession(ctx context.Context, session *models.Session) error
+
+	// ListSessions returns the active sessions of a user, most recently used first
+	ListSessions(ctx context.Context, userID int64) ([]models.Session, error)
+
+	// GetSession returns an active session by ID, or ErrSessionNotFound
+	GetSession(ctx context.Context, sessionID string) (*models.Session, error)
+
+	// RevokeSession revokes a user's session together with its refresh token family
+	RevokeSession(ctx context.Context, userID int64, sessionID string) error
+
+	// IsSessionActive reports whether the session exists and has not been revoked
+	IsSessionActive(ctx context.Context, sessionID string) (bool, error)
+
+	// ExtendSession moves the expiry of the session's current refresh token out to
+	// expiresAt and sets its last_seen_at to now. Returns ErrSessionNotFound if the
+	// session is not active.
+	ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error
+}
@@ -2,9 +2,89 @@ package repository
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"authentio/internal/models"
 )
 
+// UserFilter selects the users returned by List. Zero-valued fields match all users.
+type UserFilter struct {
+	// Email restricts the result to the user with this email (case-insensitive)
+	Email string
+
+	// EmailLike restricts the result to emails containing this substring (case-insensitive)
+	EmailLike string
+
+	// CreatedAfter restricts the result to users created after this time
+	CreatedAfter *time.Time
+
+	// IsVerified restricts the result to users with (true) or without (false) a verified email
+	IsVerified *bool
+
+	// IncludeDeleted also returns soft-deleted users, which are left out by default
+	IncludeDeleted bool
+
+	// Offset skips the first Offset matches (offset pagination, e.g. SCIM startIndex).
+	// Prefer a Cursor for large tables: it stays stable while users are added.
+	Offset int
+
+	// Limit is the maximum number of users per page
+	Limit int
+}
+
+// Cursor is the keyset position after which List continues: users are ordered
+// by (created_at, id), and the cursor holds those values of the last user of
+// the previous page.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+// ErrInvalidCursor is returned by DecodeCursor for malformed cursors
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the cursor as an opaque, URL-safe string.
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor produced by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// UserPage is one page of List results
+type UserPage struct {
+	Users []models.User
+
+	// NextCursor continues the listing after this page; nil on the last page
+	NextCursor *Cursor
+
+	// Total is the number of users matching the filter, across all pages
+	Total int
+}
+
 type UserRepository interface {
 	// FindByEmail finds a user by email address
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
@@ -12,12 +92,64 @@ type UserRepository interface {
 	// FindByID finds a user by ID
 	FindByID(ctx context.Context, id int64) (*models.User, error)
 	
+	// FindByProvider finds a user by their OAuth provider identity
+	FindByProvider(ctx context.Context, provider, providerID string) (*models.User, error)
+
+	// LinkProvider attaches an OAuth provider identity to an existing user
+	LinkProvider(ctx context.Context, userID int64, provider, providerID, avatarURL string) error
+
+	// MarkEmailVerified records that the user has verified their email address
+	MarkEmailVerified(ctx context.Context, userID int64) error
+
+	// UpdatePassword replaces the user's password hash
+	UpdatePassword(ctx context.Context, userID int64, hash string) error
+
+	// SetPendingEmail stores an address the user asked to change their email
+	// to, replacing any earlier one; empty clears it
+	SetPendingEmail(ctx context.Context, userID int64, email string) error
+
+	// FindPendingEmail returns the user's pending email, or "" if none
+	FindPendingEmail(ctx context.Context, userID int64) (string, error)
+
+	// ConfirmPendingEmail makes the pending email the user's email, marks it
+	// verified and clears the pending field. It reports false, changing
+	// nothing, unless the pending email is still email.
+	ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error)
+
+	// UpdatePhoneNumber sets the number SMS one-time codes are sent to; empty clears it
+	UpdatePhoneNumber(ctx context.Context, userID int64, phoneNumber string) error
+
+	// List returns one page of users matching filter, ordered by (created_at, id).
+	// A nil cursor starts at the first user; otherwise the page starts after it.
+	List(ctx context.Context, filter UserFilter, cursor *Cursor) (*UserPage, error)
+
 	// Create inserts a new user into the database
 	Create(ctx context.Context, user *models.User) error
+
+	// FindOrCreate returns the user with the provider identity of user (Provider
+	// and ProviderID), inserting user when there is none. It reports whether the
+	// user was created.
+	FindOrCreate(ctx context.Context, user *models.User) (*models.User, bool, error)
+
+	// CreateBatch inserts users in a single transaction: either all of them are
+	// created or, on the first error, none are
+	CreateBatch(ctx context.Context, users []*models.User) error
 	
 	// Update updates an existing user
 	Update(ctx context.Context, user *models.User) error
+
+	// Patch applies a partial profile update and returns the updated user, or
+	// nil if no such user exists
+	Patch(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error)
 	
+	// Deactivate marks a user inactive and records when and why; reactivating
+	// through Update (IsActive = true) clears both
+	Deactivate(ctx context.Context, id int64, reason string) error
+
 	// Delete soft deletes a user
 	Delete(ctx context.Context, id int64) error
+
+	// ReplaceExternalGroups sets the user's external directory groups and
+	// returns the groups they had before
+	ReplaceExternalGroups(ctx context.Context, userID int64, groups []string) ([]string, error)
 }
\ No newline at end of file
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+
+	"authentio/internal/models"
+)
+
+// WebAuthnRepository stores the passkeys and security keys (WebAuthn credentials) registered by users
+type WebAuthnRepository interface {
+	// Create stores a newly registered credential; an empty Kind stores a passkey
+	Create(ctx context.Context, credential *models.WebAuthnCredential) error
+
+	// FindByUserID returns every credential registered by a user, of both kinds, oldest first
+	FindByUserID(ctx context.Context, userID int64) ([]models.WebAuthnCredential, error)
+
+	// UpdateAfterLogin persists the credential's new sign count and flags and sets last_used_at
+	UpdateAfterLogin(ctx context.Context, credential *models.WebAuthnCredential) error
+}
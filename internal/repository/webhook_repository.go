@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"authentio/internal/models"
+)
+
+// ErrWebhookNotFound is returned when a webhook does not exist
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+type WebhookRepository interface {
+	// Create inserts a new webhook and sets its ID and creation time
+	Create(ctx context.Context, webhook *models.Webhook) error
+
+	// List returns every webhook, oldest first, without secrets
+	List(ctx context.Context) ([]models.Webhook, error)
+
+	// Delete removes a webhook. Returns ErrWebhookNotFound.
+	Delete(ctx context.Context, id int64) error
+
+	// ListForEvent returns the active webhooks of tenantID (nil for users
+	// without a tenant) subscribed to eventType, secrets included
+	ListForEvent(ctx context.Context, tenantID *int64, eventType string) ([]models.Webhook, error)
+}
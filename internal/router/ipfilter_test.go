@@ -0,0 +1,66 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"authentio/internal/constants"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func newIPFilterEngine(t *testing.T, trustedProxies []string) (*gin.Engine, *miniredis.Miniredis) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", IPFilterMiddleware(rdb, constants.IPFilterBlocklist), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r, mr
+}
+
+func serveFrom(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestIPFilterIgnoresForwardedForWithoutTrustedProxies(t *testing.T) {
+	r, mr := newIPFilterEngine(t, nil)
+	mr.SAdd(constants.IPBlocklistKey, "203.0.113.7")
+
+	if code := serveFrom(r, "203.0.113.7:4000", "198.51.100.1"); code != http.StatusForbidden {
+		t.Fatalf("blocked client with forged X-Forwarded-For got %d, want 403", code)
+	}
+}
+
+func TestIPFilterUsesForwardedForFromTrustedProxy(t *testing.T) {
+	r, mr := newIPFilterEngine(t, []string{"10.0.0.0/8"})
+	mr.SAdd(constants.IPBlocklistKey, "203.0.113.7")
+
+	if code := serveFrom(r, "10.0.0.2:4000", "203.0.113.7"); code != http.StatusForbidden {
+		t.Fatalf("blocked client behind trusted proxy got %d, want 403", code)
+	}
+	if code := serveFrom(r, "10.0.0.2:4000", "198.51.100.1"); code != http.StatusOK {
+		t.Fatalf("allowed client behind trusted proxy got %d, want 200", code)
+	}
+	if code := serveFrom(r, "203.0.113.7:4000", "198.51.100.1"); code != http.StatusForbidden {
+		t.Fatalf("blocked client claiming to be forwarded by an untrusted peer got %d, want 403", code)
+	}
+}
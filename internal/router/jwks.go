@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+
+	"authentio/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksHandler godoc
+// @Summary JSON Web Key Set
+// @Description Public keys that verify access tokens, matched to tokens by their kid header. After a key rotation both the current and the previous key are listed. Empty when tokens are signed with a shared HMAC secret.
+// @Tags authentication
+// @Produce json
+// @Success 200 {object} jwt.JWKSet "Verification keys"
+// @Router /auth/.well-known/jwks.json [get]
+func jwksHandler(jwtManager *jwt.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Verifiers may cache the set briefly; rotations keep the old key listed
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwtManager.JWKS())
+	}
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"authentio/internal/constants"
+	"authentio/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// =============================================================================
+// Permission Guards
+// =============================================================================
+
+// RequirePermission returns a middleware restricting the routes it is attached to
+// to users whose access token grants permission through their RBAC roles. Users
+// with the admin role are always allowed. It must run after the JWT middleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return middleware.PermissionRequired(permission, string(constants.RoleAdmin))
+}
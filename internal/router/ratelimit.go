@@ -0,0 +1,79 @@
+package router
+
+import (
+	"time"
+
+	"authentio/internal/middleware"
+	"authentio/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Per-Route Rate Limiting
+// =============================================================================
+
+// RateLimitConfig describes an independent request limit for a single route.
+type RateLimitConfig struct {
+	// Name scopes the limiter's counters (e.g. "login") so they are separate from other routes
+	Name string
+
+	// Window is the sliding time window the limit applies to
+	Window time.Duration
+
+	// MaxRequests is the number of requests allowed per client IP within Window
+	MaxRequests int
+
+	// Redis backs the limiter so limits hold across instances; nil uses in-memory counters
+	Redis *redis.Client
+}
+
+// RouteRateLimits holds the per-route limits applied by SetupRouter.
+type RouteRateLimits struct {
+	Login    RateLimitConfig
+	Register RateLimitConfig
+
+	// User limits authenticated requests per user instead of per client IP
+	User UserRateLimitConfig
+}
+
+// UserRateLimitConfig describes the per-user limit of authenticated requests.
+type UserRateLimitConfig struct {
+	// MaxRequests is the burst a user may send to one route, refilled at
+	// MaxRequests per Window; zero disables per-user limiting
+	MaxRequests int
+
+	// Window is how long an empty bucket takes to refill
+	Window time.Duration
+
+	// JWT verifies the access tokens users are identified by
+	JWT *jwt.Manager
+
+	// Sessions, when set, only identifies users by tokens of an active session
+	Sessions middleware.SessionChecker
+}
+
+// WithRateLimit returns a middleware enforcing cfg on the routes it is attached to.
+// With a Redis client it uses the distributed sliding-window limiter; without one
+// (development) it falls back to the in-memory limiter.
+func WithRateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	if cfg.Redis == nil {
+		return middleware.NewInMemoryRateLimiter(cfg.MaxRequests, cfg.Window).Handle
+	}
+	return middleware.NewRouteRateLimiter(cfg.Redis, cfg.Name, cfg.MaxRequests, cfg.Window).Handle
+}
+
+// UserRateLimitMiddleware limits requests carrying a valid access token per
+// user and route with a Redis token bucket, so high-frequency endpoints such
+// as /me and /auth/refresh are limited per user rather than per IP. It runs
+// before the global IP limiter, which then skips the requests it charged (see
+// middleware.SkipIfUserRateLimited). It panics on a non-positive MaxRequests
+// or Window, which config validation rejects.
+func UserRateLimitMiddleware(rdb *redis.Client, cfg UserRateLimitConfig) gin.HandlerFunc {
+	limiter, err := middleware.NewUserRateLimiter(rdb, cfg.JWT, cfg.Sessions, cfg.MaxRequests, cfg.Window)
+	if err != nil {
+		panic(err)
+	}
+	return limiter.Handle
+}
@@ -3,14 +3,18 @@ package router
 import (
 	"net/http"
 	"os"
+	"time"
 
+	"authentio/internal/constants"
 	"authentio/internal/handler"
 	"authentio/internal/middleware"
+	"authentio/internal/repository"
 	"authentio/pkg/jwt"
 	"authentio/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	// Swagger imports
@@ -18,6 +22,110 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// Options holds optional router features configured from the application config.
+type Options struct {
+	RateLimits RouteRateLimits
+	Metrics    MetricsConfig
+
+	// CORS configures cross-origin requests; no allowed origins disables CORS
+	CORS middleware.CORSConfig
+
+	// SecurityHeaders overrides the OWASP security headers set on every response
+	SecurityHeaders middleware.SecurityHeadersConfig
+
+	// MaxBodyBytes caps request bodies; zero disables the limit
+	MaxBodyBytes int64
+
+	// UserImportMaxBytes caps CSV uploads to the user import endpoint, which is
+	// exempt from MaxBodyBytes; zero disables the limit
+	UserImportMaxBytes int64
+
+	// IPFilterMode ("allowlist" or "blocklist") filters /api/v1 by client IP
+	// against Redis sets; empty disables filtering
+	IPFilterMode string
+
+	// TrustedProxies are the proxies (IPs or CIDRs) whose forwarding headers
+	// c.ClientIP() believes; empty trusts none, so only RemoteAddr counts
+	TrustedProxies []string
+
+	// InFlight counts requests so shutdown can drain them; nil disables counting
+	InFlight *middleware.InFlight
+
+	// TracerProvider records a span per request; nil disables tracing
+	TracerProvider trace.TracerProvider
+
+	// AdminToken protects /api/v1/admin; empty disables the admin API
+	AdminToken string
+
+	// SCIMToken protects /scim/v2; empty disables SCIM provisioning
+	SCIMToken string
+
+	// AdminServices and SCIMServices list the client certificates (by CN or OU)
+	// accepted in place of AdminToken and SCIMToken; empty accepts none
+	AdminServices middleware.ServiceACL
+	SCIMServices  middleware.ServiceACL
+
+	// CSRFSecret enables CSRF protection of cookie-authenticated requests to
+	// /api/v1; empty disables it
+	CSRFSecret []byte
+
+	// EmailWebhookSecret protects /webhooks/email; the email webhooks are disabled
+	// when neither it nor EmailWebhookSigningKey is set
+	EmailWebhookSecret string
+
+	// EmailWebhookSigningKey requires /webhooks/email requests to be signed with
+	// EmailWebhookSignatureScheme (see middleware.VerifyWebhookSignature), in the
+	// EmailWebhookSignatureHeader or the scheme's default header; empty skips the check
+	EmailWebhookSigningKey      string
+	EmailWebhookSignatureHeader string
+	EmailWebhookSignatureScheme string
+
+	// IdempotencyTTL is how long responses to registration and password reset
+	// requests sent with an Idempotency-Key are replayed; zero disables it
+	IdempotencyTTL time.Duration
+
+	// SwaggerEnabled serves the generated API documentation at /swagger/index.html
+	SwaggerEnabled bool
+
+	// IntrospectionSecret protects /api/v1/auth/introspect; empty disables it
+	IntrospectionSecret string
+
+	// SessionChecker, when set, makes every authenticated request check that the
+	// token's session is still active (SESSION_VALIDATION)
+	SessionChecker middleware.SessionChecker
+
+	// ClaimsCache, when set, lets authenticated requests with a recently
+	// validated access token skip its signature and session checks
+	ClaimsCache middleware.ClaimsCache
+
+	// SessionSlider, when set, extends the session of every authenticated
+	// request once half of its lifetime has passed (SLIDING_SESSION_ENABLED)
+	SessionSlider middleware.SessionSlider
+
+	// ExternalTokens, when set, also accepts access tokens of an external identity
+	// provider (EXTERNAL_JWKS_URI) on authenticated routes
+	ExternalTokens middleware.ExternalTokenAuthenticator
+
+	// ExternalJWTVerifier, when set, authenticates requests to authenticated
+	// routes with tokens of an external identity provider only, provisioning a
+	// local user in ExternalUsers for each subject (EXTERNAL_JWT_FEDERATION); see
+	// middleware.ExternalJWTMiddleware. ExternalUserPermissions is optional
+	ExternalJWTVerifier     jwt.Verifier
+	ExternalUsers           middleware.ExternalUserProvisioner
+	ExternalUserPermissions middleware.PermissionLister
+
+	// Tenants enables multi-tenancy: every route except /api/v1/admin resolves the
+	// tenant from X-Tenant-ID or the subdomain of TenantBaseDomain
+	Tenants          repository.TenantRepository
+	TenantBaseDomain string
+}
+
+// userImportRoute accepts uploads larger than Options.MaxBodyBytes
+const userImportRoute = "/api/v1/admin/users/import"
+
+// swaggerRoute serves the Swagger UI, whose scripts the default Content-Security-Policy blocks
+const swaggerRoute = "/swagger/*any"
+
 // SetupRouter godoc
 // @title Authentio API
 // @version 1.0
@@ -42,13 +150,41 @@ import (
 //   - h: Handler instance containing all route handlers
 //   - redis: Redis client for rate limiting and token blacklisting
 //   - jwtManager: JWT manager for token validation and generation
+//   - opts: Optional features (per-route rate limits, metrics)
 //
 // Returns:
 //   - *gin.Engine: Fully configured Gin router ready to serve HTTP requests
-func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manager) *gin.Engine {
+func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manager, opts Options) *gin.Engine {
+	rateLimits := opts.RateLimits
+	// DPoP-bound access tokens (RFC 9449) must come with a proof on every protected route
+	authRequired := []gin.HandlerFunc{
+		middleware.AuthRequired(jwtManager, opts.SessionChecker, opts.ExternalTokens, opts.SessionSlider, opts.ClaimsCache),
+		middleware.DPoP(jwtManager),
+	}
+	if opts.ExternalJWTVerifier != nil {
+		authRequired[0] = middleware.ExternalJWTMiddleware(opts.ExternalJWTVerifier, opts.ExternalUsers, opts.ExternalUserPermissions)
+	}
+
+	// Token endpoints bind the tokens they issue to the key of a DPoP proof, when sent
+	dpopBinding := middleware.DPoPBinding(jwtManager)
+	idempotent := middleware.IdempotencyMiddleware(redis, opts.IdempotencyTTL)
+
+	// Tenant resolution for every group except the admin API, which spans tenants
+	var tenantScoped []gin.HandlerFunc
+	if opts.Tenants != nil {
+		tenantScoped = append(tenantScoped, middleware.TenantMiddleware(opts.Tenants, opts.TenantBaseDomain))
+	}
+
 	// Initialize the Gin engine with default middleware
 	r := gin.New()
 
+	// Without trusted proxies gin would believe any client's X-Forwarded-For, and
+	// the IP filter and rate limits could be bypassed with a forged header
+	if err := r.SetTrustedProxies(opts.TrustedProxies); err != nil {
+		logger.Error("invalid trusted proxies, trusting none", "error", err)
+		_ = r.SetTrustedProxies(nil)
+	}
+
 	// =========================================================================
 	// Global Middleware Stack
 	// =========================================================================
@@ -56,11 +192,42 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 	// Recovery middleware recovers from any panics and returns a 500 error
 	r.Use(gin.Recovery())
 
-	// Custom structured request logger for consistent request logging
-	r.Use(middleware.RequestLogger())
+	// In-flight request counting for graceful shutdown; rejects requests once draining
+	if opts.InFlight != nil {
+		r.Use(opts.InFlight.Middleware())
+	}
+
+	// OpenTelemetry server span per request, continuing incoming W3C traceparent headers
+	if opts.TracerProvider != nil {
+		r.Use(middleware.TracingMiddleware(opts.TracerProvider))
+	}
+
+	// Prometheus instrumentation (request count, latency, in-flight requests)
+	if opts.Metrics.Enabled {
+		r.Use(PrometheusMiddleware())
+	}
+
+	// X-Request-ID passthrough (generated when absent) for the log line below
+	r.Use(middleware.RequestID())
+
+	// Correlation IDs + structured request/response logging (one line per request).
+	// The X-Correlation-ID header is reused if present and echoed on the response.
+	r.Use(logger.CorrelationMiddleware())
 
-	// CORS middleware handles Cross-Origin Resource Sharing headers
-	r.Use(middleware.CORSMiddleware())
+	// HSTS, CSP, nosniff, frame, referrer and permissions policies on every response
+	r.Use(middleware.SecurityHeaders(opts.SecurityHeaders, swaggerRoute))
+
+	// Client User-Agent and IP on the request context (recorded on sessions)
+	r.Use(middleware.ClientInfoMiddleware())
+
+	// CORS middleware handles Cross-Origin Resource Sharing headers and answers
+	// preflight requests before rate limiting and authentication run
+	if len(opts.CORS.AllowedOrigins) > 0 {
+		r.Use(middleware.CORSMiddleware(opts.CORS))
+	}
+
+	// Reject oversized request bodies before any handler buffers them
+	r.Use(middleware.MaxBodySizeMiddleware(opts.MaxBodyBytes, userImportRoute))
 
 	// GeoIP middleware extracts geographical information from client IP addresses
 	// Used for security monitoring and regional access control
@@ -69,8 +236,17 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 	// Environment-specific rate limiting
 	// In production: Use Redis-based distributed rate limiting for scalability
 	// In development: Use in-memory rate limiting for simplicity
+	// Requests with a valid access token are limited per user instead of per IP,
+	// so users sharing an IP (NAT) do not exhaust each other's quota
 	if os.Getenv("APP_ENV") == "production" {
-		r.Use(middleware.RateLimiterMiddlewareRedis(redis))
+		if rateLimits.User.MaxRequests > 0 {
+			rateLimits.User.JWT = jwtManager
+			rateLimits.User.Sessions = opts.SessionChecker
+			r.Use(UserRateLimitMiddleware(redis, rateLimits.User))
+		}
+		r.Use(middleware.SkipIfUserRateLimited(middleware.RateLimiterMiddlewareRedis(redis)))
+		rateLimits.Login.Redis = redis
+		rateLimits.Register.Redis = redis
 	} else {
 		r.Use(middleware.RateLimiterMiddlewareInMem())
 	}
@@ -89,19 +265,53 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Kubernetes-style probes: liveness (process up) and readiness (Postgres + Redis reachable)
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+
+	// Connection pool statistics for operators, behind the ADMIN_API_TOKEN
+	debug := r.Group("/debug")
+	debug.Use(middleware.MTLSMiddleware(), middleware.AdminTokenRequired(opts.AdminToken, opts.AdminServices))
+	{
+		debug.GET("/db-stats", h.DBStats)
+	}
+
+	// Prometheus scrape endpoint, optionally protected by a bearer token
+	if opts.Metrics.Enabled {
+		r.GET("/metrics", metricsHandler(opts.Metrics.Token))
+	}
+
 	// Swagger documentation endpoint
 	// Serves auto-generated API documentation at /swagger/index.html
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	if opts.SwaggerEnabled {
+		r.GET(swaggerRoute, ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
 
 	// =========================================================================
 	// API v1 Routes - Main Application Endpoints
 	// =========================================================================
-	api := r.Group("/api/v1")
+	// Health checks and metrics above stay reachable for load balancers and
+	// scrapers whatever the IP filter says
+	api := r.Group("/api/v1", IPFilterMiddleware(redis, opts.IPFilterMode))
+
+	// Signed double-submit CSRF tokens for browsers using cookies; requests
+	// with a bearer token are not checked
+	if len(opts.CSRFSecret) > 0 {
+		api.Use(middleware.CSRFMiddleware(opts.CSRFSecret))
+	}
 	{
 		// =====================================================================
 		// Authentication Routes - Public access
 		// =====================================================================
-		auth := api.Group("/auth")
+		// Public keys to verify access tokens with, outside tenant resolution
+		// since the signing keys are shared by every tenant
+		api.GET("/auth/.well-known/jwks.json", jwksHandler(jwtManager))
+
+		// RFC 7662 token introspection for resource servers, authenticated with
+		// INTROSPECTION_SECRET; tokens of every tenant can be introspected
+		api.POST("/auth/introspect", middleware.IntrospectionTokenRequired(opts.IntrospectionSecret), h.Introspect)
+
+		auth := api.Group("/auth", tenantScoped...)
 		{
 			// Google OAuth2 authentication endpoints
 			// Frontend sends ID token directly (mobile/app flow)
@@ -114,52 +324,214 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 			// OAuth callback endpoint - Google redirects here with authorization code
 			auth.GET("/google/callback", h.GoogleCallback)
 
-			// Basic email/password authentication
+			// Generic OAuth2 social login (google, github)
+			// Redirects to the provider's consent screen with a CSRF state cookie
+			auth.GET("/oauth/:provider", h.OAuthRedirect)
+
+			// Provider redirects here with code and state; returns a JWT token pair
+			auth.GET("/oauth/:provider/callback", h.OAuthCallback)
+
+			// PKCE flow for public clients (SPA/mobile): returns auth URL, state and code_verifier;
+			// the client then POSTs code, state and code_verifier to the callback
+			auth.GET("/oauth/:provider/authorize", h.OAuthAuthorize)
+			auth.POST("/oauth/:provider/callback", h.OAuthCallback)
+
+			// Basic email/password authentication. JSON bodies of the public auth
+			// routes are checked against schemas in internal/middleware/schemas first.
+			// Registration and password reset accept an Idempotency-Key header, so
+			// retried requests get the first response instead of running twice.
 			// User registration with email verification
-			auth.POST("/register", h.Register)
+			auth.POST("/register", WithRateLimit(rateLimits.Register), idempotent, middleware.JSONSchemaMiddleware("register"), h.Register)
 
 			// User login with credentials, returns JWT tokens
-			auth.POST("/login", h.Login)
+			auth.POST("/login", WithRateLimit(rateLimits.Login), dpopBinding, middleware.JSONSchemaMiddleware("login"), h.Login)
+
+			// Email verification link target (token is sent by email on registration)
+			auth.GET("/verify-email", h.VerifyEmail)
+
+			// Passwordless login: email a single-use link, then redeem it for JWT tokens
+			auth.POST("/magic-link", WithRateLimit(rateLimits.Login), middleware.JSONSchemaMiddleware("magic_link"), h.RequestMagicLink)
+			auth.GET("/magic-link/verify", WithRateLimit(rateLimits.Login), h.VerifyMagicLink)
 
 			// Refresh access token using valid refresh token
-			auth.POST("/refresh", h.Refresh)
+			auth.POST("/refresh", dpopBinding, middleware.JSONSchemaMiddleware("refresh"), h.Refresh)
+
+			// End the session of a refresh token and revoke the access token
+			// sent along, which may already have expired
+			auth.POST("/logout", middleware.JSONSchemaMiddleware("refresh"), h.Logout)
 
 			// Password reset flow
 			// Step 1: Request password reset (sends email with reset code)
-			auth.POST("/forgot-password", h.ForgotPassword)
+			auth.POST("/forgot-password", idempotent, middleware.JSONSchemaMiddleware("forgot_password"), h.ForgotPassword)
 
 			// Step 2: Verify reset code and set new password
-			auth.POST("/reset-password", h.ResetPassword)
+			auth.POST("/reset-password", idempotent, middleware.JSONSchemaMiddleware("reset_password"), h.ResetPassword)
 
 			// Public 2FA verification endpoint
 			// Used during login flow after credentials are verified
-			auth.POST("/2fa/verify", h.Verify2FA)
+			auth.POST("/2fa/verify", middleware.JSONSchemaMiddleware("verify_2fa"), h.Verify2FA)
+
+			// Passkey (WebAuthn) login: begin returns the assertion options,
+			// finish verifies the authenticator response and returns JWT tokens
+			auth.POST("/webauthn/login/begin", WithRateLimit(rateLimits.Login), middleware.JSONSchemaMiddleware("webauthn_login_begin"), h.BeginWebAuthnLogin)
+			auth.POST("/webauthn/login/finish", WithRateLimit(rateLimits.Login), dpopBinding, h.FinishWebAuthnLogin)
+
+			// Second step of a password login for accounts with a security key
+			auth.POST("/2fa/security-key/verify", WithRateLimit(rateLimits.Login), dpopBinding, h.VerifySecurityKey)
+
+			// Second step of a password login for accounts with an authenticator app
+			auth.POST("/2fa/totp/verify", WithRateLimit(rateLimits.Login), dpopBinding, h.VerifyTOTPLogin)
+		}
+
+		// =====================================================================
+		// Session Management - Protected routes
+		// Requires valid JWT token
+		// =====================================================================
+		sessions := api.Group("/auth/sessions", tenantScoped...)
+		sessions.Use(authRequired...) // JWT authentication required
+		{
+			// List the user's logged-in devices
+			sessions.GET("", h.ListSessions)
+
+			// Log out a single device
+			sessions.DELETE("/:id", h.RevokeSession)
+		}
+
+		// =====================================================================
+		// Passkey Registration - Protected routes
+		// Requires valid JWT token
+		// =====================================================================
+		webAuthn := api.Group("/auth/webauthn/register", tenantScoped...)
+		webAuthn.Use(authRequired...) // JWT authentication required
+		{
+			// Returns the credential creation options for navigator.credentials.create()
+			webAuthn.POST("/begin", h.BeginWebAuthnRegistration)
+
+			// Verifies the attestation and stores the passkey
+			webAuthn.POST("/finish", h.FinishWebAuthnRegistration)
 		}
 
 		// =====================================================================
 		// Two-Factor Authentication Management - Protected routes
 		// Requires valid JWT token
 		// =====================================================================
-		twoFA := api.Group("/2fa")
-		twoFA.Use(middleware.AuthRequired(jwtManager)) // JWT authentication required
+		twoFA := api.Group("/2fa", tenantScoped...)
+		twoFA.Use(authRequired...) // JWT authentication required
 		{
 			// Enable email-based 2FA for the authenticated user
 			twoFA.POST("/enableOtp", h.EnableEmail2FA)
 
+			// Enable SMS-based 2FA with the given phone number
+			twoFA.POST("/enableSms", h.EnableSMS2FA)
+
 			// Disable 2FA for the authenticated user
 			twoFA.POST("/disableOtp", h.Disable2FA)
 
 			// Send a new 2FA OTP code to the user's email
 			// Used when user needs a new code or previous code expired
 			twoFA.POST("/sendOtp", h.SendOTP)
+
+			// Start authenticator-app (TOTP) setup: returns secret, otpauth URI and QR code
+			twoFA.POST("/totp/enroll", h.EnrollTOTP)
+
+			// Verify a TOTP code; the first success enables TOTP for the user
+			twoFA.POST("/totp/verify", h.VerifyTOTP)
+
+			// Register a hardware security key (FIDO2) challenged on every password login
+			twoFA.POST("/security-keys/register/begin", h.BeginSecurityKeyRegistration)
+			twoFA.POST("/security-keys/register/finish", h.FinishSecurityKeyRegistration)
+		}
+
+		// =====================================================================
+		// Administration - Operator-only routes
+		// Requires the ADMIN_API_TOKEN bearer token or a client certificate
+		// =====================================================================
+		admin := api.Group("/admin")
+		admin.Use(middleware.MTLSMiddleware(), middleware.AdminTokenRequired(opts.AdminToken, opts.AdminServices))
+		{
+			// List users with filters and cursor pagination
+			admin.GET("/users", h.ListUsers)
+
+			// Create many users from an uploaded CSV file
+			admin.POST("/users/import", middleware.MaxBodySizeMiddleware(opts.UserImportMaxBytes), h.ImportUsers)
+
+			// Lift a failed-login lockout before it expires
+			admin.POST("/users/:id/unlock", h.UnlockUser)
+
+			// Sign one user, or everyone after a security incident, out everywhere
+			admin.POST("/users/:id/revoke-tokens", h.RevokeUserTokens)
+			admin.POST("/revoke-all", h.RevokeAllTokens)
+
+			// Active sessions of a user, with the device parsed from each User-Agent
+			admin.GET("/users/:id/sessions", h.ListUserSessions)
+
+			// Per-user feature flags, embedded in access tokens issued afterwards
+			admin.GET("/users/:id/flags", h.ListUserFlags)
+			admin.PUT("/users/:id/flags/:flag", h.SetUserFlag)
+
+			// Block client IPs, permanently or for a while (IP_FILTER_MODE=blocklist)
+			admin.POST("/ip-blocklist", h.BlockIP)
+			admin.DELETE("/ip-blocklist/:ip", h.UnblockIP)
+
+			// Deactivate an account, or soft-delete it
+			admin.POST("/users/:id/deactivate", h.DeactivateUser)
+			admin.DELETE("/users/:id", h.DeleteUser)
+
+			// Query the audit log by user, event type and time range
+			admin.GET("/audit-logs", h.ListAuditLogs)
+
+			// Manage RBAC roles and the permissions they grant
+			admin.GET("/roles", h.ListRoles)
+			admin.POST("/roles", h.CreateRole)
+			admin.GET("/roles/:id", h.GetRole)
+			admin.PUT("/roles/:id", h.UpdateRole)
+			admin.DELETE("/roles/:id", h.DeleteRole)
+
+			// Assign roles to users and take them away
+			admin.GET("/users/:id/roles", h.ListUserRoles)
+			admin.POST("/users/:id/roles", h.AssignRole)
+			admin.DELETE("/users/:id/roles/:roleId", h.RemoveRole)
+
+			// Register callback URLs that receive auth events
+			admin.GET("/webhooks", h.ListWebhooks)
+			admin.POST("/webhooks", h.CreateWebhook)
+			admin.DELETE("/webhooks/:id", h.DeleteWebhook)
+
+			// Register third-party applications of the OAuth2 authorization server
+			admin.GET("/oauth-clients", h.ListOAuthClients)
+			admin.POST("/oauth-clients", h.CreateOAuthClient)
+			admin.DELETE("/oauth-clients/:client_id", h.DeleteOAuthClient)
+		}
+
+		// =====================================================================
+		// Administration - Admin-user routes
+		// Requires a JWT access token with the role=admin claim or the
+		// permission guarding the route
+		// =====================================================================
+		adminUsers := api.Group("/admin", tenantScoped...)
+		adminUsers.Use(authRequired...)
+		{
+			// Issue a short-lived token to act as another user (audited)
+			adminUsers.POST("/users/:id/impersonate", RequirePermission(constants.PermissionImpersonateUsers), h.ImpersonateUser)
+		}
+
+		// =====================================================================
+		// Impersonation - Protected routes
+		// Requires the impersonation token being ended
+		// =====================================================================
+		impersonation := api.Group("/auth/impersonation", tenantScoped...)
+		impersonation.Use(authRequired...) // JWT authentication required
+		{
+			// Revoke the impersonation token and close its session early
+			impersonation.POST("/end", h.EndImpersonation)
 		}
 
 		// =====================================================================
 		// User Profile Management - Protected routes
 		// Requires valid JWT token
 		// =====================================================================
-		user := api.Group("/user")
-		user.Use(middleware.AuthRequired(jwtManager)) // JWT authentication required
+		user := api.Group("/user", tenantScoped...)
+		user.Use(authRequired...) // JWT authentication required
 		{
 			// Retrieve the authenticated user's profile information
 			// Returns user details without sensitive data like password
@@ -168,9 +540,100 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 			// Update the authenticated user's profile information
 			// Supports partial updates of firstName, lastName, and email
 			user.PUT("/updateProfile", h.UpdateProfile)
+
+			// Change the password (rejects reuse of recent passwords)
+			user.POST("/change-password", h.ChangePassword)
+
+			// Change the email: a code is sent to the new address, and
+			// confirming it signs out every session
+			user.POST("/change-email", h.ChangeEmail)
+			user.POST("/change-email/confirm", h.ConfirmEmailChange)
+		}
+
+		// =====================================================================
+		// Current User - Protected routes
+		// Always act on the user the access token was issued to
+		// =====================================================================
+		me := api.Group("/me", tenantScoped...)
+		me.Use(authRequired...) // JWT authentication required
+		{
+			me.GET("", h.GetMe)
+
+			// Partial update of name, avatar URL and phone number
+			me.PATCH("", h.PatchMe)
+
+			// Soft-delete the account and end all of its sessions
+			me.DELETE("", h.DeleteMe)
+
+			// Download all personal data (GDPR data portability), once per 24 hours
+			me.GET("/export", h.ExportMe)
+
+			// Logged-in devices with their parsed OS, browser and device type
+			// (the same list as /auth/sessions)
+			me.GET("/sessions", h.ListMySessions)
+
+			// Social accounts the user can sign in with; linking posts the
+			// callback of a PKCE flow started at /auth/oauth/:provider/authorize
+			me.GET("/linked-accounts", h.ListLinkedAccounts)
+			me.POST("/linked-accounts/:provider", h.LinkAccount)
+			me.DELETE("/linked-accounts/:provider", h.UnlinkAccount)
 		}
 	}
 
+	// =========================================================================
+	// SCIM 2.0 - User provisioning by identity providers
+	// Requires the SCIM_TOKEN bearer token or a client certificate;
+	// tenant-scoped like the API
+	// =========================================================================
+	scim := r.Group("/scim/v2", append([]gin.HandlerFunc{middleware.MTLSMiddleware(), middleware.SCIMTokenRequired(opts.SCIMToken, opts.SCIMServices)}, tenantScoped...)...)
+	{
+		scim.GET("/Users", h.SCIMListUsers)
+		scim.POST("/Users", h.SCIMCreateUser)
+		scim.GET("/Users/:id", h.SCIMGetUser)
+		scim.PUT("/Users/:id", h.SCIMReplaceUser)
+		scim.PATCH("/Users/:id", h.SCIMPatchUser)
+		scim.DELETE("/Users/:id", h.SCIMDeleteUser)
+	}
+
+	// =========================================================================
+	// OAuth2 authorization server - Tokens for registered third-party clients
+	// /oauth/authorize is called by the consent screen and /activate by the
+	// device verification page with the user's access token; /oauth/token and
+	// /oauth/device/code authenticate the client itself
+	// =========================================================================
+	oauthServer := r.Group("/oauth")
+	{
+		oauthServer.POST("/token", h.OAuthServerToken)
+		oauthServer.POST("/device/code", h.OAuthDeviceAuthorization)
+
+		consent := oauthServer.Group("")
+		consent.Use(authRequired...) // JWT authentication required
+		consent.GET("/authorize", h.OAuthServerAuthorize)
+	}
+	deviceActivation := r.Group("")
+	{
+		deviceActivation.Use(authRequired...) // JWT authentication required
+		deviceActivation.GET("/activate", h.ActivateDevice)
+	}
+
+	// =========================================================================
+	// Email provider webhooks - Bounces and unsubscribes
+	// Requires EMAIL_WEBHOOK_SECRET as bearer token or token query parameter,
+	// and/or a provider signature made with EMAIL_WEBHOOK_SIGNING_KEY
+	// =========================================================================
+	var emailWebhookAuth []gin.HandlerFunc
+	if opts.EmailWebhookSecret != "" || opts.EmailWebhookSigningKey == "" {
+		emailWebhookAuth = append(emailWebhookAuth, middleware.EmailWebhookTokenRequired(opts.EmailWebhookSecret))
+	}
+	if opts.EmailWebhookSigningKey != "" {
+		emailWebhookAuth = append(emailWebhookAuth, middleware.VerifyWebhookSignature(opts.EmailWebhookSigningKey, opts.EmailWebhookSignatureHeader, opts.EmailWebhookSignatureScheme))
+	}
+	emailWebhooks := r.Group("/webhooks/email", emailWebhookAuth...)
+	{
+		emailWebhooks.POST("/bounce", h.EmailBounceWebhook)
+		emailWebhooks.POST("/unsubscribe", h.EmailUnsubscribeWebhook)
+	}
+
 	// =========================================================================
 	// 404 Handler - Catch all undefined routes
 	// =========================================================================
@@ -193,8 +656,8 @@ func SetupRouter(h *handler.Handler, redis *redis.Client, jwtManager *jwt.Manage
 	logger.Info("Router configuration completed",
 		zap.Bool("production", os.Getenv("APP_ENV") == "production"),
 		zap.Bool("redis_rate_limiting", os.Getenv("APP_ENV") == "production"),
-		zap.Bool("swagger_enabled", true),
+		zap.Bool("swagger_enabled", opts.SwaggerEnabled),
 	)
 
 	return r
-}
\ No newline at end of file
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Audit Log
+// ============================================================================
+
+// WithAuditLog records every state-changing auth operation in repo.
+func (s *AuthService) WithAuditLog(repo repository.AuditRepository) *AuthService {
+	s.auditRepo = repo
+	return s
+}
+
+// QueryAuditLogs returns one page of audit log entries matching filter, newest
+// first, and the total number of matches.
+func (s *AuthService) QueryAuditLogs(ctx context.Context, filter repository.AuditFilter, page repository.Page) ([]models.AuditEntry, int, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.QueryAuditLogs")
+	defer span.End()
+
+	if s.auditRepo == nil {
+		return []models.AuditEntry{}, 0, nil
+	}
+	return s.auditRepo.Query(ctx, filter, page)
+}
+
+// audit records an event for userID (0 when the user is unknown) together with the
+// client and tenant of the request. Failures are logged and never fail the operation.
+func (s *AuthService) audit(ctx context.Context, event constants.AuditEvent, userID int64, metadata map[string]any) {
+	if s.auditRepo == nil {
+		return
+	}
+
+	client := models.ClientInfoFromContext(ctx)
+	entry := models.AuditEntry{
+		EventType: string(event),
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if userID != 0 {
+		entry.UserID = &userID
+	}
+	if tenantID, ok := repository.TenantIDFromContext(ctx); ok {
+		entry.TenantID = &tenantID
+	}
+	if event == constants.AuditLogin || event == constants.AuditLoginFailed {
+		s.locateLogin(ctx, &entry, event == constants.AuditLogin)
+	}
+	if len(metadata) > 0 {
+		data, err := json.Marshal(metadata)
+		if err != nil {
+			logger.Warn("failed to encode audit metadata", "error", err, "event", event)
+		}
+		entry.Metadata = data
+	}
+
+	// The entry is written even if the request is cancelled meanwhile
+	if err := s.auditRepo.Log(context.WithoutCancel(ctx), entry); err != nil {
+		logger.Error("failed to write audit log", "error", err, "event", event, "userID", userID)
+	}
+}
@@ -0,0 +1,275 @@
+//go:build integration
+
+package service_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/pkg/password"
+)
+
+const testPassword = "Str0ng!Passw0rd"
+
+// register signs up a user with testPassword and returns its ID.
+func (st *stack) register(t *testing.T, address string) int64 {
+	t.Helper()
+
+	resp, err := st.auth.Register(context.Background(), models.RegisterRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     address,
+		Password:  testPassword,
+	})
+	if err != nil {
+		t.Fatalf("Register(%s): %v", address, err)
+	}
+	return resp.User.ID
+}
+
+func TestRegisterStoresUser(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+
+	id := st.register(t, "jane@example.com")
+
+	user, err := st.users.FindByEmail(ctx, "jane@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("FindByEmail: %v, %v", user, err)
+	}
+	if user.ID != id || !user.IsActive {
+		t.Fatalf("stored user %+v, want active user %d", user, id)
+	}
+	if user.Password == testPassword {
+		t.Fatal("password stored in plain text")
+	}
+	if ok, _ := password.Verify(testPassword, user.Password); !ok {
+		t.Fatal("stored hash does not verify the password")
+	}
+
+	_, err = st.auth.Register(ctx, models.RegisterRequest{FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: testPassword})
+	if !errors.Is(err, service.ErrEmailTaken) {
+		t.Fatalf("second Register = %v, want ErrEmailTaken", err)
+	}
+}
+
+func TestRegisterConcurrentSameEmail(t *testing.T) {
+	st := newStack(t)
+
+	const attempts = 8
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+	)
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := st.auth.Register(context.Background(), models.RegisterRequest{
+				FirstName: "Jane", LastName: "Doe", Email: "race@example.com", Password: testPassword,
+			})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d of %d concurrent registrations succeeded, want 1", succeeded, attempts)
+	}
+}
+
+func TestLoginIssuesTokens(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+	id := st.register(t, "jane@example.com")
+
+	resp, err := st.auth.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.TwoFactorRequired || resp.AccessToken == "" || resp.RefreshToken == "" {
+		t.Fatalf("Login response %+v, want tokens", resp)
+	}
+
+	claims, err := st.jwt.VerifyToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	if uid, _ := claims["user_id"].(float64); int64(uid) != id {
+		t.Fatalf("access token user_id = %v, want %d", claims["user_id"], id)
+	}
+
+	stored, err := st.tokens.GetRefreshToken(ctx, resp.RefreshToken)
+	if err != nil {
+		t.Fatalf("GetRefreshToken: %v", err)
+	}
+	if stored.UserID != id || stored.FamilyID == "" {
+		t.Fatalf("stored refresh token %+v, want one of user %d in a family", stored, id)
+	}
+
+	for _, req := range []models.LoginRequest{
+		{Email: "jane@example.com", Password: "Wr0ng!Password"},
+		{Email: "nobody@example.com", Password: testPassword},
+	} {
+		if _, err := st.auth.Login(ctx, req); !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Login(%s, %s) = %v, want ErrInvalidCredentials", req.Email, req.Password, err)
+		}
+	}
+}
+
+func TestRefreshTokenRotates(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+	st.register(t, "jane@example.com")
+
+	login, err := st.auth.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: testPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	refreshed, err := st.auth.RefreshToken(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Fatalf("RefreshToken returned %q, want a new refresh token", refreshed.RefreshToken)
+	}
+	if _, err := st.jwt.VerifyToken(refreshed.AccessToken); err != nil {
+		t.Fatalf("refreshed access token: %v", err)
+	}
+
+	// Presenting the rotated token again is reuse: the whole family is revoked
+	if _, err := st.auth.RefreshToken(ctx, login.RefreshToken); !errors.Is(err, repository.ErrRefreshTokenReused) {
+		t.Fatalf("reused RefreshToken = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := st.auth.RefreshToken(ctx, refreshed.RefreshToken); !errors.Is(err, service.ErrInvalidRefreshToken) {
+		t.Fatalf("RefreshToken after reuse = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestVerifyOTP(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+	st.register(t, "jane@example.com")
+
+	if err := st.auth.Send2FAOTP(ctx, "jane@example.com", constants.DeliveryEmail); err != nil {
+		t.Fatalf("Send2FAOTP: %v", err)
+	}
+	code, ok := st.outbox.LastOTP("jane@example.com")
+	if !ok {
+		t.Fatal("no OTP email sent")
+	}
+
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	if err := st.auth.Verify2FA(ctx, "jane@example.com", wrong); !errors.Is(err, service.ErrInvalidOTP) {
+		t.Fatalf("Verify2FA(wrong code) = %v, want ErrInvalidOTP", err)
+	}
+	if err := st.auth.Verify2FA(ctx, "jane@example.com", code); err != nil {
+		t.Fatalf("Verify2FA: %v", err)
+	}
+
+	// Codes are single use
+	if err := st.auth.Verify2FA(ctx, "jane@example.com", code); !errors.Is(err, service.ErrInvalidOTP) {
+		t.Fatalf("second Verify2FA = %v, want ErrInvalidOTP", err)
+	}
+}
+
+func TestVerifyOTPConcurrentRedemption(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+	st.register(t, "jane@example.com")
+
+	if err := st.auth.Send2FAOTP(ctx, "jane@example.com", constants.DeliveryEmail); err != nil {
+		t.Fatalf("Send2FAOTP: %v", err)
+	}
+	code, ok := st.outbox.LastOTP("jane@example.com")
+	if !ok {
+		t.Fatal("no OTP email sent")
+	}
+
+	const attempts = 8
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = st.auth.Verify2FA(context.Background(), "jane@example.com", code)
+		}()
+	}
+	wg.Wait()
+
+	accepted := 0
+	for _, err := range errs {
+		if err == nil {
+			accepted++
+		}
+	}
+	if accepted != 1 {
+		t.Fatalf("code accepted %d times by %d concurrent redemptions, want once (errors: %v)", accepted, attempts, errs)
+	}
+}
+
+func TestRotateRefreshTokenConcurrentReuse(t *testing.T) {
+	st := newStack(t)
+	ctx := context.Background()
+	st.register(t, "jane@example.com")
+
+	// Each round races two rotations of a fresh login's refresh token
+	for round := range 10 {
+		login, err := st.auth.Login(ctx, models.LoginRequest{Email: "jane@example.com", Password: testPassword})
+		if err != nil {
+			t.Fatalf("round %d: Login: %v", round, err)
+		}
+
+		var (
+			wg      sync.WaitGroup
+			start   = make(chan struct{})
+			refresh [2]string
+			errs    [2]error
+		)
+		for i := range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				_, refresh[i], errs[i] = st.auth.RotateRefreshToken(context.Background(), login.RefreshToken)
+			}()
+		}
+		close(start)
+		wg.Wait()
+
+		winner, loser := 0, 1
+		if errs[0] != nil {
+			winner, loser = 1, 0
+		}
+		if errs[winner] != nil {
+			t.Fatalf("round %d: both rotations failed: %v, %v", round, errs[0], errs[1])
+		}
+		if !errors.Is(errs[loser], repository.ErrRefreshTokenReused) {
+			t.Fatalf("round %d: both rotations succeeded or the loser got %v, want ErrRefreshTokenReused", round, errs[loser])
+		}
+
+		// The reuse revoked the family, including the winner's new token
+		if _, err := st.tokens.GetRefreshToken(ctx, refresh[winner]); !errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			t.Fatalf("round %d: winner's refresh token after reuse: %v, want it revoked", round, err)
+		}
+		if _, _, err := st.auth.RotateRefreshToken(ctx, refresh[winner]); err == nil {
+			t.Fatalf("round %d: winner's refresh token still rotates after reuse", round)
+		}
+	}
+}
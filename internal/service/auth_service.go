@@ -3,21 +3,36 @@ package service
 import (
 	"context"
 	"crypto/rand"
-	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"net/url"
+	"sort"
 	"time"
 
 	"authentio/internal/constants"
 	"authentio/internal/models"
 	"authentio/internal/repository"
+	"authentio/pkg/circuit"
 	"authentio/pkg/email"
+	"authentio/pkg/events"
+	"authentio/pkg/flags"
+	"authentio/pkg/geoip"
 	"authentio/pkg/jwt"
+	"authentio/pkg/ldap"
+	"authentio/pkg/lock"
 	"authentio/pkg/logger"
+	"authentio/pkg/oauth"
+	otpcode "authentio/pkg/otp"
 	"authentio/pkg/password"
 	"authentio/pkg/response"
+	"authentio/pkg/sms"
+	"authentio/pkg/totp"
+	"authentio/pkg/tracing"
 
+	"github.com/redis/go-redis/v9"
+	"github.com/skip2/go-qrcode"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/idtoken"
 	"golang.org/x/oauth2"
 )
@@ -36,13 +51,123 @@ type AuthService struct {
 	jwtManager   *jwt.Manager
 	emailClient  *email.Client
 	googleClient *oauth2.Config
+
+	// googleBreaker guards the googleClient code exchange of GoogleCallback
+	googleBreaker *circuit.Breaker
+
+	// smsClient delivers OTP codes by text message; nil disables SMS delivery
+	smsClient *sms.Client
+
+	// ldap checks passwords against an LDAP directory first; nil disables LDAP login
+	ldap *ldap.Client
+
+	// passwordPolicy is enforced whenever a user chooses a new password
+	passwordPolicy password.Policy
+
+	// emailDomains are the email domains Register accepts and rejects; shared by
+	// every copy of the service so they can be reloaded
+	emailDomains *emailDomainPolicy
+
+	// oauthProviders maps provider names ("google", "github") to configured providers
+	oauthProviders map[string]oauth.Provider
+
+	// oauthIdentities stores the provider accounts linked to users; nil keeps a
+	// single provider per user, on the user itself
+	oauthIdentities repository.OAuthIdentityRepository
+
+	// emailVerification is nil when the email verification flow is disabled
+	emailVerification *EmailVerificationConfig
+
+	// lockout is nil when account lockout is disabled
+	lockout *LockoutConfig
+
+	// webhookRepo stores the callback URLs auth events are delivered to; nil disables webhook management
+	webhookRepo repository.WebhookRepository
+
+	// emailSuppressions lists addresses that bounced or unsubscribed; nil disables SuppressEmail
+	emailSuppressions repository.EmailSuppressionRepository
+
+	// passwordHistoryRepo and passwordHistoryLen prevent reuse of recent passwords
+	passwordHistoryRepo repository.PasswordHistoryRepository
+	passwordHistoryLen  int
+
+	// pkceStore holds PKCE code challenges keyed by OAuth state; nil disables PKCE
+	pkceStore *redis.Client
+	pkceTTL   time.Duration
+
+	// totpLogins holds password logins waiting for an authenticator-app code
+	totpLogins   *redis.Client
+	totpLoginTTL time.Duration
+
+	// webAuthn is nil when passkey authentication is disabled
+	webAuthn *WebAuthnConfig
+
+	// magicLink is nil when magic-link login is disabled
+	magicLink *MagicLinkConfig
+
+	// oauthServer is nil when the OAuth2 authorization server is disabled
+	oauthServer *OAuthServerConfig
+
+	// resetLinks signs password reset links; nil emails reset codes instead
+	resetLinks *PasswordResetLinkConfig
+
+	// ipFilter holds the IP blocklist; nil disables blocklist management
+	ipFilter *redis.Client
+
+	// auditRepo records security events; nil disables the audit log
+	auditRepo repository.AuditRepository
+
+	// events receives auth events for other services; events.Noop by default
+	events events.Publisher
+
+	// background tracks the emails sent off the request path (see FlushBackground)
+	background *backgroundTasks
+
+	// roleRepo stores RBAC roles and permissions; nil disables permission claims
+	roleRepo repository.RoleRepository
+
+	// impersonationTTL is the lifetime of tokens issued by ImpersonateUser
+	impersonationTTL time.Duration
+
+	// otpLength is the number of digits in emailed and texted OTP codes
+	otpLength int
+
+	// refreshTokenTTL and rememberMeTTL are the refresh token lifetimes of regular
+	// and remember-me sessions
+	refreshTokenTTL time.Duration
+	rememberMeTTL   time.Duration
+
+	// flags stores per-user feature flags; nil disables flag management
+	flags *flags.Store
+
+	// externalTokens verifies access tokens of an external identity provider; nil rejects them
+	externalTokens *jwt.JWKSVerifier
+
+	// geoIP locates the client IP of login attempts; nil disables geolocation
+	geoIP *geoip.Locator
+
+	// exportRepo collects personal data exports, rate limited in exportLimiter;
+	// nil disables data exports
+	exportRepo    repository.ExportRepository
+	exportLimiter *redis.Client
+
+	// sessionCache caches active sessions for sessionCacheTTL; nil disables the cache
+	sessionCache    *redis.Client
+	sessionCacheTTL time.Duration
+
+	// locker serializes registrations and OTP verifications; nil runs them unguarded
+	locker *lock.Locker
+
+	// tracer creates a span for every exported method
+	tracer trace.Tracer
 }
 
 // ============================================================================
 // Constructor
 // ============================================================================
 
-// NewAuthService constructs the AuthService with its dependencies.
+// NewAuthService constructs the AuthService with its dependencies. A nil
+// tracerProvider disables tracing.
 func NewAuthService(
 	userRepo repository.UserRepository,
 	twoFARepo repository.TwoFARepository,
@@ -51,6 +176,7 @@ func NewAuthService(
 	jwtManager *jwt.Manager,
 	emailClient *email.Client,
 	googleClient *oauth2.Config,
+	tracerProvider trace.TracerProvider,
 ) *AuthService {
 	return &AuthService{
 		userRepo:     userRepo,
@@ -60,7 +186,44 @@ func NewAuthService(
 		jwtManager:   jwtManager,
 		emailClient:  emailClient,
 		googleClient: googleClient,
+
+		googleBreaker: oauth.NewBreaker("google_legacy"),
+
+		passwordPolicy: password.DefaultPolicy,
+		emailDomains:   newEmailDomainPolicy(),
+		oauthProviders: map[string]oauth.Provider{},
+
+		events:           events.Noop{},
+		background:       &backgroundTasks{},
+		impersonationTTL: DefaultImpersonationTTL,
+		otpLength:        otpcode.DefaultLength,
+		refreshTokenTTL:  DefaultRefreshTokenTTL,
+		rememberMeTTL:    DefaultRememberMeTTL,
+		tracer:           tracing.Tracer(tracerProvider, "authentio/internal/service"),
+	}
+}
+
+// WithOAuthProviders registers the OAuth providers available for social login.
+func (s *AuthService) WithOAuthProviders(providers ...oauth.Provider) *AuthService {
+	for _, p := range providers {
+		s.oauthProviders[p.Name()] = p
 	}
+	return s
+}
+
+// WithMinPasswordEntropy makes the password policy reject new passwords whose
+// estimated entropy (see password.EstimateStrength) is below bits; 0 disables
+// the check.
+func (s *AuthService) WithMinPasswordEntropy(bits float64) *AuthService {
+	s.passwordPolicy.MinEntropy = bits
+	return s
+}
+
+// WithOTPLength sets the number of digits in emailed and texted OTP codes,
+// between otp.MinLength and otp.MaxLength.
+func (s *AuthService) WithOTPLength(length int) *AuthService {
+	s.otpLength = length
+	return s
 }
 
 // ============================================================================
@@ -70,10 +233,30 @@ func NewAuthService(
 // Register handles user registration flow including validation, user creation,
 // and sending welcome email.
 func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest) (*response.RegisterResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Register")
+	defer span.End()
+
+	// Sign-ups may be restricted to company domains and exclude disposable providers
+	if err := s.checkEmailDomain(req.Email); err != nil {
+		return nil, err
+	}
+
+	// Concurrent registrations of the same email would both pass the check below
+	unlock, err := s.acquireLock(ctx, registerLockKey(req.Email))
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// Check if email already exists
 	existingUser, _ := s.userRepo.FindByEmail(ctx, req.Email)
 	if existingUser != nil {
-		return nil, errors.New("email already exists")
+		return nil, ErrEmailTaken
+	}
+
+	// Enforce the password policy; returns *password.PolicyError listing every violation
+	if err := s.passwordPolicy.Check(req.Password); err != nil {
+		return nil, err
 	}
 
 	// Hash password before storage
@@ -100,8 +283,23 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 		return nil, err
 	}
 
+	// Seed the password history with the initial password
+	s.recordPasswordHistory(ctx, user.ID, hashed)
+	s.audit(ctx, constants.AuditRegister, user.ID, map[string]any{"provider": "email"})
+	s.publish(ctx, events.UserRegistered, user.ID, map[string]any{"provider": "email"})
+
 	// Send welcome email (non-blocking, log errors but don't fail registration)
-	go s.sendWelcomeEmail(user.Email, user.FirstName)
+	s.goBackground(func() { s.sendWelcomeEmail(context.WithoutCancel(ctx), user.Email, user.FirstName) })
+
+	// Send the email verification link (non-blocking, same as the welcome email)
+	if s.emailVerification != nil {
+		userID := user.ID
+		s.goBackground(func() {
+			if err := s.SendEmailVerification(context.Background(), userID); err != nil {
+				logger.Warn("failed to send verification email", "error", err, "userID", userID)
+			}
+		})
+	}
 
 	// Convert to response DTO
 	userResponse := response.UserResponse{
@@ -121,20 +319,108 @@ func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest)
 }
 
 // Login validates user credentials and returns JWT tokens upon successful authentication.
+// Accounts with a registered security key or an enabled authenticator app are
+// challenged for it instead: the response carries TwoFactorRequired and no tokens
+// (see FinishSecurityKeyLogin and FinishTOTPLogin).
 func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	// Reject locked accounts before checking the password
+	locked, err := s.IsAccountLocked(ctx, req.Email)
+	if err != nil {
+		logger.Warn("failed to check account lockout", "error", err, "email", req.Email)
+	}
+	if locked {
+		s.audit(ctx, constants.AuditLoginFailed, 0, map[string]any{"email": req.Email, "reason": "account_locked"})
+		return nil, ErrAccountLocked
+	}
+
+	// Directory users sign in with their LDAP password; everyone else, and
+	// everyone while the directory is unreachable, falls through to local passwords
+	user, err := s.authenticateLDAP(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		s.clearFailedLogins(ctx, req.Email)
+		if err := checkAccountActive(user); err != nil {
+			return nil, err
+		}
+		if challenge, err := s.secondFactorChallenge(ctx, user, req.RememberMe, ldapProvider); err != nil || challenge != nil {
+			return challenge, err
+		}
+		s.audit(ctx, constants.AuditLogin, user.ID, map[string]any{"method": ldapProvider})
+		s.publish(ctx, events.UserLoggedIn, user.ID, map[string]any{"method": ldapProvider})
+		return s.generateAuthResponse(ctx, user, req.RememberMe)
+	}
+
 	// Find user by email
-	user, err := s.userRepo.FindByEmail(ctx, req.Email)
+	user, err = s.userRepo.FindByEmail(ctx, req.Email)
 	if err != nil || user == nil {
-		return nil, errors.New("invalid email or password")
+		s.recordFailedLogin(ctx, req.Email)
+		s.audit(ctx, constants.AuditLoginFailed, 0, map[string]any{"email": req.Email, "reason": "unknown_email"})
+		return nil, ErrInvalidCredentials
+	}
+
+	// Verify password (bcrypt or argon2id, detected from the stored hash)
+	if ok, _ := password.Verify(req.Password, user.Password); !ok {
+		s.recordFailedLogin(ctx, req.Email)
+		s.audit(ctx, constants.AuditLoginFailed, user.ID, map[string]any{"reason": "invalid_password"})
+		return nil, ErrInvalidCredentials
+	}
+	s.clearFailedLogins(ctx, req.Email)
+	s.upgradePasswordHash(ctx, user, req.Password)
+
+	// Deactivated (e.g. deprovisioned) accounts cannot sign in
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
 	}
 
-	// Verify password
-	if !password.Check(req.Password, user.Password) {
-		return nil, errors.New("invalid credentials")
+	// Block unverified accounts when verification is required, and send a fresh link
+	if s.emailVerification != nil && s.emailVerification.Required && user.EmailVerifiedAt == nil {
+		userID := user.ID
+		s.goBackground(func() {
+			if err := s.SendEmailVerification(context.Background(), userID); err != nil {
+				logger.Warn("failed to resend verification email", "error", err, "userID", userID)
+			}
+		})
+		return nil, ErrEmailNotVerified
 	}
 
+	// Accounts with a second factor get a challenge instead of tokens and finish
+	// signing in with FinishSecurityKeyLogin or FinishTOTPLogin
+	if challenge, err := s.secondFactorChallenge(ctx, user, req.RememberMe, "password"); err != nil || challenge != nil {
+		return challenge, err
+	}
+
+	s.audit(ctx, constants.AuditLogin, user.ID, map[string]any{"method": "password"})
+	s.publish(ctx, events.UserLoggedIn, user.ID, map[string]any{"method": "password"})
+
 	// Generate authentication response with tokens
-	return s.generateAuthResponse(user)
+	return s.generateAuthResponse(ctx, user, req.RememberMe)
+}
+
+// upgradePasswordHash replaces a bcrypt hash made with a cost below BCRYPT_COST
+// by a hash of the password just verified, so raising the cost strengthens
+// existing accounts as their users sign in instead of forcing password resets.
+// Failures are logged and leave the old hash, which still verifies.
+func (s *AuthService) upgradePasswordHash(ctx context.Context, user *models.User, plainPassword string) {
+	if !password.NeedsRehash(user.Password, password.DefaultCost) {
+		return
+	}
+
+	hashed, err := password.Hash(plainPassword)
+	if err != nil {
+		logger.Warn("failed to rehash password", "error", err, "userID", user.ID)
+		return
+	}
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		logger.Warn("failed to store rehashed password", "error", err, "userID", user.ID)
+		return
+	}
+	user.Password = hashed
+	logger.Info("password rehashed with the current bcrypt cost", "userID", user.ID)
 }
 
 // ============================================================================
@@ -144,10 +430,13 @@ func (s *AuthService) Login(ctx context.Context, req models.LoginRequest) (*resp
 // GoogleAuth handles Google OAuth authentication by validating ID tokens
 // and creating new users or logging in existing ones.
 func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audience string) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.GoogleAuth")
+	defer span.End()
+
 	// Validate the Google ID token
 	payload, err := idtoken.Validate(ctx, idTokenStr, audience)
 	if err != nil {
-		return nil, fmt.Errorf("invalid Google token: %w", err)
+		return nil, ErrInvalidOAuthToken.wrap(err)
 	}
 
 	// Extract user information from token claims
@@ -156,12 +445,16 @@ func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audienc
 	lastName, _ := payload.Claims["family_name"].(string)
 
 	if email == "" {
-		return nil, errors.New("invalid token payload: missing email")
+		return nil, newError(CodeInvalidOAuthToken, "invalid token payload: missing email")
 	}
 
-	// Check if user exists, create if new
+	// Check if user exists, create if new. FindByEmail returns a nil user, not
+	// an error, for an unknown email
 	user, err := s.userRepo.FindByEmail(ctx, email)
-	if err == sql.ErrNoRows {
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
 		// Create new user for Google OAuth
 		user = &models.User{
 			Email:     email,
@@ -180,41 +473,206 @@ func (s *AuthService) GoogleAuth(ctx context.Context, idTokenStr string, audienc
 		}
 
 		// Send welcome email for new Google OAuth users
-		go s.sendWelcomeEmail(user.Email, user.FirstName)
-	} else if err != nil {
+		s.goBackground(func() { s.sendWelcomeEmail(context.WithoutCancel(ctx), user.Email, user.FirstName) })
+		s.audit(ctx, constants.AuditRegister, user.ID, map[string]any{"provider": "google"})
+		s.publish(ctx, events.UserRegistered, user.ID, map[string]any{"provider": "google"})
+	}
+
+	if err := checkAccountActive(user); err != nil {
 		return nil, err
 	}
 
+	s.audit(ctx, constants.AuditLogin, user.ID, map[string]any{"method": "google"})
+	s.publish(ctx, events.UserLoggedIn, user.ID, map[string]any{"method": "google"})
+
 	// Generate authentication response
-	return s.generateAuthResponse(user)
+	return s.generateAuthResponse(ctx, user, false)
 }
 
 // GoogleCallback handles the OAuth callback flow by exchanging authorization code
 // for tokens and processing the authentication.
 func (s *AuthService) GoogleCallback(ctx context.Context, code string, oauthConfig *oauth2.Config) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.GoogleCallback")
+	defer span.End()
+
 	// Exchange authorization code for tokens
-	token, err := s.googleClient.Exchange(ctx, code)
+	exchangeCtx := context.WithValue(ctx, oauth2.HTTPClient, oauth.NewHTTPClient(s.googleBreaker))
+	token, err := s.googleClient.Exchange(exchangeCtx, code)
+	if errors.Is(err, circuit.ErrCircuitOpen) {
+		return nil, ErrOAuthProviderUnavailable.wrap(err)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to exchange code: %w", err)
+		return nil, ErrOAuthExchangeFailed.wrap(err)
 	}
 
 	// Extract ID token from response
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok || rawIDToken == "" {
-		return nil, errors.New("no id_token in response")
+		return nil, newError(CodeOAuthExchangeFailed, "no id_token in response")
 	}
 
 	// Reuse GoogleAuth to validate ID token and login/create user
 	return s.GoogleAuth(ctx, rawIDToken, oauthConfig.ClientID)
 }
 
+// OAuthProvider returns the configured provider with the given name.
+func (s *AuthService) OAuthProvider(name string) (oauth.Provider, error) {
+	provider, ok := s.oauthProviders[name]
+	if !ok {
+		return nil, oauth.ErrUnknownProvider
+	}
+	return provider, nil
+}
+
+// OAuthBreakers returns the circuit breakers guarding calls to the OAuth
+// providers, ordered by name, for health reporting.
+func (s *AuthService) OAuthBreakers() []*circuit.Breaker {
+	breakers := []*circuit.Breaker{s.googleBreaker}
+	for _, p := range s.oauthProviders {
+		breakers = append(breakers, p.Breaker())
+	}
+	sort.Slice(breakers, func(i, j int) bool { return breakers[i].Name() < breakers[j].Name() })
+	return breakers
+}
+
+// HandleOAuthCallback exchanges an authorization code with the provider, finds or
+// creates the user linked to the provider identity, and issues a token pair.
+//
+// An existing email/password account is only linked when the provider reports the
+// email as verified; otherwise anyone could claim an account by registering its
+// email address with a provider.
+func (s *AuthService) HandleOAuthCallback(ctx context.Context, req models.OAuthCallbackRequest) (*models.TokenPair, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.HandleOAuthCallback")
+	defer span.End()
+
+	identity, err := s.exchangeOAuthCode(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.upsertOAuthUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.generateAuthResponse(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, constants.AuditLogin, user.ID, map[string]any{"method": "oauth", "provider": req.Provider})
+	s.publish(ctx, events.UserLoggedIn, user.ID, map[string]any{"method": "oauth", "provider": req.Provider})
+	logger.Info("oauth login successful", "provider", req.Provider, "userID", user.ID)
+	return &models.TokenPair{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+// exchangeOAuthCode checks an OAuth callback and exchanges its authorization
+// code with the provider for the user's identity.
+func (s *AuthService) exchangeOAuthCode(ctx context.Context, req models.OAuthCallbackRequest) (*oauth.Identity, error) {
+	provider, err := s.OAuthProvider(req.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if req.Code == "" || req.State == "" {
+		return nil, ErrInvalidOAuthCallback
+	}
+
+	// PKCE: the verifier must match the challenge stored when the flow started
+	if err := s.verifyPKCE(ctx, req.State, req.CodeVerifier); err != nil {
+		return nil, err
+	}
+	var exchangeOpts []oauth2.AuthCodeOption
+	if req.CodeVerifier != "" {
+		exchangeOpts = append(exchangeOpts, oauth2.VerifierOption(req.CodeVerifier))
+	}
+
+	identity, err := provider.Exchange(ctx, req.Code, exchangeOpts...)
+	if err != nil {
+		logger.Warn("oauth code exchange failed", "provider", req.Provider, "error", err)
+		if errors.Is(err, circuit.ErrCircuitOpen) {
+			return nil, ErrOAuthProviderUnavailable.wrap(err)
+		}
+		if errors.Is(err, oauth.ErrMissingEmail) {
+			return nil, ErrInvalidOAuthToken.wrap(err)
+		}
+		return nil, ErrOAuthExchangeFailed.wrap(err)
+	}
+	return identity, nil
+}
+
+// upsertOAuthUser returns the user linked to identity, linking or creating one if needed.
+func (s *AuthService) upsertOAuthUser(ctx context.Context, identity *oauth.Identity) (*models.User, error) {
+	// Returning user: matched on the provider's stable ID, not the email
+	user, err := s.findOAuthUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		return user, nil
+	}
+
+	// Existing account with the same email: link it
+	user, err = s.userRepo.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		if !identity.EmailVerified {
+			return nil, ErrProviderEmailUnverified
+		}
+		if err := s.userRepo.LinkProvider(ctx, user.ID, identity.Provider, identity.ProviderID, identity.AvatarURL); err != nil {
+			return nil, err
+		}
+		user.Provider = identity.Provider
+		user.ProviderID = identity.ProviderID
+		s.recordOAuthIdentity(ctx, user.ID, identity)
+		return user, nil
+	}
+
+	// New user
+	user = &models.User{
+		Email:      identity.Email,
+		FirstName:  identity.FirstName,
+		LastName:   identity.LastName,
+		IsActive:   true,
+		Provider:   identity.Provider,
+		ProviderID: identity.ProviderID,
+		AvatarURL:  identity.AvatarURL,
+		BaseModel: models.BaseModel{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+	}
+	if identity.EmailVerified {
+		user.EmailVerifiedAt = timePtr(time.Now())
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	s.recordOAuthIdentity(ctx, user.ID, identity)
+
+	s.goBackground(func() { s.sendWelcomeEmail(context.WithoutCancel(ctx), user.Email, user.FirstName) })
+	return user, nil
+}
+
 // ============================================================================
 // Password Reset Flow
 // ============================================================================
 
-// RequestPasswordReset initiates the password reset flow by generating a reset code
-// and sending it to the user's email.
+// RequestPasswordReset initiates the password reset flow by emailing the user a
+// signed reset link (see WithPasswordResetLinks), or a reset code when links are
+// not configured.
 func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RequestPasswordReset")
+	defer span.End()
+
 	// Check if user exists (but don't reveal if they don't to prevent email enumeration)
 	user, _ := s.userRepo.FindByEmail(ctx, email)
 	if user == nil {
@@ -222,102 +680,198 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) er
 		return nil // Return success to prevent email enumeration
 	}
 
-	// Generate reset code
-	code := generateRandomCode(6)
+	var codeOrLink string
+	var err error
+	if s.resetLinks != nil {
+		codeOrLink, err = s.passwordResetLink(user, s.resetLinks.URL)
+	} else {
+		codeOrLink, err = s.createPasswordResetCode(ctx, user.ID, email)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Send password reset email
+	if err := s.emailClient.SendPasswordReset(ctx, email, codeOrLink); err != nil {
+		logger.Error("failed to send password reset email", "error", err, "email", email)
+		return newError(CodeDeliveryFailed, "failed to send reset email")
+	}
+
+	logger.Info("password reset sent", "email", email, "link", s.resetLinks != nil)
+	return nil
+}
+
+// IssuePasswordResetLink returns a password reset link to resetURL for the user
+// with the given email, instead of emailing it: a signed link with the token
+// query parameter when links are configured, and the email and code query
+// parameters otherwise. It is meant for operators (the authentio-admin CLI), so
+// unlike RequestPasswordReset it returns ErrUserNotFound for unknown emails.
+func (s *AuthService) IssuePasswordResetLink(ctx context.Context, email, resetURL string) (string, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.IssuePasswordResetLink")
+	defer span.End()
+
+	link, err := url.Parse(resetURL)
+	if err != nil {
+		return "", internalError("invalid password reset URL", err)
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", ErrUserNotFound
+	}
+
+	var issued string
+	if s.resetLinks != nil {
+		if issued, err = s.passwordResetLink(user, resetURL); err != nil {
+			return "", err
+		}
+	} else {
+		code, err := s.createPasswordResetCode(ctx, user.ID, user.Email)
+		if err != nil {
+			return "", err
+		}
+
+		query := link.Query()
+		query.Set("email", user.Email)
+		query.Set("code", code)
+		link.RawQuery = query.Encode()
+		issued = link.String()
+	}
+
+	s.audit(ctx, constants.AuditPasswordResetIssued, user.ID, nil)
+	logger.Info("password reset link issued", "userID", user.ID)
+	return issued, nil
+}
+
+// createPasswordResetCode stores a new password_reset OTP for the user and
+// returns the code, which ResetPassword accepts together with email.
+func (s *AuthService) createPasswordResetCode(ctx context.Context, userID int64, email string) (string, error) {
+	code, err := otpcode.Generate(s.otpLength)
+	if err != nil {
+		return "", internalError("failed to generate OTP code", err)
+	}
 
 	// Store OTP with password_reset type
 	otp := &models.OTP{
-		UserID: &user.ID,
+		UserID: &userID,
 		Email:  email,
 		Code:   code,
 		Type:   string(constants.TypePasswordReset),
 	}
 
 	if err := s.otpRepo.CreateOTP(ctx, otp); err != nil {
-		return err
-	}
-
-	// Send password reset email
-	if err := s.emailClient.SendPasswordReset(email, code); err != nil {
-		logger.Error("failed to send password reset email", "error", err, "email", email)
-		return fmt.Errorf("failed to send reset email")
+		return "", err
 	}
-
-	logger.Info("password reset code sent", "email", email)
-	return nil
+	return code, nil
 }
 
-// ResetPassword verifies the reset code and updates the user's password.
+// ResetPassword verifies a reset code and updates the user's password. Reset
+// links are redeemed with RedeemPasswordReset instead.
 func (s *AuthService) ResetPassword(ctx context.Context, email, code, newPassword string) error {
-	// Verify the reset code
-	valid, err := s.otpRepo.VerifyOTP(ctx, email, code, string(constants.TypePasswordReset))
+	ctx, span := s.tracer.Start(ctx, "AuthService.ResetPassword")
+	defer span.End()
+
+	// Verify the reset code; repeated wrong codes lock it (*ErrOTPLocked)
+	valid, err := s.verifyOTPCode(ctx, email, code, constants.TypePasswordReset)
+	var lockedErr *ErrOTPLocked
+	if errors.As(err, &lockedErr) {
+		return lockedErr
+	}
+	if errors.Is(err, ErrLockConflict) {
+		return err
+	}
 	if err != nil || !valid {
-		return errors.New("invalid or expired reset code")
+		return newError(CodeInvalidOTP, "invalid or expired reset code")
 	}
 
 	// Find the user
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
-	// Hash new password
-	hashedPassword, err := password.Hash(newPassword)
-	if err != nil {
+	// Enforce the password policy and history, then store the new password
+	if err := s.setPassword(ctx, user.ID, user.Password, newPassword); err != nil {
 		return err
 	}
+	s.audit(ctx, constants.AuditPasswordReset, user.ID, nil)
+	s.publish(ctx, events.PasswordChanged, user.ID, map[string]any{"reason": "reset"})
 
-	// Update user's password
-	user.Password = hashedPassword
-	if err := s.userRepo.Update(ctx, user); err != nil {
-		return err
-	}
+	s.sendPasswordChangedEmail(ctx, email)
+
+	logger.Info("password reset successful", "email", email)
+	return nil
+}
 
-	// Send password change confirmation email
-	if err := s.emailClient.Send(
+// sendPasswordChangedEmail confirms a password reset to the account's address.
+// Failures are logged only: the password was already changed.
+func (s *AuthService) sendPasswordChangedEmail(ctx context.Context, email string) {
+	if err := s.emailClient.Send(ctx,
 		[]string{email},
 		"Password Changed Successfully",
 		"<p>Your password has been successfully changed.</p><p>If you didn't make this change, please contact support immediately.</p>",
 	); err != nil {
 		logger.Warn("failed to send password change confirmation email", "error", err, "email", email)
-		// Don't return error - password was already changed successfully
 	}
-
-	logger.Info("password reset successful", "email", email)
-	return nil
 }
 
 // ============================================================================
 // Two-Factor Authentication (2FA) Methods
 // ============================================================================
 
-// Send2FAOTP generates and sends a 2FA OTP code to the user's email.
-func (s *AuthService) Send2FAOTP(ctx context.Context, email string) error {
+// Send2FAOTP generates a 2FA OTP code and sends it over the given channel. An
+// empty channel uses the user's preference: SMS when SMS 2FA is enabled, email otherwise.
+func (s *AuthService) Send2FAOTP(ctx context.Context, email string, channel constants.DeliveryChannel) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Send2FAOTP")
+	defer span.End()
+
 	// Check if user exists
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
+	}
+
+	channel, err = s.resolveDeliveryChannel(ctx, user, channel)
+	if err != nil {
+		return err
 	}
 
 	// Generate OTP code
-	code := generateRandomCode(6)
+	code, err := otpcode.Generate(s.otpLength)
+	if err != nil {
+		return internalError("failed to generate OTP code", err)
+	}
 
 	// Store OTP with 2FA type
 	otp := &models.OTP{
-		UserID: &user.ID,
-		Email:  email,
-		Code:   code,
-		Type:   string(constants.Type2FA),
+		UserID:  &user.ID,
+		Email:   email,
+		Code:    code,
+		Type:    string(constants.Type2FA),
+		Channel: string(channel),
 	}
 
 	if err := s.otpRepo.CreateOTP(ctx, otp); err != nil {
 		return err
 	}
 
+	// Send OTP via SMS
+	if channel == constants.DeliverySMS {
+		if err := s.smsClient.SendOTP(user.PhoneNumber, code); err != nil {
+			logger.Error("failed to send 2FA SMS", "error", err, "userID", user.ID)
+			return newError(CodeDeliveryFailed, "failed to send verification SMS")
+		}
+		logger.Info("2FA code sent via SMS", "userID", user.ID)
+		return nil
+	}
+
 	// Send OTP via email
-	if err := s.emailClient.SendOTP(email, code); err != nil {
+	if err := s.emailClient.SendOTP(ctx, email, code); err != nil {
 		logger.Error("failed to send 2FA email", "error", err, "email", email)
-		return fmt.Errorf("failed to send verification email")
+		return newError(CodeDeliveryFailed, "failed to send verification email")
 	}
 
 	logger.Info("2FA code sent via email", "email", email)
@@ -326,70 +880,194 @@ func (s *AuthService) Send2FAOTP(ctx context.Context, email string) error {
 
 // Verify2FA checks OTP validity for 2FA verification.
 func (s *AuthService) Verify2FA(ctx context.Context, email, code string) error {
-	valid, err := s.otpRepo.VerifyOTP(ctx, email, code, string(constants.Type2FA))
+	ctx, span := s.tracer.Start(ctx, "AuthService.Verify2FA")
+	defer span.End()
+
+	valid, err := s.verifyOTPCode(ctx, email, code, constants.Type2FA)
+	var lockedErr *ErrOTPLocked
+	if errors.As(err, &lockedErr) {
+		return lockedErr
+	}
+	if errors.Is(err, ErrLockConflict) {
+		return err
+	}
 	if err != nil || !valid {
-		return errors.New("invalid or expired code")
+		return ErrInvalidOTP
 	}
 	return nil
 }
 
 // EnableEmail2FA enables email-based 2FA for a user.
 func (s *AuthService) EnableEmail2FA(ctx context.Context, userID int64) error {
-	return s.twoFARepo.EnableEmail2FA(ctx, userID)
+	ctx, span := s.tracer.Start(ctx, "AuthService.EnableEmail2FA")
+	defer span.End()
+
+	if err := s.twoFARepo.EnableEmail2FA(ctx, userID); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.Audit2FAEnabled, userID, map[string]any{"method": "email"})
+	s.publish(ctx, events.TwoFAEnabled, userID, map[string]any{"method": "email"})
+	return nil
 }
 
 // Disable2FA disables 2FA for a user.
 func (s *AuthService) Disable2FA(ctx context.Context, userID int64) error {
-	return s.twoFARepo.Disable2FA(ctx, userID)
+	ctx, span := s.tracer.Start(ctx, "AuthService.Disable2FA")
+	defer span.End()
+
+	if err := s.twoFARepo.Disable2FA(ctx, userID); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.Audit2FADisabled, userID, nil)
+	return nil
 }
 
 // Is2FAEnabled checks if 2FA is enabled for a user.
 func (s *AuthService) Is2FAEnabled(ctx context.Context, userID int64) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Is2FAEnabled")
+	defer span.End()
+
 	return s.twoFARepo.Is2FAEnabled(ctx, userID)
 }
 
 // ============================================================================
-// Token Management
+// TOTP (Authenticator App) Methods
 // ============================================================================
 
-// RefreshToken generates new access token using a valid refresh token.
-func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string) (*response.LoginResponse, error) {
-	// Get the refresh token from database
-	token, err := s.tokenRepo.GetRefreshToken(ctx, refreshTokenStr)
+const (
+	// totpIssuer is shown as the account label in authenticator apps
+	totpIssuer = "Authentio"
+
+	// totpSkew accepts codes from one step before or after the current one (±30s clock drift)
+	totpSkew = 1
+
+	// totpQRCodeSize is the width and height of the enrollment QR code in pixels
+	totpQRCodeSize = 256
+)
+
+var (
+	// ErrInvalidTOTPCode is returned when a TOTP code does not match the user's secret
+	ErrInvalidTOTPCode = newError(CodeInvalidTOTPCode, "invalid TOTP code")
+
+	// ErrTOTPCodeReused is returned when a TOTP code has already been used
+	ErrTOTPCodeReused = newError(CodeTOTPCodeReused, "TOTP code already used")
+)
+
+// EnrollTOTP generates a new TOTP secret for the user, stores it encrypted as pending,
+// and returns the otpauth:// URI and a QR code for an authenticator app. The secret is
+// not used until the user confirms setup with VerifyTOTP; until then any 2FA already
+// enabled, including a previous authenticator app, stays in force.
+func (s *AuthService) EnrollTOTP(ctx context.Context, userID int64) (*models.TOTPEnrollment, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.EnrollTOTP")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	secret, err := totp.GenerateSecret()
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		return nil, internalError("failed to generate TOTP secret", err)
 	}
 
-	// Get the user associated with the refresh token
-	user, err := s.userRepo.FindByID(ctx, token.UserID)
+	if err := s.twoFARepo.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		logger.Error("failed to store TOTP secret", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	uri := totp.ProvisioningURI(totpIssuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, totpQRCodeSize)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, internalError("failed to generate QR code", err)
 	}
 
-	// Generate new access token
-	accessToken, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.FirstName, user.LastName)
+	logger.Info("TOTP enrollment started", "user_id", userID)
+	return &models.TOTPEnrollment{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: png,
+	}, nil
+}
+
+// VerifyTOTP validates a 6-digit TOTP code. A code from a pending enrollment
+// confirms it: the new secret replaces the previous one and TOTP is enabled.
+// Otherwise the code is checked against the active secret, and its time step is
+// recorded so the same code cannot be used twice.
+func (s *AuthService) VerifyTOTP(ctx context.Context, userID int64, code string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.VerifyTOTP")
+	defer span.End()
+
+	pending, err := s.twoFARepo.GetPendingTOTPSecret(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrTOTPNotEnrolled) {
+		return err
+	}
+	if err == nil {
+		if step, ok := totp.Validate(pending, code, time.Now(), totpSkew); ok {
+			return s.confirmTOTPEnrollment(ctx, userID, step)
+		}
+	}
+
+	if err := s.checkTOTPCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	logger.Info("TOTP code verified", "user_id", userID)
+	return nil
+}
+
+// confirmTOTPEnrollment promotes the pending TOTP secret once a code generated
+// from it was accepted at step. This completes an enrollment, so it is audited.
+func (s *AuthService) confirmTOTPEnrollment(ctx context.Context, userID int64, step int64) error {
+	if err := s.twoFARepo.EnableTOTP(ctx, userID, step); err != nil {
+		// A concurrent request with the same code promoted the secret first
+		if errors.Is(err, repository.ErrTOTPNotEnrolled) {
+			return ErrTOTPCodeReused
+		}
+		return err
+	}
+
+	s.audit(ctx, constants.Audit2FAEnabled, userID, map[string]any{"method": "totp"})
+	s.publish(ctx, events.TwoFAEnabled, userID, map[string]any{"method": "totp"})
+	logger.Info("TOTP enrollment confirmed", "user_id", userID)
+	return nil
+}
+
+// checkTOTPCode validates a code against the user's active TOTP secret.
+// Replay protection: only steps newer than the last accepted one are allowed.
+func (s *AuthService) checkTOTPCode(ctx context.Context, userID int64, code string) error {
+	secret, err := s.twoFARepo.Get2FASecret(ctx, userID)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	// Token rotation: delete old refresh token for security
-	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshTokenStr); err != nil {
-		logger.Error("failed to delete old refresh token", "error", err)
+	step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+	if !ok {
+		return ErrInvalidTOTPCode
 	}
 
-	// Generate new refresh token
-	newRefreshToken := &models.RefreshToken{
-		UserID: user.ID,
-		Token:  generateSecureToken(),
-		BaseModel: models.BaseModel{
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-			ExpiredAt: timePtr(time.Now().Add(30 * 24 * time.Hour)),
-		},
+	recorded, err := s.twoFARepo.RecordTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		return ErrTOTPCodeReused
 	}
+	return nil
+}
+
+// ============================================================================
+// Token Management
+// ============================================================================
 
-	// Save new refresh token
-	if err := s.tokenRepo.SaveRefreshToken(ctx, newRefreshToken); err != nil {
+// RefreshToken generates new access token using a valid refresh token.
+// The presented refresh token is rotated: it can never be used again.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RefreshToken")
+	defer span.End()
+
+	user, accessToken, newRefreshToken, err := s.rotateRefreshToken(ctx, refreshTokenStr)
+	if err != nil {
 		return nil, err
 	}
 
@@ -409,14 +1087,169 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshTokenStr string)
 	}, nil
 }
 
-// Logout invalidates a specific refresh token.
-func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
-	return s.tokenRepo.DeleteRefreshToken(ctx, refreshToken)
+// RotateRefreshToken consumes a refresh token exactly once and issues a new access and
+// refresh token pair. Presenting a token that was already rotated revokes every token
+// issued from the same login and returns ErrRefreshTokenReused.
+func (s *AuthService) RotateRefreshToken(ctx context.Context, oldToken string) (newAccess, newRefresh string, err error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RotateRefreshToken")
+	defer span.End()
+
+	_, accessToken, newRefreshToken, err := s.rotateRefreshToken(ctx, oldToken)
+	if err != nil {
+		return "", "", err
+	}
+	return accessToken, newRefreshToken.Token, nil
 }
 
-// LogoutAll invalidates all refresh tokens for a user.
+// rotateRefreshToken performs the single-use rotation shared by RefreshToken and RotateRefreshToken.
+func (s *AuthService) rotateRefreshToken(ctx context.Context, oldToken string) (*models.User, string, *models.RefreshToken, error) {
+	// The successor is valid as long as the session's first refresh token was
+	sessionID, rememberMe, err := s.refreshTokenSession(ctx, oldToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	newRefreshToken := &models.RefreshToken{
+		Token:    generateSecureToken(),
+		FamilyID: generateSecureToken(), // only used if the old token predates families
+		BaseModel: models.BaseModel{
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			ExpiredAt: timePtr(time.Now().Add(s.sessionTTL(rememberMe))),
+		},
+	}
+
+	// Atomically consume the old token and store its successor
+	if err := s.tokenRepo.RotateRefreshToken(ctx, oldToken, newRefreshToken); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			logger.Warn("refresh token reuse detected, token family revoked")
+			s.evictSessions(ctx, sessionID)
+			s.audit(ctx, constants.AuditTokenReuse, 0, nil)
+			return nil, "", nil, ErrRefreshTokenReused
+		}
+		return nil, "", nil, ErrInvalidRefreshToken
+	}
+
+	// Get the user associated with the refresh token
+	user, err := s.userRepo.FindByID(ctx, newRefreshToken.UserID)
+	if err != nil || user == nil {
+		return nil, "", nil, ErrUserNotFound
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, "", nil, err
+	}
+
+	// Record the refresh on the session; legacy tokens get their session created here
+	if err := s.saveSession(ctx, newRefreshToken, rememberMe); err != nil {
+		return nil, "", nil, err
+	}
+	s.evictSessions(ctx, newRefreshToken.FamilyID)
+
+	// Generate new access token bound to the same session
+	accessToken, err := s.generateAccessToken(ctx, user, newRefreshToken.FamilyID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return user, accessToken, newRefreshToken, nil
+}
+
+// Logout invalidates a specific refresh token. accessToken, the caller's current
+// access token if known, is revoked for the rest of its lifetime, so it stops
+// working right away instead of when it expires.
+func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	s.revokeAccessToken(ctx, accessToken)
+
+	var userID int64
+	var sessionID string
+	token, err := s.tokenRepo.GetRefreshToken(ctx, refreshToken)
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return ErrInvalidRefreshToken
+	}
+	if err == nil {
+		userID, sessionID = token.UserID, token.FamilyID
+	}
+
+	if err := s.tokenRepo.DeleteRefreshToken(ctx, refreshToken); err != nil {
+		return err
+	}
+	if sessionID != "" {
+		s.evictSessions(ctx, sessionID)
+	}
+	s.audit(ctx, constants.AuditLogout, userID, nil)
+	return nil
+}
+
+// revokeAccessToken evicts the cached claims of accessToken and adds its jti to
+// the revocation list until the token expires. Tokens that fail verification, expired ones included, are
+// already rejected and are ignored.
+func (s *AuthService) revokeAccessToken(ctx context.Context, accessToken string) {
+	if accessToken == "" {
+		return
+	}
+	s.evictTokenClaims(ctx, accessToken)
+	claims, err := s.jwtManager.VerifyToken(accessToken)
+	if err != nil {
+		return
+	}
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return
+	}
+	if err := s.jwtManager.RevokeToken(ctx, jti, time.Until(exp.Time)); err != nil {
+		logger.Warn("failed to revoke access token", "error", err)
+	}
+}
+
+// LogoutAll signs a user out everywhere: every refresh token is invalidated and
+// every access token issued until now is rejected before it expires.
 func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
-	return s.tokenRepo.DeleteUserRefreshTokens(ctx, userID)
+	ctx, span := s.tracer.Start(ctx, "AuthService.LogoutAll")
+	defer span.End()
+
+	if err := s.signOutEverywhere(ctx, userID); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditLogoutAll, userID, nil)
+	return nil
+}
+
+// signOutEverywhere revokes every session of the user, then sets their
+// revoked_before cutoff so access tokens issued until now stop working too.
+func (s *AuthService) signOutEverywhere(ctx context.Context, userID int64) error {
+	if err := s.revokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.jwtManager.RevokeUserTokensIssuedBefore(ctx, userID, time.Now()); err != nil {
+		return revocationError(err)
+	}
+	return nil
+}
+
+// revokeAllSessions revokes every refresh token of the user and evicts their
+// sessions from the session cache.
+func (s *AuthService) revokeAllSessions(ctx context.Context, userID int64) error {
+	// The sessions to evict from the cache are only known before they are revoked
+	var sessionIDs []string
+	if s.sessionCache != nil {
+		sessions, err := s.tokenRepo.ListSessions(ctx, userID)
+		if err != nil {
+			logger.Warn("failed to list sessions to evict from cache", "error", err, "userID", userID)
+		}
+		for _, session := range sessions {
+			sessionIDs = append(sessionIDs, session.ID)
+		}
+	}
+
+	if err := s.tokenRepo.DeleteUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	s.evictSessions(ctx, sessionIDs...)
+	return nil
 }
 
 // ============================================================================
@@ -425,9 +1258,12 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID int64) error {
 
 // GetUserProfile returns user profile without sensitive data.
 func (s *AuthService) GetUserProfile(ctx context.Context, userID int64) (*response.UserResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.GetUserProfile")
+	defer span.End()
+
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil || user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	userResponse := &response.UserResponse{
@@ -443,16 +1279,20 @@ func (s *AuthService) GetUserProfile(ctx context.Context, userID int64) (*respon
 
 // UpdateProfile updates user profile information.
 func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, firstName, lastName, email string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.UpdateProfile")
+	defer span.End()
+
 	user, err := s.userRepo.FindByID(ctx, userID)
 	if err != nil || user == nil {
-		return errors.New("user not found")
+		return ErrUserNotFound
 	}
 
 	// If email is being changed, check it's not already taken
-	if email != "" && email != user.Email {
+	emailChanged := email != "" && email != user.Email
+	if emailChanged {
 		existingUser, _ := s.userRepo.FindByEmail(ctx, email)
 		if existingUser != nil {
-			return errors.New("email already exists")
+			return ErrEmailTaken
 		}
 		user.Email = email
 	}
@@ -470,18 +1310,57 @@ func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, firstName
 	if err := s.userRepo.Update(ctx, user); err != nil {
 		return err
 	}
+	s.audit(ctx, constants.AuditProfileUpdated, userID, map[string]any{"email_changed": emailChanged})
 
 	logger.Info("profile updated successfully", "userID", userID)
 	return nil
 }
 
+// PatchProfile applies a partial update to the user's own profile and returns
+// the updated user. A phone number, unless cleared, must be in E.164 format.
+func (s *AuthService) PatchProfile(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.PatchProfile")
+	defer span.End()
+
+	if patch.PhoneNumber != nil && *patch.PhoneNumber != "" && !e164Pattern.MatchString(*patch.PhoneNumber) {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	user, err := s.userRepo.Patch(ctx, userID, patch)
+	if err != nil {
+		return nil, internalError("failed to update profile", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	var changed []string
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{"first_name", patch.FirstName},
+		{"last_name", patch.LastName},
+		{"avatar_url", patch.AvatarURL},
+		{"phone_number", patch.PhoneNumber},
+	} {
+		if field.value != nil {
+			changed = append(changed, field.name)
+		}
+	}
+	s.audit(ctx, constants.AuditProfileUpdated, userID, map[string]any{"fields": changed})
+
+	logger.Info("profile patched", "userID", userID)
+	return user, nil
+}
+
 // ============================================================================
 // Email Methods
 // ============================================================================
 
 // sendWelcomeEmail sends a welcome email to new users after successful registration.
 // This method runs asynchronously and logs errors without failing the main operation.
-func (s *AuthService) sendWelcomeEmail(email, firstName string) {
+func (s *AuthService) sendWelcomeEmail(ctx context.Context, email, firstName string) {
 	subject := "Welcome to Authentio! 🎉"
 	body := fmt.Sprintf(`
 		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
@@ -506,7 +1385,7 @@ func (s *AuthService) sendWelcomeEmail(email, firstName string) {
 		</div>
 	`, firstName)
 
-	if err := s.emailClient.Send([]string{email}, subject, body); err != nil {
+	if err := s.emailClient.Send(ctx, []string{email}, subject, body); err != nil {
 		logger.Error("failed to send welcome email", "error", err, "email", email)
 	} else {
 		logger.Info("welcome email sent successfully", "email", email)
@@ -518,26 +1397,35 @@ func (s *AuthService) sendWelcomeEmail(email, firstName string) {
 // ============================================================================
 
 // generateAuthResponse creates authentication tokens and returns a unified login response.
-func (s *AuthService) generateAuthResponse(user *models.User) (*response.LoginResponse, error) {
-	// Generate access token
-	accessToken, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.FirstName, user.LastName)
-	if err != nil {
-		return nil, err
-	}
-
+// Every call starts a new session whose ID is the refresh token family ID. The
+// refresh token of a rememberMe session is valid for rememberMeTTL instead of
+// refreshTokenTTL; the access token lifetime is the same either way.
+func (s *AuthService) generateAuthResponse(ctx context.Context, user *models.User, rememberMe bool) (*response.LoginResponse, error) {
 	// Generate refresh token
 	refreshToken := &models.RefreshToken{
-		UserID: user.ID,
-		Token:  generateSecureToken(),
+		UserID:   user.ID,
+		Token:    generateSecureToken(),
+		FamilyID: generateSecureToken(), // each login starts a new token family
 		BaseModel: models.BaseModel{
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
-			ExpiredAt: timePtr(time.Now().Add(30 * 24 * time.Hour)), // 30 days
+			ExpiredAt: timePtr(time.Now().Add(s.sessionTTL(rememberMe))),
 		},
 	}
 
 	// Save refresh token to database
-	if err := s.tokenRepo.SaveRefreshToken(context.Background(), refreshToken); err != nil {
+	if err := s.tokenRepo.SaveRefreshToken(ctx, refreshToken); err != nil {
+		return nil, err
+	}
+
+	// Record the session for this login (device)
+	if err := s.saveSession(ctx, refreshToken, rememberMe); err != nil {
+		return nil, err
+	}
+
+	// Generate access token bound to the session
+	accessToken, err := s.generateAccessToken(ctx, user, refreshToken.FamilyID)
+	if err != nil {
 		return nil, err
 	}
 
@@ -564,14 +1452,25 @@ func (s *AuthService) generateAuthResponse(user *models.User) (*response.LoginRe
 // Utility Functions
 // ============================================================================
 
-// generateRandomCode generates a random numeric code of specified length.
-func generateRandomCode(length int) string {
-	const digits = "0123456789"
-	bytes := make([]byte, length)
-	for i := range bytes {
-		bytes[i] = digits[time.Now().UnixNano()%int64(len(digits))]
+// generateAccessToken issues an access token for user bound to the given session,
+// carrying the permissions of their roles and, for tenant users, scoped to their tenant.
+func (s *AuthService) generateAccessToken(ctx context.Context, user *models.User, sessionID string) (string, error) {
+	permissions, err := s.userPermissions(ctx, user.ID)
+	if err != nil {
+		return "", err
 	}
-	return string(bytes)
+
+	return s.jwtManager.GenerateTokenWithClaims(jwt.UserClaims{
+		UserID:      user.ID,
+		Email:       user.Email,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		SessionID:   sessionID,
+		TenantID:    user.TenantID,
+		Role:        string(user.Role),
+		Permissions: permissions,
+		DPoPJKT:     jwt.DPoPKeyFromContext(ctx),
+	})
 }
 
 // generateSecureToken generates a cryptographically secure random token.
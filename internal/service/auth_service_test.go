@@ -0,0 +1,763 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/internal/testutil/mocks"
+	"authentio/pkg/email"
+	"authentio/pkg/jwt"
+	"authentio/pkg/password"
+	"authentio/pkg/totp"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+)
+
+// mockPassword is the password of the users the mock repositories return
+const mockPassword = "Str0ng!Passw0rd"
+
+// mockPasswordHash is hashed once: hashing at the default cost is slow
+var mockPasswordHash = sync.OnceValue(func() string {
+	hashed, err := password.Hash(mockPassword)
+	if err != nil {
+		panic(err)
+	}
+	return hashed
+})
+
+// newHarness returns a harness whose expectations are asserted, and which is
+// closed, when the test ends.
+func newHarness(t *testing.T) *mocks.AuthServiceTestHarness {
+	t.Helper()
+
+	h := &mocks.AuthServiceTestHarness{}
+	h.Setup()
+	t.Cleanup(func() {
+		h.Close()
+		h.AssertExpectations(t)
+	})
+	return h
+}
+
+// mockUser returns the active user 1 with mockPassword.
+func mockUser() *models.User {
+	return &models.User{
+		BaseModel: models.BaseModel{ID: 1},
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane@example.com",
+		Password:  mockPasswordHash(),
+		IsActive:  true,
+	}
+}
+
+// sentWithSubject returns the emails sent to address with the given subject.
+func sentWithSubject(h *mocks.AuthServiceTestHarness, address, subject string) []email.Message {
+	var sent []email.Message
+	for _, msg := range h.Emails.Messages() {
+		if msg.Subject == subject && slices.Contains(msg.To, address) {
+			sent = append(sent, msg)
+		}
+	}
+	return sent
+}
+
+// userIDClaim returns the user_id claim of a verified access token.
+func userIDClaim(t *testing.T, h *mocks.AuthServiceTestHarness, accessToken string) int64 {
+	t.Helper()
+
+	claims, err := h.JWT.VerifyToken(accessToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	uid, _ := claims["user_id"].(float64)
+	return int64(uid)
+}
+
+// ============================================================================
+// Registration and Login
+// ============================================================================
+
+func TestRegister(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(nil, nil)
+	h.Users.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		ok, _ := password.Verify(mockPassword, u.Password)
+		return u.Email == "jane@example.com" && u.IsActive && ok
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 7
+	}).Return(nil)
+
+	resp, err := h.Service.Register(ctx, models.RegisterRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane@example.com",
+		Password:  mockPassword,
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if resp.User.ID != 7 || resp.User.Email != "jane@example.com" {
+		t.Fatalf("Register returned user %+v, want user 7", resp.User)
+	}
+
+	h.Service.FlushBackground(ctx)
+	if len(sentWithSubject(h, "jane@example.com", "Welcome to Authentio! 🎉")) != 1 {
+		t.Fatal("no welcome email sent")
+	}
+}
+
+func TestRegisterEmailTaken(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+
+	_, err := h.Service.Register(context.Background(), models.RegisterRequest{
+		FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: mockPassword,
+	})
+	if !errors.Is(err, service.ErrEmailTaken) {
+		t.Fatalf("Register = %v, want ErrEmailTaken", err)
+	}
+	h.Users.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRegisterWeakPassword(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(nil, nil)
+
+	_, err := h.Service.Register(context.Background(), models.RegisterRequest{
+		FirstName: "Jane", LastName: "Doe", Email: "jane@example.com", Password: "short",
+	})
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Register = %v, want *password.PolicyError", err)
+	}
+	h.Users.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestLogin(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.TwoFA.On("Is2FAEnabled", mock.Anything, int64(1)).Return(false, nil)
+	h.Tokens.On("SaveRefreshToken", mock.Anything, mock.MatchedBy(func(rt *models.RefreshToken) bool {
+		return rt.UserID == 1 && rt.Token != "" && rt.FamilyID != ""
+	})).Return(nil)
+	h.Tokens.On("SaveSession", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+		return s.UserID == 1 && s.RefreshTokenHash != ""
+	})).Return(nil)
+
+	resp, err := h.Service.Login(context.Background(), models.LoginRequest{Email: "jane@example.com", Password: mockPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if resp.TwoFactorRequired || resp.RefreshToken == "" {
+		t.Fatalf("Login response %+v, want tokens", resp)
+	}
+	if id := userIDClaim(t, h, resp.AccessToken); id != 1 {
+		t.Fatalf("access token user_id = %d, want 1", id)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.Users.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+
+	for _, req := range []models.LoginRequest{
+		{Email: "jane@example.com", Password: "Wr0ng!Passw0rd"},
+		{Email: "nobody@example.com", Password: mockPassword},
+	} {
+		if _, err := h.Service.Login(ctx, req); !errors.Is(err, service.ErrInvalidCredentials) {
+			t.Errorf("Login(%s, %s) = %v, want ErrInvalidCredentials", req.Email, req.Password, err)
+		}
+	}
+	h.Tokens.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestLoginInactiveAccount(t *testing.T) {
+	h := newHarness(t)
+
+	user := mockUser()
+	user.IsActive = false
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(user, nil)
+
+	_, err := h.Service.Login(context.Background(), models.LoginRequest{Email: "jane@example.com", Password: mockPassword})
+	if err == nil {
+		t.Fatal("Login of a deactivated account succeeded")
+	}
+	h.Tokens.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestLoginTOTPChallenge(t *testing.T) {
+	h := newHarness(t)
+	rdb := redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	h.Service.WithTOTPLogin(rdb, time.Minute)
+
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.TwoFA.On("Is2FAEnabled", mock.Anything, int64(1)).Return(true, nil)
+	h.TwoFA.On("Get2FAMethod", mock.Anything, int64(1)).Return("totp", nil)
+
+	resp, err := h.Service.Login(context.Background(), models.LoginRequest{Email: "jane@example.com", Password: mockPassword})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !resp.TwoFactorRequired || resp.AccessToken != "" || resp.RefreshToken != "" {
+		t.Fatalf("Login response %+v, want a TOTP challenge without tokens", resp)
+	}
+	h.Tokens.AssertNotCalled(t, "SaveRefreshToken", mock.Anything, mock.Anything)
+}
+
+// ============================================================================
+// Password Reset
+// ============================================================================
+
+func TestRequestPasswordReset(t *testing.T) {
+	h := newHarness(t)
+
+	var code string
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.OTPs.On("CreateOTP", mock.Anything, mock.MatchedBy(func(otp *models.OTP) bool {
+		return otp.Type == string(constants.TypePasswordReset) && otp.Email == "jane@example.com" &&
+			otp.UserID != nil && *otp.UserID == 1
+	})).Run(func(args mock.Arguments) {
+		code = args.Get(1).(*models.OTP).Code
+	}).Return(nil)
+
+	if err := h.Service.RequestPasswordReset(context.Background(), "jane@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	sent := sentWithSubject(h, "jane@example.com", "Password reset request")
+	if len(sent) != 1 || code == "" || !strings.Contains(sent[0].Text, code) {
+		t.Fatalf("reset email %+v, want one containing code %q", sent, code)
+	}
+}
+
+func TestRequestPasswordResetUnknownEmail(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+
+	// Unknown emails succeed too, so the response does not reveal which accounts exist
+	if err := h.Service.RequestPasswordReset(context.Background(), "nobody@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	h.OTPs.AssertNotCalled(t, "CreateOTP", mock.Anything, mock.Anything)
+	if n := len(h.Emails.Messages()); n != 0 {
+		t.Fatalf("%d emails sent for an unknown address", n)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	h := newHarness(t)
+	const newPassword = "N3w!Passw0rd-2024"
+
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "password_reset").Return(&models.OTP{BaseModel: models.BaseModel{ID: 9}}, nil)
+	h.OTPs.On("VerifyOTP", mock.Anything, "jane@example.com", "123456", "password_reset").Return(true, nil)
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.Users.On("UpdatePassword", mock.Anything, int64(1), mock.MatchedBy(func(hash string) bool {
+		ok, _ := password.Verify(newPassword, hash)
+		return ok
+	})).Return(nil)
+
+	if err := h.Service.ResetPassword(context.Background(), "jane@example.com", "123456", newPassword); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if len(sentWithSubject(h, "jane@example.com", "Password Changed Successfully")) != 1 {
+		t.Fatal("no password changed email sent")
+	}
+}
+
+func TestResetPasswordInvalidCode(t *testing.T) {
+	h := newHarness(t)
+
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "password_reset").Return(&models.OTP{BaseModel: models.BaseModel{ID: 9}}, nil)
+	h.OTPs.On("VerifyOTP", mock.Anything, "jane@example.com", "000000", "password_reset").Return(false, nil)
+	h.OTPs.On("RecordVerificationAttempt", mock.Anything, int64(9)).Return(2, nil)
+
+	err := h.Service.ResetPassword(context.Background(), "jane@example.com", "000000", "N3w!Passw0rd-2024")
+	if !errors.Is(err, service.ErrInvalidOTP) {
+		t.Fatalf("ResetPassword = %v, want an invalid OTP error", err)
+	}
+	h.Users.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ============================================================================
+// Two-Factor Authentication
+// ============================================================================
+
+func TestSend2FAOTP(t *testing.T) {
+	h := newHarness(t)
+
+	var stored *models.OTP
+	h.Users.On("FindByEmail", mock.Anything, "jane@example.com").Return(mockUser(), nil)
+	h.OTPs.On("CreateOTP", mock.Anything, mock.MatchedBy(func(otp *models.OTP) bool {
+		return otp.Type == string(constants.Type2FA) && otp.Channel == string(constants.DeliveryEmail)
+	})).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*models.OTP)
+	}).Return(nil)
+
+	if err := h.Service.Send2FAOTP(context.Background(), "jane@example.com", constants.DeliveryEmail); err != nil {
+		t.Fatalf("Send2FAOTP: %v", err)
+	}
+	code, ok := h.Emails.LastOTP("jane@example.com")
+	if !ok || stored == nil || code != stored.Code {
+		t.Fatalf("emailed code %q (sent %v), want the stored code", code, ok)
+	}
+}
+
+func TestSend2FAOTPUnknownUser(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByEmail", mock.Anything, "nobody@example.com").Return(nil, nil)
+
+	if err := h.Service.Send2FAOTP(context.Background(), "nobody@example.com", ""); !errors.Is(err, service.ErrUserNotFound) {
+		t.Fatalf("Send2FAOTP = %v, want ErrUserNotFound", err)
+	}
+	h.OTPs.AssertNotCalled(t, "CreateOTP", mock.Anything, mock.Anything)
+}
+
+func TestVerify2FA(t *testing.T) {
+	h := newHarness(t)
+
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "2fa").Return(&models.OTP{BaseModel: models.BaseModel{ID: 5}}, nil)
+	h.OTPs.On("VerifyOTP", mock.Anything, "jane@example.com", "123456", "2fa").Return(true, nil)
+
+	if err := h.Service.Verify2FA(context.Background(), "jane@example.com", "123456"); err != nil {
+		t.Fatalf("Verify2FA: %v", err)
+	}
+}
+
+func TestVerify2FAWrongCode(t *testing.T) {
+	h := newHarness(t)
+
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "2fa").Return(&models.OTP{BaseModel: models.BaseModel{ID: 5}}, nil)
+	h.OTPs.On("VerifyOTP", mock.Anything, "jane@example.com", "000000", "2fa").Return(false, nil)
+	h.OTPs.On("RecordVerificationAttempt", mock.Anything, int64(5)).Return(2, nil)
+
+	if err := h.Service.Verify2FA(context.Background(), "jane@example.com", "000000"); !errors.Is(err, service.ErrInvalidOTP) {
+		t.Fatalf("Verify2FA = %v, want ErrInvalidOTP", err)
+	}
+}
+
+func TestVerify2FALocksAfterLastAttempt(t *testing.T) {
+	h := newHarness(t)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "2fa").Return(&models.OTP{BaseModel: models.BaseModel{ID: 5}}, nil).Once()
+	h.OTPs.On("VerifyOTP", mock.Anything, "jane@example.com", "000000", "2fa").Return(false, nil)
+	h.OTPs.On("RecordVerificationAttempt", mock.Anything, int64(5)).Return(0, nil)
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "2fa").Return(&models.OTP{BaseModel: models.BaseModel{ID: 5}, LockedUntil: &lockedUntil}, nil).Once()
+
+	err := h.Service.Verify2FA(context.Background(), "jane@example.com", "000000")
+	var locked *service.ErrOTPLocked
+	if !errors.As(err, &locked) || locked.RetryAfter <= 0 {
+		t.Fatalf("Verify2FA = %v, want *ErrOTPLocked", err)
+	}
+}
+
+func TestVerify2FANoActiveCode(t *testing.T) {
+	h := newHarness(t)
+
+	h.OTPs.On("FindActiveOTP", mock.Anything, "jane@example.com", "2fa").Return(nil, nil)
+
+	if err := h.Service.Verify2FA(context.Background(), "jane@example.com", "123456"); !errors.Is(err, service.ErrInvalidOTP) {
+		t.Fatalf("Verify2FA = %v, want ErrInvalidOTP", err)
+	}
+	h.OTPs.AssertNotCalled(t, "VerifyOTP", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEnableAndDisable2FA(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	h.TwoFA.On("EnableEmail2FA", mock.Anything, int64(1)).Return(nil)
+	h.TwoFA.On("Is2FAEnabled", mock.Anything, int64(1)).Return(true, nil)
+	h.TwoFA.On("Disable2FA", mock.Anything, int64(1)).Return(nil)
+
+	if err := h.Service.EnableEmail2FA(ctx, 1); err != nil {
+		t.Fatalf("EnableEmail2FA: %v", err)
+	}
+	if enabled, err := h.Service.Is2FAEnabled(ctx, 1); err != nil || !enabled {
+		t.Fatalf("Is2FAEnabled = %v, %v, want true", enabled, err)
+	}
+	if err := h.Service.Disable2FA(ctx, 1); err != nil {
+		t.Fatalf("Disable2FA: %v", err)
+	}
+}
+
+func TestEnable2FARepositoryError(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+	dbErr := errors.New("connection refused")
+
+	h.TwoFA.On("EnableEmail2FA", mock.Anything, int64(1)).Return(dbErr)
+	h.TwoFA.On("Disable2FA", mock.Anything, int64(1)).Return(dbErr)
+	h.TwoFA.On("Is2FAEnabled", mock.Anything, int64(1)).Return(false, dbErr)
+
+	if err := h.Service.EnableEmail2FA(ctx, 1); !errors.Is(err, dbErr) {
+		t.Errorf("EnableEmail2FA = %v, want the repository error", err)
+	}
+	if err := h.Service.Disable2FA(ctx, 1); !errors.Is(err, dbErr) {
+		t.Errorf("Disable2FA = %v, want the repository error", err)
+	}
+	if _, err := h.Service.Is2FAEnabled(ctx, 1); !errors.Is(err, dbErr) {
+		t.Errorf("Is2FAEnabled = %v, want the repository error", err)
+	}
+}
+
+// ============================================================================
+// TOTP (Authenticator App)
+// ============================================================================
+
+func TestEnrollTOTP(t *testing.T) {
+	h := newHarness(t)
+
+	var saved string
+	h.Users.On("FindByID", mock.Anything, int64(1)).Return(mockUser(), nil)
+	h.TwoFA.On("SaveTOTPSecret", mock.Anything, int64(1), mock.AnythingOfType("string")).Run(func(args mock.Arguments) {
+		saved = args.String(2)
+	}).Return(nil)
+
+	enrollment, err := h.Service.EnrollTOTP(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("EnrollTOTP: %v", err)
+	}
+	if enrollment.Secret == "" || enrollment.Secret != saved {
+		t.Fatalf("enrollment secret %q, want the stored secret %q", enrollment.Secret, saved)
+	}
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/") || len(enrollment.QRCodePNG) == 0 {
+		t.Fatalf("enrollment %+v, want an otpauth URI and a QR code", enrollment)
+	}
+}
+
+func TestEnrollTOTPUnknownUser(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByID", mock.Anything, int64(2)).Return(nil, nil)
+
+	if _, err := h.Service.EnrollTOTP(context.Background(), 2); !errors.Is(err, service.ErrUserNotFound) {
+		t.Fatalf("EnrollTOTP = %v, want ErrUserNotFound", err)
+	}
+	h.TwoFA.AssertNotCalled(t, "SaveTOTPSecret", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// currentTOTPCode returns the code of secret for the current time step.
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestVerifyTOTPConfirmsEnrollment(t *testing.T) {
+	h := newHarness(t)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.TwoFA.On("GetPendingTOTPSecret", mock.Anything, int64(1)).Return(secret, nil)
+	h.TwoFA.On("EnableTOTP", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(nil)
+
+	if err := h.Service.VerifyTOTP(context.Background(), 1, currentTOTPCode(t, secret)); err != nil {
+		t.Fatalf("VerifyTOTP: %v", err)
+	}
+	h.TwoFA.AssertNotCalled(t, "RecordTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestVerifyTOTPActiveSecret(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := currentTOTPCode(t, secret)
+	h.TwoFA.On("GetPendingTOTPSecret", mock.Anything, int64(1)).Return("", repository.ErrTOTPNotEnrolled)
+	h.TwoFA.On("Get2FASecret", mock.Anything, int64(1)).Return(secret, nil)
+	h.TwoFA.On("RecordTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+	h.TwoFA.On("RecordTOTPStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(false, nil).Once()
+
+	if err := h.Service.VerifyTOTP(ctx, 1, code); err != nil {
+		t.Fatalf("VerifyTOTP: %v", err)
+	}
+	// The step of the code is already recorded: the code cannot be replayed
+	if err := h.Service.VerifyTOTP(ctx, 1, code); !errors.Is(err, service.ErrTOTPCodeReused) {
+		t.Fatalf("replayed VerifyTOTP = %v, want ErrTOTPCodeReused", err)
+	}
+}
+
+func TestVerifyTOTPWrongCode(t *testing.T) {
+	h := newHarness(t)
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := "000000"
+	if currentTOTPCode(t, secret) == wrong {
+		wrong = "111111"
+	}
+	h.TwoFA.On("GetPendingTOTPSecret", mock.Anything, int64(1)).Return("", repository.ErrTOTPNotEnrolled)
+	h.TwoFA.On("Get2FASecret", mock.Anything, int64(1)).Return(secret, nil)
+
+	if err := h.Service.VerifyTOTP(context.Background(), 1, wrong); !errors.Is(err, service.ErrInvalidTOTPCode) {
+		t.Fatalf("VerifyTOTP = %v, want ErrInvalidTOTPCode", err)
+	}
+	h.TwoFA.AssertNotCalled(t, "RecordTOTPStep", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ============================================================================
+// Token Management
+// ============================================================================
+
+// expectRotation sets up the calls of a successful rotation of the refresh
+// token old, which belongs to user 1 in session family-1.
+func expectRotation(h *mocks.AuthServiceTestHarness, old string) {
+	h.Tokens.On("GetRefreshToken", mock.Anything, old).Return(&models.RefreshToken{UserID: 1, FamilyID: "family-1", Token: old}, nil)
+	h.Tokens.On("GetSession", mock.Anything, "family-1").Return(&models.Session{ID: "family-1", UserID: 1}, nil)
+	h.Tokens.On("RotateRefreshToken", mock.Anything, old, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+		// The repository copies the user and family of the consumed token
+		next := args.Get(2).(*models.RefreshToken)
+		next.UserID, next.FamilyID = 1, "family-1"
+	}).Return(nil)
+	h.Users.On("FindByID", mock.Anything, int64(1)).Return(mockUser(), nil)
+	h.Tokens.On("SaveSession", mock.Anything, mock.MatchedBy(func(s *models.Session) bool {
+		return s.ID == "family-1" && s.UserID == 1
+	})).Return(nil)
+}
+
+func TestRefreshToken(t *testing.T) {
+	h := newHarness(t)
+	expectRotation(h, "old-refresh-token")
+
+	resp, err := h.Service.RefreshToken(context.Background(), "old-refresh-token")
+	if err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if resp.RefreshToken == "" || resp.RefreshToken == "old-refresh-token" {
+		t.Fatalf("RefreshToken returned %q, want a new refresh token", resp.RefreshToken)
+	}
+	if id := userIDClaim(t, h, resp.AccessToken); id != 1 || resp.User.ID != 1 {
+		t.Fatalf("refreshed tokens for user %d (%d), want 1", id, resp.User.ID)
+	}
+}
+
+func TestRotateRefreshToken(t *testing.T) {
+	h := newHarness(t)
+	expectRotation(h, "old-refresh-token")
+
+	access, refresh, err := h.Service.RotateRefreshToken(context.Background(), "old-refresh-token")
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if refresh == "" || refresh == "old-refresh-token" {
+		t.Fatalf("RotateRefreshToken returned %q, want a new refresh token", refresh)
+	}
+	if id := userIDClaim(t, h, access); id != 1 {
+		t.Fatalf("access token user_id = %d, want 1", id)
+	}
+}
+
+func TestRotateRefreshTokenReused(t *testing.T) {
+	h := newHarness(t)
+
+	h.Tokens.On("GetRefreshToken", mock.Anything, "used-token").Return(&models.RefreshToken{UserID: 1, FamilyID: "family-1"}, nil)
+	h.Tokens.On("GetSession", mock.Anything, "family-1").Return(nil, repository.ErrSessionNotFound)
+	h.Tokens.On("RotateRefreshToken", mock.Anything, "used-token", mock.Anything).Return(repository.ErrRefreshTokenReused)
+
+	_, _, err := h.Service.RotateRefreshToken(context.Background(), "used-token")
+	if !errors.Is(err, repository.ErrRefreshTokenReused) {
+		t.Fatalf("RotateRefreshToken = %v, want ErrRefreshTokenReused", err)
+	}
+	h.Users.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
+
+func TestRotateRefreshTokenUnknown(t *testing.T) {
+	h := newHarness(t)
+
+	h.Tokens.On("GetRefreshToken", mock.Anything, "unknown-token").Return(nil, repository.ErrRefreshTokenNotFound)
+	h.Tokens.On("RotateRefreshToken", mock.Anything, "unknown-token", mock.Anything).Return(repository.ErrRefreshTokenNotFound)
+
+	_, err := h.Service.RefreshToken(context.Background(), "unknown-token")
+	if !errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, repository.ErrRefreshTokenReused) {
+		t.Fatalf("RefreshToken = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	accessToken, err := h.JWT.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "family-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Tokens.On("GetRefreshToken", mock.Anything, "refresh-token").Return(&models.RefreshToken{UserID: 1, FamilyID: "family-1"}, nil)
+	h.Tokens.On("DeleteRefreshToken", mock.Anything, "refresh-token").Return(nil)
+
+	if err := h.Service.Logout(ctx, "refresh-token", accessToken); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	// The access token is revoked for the rest of its lifetime
+	claims, err := h.JWT.VerifyToken(accessToken)
+	if err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+	jti, _ := claims["jti"].(string)
+	if revoked, err := h.JWT.IsRevoked(ctx, jti); err != nil || !revoked {
+		t.Fatalf("IsRevoked = %v, %v, want the access token revoked", revoked, err)
+	}
+}
+
+func TestLogoutUnknownToken(t *testing.T) {
+	h := newHarness(t)
+
+	h.Tokens.On("GetRefreshToken", mock.Anything, "unknown-token").Return(nil, repository.ErrRefreshTokenNotFound)
+
+	if err := h.Service.Logout(context.Background(), "unknown-token", ""); !errors.Is(err, service.ErrInvalidRefreshToken) {
+		t.Fatalf("Logout = %v, want ErrInvalidRefreshToken", err)
+	}
+	h.Tokens.AssertNotCalled(t, "DeleteRefreshToken", mock.Anything, mock.Anything)
+}
+
+func TestLogoutAll(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	h.Tokens.On("DeleteUserRefreshTokens", mock.Anything, int64(1)).Return(nil)
+
+	if err := h.Service.LogoutAll(ctx, 1); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+
+	// Access tokens issued before the logout are rejected
+	issuedBefore := jwt.Claims{"user_id": float64(1), "iat": float64(time.Now().Add(-time.Minute).Unix())}
+	if revoked, err := h.JWT.IssuedBeforeRevocation(ctx, issuedBefore); err != nil || !revoked {
+		t.Fatalf("IssuedBeforeRevocation = %v, %v, want earlier tokens revoked", revoked, err)
+	}
+}
+
+func TestLogoutAllRepositoryError(t *testing.T) {
+	h := newHarness(t)
+	dbErr := errors.New("connection refused")
+
+	h.Tokens.On("DeleteUserRefreshTokens", mock.Anything, int64(1)).Return(dbErr)
+
+	if err := h.Service.LogoutAll(context.Background(), 1); !errors.Is(err, dbErr) {
+		t.Fatalf("LogoutAll = %v, want the repository error", err)
+	}
+}
+
+// ============================================================================
+// Profile Management
+// ============================================================================
+
+func TestGetUserProfile(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	h.Users.On("FindByID", mock.Anything, int64(1)).Return(mockUser(), nil)
+	h.Users.On("FindByID", mock.Anything, int64(2)).Return(nil, nil)
+
+	profile, err := h.Service.GetUserProfile(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetUserProfile: %v", err)
+	}
+	if profile.ID != 1 || profile.Email != "jane@example.com" || profile.FirstName != "Jane" {
+		t.Fatalf("GetUserProfile = %+v, want Jane's profile", profile)
+	}
+	if _, err := h.Service.GetUserProfile(ctx, 2); !errors.Is(err, service.ErrUserNotFound) {
+		t.Fatalf("GetUserProfile(unknown) = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByID", mock.Anything, int64(1)).Return(mockUser(), nil)
+	h.Users.On("FindByEmail", mock.Anything, "janet@example.com").Return(nil, nil)
+	h.Users.On("Update", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.ID == 1 && u.FirstName == "Janet" && u.LastName == "Doe" && u.Email == "janet@example.com"
+	})).Return(nil)
+
+	if err := h.Service.UpdateProfile(context.Background(), 1, "Janet", "", "janet@example.com"); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+}
+
+func TestUpdateProfileEmailTaken(t *testing.T) {
+	h := newHarness(t)
+
+	h.Users.On("FindByID", mock.Anything, int64(1)).Return(mockUser(), nil)
+	h.Users.On("FindByEmail", mock.Anything, "john@example.com").Return(&models.User{BaseModel: models.BaseModel{ID: 2}}, nil)
+
+	err := h.Service.UpdateProfile(context.Background(), 1, "", "", "john@example.com")
+	if !errors.Is(err, service.ErrEmailTaken) {
+		t.Fatalf("UpdateProfile = %v, want ErrEmailTaken", err)
+	}
+	h.Users.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestPatchProfile(t *testing.T) {
+	h := newHarness(t)
+
+	phone := "+2348012345678"
+	patch := models.UserPatch{PhoneNumber: &phone}
+	patched := mockUser()
+	patched.PhoneNumber = phone
+	h.Users.On("Patch", mock.Anything, int64(1), patch).Return(patched, nil)
+
+	user, err := h.Service.PatchProfile(context.Background(), 1, patch)
+	if err != nil {
+		t.Fatalf("PatchProfile: %v", err)
+	}
+	if user.PhoneNumber != phone {
+		t.Fatalf("PatchProfile returned phone %q, want %q", user.PhoneNumber, phone)
+	}
+}
+
+func TestPatchProfileRejectsInvalidPhone(t *testing.T) {
+	h := newHarness(t)
+
+	phone := "08012345678"
+	_, err := h.Service.PatchProfile(context.Background(), 1, models.UserPatch{PhoneNumber: &phone})
+	if !errors.Is(err, service.ErrInvalidPhoneNumber) {
+		t.Fatalf("PatchProfile = %v, want ErrInvalidPhoneNumber", err)
+	}
+	h.Users.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatchProfileUnknownUser(t *testing.T) {
+	h := newHarness(t)
+
+	name := "Janet"
+	patch := models.UserPatch{FirstName: &name}
+	h.Users.On("Patch", mock.Anything, int64(2), patch).Return(nil, nil)
+
+	if _, err := h.Service.PatchProfile(context.Background(), 2, patch); !errors.Is(err, service.ErrUserNotFound) {
+		t.Fatalf("PatchProfile = %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// ============================================================================
+// Background Emails
+// ============================================================================
+
+// backgroundTasks tracks the emails sent off the request path (welcome,
+// verification, lockout and new-country notices), so shutdown can wait for them.
+// It is shared by pointer because handlers hold copies of the AuthService.
+type backgroundTasks struct {
+	wg sync.WaitGroup
+}
+
+// goBackground runs fn in a new goroutine that FlushBackground waits for.
+func (s *AuthService) goBackground(fn func()) {
+	s.background.wg.Add(1)
+	go func() {
+		defer s.background.wg.Done()
+		fn()
+	}()
+}
+
+// FlushBackground waits until the emails sent in the background have been
+// handed to the email client or queue, or until ctx is done, whichever comes
+// first. It returns ctx.Err() when emails were still being sent.
+func (s *AuthService) FlushBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Token Claims Cache
+// ============================================================================
+
+const (
+	// claimsCacheKeyPrefix namespaces cached token claims; the key is followed by
+	// the hex SHA-256 of the access token, so Redis never holds a usable token
+	claimsCacheKeyPrefix = "auth:claims:"
+
+	// claimsSessionKeyPrefix namespaces the set of cached token hashes of each
+	// session, so revoking a session can evict the claims of all its tokens
+	claimsSessionKeyPrefix = "auth:claims:session:"
+)
+
+// CachedClaims returns the claims cached for an access token that passed
+// signature and session validation within the session cache TTL. It implements
+// middleware.ClaimsCache and reports false when the session cache is disabled,
+// for unknown tokens and on Redis errors.
+func (s *AuthService) CachedClaims(ctx context.Context, token string) (jwt.Claims, bool) {
+	if s.sessionCache == nil || s.sessionCacheTTL <= 0 {
+		return nil, false
+	}
+
+	data, err := s.sessionCache.Get(ctx, claimsCacheKey(token)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Warn("claims cache lookup failed, verifying the token", "error", err)
+		}
+		return nil, false
+	}
+
+	var claims jwt.Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		logger.Warn("discarding unreadable cached claims", "error", err)
+		return nil, false
+	}
+	return claims, true
+}
+
+// CacheClaims caches the verified claims of an access token whose session was
+// found active, for the session cache TTL or until the token expires, whichever
+// comes first. The entry is evicted with the token's session (logout, session
+// revocation, refresh, reuse detection) and by Logout for the token itself.
+// Failures are logged: the token is verified again on its next use.
+func (s *AuthService) CacheClaims(ctx context.Context, token string, claims jwt.Claims) {
+	if s.sessionCache == nil || s.sessionCacheTTL <= 0 {
+		return
+	}
+
+	ttl := s.sessionCacheTTL
+	if exp, ok := claims["exp"].(float64); ok {
+		if remaining := time.Until(time.Unix(int64(exp), 0)); remaining < ttl {
+			ttl = remaining
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		logger.Warn("failed to encode claims for the cache", "error", err)
+		return
+	}
+
+	pipe := s.sessionCache.TxPipeline()
+	pipe.Set(ctx, claimsCacheKey(token), data, ttl)
+	if sessionID, _ := claims["session_id"].(string); sessionID != "" {
+		// Every entry of the set expires within sessionCacheTTL of being added
+		pipe.SAdd(ctx, claimsSessionKey(sessionID), tokenHash(token))
+		pipe.Expire(ctx, claimsSessionKey(sessionID), s.sessionCacheTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warn("failed to cache token claims", "error", err)
+	}
+}
+
+// evictTokenClaims removes the cached claims of a revoked access token.
+func (s *AuthService) evictTokenClaims(ctx context.Context, token string) {
+	if s.sessionCache == nil {
+		return
+	}
+	if err := s.sessionCache.Del(ctx, claimsCacheKey(token)).Err(); err != nil {
+		logger.Warn("failed to evict token claims from cache", "error", err)
+	}
+}
+
+// evictSessionClaims removes the cached claims of every token of the sessions.
+func (s *AuthService) evictSessionClaims(ctx context.Context, sessionIDs ...string) {
+	var keys []string
+	for _, id := range sessionIDs {
+		hashes, err := s.sessionCache.SMembers(ctx, claimsSessionKey(id)).Result()
+		if err != nil {
+			logger.Warn("failed to list cached token claims of session", "error", err, "sessionID", id)
+			continue
+		}
+		for _, hash := range hashes {
+			keys = append(keys, claimsCacheKeyPrefix+hash)
+		}
+		keys = append(keys, claimsSessionKey(id))
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.sessionCache.Del(ctx, keys...).Err(); err != nil {
+		logger.Warn("failed to evict token claims from cache", "error", err, "sessions", len(sessionIDs))
+	}
+}
+
+// claimsCacheKey returns the Redis key caching the claims of token
+func claimsCacheKey(token string) string {
+	return claimsCacheKeyPrefix + tokenHash(token)
+}
+
+// claimsSessionKey returns the Redis key listing the cached tokens of a session
+func claimsSessionKey(sessionID string) string {
+	return claimsSessionKeyPrefix + sessionID
+}
+
+// tokenHash returns the hex-encoded SHA-256 of an access token
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
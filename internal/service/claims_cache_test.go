@@ -0,0 +1,234 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"authentio/internal/middleware"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/internal/testutil/mocks"
+	"authentio/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+)
+
+// withClaimsCache enables the session and claims cache of the harness's
+// service on its in-memory Redis.
+func withClaimsCache(t *testing.T, h *mocks.AuthServiceTestHarness, ttl time.Duration) {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	h.Service.WithSessionCache(rdb, ttl)
+}
+
+// cachedToken issues an access token of user 1 in session sessionID and caches
+// its verified claims.
+func cachedToken(t *testing.T, h *mocks.AuthServiceTestHarness, sessionID string) string {
+	t.Helper()
+
+	token, err := h.JWT.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := h.JWT.VerifyToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Service.CacheClaims(context.Background(), token, jwt.Claims(claims))
+	if _, ok := h.Service.CachedClaims(context.Background(), token); !ok {
+		t.Fatal("claims not cached")
+	}
+	return token
+}
+
+func TestClaimsCacheRoundTrip(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, time.Minute)
+	ctx := context.Background()
+
+	token := cachedToken(t, h, "family-1")
+	claims, ok := h.Service.CachedClaims(ctx, token)
+	if !ok {
+		t.Fatal("CachedClaims missed a cached token")
+	}
+	if claims["user_id"] != float64(1) || claims["session_id"] != "family-1" || claims["email"] != "jane@example.com" {
+		t.Fatalf("CachedClaims = %v, want the token's claims", claims)
+	}
+
+	// Only the SHA-256 of the token is stored
+	for _, key := range h.Redis.Keys() {
+		if key == "auth:claims:"+token {
+			t.Fatal("claims cached under the raw token")
+		}
+	}
+
+	other, err := h.JWT.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "family-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Service.CachedClaims(ctx, other); ok {
+		t.Fatal("CachedClaims hit for a token that was never cached")
+	}
+}
+
+func TestClaimsCacheDisabled(t *testing.T) {
+	h := newHarness(t)
+	ctx := context.Background()
+
+	token, err := h.JWT.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "family-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Service.CacheClaims(ctx, token, jwt.Claims{"user_id": float64(1)})
+	if _, ok := h.Service.CachedClaims(ctx, token); ok {
+		t.Fatal("claims cached without a session cache")
+	}
+}
+
+func TestClaimsCacheExpires(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, 30*time.Second)
+	ctx := context.Background()
+
+	token := cachedToken(t, h, "family-1")
+	h.Redis.FastForward(31 * time.Second)
+	if _, ok := h.Service.CachedClaims(ctx, token); ok {
+		t.Fatal("claims still cached after the session cache TTL")
+	}
+
+	// Claims are never cached past the token's expiry
+	expiring := "expiring-token"
+	h.Service.CacheClaims(ctx, expiring, jwt.Claims{"user_id": float64(1), "exp": float64(time.Now().Add(5 * time.Second).Unix())})
+	h.Redis.FastForward(6 * time.Second)
+	if _, ok := h.Service.CachedClaims(ctx, expiring); ok {
+		t.Fatal("claims still cached after the token expired")
+	}
+
+	h.Service.CacheClaims(ctx, "expired-token", jwt.Claims{"user_id": float64(1), "exp": float64(time.Now().Add(-time.Minute).Unix())})
+	if _, ok := h.Service.CachedClaims(ctx, "expired-token"); ok {
+		t.Fatal("claims of an expired token were cached")
+	}
+}
+
+func TestClaimsCacheEvictedOnLogout(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, time.Minute)
+	ctx := context.Background()
+
+	token := cachedToken(t, h, "family-1")
+	h.Tokens.On("GetRefreshToken", mock.Anything, "refresh-token").Return(&models.RefreshToken{UserID: 1, FamilyID: "family-1"}, nil)
+	h.Tokens.On("DeleteRefreshToken", mock.Anything, "refresh-token").Return(nil)
+
+	if err := h.Service.Logout(ctx, "refresh-token", token); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, ok := h.Service.CachedClaims(ctx, token); ok {
+		t.Fatal("claims still cached after logout")
+	}
+}
+
+func TestClaimsCacheEvictedOnSessionRevocation(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, time.Minute)
+	ctx := context.Background()
+
+	revoked := cachedToken(t, h, "family-1")
+	alsoRevoked := cachedToken(t, h, "family-1")
+	kept := cachedToken(t, h, "family-2")
+	h.Tokens.On("RevokeSession", mock.Anything, int64(1), "family-1").Return(nil)
+
+	if err := h.Service.RevokeSession(ctx, 1, "family-1"); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	for _, token := range []string{revoked, alsoRevoked} {
+		if _, ok := h.Service.CachedClaims(ctx, token); ok {
+			t.Fatal("claims of a revoked session still cached")
+		}
+	}
+	if _, ok := h.Service.CachedClaims(ctx, kept); !ok {
+		t.Fatal("claims of another session were evicted")
+	}
+}
+
+func TestClaimsCacheEvictedOnLogoutAll(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, time.Minute)
+	ctx := context.Background()
+
+	first := cachedToken(t, h, "family-1")
+	second := cachedToken(t, h, "family-2")
+	h.Tokens.On("ListSessions", mock.Anything, int64(1)).Return([]models.Session{{ID: "family-1"}, {ID: "family-2"}}, nil)
+	h.Tokens.On("DeleteUserRefreshTokens", mock.Anything, int64(1)).Return(nil)
+
+	if err := h.Service.LogoutAll(ctx, 1); err != nil {
+		t.Fatalf("LogoutAll: %v", err)
+	}
+	for _, token := range []string{first, second} {
+		if _, ok := h.Service.CachedClaims(ctx, token); ok {
+			t.Fatal("claims still cached after LogoutAll")
+		}
+	}
+}
+
+func TestClaimsCacheEvictedOnRefresh(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, time.Minute)
+	ctx := context.Background()
+
+	token := cachedToken(t, h, "family-1")
+	expectRotation(h, "old-refresh-token")
+
+	if _, err := h.Service.RefreshToken(ctx, "old-refresh-token"); err != nil {
+		t.Fatalf("RefreshToken: %v", err)
+	}
+	if _, ok := h.Service.CachedClaims(ctx, token); ok {
+		t.Fatal("claims still cached after the session was refreshed")
+	}
+}
+
+func TestRevokedSessionRejectedWithinCacheTTL(t *testing.T) {
+	h := newHarness(t)
+	withClaimsCache(t, h, 30*time.Second)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", middleware.AuthRequired(h.JWT, h.Service, nil, nil, h.Service), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	serve := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "127.0.0.1:4000"
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	token, err := h.JWT.GenerateTokenWithClaims(jwt.UserClaims{UserID: 1, Email: "jane@example.com", SessionID: "family-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Tokens.On("GetSession", mock.Anything, "family-1").
+		Return(&models.Session{ID: "family-1", UserID: 1, LastSeenAt: time.Now()}, nil).Once()
+
+	// Postgres is queried for the first request only
+	for i := range 3 {
+		if code := serve(token); code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, code)
+		}
+	}
+
+	// A session revoked outside this service stays cached for at most the TTL
+	h.Tokens.On("GetSession", mock.Anything, "family-1").Return(nil, repository.ErrSessionNotFound)
+	h.Redis.FastForward(31 * time.Second)
+	if code := serve(token); code != http.StatusUnauthorized {
+		t.Fatalf("token of a session revoked %s ago: status %d, want 401", 31*time.Second, code)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Personal Data Export (GDPR data portability)
+// ============================================================================
+
+// dataExportKeyPrefix namespaces the per-user export rate limit in Redis; the
+// key is followed by the user ID and expires when the next export is allowed
+const dataExportKeyPrefix = "auth:export:"
+
+// dataExportInterval is how often a user may export their data
+const dataExportInterval = 24 * time.Hour
+
+// ErrDataExportDisabled is returned when no export repository is configured
+var ErrDataExportDisabled = newError(CodeDataExportDisabled, "data export is not enabled")
+
+// ErrExportRateLimited is returned when the user already exported their data
+// within dataExportInterval. RetryAfter is when the next export is allowed.
+type ErrExportRateLimited struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrExportRateLimited) Error() string {
+	return fmt.Sprintf("data can be exported once a day, try again in %s", e.RetryAfter.Round(time.Minute))
+}
+
+// WithDataExport enables personal data exports, rate limited per user in Redis.
+func (s *AuthService) WithDataExport(repo repository.ExportRepository, rdb *redis.Client) *AuthService {
+	s.exportRepo = repo
+	s.exportLimiter = rdb
+	return s
+}
+
+// ExportUserData returns all personal data stored about the user. Each user may
+// export once per 24 hours; further requests fail with *ErrExportRateLimited.
+// An export that fails does not count.
+func (s *AuthService) ExportUserData(ctx context.Context, userID int64) (*models.UserExport, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ExportUserData")
+	defer span.End()
+
+	if s.exportRepo == nil || s.exportLimiter == nil {
+		return nil, ErrDataExportDisabled
+	}
+
+	key := dataExportKeyPrefix + strconv.FormatInt(userID, 10)
+	allowed, err := s.exportLimiter.SetNX(ctx, key, time.Now().UTC().Format(time.RFC3339), dataExportInterval).Result()
+	if err != nil {
+		return nil, internalError("failed to check data export rate limit", err)
+	}
+	if !allowed {
+		retryAfter, err := s.exportLimiter.TTL(ctx, key).Result()
+		if err != nil || retryAfter < 0 {
+			retryAfter = dataExportInterval
+		}
+		return nil, &ErrExportRateLimited{RetryAfter: retryAfter}
+	}
+
+	export, err := s.exportRepo.ExportUser(ctx, userID)
+	if err == nil && export == nil {
+		err = ErrUserNotFound
+	}
+	if err != nil {
+		if delErr := s.exportLimiter.Del(context.WithoutCancel(ctx), key).Err(); delErr != nil {
+			logger.Warn("failed to reset data export rate limit", "error", delErr, "userID", userID)
+		}
+		return nil, err
+	}
+
+	s.audit(ctx, constants.AuditDataExported, userID, nil)
+	logger.Info("personal data exported", "userID", userID, "sessions", len(export.Sessions), "auditEntries", len(export.AuditLog))
+	return export, nil
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Device Authorization Grant (RFC 8628)
+// ============================================================================
+
+// Statuses of a device authorization request. A request is pending until the
+// user approves or denies it at the verification URI, or until it expires.
+const (
+	DeviceStatusPending  = "pending"
+	DeviceStatusApproved = "approved"
+	DeviceStatusDenied   = "denied"
+	DeviceStatusExpired  = "expired"
+)
+
+// Redis key prefixes of device authorization requests, keyed by the SHA-256 of
+// the device code, and of their user codes, keyed by the SHA-256 of the
+// normalized user code and holding the device code hash
+const (
+	oauthDeviceKeyPrefix   = "oauth_device:"
+	oauthUserCodeKeyPrefix = "oauth_user_code:"
+)
+
+// userCodeAlphabet is the RFC 8628 section 6.1 alphabet: consonants only, so
+// codes are easy to type on a TV remote and never spell words. Eight of them
+// give about 34 bits, plenty for a code that expires in minutes.
+const (
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+)
+
+// slowDownStep is how much every slow_down answer adds to the polling interval
+// (RFC 8628 section 3.5), and pollLeeway how much sooner than the interval a
+// poll may arrive, as network delays shift requests around
+const (
+	slowDownStep = 5 * time.Second
+	pollLeeway   = 500 * time.Millisecond
+)
+
+// deviceChangeAttempts is how often a device authorization is reread before a
+// change is given up because concurrent polls and approvals keep modifying it
+const deviceChangeAttempts = 3
+
+var (
+	// ErrAuthorizationPending is returned while the user has not answered a device request
+	ErrAuthorizationPending = newError(CodeOAuthAuthorizationPending, "the user has not approved the request yet")
+
+	// ErrSlowDown is returned when a device polls faster than its interval,
+	// which grows by 5 seconds every time
+	ErrSlowDown = newError(CodeOAuthSlowDown, "polling too fast; increase the interval by 5 seconds")
+
+	// ErrDeviceAccessDenied is returned once when the user denied a device request
+	ErrDeviceAccessDenied = newError(CodeOAuthAccessDenied, "the user denied the request")
+
+	// ErrDeviceCodeExpired is returned for device codes the user did not answer in time
+	ErrDeviceCodeExpired = newError(CodeOAuthExpiredToken, "the device code has expired")
+
+	// ErrInvalidUserCode is returned by ActivateDevice for unknown or expired
+	// user codes and for requests that were already answered
+	ErrInvalidUserCode = newError(CodeInvalidUserCode, "invalid, expired or already used user code")
+)
+
+// deviceAuthorization is what a device code stands for
+type deviceAuthorization struct {
+	ClientID     string    `json:"client_id"`
+	Scope        string    `json:"scope"`
+	Status       string    `json:"status"`
+	UserID       int64     `json:"user_id,omitempty"` // approved requests only
+	Interval     int64     `json:"interval"`          // seconds between polls, raised by every slow_down
+	LastPolledAt time.Time `json:"last_polled_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// ----------------------------------------------------------------------------
+// Device authorization endpoint
+// ----------------------------------------------------------------------------
+
+// RequestDeviceAuthorization starts the device flow for a client on a device
+// without a browser or keyboard (a CLI, a smart TV). The device shows the user
+// code and verification URI, and polls the token endpoint with the device code
+// until the user, signed in on another device, approves or denies the request
+// with ActivateDevice.
+func (s *AuthService) RequestDeviceAuthorization(ctx context.Context, req models.DeviceAuthorizationRequest) (*models.DeviceAuthorizationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RequestDeviceAuthorization")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	scope, ok := grantedScope(client.AllowedScopes, req.Scope)
+	if !ok {
+		return nil, newError(CodeOAuthInvalidScope, "requested scope is not allowed for this client")
+	}
+
+	deviceCode, err := randomOAuthToken(32)
+	if err != nil {
+		return nil, internalError("failed to generate device code", err)
+	}
+	deviceHash := hashOAuthToken(deviceCode)
+	ttl := s.oauthServer.DeviceCodeTTL
+	interval := s.oauthServer.DevicePollInterval
+
+	// The request outlives its user code, so polls after expiry get
+	// expired_token instead of an unknown-code invalid_grant
+	value, err := json.Marshal(deviceAuthorization{
+		ClientID:  client.ClientID,
+		Scope:     scope,
+		Status:    DeviceStatusPending,
+		Interval:  int64(interval / time.Second),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return nil, internalError("failed to encode device authorization", err)
+	}
+	if err := s.oauthServer.Redis.Set(ctx, oauthDeviceKeyPrefix+deviceHash, value, 2*ttl).Err(); err != nil {
+		return nil, internalError("failed to store device authorization", err)
+	}
+
+	// User codes are short, so retry the rare collision with a pending one
+	var userCode string
+	for attempt := 0; userCode == "" && attempt < 5; attempt++ {
+		candidate, err := randomUserCode()
+		if err != nil {
+			return nil, internalError("failed to generate user code", err)
+		}
+		stored, err := s.oauthServer.Redis.SetNX(ctx, oauthUserCodeKeyPrefix+hashOAuthToken(candidate), deviceHash, ttl).Result()
+		if err != nil {
+			return nil, internalError("failed to store user code", err)
+		}
+		if stored {
+			userCode = candidate
+		}
+	}
+	if userCode == "" {
+		return nil, internalError("failed to generate a unique user code", nil)
+	}
+
+	display := userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+	logger.Info("oauth device authorization requested", "clientID", client.ClientID, "scope", scope)
+	return &models.DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                display,
+		VerificationURI:         s.oauthServer.DeviceVerificationURI,
+		VerificationURIComplete: appendQuery(s.oauthServer.DeviceVerificationURI, map[string]string{"user_code": display}),
+		ExpiresIn:               int(ttl.Seconds()),
+		Interval:                int(interval.Seconds()),
+	}, nil
+}
+
+// ----------------------------------------------------------------------------
+// Activation by the user
+// ----------------------------------------------------------------------------
+
+// ActivateDevice approves or denies, on behalf of userID, the device request of
+// userCode, which is matched ignoring case, spaces and dashes. Each user code
+// can be answered once. The device's next poll receives tokens for userID or
+// access_denied. Returns ErrInvalidUserCode.
+func (s *AuthService) ActivateDevice(ctx context.Context, userID int64, userCode string, approve bool) (*models.DeviceActivationResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ActivateDevice")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+
+	userCode = normalizeUserCode(userCode)
+	if userCode == "" {
+		return nil, ErrInvalidUserCode
+	}
+	userCodeKey := oauthUserCodeKeyPrefix + hashOAuthToken(userCode)
+	deviceHash, err := s.oauthServer.Redis.Get(ctx, userCodeKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidUserCode
+	}
+	if err != nil {
+		return nil, internalError("failed to look up user code", err)
+	}
+
+	status := DeviceStatusDenied
+	if approve {
+		status = DeviceStatusApproved
+	}
+	var device deviceAuthorization
+	err = s.changeDeviceAuthorization(ctx, oauthDeviceKeyPrefix+deviceHash, func(d *deviceAuthorization) (bool, error) {
+		if d.Status != DeviceStatusPending || time.Now().After(d.ExpiresAt) {
+			return false, ErrInvalidUserCode
+		}
+		d.Status = status
+		if approve {
+			d.UserID = userID
+		}
+		device = *d
+		return false, nil
+	})
+	if errors.Is(err, ErrOAuthInvalidGrant) {
+		return nil, ErrInvalidUserCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.oauthServer.Redis.Del(ctx, userCodeKey).Err(); err != nil {
+		logger.Warn("failed to remove answered user code", "error", err)
+	}
+
+	// The name is only shown to the user; a deleted client fails the next poll
+	clientName := ""
+	client, err := s.oauthServer.Clients.FindByClientID(ctx, device.ClientID)
+	if err != nil && !errors.Is(err, repository.ErrOAuthClientNotFound) {
+		logger.Warn("failed to look up oauth client of device request", "error", err, "clientID", device.ClientID)
+	}
+	if client != nil {
+		clientName = client.Name
+	}
+
+	logger.Info("oauth device request answered", "clientID", device.ClientID, "userID", userID, "status", status)
+	return &models.DeviceActivationResponse{
+		ClientID:   device.ClientID,
+		ClientName: clientName,
+		Scope:      device.Scope,
+		Status:     status,
+	}, nil
+}
+
+// ----------------------------------------------------------------------------
+// Token endpoint
+// ----------------------------------------------------------------------------
+
+// exchangeDeviceCode answers a poll of the device flow: tokens once the user
+// approved, access_denied once they denied, expired_token after the request
+// expired, and authorization_pending before that. Polls sooner than the interval
+// are answered with slow_down and lengthen it.
+func (s *AuthService) exchangeDeviceCode(ctx context.Context, client *models.OAuthClient, req models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.DeviceCode == "" {
+		return nil, newError(CodeOAuthInvalidRequest, "device_code is required")
+	}
+
+	var outcome error
+	var device deviceAuthorization
+	err := s.changeDeviceAuthorization(ctx, oauthDeviceKeyPrefix+hashOAuthToken(req.DeviceCode), func(d *deviceAuthorization) (bool, error) {
+		if d.ClientID != client.ClientID {
+			return false, ErrOAuthInvalidGrant
+		}
+
+		now := time.Now()
+		interval := time.Duration(d.Interval) * time.Second
+		tooSoon := !d.LastPolledAt.IsZero() && now.Sub(d.LastPolledAt) < interval-pollLeeway
+		d.LastPolledAt = now
+
+		switch {
+		case d.Status == DeviceStatusApproved:
+			// Removed in the same transaction, so only one poll gets tokens
+			device, outcome = *d, nil
+			return true, nil
+		case d.Status == DeviceStatusDenied:
+			outcome = ErrDeviceAccessDenied
+			return true, nil
+		case d.Status == DeviceStatusExpired || now.After(d.ExpiresAt):
+			d.Status, outcome = DeviceStatusExpired, ErrDeviceCodeExpired
+		case tooSoon:
+			d.Interval += int64(slowDownStep / time.Second)
+			outcome = ErrSlowDown
+		default:
+			outcome = ErrAuthorizationPending
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if outcome != nil {
+		return nil, outcome
+	}
+
+	return s.issueOAuthTokens(ctx, client, device.UserID, device.Scope)
+}
+
+// ----------------------------------------------------------------------------
+// Helpers
+// ----------------------------------------------------------------------------
+
+// changeDeviceAuthorization applies change to the device authorization stored
+// under key and stores the result with the remaining TTL, or removes it when
+// change says so. An error of change is returned without storing anything.
+// The change runs in a transaction watching key and is retried when a
+// concurrent poll or answer modified it first, so neither overwrites the other.
+// Returns ErrOAuthInvalidGrant when nothing is stored under key.
+func (s *AuthService) changeDeviceAuthorization(ctx context.Context, key string, change func(*deviceAuthorization) (remove bool, err error)) error {
+	apply := func(tx *redis.Tx) error {
+		value, err := tx.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return ErrOAuthInvalidGrant
+		}
+		if err != nil {
+			return internalError("failed to load device authorization", err)
+		}
+		var device deviceAuthorization
+		if err := json.Unmarshal(value, &device); err != nil {
+			return internalError("failed to decode device authorization", err)
+		}
+
+		remove, err := change(&device)
+		if err != nil {
+			return err
+		}
+		if value, err = json.Marshal(device); err != nil {
+			return internalError("failed to encode device authorization", err)
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if remove {
+				pipe.Del(ctx, key)
+			} else {
+				pipe.Set(ctx, key, value, redis.KeepTTL)
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.TxFailedErr) {
+			return internalError("failed to store device authorization", err)
+		}
+		return err
+	}
+
+	for attempt := 0; attempt < deviceChangeAttempts; attempt++ {
+		err := s.oauthServer.Redis.Watch(ctx, apply, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return internalError("device authorization kept changing concurrently", redis.TxFailedErr)
+}
+
+// randomUserCode returns userCodeLength random characters of userCodeAlphabet.
+func randomUserCode() (string, error) {
+	size := big.NewInt(int64(len(userCodeAlphabet)))
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode uppercases a user code as typed and drops the dashes and
+// spaces users add or leave out
+func normalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+	otpcode "authentio/pkg/otp"
+	"authentio/pkg/password"
+)
+
+// ============================================================================
+// Email Changes
+// ============================================================================
+
+// ErrSameEmail is returned when the requested new email is the current one
+var ErrSameEmail = newError(CodeEmailTaken, "new email is the same as the current email")
+
+// ChangeEmail starts changing the user's email to newEmail after verifying
+// their password. The new address is stored as the pending email and sent a
+// one-time code; the email only changes once ConfirmEmailChange redeems it.
+// Requesting another change replaces the pending one.
+func (s *AuthService) ChangeEmail(ctx context.Context, userID int64, newEmail, currentPassword string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ChangeEmail")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	if ok, _ := password.Verify(currentPassword, user.Password); !ok {
+		return ErrIncorrectPassword
+	}
+
+	newEmail = strings.TrimSpace(newEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if existing, _ := s.userRepo.FindByEmail(ctx, newEmail); existing != nil {
+		return ErrEmailTaken
+	}
+
+	code, err := otpcode.Generate(s.otpLength)
+	if err != nil {
+		return internalError("failed to generate OTP code", err)
+	}
+	otp := &models.OTP{
+		UserID: &userID,
+		Email:  newEmail,
+		Code:   code,
+		Type:   string(constants.TypeEmailChange),
+	}
+	if err := s.otpRepo.CreateOTP(ctx, otp); err != nil {
+		return err
+	}
+	if err := s.userRepo.SetPendingEmail(ctx, userID, newEmail); err != nil {
+		return err
+	}
+
+	if err := s.emailClient.SendOTP(ctx, newEmail, code); err != nil {
+		logger.Error("failed to send email change code", "error", err, "email", newEmail)
+		return newError(CodeDeliveryFailed, "failed to send confirmation code")
+	}
+	s.audit(ctx, constants.AuditEmailChangeRequested, userID, nil)
+
+	logger.Info("email change requested", "userID", userID)
+	return nil
+}
+
+// ConfirmEmailChange redeems the code sent by ChangeEmail. The pending email
+// becomes the user's (verified) email, every session is revoked so the user
+// signs in again with the new address, and the old address is notified.
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, userID int64, code string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ConfirmEmailChange")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+	pending, err := s.userRepo.FindPendingEmail(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if pending == "" {
+		return ErrInvalidOTP
+	}
+
+	// Repeated wrong codes lock it (*ErrOTPLocked)
+	valid, err := s.verifyOTPCode(ctx, pending, code, constants.TypeEmailChange)
+	var lockedErr *ErrOTPLocked
+	if errors.As(err, &lockedErr) {
+		return lockedErr
+	}
+	if errors.Is(err, ErrLockConflict) {
+		return err
+	}
+	if err != nil || !valid {
+		return ErrInvalidOTP
+	}
+
+	// The address may have been registered since the code was sent
+	if existing, _ := s.userRepo.FindByEmail(ctx, pending); existing != nil {
+		return ErrEmailTaken
+	}
+	changed, err := s.userRepo.ConfirmPendingEmail(ctx, userID, pending)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return ErrInvalidOTP
+	}
+	s.audit(ctx, constants.AuditEmailChanged, userID, nil)
+
+	if err := s.signOutEverywhere(ctx, userID); err != nil {
+		logger.Error("failed to revoke sessions after email change", "error", err, "userID", userID)
+	}
+	s.sendEmailChangedNotice(ctx, user.Email, pending)
+
+	logger.Info("email changed", "userID", userID)
+	return nil
+}
+
+// sendEmailChangedNotice tells the previous address that the account's email
+// changed. Failures are logged only: the email was already changed.
+func (s *AuthService) sendEmailChangedNotice(ctx context.Context, oldEmail, newEmail string) {
+	if err := s.emailClient.Send(ctx,
+		[]string{oldEmail},
+		"Email Address Changed",
+		"<p>The email address of your account was changed to "+html.EscapeString(newEmail)+".</p><p>If you didn't make this change, please contact support immediately.</p>",
+	); err != nil {
+		logger.Warn("failed to send email change notice", "error", err, "email", oldEmail)
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"sync/atomic"
+
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Registration Email Domains
+// ============================================================================
+
+// ErrDomainNotAllowed is returned by Register for emails whose domain is not on
+// the allowlist or is blocked
+var ErrDomainNotAllowed = newError(CodeDomainNotAllowed, "registration is not allowed for this email domain")
+
+// disposableEmailDomains is a short seed list of disposable email providers,
+// blocked on top of the configured blocklist
+//
+//go:embed disposable_email_domains.txt
+var disposableEmailDomains string
+
+// emailDomainPolicy holds the domain lists Register checks. The AuthService
+// only has a pointer to it, so the lists of every copy of the service (the
+// handlers hold one) can be swapped at once by SetEmailDomains, e.g. on SIGHUP.
+type emailDomainPolicy struct {
+	lists atomic.Pointer[emailDomainLists]
+}
+
+// emailDomainLists are the lowercased domains of an emailDomainPolicy
+type emailDomainLists struct {
+	allowed map[string]bool // empty allows every domain not blocked
+	blocked map[string]bool
+}
+
+// newEmailDomainPolicy returns a policy blocking only the disposable seed list
+func newEmailDomainPolicy() *emailDomainPolicy {
+	p := &emailDomainPolicy{}
+	p.set(nil, nil)
+	return p
+}
+
+// set replaces the lists; the seed list is always blocked
+func (p *emailDomainPolicy) set(allowed, blocked []string) {
+	lists := &emailDomainLists{allowed: domainSet(allowed), blocked: domainSet(blocked)}
+	scanner := bufio.NewScanner(strings.NewReader(disposableEmailDomains))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			lists.blocked[strings.ToLower(line)] = true
+		}
+	}
+	p.lists.Store(lists)
+}
+
+// allows reports whether users may register with an address at domain. A list
+// entry covers its subdomains too, so "example.com" allows "eu.example.com".
+// Blocked domains are rejected even when they are allowed.
+func (p *emailDomainPolicy) allows(domain string) bool {
+	lists := p.lists.Load()
+	if matchesDomain(lists.blocked, domain) {
+		return false
+	}
+	return len(lists.allowed) == 0 || matchesDomain(lists.allowed, domain)
+}
+
+// WithEmailDomains restricts registration to emails at the allowed domains,
+// when any are given, and rejects emails at the blocked domains and at the
+// disposable providers of the embedded seed list. See SetEmailDomains.
+func (s *AuthService) WithEmailDomains(allowed, blocked []string) *AuthService {
+	s.emailDomains.set(allowed, blocked)
+	return s
+}
+
+// SetEmailDomains replaces the allowed and blocked email domains of this
+// service and of every copy of it, while it serves requests. Registrations
+// already past the check are not affected.
+func (s *AuthService) SetEmailDomains(allowed, blocked []string) {
+	s.emailDomains.set(allowed, blocked)
+	logger.Info("registration email domains updated", "allowed", len(allowed), "blocked", len(blocked))
+}
+
+// checkEmailDomain returns ErrDomainNotAllowed unless email may be used to register
+func (s *AuthService) checkEmailDomain(email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil // malformed addresses are left to request validation
+	}
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+	if s.emailDomains.allows(domain) {
+		return nil
+	}
+	logger.Debug("registration from disallowed email domain", "domain", domain)
+	return ErrDomainNotAllowed
+}
+
+// matchesDomain reports whether domain or one of its parent domains is in set
+func matchesDomain(set map[string]bool, domain string) bool {
+	for domain != "" {
+		if set[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+	return false
+}
+
+// domainSet returns the lowercased, non-empty domains as a set; a leading "@"
+// is ignored, so "@example.com" works too
+func domainSet(domains []string) map[string]bool {
+	set := make(map[string]bool, len(domains))
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "@")
+		if d != "" {
+			set[d] = true
+		}
+	}
+	return set
+}
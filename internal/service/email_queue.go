@@ -0,0 +1,16 @@
+package service
+
+import (
+	"authentio/pkg/email"
+)
+
+// ============================================================================
+// Asynchronous Email Delivery
+// ============================================================================
+
+// WithEmailQueue makes the service enqueue every email on outbox instead of
+// sending it over SMTP in the request path. A queue worker delivers them.
+func (s *AuthService) WithEmailQueue(outbox email.Outbox) *AuthService {
+	s.emailClient = s.emailClient.Queued(outbox)
+	return s
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"net/mail"
+
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Email Suppression (bounces and unsubscribes)
+// ============================================================================
+
+// ErrEmailSuppressionDisabled is returned by SuppressEmail when no suppression list is configured
+var ErrEmailSuppressionDisabled = newError(CodeServiceUnavailable, "email suppression list is not configured")
+
+// WithEmailSuppression stops all email to addresses on repo's suppression list:
+// the service's email client skips them with a warning instead of sending.
+// SuppressEmail adds addresses, typically from the email provider's webhooks.
+func (s *AuthService) WithEmailSuppression(repo repository.EmailSuppressionRepository) *AuthService {
+	s.emailSuppressions = repo
+	s.emailClient = s.emailClient.Suppressed(repo)
+	return s
+}
+
+// SuppressEmail puts address on the suppression list for reason (one of the
+// constants.Suppression* reasons). Invalid addresses are ignored, since
+// webhook payloads are not under our control; the result reports whether the
+// address was added.
+func (s *AuthService) SuppressEmail(ctx context.Context, address, reason string) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.SuppressEmail")
+	defer span.End()
+
+	if s.emailSuppressions == nil {
+		return false, ErrEmailSuppressionDisabled
+	}
+
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		logger.Warn("ignoring invalid address in email webhook", "error", err)
+		return false, nil
+	}
+
+	if err := s.emailSuppressions.Add(ctx, parsed.Address, reason); err != nil {
+		return false, err
+	}
+	logger.Info("email address suppressed", "email", parsed.Address, "reason", reason)
+	return true, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Email Verification
+// ============================================================================
+
+// emailVerificationKeyPrefix namespaces pending verification nonces in Redis
+const emailVerificationKeyPrefix = "email_verify:"
+
+// consumeNonceScript deletes KEYS[1] only if it still holds ARGV[1], returning 1 on success
+var consumeNonceScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var (
+	// ErrEmailNotVerified is returned by Login when verification is required and still pending
+	ErrEmailNotVerified = newError(CodeEmailNotVerified, "email address not verified")
+
+	// ErrInvalidVerificationToken is returned for tampered, expired, superseded or already-used tokens
+	ErrInvalidVerificationToken = newError(CodeInvalidVerificationToken, "invalid or expired verification token")
+
+	// ErrEmailVerificationDisabled is returned when the verification flow is not configured
+	ErrEmailVerificationDisabled = newError(CodeEmailVerificationDisabled, "email verification is not configured")
+)
+
+// EmailVerificationConfig configures the email verification flow.
+type EmailVerificationConfig struct {
+	// Redis stores the pending token's nonce so each link can only be redeemed once
+	Redis *redis.Client
+
+	// Secret signs verification tokens (HMAC-SHA256)
+	Secret []byte
+
+	// URL is the verification endpoint; the token is appended as the "token" query parameter
+	URL string
+
+	// TTL is how long a verification link stays valid
+	TTL time.Duration
+
+	// Required blocks login until the user has verified their email
+	Required bool
+}
+
+// WithEmailVerification enables the email verification flow.
+func (s *AuthService) WithEmailVerification(cfg EmailVerificationConfig) *AuthService {
+	s.emailVerification = &cfg
+	return s
+}
+
+// SendEmailVerification emails the user a signed, time-limited verification link.
+// Sending a new link invalidates any previous one.
+func (s *AuthService) SendEmailVerification(ctx context.Context, userID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.SendEmailVerification")
+	defer span.End()
+
+	if s.emailVerification == nil {
+		return ErrEmailVerificationDisabled
+	}
+	cfg := s.emailVerification
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	nonce := generateSecureToken()
+	expiresAt := time.Now().Add(cfg.TTL)
+	if err := cfg.Redis.Set(ctx, emailVerificationKeyPrefix+strconv.FormatInt(userID, 10), nonce, cfg.TTL).Err(); err != nil {
+		return internalError("failed to store verification token", err)
+	}
+
+	token := s.signVerificationToken(userID, nonce, expiresAt)
+	link := cfg.URL + "?token=" + url.QueryEscape(token)
+
+	hours := int(cfg.TTL.Hours())
+	if hours < 1 {
+		hours = 1
+	}
+	if err := s.emailClient.SendEmailVerification(ctx, user.Email, link, hours); err != nil {
+		logger.Error("failed to send verification email", "error", err, "email", user.Email)
+		return newError(CodeDeliveryFailed, "failed to send verification email")
+	}
+
+	logger.Info("verification email sent", "userID", userID)
+	return nil
+}
+
+// VerifyEmail redeems a verification token and marks the user's email as verified.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.VerifyEmail")
+	defer span.End()
+
+	if s.emailVerification == nil {
+		return ErrEmailVerificationDisabled
+	}
+
+	userID, nonce, err := s.parseVerificationToken(token)
+	if err != nil {
+		return err
+	}
+
+	// Single use: the nonce must still be the current one, and is consumed atomically
+	key := emailVerificationKeyPrefix + strconv.FormatInt(userID, 10)
+	consumed, err := consumeNonceScript.Run(ctx, s.emailVerification.Redis, []string{key}, nonce).Int()
+	if err != nil {
+		return err
+	}
+	if consumed == 0 {
+		return ErrInvalidVerificationToken
+	}
+
+	if err := s.userRepo.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditEmailVerified, userID, nil)
+
+	logger.Info("email verified", "userID", userID)
+	return nil
+}
+
+// signVerificationToken returns base64url("userID:nonce:expiresAt") + "." + base64url(HMAC).
+func (s *AuthService) signVerificationToken(userID int64, nonce string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d:%s:%d", userID, nonce, expiresAt.Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.verificationMAC(encoded))
+}
+
+// parseVerificationToken checks the signature and expiry and returns the user ID and nonce.
+func (s *AuthService) parseVerificationToken(token string) (int64, string, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	gotMAC, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(gotMAC, s.verificationMAC(encoded)) {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidVerificationToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return 0, "", ErrInvalidVerificationToken
+	}
+
+	return userID, parts[1], nil
+}
+
+func (s *AuthService) verificationMAC(encodedPayload string) []byte {
+	mac := hmac.New(sha256.New, s.emailVerification.Secret)
+	mac.Write([]byte(encodedPayload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"errors"
+
+	"authentio/internal/repository"
+)
+
+// ============================================================================
+// Service Errors
+// ============================================================================
+
+// ErrorCode identifies an error case so clients can handle it without parsing
+// messages. Codes are part of the API: never change or reuse one.
+type ErrorCode string
+
+// Error codes returned by AuthService. The HTTP status each one is served with
+// is set by handler.WriteError.
+const (
+	// Credentials and account state
+	CodeInvalidCredentials ErrorCode = "invalid_credentials" // wrong email or password
+	CodeAccountLocked      ErrorCode = "account_locked"      // too many failed logins; retry later
+	CodeAccountDisabled    ErrorCode = "account_disabled"    // deprovisioned (inactive) account
+	CodeAccountDeactivated ErrorCode = "account_deactivated" // deactivated by an operator
+	CodeEmailNotVerified   ErrorCode = "email_not_verified"  // sign-in requires a verified email
+	CodeIncorrectPassword  ErrorCode = "incorrect_password"  // current password does not match
+	CodePasswordReused     ErrorCode = "password_reused"     // new password was used recently
+	CodePasswordPolicy     ErrorCode = "password_policy"     // new password too weak; details.violations lists why
+
+	// Users
+	CodeUserNotFound      ErrorCode = "user_not_found"
+	CodeEmailTaken        ErrorCode = "email_taken"
+	CodeExportRateLimited ErrorCode = "export_rate_limited" // one data export per day; see Retry-After
+	CodeLockConflict      ErrorCode = "lock_conflict"       // a concurrent request is doing the same; retry
+	CodeDomainNotAllowed  ErrorCode = "domain_not_allowed"  // sign-ups from this email domain are not accepted
+
+	// Tokens, codes and links
+	CodeInvalidRefreshToken      ErrorCode = "invalid_refresh_token"      // unknown, expired or reused refresh token
+	CodeInvalidOTP               ErrorCode = "invalid_otp"                // wrong or expired one-time code
+	CodeOTPLocked                ErrorCode = "otp_locked"                 // too many wrong codes; see Retry-After
+	CodeInvalidTOTPCode          ErrorCode = "invalid_totp_code"          // wrong authenticator-app code
+	CodeTOTPCodeReused           ErrorCode = "totp_code_reused"           // authenticator-app code already used
+	CodeTOTPNotEnrolled          ErrorCode = "totp_not_enrolled"          // no authenticator app set up
+	CodeTOTPLoginNotFound        ErrorCode = "totp_login_not_found"       // two-factor token unknown, used or expired
+	CodeInvalidVerificationToken ErrorCode = "invalid_verification_token" // wrong or expired email verification link
+	CodeInvalidMagicLink         ErrorCode = "invalid_magic_link"         // wrong, used or expired magic link
+	CodeInvalidResetLink         ErrorCode = "invalid_reset_link"         // forged, used or expired password reset link
+	CodeSessionNotFound          ErrorCode = "session_not_found"
+
+	// OAuth and SSO
+	CodeUnknownProvider         ErrorCode = "unknown_provider"          // OAuth provider is not configured
+	CodeInvalidOAuthCallback    ErrorCode = "invalid_oauth_callback"    // missing code or state
+	CodeOAuthExchangeFailed     ErrorCode = "oauth_exchange_failed"     // provider rejected the authorization code
+	CodeInvalidOAuthToken       ErrorCode = "invalid_oauth_token"       // invalid or incomplete provider ID token
+	CodeProviderEmailUnverified ErrorCode = "provider_email_unverified" // provider has not verified the email
+	CodePKCEMismatch            ErrorCode = "pkce_mismatch"             // code_verifier does not match the challenge
+	CodeAccountAlreadyLinked    ErrorCode = "account_already_linked"    // provider account is linked to another user
+	CodeProviderAlreadyLinked   ErrorCode = "provider_already_linked"   // user has another account of the provider linked
+	CodeLastSignInMethod        ErrorCode = "last_sign_in_method"       // unlinking would leave no way to sign in
+	CodeLinkedAccountNotFound   ErrorCode = "linked_account_not_found"
+
+	// OAuth2 authorization server; the RFC 6749 codes double as its "error" field
+	CodeOAuthInvalidRequest          ErrorCode = "invalid_request"           // missing or malformed parameter
+	CodeOAuthInvalidClient           ErrorCode = "invalid_client"            // unknown client or wrong secret
+	CodeOAuthInvalidGrant            ErrorCode = "invalid_grant"             // wrong, used or expired code or refresh token
+	CodeOAuthUnauthorizedClient      ErrorCode = "unauthorized_client"       // client may not use this grant type
+	CodeOAuthUnsupportedGrantType    ErrorCode = "unsupported_grant_type"    // grant type the server does not support
+	CodeOAuthUnsupportedResponseType ErrorCode = "unsupported_response_type" // response type other than code
+	CodeOAuthInvalidScope            ErrorCode = "invalid_scope"             // scope the client is not allowed
+	CodeOAuthAuthorizationPending    ErrorCode = "authorization_pending"     // device grant: the user has not answered yet
+	CodeOAuthSlowDown                ErrorCode = "slow_down"                 // device grant: polled faster than the interval
+	CodeOAuthAccessDenied            ErrorCode = "access_denied"             // device grant: the user denied the request
+	CodeOAuthExpiredToken            ErrorCode = "expired_token"             // device grant: the device code expired
+	CodeInvalidUserCode              ErrorCode = "invalid_user_code"         // unknown, expired or already answered device user code
+
+	// Two-factor delivery
+	CodeNoPhoneNumber          ErrorCode = "no_phone_number"
+	CodeInvalidPhoneNumber     ErrorCode = "invalid_phone_number"     // not in E.164 format
+	CodeInvalidDeliveryChannel ErrorCode = "invalid_delivery_channel" // channel other than email or sms
+
+	// Passkeys
+	CodeWebAuthnSessionNotFound    ErrorCode = "webauthn_session_not_found" // ceremony expired or was not started
+	CodeNoWebAuthnCredentials      ErrorCode = "no_webauthn_credentials"
+	CodeWebAuthnVerificationFailed ErrorCode = "webauthn_verification_failed"
+
+	// Administration
+	CodeCannotImpersonate   ErrorCode = "cannot_impersonate"
+	CodeNotImpersonating    ErrorCode = "not_impersonating"
+	CodeRoleNotFound        ErrorCode = "role_not_found"
+	CodeRoleExists          ErrorCode = "role_exists"
+	CodeInvalidRoleName     ErrorCode = "invalid_role_name"
+	CodeInvalidPermission   ErrorCode = "invalid_permission"
+	CodeInvalidIP           ErrorCode = "invalid_ip"
+	CodeInvalidFlag         ErrorCode = "invalid_feature_flag"
+	CodeWebhookNotFound     ErrorCode = "webhook_not_found"
+	CodeInvalidWebhook      ErrorCode = "invalid_webhook"
+	CodeOAuthClientNotFound ErrorCode = "oauth_client_not_found"
+	CodeInvalidOAuthClient  ErrorCode = "invalid_oauth_client" // client registration with a bad redirect URI or scope
+
+	// Features that are not configured on this server
+	CodeEmailVerificationDisabled ErrorCode = "email_verification_disabled"
+	CodeMagicLinkDisabled         ErrorCode = "magic_link_disabled"
+	CodeWebAuthnDisabled          ErrorCode = "webauthn_disabled"
+	CodeSMSDisabled               ErrorCode = "sms_disabled"
+	CodePKCEDisabled              ErrorCode = "pkce_disabled"
+	CodeRBACDisabled              ErrorCode = "rbac_disabled"
+	CodeIPFilterDisabled          ErrorCode = "ip_filter_disabled"
+	CodeFeatureFlagsDisabled      ErrorCode = "feature_flags_disabled"
+	CodeDataExportDisabled        ErrorCode = "data_export_disabled"
+	CodeWebhooksDisabled          ErrorCode = "webhooks_disabled"
+	CodeOAuthServerDisabled       ErrorCode = "oauth_server_disabled"
+	CodeLinkedAccountsDisabled    ErrorCode = "linked_accounts_disabled"
+
+	// Server-side failures
+	CodeDeliveryFailed     ErrorCode = "delivery_failed"     // email or SMS could not be sent
+	CodeServiceUnavailable ErrorCode = "service_unavailable" // a dependency is not configured or reachable
+	CodeInternal           ErrorCode = "internal_error"      // anything else; the message is not shown to clients
+)
+
+// Errors of the core login, token and OAuth flows. Feature-specific errors are
+// declared next to the feature, e.g. ErrMagicLinkDisabled in magic_link.go.
+var (
+	// ErrInvalidCredentials is returned by Login for an unknown email or a wrong
+	// password, without saying which
+	ErrInvalidCredentials = newError(CodeInvalidCredentials, "invalid email or password")
+
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = newError(CodeInvalidRefreshToken, "invalid refresh token")
+
+	// ErrRefreshTokenReused is returned when an already-rotated refresh token is
+	// presented again; every token of its login has been revoked
+	ErrRefreshTokenReused = newError(CodeInvalidRefreshToken, "refresh token already used").wrap(repository.ErrRefreshTokenReused)
+
+	// ErrInvalidOTP is returned for wrong or expired one-time codes
+	ErrInvalidOTP = newError(CodeInvalidOTP, "invalid or expired code")
+
+	// ErrInvalidOAuthCallback is returned when an OAuth callback lacks its code or state
+	ErrInvalidOAuthCallback = newError(CodeInvalidOAuthCallback, "missing code or state")
+
+	// ErrOAuthExchangeFailed is returned when the provider rejects the authorization code
+	ErrOAuthExchangeFailed = newError(CodeOAuthExchangeFailed, "failed to exchange code")
+
+	// ErrOAuthProviderUnavailable is returned while the provider's circuit breaker is open
+	ErrOAuthProviderUnavailable = newError(CodeServiceUnavailable, "oauth provider temporarily unavailable")
+
+	// ErrInvalidOAuthToken is returned for provider ID tokens that fail validation
+	ErrInvalidOAuthToken = newError(CodeInvalidOAuthToken, "invalid Google token")
+
+	// ErrProviderEmailUnverified is returned when an OAuth identity would be linked
+	// to an existing account through an email the provider has not verified
+	ErrProviderEmailUnverified = newError(CodeProviderEmailUnverified, "email not verified by provider")
+)
+
+// AuthError is an error with a stable code. Sentinels such as ErrUserNotFound are
+// AuthErrors, and errors.Is matches any AuthError with the same code, so a
+// sentinel still matches after WithDetails or with a more specific message.
+type AuthError struct {
+	Code    ErrorCode
+	Message string
+
+	// Details carries machine-readable context, e.g. the violated password rules
+	Details map[string]any
+
+	// cause is the underlying error; it is logged but never shown to clients
+	cause error
+}
+
+// newError creates an AuthError.
+func newError(code ErrorCode, message string) *AuthError {
+	return &AuthError{Code: code, Message: message}
+}
+
+// internalError wraps an unexpected failure; message says what was being done.
+func internalError(message string, cause error) *AuthError {
+	return &AuthError{Code: CodeInternal, Message: message, cause: cause}
+}
+
+func (e *AuthError) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *AuthError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether target is an AuthError with the same code.
+func (e *AuthError) Is(target error) bool {
+	t, ok := target.(*AuthError)
+	return ok && t.Code == e.Code
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *AuthError) WithDetails(details map[string]any) *AuthError {
+	dup := *e
+	dup.Details = details
+	return &dup
+}
+
+// wrap returns a copy of e caused by err.
+func (e *AuthError) wrap(err error) *AuthError {
+	dup := *e
+	dup.cause = err
+	return &dup
+}
+
+// Code returns the code of err, or CodeInternal if it is not an AuthError.
+func Code(err error) ErrorCode {
+	var authErr *AuthError
+	if errors.As(err, &authErr) {
+		return authErr.Code
+	}
+	return CodeInternal
+}
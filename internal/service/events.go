@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/repository"
+	"authentio/pkg/events"
+)
+
+// ============================================================================
+// Event Publishing
+// ============================================================================
+
+// WithEventPublisher publishes registrations, logins, password changes and 2FA
+// enrollments to publisher, for other services to react to.
+func (s *AuthService) WithEventPublisher(publisher events.Publisher) *AuthService {
+	s.events = publisher
+	return s
+}
+
+// publish sends an event for userID with the tenant of the request. Publishers
+// are fire-and-forget, so this never blocks or fails the operation.
+func (s *AuthService) publish(ctx context.Context, eventType events.Type, userID int64, data map[string]any) {
+	event := events.Event{
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+	if tenantID, ok := repository.TenantIDFromContext(ctx); ok {
+		event.TenantID = &tenantID
+	}
+	s.events.Publish(context.WithoutCancel(ctx), event)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// External Identity Provider Tokens
+// ============================================================================
+
+// ErrInvalidExternalToken is returned for third-party tokens that fail
+// verification or do not identify a user by email
+var ErrInvalidExternalToken = newError(CodeInvalidOAuthToken, "invalid external token")
+
+// WithExternalTokens accepts access tokens issued by an external identity
+// provider (Cognito, Auth0, ...) and verified by verifier, in addition to the
+// tokens this service issues. See AuthenticateExternalToken.
+func (s *AuthService) WithExternalTokens(verifier *jwt.JWKSVerifier) *AuthService {
+	s.externalTokens = verifier
+	return s
+}
+
+// AuthenticateExternalToken verifies a token issued by the external identity
+// provider and maps it to the local account with the same email, which the
+// provider must have verified (email_verified claim). The returned claims have
+// the shape of this service's access tokens, with the account's role and
+// permissions, so the token can be used wherever a local one is; they carry no
+// session_id. The token's own claims are kept under "external_claims".
+func (s *AuthService) AuthenticateExternalToken(ctx context.Context, token string) (jwt.Claims, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.AuthenticateExternalToken")
+	defer span.End()
+
+	if s.externalTokens == nil {
+		return nil, ErrInvalidExternalToken
+	}
+
+	claims, err := s.externalTokens.Verify(token)
+	if err != nil {
+		logger.Debug("external token rejected", "error", err)
+		return nil, ErrInvalidExternalToken
+	}
+	external := *claims
+
+	email, _ := external["email"].(string)
+	if email == "" {
+		return nil, ErrInvalidExternalToken
+	}
+	if !emailVerified(external["email_verified"]) {
+		return nil, ErrProviderEmailUnverified
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, internalError("failed to look up user", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.userPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	local := jwt.Claims{
+		"user_id":         float64(user.ID),
+		"email":           user.Email,
+		"first_name":      user.FirstName,
+		"last_name":       user.LastName,
+		"name":            user.FirstName + " " + user.LastName,
+		"role":            string(user.Role),
+		"external_claims": external,
+	}
+	if jti, ok := external["jti"].(string); ok {
+		local["jti"] = jti
+	}
+	if user.TenantID != nil {
+		local["tenant_id"] = float64(*user.TenantID)
+	}
+	if len(permissions) > 0 {
+		list := make([]interface{}, len(permissions))
+		for i, p := range permissions {
+			list[i] = p
+		}
+		local["permissions"] = list
+	}
+	return local, nil
+}
+
+// emailVerified reads an email_verified claim, which Cognito sends as the
+// string "true" and most other providers as a boolean.
+func emailVerified(claim any) bool {
+	switch v := claim.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/flags"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Feature Flags
+// ============================================================================
+
+var (
+	// ErrFeatureFlagsDisabled is returned when no flag store is configured
+	ErrFeatureFlagsDisabled = newError(CodeFeatureFlagsDisabled, "feature flags are not enabled")
+
+	// ErrInvalidFlag is returned for malformed flag names
+	ErrInvalidFlag = newError(CodeInvalidFlag, "flag names are 1-64 lowercase letters, digits, '.', '_' or '-'")
+)
+
+// WithFeatureFlags enables per-user feature flags. The store's ClaimTransformer
+// must be registered on the JWT manager separately for flags to reach tokens.
+func (s *AuthService) WithFeatureFlags(store *flags.Store) *AuthService {
+	s.flags = store
+	return s
+}
+
+// SetUserFlag enables or disables a feature flag for a user, for ttl or, when
+// ttl is zero, until it is set again. Tokens issued before the change keep the
+// flags they were issued with.
+func (s *AuthService) SetUserFlag(ctx context.Context, userID int64, flag string, enabled bool, ttl time.Duration) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.SetUserFlag")
+	defer span.End()
+
+	if s.flags == nil {
+		return ErrFeatureFlagsDisabled
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.flags.Set(ctx, strconv.FormatInt(userID, 10), flag, enabled, ttl); err != nil {
+		if errors.Is(err, flags.ErrInvalidFlag) {
+			return ErrInvalidFlag
+		}
+		return internalError("failed to set feature flag", err)
+	}
+	s.audit(ctx, constants.AuditFeatureFlagSet, userID, map[string]any{"flag": flag, "enabled": enabled, "ttl": ttl.String()})
+
+	logger.Info("feature flag set", "userID", userID, "flag", flag, "enabled", enabled)
+	return nil
+}
+
+// ListUserFlags returns every feature flag set for a user, enabled or not.
+func (s *AuthService) ListUserFlags(ctx context.Context, userID int64) (map[string]bool, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListUserFlags")
+	defer span.End()
+
+	if s.flags == nil {
+		return nil, ErrFeatureFlagsDisabled
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	userFlags, err := s.flags.List(ctx, strconv.FormatInt(userID, 10))
+	if err != nil {
+		return nil, internalError("failed to list feature flags", err)
+	}
+	return userFlags, nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/pkg/geoip"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Login Geolocation
+// ============================================================================
+
+// loginCountryWindow is how long a country stays known for a user: a login
+// from a country not seen within it triggers a "Was this you?" email
+const loginCountryWindow = 30 * 24 * time.Hour
+
+// WithGeoIP records the country and city of every login attempt in the audit
+// log and emails users whose login comes from a country they have not logged
+// in from for 30 days. Requires the audit log (see WithAuditLog).
+func (s *AuthService) WithGeoIP(locator *geoip.Locator) *AuthService {
+	s.geoIP = locator
+	return s
+}
+
+// locateLogin fills in the location of a login attempt's client IP. For
+// successful logins it first checks the user's recent login countries, so it
+// must run before the entry is written.
+func (s *AuthService) locateLogin(ctx context.Context, entry *models.AuditEntry, successful bool) {
+	loc, ok := s.geoIP.Lookup(entry.IP)
+	if !ok {
+		return
+	}
+	entry.LoginCountry, entry.LoginCity = loc.Country, loc.City
+
+	if !successful || entry.UserID == nil {
+		return
+	}
+	userID := *entry.UserID
+	countries, err := s.auditRepo.LoginCountries(ctx, userID, time.Now().Add(-loginCountryWindow))
+	if err != nil {
+		logger.Warn("failed to check login countries", "error", err, "userID", userID)
+		return
+	}
+
+	// Users without located logins in the window have nothing to compare against
+	if len(countries) > 0 && !slices.Contains(countries, loc.Country) {
+		logger.Info("login from new country", "userID", userID, "country", loc.Country)
+		s.goBackground(func() { s.sendNewCountryEmail(context.WithoutCancel(ctx), userID, loc, entry.IP) })
+	}
+}
+
+// sendNewCountryEmail asks the user to confirm a login from a new country.
+// This method runs asynchronously and logs errors without failing the main operation.
+func (s *AuthService) sendNewCountryEmail(ctx context.Context, userID int64, loc geoip.Location, ip string) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return
+	}
+
+	where := loc.Country
+	if loc.City != "" {
+		where = loc.City + ", " + loc.Country
+	}
+
+	subject := "New sign-in to your Authentio account - was this you?"
+	body := fmt.Sprintf(`
+		<div style="font-family: Arial, sans-serif; max-width: 600px; margin: 0 auto;">
+			<h2 style="color: #2563eb;">Authentio</h2>
+			<p>Hi %s,</p>
+			<p>Your account was just signed in to from <strong>%s</strong> (IP address %s), a country you haven't signed in from recently.</p>
+			<p><strong>Was this you?</strong> If so, you can ignore this email. If it wasn't, reset your password right away and sign out of all sessions.</p>
+		</div>
+	`, html.EscapeString(user.FirstName), html.EscapeString(where), html.EscapeString(ip))
+
+	if err := s.emailClient.Send(ctx, []string{user.Email}, subject, body); err != nil {
+		logger.Error("failed to send new country login email", "error", err, "userID", userID)
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Impersonation (support staff acting as a user)
+// ============================================================================
+
+// DefaultImpersonationTTL is the lifetime of impersonation tokens unless
+// WithImpersonationTTL sets another
+const DefaultImpersonationTTL = time.Hour
+
+var (
+	// ErrCannotImpersonate is returned when the target is the admin themself or may impersonate too
+	ErrCannotImpersonate = newError(CodeCannotImpersonate, "this user cannot be impersonated")
+
+	// ErrNotImpersonating is returned by EndImpersonation for regular access tokens
+	ErrNotImpersonating = newError(CodeNotImpersonating, "token is not an impersonation token")
+)
+
+// WithImpersonationTTL sets the lifetime of impersonation tokens.
+func (s *AuthService) WithImpersonationTTL(ttl time.Duration) *AuthService {
+	s.impersonationTTL = ttl
+	return s
+}
+
+// ImpersonateUser issues adminUserID an access token for targetUserID, carrying an
+// "impersonated_by" claim with the admin's ID. The token is short-lived and comes
+// without a refresh token, so impersonation ends at the latest when it expires.
+//
+// The token is bound to a session of its own, which the target user sees in their
+// session list and can revoke. Admins and other users allowed to impersonate
+// cannot be impersonated, so impersonation cannot be chained to gain more access.
+func (s *AuthService) ImpersonateUser(ctx context.Context, adminUserID, targetUserID int64) (*models.TokenPair, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ImpersonateUser")
+	defer span.End()
+
+	if adminUserID == targetUserID {
+		return nil, ErrCannotImpersonate
+	}
+
+	target, err := s.GetUser(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountActive(target); err != nil {
+		return nil, err
+	}
+	if target.Role == constants.RoleAdmin {
+		return nil, ErrCannotImpersonate
+	}
+	if canImpersonate, err := s.HasPermission(ctx, target.ID, constants.PermissionImpersonateUsers); err != nil {
+		return nil, err
+	} else if canImpersonate {
+		return nil, ErrCannotImpersonate
+	}
+
+	// A session without a refresh token: only the access token below can use it
+	sessionID := generateSecureToken()
+	client := models.ClientInfoFromContext(ctx)
+	if err := s.tokenRepo.SaveSession(ctx, &models.Session{
+		ID:               sessionID,
+		UserID:           target.ID,
+		RefreshTokenHash: hashRefreshToken(generateSecureToken()),
+		UserAgent:        client.UserAgent,
+		IP:               client.IP,
+	}); err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.userPermissions(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.jwtManager.GenerateTokenWithClaims(jwt.UserClaims{
+		UserID:         target.ID,
+		Email:          target.Email,
+		FirstName:      target.FirstName,
+		LastName:       target.LastName,
+		SessionID:      sessionID,
+		TenantID:       target.TenantID,
+		Role:           string(target.Role),
+		ImpersonatedBy: &adminUserID,
+		Permissions:    permissions,
+		TTL:            s.impersonationTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, constants.AuditImpersonationStarted, target.ID, map[string]any{
+		"admin_id":   adminUserID,
+		"session_id": sessionID,
+		"expires_in": int(s.impersonationTTL.Seconds()),
+	})
+	logger.Warn("impersonation started", "adminID", adminUserID, "userID", target.ID)
+
+	return &models.TokenPair{
+		AccessToken: accessToken,
+		ExpiresIn:   int(s.impersonationTTL.Seconds()),
+	}, nil
+}
+
+// EndImpersonation ends an impersonation before its token expires: the token is
+// revoked and its session closed. userID is the impersonated user, adminUserID
+// the "impersonated_by" claim of the token (0 if absent), and jti/sessionID
+// identify the token.
+func (s *AuthService) EndImpersonation(ctx context.Context, userID, adminUserID int64, jti, sessionID string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.EndImpersonation")
+	defer span.End()
+
+	if adminUserID == 0 {
+		return ErrNotImpersonating
+	}
+
+	// The token cannot outlive the impersonation TTL, so that bounds its revocation
+	if err := s.jwtManager.RevokeToken(ctx, jti, s.impersonationTTL); err != nil {
+		logger.Warn("failed to revoke impersonation token", "error", err, "userID", userID)
+	}
+	if err := s.tokenRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.evictSessions(ctx, sessionID)
+
+	s.audit(ctx, constants.AuditImpersonationEnded, userID, map[string]any{
+		"admin_id":   adminUserID,
+		"session_id": sessionID,
+	})
+	logger.Info("impersonation ended", "adminID", adminUserID, "userID", userID)
+	return nil
+}
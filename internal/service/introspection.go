@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"authentio/internal/repository"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Token Introspection (RFC 7662)
+// ============================================================================
+
+// Token type hints accepted by IntrospectToken
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// TokenIntrospection is the RFC 7662 description of a token. Only Active is set
+// for tokens that are unknown, expired or revoked, so callers learn nothing else
+// about them.
+type TokenIntrospection struct {
+	Active bool `json:"active" example:"true"`
+
+	// Scope is the space-separated "scope" claim, or the token's RBAC permissions
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+
+	// Username is the user's email address
+	Username  string `json:"username,omitempty" example:"jane.doe@example.com"`
+	TokenType string `json:"token_type,omitempty" example:"access_token"`
+
+	Exp int64  `json:"exp,omitempty" example:"1735689600"`
+	Iat int64  `json:"iat,omitempty" example:"1735686000"`
+	Sub string `json:"sub,omitempty" example:"42"`
+	Jti string `json:"jti,omitempty" example:"3f2a9c1e7b8d4f60a1b2c3d4e5f60718"`
+}
+
+// inactiveToken is the whole answer for a token that is not active
+var inactiveToken = &TokenIntrospection{Active: false}
+
+// IntrospectToken reports whether token is an active access or refresh token and,
+// if so, who it belongs to. hint (TokenTypeAccess or TokenTypeRefresh) is the
+// type to try first; the other type is tried when it does not match, as RFC 7662
+// section 2.1 requires. An error is returned only when token status cannot be
+// determined.
+func (s *AuthService) IntrospectToken(ctx context.Context, token, hint string) (*TokenIntrospection, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.IntrospectToken")
+	defer span.End()
+
+	lookups := []func(context.Context, string) (*TokenIntrospection, error){s.introspectAccessToken, s.introspectRefreshToken}
+	if hint == TokenTypeRefresh {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		result, err := lookup(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if result.Active {
+			return result, nil
+		}
+	}
+	return inactiveToken, nil
+}
+
+// introspectAccessToken checks token as a JWT access token, applying the same
+// revocation, tenant and session checks as the auth middleware.
+func (s *AuthService) introspectAccessToken(ctx context.Context, token string) (*TokenIntrospection, error) {
+	claims, err := s.jwtManager.VerifyToken(token)
+	if err != nil {
+		return inactiveToken, nil
+	}
+
+	jti, _ := claims["jti"].(string)
+	revoked, err := s.jwtManager.IsRevoked(ctx, jti)
+	if err == nil && !revoked {
+		revoked, err = s.jwtManager.IssuedBeforeRevocation(ctx, jwt.Claims(claims))
+	}
+	if err != nil {
+		if s.jwtManager.StrictRevocation() {
+			return nil, newError(CodeServiceUnavailable, "unable to verify token status").wrap(err)
+		}
+		logger.Warn("token revocation check failed, assuming not revoked", "error", err)
+	} else if revoked {
+		return inactiveToken, nil
+	}
+
+	// A tenant-scoped request only learns about tokens of its own tenant
+	tokenTenant, hasTenant := claims["tenant_id"].(float64)
+	if requestTenant, scoped := repository.TenantIDFromContext(ctx); scoped && (!hasTenant || int64(tokenTenant) != requestTenant) {
+		return inactiveToken, nil
+	}
+
+	if sessionID, _ := claims["session_id"].(string); sessionID != "" {
+		active, err := s.IsSessionActive(ctx, sessionID)
+		if err != nil {
+			return nil, internalError("failed to check session", err)
+		}
+		if !active {
+			return inactiveToken, nil
+		}
+	}
+
+	// The subject is the user, or the client itself for client credentials tokens
+	var sub string
+	if userID, ok := claims["user_id"].(float64); ok {
+		sub = strconv.FormatInt(int64(userID), 10)
+	} else if clientID, ok := claims["client_id"].(string); ok && clientID != "" {
+		sub = clientID
+	} else {
+		return inactiveToken, nil
+	}
+
+	result := &TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeAccess,
+		Sub:       sub,
+		Jti:       jti,
+	}
+	result.Username, _ = claims["email"].(string)
+	result.ClientID, _ = claims["client_id"].(string)
+	if exp, ok := claims["exp"].(float64); ok {
+		result.Exp = int64(exp)
+	}
+	if iat, ok := claims["iat"].(float64); ok {
+		result.Iat = int64(iat)
+	}
+
+	// Scope: an explicit claim from a ClaimTransformer, else the RBAC permissions
+	if scope, ok := claims["scope"].(string); ok {
+		result.Scope = scope
+	} else if list, ok := claims["permissions"].([]interface{}); ok {
+		permissions := make([]string, 0, len(list))
+		for _, p := range list {
+			if name, ok := p.(string); ok {
+				permissions = append(permissions, name)
+			}
+		}
+		result.Scope = strings.Join(permissions, " ")
+	}
+	return result, nil
+}
+
+// introspectRefreshToken checks token as a refresh token. Used (rotated) refresh
+// tokens are inactive.
+func (s *AuthService) introspectRefreshToken(ctx context.Context, token string) (*TokenIntrospection, error) {
+	refreshToken, err := s.tokenRepo.GetRefreshToken(ctx, token)
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return inactiveToken, nil
+	}
+	if err != nil {
+		return nil, internalError("failed to look up refresh token", err)
+	}
+
+	result := &TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeRefresh,
+		Sub:       strconv.FormatInt(refreshToken.UserID, 10),
+		Iat:       refreshToken.CreatedAt.Unix(),
+	}
+	if refreshToken.ExpiredAt != nil {
+		result.Exp = refreshToken.ExpiredAt.Unix()
+	}
+	return result, nil
+}
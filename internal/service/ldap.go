@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/events"
+	"authentio/pkg/ldap"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// LDAP / Active Directory Authentication
+// ============================================================================
+
+// ldapProvider is the users.provider value of directory accounts
+const ldapProvider = "ldap"
+
+// WithLDAP makes Login check credentials against an LDAP directory before local
+// passwords. Directory users are created on their first login and their name,
+// email and groups are refreshed on every login.
+func (s *AuthService) WithLDAP(client *ldap.Client) *AuthService {
+	s.ldap = client
+	return s
+}
+
+// authenticateLDAP checks the credentials against the directory and returns the
+// local user for the directory account, creating or updating it as needed.
+//
+// It returns nil without an error whenever the directory does not vouch for the
+// user (LDAP disabled, unknown user, wrong password, or directory unreachable),
+// so that Login falls back to the local password.
+func (s *AuthService) authenticateLDAP(ctx context.Context, username, plainPassword string) (*models.User, error) {
+	if s.ldap == nil {
+		return nil, nil
+	}
+
+	dirUser, err := s.ldap.Authenticate(username, plainPassword)
+	if errors.Is(err, ldap.ErrInvalidCredentials) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Warn("LDAP authentication unavailable, falling back to local password", "error", err)
+		return nil, nil
+	}
+	if dirUser.Email == "" {
+		dirUser.Email = username
+	}
+
+	user, err := s.upsertLDAPUser(ctx, dirUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncLDAPGroups(ctx, user.ID, dirUser.Groups); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// upsertLDAPUser returns the local user linked to the directory account, copying
+// the directory's display name and email onto it. Directory emails count as
+// verified; an existing local account with the same email is linked.
+func (s *AuthService) upsertLDAPUser(ctx context.Context, dirUser *ldap.LDAPUser) (*models.User, error) {
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(dirUser.DisplayName), " ")
+	lastName = strings.TrimSpace(lastName)
+
+	// Returning user: matched on the DN, or on the email after a rename or move
+	user, err := s.userRepo.FindByProvider(ctx, ldapProvider, dirUser.DN)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if user, err = s.userRepo.FindByEmail(ctx, dirUser.Email); err != nil {
+			return nil, err
+		}
+		if user != nil {
+			if err := s.userRepo.LinkProvider(ctx, user.ID, ldapProvider, dirUser.DN, user.AvatarURL); err != nil {
+				return nil, err
+			}
+			user.Provider = ldapProvider
+			user.ProviderID = dirUser.DN
+		}
+	}
+
+	if user != nil {
+		if firstName == "" {
+			firstName, lastName = user.FirstName, user.LastName
+		}
+		if user.FirstName == firstName && user.LastName == lastName && strings.EqualFold(user.Email, dirUser.Email) {
+			return user, nil
+		}
+		user.FirstName = firstName
+		user.LastName = lastName
+		user.Email = dirUser.Email
+		user.UpdatedAt = time.Now()
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	// First login: provision the directory user
+	now := time.Now()
+	user = &models.User{
+		Email:           dirUser.Email,
+		FirstName:       firstName,
+		LastName:        lastName,
+		IsActive:        true,
+		Role:            constants.RoleUser,
+		Provider:        ldapProvider,
+		ProviderID:      dirUser.DN,
+		EmailVerifiedAt: &now,
+		BaseModel: models.BaseModel{
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, constants.AuditRegister, user.ID, map[string]any{"provider": ldapProvider})
+	s.publish(ctx, events.UserRegistered, user.ID, map[string]any{"provider": ldapProvider})
+	logger.Info("LDAP user provisioned", "userID", user.ID, "dn", dirUser.DN)
+	return user, nil
+}
+
+// syncLDAPGroups stores the user's directory groups. With RBAC enabled, roles
+// named after a group are assigned when the user joins the group and removed
+// when they leave it; other roles are left alone.
+func (s *AuthService) syncLDAPGroups(ctx context.Context, userID int64, groups []string) error {
+	previous, err := s.userRepo.ReplaceExternalGroups(ctx, userID, groups)
+	if err != nil {
+		return err
+	}
+	if s.roleRepo == nil {
+		return nil
+	}
+
+	for _, group := range groups {
+		if slices.Contains(previous, group) {
+			continue
+		}
+		role, err := s.roleRepo.FindByName(ctx, group)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			continue
+		}
+		if err := s.roleRepo.AssignToUser(ctx, userID, role.ID); err != nil {
+			return err
+		}
+		s.audit(ctx, constants.AuditRoleAssigned, userID, map[string]any{"role_id": role.ID, "source": ldapProvider})
+	}
+
+	for _, group := range previous {
+		if slices.Contains(groups, group) {
+			continue
+		}
+		role, err := s.roleRepo.FindByName(ctx, group)
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			continue
+		}
+		err = s.roleRepo.RemoveFromUser(ctx, userID, role.ID)
+		if errors.Is(err, repository.ErrRoleNotFound) {
+			continue // already removed by an operator
+		}
+		if err != nil {
+			return err
+		}
+		s.audit(ctx, constants.AuditRoleRemoved, userID, map[string]any{"role_id": role.ID, "source": ldapProvider})
+	}
+	return nil
+}
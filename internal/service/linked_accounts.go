@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+	"authentio/pkg/oauth"
+)
+
+// ============================================================================
+// Linked Social Accounts
+// ============================================================================
+
+var (
+	// ErrLinkedAccountsDisabled is returned when no OAuth identity repository is configured
+	ErrLinkedAccountsDisabled = newError(CodeLinkedAccountsDisabled, "linked accounts are not enabled")
+
+	// ErrAccountAlreadyLinked is returned when the provider account signs in
+	// another user
+	ErrAccountAlreadyLinked = newError(CodeAccountAlreadyLinked, "this provider account is linked to another user")
+
+	// ErrProviderAlreadyLinked is returned when the user already has a different
+	// account of the provider linked; unlink it first
+	ErrProviderAlreadyLinked = newError(CodeProviderAlreadyLinked, "another account of this provider is already linked")
+
+	// ErrLastSignInMethod is returned when unlinking would leave a user without
+	// a password or linked account to sign in with
+	ErrLastSignInMethod = newError(CodeLastSignInMethod, "cannot unlink the only way to sign in; set a password first")
+)
+
+// WithOAuthIdentities lets users link several social provider accounts. OAuth
+// logins then look up the linking identity first, and record one when they
+// link or create a user. Without it a user has the single provider stored on
+// the user itself.
+func (s *AuthService) WithOAuthIdentities(repo repository.OAuthIdentityRepository) *AuthService {
+	s.oauthIdentities = repo
+	return s
+}
+
+// ListLinkedAccounts returns the provider accounts linked to a user, ordered by provider.
+func (s *AuthService) ListLinkedAccounts(ctx context.Context, userID int64) ([]models.OAuthIdentity, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListLinkedAccounts")
+	defer span.End()
+
+	if s.oauthIdentities == nil {
+		return nil, ErrLinkedAccountsDisabled
+	}
+	return s.oauthIdentities.ListByUser(ctx, userID)
+}
+
+// LinkOAuthIdentity links the provider account of an OAuth callback to a
+// signed-in user, who can then sign in with it too. The flow must have been
+// started with StartOAuthAuthorization: the PKCE verifier binds the callback to
+// the request that started it, since there is no state cookie to check.
+//
+// A provider account links to one user only, and a user links one account per
+// provider. Linking the same account again returns the existing identity.
+// Unlike OAuth login, linking does not require the provider to have verified
+// the email, since the user proves they own both accounts.
+func (s *AuthService) LinkOAuthIdentity(ctx context.Context, userID int64, req models.OAuthCallbackRequest) (*models.OAuthIdentity, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.LinkOAuthIdentity")
+	defer span.End()
+
+	if s.oauthIdentities == nil {
+		return nil, ErrLinkedAccountsDisabled
+	}
+	if s.pkceStore == nil {
+		return nil, ErrPKCEDisabled
+	}
+	if req.CodeVerifier == "" {
+		return nil, ErrPKCEMismatch
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	identity, err := s.exchangeOAuthCode(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize links of the same provider account, so two users cannot claim it at once
+	unlock, err := s.acquireLock(ctx, "lock:oauth_identity:"+identity.Provider+":"+identity.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	owner, err := s.findOAuthUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	if owner != nil && owner.ID != userID {
+		return nil, ErrAccountAlreadyLinked
+	}
+
+	linked, err := s.oauthIdentities.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range linked {
+		if linked[i].Provider != identity.Provider {
+			continue
+		}
+		if linked[i].ProviderUserID == identity.ProviderID {
+			return &linked[i], nil
+		}
+		return nil, ErrProviderAlreadyLinked
+	}
+
+	linkedIdentity := newOAuthIdentity(userID, identity)
+	if err := s.oauthIdentities.Create(ctx, linkedIdentity); err != nil {
+		return nil, internalError("failed to link account", err)
+	}
+
+	s.audit(ctx, constants.AuditAccountLinked, userID, map[string]any{"provider": identity.Provider})
+	logger.Info("oauth account linked", "provider", identity.Provider, "userID", userID)
+	return linkedIdentity, nil
+}
+
+// UnlinkOAuthIdentity removes the linked account of provider from a user. The
+// last linked account of a user without a password cannot be removed, since
+// nothing else would sign them in. Returns repository.ErrOAuthIdentityNotFound
+// when no account of provider is linked.
+func (s *AuthService) UnlinkOAuthIdentity(ctx context.Context, userID int64, provider string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.UnlinkOAuthIdentity")
+	defer span.End()
+
+	if s.oauthIdentities == nil {
+		return ErrLinkedAccountsDisabled
+	}
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	linked, err := s.oauthIdentities.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var target *models.OAuthIdentity
+	var remaining []models.OAuthIdentity
+	for i := range linked {
+		if linked[i].Provider == provider {
+			target = &linked[i]
+		} else {
+			remaining = append(remaining, linked[i])
+		}
+	}
+	if target == nil {
+		return repository.ErrOAuthIdentityNotFound
+	}
+	if user.Password == "" && len(remaining) == 0 {
+		return ErrLastSignInMethod
+	}
+
+	if err := s.oauthIdentities.Delete(ctx, userID, provider); err != nil {
+		return err
+	}
+
+	// The provider stored on the user would otherwise keep signing them in
+	legacy, err := s.userRepo.FindByProvider(ctx, provider, target.ProviderUserID)
+	if err != nil {
+		return err
+	}
+	if legacy != nil && legacy.ID == userID {
+		next, nextID := "email", ""
+		if len(remaining) > 0 {
+			next, nextID = remaining[0].Provider, remaining[0].ProviderUserID
+		}
+		if err := s.userRepo.LinkProvider(ctx, userID, next, nextID, ""); err != nil {
+			return err
+		}
+	}
+
+	s.audit(ctx, constants.AuditAccountUnlinked, userID, map[string]any{"provider": provider})
+	logger.Info("oauth account unlinked", "provider", provider, "userID", userID)
+	return nil
+}
+
+// findOAuthUser returns the user a provider account signs in, or nil: the user
+// of its linked identity, else the user whose own provider it is.
+func (s *AuthService) findOAuthUser(ctx context.Context, identity *oauth.Identity) (*models.User, error) {
+	if s.oauthIdentities != nil {
+		linked, err := s.oauthIdentities.FindByProviderUserID(ctx, identity.Provider, identity.ProviderID)
+		if err != nil && !errors.Is(err, repository.ErrOAuthIdentityNotFound) {
+			return nil, err
+		}
+		if linked != nil {
+			user, err := s.userRepo.FindByID(ctx, linked.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if user != nil {
+				return user, nil
+			}
+		}
+	}
+	return s.userRepo.FindByProvider(ctx, identity.Provider, identity.ProviderID)
+}
+
+// recordOAuthIdentity stores the identity an OAuth login linked or created a
+// user with. Failures are only logged: the provider stored on the user still
+// signs them in.
+func (s *AuthService) recordOAuthIdentity(ctx context.Context, userID int64, identity *oauth.Identity) {
+	if s.oauthIdentities == nil {
+		return
+	}
+	if err := s.oauthIdentities.Create(ctx, newOAuthIdentity(userID, identity)); err != nil {
+		logger.Warn("failed to record oauth identity", "error", err, "provider", identity.Provider, "userID", userID)
+	}
+}
+
+// newOAuthIdentity returns the linked identity of a provider account for userID
+func newOAuthIdentity(userID int64, identity *oauth.Identity) *models.OAuthIdentity {
+	return &models.OAuthIdentity{
+		UserID:         userID,
+		Provider:       identity.Provider,
+		ProviderUserID: identity.ProviderID,
+		Email:          identity.Email,
+		AccessToken:    identity.AccessToken,
+		RefreshToken:   identity.RefreshToken,
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"authentio/pkg/lock"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Distributed Locks
+// ============================================================================
+
+// operationLockTTL bounds how long a crashed request can hold a lock
+const operationLockTTL = 10 * time.Second
+
+// ErrLockConflict is returned when a concurrent request is already registering
+// the same email or redeeming the same one-time code
+var ErrLockConflict = newError(CodeLockConflict, "another request is already in progress, try again").wrap(lock.ErrLockConflict)
+
+// WithLocker serializes registrations of the same email and verifications of
+// the same one-time code across every instance of the service.
+func (s *AuthService) WithLocker(locker *lock.Locker) *AuthService {
+	s.locker = locker
+	return s
+}
+
+// registerLockKey is the lock held while registering email
+func registerLockKey(email string) string {
+	return "lock:register:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// acquireLock takes the lock key. It returns ErrLockConflict while another
+// request holds it. Without a Locker, or when Redis is unreachable, it returns
+// a no-op unlock so the operation goes ahead unguarded: the database still
+// rejects duplicate users and used codes.
+func (s *AuthService) acquireLock(ctx context.Context, key string) (unlock func(), err error) {
+	if s.locker == nil {
+		return func() {}, nil
+	}
+
+	unlock, err = s.locker.Acquire(ctx, key, operationLockTTL)
+	if errors.Is(err, lock.ErrLockConflict) {
+		return nil, ErrLockConflict
+	}
+	if err != nil {
+		logger.Warn("failed to acquire lock, continuing without it", "error", err, "key", key)
+		return func() {}, nil
+	}
+	return unlock, nil
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Magic-Link (Passwordless) Login
+// ============================================================================
+
+// magicLinkKeyPrefix namespaces pending magic-link tokens in Redis. Keys hold the
+// SHA-256 of the token, so a Redis dump contains no usable links.
+const magicLinkKeyPrefix = "magic_link:"
+
+var (
+	// ErrMagicLinkDisabled is returned when magic-link login is not configured
+	ErrMagicLinkDisabled = newError(CodeMagicLinkDisabled, "magic-link login is not enabled")
+
+	// ErrInvalidMagicLink is returned for unknown, expired or already-used links
+	ErrInvalidMagicLink = newError(CodeInvalidMagicLink, "invalid or expired magic link")
+)
+
+// MagicLinkConfig configures passwordless login by email.
+type MagicLinkConfig struct {
+	// Redis stores pending tokens until they are redeemed or expire
+	Redis *redis.Client
+
+	// URL is the login link target; the token is appended as the "token" query parameter
+	URL string
+
+	// TTL is how long a link stays valid
+	TTL time.Duration
+}
+
+// WithMagicLink enables magic-link login.
+func (s *AuthService) WithMagicLink(cfg MagicLinkConfig) *AuthService {
+	s.magicLink = &cfg
+	return s
+}
+
+// SendMagicLink emails a one-time login link to the account with the given email.
+// Like RequestPasswordReset, it succeeds for unknown emails to prevent enumeration.
+func (s *AuthService) SendMagicLink(ctx context.Context, email string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.SendMagicLink")
+	defer span.End()
+
+	if s.magicLink == nil {
+		return ErrMagicLinkDisabled
+	}
+	cfg := s.magicLink
+
+	user, _ := s.userRepo.FindByEmail(ctx, email)
+	if user == nil || !user.IsActive {
+		logger.Info("magic link requested for unknown or disabled account", "email", email)
+		return nil
+	}
+
+	token, err := generateMagicLinkToken()
+	if err != nil {
+		return err
+	}
+	if err := cfg.Redis.Set(ctx, magicLinkKey(token), strconv.FormatInt(user.ID, 10), cfg.TTL).Err(); err != nil {
+		return internalError("failed to store magic link", err)
+	}
+
+	minutes := int(cfg.TTL.Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	link := cfg.URL + "?token=" + url.QueryEscape(token)
+	if err := s.emailClient.SendMagicLink(ctx, user.Email, link, minutes); err != nil {
+		logger.Error("failed to send magic link email", "error", err, "email", user.Email)
+		return newError(CodeDeliveryFailed, "failed to send magic link email")
+	}
+
+	logger.Info("magic link sent", "userID", user.ID)
+	return nil
+}
+
+// RedeemMagicLink consumes a magic-link token and issues a token pair. Each link
+// works once: the token is deleted from Redis as it is read. Opening the link
+// proves ownership of the address, so the email is marked verified.
+func (s *AuthService) RedeemMagicLink(ctx context.Context, token string) (*models.TokenPair, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RedeemMagicLink")
+	defer span.End()
+
+	if s.magicLink == nil {
+		return nil, ErrMagicLinkDisabled
+	}
+
+	value, err := s.magicLink.Redis.GetDel(ctx, magicLinkKey(token)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, internalError("failed to load magic link", err)
+	}
+
+	userID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidMagicLink
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	locked, err := s.IsAccountLocked(ctx, user.Email)
+	if err != nil {
+		logger.Warn("failed to check account lockout", "error", err, "email", user.Email)
+	}
+	if locked {
+		return nil, ErrAccountLocked
+	}
+
+	if user.EmailVerifiedAt == nil {
+		if err := s.userRepo.MarkEmailVerified(ctx, user.ID); err != nil {
+			logger.Warn("failed to mark email verified after magic-link login", "error", err, "userID", user.ID)
+		}
+	}
+
+	resp, err := s.generateAuthResponse(ctx, user, false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(ctx, constants.AuditLogin, user.ID, map[string]any{"method": "magic_link"})
+	s.publish(ctx, events.UserLoggedIn, user.ID, map[string]any{"method": "magic_link"})
+	logger.Info("magic link login successful", "userID", user.ID)
+	return &models.TokenPair{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresIn:    resp.ExpiresIn,
+	}, nil
+}
+
+// generateMagicLinkToken returns 32 random bytes, URL-safe base64 encoded.
+func generateMagicLinkToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// magicLinkKey returns the Redis key of a token: the prefix plus its SHA-256.
+func magicLinkKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return magicLinkKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+//go:build integration
+
+package service_test
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	dbpkg "authentio/internal/database"
+	"authentio/internal/repository"
+	"authentio/internal/service"
+	"authentio/internal/testutil"
+	"authentio/pkg/crypto"
+	"authentio/pkg/email"
+	"authentio/pkg/jwt"
+	"authentio/pkg/lock"
+)
+
+// containers are the Postgres and Redis every integration test of the package uses
+var containers *testutil.Containers
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	c, err := testutil.StartContainers(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start test containers: %v\n", err)
+		os.Exit(1)
+	}
+	containers = c
+
+	code := m.Run()
+	if err := c.Terminate(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop test containers: %v\n", err)
+	}
+	os.Exit(code)
+}
+
+// stack is an AuthService wired to the real repositories, the way cmd/server
+// wires it, with the email it sends captured in outbox.
+type stack struct {
+	auth   *service.AuthService
+	jwt    *jwt.Manager
+	users  repository.UserRepository
+	tokens repository.TokenRepository
+	outbox *testutil.Outbox
+}
+
+// newStack builds a stack on an empty database and Redis.
+func newStack(t *testing.T) *stack {
+	t.Helper()
+
+	db := containers.DB(t)
+	rdb := containers.Redis(t)
+
+	var key [crypto.KeySize]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	st := &stack{
+		jwt:    jwt.NewManager("integration-test-secret-at-least-32-bytes").WithRevocationStore(rdb, true),
+		users:  dbpkg.NewUserRepository(db, nil),
+		tokens: dbpkg.NewTokenRepository(db, nil),
+		outbox: &testutil.Outbox{},
+	}
+	emailClient := email.NewClient("localhost", 25, "", "", "noreply@authentio.test", email.WithSender(st.outbox))
+
+	st.auth = service.NewAuthService(
+		st.users,
+		dbpkg.NewTwoFARepository(db, &key, nil),
+		dbpkg.NewOTPRepository(db, &key, nil),
+		st.tokens,
+		st.jwt,
+		emailClient,
+		nil,
+		nil,
+	)
+	st.auth.WithLocker(lock.New(rdb))
+	st.auth.WithTOTPLogin(rdb, time.Minute)
+
+	// Welcome emails are sent in the background; let them finish before the
+	// database and Redis are closed
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		st.auth.FlushBackground(ctx)
+	})
+	return st
+}
@@ -0,0 +1,548 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// ============================================================================
+// OAuth2 Authorization Server (RFC 6749, PKCE per RFC 7636)
+// ============================================================================
+
+// Grant and response types supported by the authorization server
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+	GrantDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+
+	responseTypeCode = "code"
+)
+
+// Redis key prefixes of pending authorization codes and issued refresh tokens.
+// Keys hold the SHA-256 of the value, so a Redis dump contains no usable tokens.
+const (
+	oauthCodeKeyPrefix    = "oauth_code:"
+	oauthRefreshKeyPrefix = "oauth_refresh:"
+)
+
+var (
+	// ErrOAuthServerDisabled is returned when the authorization server is not configured
+	ErrOAuthServerDisabled = newError(CodeOAuthServerDisabled, "oauth authorization server is not enabled")
+
+	// ErrOAuthInvalidClient is returned for unknown clients and wrong client secrets
+	ErrOAuthInvalidClient = newError(CodeOAuthInvalidClient, "client authentication failed")
+
+	// ErrOAuthInvalidGrant is returned for unknown, used or expired codes and
+	// refresh tokens, and for codes presented with the wrong client, redirect
+	// URI or code_verifier
+	ErrOAuthInvalidGrant = newError(CodeOAuthInvalidGrant, "invalid, expired or already used grant")
+)
+
+// OAuthServerConfig configures the OAuth2 authorization server, which lets
+// registered third-party clients obtain tokens for users (authorization code
+// with PKCE) or for themselves (client credentials).
+type OAuthServerConfig struct {
+	// Clients stores the registered third-party applications
+	Clients repository.OAuthClientRepository
+
+	// Redis stores authorization codes and refresh tokens until they are used or expire
+	Redis *redis.Client
+
+	// CodeTTL is how long an authorization code can be exchanged
+	CodeTTL time.Duration
+
+	// AccessTokenTTL and RefreshTokenTTL are the lifetimes of issued tokens
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// DeviceCodeTTL is how long the user has to approve a device authorization
+	// request, and DevicePollInterval how often the device may poll meanwhile
+	DeviceCodeTTL      time.Duration
+	DevicePollInterval time.Duration
+
+	// DeviceVerificationURI is the page where users enter the user code of a
+	// device; it calls GET /activate with their access token
+	DeviceVerificationURI string
+}
+
+// WithOAuthServer enables the OAuth2 authorization server and client registration.
+func (s *AuthService) WithOAuthServer(cfg OAuthServerConfig) *AuthService {
+	s.oauthServer = &cfg
+	return s
+}
+
+// oauthGrant is what an authorization code or refresh token stands for
+type oauthGrant struct {
+	ClientID      string `json:"client_id"`
+	UserID        int64  `json:"user_id"`
+	Scope         string `json:"scope"`
+	RedirectURI   string `json:"redirect_uri,omitempty"`   // authorization codes only
+	CodeChallenge string `json:"code_challenge,omitempty"` // authorization codes only
+	IssuedAt      int64  `json:"iat,omitempty"`            // refresh tokens only, for bulk revocation
+}
+
+// ----------------------------------------------------------------------------
+// Client registration
+// ----------------------------------------------------------------------------
+
+// CreateOAuthClient registers a third-party application. Public clients (native
+// and browser apps, which cannot keep a secret) get no secret and may only use
+// the authorization code grant. The returned client of a confidential client
+// carries its secret, which is not shown again.
+func (s *AuthService) CreateOAuthClient(ctx context.Context, name string, redirectURIs, scopes []string, public bool) (*models.OAuthClient, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.CreateOAuthClient")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+
+	for _, raw := range redirectURIs {
+		u, err := url.Parse(raw)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return nil, newError(CodeInvalidOAuthClient, "redirect uri must be an absolute URI without fragment: "+raw)
+		}
+	}
+	for _, scope := range scopes {
+		if !validScopeToken(scope) {
+			return nil, newError(CodeInvalidOAuthClient, "invalid scope "+scope)
+		}
+	}
+
+	clientID, err := randomOAuthToken(16)
+	if err != nil {
+		return nil, internalError("failed to generate client id", err)
+	}
+	client := &models.OAuthClient{
+		ClientID:      clientID,
+		Name:          name,
+		RedirectURIs:  redirectURIs,
+		AllowedScopes: scopes,
+		Public:        public,
+	}
+	if !public {
+		if client.ClientSecret, err = randomOAuthToken(32); err != nil {
+			return nil, internalError("failed to generate client secret", err)
+		}
+		client.ClientSecretHash = hashOAuthToken(client.ClientSecret)
+	}
+
+	if err := s.oauthServer.Clients.Create(ctx, client); err != nil {
+		return nil, err
+	}
+	client.ClientSecretHash = ""
+
+	logger.Info("oauth client created", "clientID", client.ClientID, "name", name, "public", public)
+	return client, nil
+}
+
+// ListOAuthClients returns every registered client, oldest first, without secrets.
+func (s *AuthService) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListOAuthClients")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+	return s.oauthServer.Clients.List(ctx)
+}
+
+// DeleteOAuthClient removes a client. Its access tokens stay valid until they
+// expire; its codes and refresh tokens are rejected from now on.
+// Returns repository.ErrOAuthClientNotFound.
+func (s *AuthService) DeleteOAuthClient(ctx context.Context, clientID string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.DeleteOAuthClient")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return ErrOAuthServerDisabled
+	}
+	if err := s.oauthServer.Clients.Delete(ctx, clientID); err != nil {
+		return err
+	}
+
+	logger.Info("oauth client deleted", "clientID", clientID)
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Authorization endpoint
+// ----------------------------------------------------------------------------
+
+// AuthorizeOAuthClient issues an authorization code to the client of req on
+// behalf of userID, who has consented by calling the endpoint while signed in.
+// PKCE with S256 is required of every client.
+//
+// An unknown client or unregistered redirect URI is returned as an error: the
+// user must not be sent to an unverified URI. Every other problem is reported
+// to the client the way RFC 6749 section 4.1.2.1 prescribes, as error and
+// error_description parameters of the returned redirect URI.
+func (s *AuthService) AuthorizeOAuthClient(ctx context.Context, userID int64, req models.OAuthAuthorizeRequest) (*models.OAuthAuthorizeResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.AuthorizeOAuthClient")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+
+	client, err := s.oauthServer.Clients.FindByClientID(ctx, req.ClientID)
+	if errors.Is(err, repository.ErrOAuthClientNotFound) {
+		return nil, newError(CodeOAuthInvalidRequest, "unknown client_id")
+	}
+	if err != nil {
+		return nil, internalError("failed to look up oauth client", err)
+	}
+	redirectURI, ok := matchRedirectURI(client, req.RedirectURI)
+	if !ok {
+		return nil, newError(CodeOAuthInvalidRequest, "redirect_uri is not registered for this client")
+	}
+
+	// From here on, errors go back to the client
+	fail := func(code ErrorCode, description string) (*models.OAuthAuthorizeResponse, error) {
+		return &models.OAuthAuthorizeResponse{RedirectTo: appendQuery(redirectURI, map[string]string{
+			"error":             string(code),
+			"error_description": description,
+			"state":             req.State,
+		})}, nil
+	}
+
+	if req.ResponseType != responseTypeCode {
+		return fail(CodeOAuthUnsupportedResponseType, "response_type must be code")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return fail(CodeOAuthInvalidRequest, "code_challenge with code_challenge_method S256 is required")
+	}
+	scope, ok := grantedScope(client.AllowedScopes, req.Scope)
+	if !ok {
+		return fail(CodeOAuthInvalidScope, "requested scope is not allowed for this client")
+	}
+
+	code, err := randomOAuthToken(32)
+	if err != nil {
+		return nil, internalError("failed to generate authorization code", err)
+	}
+	grant := oauthGrant{
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		Scope:         scope,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+	}
+	if err := s.storeOAuthGrant(ctx, oauthCodeKeyPrefix, code, grant, s.oauthServer.CodeTTL); err != nil {
+		return nil, err
+	}
+
+	logger.Info("oauth authorization code issued", "clientID", client.ClientID, "userID", userID, "scope", scope)
+	return &models.OAuthAuthorizeResponse{RedirectTo: appendQuery(redirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})}, nil
+}
+
+// ----------------------------------------------------------------------------
+// Token endpoint
+// ----------------------------------------------------------------------------
+
+// ExchangeOAuthToken implements the token endpoint for the authorization_code,
+// refresh_token, client_credentials and device_code grants. Confidential
+// clients must authenticate with their secret; public clients send only their
+// client_id. Codes and refresh tokens work once: every refresh returns a new
+// refresh token.
+func (s *AuthService) ExchangeOAuthToken(ctx context.Context, req models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ExchangeOAuthToken")
+	defer span.End()
+
+	if s.oauthServer == nil {
+		return nil, ErrOAuthServerDisabled
+	}
+
+	client, err := s.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return s.exchangeAuthorizationCode(ctx, client, req)
+	case GrantRefreshToken:
+		return s.exchangeOAuthRefreshToken(ctx, client, req)
+	case GrantClientCredentials:
+		return s.exchangeClientCredentials(ctx, client, req)
+	case GrantDeviceCode:
+		return s.exchangeDeviceCode(ctx, client, req)
+	case "":
+		return nil, newError(CodeOAuthInvalidRequest, "grant_type is required")
+	default:
+		return nil, newError(CodeOAuthUnsupportedGrantType, "unsupported grant_type "+req.GrantType)
+	}
+}
+
+func (s *AuthService) exchangeAuthorizationCode(ctx context.Context, client *models.OAuthClient, req models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, newError(CodeOAuthInvalidRequest, "code and code_verifier are required")
+	}
+
+	grant, err := s.takeOAuthGrant(ctx, oauthCodeKeyPrefix, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if grant.ClientID != client.ClientID || grant.RedirectURI != req.RedirectURI {
+		return nil, ErrOAuthInvalidGrant
+	}
+	challenge := oauth2.S256ChallengeFromVerifier(req.CodeVerifier)
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(grant.CodeChallenge)) != 1 {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	return s.issueOAuthTokens(ctx, client, grant.UserID, grant.Scope)
+}
+
+func (s *AuthService) exchangeOAuthRefreshToken(ctx context.Context, client *models.OAuthClient, req models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, newError(CodeOAuthInvalidRequest, "refresh_token is required")
+	}
+
+	grant, err := s.takeOAuthGrant(ctx, oauthRefreshKeyPrefix, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if grant.ClientID != client.ClientID {
+		return nil, ErrOAuthInvalidGrant
+	}
+	revoked, err := s.oauthGrantRevoked(ctx, grant)
+	if err != nil {
+		return nil, internalError("failed to check token revocation", err)
+	}
+	if revoked {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	// The client may narrow the scope of the new tokens, never widen it
+	scope := grant.Scope
+	if req.Scope != "" {
+		var ok bool
+		if scope, ok = grantedScope(strings.Fields(grant.Scope), req.Scope); !ok {
+			return nil, newError(CodeOAuthInvalidScope, "scope exceeds the original grant")
+		}
+	}
+
+	return s.issueOAuthTokens(ctx, client, grant.UserID, scope)
+}
+
+func (s *AuthService) exchangeClientCredentials(ctx context.Context, client *models.OAuthClient, req models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if client.Public {
+		return nil, newError(CodeOAuthUnauthorizedClient, "public clients cannot use the client_credentials grant")
+	}
+	scope, ok := grantedScope(client.AllowedScopes, req.Scope)
+	if !ok {
+		return nil, newError(CodeOAuthInvalidScope, "requested scope is not allowed for this client")
+	}
+
+	accessToken, err := s.jwtManager.GenerateClientToken(client.ClientID, strings.Fields(scope), s.oauthServer.AccessTokenTTL)
+	if err != nil {
+		return nil, internalError("failed to generate access token", err)
+	}
+
+	logger.Info("oauth client credentials token issued", "clientID", client.ClientID, "scope", scope)
+	return &models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.oauthServer.AccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// issueOAuthTokens issues an access and refresh token for userID to client,
+// provided the account can still sign in.
+func (s *AuthService) issueOAuthTokens(ctx context.Context, client *models.OAuthClient, userID int64, scope string) (*models.OAuthTokenResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrOAuthInvalidGrant
+	}
+	if err := checkAccountActive(user); err != nil {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	accessToken, err := s.jwtManager.GenerateTokenWithClaims(jwt.UserClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		TenantID:  user.TenantID,
+		ClientID:  client.ClientID,
+		Scopes:    strings.Fields(scope),
+		TTL:       s.oauthServer.AccessTokenTTL,
+	})
+	if err != nil {
+		return nil, internalError("failed to generate access token", err)
+	}
+
+	refreshToken, err := randomOAuthToken(32)
+	if err != nil {
+		return nil, internalError("failed to generate refresh token", err)
+	}
+	grant := oauthGrant{ClientID: client.ClientID, UserID: user.ID, Scope: scope, IssuedAt: time.Now().Unix()}
+	if err := s.storeOAuthGrant(ctx, oauthRefreshKeyPrefix, refreshToken, grant, s.oauthServer.RefreshTokenTTL); err != nil {
+		return nil, err
+	}
+
+	logger.Info("oauth tokens issued", "clientID", client.ClientID, "userID", user.ID, "scope", scope)
+	return &models.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.oauthServer.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// authenticateOAuthClient looks up the client and checks its secret. Public
+// clients must not send one.
+func (s *AuthService) authenticateOAuthClient(ctx context.Context, clientID, secret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrOAuthInvalidClient
+	}
+	client, err := s.oauthServer.Clients.FindByClientID(ctx, clientID)
+	if errors.Is(err, repository.ErrOAuthClientNotFound) {
+		return nil, ErrOAuthInvalidClient
+	}
+	if err != nil {
+		return nil, internalError("failed to look up oauth client", err)
+	}
+
+	if client.Public {
+		if secret != "" {
+			return nil, ErrOAuthInvalidClient
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashOAuthToken(secret)), []byte(client.ClientSecretHash)) != 1 {
+		return nil, ErrOAuthInvalidClient
+	}
+	return client, nil
+}
+
+// ----------------------------------------------------------------------------
+// Helpers
+// ----------------------------------------------------------------------------
+
+// storeOAuthGrant stores grant under the hash of token for ttl.
+func (s *AuthService) storeOAuthGrant(ctx context.Context, prefix, token string, grant oauthGrant, ttl time.Duration) error {
+	value, err := json.Marshal(grant)
+	if err != nil {
+		return internalError("failed to encode oauth grant", err)
+	}
+	if err := s.oauthServer.Redis.Set(ctx, prefix+hashOAuthToken(token), value, ttl).Err(); err != nil {
+		return internalError("failed to store oauth grant", err)
+	}
+	return nil
+}
+
+// takeOAuthGrant consumes the grant stored for token, so it cannot be used again.
+func (s *AuthService) takeOAuthGrant(ctx context.Context, prefix, token string) (*oauthGrant, error) {
+	value, err := s.oauthServer.Redis.GetDel(ctx, prefix+hashOAuthToken(token)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrOAuthInvalidGrant
+	}
+	if err != nil {
+		return nil, internalError("failed to load oauth grant", err)
+	}
+
+	var grant oauthGrant
+	if err := json.Unmarshal(value, &grant); err != nil {
+		return nil, internalError("failed to decode oauth grant", err)
+	}
+	return &grant, nil
+}
+
+// matchRedirectURI returns the redirect URI to use: requested when it is one of
+// the client's registered URIs (compared exactly), or the only registered URI
+// when none was requested.
+func matchRedirectURI(client *models.OAuthClient, requested string) (string, bool) {
+	if requested == "" {
+		if len(client.RedirectURIs) == 1 {
+			return client.RedirectURIs[0], true
+		}
+		return "", false
+	}
+	return requested, slices.Contains(client.RedirectURIs, requested)
+}
+
+// grantedScope returns the space-separated scope to grant for requested, which
+// must be a subset of allowed. An empty request grants everything allowed.
+func grantedScope(allowed []string, requested string) (string, bool) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		return strings.Join(allowed, " "), true
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return "", false
+		}
+	}
+	return strings.Join(scopes, " "), true
+}
+
+// validScopeToken reports whether scope is a valid RFC 6749 scope-token:
+// printable ASCII except space, double quote and backslash.
+func validScopeToken(scope string) bool {
+	if scope == "" {
+		return false
+	}
+	for _, r := range scope {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// appendQuery adds the non-empty params to the query of rawURL.
+func appendQuery(rawURL string, params map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// randomOAuthToken returns n random bytes, base64url encoded.
+func randomOAuthToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashOAuthToken returns the hex SHA-256 of token.
+func hashOAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
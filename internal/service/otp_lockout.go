@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// OTP Brute-Force Protection
+// ============================================================================
+
+// ErrOTPLocked is returned when a one-time code has been locked after too many
+// wrong guesses. No code is checked until RetryAfter has passed.
+type ErrOTPLocked struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrOTPLocked) Error() string {
+	return fmt.Sprintf("too many incorrect codes, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+// verifyOTPCode checks a one-time code against the newest active OTP of the
+// given type sent to email. A locked OTP is refused with *ErrOTPLocked before the
+// code is compared; a wrong code counts towards the next lockout.
+func (s *AuthService) verifyOTPCode(ctx context.Context, email, code string, otpType constants.Type) (bool, error) {
+	otp, err := s.otpRepo.FindActiveOTP(ctx, email, string(otpType))
+	if err != nil {
+		return false, err
+	}
+	if otp == nil {
+		return false, nil
+	}
+	if err := otpLockedError(otp.LockedUntil); err != nil {
+		return false, err
+	}
+
+	// Redeem the code at most once, even when it is submitted twice at the same time
+	unlock, err := s.acquireLock(ctx, "lock:otp:"+strconv.FormatInt(otp.ID, 10))
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	valid, err := s.otpRepo.VerifyOTP(ctx, email, code, string(otpType))
+	if err != nil || valid {
+		return valid, err
+	}
+
+	attemptsLeft, err := s.otpRepo.RecordVerificationAttempt(ctx, otp.ID)
+	if err != nil {
+		logger.Warn("failed to record OTP verification attempt", "error", err, "email", email)
+		return false, nil
+	}
+	if attemptsLeft > 0 {
+		return false, nil
+	}
+
+	logger.Warn("OTP locked after too many incorrect codes", "email", email, "type", string(otpType))
+	if otp.UserID != nil {
+		s.audit(ctx, constants.AuditOTPLocked, *otp.UserID, map[string]any{"type": string(otpType)})
+	}
+
+	// Report the lockout this attempt just started
+	locked, err := s.otpRepo.FindActiveOTP(ctx, email, string(otpType))
+	if err != nil || locked == nil {
+		return false, nil
+	}
+	if err := otpLockedError(locked.LockedUntil); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// otpLockedError returns *ErrOTPLocked while lockedUntil lies in the future.
+func otpLockedError(lockedUntil *time.Time) error {
+	if lockedUntil == nil {
+		return nil
+	}
+	if retryAfter := time.Until(*lockedUntil); retryAfter > 0 {
+		return &ErrOTPLocked{RetryAfter: retryAfter}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+
+	"authentio/internal/constants"
+	"authentio/internal/repository"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/password"
+)
+
+// ============================================================================
+// Password Changes & History
+// ============================================================================
+
+var (
+	// ErrPasswordReused is returned when a new password matches one of the user's recent passwords
+	ErrPasswordReused = newError(CodePasswordReused, "password was used recently; choose a different password")
+
+	// ErrIncorrectPassword is returned when the current password given to ChangePassword is wrong
+	ErrIncorrectPassword = newError(CodeIncorrectPassword, "current password is incorrect")
+)
+
+// WithPasswordHistory enables password reuse prevention over the last length passwords.
+// A length of 0 disables the check.
+func (s *AuthService) WithPasswordHistory(repo repository.PasswordHistoryRepository, length int) *AuthService {
+	s.passwordHistoryRepo = repo
+	s.passwordHistoryLen = length
+	return s
+}
+
+// ChangePassword sets a new password for an authenticated user after verifying the
+// current one. The new password must satisfy the policy and must not match any of
+// the user's last PasswordHistoryLen passwords.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return ErrUserNotFound
+	}
+
+	if ok, _ := password.Verify(currentPassword, user.Password); !ok {
+		return ErrIncorrectPassword
+	}
+
+	if err := s.setPassword(ctx, user.ID, user.Password, newPassword); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditPasswordChanged, userID, nil)
+	s.publish(ctx, events.PasswordChanged, userID, map[string]any{"reason": "change"})
+
+	logger.Info("password changed", "userID", userID)
+	return nil
+}
+
+// setPassword validates newPassword against the policy and history, then stores it.
+// currentHash is the user's existing hash, which always counts as history.
+func (s *AuthService) setPassword(ctx context.Context, userID int64, currentHash, newPassword string) error {
+	if err := s.passwordPolicy.Check(newPassword); err != nil {
+		return err
+	}
+
+	reused, err := s.isPasswordReused(ctx, userID, currentHash, newPassword)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return ErrPasswordReused
+	}
+
+	hashed, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+
+	s.recordPasswordHistory(ctx, userID, hashed)
+	return nil
+}
+
+// isPasswordReused checks candidate against the current hash and the stored history.
+// Every hash is checked, even after a match, so the response time does not reveal
+// how far back in the history the match was.
+func (s *AuthService) isPasswordReused(ctx context.Context, userID int64, currentHash, candidate string) (bool, error) {
+	if s.passwordHistoryRepo == nil || s.passwordHistoryLen <= 0 {
+		return false, nil
+	}
+
+	hashes, err := s.passwordHistoryRepo.Recent(ctx, userID, s.passwordHistoryLen)
+	if err != nil {
+		return false, err
+	}
+	if currentHash != "" {
+		hashes = append(hashes, currentHash)
+	}
+
+	reused := false
+	for _, hash := range hashes {
+		match, _ := password.Verify(candidate, hash)
+		reused = reused || match
+	}
+	return reused, nil
+}
+
+// recordPasswordHistory stores a new hash, logging (not failing) on error since the
+// password itself has already been changed.
+func (s *AuthService) recordPasswordHistory(ctx context.Context, userID int64, hash string) {
+	if s.passwordHistoryRepo == nil || s.passwordHistoryLen <= 0 {
+		return
+	}
+	if err := s.passwordHistoryRepo.Add(ctx, userID, hash, s.passwordHistoryLen); err != nil {
+		logger.Warn("failed to record password history", "error", err, "userID", userID)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/signedurl"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Signed Password Reset Links
+// ============================================================================
+
+// passwordResetUsedKeyPrefix namespaces redeemed reset links in Redis. Keys hold
+// the SHA-256 of the token and expire with it.
+const passwordResetUsedKeyPrefix = "password_reset:used:"
+
+// ErrInvalidResetLink is returned for forged, expired or already-used reset links
+var ErrInvalidResetLink = newError(CodeInvalidResetLink, "invalid or expired password reset link")
+
+// PasswordResetLinkConfig configures signed password reset links.
+type PasswordResetLinkConfig struct {
+	// Secret signs the links (HMAC-SHA256)
+	Secret string
+
+	// URL is the password reset page; the token is appended as the "token" query parameter
+	URL string
+
+	// TTL is how long a link stays valid
+	TTL time.Duration
+
+	// Redis records redeemed links so each works only once
+	Redis *redis.Client
+}
+
+// WithPasswordResetLinks makes RequestPasswordReset email a signed link instead
+// of a reset code. The link's token carries the user ID, email and expiry and
+// is signed with cfg.Secret, so RedeemPasswordReset verifies it without a
+// lookup; Redis only records links already redeemed.
+func (s *AuthService) WithPasswordResetLinks(cfg PasswordResetLinkConfig) *AuthService {
+	s.resetLinks = &cfg
+	return s
+}
+
+// passwordResetLink returns a signed reset link for user, pointing to resetURL.
+func (s *AuthService) passwordResetLink(user *models.User, resetURL string) (string, error) {
+	link, err := url.Parse(resetURL)
+	if err != nil {
+		return "", internalError("invalid password reset URL", err)
+	}
+
+	payload := strconv.FormatInt(user.ID, 10) + "|" + user.Email
+	query := link.Query()
+	query.Set("token", signedurl.Generate(s.resetLinks.Secret, payload, s.resetLinks.TTL))
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// RedeemPasswordReset verifies a signed reset link token and sets the user's
+// new password. Each token works once. A token stops working when the user's
+// email changes; a new password rejected by the password policy leaves the
+// token usable, so the user can retry with a stronger one.
+func (s *AuthService) RedeemPasswordReset(ctx context.Context, token, newPassword string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RedeemPasswordReset")
+	defer span.End()
+
+	if s.resetLinks == nil {
+		return ErrInvalidResetLink
+	}
+
+	payload, err := signedurl.Verify(s.resetLinks.Secret, token)
+	if err != nil {
+		logger.Info("password reset link rejected", "error", err)
+		return ErrInvalidResetLink
+	}
+	rawID, linkEmail, _ := strings.Cut(payload, "|")
+	userID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return ErrInvalidResetLink
+	}
+
+	// Claim the token before changing the password, so concurrent redemptions
+	// of the same link cannot both succeed
+	expiresAt, _ := signedurl.ExpiresAt(token)
+	usedKey := passwordResetUsedKey(token)
+	first, err := s.resetLinks.Redis.SetNX(ctx, usedKey, strconv.FormatInt(userID, 10), time.Until(expiresAt)).Result()
+	if err != nil {
+		return internalError("failed to record password reset link", err)
+	}
+	if !first {
+		logger.Info("password reset link reused", "userID", userID)
+		return ErrInvalidResetLink
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		s.releaseResetLink(ctx, usedKey)
+		return err
+	}
+	if user == nil || !strings.EqualFold(user.Email, linkEmail) {
+		return ErrInvalidResetLink
+	}
+
+	// Enforce the password policy and history, then store the new password
+	if err := s.setPassword(ctx, user.ID, user.Password, newPassword); err != nil {
+		s.releaseResetLink(ctx, usedKey)
+		return err
+	}
+	s.audit(ctx, constants.AuditPasswordReset, user.ID, map[string]any{"method": "link"})
+	s.publish(ctx, events.PasswordChanged, user.ID, map[string]any{"reason": "reset"})
+	s.sendPasswordChangedEmail(ctx, user.Email)
+
+	logger.Info("password reset via link successful", "userID", user.ID)
+	return nil
+}
+
+// releaseResetLink makes a claimed token usable again after the reset failed.
+func (s *AuthService) releaseResetLink(ctx context.Context, usedKey string) {
+	if err := s.resetLinks.Redis.Del(context.WithoutCancel(ctx), usedKey).Err(); err != nil {
+		logger.Warn("failed to release password reset link", "error", err)
+	}
+}
+
+// passwordResetUsedKey returns the Redis key recording a redeemed token.
+func passwordResetUsedKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return passwordResetUsedKeyPrefix + hex.EncodeToString(sum[:])
+}
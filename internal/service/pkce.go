@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"authentio/internal/models"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/oauth2"
+)
+
+// ============================================================================
+// OAuth2 PKCE (RFC 7636)
+// ============================================================================
+
+// pkceKeyPrefix namespaces stored code challenges in Redis, keyed by OAuth state
+const pkceKeyPrefix = "oauth_pkce:"
+
+// ErrPKCEMismatch is returned when the code_verifier does not match the stored
+// code_challenge, or no challenge exists for the given state
+var ErrPKCEMismatch = newError(CodePKCEMismatch, "PKCE code verifier mismatch")
+
+// ErrPKCEDisabled is returned when a PKCE flow is started without a challenge store
+var ErrPKCEDisabled = newError(CodePKCEDisabled, "PKCE is not configured")
+
+// WithPKCEStore enables the PKCE authorize flow. Challenges are kept in Redis for ttl.
+func (s *AuthService) WithPKCEStore(rdb *redis.Client, ttl time.Duration) *AuthService {
+	s.pkceStore = rdb
+	s.pkceTTL = ttl
+	return s
+}
+
+// StartOAuthAuthorization begins a PKCE authorization-code flow for public clients.
+// It generates the state and code_verifier, stores the S256 code_challenge keyed by
+// state, and returns the provider URL along with the verifier the client must send
+// back to the callback.
+func (s *AuthService) StartOAuthAuthorization(ctx context.Context, providerName string) (*models.OAuthAuthorization, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.StartOAuthAuthorization")
+	defer span.End()
+
+	provider, err := s.OAuthProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if s.pkceStore == nil {
+		return nil, ErrPKCEDisabled
+	}
+
+	state := generateSecureToken()
+	verifier := oauth2.GenerateVerifier()
+	challenge := oauth2.S256ChallengeFromVerifier(verifier)
+
+	if err := s.pkceStore.Set(ctx, pkceKeyPrefix+state, challenge, s.pkceTTL).Err(); err != nil {
+		return nil, internalError("failed to store code challenge", err)
+	}
+
+	return &models.OAuthAuthorization{
+		AuthURL:      provider.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)),
+		State:        state,
+		CodeVerifier: verifier,
+		ExpiresIn:    int(s.pkceTTL.Seconds()),
+	}, nil
+}
+
+// verifyPKCE consumes the challenge stored for state and checks it against verifier.
+// If verifier is empty, it succeeds only when no PKCE flow was started for state.
+func (s *AuthService) verifyPKCE(ctx context.Context, state, verifier string) error {
+	if s.pkceStore == nil {
+		if verifier != "" {
+			return ErrPKCEMismatch
+		}
+		return nil
+	}
+
+	challenge, err := s.pkceStore.GetDel(ctx, pkceKeyPrefix+state).Result()
+	if err == redis.Nil {
+		// No PKCE flow for this state: only valid for the cookie-based (confidential) flow
+		if verifier != "" {
+			return ErrPKCEMismatch
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// A PKCE flow was started for this state, so the verifier is mandatory
+	expected := oauth2.S256ChallengeFromVerifier(verifier)
+	if verifier == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) != 1 {
+		return ErrPKCEMismatch
+	}
+	return nil
+}
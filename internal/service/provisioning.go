@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+	"authentio/pkg/password"
+)
+
+// ============================================================================
+// User Provisioning (SCIM)
+// ============================================================================
+
+var (
+	// ErrUserNotFound is returned when a provisioned user does not exist
+	ErrUserNotFound = newError(CodeUserNotFound, "user not found")
+
+	// ErrEmailTaken is returned when another user already has the email address
+	ErrEmailTaken = newError(CodeEmailTaken, "email already exists")
+
+	// ErrAccountDisabled is returned by Login for deactivated (deprovisioned) accounts
+	ErrAccountDisabled = newError(CodeAccountDisabled, "account is disabled")
+)
+
+// ListUsers returns one page of users matching filter, starting after cursor
+// (nil for the first page), together with the total number of matches.
+func (s *AuthService) ListUsers(ctx context.Context, filter repository.UserFilter, cursor *repository.Cursor) (*repository.UserPage, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListUsers")
+	defer span.End()
+
+	return s.userRepo.List(ctx, filter, cursor)
+}
+
+// GetUser returns a user by ID, or ErrUserNotFound.
+func (s *AuthService) GetUser(ctx context.Context, userID int64) (*models.User, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.GetUser")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// ProvisionUser creates a user on behalf of an identity provider. A password is
+// optional: users provisioned without one sign in through SSO, OAuth or a
+// password reset. Provisioned emails count as verified, since the identity
+// provider owns them.
+func (s *AuthService) ProvisionUser(ctx context.Context, user *models.User, plainPassword string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ProvisionUser")
+	defer span.End()
+
+	existing, err := s.userRepo.FindByEmail(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrEmailTaken
+	}
+
+	if plainPassword != "" {
+		if err := s.passwordPolicy.Check(plainPassword); err != nil {
+			return err
+		}
+		hashed, err := password.Hash(plainPassword)
+		if err != nil {
+			return err
+		}
+		user.Password = hashed
+	}
+
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.EmailVerifiedAt = &now
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+	if user.Password != "" {
+		s.recordPasswordHistory(ctx, user.ID, user.Password)
+	}
+
+	s.audit(ctx, constants.AuditUserProvisioned, user.ID, map[string]any{"email": user.Email})
+	logger.Info("user provisioned", "userID", user.ID)
+	return nil
+}
+
+// UpdateProvisionedUser saves the name, email and active flag of a user changed by
+// an identity provider. Deactivating a user signs them out everywhere.
+func (s *AuthService) UpdateProvisionedUser(ctx context.Context, user *models.User) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.UpdateProvisionedUser")
+	defer span.End()
+
+	current, err := s.GetUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(user.Email, current.Email) {
+		existing, err := s.userRepo.FindByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != user.ID {
+			return ErrEmailTaken
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditUserUpdated, user.ID, map[string]any{"email": user.Email, "active": user.IsActive})
+
+	if current.IsActive && !user.IsActive {
+		if err := s.LogoutAll(ctx, user.ID); err != nil {
+			logger.Warn("failed to revoke sessions of deactivated user", "error", err, "userID", user.ID)
+		}
+		logger.Info("user deactivated", "userID", user.ID)
+	}
+	return nil
+}
+
+// DeprovisionUser soft-deletes a user and revokes all of their sessions.
+func (s *AuthService) DeprovisionUser(ctx context.Context, userID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.DeprovisionUser")
+	defer span.End()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.LogoutAll(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, constants.AuditUserDeprovisioned, userID, nil)
+	logger.Info("user deprovisioned", "userID", userID)
+	return nil
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Role-Based Access Control
+// ============================================================================
+
+// permissionPattern restricts permission names to lowercase "resource:action"
+// style identifiers, e.g. "users:impersonate" or "reports.billing:read".
+var permissionPattern = regexp.MustCompile(`^[a-z][a-z0-9_.:-]{0,127}$`)
+
+var (
+	// ErrRBACDisabled is returned when no role repository is configured
+	ErrRBACDisabled = newError(CodeRBACDisabled, "role-based access control is not enabled")
+
+	// ErrRoleExists is returned when another role already has the name
+	ErrRoleExists = newError(CodeRoleExists, "role already exists")
+
+	// ErrInvalidRoleName is returned for empty or overlong role names
+	ErrInvalidRoleName = newError(CodeInvalidRoleName, "role name must be 1 to 64 characters")
+
+	// ErrInvalidPermission is returned for permission names not matching permissionPattern
+	ErrInvalidPermission = newError(CodeInvalidPermission, "invalid permission name")
+)
+
+// WithRoles enables RBAC: role management, and a "permissions" claim listing the
+// permissions of the user's roles in every access token.
+func (s *AuthService) WithRoles(repo repository.RoleRepository) *AuthService {
+	s.roleRepo = repo
+	return s
+}
+
+// CreateRole creates a role granting the given permissions.
+func (s *AuthService) CreateRole(ctx context.Context, name string, permissions []string) (*models.Role, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.CreateRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return nil, ErrRBACDisabled
+	}
+
+	role, err := newRole(name, permissions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRoleNameFree(ctx, role.Name, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	logger.Info("role created", "roleID", role.ID, "name", role.Name)
+	return role, nil
+}
+
+// GetRole returns a role by ID, or repository.ErrRoleNotFound.
+func (s *AuthService) GetRole(ctx context.Context, roleID int64) (*models.Role, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.GetRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return nil, ErrRBACDisabled
+	}
+
+	role, err := s.roleRepo.FindByID(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, repository.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// ListRoles returns every role, ordered by name.
+func (s *AuthService) ListRoles(ctx context.Context) ([]models.Role, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListRoles")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return nil, ErrRBACDisabled
+	}
+	return s.roleRepo.List(ctx)
+}
+
+// UpdateRole renames a role and replaces its permissions. Users holding the role
+// get the new permissions with their next access token.
+func (s *AuthService) UpdateRole(ctx context.Context, roleID int64, name string, permissions []string) (*models.Role, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.UpdateRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return nil, ErrRBACDisabled
+	}
+
+	role, err := newRole(name, permissions)
+	if err != nil {
+		return nil, err
+	}
+	role.ID = roleID
+	if err := s.checkRoleNameFree(ctx, role.Name, roleID); err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	logger.Info("role updated", "roleID", role.ID, "name", role.Name)
+	return role, nil
+}
+
+// DeleteRole deletes a role and unassigns it from every user.
+func (s *AuthService) DeleteRole(ctx context.Context, roleID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.DeleteRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return ErrRBACDisabled
+	}
+
+	if err := s.roleRepo.Delete(ctx, roleID); err != nil {
+		return err
+	}
+
+	logger.Info("role deleted", "roleID", roleID)
+	return nil
+}
+
+// AssignRole gives a user a role. Assigning a role the user already has is not an error.
+func (s *AuthService) AssignRole(ctx context.Context, userID, roleID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.AssignRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return ErrRBACDisabled
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.AssignToUser(ctx, userID, roleID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, constants.AuditRoleAssigned, userID, map[string]any{"role_id": roleID})
+	logger.Info("role assigned", "userID", userID, "roleID", roleID)
+	return nil
+}
+
+// RemoveRole takes a role away from a user. Tokens issued before the change keep
+// their permissions until they expire or are refreshed.
+func (s *AuthService) RemoveRole(ctx context.Context, userID, roleID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RemoveRole")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return ErrRBACDisabled
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.roleRepo.RemoveFromUser(ctx, userID, roleID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, constants.AuditRoleRemoved, userID, map[string]any{"role_id": roleID})
+	logger.Info("role removed", "userID", userID, "roleID", roleID)
+	return nil
+}
+
+// ListUserRoles returns the roles of a user, ordered by name.
+func (s *AuthService) ListUserRoles(ctx context.Context, userID int64) ([]models.Role, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListUserRoles")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return nil, ErrRBACDisabled
+	}
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.ListForUser(ctx, userID)
+}
+
+// HasPermission reports whether any of the user's roles grants permission. Unlike
+// the "permissions" token claim, the answer reflects role changes immediately.
+func (s *AuthService) HasPermission(ctx context.Context, userID int64, permission string) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.HasPermission")
+	defer span.End()
+
+	if s.roleRepo == nil {
+		return false, nil
+	}
+	return s.roleRepo.UserHasPermission(ctx, userID, permission)
+}
+
+// ============================================================================
+// RBAC Helpers
+// ============================================================================
+
+// userPermissions returns the permissions to embed in a user's access token;
+// none when RBAC is disabled.
+func (s *AuthService) userPermissions(ctx context.Context, userID int64) ([]string, error) {
+	if s.roleRepo == nil {
+		return nil, nil
+	}
+	return s.roleRepo.PermissionsForUser(ctx, userID)
+}
+
+// checkRoleNameFree returns ErrRoleExists if a role other than exceptID has the name.
+func (s *AuthService) checkRoleNameFree(ctx context.Context, name string, exceptID int64) error {
+	existing, err := s.roleRepo.FindByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != exceptID {
+		return ErrRoleExists
+	}
+	return nil
+}
+
+// newRole validates a role name and its permissions, dropping duplicate permissions.
+func newRole(name string, permissions []string) (*models.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 64 {
+		return nil, ErrInvalidRoleName
+	}
+
+	seen := make(map[string]bool, len(permissions))
+	unique := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		if !permissionPattern.MatchString(p) {
+			return nil, ErrInvalidPermission
+		}
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return &models.Role{Name: name, Permissions: unique}, nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Security Keys (FIDO2 Second Factor)
+// ============================================================================
+
+// securityKeyLoginKeyPrefix namespaces password logins waiting for a security
+// key assertion; the key holds the SHA-256 of the two-factor token
+const securityKeyLoginKeyPrefix = "security_key_login:"
+
+// securityKeyCeremony names the WebAuthn ceremony of a second-factor assertion
+const securityKeyCeremony = "2fa"
+
+// ErrSecurityKeyLoginNotFound is returned when a two-factor token is unknown,
+// already used or expired
+var ErrSecurityKeyLoginNotFound = newError(CodeWebAuthnSessionNotFound, "security key login expired or was not started")
+
+// pendingSecurityKeyLogin is a login whose password was verified and that only
+// needs the security key assertion to issue tokens
+type pendingSecurityKeyLogin struct {
+	UserID     int64  `json:"user_id"`
+	RememberMe bool   `json:"remember_me"`
+	Method     string `json:"method"`
+}
+
+// BeginSecurityKeyRegistration starts registering a hardware security key (a
+// YubiKey, etc.) as second factor for an authenticated user. Once registered,
+// every password login of the user is challenged for one of their keys.
+func (s *AuthService) BeginSecurityKeyRegistration(ctx context.Context, userID int64) (*protocol.CredentialCreation, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.BeginSecurityKeyRegistration")
+	defer span.End()
+
+	return s.beginWebAuthnRegistration(ctx, userID, constants.WebAuthnSecurityKey)
+}
+
+// FinishSecurityKeyRegistration verifies the authenticator's attestation against
+// the challenge issued by BeginSecurityKeyRegistration and stores the security key.
+func (s *AuthService) FinishSecurityKeyRegistration(ctx context.Context, userID int64, resp *protocol.ParsedCredentialCreationData) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.FinishSecurityKeyRegistration")
+	defer span.End()
+
+	return s.finishWebAuthnRegistration(ctx, userID, constants.WebAuthnSecurityKey, resp)
+}
+
+// Begin2FAAssertion challenges the user for one of their registered security
+// keys. User verification (PIN, biometrics) is not required: the key is a
+// second factor next to the password.
+func (s *AuthService) Begin2FAAssertion(ctx context.Context, userID int64) (*protocol.CredentialAssertion, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Begin2FAAssertion")
+	defer span.End()
+
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID, constants.WebAuthnSecurityKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.credentials) == 0 {
+		return nil, ErrNoWebAuthnCredentials
+	}
+
+	assertion, session, err := s.webAuthn.WebAuthn.BeginLogin(user,
+		webauthn.WithUserVerification(protocol.VerificationDiscouraged),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveWebAuthnSession(ctx, securityKeyCeremony, userID, session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// Finish2FAAssertion verifies a security key assertion against the challenge
+// issued by Begin2FAAssertion. Like passkey logins, assertions whose sign count
+// did not increase are rejected as coming from a cloned key.
+func (s *AuthService) Finish2FAAssertion(ctx context.Context, userID int64, resp *protocol.ParsedCredentialAssertionData) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.Finish2FAAssertion")
+	defer span.End()
+
+	if s.webAuthn == nil {
+		return ErrWebAuthnDisabled
+	}
+
+	session, err := s.takeWebAuthnSession(ctx, securityKeyCeremony, userID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID, constants.WebAuthnSecurityKey)
+	if err != nil {
+		return err
+	}
+
+	credential, err := s.webAuthn.WebAuthn.ValidateLogin(user, *session, resp)
+	if err != nil {
+		logger.Warn("security key verification failed", "userID", userID, "error", err)
+		return ErrWebAuthnVerificationFailed
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Warn("security key sign count did not increase, possible cloned authenticator", "userID", userID)
+		return ErrWebAuthnVerificationFailed
+	}
+	return s.updateWebAuthnCredential(ctx, user, credential)
+}
+
+// FinishSecurityKeyLogin completes a password login that was challenged for a
+// security key: the assertion is verified with Finish2FAAssertion and, on
+// success, tokens are issued as the password login would have. Each two-factor
+// token can be used once.
+func (s *AuthService) FinishSecurityKeyLogin(ctx context.Context, twoFactorToken string, resp *protocol.ParsedCredentialAssertionData) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.FinishSecurityKeyLogin")
+	defer span.End()
+
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	data, err := s.webAuthn.Redis.GetDel(ctx, securityKeyLoginKey(twoFactorToken)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSecurityKeyLoginNotFound
+	}
+	if err != nil {
+		return nil, internalError("failed to load security key login", err)
+	}
+	var pending pendingSecurityKeyLogin
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, internalError("failed to decode security key login", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, pending.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.Finish2FAAssertion(ctx, user.ID, resp); err != nil {
+		if errors.Is(err, ErrWebAuthnVerificationFailed) {
+			s.recordFailedLogin(ctx, user.Email)
+			s.audit(ctx, constants.AuditLoginFailed, user.ID, map[string]any{"reason": "security_key_verification_failed"})
+		}
+		return nil, err
+	}
+
+	// The account may have been disabled while the key was asked for
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"method": pending.Method, "second_factor": string(constants.WebAuthnSecurityKey)}
+	s.audit(ctx, constants.AuditLogin, user.ID, details)
+	s.publish(ctx, events.UserLoggedIn, user.ID, details)
+	logger.Info("security key login successful", "userID", user.ID)
+	return s.generateAuthResponse(ctx, user, pending.RememberMe)
+}
+
+// securityKeyChallenge is called by Login once the password is verified. For
+// accounts with a security key it returns the response asking for the key,
+// with a two-factor token valid for the WebAuthn session TTL; for all others,
+// and when WebAuthn is disabled, it returns nil.
+func (s *AuthService) securityKeyChallenge(ctx context.Context, user *models.User, rememberMe bool, method string) (*response.LoginResponse, error) {
+	if s.webAuthn == nil {
+		return nil, nil
+	}
+
+	assertion, err := s.Begin2FAAssertion(ctx, user.ID)
+	if errors.Is(err, ErrNoWebAuthnCredentials) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(pendingSecurityKeyLogin{UserID: user.ID, RememberMe: rememberMe, Method: method})
+	if err != nil {
+		return nil, err
+	}
+	token := generateSecureToken()
+	if err := s.webAuthn.Redis.Set(ctx, securityKeyLoginKey(token), data, s.webAuthn.SessionTTL).Err(); err != nil {
+		return nil, internalError("failed to store security key login", err)
+	}
+
+	logger.Info("security key required to finish login", "userID", user.ID)
+	return &response.LoginResponse{
+		User: response.UserResponse{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			IsActive:  user.IsActive,
+		},
+		TwoFactorRequired:    true,
+		TwoFactorToken:       token,
+		SecurityKeyChallenge: assertion,
+	}, nil
+}
+
+// securityKeyLoginKey returns the Redis key of a two-factor token: the prefix
+// plus its SHA-256, so tokens cannot be read back from Redis.
+func securityKeyLoginKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return securityKeyLoginKeyPrefix + hex.EncodeToString(sum[:])
+}
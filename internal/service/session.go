@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Sessions (devices)
+// ============================================================================
+
+// Refresh token lifetimes unless WithRefreshTokenTTL sets others
+const (
+	DefaultRefreshTokenTTL = 24 * time.Hour
+	DefaultRememberMeTTL   = 30 * 24 * time.Hour
+)
+
+// WithRefreshTokenTTL sets the refresh token lifetime of regular sessions and of
+// sessions started with remember-me. Every rotation issues a token with the same
+// lifetime, so a session ends after that long without a refresh.
+func (s *AuthService) WithRefreshTokenTTL(ttl, rememberMeTTL time.Duration) *AuthService {
+	s.refreshTokenTTL = ttl
+	s.rememberMeTTL = rememberMeTTL
+	return s
+}
+
+// ListSessions returns the active sessions (logged-in devices) of a user,
+// most recently used first, with the device parsed from their User-Agent.
+func (s *AuthService) ListSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListSessions")
+	defer span.End()
+
+	sessions, err := s.tokenRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// Sessions saved before devices were recorded only have their User-Agent
+	for i := range sessions {
+		if sessions[i].Device.DeviceType == "" {
+			sessions[i].Device = models.NewDeviceFingerprint(sessions[i].UserAgent)
+		}
+	}
+	return sessions, nil
+}
+
+// ListUserSessions returns the active sessions of any user, for admins. Returns
+// ErrUserNotFound if there is no such user.
+func (s *AuthService) ListUserSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListUserSessions")
+	defer span.End()
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+	return s.ListSessions(ctx, userID)
+}
+
+// RevokeSession logs a single device out: the session is marked revoked and its
+// refresh token family can no longer be rotated. Access tokens already issued for
+// the session stay valid until expiry unless session validation is enabled.
+// Returns repository.ErrSessionNotFound if the session is not an active session of the user.
+func (s *AuthService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RevokeSession")
+	defer span.End()
+
+	if err := s.tokenRepo.RevokeSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	s.evictSessions(ctx, sessionID)
+	s.audit(ctx, constants.AuditSessionRevoked, userID, map[string]any{"session_id": sessionID})
+
+	logger.Info("session revoked", "userID", userID, "sessionID", sessionID)
+	return nil
+}
+
+// SlideSession keeps an active session alive: once less than half of its
+// lifetime (REFRESH_TOKEN_TTL, or REMEMBER_ME_TTL for remember-me sessions) is
+// left, the session is extended by a full lifetime from now, as a refresh
+// would. It implements middleware.SessionSlider, so with SLIDING_SESSION_ENABLED
+// a user who keeps using their access tokens is not logged out because they
+// did not refresh in time. Unknown, revoked and already expired sessions are
+// left alone.
+func (s *AuthService) SlideSession(ctx context.Context, sessionID string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.SlideSession")
+	defer span.End()
+
+	session, err := s.tokenRepo.GetSession(ctx, sessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ttl := s.sessionTTL(session.RememberMe)
+	remaining := time.Until(session.LastSeenAt.Add(ttl))
+	if remaining <= 0 || remaining >= ttl/2 {
+		return nil
+	}
+
+	err = s.tokenRepo.ExtendSession(ctx, sessionID, time.Now().Add(ttl))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil // Revoked meanwhile
+	}
+	if err != nil {
+		return err
+	}
+	logger.Debug("session extended", "sessionID", sessionID, "ttl", ttl.String())
+	return nil
+}
+
+// saveSession records the session a refresh token belongs to, together with the
+// client the request came from. It is called on login and on every refresh, which
+// keeps last_seen_at current. rememberMe is only stored when the session is created.
+func (s *AuthService) saveSession(ctx context.Context, refreshToken *models.RefreshToken, rememberMe bool) error {
+	client := models.ClientInfoFromContext(ctx)
+	return s.tokenRepo.SaveSession(ctx, &models.Session{
+		ID:               refreshToken.FamilyID,
+		UserID:           refreshToken.UserID,
+		RefreshTokenHash: hashRefreshToken(refreshToken.Token),
+		UserAgent:        client.UserAgent,
+		IP:               client.IP,
+		Device:           models.NewDeviceFingerprint(client.UserAgent),
+		RememberMe:       rememberMe,
+	})
+}
+
+// sessionTTL returns the refresh token lifetime of a regular or remember-me session.
+func (s *AuthService) sessionTTL(rememberMe bool) time.Duration {
+	if rememberMe {
+		return s.rememberMeTTL
+	}
+	return s.refreshTokenTTL
+}
+
+// refreshTokenSession returns the session refreshToken belongs to and whether it
+// is a remember-me session. Unknown tokens return no session; used and legacy
+// (session-less) tokens report rememberMe false. Both are left for
+// RotateRefreshToken to reject or detect as reused.
+func (s *AuthService) refreshTokenSession(ctx context.Context, refreshToken string) (sessionID string, rememberMe bool, err error) {
+	current, err := s.tokenRepo.GetRefreshToken(ctx, refreshToken)
+	if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, internalError("failed to look up refresh token", err)
+	}
+
+	session, err := s.tokenRepo.GetSession(ctx, current.FamilyID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return current.FamilyID, false, nil
+	}
+	if err != nil {
+		return "", false, internalError("failed to look up session", err)
+	}
+	return current.FamilyID, session.RememberMe, nil
+}
+
+// hashRefreshToken returns the hex-encoded SHA-256 of a refresh token, so the
+// sessions table never holds a usable token.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"authentio/internal/repository"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Session Cache
+// ============================================================================
+
+// sessionCacheKeyPrefix namespaces cached active sessions; the key is followed
+// by the session ID and holds the tenant the session was looked up in
+const sessionCacheKeyPrefix = "auth:session:"
+
+// WithSessionCache caches active sessions, and the verified claims of access
+// tokens of active sessions (see CacheClaims), in Redis for up to ttl, so session
+// validation does not query Postgres on every authenticated request. Sessions
+// revoked through this service are evicted at once; a session revoked any other
+// way (another instance without the cache, a direct database change) is still
+// accepted for at most ttl. A zero ttl disables the cache.
+func (s *AuthService) WithSessionCache(rdb *redis.Client, ttl time.Duration) *AuthService {
+	s.sessionCache = rdb
+	s.sessionCacheTTL = ttl
+	return s
+}
+
+// IsSessionActive reports whether the session exists and has not been revoked.
+// It implements middleware.SessionChecker: active sessions are served from the
+// session cache when one is configured, and looked up and cached otherwise.
+// Revoked sessions are never cached. Redis errors fall back to the database.
+func (s *AuthService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.IsSessionActive")
+	defer span.End()
+
+	if s.sessionCache == nil || s.sessionCacheTTL <= 0 {
+		return s.tokenRepo.IsSessionActive(ctx, sessionID)
+	}
+
+	tenant := sessionCacheTenant(ctx)
+	cached, err := s.sessionCache.Get(ctx, sessionCacheKey(sessionID)).Result()
+	switch {
+	case err == nil && cached == tenant:
+		return true, nil
+	case err != nil && !errors.Is(err, redis.Nil):
+		logger.Warn("session cache lookup failed, checking the database", "error", err)
+	}
+
+	session, err := s.tokenRepo.GetSession(ctx, sessionID)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Never cache a session past the point its refresh tokens expire
+	ttl := s.sessionCacheTTL
+	if remaining := time.Until(session.LastSeenAt.Add(s.sessionTTL(session.RememberMe))); remaining < ttl {
+		ttl = remaining
+	}
+	if ttl > 0 {
+		if err := s.sessionCache.Set(ctx, sessionCacheKey(sessionID), tenant, ttl).Err(); err != nil {
+			logger.Warn("failed to cache session", "error", err)
+		}
+	}
+	return true, nil
+}
+
+// evictSessions removes sessions, and the cached claims of their access tokens,
+// from the session cache after they were revoked or rotated. Failures are
+// logged: the entries expire on their own within the cache TTL.
+func (s *AuthService) evictSessions(ctx context.Context, sessionIDs ...string) {
+	if s.sessionCache == nil || len(sessionIDs) == 0 {
+		return
+	}
+
+	keys := make([]string, len(sessionIDs))
+	for i, id := range sessionIDs {
+		keys[i] = sessionCacheKey(id)
+	}
+	if err := s.sessionCache.Del(ctx, keys...).Err(); err != nil {
+		logger.Warn("failed to evict sessions from cache", "error", err, "sessions", len(sessionIDs))
+	}
+	s.evictSessionClaims(ctx, sessionIDs...)
+}
+
+// sessionCacheKey returns the Redis key caching a session
+func sessionCacheKey(sessionID string) string {
+	return sessionCacheKeyPrefix + sessionID
+}
+
+// sessionCacheTenant returns the cached value for sessions looked up in ctx: the
+// tenant of a tenant-scoped request, or "-". A session cached for one tenant is
+// looked up again when presented on behalf of another.
+func sessionCacheTenant(ctx context.Context) string {
+	if tenantID, ok := repository.TenantIDFromContext(ctx); ok {
+		return strconv.FormatInt(tenantID, 10)
+	}
+	return "-"
+}
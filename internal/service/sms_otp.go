@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/sms"
+)
+
+// ============================================================================
+// SMS OTP Delivery
+// ============================================================================
+
+// e164Pattern matches an E.164 phone number, e.g. +2348012345678
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+var (
+	// ErrSMSDisabled is returned when SMS delivery is requested but Twilio is not configured
+	ErrSMSDisabled = newError(CodeSMSDisabled, "SMS delivery is not enabled")
+
+	// ErrNoPhoneNumber is returned when SMS delivery is requested for a user without a phone number
+	ErrNoPhoneNumber = newError(CodeNoPhoneNumber, "no phone number on file")
+
+	// ErrInvalidPhoneNumber is returned for phone numbers that are not in E.164 format
+	ErrInvalidPhoneNumber = newError(CodeInvalidPhoneNumber, "phone number must be in E.164 format, e.g. +15551234567")
+
+	// ErrInvalidDeliveryChannel is returned for channels other than email and sms
+	ErrInvalidDeliveryChannel = newError(CodeInvalidDeliveryChannel, "delivery channel must be email or sms")
+)
+
+// WithSMS enables SMS delivery of OTP codes.
+func (s *AuthService) WithSMS(client *sms.Client) *AuthService {
+	s.smsClient = client
+	return s
+}
+
+// EnableSMS2FA stores the user's phone number and makes SMS their 2FA method.
+func (s *AuthService) EnableSMS2FA(ctx context.Context, userID int64, phoneNumber string) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.EnableSMS2FA")
+	defer span.End()
+
+	if s.smsClient == nil {
+		return ErrSMSDisabled
+	}
+	if !e164Pattern.MatchString(phoneNumber) {
+		return ErrInvalidPhoneNumber
+	}
+
+	if err := s.userRepo.UpdatePhoneNumber(ctx, userID, phoneNumber); err != nil {
+		return err
+	}
+	if err := s.twoFARepo.EnableSMS2FA(ctx, userID); err != nil {
+		return err
+	}
+
+	s.audit(ctx, constants.Audit2FAEnabled, userID, map[string]any{"method": "sms"})
+	s.publish(ctx, events.TwoFAEnabled, userID, map[string]any{"method": "sms"})
+	logger.Info("SMS 2FA enabled", "userID", userID)
+	return nil
+}
+
+// resolveDeliveryChannel picks the channel an OTP is sent through. An explicit
+// channel wins; otherwise SMS is used when it is the user's 2FA method and can be
+// delivered, and email in every other case.
+func (s *AuthService) resolveDeliveryChannel(ctx context.Context, user *models.User, channel constants.DeliveryChannel) (constants.DeliveryChannel, error) {
+	switch channel {
+	case constants.DeliveryEmail:
+		return channel, nil
+	case constants.DeliverySMS:
+	case "":
+		method, err := s.twoFARepo.Get2FAMethod(ctx, user.ID)
+		if err != nil || method != string(constants.DeliverySMS) || s.smsClient == nil || user.PhoneNumber == "" {
+			return constants.DeliveryEmail, nil
+		}
+		return constants.DeliverySMS, nil
+	default:
+		return "", ErrInvalidDeliveryChannel
+	}
+
+	if s.smsClient == nil {
+		return "", ErrSMSDisabled
+	}
+	if user.PhoneNumber == "" {
+		return "", ErrNoPhoneNumber
+	}
+	return constants.DeliverySMS, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Bulk Token Revocation
+// ============================================================================
+
+// RevokeAllTokens signs every user out after a security incident: access tokens
+// issued until now are rejected by the auth middleware and introspection, and
+// every refresh token and OAuth refresh grant stops working, so users have to
+// sign in again. Admin requests are not tenant-scoped, so this covers every
+// tenant of the deployment.
+func (s *AuthService) RevokeAllTokens(ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RevokeAllTokens")
+	defer span.End()
+
+	if err := s.jwtManager.RevokeTokensIssuedBefore(ctx, time.Now()); err != nil {
+		return revocationError(err)
+	}
+	if err := s.tokenRepo.DeleteAllRefreshTokens(ctx); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditAllTokensRevoked, 0, nil)
+
+	logger.Warn("all tokens revoked")
+	return nil
+}
+
+// RevokeAllUserTokens signs one user out everywhere on an admin's request: their
+// access tokens issued until now are rejected, and their refresh tokens and
+// OAuth refresh grants stop working. It is LogoutAll for a user that must exist,
+// recorded as a revocation rather than a logout.
+func (s *AuthService) RevokeAllUserTokens(ctx context.Context, userID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.RevokeAllUserTokens")
+	defer span.End()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := s.signOutEverywhere(ctx, userID); err != nil {
+		return err
+	}
+	s.audit(ctx, constants.AuditTokensRevoked, userID, nil)
+
+	logger.Info("user tokens revoked", "userID", userID)
+	return nil
+}
+
+// revocationError maps a failure to store a revocation cutoff to a service error.
+func revocationError(err error) error {
+	if errors.Is(err, jwt.ErrNoRevocationStore) {
+		return newError(CodeServiceUnavailable, "token revocation is not configured").wrap(err)
+	}
+	return internalError("failed to store token revocation", err)
+}
+
+// oauthGrantRevoked reports whether grant was issued before its user's tokens,
+// or every token, were revoked in bulk.
+func (s *AuthService) oauthGrantRevoked(ctx context.Context, grant *oauthGrant) (bool, error) {
+	return s.jwtManager.IssuedBeforeRevocation(ctx, jwt.Claims{
+		"user_id": float64(grant.UserID),
+		"iat":     float64(grant.IssuedAt),
+	})
+}
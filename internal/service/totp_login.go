@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// Authenticator-App Login (TOTP Second Factor)
+// ============================================================================
+
+// totpLoginKeyPrefix namespaces password logins waiting for a TOTP code; the
+// key holds the SHA-256 of the two-factor token
+const totpLoginKeyPrefix = "totp_login:"
+
+var (
+	// ErrTOTPLoginNotFound is returned when a two-factor token is unknown,
+	// already used or expired
+	ErrTOTPLoginNotFound = newError(CodeTOTPLoginNotFound, "authenticator app login expired or was not started")
+
+	// ErrTOTPLoginUnavailable is returned by Login for an account with TOTP
+	// enabled when no store for pending logins is configured. Signing such an
+	// account in with the password alone would skip its second factor.
+	ErrTOTPLoginUnavailable = newError(CodeServiceUnavailable, "authenticator app login is not configured")
+)
+
+// pendingTOTPLogin is a login whose password was verified and that only needs
+// a code from the user's authenticator app to issue tokens
+type pendingTOTPLogin struct {
+	UserID     int64  `json:"user_id"`
+	RememberMe bool   `json:"remember_me"`
+	Method     string `json:"method"`
+}
+
+// WithTOTPLogin enables the TOTP challenge of password logins. Pending logins
+// are kept in Redis for ttl, the time a user has to enter their code.
+func (s *AuthService) WithTOTPLogin(rdb *redis.Client, ttl time.Duration) *AuthService {
+	s.totpLogins = rdb
+	s.totpLoginTTL = ttl
+	return s
+}
+
+// FinishTOTPLogin completes a password login challenged for an authenticator-app
+// code. The two-factor token is single use: a wrong code means signing in again.
+func (s *AuthService) FinishTOTPLogin(ctx context.Context, twoFactorToken, code string) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.FinishTOTPLogin")
+	defer span.End()
+
+	if s.totpLogins == nil {
+		return nil, ErrTOTPLoginNotFound
+	}
+
+	data, err := s.totpLogins.GetDel(ctx, totpLoginKey(twoFactorToken)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrTOTPLoginNotFound
+	}
+	if err != nil {
+		return nil, internalError("failed to load authenticator app login", err)
+	}
+	var pending pendingTOTPLogin
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, internalError("failed to decode authenticator app login", err)
+	}
+
+	user, err := s.userRepo.FindByID(ctx, pending.UserID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.checkTOTPCode(ctx, user.ID, code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) || errors.Is(err, ErrTOTPCodeReused) {
+			s.recordFailedLogin(ctx, user.Email)
+			s.audit(ctx, constants.AuditLoginFailed, user.ID, map[string]any{"reason": "invalid_totp_code"})
+		}
+		return nil, err
+	}
+
+	// The account may have been deactivated while the code was being entered
+	if err := checkAccountActive(user); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"method": pending.Method, "second_factor": "totp"}
+	s.audit(ctx, constants.AuditLogin, user.ID, details)
+	s.publish(ctx, events.UserLoggedIn, user.ID, details)
+	logger.Info("authenticator app login successful", "userID", user.ID)
+	return s.generateAuthResponse(ctx, user, pending.RememberMe)
+}
+
+// secondFactorChallenge is called by Login once the password is verified. It
+// returns the challenge for the account's second factor, its security key
+// before its authenticator app, or nil for accounts without one.
+func (s *AuthService) secondFactorChallenge(ctx context.Context, user *models.User, rememberMe bool, method string) (*response.LoginResponse, error) {
+	if challenge, err := s.securityKeyChallenge(ctx, user, rememberMe, method); err != nil || challenge != nil {
+		return challenge, err
+	}
+	return s.totpChallenge(ctx, user, rememberMe, method)
+}
+
+// totpChallenge returns, for accounts with TOTP enabled, the response asking for
+// an authenticator-app code, with a two-factor token valid for the TOTP login
+// TTL; for all others it returns nil.
+func (s *AuthService) totpChallenge(ctx context.Context, user *models.User, rememberMe bool, method string) (*response.LoginResponse, error) {
+	enabled, err := s.twoFARepo.Is2FAEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, internalError("failed to check 2FA status", err)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	twoFAMethod, err := s.twoFARepo.Get2FAMethod(ctx, user.ID)
+	if err != nil {
+		return nil, internalError("failed to check 2FA method", err)
+	}
+	if twoFAMethod != "totp" {
+		return nil, nil
+	}
+	if s.totpLogins == nil {
+		return nil, ErrTOTPLoginUnavailable
+	}
+
+	data, err := json.Marshal(pendingTOTPLogin{UserID: user.ID, RememberMe: rememberMe, Method: method})
+	if err != nil {
+		return nil, err
+	}
+	token := generateSecureToken()
+	if err := s.totpLogins.Set(ctx, totpLoginKey(token), data, s.totpLoginTTL).Err(); err != nil {
+		return nil, internalError("failed to store authenticator app login", err)
+	}
+
+	logger.Info("authenticator app code required to finish login", "userID", user.ID)
+	return &response.LoginResponse{
+		User: response.UserResponse{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			IsActive:  user.IsActive,
+		},
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+	}, nil
+}
+
+// totpLoginKey returns the Redis key of a two-factor token: the prefix plus its
+// SHA-256, so tokens cannot be read back from Redis.
+func totpLoginKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return totpLoginKeyPrefix + hex.EncodeToString(sum[:])
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"context"
-	"errors"
 
 	"authentio/internal/repository"
 	"authentio/pkg/logger"
@@ -39,7 +38,7 @@ func (s *TwoFAService) VerifyOTP(ctx context.Context, email, code, otpType strin
 	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil || user == nil {
 		logger.Info("verify otp: user not found", "email", email)
-		return false, errors.New("user not found")
+		return false, ErrUserNotFound
 	}
 
 	return s.twoFARepo.VerifyOTP(ctx, user.ID, email, code, otpType)
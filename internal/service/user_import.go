@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Bulk User Import
+// ============================================================================
+
+// Import row statuses
+const (
+	ImportCreated = "created"
+	ImportFailed  = "failed"
+
+	// ImportSkipped marks valid rows that were not written because an atomic
+	// import was abandoned over another row
+	ImportSkipped = "skipped"
+)
+
+// UserImportRow is one user to import. Imported users have no password; they
+// sign in through a password reset, a magic link, SSO or OAuth.
+type UserImportRow struct {
+	// Line is the row's line number in the uploaded file, echoed in the result
+	Line int
+
+	Email     string
+	FirstName string
+	LastName  string
+	Role      constants.Role
+}
+
+// UserImportResult is the outcome of importing one row.
+type UserImportResult struct {
+	Line   int    `json:"line" example:"2"`
+	Email  string `json:"email" example:"jane.doe@example.com"`
+	Status string `json:"status" example:"created"`
+	UserID int64  `json:"user_id,omitempty" example:"42"`
+	Error  string `json:"error,omitempty" example:"invalid email or password"`
+}
+
+// ImportUsers creates a user for every row. Like ProvisionUser, emails count as
+// verified since an operator vouches for them.
+//
+// By default the import is best effort: every row is created on its own, and a
+// failed row does not undo the rows before it. With atomic set, all rows are
+// checked first and then inserted in a single transaction, so either every row
+// is created or none is.
+func (s *AuthService) ImportUsers(ctx context.Context, rows []UserImportRow, atomic bool) ([]UserImportResult, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ImportUsers")
+	defer span.End()
+
+	if atomic {
+		return s.importUsersAtomically(ctx, rows)
+	}
+
+	results := make([]UserImportResult, len(rows))
+	for i, row := range rows {
+		results[i] = UserImportResult{Line: row.Line, Email: row.Email}
+
+		user := newImportedUser(row)
+		if err := s.ProvisionUser(ctx, user, ""); err != nil {
+			results[i].Status = ImportFailed
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Status = ImportCreated
+		results[i].UserID = user.ID
+	}
+
+	logger.Info("users imported", "rows", len(rows), "atomic", false)
+	return results, nil
+}
+
+// importUsersAtomically checks every row, then inserts them all in one transaction.
+func (s *AuthService) importUsersAtomically(ctx context.Context, rows []UserImportRow) ([]UserImportResult, error) {
+	results := make([]UserImportResult, len(rows))
+	users := make([]*models.User, len(rows))
+	seen := make(map[string]bool, len(rows))
+	failed := false
+
+	for i, row := range rows {
+		results[i] = UserImportResult{Line: row.Line, Email: row.Email, Status: ImportSkipped}
+		users[i] = newImportedUser(row)
+
+		email := strings.ToLower(row.Email)
+		existing, err := s.userRepo.FindByEmail(ctx, row.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil || seen[email] {
+			results[i].Status = ImportFailed
+			results[i].Error = ErrEmailTaken.Error()
+			failed = true
+		}
+		seen[email] = true
+	}
+	if failed {
+		return results, nil
+	}
+
+	if err := s.userRepo.CreateBatch(ctx, users); err != nil {
+		logger.Error("atomic user import failed", "error", err, "rows", len(rows))
+		for i := range results {
+			results[i].Status = ImportFailed
+			results[i].Error = "import transaction failed"
+		}
+		return results, nil
+	}
+
+	for i, user := range users {
+		results[i].Status = ImportCreated
+		results[i].UserID = user.ID
+		s.audit(ctx, constants.AuditUserProvisioned, user.ID, map[string]any{"email": user.Email, "source": "import"})
+	}
+
+	logger.Info("users imported", "rows", len(rows), "atomic", true)
+	return results, nil
+}
+
+// newImportedUser builds the user for an import row.
+func newImportedUser(row UserImportRow) *models.User {
+	now := time.Now()
+	role := row.Role
+	if role == "" {
+		role = constants.RoleUser
+	}
+	return &models.User{
+		Email:           row.Email,
+		FirstName:       row.FirstName,
+		LastName:        row.LastName,
+		Role:            role,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+		BaseModel: models.BaseModel{
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"authentio/internal/constants"
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+	"authentio/pkg/response"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+)
+
+// ============================================================================
+// WebAuthn / Passkeys
+// ============================================================================
+
+// webAuthnSessionKeyPrefix namespaces in-flight WebAuthn ceremonies in Redis
+const webAuthnSessionKeyPrefix = "webauthn_session:"
+
+var (
+	// ErrWebAuthnDisabled is returned when no relying party is configured
+	ErrWebAuthnDisabled = newError(CodeWebAuthnDisabled, "passkey authentication is not enabled")
+
+	// ErrWebAuthnSessionNotFound is returned when a ceremony was never started or has expired
+	ErrWebAuthnSessionNotFound = newError(CodeWebAuthnSessionNotFound, "passkey request expired or was not started")
+
+	// ErrNoWebAuthnCredentials is returned when passkey login is attempted for an account without passkeys
+	ErrNoWebAuthnCredentials = newError(CodeNoWebAuthnCredentials, "no passkeys registered for this account")
+
+	// ErrWebAuthnVerificationFailed is returned when the authenticator response does not verify
+	ErrWebAuthnVerificationFailed = newError(CodeWebAuthnVerificationFailed, "passkey verification failed")
+)
+
+// WebAuthnConfig configures passkey registration and login.
+type WebAuthnConfig struct {
+	// WebAuthn is the relying party (RP ID, display name and allowed origins)
+	WebAuthn *webauthn.WebAuthn
+
+	// Repo stores registered credentials
+	Repo repository.WebAuthnRepository
+
+	// Redis holds the challenge of each ceremony between its begin and finish calls
+	Redis *redis.Client
+
+	// SessionTTL bounds how long a ceremony may take
+	SessionTTL time.Duration
+}
+
+// WithWebAuthn enables passkey registration and login.
+func (s *AuthService) WithWebAuthn(cfg WebAuthnConfig) *AuthService {
+	s.webAuthn = &cfg
+	return s
+}
+
+// BeginWebAuthnRegistration starts registering a new passkey for an authenticated
+// user. Already registered passkeys are excluded so the same authenticator cannot
+// be registered twice.
+func (s *AuthService) BeginWebAuthnRegistration(ctx context.Context, userID int64) (*protocol.CredentialCreation, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.BeginWebAuthnRegistration")
+	defer span.End()
+
+	return s.beginWebAuthnRegistration(ctx, userID, constants.WebAuthnPasskey)
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's attestation against the
+// challenge issued by BeginWebAuthnRegistration and stores the new passkey.
+func (s *AuthService) FinishWebAuthnRegistration(ctx context.Context, userID int64, resp *protocol.ParsedCredentialCreationData) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.FinishWebAuthnRegistration")
+	defer span.End()
+
+	return s.finishWebAuthnRegistration(ctx, userID, constants.WebAuthnPasskey, resp)
+}
+
+// beginWebAuthnRegistration starts registering a credential of the given kind.
+// Passkeys are preferably discoverable (resident) so they can sign in without
+// an email; security keys are not, as they are only asked for after a password.
+func (s *AuthService) beginWebAuthnRegistration(ctx context.Context, userID int64, kind constants.WebAuthnKind) (*protocol.CredentialCreation, error) {
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	residentKey := protocol.ResidentKeyRequirementPreferred
+	if kind == constants.WebAuthnSecurityKey {
+		residentKey = protocol.ResidentKeyRequirementDiscouraged
+	}
+	creation, session, err := s.webAuthn.WebAuthn.BeginRegistration(user,
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithResidentKeyRequirement(residentKey),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveWebAuthnSession(ctx, registrationCeremony(kind), userID, session); err != nil {
+		return nil, err
+	}
+	return creation, nil
+}
+
+// finishWebAuthnRegistration verifies an attestation against the challenge of
+// beginWebAuthnRegistration for the same kind and stores the new credential.
+func (s *AuthService) finishWebAuthnRegistration(ctx context.Context, userID int64, kind constants.WebAuthnKind, resp *protocol.ParsedCredentialCreationData) error {
+	if s.webAuthn == nil {
+		return ErrWebAuthnDisabled
+	}
+
+	session, err := s.takeWebAuthnSession(ctx, registrationCeremony(kind), userID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, userID, "")
+	if err != nil {
+		return err
+	}
+
+	credential, err := s.webAuthn.WebAuthn.CreateCredential(user, *session, resp)
+	if err != nil {
+		logger.Warn("webauthn registration failed", "userID", userID, "kind", string(kind), "error", err)
+		return ErrWebAuthnVerificationFailed
+	}
+
+	if err := s.webAuthn.Repo.Create(ctx, &models.WebAuthnCredential{
+		UserID:     userID,
+		Kind:       kind,
+		Credential: *credential,
+	}); err != nil {
+		return internalError("failed to store "+kindName(kind), err)
+	}
+
+	event := constants.AuditPasskeyRegistered
+	if kind == constants.WebAuthnSecurityKey {
+		event = constants.AuditSecurityKeyRegistered
+	}
+	s.audit(ctx, event, userID, nil)
+
+	logger.Info(kindName(kind)+" registered", "userID", userID)
+	return nil
+}
+
+// BeginWebAuthnLogin starts a passkey login for the account with the given email.
+func (s *AuthService) BeginWebAuthnLogin(ctx context.Context, email string) (*protocol.CredentialAssertion, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.BeginWebAuthnLogin")
+	defer span.End()
+
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	locked, err := s.IsAccountLocked(ctx, email)
+	if err != nil {
+		logger.Warn("failed to check account lockout", "error", err, "email", email)
+	}
+	if locked {
+		return nil, ErrAccountLocked
+	}
+
+	existing, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrNoWebAuthnCredentials
+	}
+	if err := checkAccountActive(existing); err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, existing.ID, constants.WebAuthnPasskey)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.credentials) == 0 {
+		return nil, ErrNoWebAuthnCredentials
+	}
+
+	assertion, session, err := s.webAuthn.WebAuthn.BeginLogin(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveWebAuthnSession(ctx, "login", existing.ID, session); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishWebAuthnLogin verifies the authenticator's assertion against the challenge
+// issued by BeginWebAuthnLogin and, on success, issues tokens like a password login.
+//
+// A sign count that did not increase indicates a cloned authenticator; such
+// assertions are rejected.
+func (s *AuthService) FinishWebAuthnLogin(ctx context.Context, email string, resp *protocol.ParsedCredentialAssertionData) (*response.LoginResponse, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.FinishWebAuthnLogin")
+	defer span.End()
+
+	if s.webAuthn == nil {
+		return nil, ErrWebAuthnDisabled
+	}
+
+	existing, err := s.userRepo.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrWebAuthnSessionNotFound
+	}
+
+	session, err := s.takeWebAuthnSession(ctx, "login", existing.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadWebAuthnUser(ctx, existing.ID, constants.WebAuthnPasskey)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webAuthn.WebAuthn.ValidateLogin(user, *session, resp)
+	if err != nil {
+		logger.Warn("passkey login failed", "userID", existing.ID, "error", err)
+		s.recordFailedLogin(ctx, email)
+		s.audit(ctx, constants.AuditLoginFailed, existing.ID, map[string]any{"reason": "passkey_verification_failed"})
+		return nil, ErrWebAuthnVerificationFailed
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.Warn("passkey sign count did not increase, possible cloned authenticator", "userID", existing.ID)
+		return nil, ErrWebAuthnVerificationFailed
+	}
+
+	if err := s.updateWebAuthnCredential(ctx, user, credential); err != nil {
+		return nil, err
+	}
+	s.clearFailedLogins(ctx, email)
+
+	s.audit(ctx, constants.AuditLogin, existing.ID, map[string]any{"method": "passkey"})
+	s.publish(ctx, events.UserLoggedIn, existing.ID, map[string]any{"method": "passkey"})
+	logger.Info("passkey login successful", "userID", existing.ID)
+	return s.generateAuthResponse(ctx, existing, false)
+}
+
+// ============================================================================
+// WebAuthn Helpers
+// ============================================================================
+
+// webAuthnUser adapts a user and their stored credentials to webauthn.User.
+type webAuthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+// WebAuthnID returns the user handle: the big-endian user ID, which reveals no personal data.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(u.user.ID))
+	return id
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.FirstName + " " + u.user.LastName
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		credentials[i] = c.Credential
+	}
+	return credentials
+}
+
+// loadWebAuthnUser loads a user together with their registered credentials of
+// the given kind; an empty kind loads passkeys and security keys alike.
+func (s *AuthService) loadWebAuthnUser(ctx context.Context, userID int64, kind constants.WebAuthnKind) (*webAuthnUser, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	credentials, err := s.webAuthn.Repo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "" {
+		matching := credentials[:0]
+		for _, c := range credentials {
+			if c.Kind == kind {
+				matching = append(matching, c)
+			}
+		}
+		credentials = matching
+	}
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// updateWebAuthnCredential persists the new sign count and flags of a validated
+// credential on the matching stored credential.
+func (s *AuthService) updateWebAuthnCredential(ctx context.Context, user *webAuthnUser, credential *webauthn.Credential) error {
+	for i := range user.credentials {
+		if bytes.Equal(user.credentials[i].Credential.ID, credential.ID) {
+			user.credentials[i].Credential = *credential
+			if err := s.webAuthn.Repo.UpdateAfterLogin(ctx, &user.credentials[i]); err != nil {
+				return internalError("failed to update "+kindName(user.credentials[i].Kind), err)
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// registrationCeremony names the registration ceremony of a credential kind, so
+// passkey and security key registrations in flight do not replace each other.
+func registrationCeremony(kind constants.WebAuthnKind) string {
+	if kind == constants.WebAuthnSecurityKey {
+		return "register_security_key"
+	}
+	return "register"
+}
+
+// kindName is the credential kind as used in messages
+func kindName(kind constants.WebAuthnKind) string {
+	if kind == constants.WebAuthnSecurityKey {
+		return "security key"
+	}
+	return "passkey"
+}
+
+// saveWebAuthnSession stores a ceremony's session data until its finish call.
+// Starting a new ceremony replaces any unfinished one of the same kind.
+func (s *AuthService) saveWebAuthnSession(ctx context.Context, ceremony string, userID int64, session *webauthn.SessionData) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	key := webAuthnSessionKeyPrefix + ceremony + ":" + strconv.FormatInt(userID, 10)
+	if err := s.webAuthn.Redis.Set(ctx, key, data, s.webAuthn.SessionTTL).Err(); err != nil {
+		return internalError("failed to store passkey challenge", err)
+	}
+	return nil
+}
+
+// takeWebAuthnSession atomically reads and deletes a ceremony's session data, so
+// each challenge can be answered only once.
+func (s *AuthService) takeWebAuthnSession(ctx context.Context, ceremony string, userID int64) (*webauthn.SessionData, error) {
+	key := webAuthnSessionKeyPrefix + ceremony + ":" + strconv.FormatInt(userID, 10)
+	data, err := s.webAuthn.Redis.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrWebAuthnSessionNotFound
+	}
+	if err != nil {
+		return nil, internalError("failed to load passkey challenge", err)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
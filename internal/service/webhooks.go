@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"slices"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+	"authentio/pkg/events"
+	"authentio/pkg/logger"
+)
+
+// ============================================================================
+// Webhooks
+// ============================================================================
+
+var (
+	// ErrWebhooksDisabled is returned when no webhook repository is configured
+	ErrWebhooksDisabled = newError(CodeWebhooksDisabled, "webhooks are not enabled")
+
+	// ErrInvalidWebhookURL is returned for webhook URLs that are not absolute http(s) URLs
+	ErrInvalidWebhookURL = newError(CodeInvalidWebhook, "webhook url must be an absolute http or https URL")
+)
+
+// WithWebhooks enables webhook management. The service is then also the
+// events.WebhookResolver of an events.WebhookPublisher, which delivers the
+// published events to the matching webhooks.
+func (s *AuthService) WithWebhooks(repo repository.WebhookRepository) *AuthService {
+	s.webhookRepo = repo
+	return s
+}
+
+// CreateWebhook registers a callback URL for the events of tenantID (nil for
+// users without a tenant). An empty eventTypes subscribes to every event; an
+// empty secret is replaced by a random one. The returned webhook carries the
+// secret, which is not shown again.
+func (s *AuthService) CreateWebhook(ctx context.Context, tenantID *int64, rawURL, secret string, eventTypes []string) (*models.Webhook, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.CreateWebhook")
+	defer span.End()
+
+	if s.webhookRepo == nil {
+		return nil, ErrWebhooksDisabled
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(events.Types, events.Type(t)) {
+			return nil, newError(CodeInvalidWebhook, "unknown event type "+t)
+		}
+	}
+
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, internalError("failed to generate webhook secret", err)
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	webhook := &models.Webhook{
+		TenantID: tenantID,
+		URL:      u.String(),
+		Secret:   secret,
+		Events:   eventTypes,
+		Active:   true,
+	}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	logger.Info("webhook created", "webhookID", webhook.ID, "url", webhook.URL)
+	return webhook, nil
+}
+
+// ListWebhooks returns every webhook, oldest first, without secrets.
+func (s *AuthService) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	ctx, span := s.tracer.Start(ctx, "AuthService.ListWebhooks")
+	defer span.End()
+
+	if s.webhookRepo == nil {
+		return nil, ErrWebhooksDisabled
+	}
+	return s.webhookRepo.List(ctx)
+}
+
+// DeleteWebhook removes a webhook; events already queued for it may still be delivered.
+// Returns repository.ErrWebhookNotFound.
+func (s *AuthService) DeleteWebhook(ctx context.Context, webhookID int64) error {
+	ctx, span := s.tracer.Start(ctx, "AuthService.DeleteWebhook")
+	defer span.End()
+
+	if s.webhookRepo == nil {
+		return ErrWebhooksDisabled
+	}
+	if err := s.webhookRepo.Delete(ctx, webhookID); err != nil {
+		return err
+	}
+
+	logger.Info("webhook deleted", "webhookID", webhookID)
+	return nil
+}
+
+// WebhooksFor returns the active webhooks subscribed to event: those of the
+// event's tenant that list its type or no types at all. It implements
+// events.WebhookResolver.
+func (s *AuthService) WebhooksFor(ctx context.Context, event events.Event) ([]events.Webhook, error) {
+	if s.webhookRepo == nil {
+		return nil, nil
+	}
+
+	webhooks, err := s.webhookRepo.ListForEvent(ctx, event.TenantID, string(event.Type))
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]events.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		targets[i] = events.Webhook{ID: w.ID, URL: w.URL, Secret: w.Secret}
+	}
+	return targets, nil
+}
@@ -0,0 +1,182 @@
+//go:build integration
+
+// Package testutil starts the Postgres and Redis servers the integration tests
+// run against. The tests are built with the integration tag:
+//
+//	go test -tags integration ./...
+//
+// Containers are started with testcontainers-go, which needs a Docker daemon.
+// TEST_POSTGRES_DSN and TEST_REDIS_ADDR point the tests at servers that are
+// already running instead, e.g. the service containers of a CI job. Every test
+// empties the database and Redis it uses, so packages sharing those servers
+// must run one at a time (go test -p 1).
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	dbpkg "authentio/internal/database"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+// Images of the servers started for the tests, matching docker-compose.yml
+const (
+	PostgresImage = "postgres:18"
+	RedisImage    = "redis:alpine"
+)
+
+// startTimeout bounds pulling and starting both containers
+const startTimeout = 3 * time.Minute
+
+// Containers is a running Postgres and Redis shared by the tests of a package.
+// Start it in TestMain and Terminate it once m.Run returns.
+type Containers struct {
+	PostgresDSN string
+	RedisAddr   string
+
+	containers []testcontainers.Container
+}
+
+// StartContainers starts Postgres and Redis, or uses the servers named by
+// TEST_POSTGRES_DSN and TEST_REDIS_ADDR.
+func StartContainers(ctx context.Context) (c *Containers, err error) {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	// testcontainers panics when it finds no Docker daemon
+	defer func() {
+		if r := recover(); r != nil {
+			c, err = nil, fmt.Errorf("docker unavailable (set TEST_POSTGRES_DSN and TEST_REDIS_ADDR to use running servers): %v", r)
+		}
+	}()
+
+	c = &Containers{
+		PostgresDSN: os.Getenv("TEST_POSTGRES_DSN"),
+		RedisAddr:   os.Getenv("TEST_REDIS_ADDR"),
+	}
+
+	if c.PostgresDSN == "" {
+		pg, err := tcpostgres.Run(ctx, PostgresImage,
+			tcpostgres.WithDatabase("authentio_test"),
+			tcpostgres.WithUsername("authentio"),
+			tcpostgres.WithPassword("authentio"),
+			tcpostgres.BasicWaitStrategies(),
+		)
+		if pg != nil {
+			c.containers = append(c.containers, pg)
+		}
+		if err != nil {
+			c.Terminate(context.Background())
+			return nil, fmt.Errorf("start postgres container: %w", err)
+		}
+		if c.PostgresDSN, err = pg.ConnectionString(ctx, "sslmode=disable"); err != nil {
+			c.Terminate(context.Background())
+			return nil, fmt.Errorf("postgres connection string: %w", err)
+		}
+	}
+
+	if c.RedisAddr == "" {
+		rc, err := tcredis.Run(ctx, RedisImage)
+		if rc != nil {
+			c.containers = append(c.containers, rc)
+		}
+		if err != nil {
+			c.Terminate(context.Background())
+			return nil, fmt.Errorf("start redis container: %w", err)
+		}
+		if c.RedisAddr, err = rc.Endpoint(ctx, ""); err != nil {
+			c.Terminate(context.Background())
+			return nil, fmt.Errorf("redis endpoint: %w", err)
+		}
+	}
+
+	return c, nil
+}
+
+// Terminate stops the containers started by StartContainers. Servers named by
+// the environment variables are left running.
+func (c *Containers) Terminate(ctx context.Context) error {
+	var errs []error
+	for _, container := range c.containers {
+		if err := testcontainers.TerminateContainer(container, testcontainers.StopContext(ctx)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.containers = nil
+	return errors.Join(errs...)
+}
+
+// DB opens the test database, applies the migrations with RunMigrations and
+// empties every table, so each test starts from a fresh schema. The pool is
+// closed when the test ends.
+func (c *Containers) DB(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := dbpkg.Open(dbpkg.DriverPostgres, c.PostgresDSN, false)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := dbpkg.RunMigrations(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	TruncateTables(t, db)
+	return db
+}
+
+// TruncateTables empties every table of the public schema except the
+// migration bookkeeping, and resets their identity sequences.
+func TruncateTables(t testing.TB, db *sql.DB) {
+	t.Helper()
+
+	rows, err := db.Query(`SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename <> 'schema_migrations'`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table name: %v", err)
+		}
+		tables = append(tables, `"`+name+`"`)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	if len(tables) == 0 {
+		return
+	}
+
+	if _, err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+}
+
+// Redis returns a client of the test Redis with an empty database. The client
+// is closed when the test ends.
+func (c *Containers) Redis(t testing.TB) *redis.Client {
+	t.Helper()
+
+	rdb := redis.NewClient(&redis.Options{Addr: c.RedisAddr})
+	t.Cleanup(func() { rdb.Close() })
+
+	if err := rdb.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("flush test redis: %v", err)
+	}
+	return rdb
+}
@@ -0,0 +1,91 @@
+// Package mocks provides testify mocks of the repository interfaces the
+// AuthService depends on, and AuthServiceTestHarness, which wires them into a
+// service for unit tests that need neither Postgres nor Redis.
+package mocks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"authentio/internal/service"
+	"authentio/internal/testutil"
+	"authentio/pkg/email"
+	"authentio/pkg/jwt"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestJWTSecret signs the access tokens of the harness's JWT manager
+const TestJWTSecret = "mock-harness-jwt-secret-at-least-32-bytes"
+
+// AuthServiceTestHarness is an AuthService backed by mock repositories.
+//
+//	h := &mocks.AuthServiceTestHarness{}
+//	h.Setup()
+//	defer h.Close()
+//	h.Users.On("FindByID", mock.Anything, int64(1)).Return(user, nil)
+//	...
+//	h.AssertExpectations(t)
+type AuthServiceTestHarness struct {
+	Users  *MockUserRepository
+	TwoFA  *MockTwoFARepository
+	OTPs   *MockOTPRepository
+	Tokens *MockTokenRepository
+
+	// JWT is a real manager with TestJWTSecret whose revocation store is an
+	// in-memory Redis, so tokens can be issued, verified and revoked without a
+	// server; jwt.Manager is a concrete type and cannot be mocked
+	JWT *jwt.Manager
+
+	// Redis is the in-memory server behind JWT's revocation store
+	Redis *miniredis.Miniredis
+
+	// Emails records every email the service sends
+	Emails *testutil.Outbox
+
+	// Service is the AuthService under test
+	Service *service.AuthService
+
+	rdb *redis.Client
+}
+
+// Setup creates fresh mocks and a new AuthService wired to them. Optional
+// features can be enabled on Service afterwards with its WithX methods.
+func (h *AuthServiceTestHarness) Setup() {
+	h.Users = &MockUserRepository{}
+	h.TwoFA = &MockTwoFARepository{}
+	h.OTPs = &MockOTPRepository{}
+	h.Tokens = &MockTokenRepository{}
+	h.Emails = &testutil.Outbox{}
+
+	h.Redis = miniredis.NewMiniRedis()
+	if err := h.Redis.Start(); err != nil {
+		panic("mocks: start in-memory redis: " + err.Error())
+	}
+	h.rdb = redis.NewClient(&redis.Options{Addr: h.Redis.Addr()})
+	h.JWT = jwt.NewManager(TestJWTSecret).WithRevocationStore(h.rdb, true)
+
+	emailClient := email.NewClient("localhost", 25, "", "", "noreply@authentio.test", email.WithSender(h.Emails))
+	h.Service = service.NewAuthService(h.Users, h.TwoFA, h.OTPs, h.Tokens, h.JWT, emailClient, nil, nil)
+}
+
+// Close waits for the emails the service sends in the background, then stops
+// the in-memory Redis.
+func (h *AuthServiceTestHarness) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.Service.FlushBackground(ctx)
+
+	h.rdb.Close()
+	h.Redis.Close()
+}
+
+// AssertExpectations fails t unless every call expected with On was made on
+// each of the mock repositories.
+func (h *AuthServiceTestHarness) AssertExpectations(t *testing.T) {
+	t.Helper()
+	mock.AssertExpectationsForObjects(t, h.Users, h.TwoFA, h.OTPs, h.Tokens)
+}
@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ repository.OTPRepository = (*MockOTPRepository)(nil)
+
+// MockOTPRepository is a testify mock of repository.OTPRepository.
+type MockOTPRepository struct {
+	mock.Mock
+}
+
+func (m *MockOTPRepository) CreateOTP(ctx context.Context, otp *models.OTP) error {
+	return m.Called(ctx, otp).Error(0)
+}
+
+func (m *MockOTPRepository) VerifyOTP(ctx context.Context, email, code, otpType string) (bool, error) {
+	args := m.Called(ctx, email, code, otpType)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOTPRepository) FindActiveOTP(ctx context.Context, email, otpType string) (*models.OTP, error) {
+	args := m.Called(ctx, email, otpType)
+	otp, _ := args.Get(0).(*models.OTP)
+	return otp, args.Error(1)
+}
+
+func (m *MockOTPRepository) RecordVerificationAttempt(ctx context.Context, otpID int64) (int, error) {
+	args := m.Called(ctx, otpID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOTPRepository) CleanupExpiredOTPs(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
@@ -0,0 +1,81 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ repository.TokenRepository = (*MockTokenRepository)(nil)
+
+// MockTokenRepository is a testify mock of repository.TokenRepository.
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *MockTokenRepository) GetRefreshToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	args := m.Called(ctx, token)
+	refreshToken, _ := args.Get(0).(*models.RefreshToken)
+	return refreshToken, args.Error(1)
+}
+
+func (m *MockTokenRepository) RotateRefreshToken(ctx context.Context, oldToken string, newToken *models.RefreshToken) error {
+	return m.Called(ctx, oldToken, newToken).Error(0)
+}
+
+func (m *MockTokenRepository) RevokeTokenFamily(ctx context.Context, familyID string) error {
+	return m.Called(ctx, familyID).Error(0)
+}
+
+func (m *MockTokenRepository) DeleteRefreshToken(ctx context.Context, token string) error {
+	return m.Called(ctx, token).Error(0)
+}
+
+func (m *MockTokenRepository) DeleteUserRefreshTokens(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockTokenRepository) DeleteAllRefreshTokens(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *MockTokenRepository) CleanupExpiredTokens(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *MockTokenRepository) SaveSession(ctx context.Context, session *models.Session) error {
+	return m.Called(ctx, session).Error(0)
+}
+
+func (m *MockTokenRepository) ListSessions(ctx context.Context, userID int64) ([]models.Session, error) {
+	args := m.Called(ctx, userID)
+	sessions, _ := args.Get(0).([]models.Session)
+	return sessions, args.Error(1)
+}
+
+func (m *MockTokenRepository) GetSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	args := m.Called(ctx, sessionID)
+	session, _ := args.Get(0).(*models.Session)
+	return session, args.Error(1)
+}
+
+func (m *MockTokenRepository) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	return m.Called(ctx, userID, sessionID).Error(0)
+}
+
+func (m *MockTokenRepository) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	args := m.Called(ctx, sessionID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) ExtendSession(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	return m.Called(ctx, sessionID, expiresAt).Error(0)
+}
@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+
+	"authentio/internal/repository"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ repository.TwoFARepository = (*MockTwoFARepository)(nil)
+
+// MockTwoFARepository is a testify mock of repository.TwoFARepository.
+type MockTwoFARepository struct {
+	mock.Mock
+}
+
+func (m *MockTwoFARepository) EnableEmail2FA(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockTwoFARepository) EnableSMS2FA(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockTwoFARepository) Disable2FA(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockTwoFARepository) Is2FAEnabled(ctx context.Context, userID int64) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFARepository) Get2FAMethod(ctx context.Context, userID int64) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTwoFARepository) VerifyOTP(ctx context.Context, userID int64, email, code, otpType string) (bool, error) {
+	args := m.Called(ctx, userID, email, code, otpType)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTwoFARepository) SaveTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	return m.Called(ctx, userID, secret).Error(0)
+}
+
+func (m *MockTwoFARepository) GetPendingTOTPSecret(ctx context.Context, userID int64) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTwoFARepository) Get2FASecret(ctx context.Context, userID int64) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTwoFARepository) EnableTOTP(ctx context.Context, userID int64, step int64) error {
+	return m.Called(ctx, userID, step).Error(0)
+}
+
+func (m *MockTwoFARepository) RecordTOTPStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
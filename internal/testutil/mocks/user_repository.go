@@ -0,0 +1,109 @@
+package mocks
+
+import (
+	"context"
+
+	"authentio/internal/models"
+	"authentio/internal/repository"
+
+	"github.com/stretchr/testify/mock"
+)
+
+var _ repository.UserRepository = (*MockUserRepository)(nil)
+
+// MockUserRepository is a testify mock of repository.UserRepository.
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) FindByProvider(ctx context.Context, provider, providerID string) (*models.User, error) {
+	args := m.Called(ctx, provider, providerID)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) LinkProvider(ctx context.Context, userID int64, provider, providerID, avatarURL string) error {
+	return m.Called(ctx, userID, provider, providerID, avatarURL).Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, userID int64, hash string) error {
+	return m.Called(ctx, userID, hash).Error(0)
+}
+
+func (m *MockUserRepository) SetPendingEmail(ctx context.Context, userID int64, email string) error {
+	return m.Called(ctx, userID, email).Error(0)
+}
+
+func (m *MockUserRepository) FindPendingEmail(ctx context.Context, userID int64) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserRepository) ConfirmPendingEmail(ctx context.Context, userID int64, email string) (bool, error) {
+	args := m.Called(ctx, userID, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdatePhoneNumber(ctx context.Context, userID int64, phoneNumber string) error {
+	return m.Called(ctx, userID, phoneNumber).Error(0)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, filter repository.UserFilter, cursor *repository.Cursor) (*repository.UserPage, error) {
+	args := m.Called(ctx, filter, cursor)
+	page, _ := args.Get(0).(*repository.UserPage)
+	return page, args.Error(1)
+}
+
+func (m *MockUserRepository) Create(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *MockUserRepository) FindOrCreate(ctx context.Context, user *models.User) (*models.User, bool, error) {
+	args := m.Called(ctx, user)
+	found, _ := args.Get(0).(*models.User)
+	return found, args.Bool(1), args.Error(2)
+}
+
+func (m *MockUserRepository) CreateBatch(ctx context.Context, users []*models.User) error {
+	return m.Called(ctx, users).Error(0)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *MockUserRepository) Patch(ctx context.Context, userID int64, patch models.UserPatch) (*models.User, error) {
+	args := m.Called(ctx, userID, patch)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) Deactivate(ctx context.Context, id int64, reason string) error {
+	return m.Called(ctx, id, reason).Error(0)
+}
+
+func (m *MockUserRepository) Delete(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockUserRepository) ReplaceExternalGroups(ctx context.Context, userID int64, groups []string) ([]string, error) {
+	args := m.Called(ctx, userID, groups)
+	previous, _ := args.Get(0).([]string)
+	return previous, args.Error(1)
+}
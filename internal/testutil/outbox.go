@@ -0,0 +1,49 @@
+package testutil
+
+import (
+	"regexp"
+	"slices"
+	"sync"
+
+	"authentio/pkg/email"
+)
+
+// otpPattern finds the code in the plain-text body of email.Client.SendOTP
+var otpPattern = regexp.MustCompile(`verification code is (\d+)`)
+
+// Outbox is an email.Sender that keeps every message instead of delivering it,
+// so tests can read the codes and links the service emails. Pass it to
+// email.NewClient with email.WithSender.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []email.Message
+}
+
+// Send records msg.
+func (o *Outbox) Send(msg email.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (o *Outbox) Messages() []email.Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return slices.Clone(o.messages)
+}
+
+// LastOTP returns the code of the newest one-time code email sent to address.
+func (o *Outbox) LastOTP(address string) (string, bool) {
+	messages := o.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if !slices.Contains(messages[i].To, address) {
+			continue
+		}
+		if m := otpPattern.FindStringSubmatch(messages[i].Text); m != nil {
+			return m[1], true
+		}
+	}
+	return "", false
+}
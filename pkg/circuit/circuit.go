@@ -0,0 +1,177 @@
+// Package circuit implements a circuit breaker that stops calls to a failing
+// dependency for a while instead of letting every request wait for it.
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the dependency while the breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	// Closed lets every call through and counts consecutive failures
+	Closed State = iota
+	// Open rejects every call with ErrCircuitOpen until the timeout has passed
+	Open
+	// HalfOpen lets calls through to probe the dependency; a failure opens the
+	// breaker again, enough successes close it
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Breaker is a Closed/Open/Half-Open circuit breaker. It opens after
+// failureThreshold consecutive failures, stays open for timeout, and closes
+// again after successThreshold consecutive successes while half-open. It is
+// safe for concurrent use.
+type Breaker struct {
+	name             string
+	failureThreshold int
+	successThreshold int
+	timeout          time.Duration
+
+	mu        sync.Mutex
+	state     State
+	failures  int // consecutive failures while closed
+	successes int // consecutive successes while half-open
+	openedAt  time.Time
+}
+
+// New creates a closed Breaker. Thresholds below 1 are treated as 1.
+func New(name string, failureThreshold, successThreshold int, timeout time.Duration) *Breaker {
+	return &Breaker{
+		name:             name,
+		failureThreshold: max(failureThreshold, 1),
+		successThreshold: max(successThreshold, 1),
+		timeout:          timeout,
+	}
+}
+
+// Name returns the name the breaker was created with, e.g. "oauth_google".
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state. An open breaker whose timeout has passed
+// reports HalfOpen, since the next call will be let through.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState(time.Now())
+}
+
+// Allow reports whether a call may proceed, returning ErrCircuitOpen if not.
+// Every allowed call must be followed by Record with its outcome.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.currentState(time.Now())
+	if state == Open {
+		return ErrCircuitOpen
+	}
+	if state == HalfOpen && b.state == Open {
+		b.state = HalfOpen
+		b.successes = 0
+	}
+	return nil
+}
+
+// Record counts the outcome of a call that Allow let through.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.open()
+		}
+	case HalfOpen:
+		if !success {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.successThreshold {
+			b.state = Closed
+			b.failures = 0
+		}
+	}
+}
+
+// Do runs fn unless the breaker is open, and records whether it returned an error.
+func (b *Breaker) Do(fn func() error) error {
+	if err := b.Allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.Record(err == nil)
+	return err
+}
+
+// currentState returns the state as of now; b.mu must be held.
+func (b *Breaker) currentState(now time.Time) State {
+	if b.state == Open && now.Sub(b.openedAt) >= b.timeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// open trips the breaker; b.mu must be held.
+func (b *Breaker) open() {
+	b.state = Open
+	b.openedAt = time.Now()
+	b.failures = 0
+	b.successes = 0
+}
+
+// =============================================================================
+// HTTP
+// =============================================================================
+
+// Transport is an http.RoundTripper that sends requests through a Breaker.
+// Transport errors and 5xx responses count as failures; other responses,
+// including 4xx, mean the dependency is up.
+type Transport struct {
+	Breaker *Breaker
+	Base    http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.Breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	t.Breaker.Record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
@@ -0,0 +1,88 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length in bytes of an AES-256 key
+const KeySize = 32
+
+// ErrCiphertextTooShort is returned when a ciphertext is shorter than the GCM nonce
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// Encrypt seals plaintext with AES-256-GCM. The random nonce is prepended to the ciphertext.
+func Encrypt(plaintext []byte, key [KeySize]byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt.
+func Decrypt(ciphertext []byte, key [KeySize]byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, ErrCiphertextTooShort
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// EncryptString encrypts a string and returns it base64 encoded, ready for a TEXT column.
+func EncryptString(plaintext string, key [KeySize]byte) (string, error) {
+	sealed, err := Encrypt([]byte(plaintext), key)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString reverses EncryptString.
+func DecryptString(encoded string, key [KeySize]byte) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("decode ciphertext: %w", err)
+	}
+	plaintext, err := Decrypt(sealed, key)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// ParseKey decodes a base64-encoded 32-byte key.
+func ParseKey(encoded string) ([KeySize]byte, error) {
+	var key [KeySize]byte
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return key, fmt.Errorf("decode key: %w", err)
+	}
+	if len(raw) != KeySize {
+		return key, fmt.Errorf("key must be %d bytes, got %d", KeySize, len(raw))
+	}
+	copy(key[:], raw)
+	return key, nil
+}
+
+func newGCM(key [KeySize]byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
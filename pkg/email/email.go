@@ -1,14 +1,22 @@
 package email
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"html"
+	"html/template"
 	"net"
 	"net/smtp"
 	"strconv"
-	"strings"
+	"time"
 
 	"authentio/pkg/logger"
+	"authentio/pkg/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client is a simple SMTP client used to send transactional emails (OTP, password reset, etc.)
@@ -18,58 +26,199 @@ type Client struct {
 	Username string
 	Password string
 	From     string // optional From address; if empty Username will be used
+	TLS      bool   // implicit TLS (SMTPS); port 465 always uses it
+
+	// failover lists the servers tried in order when Host cannot deliver (see WithFailover).
+	failover []SMTPConfig
+
+	// RetryPolicy enables retries of transient connection failures; nil sends once.
+	RetryPolicy *RetryPolicy
+
+	// templates holds HTML templates loaded via LoadTemplates; nil means inline bodies are used.
+	templates *template.Template
+
+	// outbox, when set, receives rendered messages instead of the SMTP server (see Queued).
+	outbox Outbox
+
+	// tracer creates a span per Send; nil disables tracing (see WithTracerProvider).
+	tracer trace.Tracer
+
+	// suppressions, when set, removes suppressed recipients before sending (see Suppressed).
+	suppressions SuppressionList
+
+	// sender, when set, delivers messages instead of the SMTP servers (see WithSender).
+	sender Sender
 }
 
-// NewClient constructs a new email client.
-func NewClient(host string, port int, username, password, from string) *Client {
-	return &Client{
+// SuppressionList holds addresses that must not receive email, e.g. because
+// mail to them bounced or their owner unsubscribed.
+type SuppressionList interface {
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+}
+
+// Outbox accepts rendered messages for asynchronous delivery, e.g. a job queue.
+type Outbox interface {
+	EnqueueEmail(ctx context.Context, msg Message) error
+}
+
+// NewClient constructs a new email client. Options such as WithRetryPolicy are applied in order.
+func NewClient(host string, port int, username, password, from string, opts ...ClientOption) *Client {
+	c := &Client{
 		Host:     host,
 		Port:     port,
 		Username: username,
 		Password: password,
 		From:     from,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Queued returns a copy of the client that hands every message to outbox instead
+// of sending it. Templates are still rendered by the copy, so the outbox only
+// receives finished messages. The original client keeps sending directly.
+func (c *Client) Queued(outbox Outbox) *Client {
+	queued := *c
+	queued.outbox = outbox
+	return &queued
+}
+
+// Suppressed returns a copy of the client that drops recipients on list before
+// sending, and skips the message when none are left. If the list cannot be
+// checked the recipient is kept, so an outage of the list does not stop
+// password resets and OTP codes.
+func (c *Client) Suppressed(list SuppressionList) *Client {
+	suppressed := *c
+	suppressed.suppressions = list
+	return &suppressed
+}
+
+// WithTracerProvider records a span for every Send, as a child of the span in the
+// caller's context.
+func WithTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(c *Client) {
+		c.tracer = tracing.Tracer(tp, "authentio/pkg/email")
+	}
 }
 
 // Send sends an email to one or more recipients. The body may contain HTML.
-func (c *Client) Send(to []string, subject, body string) error {
-	if len(to) == 0 {
+// It is SendMessage with an HTML body and no plain-text part.
+func (c *Client) Send(ctx context.Context, to []string, subject, body string) error {
+	return c.SendMessage(ctx, Message{To: to, Subject: subject, HTML: body})
+}
+
+// SendMessage sends msg to its recipients, as multipart/alternative when it has
+// both an HTML and a plain-text body. Connection-level failures are retried
+// according to the client's RetryPolicy, after which the servers added with
+// WithFailover are tried in order. A queued client (see Queued) enqueues the
+// message instead, and a client with a Sender (see WithSender) delivers through it.
+func (c *Client) SendMessage(ctx context.Context, msg Message) (err error) {
+	if len(msg.To) == 0 {
 		return fmt.Errorf("no recipients specified")
 	}
 
+	if c.tracer != nil {
+		var span trace.Span
+		ctx, span = c.tracer.Start(ctx, "email.Send", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.Int("email.recipients", len(msg.To)),
+			attribute.Bool("email.queued", c.outbox != nil),
+		))
+		defer func() {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}()
+	}
+
+	if c.suppressions != nil {
+		if msg.To = c.unsuppressed(ctx, msg.To); len(msg.To) == 0 {
+			logger.Warn("email not sent, all recipients are suppressed", "subject", msg.Subject)
+			return nil
+		}
+	}
+
+	if c.outbox != nil {
+		return c.outbox.EnqueueEmail(ctx, msg)
+	}
+
+	if c.sender != nil {
+		return c.sendWithRetry(msg)
+	}
+	return NewSMTPSender(c).Send(msg)
+}
+
+// unsuppressed returns the recipients that are not on the suppression list.
+func (c *Client) unsuppressed(ctx context.Context, to []string) []string {
+	kept := make([]string, 0, len(to))
+	for _, addr := range to {
+		suppressed, err := c.suppressions.IsSuppressed(ctx, addr)
+		if err != nil {
+			logger.Warn("failed to check email suppression list, sending anyway", "error", err)
+		}
+		if suppressed {
+			logger.Warn("skipping suppressed email recipient", "email", addr)
+			continue
+		}
+		kept = append(kept, addr)
+	}
+	return kept
+}
+
+// sendWithRetry delivers through the client's Sender or, without one, its own
+// SMTP server, retrying connection-level failures according to its RetryPolicy.
+func (c *Client) sendWithRetry(msg Message) (err error) {
+	send := c.send
+	if c.sender != nil {
+		send = c.sender.Send
+	}
+
+	attempts := c.RetryPolicy.attempts()
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = send(msg); err == nil {
+			return nil
+		}
+		if attempt == attempts || !isRetryable(err) {
+			break
+		}
+
+		wait := c.RetryPolicy.backoff(attempt)
+		logger.Warn("email send failed, retrying", "error", err, "attempt", attempt, "backoff", wait.String())
+		time.Sleep(wait)
+	}
+	return err
+}
+
+// send performs a single delivery attempt.
+func (c *Client) send(msg Message) error {
+
 	from := c.From
 	if from == "" {
 		from = c.Username
 	}
 
-	// Build message with basic MIME headers (HTML)
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = strings.Join(to, ",")
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"utf-8\""
-
-	var msg strings.Builder
-	for k, v := range headers {
-		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
+	// Build message with MIME headers (HTML, plain text, or both as alternatives)
+	data, err := mimeMessage(from, msg)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
 	}
-	msg.WriteString("\r\n")
-	msg.WriteString(body)
 
 	addr := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
 
 	auth := smtp.PlainAuth("", c.Username, c.Password, c.Host)
 
-	// Use direct TLS for port 465, otherwise try SendMail which will typically use STARTTLS on 587
-	if c.Port == 465 {
-		return c.sendUsingTLS(addr, auth, from, to, []byte(msg.String()))
+	// Use direct TLS for SMTPS servers and port 465, otherwise try SendMail which will typically use STARTTLS on 587
+	if c.TLS || c.Port == 465 {
+		return c.sendUsingTLS(addr, auth, from, msg.To, data)
 	}
 
 	// Try standard SendMail (works for servers advertising STARTTLS)
-	if err := smtp.SendMail(addr, auth, from, to, []byte(msg.String())); err != nil {
+	if err := smtp.SendMail(addr, auth, from, msg.To, data); err != nil {
 		logger.Warn("smtp.SendMail failed, falling back to direct TLS", "error", err)
-		return c.sendUsingTLS(addr, auth, from, to, []byte(msg.String()))
+		return c.sendUsingTLS(addr, auth, from, msg.To, data)
 	}
 	return nil
 }
@@ -132,15 +281,61 @@ func (c *Client) sendUsingTLS(addr string, auth smtp.Auth, from string, to []str
 }
 
 // SendOTP is a convenience helper that formats and sends an OTP email.
-func (c *Client) SendOTP(to string, code string) error {
-	subject := "Your verification code"
-	body := fmt.Sprintf(`<p>Your verification code is <strong>%s</strong>. It will expire in 10 minutes.</p>`, code)
-	return c.Send([]string{to}, subject, body)
+// It renders the otp.html template when loaded, and an inline body otherwise;
+// either way the message has a plain-text alternative.
+func (c *Client) SendOTP(ctx context.Context, to string, code string) error {
+	msg := Message{
+		To:   []string{to},
+		Text: fmt.Sprintf("Your verification code is %s. It will expire in 10 minutes.", code),
+	}
+	if c.hasTemplate(TemplateOTP) {
+		return c.sendTemplate(ctx, msg, TemplateOTP, OTPTemplateData{Code: code, ExpiresInMinutes: 10})
+	}
+
+	msg.Subject = "Your verification code"
+	msg.HTML = fmt.Sprintf(`<p>Your verification code is <strong>%s</strong>. It will expire in 10 minutes.</p>`, code)
+	return c.SendMessage(ctx, msg)
 }
 
 // SendPasswordReset sends a password reset email with a provided code or link.
-func (c *Client) SendPasswordReset(to string, codeOrLink string) error {
-	subject := "Password reset request"
-	body := fmt.Sprintf(`<p>We received a request to reset your password. Use the code below or click the link:</p><p><strong>%s</strong></p>`, codeOrLink)
-	return c.Send([]string{to}, subject, body)
+// It renders the password_reset.html template when loaded, and an inline body
+// otherwise; either way the message has a plain-text alternative.
+func (c *Client) SendPasswordReset(ctx context.Context, to string, codeOrLink string) error {
+	msg := Message{
+		To:   []string{to},
+		Text: fmt.Sprintf("We received a request to reset your password. Use the code below or open the link:\n\n%s\n", codeOrLink),
+	}
+	if c.hasTemplate(TemplatePasswordReset) {
+		return c.sendTemplate(ctx, msg, TemplatePasswordReset, PasswordResetTemplateData{CodeOrLink: codeOrLink})
+	}
+
+	msg.Subject = "Password reset request"
+	msg.HTML = fmt.Sprintf(`<p>We received a request to reset your password. Use the code below or click the link:</p><p><strong>%s</strong></p>`, codeOrLink)
+	return c.SendMessage(ctx, msg)
+}
+
+// SendEmailVerification sends a link the user must open to confirm their email address.
+// It renders the email_verification.html template when loaded, and an inline body otherwise.
+func (c *Client) SendEmailVerification(ctx context.Context, to string, link string, expiresInHours int) error {
+	if c.hasTemplate(TemplateEmailVerification) {
+		return c.SendTemplate(ctx, []string{to}, TemplateEmailVerification, EmailVerificationTemplateData{Link: link, ExpiresInHours: expiresInHours})
+	}
+
+	subject := "Verify your email address"
+	body := fmt.Sprintf(`<p>Please confirm your email address by clicking the link below. It will expire in %d hours.</p><p><a href="%s">%s</a></p>`,
+		expiresInHours, html.EscapeString(link), html.EscapeString(link))
+	return c.Send(ctx, []string{to}, subject, body)
+}
+
+// SendMagicLink sends a one-time link that signs the user in without a password.
+// It renders the magic_link.html template when loaded, and an inline body otherwise.
+func (c *Client) SendMagicLink(ctx context.Context, to string, link string, expiresInMinutes int) error {
+	if c.hasTemplate(TemplateMagicLink) {
+		return c.SendTemplate(ctx, []string{to}, TemplateMagicLink, MagicLinkTemplateData{Link: link, ExpiresInMinutes: expiresInMinutes})
+	}
+
+	subject := "Your sign-in link"
+	body := fmt.Sprintf(`<p>Click the link below to sign in. It will expire in %d minutes and can only be used once.</p><p><a href="%s">%s</a></p>`,
+		expiresInMinutes, html.EscapeString(link), html.EscapeString(link))
+	return c.Send(ctx, []string{to}, subject, body)
 }
@@ -0,0 +1,40 @@
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+// MailgunSender is a Sender that delivers through the Mailgun Messages API.
+type MailgunSender struct {
+	From string
+	mg   *mailgun.MailgunImpl
+}
+
+// NewMailgunSender creates a MailgunSender sending as from through domain.
+// apiBase selects the region, e.g. mailgun.APIBaseEU; empty uses the US API.
+func NewMailgunSender(domain, apiKey, apiBase, from string) *MailgunSender {
+	mg := mailgun.NewMailgun(domain, apiKey)
+	if apiBase != "" {
+		mg.SetAPIBase(apiBase)
+	}
+	return &MailgunSender{From: from, mg: mg}
+}
+
+// Send delivers one message addressed to every recipient.
+func (s *MailgunSender) Send(msg Message) error {
+	message := mailgun.NewMessage(s.From, msg.Subject, msg.Text, msg.To...)
+	if msg.HTML != "" {
+		message.SetHtml(msg.HTML)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	if _, _, err := s.mg.Send(ctx, message); err != nil {
+		return fmt.Errorf("mailgun send failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// Message is an email with an HTML body, a plain-text body, or both. With both,
+// it is sent as multipart/alternative, so clients that do not render HTML (and
+// spam filters that prefer a text part) get the plain-text version.
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// mimeMessage renders msg as an RFC 5322 message from the given sender: a
+// single text/html or text/plain part, or a multipart/alternative body with the
+// plain-text part first, as RFC 2046 orders alternatives by increasing fidelity.
+func mimeMessage(from string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ","))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.Text == "" || msg.HTML == "" {
+		contentType, body := `text/html; charset="utf-8"`, msg.HTML
+		if msg.HTML == "" {
+			contentType, body = `text/plain; charset="utf-8"`, msg.Text
+		}
+		fmt.Fprintf(&buf, "Content-Type: %s\r\n\r\n", contentType)
+		buf.WriteString(body)
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	if err := writePart(parts, `text/plain; charset="utf-8"`, msg.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(parts, `text/html; charset="utf-8"`, msg.HTML); err != nil {
+		return nil, err
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writePart adds a quoted-printable encoded part, which keeps long HTML lines
+// within the line length limit of SMTP.
+func writePart(parts *multipart.Writer, contentType, body string) error {
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,87 @@
+package email
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how Send retries transient (connection-level) failures.
+// Permanent SMTP responses such as 5xx rejections are never retried.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first; values below 2 disable retries
+	InitialBackoff time.Duration // wait before the second attempt
+	MaxBackoff     time.Duration // upper bound for any single wait; zero means unbounded
+	Multiplier     float64       // growth factor applied to the backoff after each attempt
+}
+
+// ClientOption customises a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithRetryPolicy makes Send retry connection-level errors up to maxAttempts times,
+// doubling the wait from initialBackoff up to maxBackoff between attempts.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.RetryPolicy = &RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+			Multiplier:     2,
+		}
+	}
+}
+
+// attempts returns the number of delivery attempts allowed by the policy.
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// backoff returns the wait before the given retry (1 for the first retry).
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := p.InitialBackoff
+	for i := 1; i < retry; i++ {
+		wait = time.Duration(float64(wait) * multiplier)
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return wait
+}
+
+// isRetryable reports whether err is a connection-level failure worth retrying.
+// Any reply from the SMTP server itself (e.g. "550 mailbox unavailable") is treated as final.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
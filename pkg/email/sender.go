@@ -0,0 +1,40 @@
+package email
+
+// Sender delivers a rendered message. A Client sends through an
+// SMTPSender for its own SMTP servers unless WithSender sets another, e.g. a
+// SendGridSender or MailgunSender for providers reached over their HTTP APIs.
+// The Client still renders templates, drops suppressed recipients, queues
+// messages and retries connection-level failures around whichever Sender it uses.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// WithSender makes the client deliver through sender instead of SMTP. The
+// client's SMTP settings and failover servers are then unused.
+func WithSender(sender Sender) ClientOption {
+	return func(c *Client) {
+		c.sender = sender
+	}
+}
+
+// SMTPSender delivers through the SMTP servers of a Client: its own server,
+// retried according to its RetryPolicy, then the servers added with WithFailover.
+type SMTPSender struct {
+	client *Client
+}
+
+// NewSMTPSender returns the Sender for the SMTP servers of c.
+func NewSMTPSender(c *Client) *SMTPSender {
+	return &SMTPSender{client: c}
+}
+
+// Send delivers the message through the first SMTP server that accepts it.
+func (s *SMTPSender) Send(msg Message) error {
+	c := s.client
+	if len(c.failover) > 0 {
+		return c.relays().each(func(relay *Client) error {
+			return relay.sendWithRetry(msg)
+		})
+	}
+	return c.sendWithRetry(msg)
+}
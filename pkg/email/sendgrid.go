@@ -1,7 +1,10 @@
 package email
 
 import (
+	"context"
 	"fmt"
+	netmail "net/mail"
+	"time"
 	
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -46,4 +49,54 @@ func (c *SendGridClient) SendPasswordReset(toEmail, code string) error {
 	subject := "Password reset request"
 	htmlContent := fmt.Sprintf(`<p>We received a request to reset your password. Use the code below:</p><p><strong>%s</strong></p>`, code)
 	return c.Send(toEmail, "", subject, htmlContent)
-}
\ No newline at end of file
+}
+// providerTimeout bounds one API call of a SendGridSender or MailgunSender
+const providerTimeout = 10 * time.Second
+
+// SendGridSender is a Sender that delivers through the SendGrid v3 Mail Send API.
+type SendGridSender struct {
+	APIKey string
+	From   *mail.Email
+}
+
+// NewSendGridSender creates a SendGridSender sending as from, an address or
+// "Name <address>".
+func NewSendGridSender(apiKey, from string) *SendGridSender {
+	sender := &SendGridSender{APIKey: apiKey, From: mail.NewEmail("", from)}
+	if addr, err := netmail.ParseAddress(from); err == nil {
+		sender.From = mail.NewEmail(addr.Name, addr.Address)
+	}
+	return sender
+}
+
+// Send delivers one message addressed to every recipient. SendGrid requires
+// the text/plain content to come before text/html.
+func (s *SendGridSender) Send(msg Message) error {
+	personalization := mail.NewPersonalization()
+	for _, addr := range msg.To {
+		personalization.AddTos(mail.NewEmail("", addr))
+	}
+	message := mail.NewV3Mail().
+		SetFrom(s.From).
+		AddPersonalizations(personalization)
+	if msg.Text != "" {
+		message.AddContent(mail.NewContent("text/plain", msg.Text))
+	}
+	if msg.HTML != "" {
+		message.AddContent(mail.NewContent("text/html", msg.HTML))
+	}
+	message.Subject = msg.Subject
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	// A Client holds the request body, so it is not shared between sends
+	response, err := sendgrid.NewSendClient(s.APIKey).SendWithContext(ctx, message)
+	if err != nil {
+		return fmt.Errorf("sendgrid send failed: %w", err)
+	}
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("sendgrid error: %d - %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Names of the templates used by the built-in helpers. When a template with one of
+// these names is loaded, the corresponding helper renders it instead of its inline body.
+const (
+	TemplateOTP               = "otp.html"
+	TemplatePasswordReset     = "password_reset.html"
+	TemplateEmailVerification = "email_verification.html"
+	TemplateMagicLink         = "magic_link.html"
+)
+
+// defaultSubjects are used when a template has no <title> element.
+var defaultSubjects = map[string]string{
+	TemplateOTP:               "Your verification code",
+	TemplatePasswordReset:     "Password reset request",
+	TemplateEmailVerification: "Verify your email address",
+	TemplateMagicLink:         "Your sign-in link",
+}
+
+// titlePattern extracts the subject line from a rendered template's <title> element.
+var titlePattern = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+
+// OTPTemplateData is passed to the otp.html template.
+type OTPTemplateData struct {
+	Code             string
+	ExpiresInMinutes int
+}
+
+// PasswordResetTemplateData is passed to the password_reset.html template.
+type PasswordResetTemplateData struct {
+	CodeOrLink string
+}
+
+// EmailVerificationTemplateData is passed to the email_verification.html template.
+type EmailVerificationTemplateData struct {
+	Link           string
+	ExpiresInHours int
+}
+
+// MagicLinkTemplateData is passed to the magic_link.html template.
+type MagicLinkTemplateData struct {
+	Link             string
+	ExpiresInMinutes int
+}
+
+// LoadTemplates parses every *.html file in dir. Each template is addressed by its
+// file name (e.g. "otp.html"). Calling it again replaces the previously loaded set.
+func (c *Client) LoadTemplates(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return fmt.Errorf("list templates: %w", err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no *.html templates found in %s", dir)
+	}
+
+	tmpl, err := template.ParseFiles(files...)
+	if err != nil {
+		return fmt.Errorf("parse templates: %w", err)
+	}
+
+	c.templates = tmpl
+	return nil
+}
+
+// SendTemplate renders the named template with data and sends the result as the email body.
+// The subject is taken from the template's <title> element, falling back to the default
+// subject of the built-in templates.
+func (c *Client) SendTemplate(ctx context.Context, to []string, templateName string, data any) error {
+	return c.sendTemplate(ctx, Message{To: to}, templateName, data)
+}
+
+// sendTemplate renders the named template as the HTML body and subject of msg,
+// which may carry a plain-text body, and sends it.
+func (c *Client) sendTemplate(ctx context.Context, msg Message, templateName string, data any) error {
+	body, err := c.renderTemplate(templateName, data)
+	if err != nil {
+		return err
+	}
+	msg.Subject, msg.HTML = subjectFor(templateName, body), body
+	return c.SendMessage(ctx, msg)
+}
+
+// subjectFor derives the subject line for a rendered template.
+func subjectFor(templateName, body string) string {
+	if m := titlePattern.FindStringSubmatch(body); m != nil {
+		if title := strings.TrimSpace(html.UnescapeString(m[1])); title != "" {
+			return title
+		}
+	}
+	if subject, ok := defaultSubjects[templateName]; ok {
+		return subject
+	}
+	return "Authentio"
+}
+
+// hasTemplate reports whether a template with the given name has been loaded.
+func (c *Client) hasTemplate(name string) bool {
+	return c.templates != nil && c.templates.Lookup(name) != nil
+}
+
+// renderTemplate executes the named template into a string.
+func (c *Client) renderTemplate(name string, data any) (string, error) {
+	if !c.hasTemplate(name) {
+		return "", fmt.Errorf("email template %q not loaded", name)
+	}
+
+	var buf bytes.Buffer
+	if err := c.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("render template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
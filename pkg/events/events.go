@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Type identifies the kind of an auth event
+type Type string
+
+const (
+	UserRegistered  Type = "user_registered"
+	UserLoggedIn    Type = "user_logged_in"
+	PasswordChanged Type = "password_changed"
+	TwoFAEnabled    Type = "two_fa_enabled"
+)
+
+// Types lists every event type, e.g. to validate webhook subscriptions.
+var Types = []Type{UserRegistered, UserLoggedIn, PasswordChanged, TwoFAEnabled}
+
+// Event is published as a JSON message when something happens to an account.
+type Event struct {
+	Type       Type           `json:"type"`
+	UserID     int64          `json:"user_id"`
+	TenantID   *int64         `json:"tenant_id,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// Topics maps event types to the topic (NATS subject) they are published to.
+// Events of types without a topic are dropped.
+type Topics map[Type]string
+
+// Publisher sends events to a message broker.
+//
+// Publish is fire-and-forget: it must not block the caller on the broker, and
+// delivery errors are logged rather than returned, so auth operations never fail
+// or slow down because the broker is unavailable.
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+
+	// Close flushes pending messages and releases the connection
+	Close() error
+}
+
+// Multi is a Publisher that hands every event to each of its publishers in turn.
+type Multi []Publisher
+
+func (m Multi) Publish(ctx context.Context, event Event) {
+	for _, p := range m {
+		p.Publish(ctx, event)
+	}
+}
+
+// Close closes every publisher and returns their errors joined.
+func (m Multi) Close() error {
+	var errs []error
+	for _, p := range m {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Noop is a Publisher that discards every event. It is used when no broker is configured.
+type Noop struct{}
+
+func (Noop) Publish(context.Context, Event) {}
+
+func (Noop) Close() error { return nil }
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authentio/pkg/logger"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events as JSON messages to NATS subjects.
+//
+// nats.Conn.Publish only appends to the connection's write buffer (and to its
+// reconnect buffer while disconnected), so Publish returns without waiting for
+// the server. Errors reported later by the connection are logged asynchronously.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	topics Topics
+}
+
+// NewNATSPublisher connects to the NATS server(s) at url (comma-separated for a
+// cluster). The connection reconnects indefinitely, buffering messages meanwhile.
+func NewNATSPublisher(url string, topics Topics) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("authentio"),
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(2*time.Second),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("disconnected from NATS", "error", err)
+			}
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info("reconnected to NATS", "url", c.ConnectedUrl())
+		}),
+		nats.ErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) {
+			logger.Error("NATS error", "error", err)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSPublisher{conn: conn, topics: topics}, nil
+}
+
+// Publish encodes event as JSON and publishes it to the topic of its type.
+func (p *NATSPublisher) Publish(_ context.Context, event Event) {
+	subject, ok := p.topics[event.Type]
+	if !ok || subject == "" {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("failed to encode event", "error", err, "type", event.Type)
+		return
+	}
+	if err := p.conn.Publish(subject, data); err != nil {
+		logger.Warn("failed to publish event", "error", err, "type", event.Type, "subject", subject)
+	}
+}
+
+// Close flushes buffered messages and closes the connection.
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"authentio/pkg/logger"
+)
+
+// Webhook deliveries: each event is POSTed as JSON to every subscribed URL,
+// signed with the webhook's secret. A delivery is tried webhookAttempts times,
+// waiting webhookBackoff, then twice as long, between attempts.
+const (
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+	EventHeader     = "X-Webhook-Event"     // the event type
+
+	webhookAttempts  = 3
+	webhookBackoff   = time.Second
+	webhookTimeout   = 10 * time.Second
+	webhookQueueSize = 1024
+)
+
+// Webhook is a callback URL that receives events.
+type Webhook struct {
+	ID     int64
+	URL    string
+	Secret string
+}
+
+// WebhookResolver returns the webhooks subscribed to an event.
+type WebhookResolver interface {
+	WebhooksFor(ctx context.Context, event Event) ([]Webhook, error)
+}
+
+// WebhookPublisher delivers events to the webhooks its resolver returns.
+// Publish only queues the event; a pool of workers looks up the webhooks and
+// delivers, so slow or failing endpoints never hold up an auth operation.
+// When the queue is full, events are dropped with a warning.
+type WebhookPublisher struct {
+	resolver WebhookResolver
+	client   *http.Client
+
+	mu     sync.RWMutex // guards closed against Publish racing Close
+	closed bool
+	queue  chan Event
+	wg     sync.WaitGroup
+}
+
+// NewWebhookPublisher starts workers delivery goroutines (at least one).
+func NewWebhookPublisher(resolver WebhookResolver, workers int) *WebhookPublisher {
+	p := &WebhookPublisher{
+		resolver: resolver,
+		client:   &http.Client{Timeout: webhookTimeout},
+		queue:    make(chan Event, webhookQueueSize),
+	}
+	for i := 0; i < max(workers, 1); i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// Publish queues event for delivery.
+func (p *WebhookPublisher) Publish(_ context.Context, event Event) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.queue <- event:
+	default:
+		logger.Warn("webhook queue full, dropping event", "type", event.Type, "user_id", event.UserID)
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are delivered.
+func (p *WebhookPublisher) Close() error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return nil
+}
+
+func (p *WebhookPublisher) work() {
+	defer p.wg.Done()
+	for event := range p.queue {
+		p.dispatch(event)
+	}
+}
+
+// dispatch delivers event to every webhook subscribed to it, one after another.
+func (p *WebhookPublisher) dispatch(event Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	webhooks, err := p.resolver.WebhooksFor(ctx, event)
+	cancel()
+	if err != nil {
+		logger.Error("failed to look up webhooks", "error", err, "type", event.Type)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("failed to encode event", "error", err, "type", event.Type)
+		return
+	}
+	for _, webhook := range webhooks {
+		if err := p.deliver(webhook, event.Type, body); err != nil {
+			logger.Warn("webhook delivery failed", "error", err, "webhook_id", webhook.ID, "type", event.Type)
+		}
+	}
+}
+
+// deliver POSTs body to webhook, retrying transport errors, 429 and 5xx
+// responses with exponential backoff.
+func (p *WebhookPublisher) deliver(webhook Webhook, eventType Type, body []byte) error {
+	signature := Sign(webhook.Secret, body)
+
+	var err error
+	backoff := webhookBackoff
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		var retry bool
+		if retry, err = p.post(webhook.URL, eventType, signature, body); err == nil || !retry {
+			return err
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", webhookAttempts, err)
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying.
+func (p *WebhookPublisher) post(url string, eventType Type, signature string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "authentio-webhooks")
+	req.Header.Set(EventHeader, string(eventType))
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // lets the connection be reused
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("POST %s: unexpected status %d", url, resp.StatusCode)
+}
+
+// Sign returns the X-Webhook-Signature value for body: "sha256=" followed by
+// the hex-encoded HMAC-SHA256 of body with secret. Receivers recompute it over
+// the raw request body and compare in constant time.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
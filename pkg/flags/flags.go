@@ -0,0 +1,230 @@
+// Package flags stores per-user feature flags (e.g. beta access) in Redis.
+//
+// Each flag is its own key, so it can expire on its own TTL, and a per-user
+// set indexes the flag names so List does not have to scan the keyspace:
+//
+//	feature_flags:<userID>          set of flag names
+//	feature_flag:<userID>:<flag>    "1" (enabled) or "0" (disabled)
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"authentio/pkg/jwt"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Store
+// =============================================================================
+
+const (
+	indexKeyPrefix = "feature_flags:"
+	flagKeyPrefix  = "feature_flag:"
+)
+
+// ClaimName is the access token claim listing the user's enabled flags
+const ClaimName = "feature_flags"
+
+// maxCachedUsers is the cache size above which expired entries are swept
+const maxCachedUsers = 10000
+
+// claimLookupTimeout bounds the Redis lookup made while a token is generated
+const claimLookupTimeout = 500 * time.Millisecond
+
+// ErrInvalidFlag is returned for flag names that are not 1-64 lowercase
+// letters, digits, '.', '_' or '-'
+var ErrInvalidFlag = errors.New("invalid feature flag name")
+
+var flagName = regexp.MustCompile(`^[a-z0-9._-]{1,64}$`)
+
+// Store reads and writes per-user feature flags.
+type Store struct {
+	rdb *redis.Client
+
+	// cacheTTL keeps List results in memory; zero disables the cache (see WithCacheTTL)
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedFlags
+}
+
+// cachedFlags is a List result and when it stops being served
+type cachedFlags struct {
+	flags   map[string]bool
+	expires time.Time
+}
+
+// NewStore creates a Store backed by rdb.
+func NewStore(rdb *redis.Client) *Store {
+	return &Store{rdb: rdb, cache: make(map[string]cachedFlags)}
+}
+
+// WithCacheTTL serves List results from memory for up to ttl. Set clears the
+// cached flags of the user on this instance; other instances see the change
+// once their copy expires. The store is returned to allow chaining.
+func (s *Store) WithCacheTTL(ttl time.Duration) *Store {
+	s.cacheTTL = ttl
+	return s
+}
+
+// Set enables or disables flag for the user. The flag is removed after ttl;
+// zero keeps it until it is set again.
+func (s *Store) Set(ctx context.Context, userID, flag string, enabled bool, ttl time.Duration) error {
+	if !flagName.MatchString(flag) {
+		return ErrInvalidFlag
+	}
+
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Set(ctx, flagKey(userID, flag), value, ttl)
+	pipe.SAdd(ctx, indexKeyPrefix+userID, flag)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to set feature flag: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.cache, userID)
+	s.mu.Unlock()
+	return nil
+}
+
+// Get reports whether flag is enabled for the user. Flags that were never set
+// or have expired are disabled.
+func (s *Store) Get(ctx context.Context, userID, flag string) (bool, error) {
+	value, err := s.rdb.Get(ctx, flagKey(userID, flag)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get feature flag: %w", err)
+	}
+	return value == "1", nil
+}
+
+// List returns every flag set for the user, enabled or not. Expired flags are
+// left out and dropped from the user's index.
+func (s *Store) List(ctx context.Context, userID string) (map[string]bool, error) {
+	if cached, ok := s.cached(userID); ok {
+		return maps.Clone(cached), nil
+	}
+
+	names, err := s.rdb.SMembers(ctx, indexKeyPrefix+userID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	flags := make(map[string]bool, len(names))
+	if len(names) > 0 {
+		keys := make([]string, len(names))
+		for i, name := range names {
+			keys[i] = flagKey(userID, name)
+		}
+		values, err := s.rdb.MGet(ctx, keys...).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list feature flags: %w", err)
+		}
+
+		var expired []any
+		for i, value := range values {
+			if value == nil {
+				expired = append(expired, names[i])
+				continue
+			}
+			flags[names[i]] = value == "1"
+		}
+		if len(expired) > 0 {
+			if err := s.rdb.SRem(ctx, indexKeyPrefix+userID, expired...).Err(); err != nil {
+				logger.Warn("failed to prune expired feature flags", "error", err, "userID", userID)
+			}
+		}
+	}
+
+	if s.cacheTTL > 0 {
+		s.store(userID, flags)
+	}
+	return flags, nil
+}
+
+// ClaimTransformer embeds the user's enabled flags, sorted, in the ClaimName
+// claim of new access tokens. The claim is a snapshot: changes show up in the
+// next token. When Redis cannot be reached the claim is left out rather than
+// failing the login.
+func (s *Store) ClaimTransformer() jwt.ClaimTransformer {
+	return func(userID string, _ jwt.Claims) (jwt.Claims, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), claimLookupTimeout)
+		defer cancel()
+
+		flags, err := s.List(ctx, userID)
+		if err != nil {
+			logger.Warn("feature flags left out of token", "error", err, "userID", userID)
+			return nil, nil
+		}
+
+		enabled := make([]string, 0, len(flags))
+		for name, on := range flags {
+			if on {
+				enabled = append(enabled, name)
+			}
+		}
+		if len(enabled) == 0 {
+			return nil, nil
+		}
+		sort.Strings(enabled)
+		return jwt.Claims{ClaimName: enabled}, nil
+	}
+}
+
+// cached returns the user's cached flags if they have not expired.
+func (s *Store) cached(userID string) (map[string]bool, bool) {
+	if s.cacheTTL <= 0 {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[userID]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.cache, userID)
+		return nil, false
+	}
+	return entry.flags, true
+}
+
+// store caches a copy of the user's flags. Expired entries of other users are
+// swept once the cache grows past maxCachedUsers.
+func (s *Store) store(userID string, flags map[string]bool) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= maxCachedUsers {
+		for id, entry := range s.cache {
+			if now.After(entry.expires) {
+				delete(s.cache, id)
+			}
+		}
+	}
+	s.cache[userID] = cachedFlags{flags: maps.Clone(flags), expires: now.Add(s.cacheTTL)}
+}
+
+// flagKey returns the Redis key holding one flag of a user
+func flagKey(userID, flag string) string {
+	return flagKeyPrefix + userID + ":" + flag
+}
@@ -0,0 +1,61 @@
+// Package geoip resolves client IPs to a country and city using a MaxMind
+// GeoLite2 (or GeoIP2) City database on local disk.
+package geoip
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Location is where an IP address is registered. Either field is empty when
+// the database does not know it.
+type Location struct {
+	Country string // ISO 3166-1 alpha-2 code, e.g. "NG"
+	City    string // English city name, e.g. "Lagos"
+}
+
+// Locator looks up IP addresses in a GeoLite2 City database. A nil Locator is
+// valid and locates nothing, so callers need not check whether geolocation is
+// configured.
+type Locator struct {
+	reader *geoip2.Reader
+}
+
+// Open memory-maps the .mmdb database at path. The file must stay in place
+// until Close is called.
+func Open(path string) (*Locator, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return &Locator{reader: reader}, nil
+}
+
+// Lookup returns the location of ip. ok is false for unparsable, private and
+// unknown addresses, and when the Locator is nil.
+func (l *Locator) Lookup(ip string) (loc Location, ok bool) {
+	if l == nil {
+		return Location{}, false
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil || addr.IsPrivate() || addr.IsLoopback() {
+		return Location{}, false
+	}
+
+	record, err := l.reader.City(addr)
+	if err != nil {
+		return Location{}, false
+	}
+	loc = Location{Country: record.Country.IsoCode, City: record.City.Names["en"]}
+	return loc, loc.Country != ""
+}
+
+// Close unmaps the database.
+func (l *Locator) Close() error {
+	if l == nil {
+		return nil
+	}
+	return l.reader.Close()
+}
@@ -0,0 +1,152 @@
+package jwt
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// =============================================================================
+// Custom Claims
+// =============================================================================
+
+// Claims is a set of token claims keyed by claim name.
+type Claims map[string]any
+
+// ClaimTransformer adds custom claims (e.g. org_id or feature_flags) to new
+// tokens. It receives the user ID and a copy of the standard claims and returns
+// the claims to merge into the token; it cannot replace the claims the manager
+// relies on (see reservedClaims). Returning an error aborts token generation.
+type ClaimTransformer func(userID string, base Claims) (Claims, error)
+
+// ClaimExtractor checks the custom claims of a token whose signature and expiry
+// have been verified, e.g. that org_id is present and has the right type with
+// ExtractClaim. Returning an error rejects the token.
+type ClaimExtractor func(claims Claims) error
+
+// reservedClaims are set by the manager itself; a transformer overriding one of
+// them could change who a token identifies or how long it is valid.
+var reservedClaims = map[string]bool{
+	"user_id":         true,
+	"jti":             true,
+	"iat":             true,
+	"exp":             true,
+	"nbf":             true,
+	"session_id":      true,
+	"tenant_id":       true,
+	"role":            true,
+	"impersonated_by": true,
+	"permissions":     true,
+	"cnf":             true,
+	"aud":             true,
+}
+
+// Scopes returns the scopes of the "scope" claim, a space-separated string as in
+// RFC 9068, or a list of strings as some identity providers issue it. A token
+// without the claim has no scopes.
+func (c Claims) Scopes() []string {
+	switch scope := c["scope"].(type) {
+	case string:
+		return strings.Fields(scope)
+	case []string:
+		return scope
+	case []any:
+		scopes, err := ExtractClaim[[]string](c, "scope")
+		if err != nil {
+			return nil
+		}
+		return scopes
+	}
+	return nil
+}
+
+// WithClaimTransformer registers a transformer that runs on every generated
+// token. Transformers run in the order they were added, each seeing the claims
+// merged so far. The manager is returned to allow chaining after NewManager.
+func (m *Manager) WithClaimTransformer(t ClaimTransformer) *Manager {
+	m.transformers = append(m.transformers, t)
+	return m
+}
+
+// WithClaimExtractor registers an extractor that runs on every verified token,
+// in the order they were added. The manager is returned to allow chaining.
+func (m *Manager) WithClaimExtractor(e ClaimExtractor) *Manager {
+	m.extractors = append(m.extractors, e)
+	return m
+}
+
+// applyTransformers merges the claims of every registered transformer into claims.
+func (m *Manager) applyTransformers(userID int64, claims map[string]any) error {
+	for _, transform := range m.transformers {
+		extra, err := transform(strconv.FormatInt(userID, 10), maps.Clone(Claims(claims)))
+		if err != nil {
+			return fmt.Errorf("claim transformer: %w", err)
+		}
+		for name, value := range extra {
+			if reservedClaims[name] {
+				return fmt.Errorf("claim transformer may not set the %q claim", name)
+			}
+			claims[name] = value
+		}
+	}
+	return nil
+}
+
+// applyExtractors runs every registered extractor on verified claims.
+func (m *Manager) applyExtractors(claims map[string]any) error {
+	for _, extract := range m.extractors {
+		if err := extract(Claims(claims)); err != nil {
+			return fmt.Errorf("invalid token claims: %w", err)
+		}
+	}
+	return nil
+}
+
+// ExtractClaim returns the named claim as a T. Claims decoded from a token
+// follow JSON's types, so numbers are accepted for any integer or float T and
+// JSON arrays for []string.
+func ExtractClaim[T any](claims Claims, name string) (T, error) {
+	var zero T
+	raw, ok := claims[name]
+	if !ok {
+		return zero, fmt.Errorf("missing %q claim", name)
+	}
+	if value, ok := raw.(T); ok {
+		return value, nil
+	}
+
+	var converted any
+	switch any(zero).(type) {
+	case int64:
+		if f, ok := raw.(float64); ok && f == float64(int64(f)) {
+			converted = int64(f)
+		}
+	case int:
+		if f, ok := raw.(float64); ok && f == float64(int(f)) {
+			converted = int(f)
+		}
+	case float64:
+		if i, ok := raw.(int64); ok {
+			converted = float64(i)
+		}
+	case []string:
+		if list, ok := raw.([]any); ok {
+			values := make([]string, 0, len(list))
+			for _, item := range list {
+				s, ok := item.(string)
+				if !ok {
+					break
+				}
+				values = append(values, s)
+			}
+			if len(values) == len(list) {
+				converted = values
+			}
+		}
+	}
+	if value, ok := converted.(T); ok {
+		return value, nil
+	}
+	return zero, fmt.Errorf("%q claim has type %T, want %T", name, raw, zero)
+}
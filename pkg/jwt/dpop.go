@@ -0,0 +1,217 @@
+package jwt
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// DPoP Token Binding (RFC 9449)
+// =============================================================================
+
+// dpopProofType is the typ header every DPoP proof must carry
+const dpopProofType = "dpop+jwt"
+
+// dpopMaxSkew is how far a proof's iat may be from now, either way
+const dpopMaxSkew = 5 * time.Second
+
+// dpopJTIKeyPrefix namespaces the IDs of DPoP proofs already used. Proofs are
+// only accepted within dpopMaxSkew of their iat, so IDs are kept for that window.
+const dpopJTIKeyPrefix = "jwt:dpop:jti:"
+
+// dpopStoreTimeout bounds the Redis call made for the replay check
+const dpopStoreTimeout = 500 * time.Millisecond
+
+// Errors returned by VerifyDPoP and DPoPThumbprint, one per failure case.
+var (
+	ErrDPoPProofMissing     = errors.New("DPoP proof missing")
+	ErrDPoPInvalidProof     = errors.New("invalid DPoP proof")
+	ErrDPoPMethodMismatch   = errors.New("DPoP proof htm does not match the request method")
+	ErrDPoPURIMismatch      = errors.New("DPoP proof htu does not match the request URI")
+	ErrDPoPProofExpired     = errors.New("DPoP proof iat outside the accepted window")
+	ErrDPoPProofReplayed    = errors.New("DPoP proof jti already used")
+	ErrDPoPTokenNotBound    = errors.New("access token is not bound to a DPoP key")
+	ErrDPoPKeyMismatch      = errors.New("DPoP proof key does not match the access token's cnf.jkt")
+	ErrDPoPTokenMismatch    = errors.New("DPoP proof ath does not match the access token")
+	ErrDPoPStoreUnavailable = errors.New("DPoP replay check failed")
+)
+
+// dpopClaims are the claims of a DPoP proof; jti and iat are registered claims
+type dpopClaims struct {
+	HTM string `json:"htm"`
+	HTU string `json:"htu"`
+	ATH string `json:"ath"`
+	jwt.RegisteredClaims
+}
+
+// dpopKeyContext carries the thumbprint new access tokens are bound to
+type dpopKeyContext struct{}
+
+// WithDPoPReplayStore rejects DPoP proofs whose jti was seen before, tracking
+// them in Redis. Without a store the replay check is skipped. The manager is
+// returned to allow chaining.
+func (m *Manager) WithDPoPReplayStore(rdb *redis.Client) *Manager {
+	m.dpopReplay = rdb
+	return m
+}
+
+// WithDPoPKey returns a context whose access tokens get bound to the key with
+// JWK thumbprint jkt (see DPoPThumbprint and UserClaims.DPoPJKT).
+func WithDPoPKey(ctx context.Context, jkt string) context.Context {
+	return context.WithValue(ctx, dpopKeyContext{}, jkt)
+}
+
+// DPoPKeyFromContext returns the thumbprint stored by WithDPoPKey, or "".
+func DPoPKeyFromContext(ctx context.Context) string {
+	jkt, _ := ctx.Value(dpopKeyContext{}).(string)
+	return jkt
+}
+
+// VerifyDPoP checks that dpopProof proves possession of the key accessToken is
+// bound to, for a request with method htm to URI htu. Per RFC 9449 the proof
+// must be a dpop+jwt signed with the public key in its jwk header, name the
+// request's method and URI (query and fragment ignored), be issued within
+// dpopMaxSkew of now, carry a jti not seen before and the hash of the access
+// token (ath). The token's cnf.jkt claim must be the thumbprint of the proof key.
+func (m *Manager) VerifyDPoP(accessToken, dpopProof, htm, htu string) error {
+	claims, err := m.VerifyToken(accessToken)
+	if err != nil {
+		return err
+	}
+	cnf, _ := claims["cnf"].(map[string]any)
+	jkt, _ := cnf["jkt"].(string)
+	if jkt == "" {
+		return ErrDPoPTokenNotBound
+	}
+
+	proof, thumbprint, err := m.verifyDPoPProof(dpopProof, htm, htu)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(thumbprint), []byte(jkt)) != 1 {
+		return ErrDPoPKeyMismatch
+	}
+
+	sum := sha256.Sum256([]byte(accessToken))
+	if subtle.ConstantTimeCompare([]byte(proof.ATH), []byte(base64.RawURLEncoding.EncodeToString(sum[:]))) != 1 {
+		return ErrDPoPTokenMismatch
+	}
+
+	return m.checkDPoPReplay(thumbprint, proof.ID)
+}
+
+// DPoPThumbprint verifies a DPoP proof sent without an access token, as with a
+// token request, and returns the JWK thumbprint of its key: the value to bind
+// the issued access token to with WithDPoPKey.
+func (m *Manager) DPoPThumbprint(dpopProof, htm, htu string) (string, error) {
+	proof, thumbprint, err := m.verifyDPoPProof(dpopProof, htm, htu)
+	if err != nil {
+		return "", err
+	}
+	if err := m.checkDPoPReplay(thumbprint, proof.ID); err != nil {
+		return "", err
+	}
+	return thumbprint, nil
+}
+
+// verifyDPoPProof checks a proof's header, signature, htm, htu and iat and
+// returns its claims and the thumbprint of its key. jti replay is left to the
+// caller, so that a proof is only recorded once it has been fully accepted.
+func (m *Manager) verifyDPoPProof(dpopProof, htm, htu string) (*dpopClaims, string, error) {
+	if dpopProof == "" {
+		return nil, "", ErrDPoPProofMissing
+	}
+
+	var jwk JWK
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(dpopProof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, errors.New("typ must be " + dpopProofType)
+		}
+		raw, ok := token.Header["jwk"].(map[string]any)
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+		// The proof must carry a public key only
+		if _, private := raw["d"]; private {
+			return nil, errors.New("jwk header contains a private key")
+		}
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &jwk); err != nil {
+			return nil, err
+		}
+		return jwk.publicKey()
+	}, jwt.WithValidMethods(externalAlgorithms))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrDPoPInvalidProof, err)
+	}
+	if claims.ID == "" || claims.IssuedAt == nil {
+		return nil, "", fmt.Errorf("%w: missing jti or iat", ErrDPoPInvalidProof)
+	}
+
+	if claims.HTM != htm {
+		return nil, "", ErrDPoPMethodMismatch
+	}
+	if !sameDPoPURI(claims.HTU, htu) {
+		return nil, "", ErrDPoPURIMismatch
+	}
+	if issued := claims.IssuedAt.Time; time.Since(issued) > dpopMaxSkew || time.Until(issued) > dpopMaxSkew {
+		return nil, "", ErrDPoPProofExpired
+	}
+
+	return claims, jwk.thumbprint(), nil
+}
+
+// checkDPoPReplay records the proof's jti, scoped to its key, and rejects it
+// when it was recorded before. A Redis failure rejects the proof: accepting it
+// would defeat the replay protection.
+func (m *Manager) checkDPoPReplay(thumbprint, jti string) error {
+	if m.dpopReplay == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dpopStoreTimeout)
+	defer cancel()
+
+	sum := sha256.Sum256([]byte(thumbprint + ":" + jti))
+	key := dpopJTIKeyPrefix + base64.RawURLEncoding.EncodeToString(sum[:])
+	// The ID must outlive the whole window the proof is accepted in
+	first, err := m.dpopReplay.SetNX(ctx, key, "1", 2*dpopMaxSkew).Result()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDPoPStoreUnavailable, err)
+	}
+	if !first {
+		return ErrDPoPProofReplayed
+	}
+	return nil
+}
+
+// sameDPoPURI compares htu values as RFC 9449 asks: without query and
+// fragment, with case-insensitive scheme and host.
+func sameDPoPURI(proof, request string) bool {
+	p, err := url.Parse(proof)
+	if err != nil {
+		return false
+	}
+	r, err := url.Parse(request)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(p.Scheme, r.Scheme) &&
+		strings.EqualFold(p.Host, r.Host) &&
+		p.EscapedPath() == r.EscapedPath()
+}
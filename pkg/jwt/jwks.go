@@ -0,0 +1,258 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// =============================================================================
+// External Token Verification (JWKS)
+// =============================================================================
+
+// jwksMinRefreshInterval limits refetches triggered by unknown kids, so tokens
+// with made-up kids cannot make the verifier hammer the identity provider
+const jwksMinRefreshInterval = 30 * time.Second
+
+// jwksMaxBytes bounds the size of a fetched key set
+const jwksMaxBytes = 1 << 20
+
+// externalAlgorithms are the asymmetric algorithms accepted from identity
+// providers. HMAC is never accepted: its secret cannot be published in a JWKS.
+var externalAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// ErrUnknownKey is returned for tokens whose kid is not in the key set, even
+// after refetching it
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Verifier verifies a token issued by an external identity provider and
+// returns its claims. It is implemented by JWKSVerifier.
+type Verifier interface {
+	Verify(tokenString string) (*Claims, error)
+}
+
+// JWKSVerifier verifies tokens issued by an external identity provider (Amazon
+// Cognito, Auth0, ...) against the public keys it publishes at a JWKS URI.
+// Keys are cached for the configured duration; a token signed with a kid that
+// is not cached makes the verifier refetch the set, so key rotations at the
+// provider are picked up without waiting for the cache to expire.
+type JWKSVerifier struct {
+	uri      string
+	cacheFor time.Duration
+	client   *http.Client
+
+	// issuer and audience, when set, must match the iss and aud claims
+	issuer   string
+	audience string
+
+	mu        sync.Mutex
+	keys      map[string]externalKey
+	fetchedAt time.Time
+}
+
+// externalKey is a public key of the set and the algorithm it is published
+// for; an empty alg accepts any algorithm matching the key type
+type externalKey struct {
+	key crypto.PublicKey
+	alg string
+}
+
+// NewJWKSVerifier creates a verifier for the key set at jwksURI, fetching it
+// once so a wrong URI is reported at startup. The set is refetched once it is
+// older than cacheFor.
+func NewJWKSVerifier(jwksURI string, cacheFor time.Duration) (*JWKSVerifier, error) {
+	v := &JWKSVerifier{
+		uri:      jwksURI,
+		cacheFor: cacheFor,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+	if err := v.refresh(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// WithIssuer requires the iss claim of verified tokens to equal issuer. The
+// verifier is returned to allow chaining.
+func (v *JWKSVerifier) WithIssuer(issuer string) *JWKSVerifier {
+	v.issuer = issuer
+	return v
+}
+
+// WithAudience requires the aud claim of verified tokens to contain audience.
+// The verifier is returned to allow chaining.
+func (v *JWKSVerifier) WithAudience(audience string) *JWKSVerifier {
+	v.audience = audience
+	return v
+}
+
+// Verify checks the signature, expiry and, when configured, issuer and
+// audience of an externally issued token and returns its claims. The key is
+// selected by the token's kid header, which is required.
+func (v *JWKSVerifier) Verify(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{jwt.WithValidMethods(externalAlgorithms), jwt.WithExpirationRequired()}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid header")
+		}
+		key, err := v.key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+		return key.key, nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	result := Claims(claims)
+	return &result, nil
+}
+
+// key returns the public key with the given kid, refetching the key set when
+// it is stale or, at most every jwksMinRefreshInterval, when the kid is unknown.
+func (v *JWKSVerifier) key(kid string) (externalKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	age := time.Since(v.fetchedAt)
+	v.mu.Unlock()
+
+	if ok && age < v.cacheFor {
+		return key, nil
+	}
+	if ok || age >= jwksMinRefreshInterval {
+		if err := v.refresh(); err != nil {
+			// A provider outage should not reject tokens signed with a cached key
+			if ok {
+				return key, nil
+			}
+			return externalKey{}, err
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return externalKey{}, ErrUnknownKey
+}
+
+// refresh fetches the key set and replaces the cached keys. Keys that are not
+// signature keys or cannot be decoded are skipped.
+func (v *JWKSVerifier) refresh() error {
+	resp, err := v.client.Get(v.uri)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKSet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]externalKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kid == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = externalKey{key: key, alg: jwk.Alg}
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+// publicKey decodes the key material of an RSA or EC (P-256, P-384, P-521) JWK.
+func (k JWK) publicKey() (crypto.PublicKey, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+
+	switch k.Kty {
+	case "RSA":
+		n, err := b64(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := b64(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported EC curve")
+		}
+		x, err := b64(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := b64(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		// Validate the point through the uncompressed encoding: 0x04 || X || Y
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("invalid EC key")
+		}
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
@@ -3,54 +3,306 @@ package jwt
 
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrAudienceMismatch is returned by VerifyTokenForAudience when the token's aud
+// claim does not contain the expected audience.
+var ErrAudienceMismatch = errors.New("token audience mismatch")
+
+// Supported signing algorithms for asymmetric managers.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
 )
 
 // Manager is responsible for handling all JWT-related operations:
 // generation, signing, and verification.
 type Manager struct {
-	secretKey string
+	// signingMethod is HS256 for symmetric managers, or RS256/ES256 for asymmetric ones.
+	signingMethod jwt.SigningMethod
+
+	// keys holds the signing key and the key it replaced; mu guards rotation.
+	mu   sync.RWMutex
+	keys KeySet
+
+	// revocations stores revoked token IDs; nil disables revocation checks.
+	revocations      *redis.Client
+	strictRevocation bool
+
+	// dpopReplay stores the IDs of used DPoP proofs; nil skips the replay check.
+	dpopReplay *redis.Client
+
+	// transformers add custom claims to new tokens; extractors check them on
+	// verified ones. Both are registered at startup, before tokens are issued.
+	transformers []ClaimTransformer
+	extractors   []ClaimExtractor
 }
 
 // NewManager constructs the Manager with its required dependency, the secret key.
 func NewManager(secretKey string) *Manager {
-	return &Manager{secretKey: secretKey}
+	return &Manager{
+		signingMethod: jwt.SigningMethodHS256,
+		keys:          KeySet{Current: newSecretKey(secretKey)},
+	}
+}
+
+// NewAsymmetricManager constructs a Manager that signs tokens with a private key
+// and verifies them with the matching public key. Supported algorithms are RS256
+// (RSA keys) and ES256 (ECDSA P-256 keys). Services that only need to verify tokens
+// can be handed the public key via PublicKeyPEM without ever seeing the private key.
+func NewAsymmetricManager(privateKey crypto.PrivateKey, publicKey crypto.PublicKey, algorithm string) (*Manager, error) {
+	if publicKey == nil {
+		return nil, errors.New("public key is required")
+	}
+
+	switch algorithm {
+	case AlgorithmRS256:
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return nil, errors.New("RS256 requires an *rsa.PublicKey")
+		}
+		if privateKey != nil {
+			if _, ok := privateKey.(*rsa.PrivateKey); !ok {
+				return nil, errors.New("RS256 requires an *rsa.PrivateKey")
+			}
+		}
+		return newAsymmetricManager(jwt.SigningMethodRS256, privateKey, publicKey)
+
+	case AlgorithmES256:
+		pub, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, errors.New("ES256 requires an *ecdsa.PublicKey")
+		}
+		if pub.Curve.Params().BitSize != 256 {
+			return nil, errors.New("ES256 requires a P-256 key")
+		}
+		if privateKey != nil {
+			if _, ok := privateKey.(*ecdsa.PrivateKey); !ok {
+				return nil, errors.New("ES256 requires an *ecdsa.PrivateKey")
+			}
+		}
+		return newAsymmetricManager(jwt.SigningMethodES256, privateKey, publicKey)
+
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+}
+
+// newAsymmetricManager builds a Manager around an already validated key pair.
+func newAsymmetricManager(method jwt.SigningMethod, privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (*Manager, error) {
+	key, err := newKeyPair(privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{signingMethod: method, keys: KeySet{Current: key}}, nil
+}
+
+// PublicKeyPEM returns the PEM-encoded (PKIX) public key used to verify tokens.
+// It returns nil for symmetric (HS256) managers, which have no public key to share.
+func (m *Manager) PublicKeyPEM() []byte {
+	publicKey := m.Keys().Current.PublicKey
+	if publicKey == nil {
+		return nil
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// UserClaims are the identity claims embedded in an access token.
+type UserClaims struct {
+	UserID    int64
+	Email     string
+	FirstName string
+	LastName  string
+
+	// SessionID binds the token to a login session ("session_id" claim) so the auth
+	// middleware can reject tokens whose session was revoked; empty omits the claim
+	SessionID string
+
+	// TenantID scopes the token to a tenant ("tenant_id" claim); nil omits the claim
+	TenantID *int64
+
+	// Role is the user's role ("role" claim), e.g. "admin"; empty omits the claim
+	Role string
+
+	// ImpersonatedBy is the ID of the admin acting as the user ("impersonated_by"
+	// claim); nil omits the claim
+	ImpersonatedBy *int64
+
+	// Permissions are the permissions granted by the user's roles ("permissions"
+	// claim); empty omits the claim
+	Permissions []string
+
+	// DPoPJKT binds the token to a DPoP key by its JWK thumbprint ("cnf" claim,
+	// RFC 9449); empty issues a plain bearer token
+	DPoPJKT string
+
+	// ClientID is the OAuth client the token was issued to ("client_id" claim,
+	// RFC 9068); empty for tokens of first-party logins
+	ClientID string
+
+	// Scopes are the scopes granted to the token, encoded as the space-separated
+	// "scope" claim (RFC 9068); empty omits the claim
+	Scopes []string
+
+	// Audience lists the services the token is intended for ("aud" claim);
+	// empty omits the claim. Verify it with VerifyTokenForAudience.
+	Audience []string
+
+	// TTL is the token lifetime; zero means the default of 24 hours
+	TTL time.Duration
 }
 
 // GenerateToken creates a new JWT access token with the specified user claims.
 func (m *Manager) GenerateToken(userID int64, email string, firstName, lastName string) (string, error) {
+	return m.GenerateTokenWithClaims(UserClaims{
+		UserID:    userID,
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+	})
+}
+
+// GenerateTokenWithClaims creates a new JWT access token, including the optional
+// session, tenant, role, impersonation, permissions, scope and audience claims
+// when they are set,
+// and the custom claims of any registered ClaimTransformer.
+func (m *Manager) GenerateTokenWithClaims(user UserClaims) (string, error) {
+	// Every token gets a unique ID so it can be revoked individually
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	ttl := user.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
 	// Define the token's payload (claims). 'exp' is the standard expiration time claim.
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"first_name": firstName,  // Change from "name" to "first_name"
-            "last_name":  lastName, 
-		"name":    firstName + " " + lastName,
-		// Token expires 24 hours from creation, represented as a Unix timestamp
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"user_id":    user.UserID,
+		"email":      user.Email,
+		"first_name": user.FirstName,
+		"last_name":  user.LastName,
+		"name":       user.FirstName + " " + user.LastName,
+		"jti":        jti,
+		"iat":        time.Now().Unix(),
+		// Token expires TTL (24 hours by default) from creation, represented as a Unix timestamp
+		"exp": time.Now().Add(ttl).Unix(),
+	}
+	if user.SessionID != "" {
+		claims["session_id"] = user.SessionID
+	}
+	if user.TenantID != nil {
+		claims["tenant_id"] = *user.TenantID
+	}
+	if user.Role != "" {
+		claims["role"] = user.Role
+	}
+	if user.ImpersonatedBy != nil {
+		claims["impersonated_by"] = *user.ImpersonatedBy
+	}
+	if len(user.Permissions) > 0 {
+		claims["permissions"] = user.Permissions
+	}
+	if user.DPoPJKT != "" {
+		claims["cnf"] = map[string]any{"jkt": user.DPoPJKT}
+	}
+	if user.ClientID != "" {
+		claims["client_id"] = user.ClientID
+	}
+	if len(user.Scopes) > 0 {
+		claims["scope"] = strings.Join(user.Scopes, " ")
+	}
+	if len(user.Audience) > 0 {
+		claims["aud"] = user.Audience
+	}
+
+	// Merge custom claims from the registered transformers
+	if err := m.applyTransformers(user.UserID, claims); err != nil {
+		return "", err
+	}
+
+	return m.sign(claims)
+}
+
+// GenerateClientToken creates an access token for an OAuth client acting on its
+// own behalf (client credentials grant): it has "sub" and "client_id" set to
+// clientID, the granted scopes, and no user claims. A zero ttl means 24 hours.
+func (m *Manager) GenerateClientToken(clientID string, scopes []string, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	claims := jwt.MapClaims{
+		"sub":       clientID,
+		"client_id": clientID,
+		"jti":       jti,
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(ttl).Unix(),
 	}
+	if len(scopes) > 0 {
+		claims["scope"] = strings.Join(scopes, " ")
+	}
+	return m.sign(claims)
+}
 
-	// Create the token object, specifying the signing method (HS256) and the claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// sign signs claims with the manager's signing method and current key.
+func (m *Manager) sign(claims jwt.MapClaims) (string, error) {
+	// Create the token object, specifying the manager's signing method and the claims
+	token := jwt.NewWithClaims(m.signingMethod, claims)
 
-	// Sign the token using the secret key
-	return token.SignedString([]byte(m.secretKey))
+	// Sign the token using the current secret or private key. The kid header tells
+	// verifiers which key to use once the key has been rotated.
+	current := m.Keys().Current
+	key, err := current.signingKey()
+	if err != nil {
+		return "", err
+	}
+	token.Header["kid"] = current.ID
+	return token.SignedString(key)
 }
 
 // VerifyToken parses, validates, and returns the claims from a given token string.
 func (m *Manager) VerifyToken(tokenString string) (jwt.MapClaims, error) {
-	// Parse the token. The keyFunc is called during parsing to get the secret key
+	// Parse the token. The keyFunc is called during parsing to get the key
 	// needed to verify the token's signature.
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// SECURITY CHECK: Ensure the token's signing method is what we expect (HS256)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		// SECURITY CHECK: Ensure the token's signing method is exactly the one we issue with.
+		// This prevents algorithm-confusion attacks (e.g. HS256 signed with a public key).
+		if token.Method.Alg() != m.signingMethod.Alg() {
 			return nil, errors.New("unexpected signing method")
 		}
-		// Return the secret key used for verification
-		return []byte(m.secretKey), nil
+		// Return the key(s) the token may have been signed with: the one named by
+		// its kid header, or both the current and previous key for tokens without one
+		keys := m.verificationKeys(token)
+		if len(keys.Keys) == 0 {
+			return nil, errors.New("unknown signing key")
+		}
+		return keys, nil
 	})
 
 	if err != nil {
@@ -68,5 +320,26 @@ func (m *Manager) VerifyToken(tokenString string) (jwt.MapClaims, error) {
 		return nil, errors.New("invalid token claims format")
 	}
 
+	// Check custom claims with the registered extractors
+	if err := m.applyExtractors(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// VerifyTokenForAudience verifies a token like VerifyToken and also requires its
+// aud claim, a string or a list of strings, to contain audience. Tokens without
+// the claim, or intended only for other services, fail with ErrAudienceMismatch.
+func (m *Manager) VerifyTokenForAudience(tokenString, audience string) (jwt.MapClaims, error) {
+	claims, err := m.VerifyToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	aud, err := claims.GetAudience()
+	if err != nil || !slices.Contains(aud, audience) {
+		return nil, ErrAudienceMismatch
+	}
 	return claims, nil
 }
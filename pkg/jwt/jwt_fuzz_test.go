@@ -0,0 +1,65 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// FuzzParseToken checks that VerifyToken never panics on malformed input and
+// that every rejection explains itself.
+func FuzzParseToken(f *testing.F) {
+	manager := NewManager("fuzz-secret-key-of-at-least-32-bytes")
+
+	valid, err := manager.GenerateToken(1, "user@example.com", "Ada", "Lovelace")
+	if err != nil {
+		f.Fatal(err)
+	}
+	// Signed by hand: GenerateTokenWithClaims replaces a non-positive TTL with the default
+	now := time.Now()
+	expired, err := manager.sign(gojwt.MapClaims{
+		"user_id": 1,
+		"email":   "user@example.com",
+		"jti":     "expired-seed",
+		"iat":     now.Add(-2 * time.Hour).Unix(),
+		"exp":     now.Add(-time.Hour).Unix(),
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+	if _, err := manager.VerifyToken(expired); err == nil {
+		f.Fatal("expired seed verified")
+	}
+	header, rest, _ := strings.Cut(valid, ".")
+	payload, signature, _ := strings.Cut(rest, ".")
+
+	for _, seed := range []string{
+		valid,
+		expired,
+		header + "." + payload + "." + signature[:len(signature)-2] + "AA", // tampered signature
+		header + ".eyJzdWIiOiIyIn0." + signature,                           // tampered payload
+		"eyJhbGciOiJub25lIn0." + payload + ".",                             // alg none
+		header + "." + payload,                                             // missing segment
+		"",
+		".",
+		"..",
+		"not-a-token",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := manager.VerifyToken(token)
+		if err != nil {
+			if err.Error() == "" {
+				t.Fatalf("VerifyToken(%q) returned an error without a message", token)
+			}
+			return
+		}
+		if claims == nil {
+			t.Fatalf("VerifyToken(%q) returned neither claims nor an error", token)
+		}
+	})
+}
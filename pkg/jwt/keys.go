@@ -0,0 +1,256 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// =============================================================================
+// Signing Keys and Rotation
+// =============================================================================
+
+// Key is one signing key: an HMAC secret for HS256 managers, or a key pair for
+// RS256/ES256 managers. ID is sent as the "kid" header of the tokens it signs.
+type Key struct {
+	ID string
+
+	// Secret is the HMAC secret of symmetric managers
+	Secret []byte
+
+	// PrivateKey and PublicKey are only set for asymmetric managers. PrivateKey may
+	// be nil for verify-only managers.
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// IsZero reports whether k holds no key.
+func (k Key) IsZero() bool {
+	return k.ID == ""
+}
+
+// KeySet is the signing key of a Manager and the key it replaced. New tokens are
+// always signed with Current; tokens signed with either key verify, so rotating
+// the key does not sign everyone out. Previous is zero when there is none.
+type KeySet struct {
+	Current  Key
+	Previous Key
+}
+
+// NewRotatingManager constructs a symmetric (HS256) Manager that signs with the
+// primary secret and still accepts tokens signed with the secondary one, e.g. the
+// secret in use before the last rotation. An empty secondary is ignored.
+func NewRotatingManager(primary, secondary string) *Manager {
+	m := NewManager(primary)
+	if secondary != "" && secondary != primary {
+		m.keys.Previous = newSecretKey(secondary)
+	}
+	return m
+}
+
+// Keys returns the manager's current key set.
+func (m *Manager) Keys() KeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.keys
+}
+
+// RotateKeys makes newSecret the signing secret of a symmetric manager. The
+// current secret becomes the previous one, so tokens issued before the rotation
+// remain valid until they expire; the secret before that stops being accepted.
+func (m *Manager) RotateKeys(newSecret string) error {
+	if m.signingMethod != jwt.SigningMethodHS256 {
+		return errors.New("asymmetric managers rotate keys with RotateKeyPair")
+	}
+	if newSecret == "" {
+		return errors.New("new secret is required")
+	}
+
+	key := newSecretKey(newSecret)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key.ID == m.keys.Current.ID {
+		return errors.New("new secret must differ from the current one")
+	}
+	m.keys = KeySet{Current: key, Previous: m.keys.Current}
+	return nil
+}
+
+// RotateKeyPair is RotateKeys for asymmetric managers. The new key pair must be
+// of the kind the manager was created for.
+func (m *Manager) RotateKeyPair(privateKey crypto.PrivateKey, publicKey crypto.PublicKey) error {
+	if m.signingMethod == jwt.SigningMethodHS256 {
+		return errors.New("symmetric managers rotate keys with RotateKeys")
+	}
+
+	// Reuse the constructor's validation of the key types
+	rotated, err := NewAsymmetricManager(privateKey, publicKey, m.signingMethod.Alg())
+	if err != nil {
+		return err
+	}
+	key := rotated.keys.Current
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if key.ID == m.keys.Current.ID {
+		return errors.New("new key pair must differ from the current one")
+	}
+	m.keys = KeySet{Current: key, Previous: m.keys.Current}
+	return nil
+}
+
+// verificationKeys returns the keys a token may be verified with: the key named
+// by its kid header, or every key of the set when the header is missing (tokens
+// issued before kid headers were added). An unknown kid yields an empty set.
+func (m *Manager) verificationKeys(token *jwt.Token) jwt.VerificationKeySet {
+	keys := m.Keys()
+	kid, _ := token.Header["kid"].(string)
+
+	var set jwt.VerificationKeySet
+	for _, key := range []Key{keys.Current, keys.Previous} {
+		if key.IsZero() || (kid != "" && kid != key.ID) {
+			continue
+		}
+		set.Keys = append(set.Keys, key.verificationKey())
+	}
+	return set
+}
+
+// signingKey returns the key used to sign new tokens.
+func (k Key) signingKey() (interface{}, error) {
+	if k.PublicKey == nil {
+		return k.Secret, nil
+	}
+	if k.PrivateKey == nil {
+		return nil, errors.New("manager has no private key and can only verify tokens")
+	}
+	return k.PrivateKey, nil
+}
+
+// verificationKey returns the key used to verify token signatures.
+func (k Key) verificationKey() jwt.VerificationKey {
+	if k.PublicKey == nil {
+		return k.Secret
+	}
+	return k.PublicKey
+}
+
+// newSecretKey wraps an HMAC secret. Its ID is derived from a hash of the
+// secret, so it identifies the key without revealing it.
+func newSecretKey(secret string) Key {
+	sum := sha256.Sum256([]byte("authentio-kid:" + secret))
+	return Key{ID: hex.EncodeToString(sum[:8]), Secret: []byte(secret)}
+}
+
+// newKeyPair wraps a key pair. Its ID is the RFC 7638 thumbprint of the public key.
+func newKeyPair(privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (Key, error) {
+	jwk, err := publicJWK(publicKey)
+	if err != nil {
+		return Key{}, err
+	}
+	return Key{ID: jwk.thumbprint(), PrivateKey: privateKey, PublicKey: publicKey}, nil
+}
+
+// =============================================================================
+// JSON Web Key Set
+// =============================================================================
+
+// JWK is the public half of a signing key as a JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is a JSON Web Key Set as served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys tokens are verified with: the current key and,
+// after a rotation, the previous one. Symmetric managers have no public keys and
+// return an empty set; HMAC secrets are never published.
+func (m *Manager) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	keys := m.Keys()
+	for _, key := range []Key{keys.Current, keys.Previous} {
+		if key.IsZero() || key.PublicKey == nil {
+			continue
+		}
+		jwk, err := publicJWK(key.PublicKey)
+		if err != nil {
+			continue
+		}
+		jwk.Use = "sig"
+		jwk.Alg = m.signingMethod.Alg()
+		jwk.Kid = key.ID
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// publicJWK encodes the key material of an RSA or P-256 public key.
+func publicJWK(publicKey crypto.PublicKey) (JWK, error) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}, nil
+
+	case *ecdsa.PublicKey:
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Uncompressed point: 0x04 || X || Y, 32 bytes each for P-256
+		point := ecdhKey.Bytes()
+		if len(point) != 65 {
+			return JWK{}, errors.New("ES256 requires a P-256 key")
+		}
+		return JWK{Kty: "EC", Crv: "P-256", X: b64(point[1:33]), Y: b64(point[33:])}, nil
+
+	default:
+		return JWK{}, errors.New("unsupported public key type")
+	}
+}
+
+// thumbprint returns the RFC 7638 thumbprint: the SHA-256 of the required
+// members in lexicographic order, base64url encoded.
+func (k JWK) thumbprint() string {
+	var members any
+	if k.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	}
+	data, _ := json.Marshal(members)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
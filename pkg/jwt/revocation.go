@@ -0,0 +1,125 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// revokedKeyPrefix namespaces revoked token IDs in Redis.
+const revokedKeyPrefix = "jwt:revoked:"
+
+// revokedBeforeKey holds the Unix time before which every token is revoked;
+// revokedBeforeKey + ":" + user ID holds the same cutoff for one user's tokens.
+const revokedBeforeKey = "auth:revoked_before"
+
+// ErrNoRevocationStore is returned when tokens are revoked in bulk on a manager
+// without a revocation store, since the cutoff would be silently ignored.
+var ErrNoRevocationStore = errors.New("token revocation store not configured")
+
+// WithRevocationStore enables access-token revocation backed by Redis.
+// When strict is true, callers should reject requests if the store cannot be reached
+// instead of failing open. The manager is returned to allow chaining after NewManager.
+func (m *Manager) WithRevocationStore(rdb *redis.Client, strict bool) *Manager {
+	m.revocations = rdb
+	m.strictRevocation = strict
+	return m
+}
+
+// StrictRevocation reports whether revocation checks must fail closed when Redis is unavailable.
+func (m *Manager) StrictRevocation() bool {
+	return m.strictRevocation
+}
+
+// RevokeToken marks the token with the given JTI as revoked for ttl, which should be the
+// token's remaining lifetime. Once the token would have expired anyway, the key disappears.
+func (m *Manager) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	if m.revocations == nil || jti == "" || ttl <= 0 {
+		return nil
+	}
+	return m.revocations.Set(ctx, revokedKeyPrefix+jti, "1", ttl).Err()
+}
+
+// IsRevoked checks whether the token with the given JTI has been revoked.
+// It always returns false when no revocation store is configured.
+func (m *Manager) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	if m.revocations == nil || jti == "" {
+		return false, nil
+	}
+	exists, err := m.revocations.Exists(ctx, revokedKeyPrefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// RevokeTokensIssuedBefore revokes every token issued before t, for every user
+// and client. Tokens issued afterwards, e.g. by signing in again, are unaffected.
+func (m *Manager) RevokeTokensIssuedBefore(ctx context.Context, t time.Time) error {
+	if m.revocations == nil {
+		return ErrNoRevocationStore
+	}
+	return m.revocations.Set(ctx, revokedBeforeKey, t.Unix(), 0).Err()
+}
+
+// RevokeUserTokensIssuedBefore revokes every token of userID issued before t.
+func (m *Manager) RevokeUserTokensIssuedBefore(ctx context.Context, userID int64, t time.Time) error {
+	if m.revocations == nil {
+		return ErrNoRevocationStore
+	}
+	return m.revocations.Set(ctx, userRevokedBeforeKey(userID), t.Unix(), 0).Err()
+}
+
+// IssuedBeforeRevocation reports whether verified claims belong to a token issued
+// before the cutoff of RevokeTokensIssuedBefore, or of RevokeUserTokensIssuedBefore
+// for its user_id. Once a cutoff is set, tokens without an iat claim are treated
+// as revoked. It always returns false when no revocation store is configured.
+func (m *Manager) IssuedBeforeRevocation(ctx context.Context, claims Claims) (bool, error) {
+	if m.revocations == nil {
+		return false, nil
+	}
+
+	keys := []string{revokedBeforeKey}
+	if userID, ok := claims["user_id"].(float64); ok {
+		keys = append(keys, userRevokedBeforeKey(int64(userID)))
+	}
+	cutoffs, err := m.revocations.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+
+	iat, hasIat := claims["iat"].(float64)
+	for _, cutoff := range cutoffs {
+		value, ok := cutoff.(string)
+		if !ok {
+			continue // no cutoff set
+		}
+		before, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, err
+		}
+		if !hasIat || int64(iat) < before {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// userRevokedBeforeKey is the Redis key of a user's revocation cutoff.
+func userRevokedBeforeKey(userID int64) string {
+	return revokedBeforeKey + ":" + strconv.FormatInt(userID, 10)
+}
+
+// newTokenID generates a random identifier for the 'jti' claim.
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,47 @@
+package kms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+// AWSKMS decrypts with AWS KMS. Credentials and region come from the default
+// AWS chain: AWS_REGION, AWS_ACCESS_KEY_ID and friends, shared config files,
+// or the instance or task role.
+type AWSKMS struct {
+	client *kms.Client
+	keyID  string
+}
+
+// NewAWSKMS creates an AWS KMS decrypter. keyID (a key ID, ARN or alias) is
+// optional for symmetric keys; when set, ciphertext encrypted under any other
+// key is rejected.
+func NewAWSKMS(ctx context.Context, keyID string) (*AWSKMS, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("load AWS config: %w", err)
+	}
+	return &AWSKMS{client: kms.NewFromConfig(cfg), keyID: keyID}, nil
+}
+
+// Decrypt decrypts base64 ciphertext as printed by aws kms encrypt.
+func (k *AWSKMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	blob, err := decodeBase64(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &kms.DecryptInput{CiphertextBlob: blob}
+	if k.keyID != "" {
+		input.KeyId = aws.String(k.keyID)
+	}
+	output, err := k.client.Decrypt(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("aws kms decrypt: %w", err)
+	}
+	return output.Plaintext, nil
+}
@@ -0,0 +1,51 @@
+package kms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	gcpkms "cloud.google.com/go/kms/apiv1"
+	"cloud.google.com/go/kms/apiv1/kmspb"
+)
+
+// GCPKMS decrypts with Google Cloud KMS, authenticating with Application
+// Default Credentials (GOOGLE_APPLICATION_CREDENTIALS or the attached service
+// account).
+type GCPKMS struct {
+	client  *gcpkms.KeyManagementClient
+	keyName string
+}
+
+// NewGCPKMS creates a Cloud KMS decrypter for the crypto key keyName, e.g.
+// projects/p/locations/global/keyRings/authentio/cryptoKeys/config.
+func NewGCPKMS(ctx context.Context, keyName string) (*GCPKMS, error) {
+	if keyName == "" {
+		return nil, errors.New("GCP KMS needs the crypto key resource name")
+	}
+	client, err := gcpkms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create GCP KMS client: %w", err)
+	}
+	return &GCPKMS{client: client, keyName: keyName}, nil
+}
+
+// Decrypt decrypts base64 ciphertext, as written by gcloud kms encrypt and
+// piped through base64.
+func (k *GCPKMS) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	blob, err := decodeBase64(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := k.client.Decrypt(ctx, &kmspb.DecryptRequest{Name: k.keyName, Ciphertext: blob})
+	if err != nil {
+		return nil, fmt.Errorf("gcp kms decrypt: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+// Close releases the connection to Cloud KMS
+func (k *GCPKMS) Close() error {
+	return k.client.Close()
+}
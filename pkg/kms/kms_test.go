@@ -0,0 +1,58 @@
+package kms
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+)
+
+// Compile-time checks that every provider satisfies Decrypter
+var (
+	_ Decrypter = NoopKMS{}
+	_ Decrypter = (*AWSKMS)(nil)
+	_ Decrypter = (*GCPKMS)(nil)
+)
+
+func TestNoopKMSDecrypt(t *testing.T) {
+	secret := "a-32-byte-secret-for-jwt-signing"
+	encoded := base64.StdEncoding.EncodeToString([]byte(secret))
+
+	for name, ciphertext := range map[string]string{
+		"plain":              encoded,
+		"trailing newline":   encoded + "\n",
+		"surrounding spaces": "  " + encoded + " ",
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := NoopKMS{}.Decrypt(context.Background(), []byte(ciphertext))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != secret {
+				t.Fatalf("Decrypt = %q, want %q", got, secret)
+			}
+		})
+	}
+}
+
+func TestNoopKMSRejectsInvalidBase64(t *testing.T) {
+	if _, err := (NoopKMS{}).Decrypt(context.Background(), []byte("not base64!")); err == nil {
+		t.Fatal("Decrypt accepted invalid base64")
+	}
+}
+
+func TestNew(t *testing.T) {
+	d, err := New(context.Background(), ProviderNoop, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.(NoopKMS); !ok {
+		t.Fatalf("New(noop) = %T, want NoopKMS", d)
+	}
+
+	if _, err := New(context.Background(), "vault", ""); err == nil {
+		t.Fatal("New accepted an unknown provider")
+	}
+	if _, err := New(context.Background(), ProviderGCP, ""); err == nil {
+		t.Fatal("New(gcp) accepted an empty key name")
+	}
+}
@@ -0,0 +1,219 @@
+// Package ldap authenticates users against an LDAP directory or Active Directory.
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Bind mechanisms
+const (
+	// MechanismSimple sends the bind DN and password as they are; use an
+	// ldaps:// address so the password is encrypted in transit
+	MechanismSimple = "simple"
+
+	// MechanismDigestMD5 binds with SASL DIGEST-MD5, which never sends the
+	// password itself. BindDN then holds the service account's SASL username.
+	MechanismDigestMD5 = "DIGEST-MD5"
+)
+
+// DefaultUserFilter finds users by email address
+const DefaultUserFilter = "(mail=%s)"
+
+var (
+	// ErrInvalidCredentials is returned when the user does not exist in the
+	// directory or the password is wrong
+	ErrInvalidCredentials = errors.New("invalid LDAP credentials")
+
+	// ErrUnsupportedMechanism is returned for bind mechanisms other than simple and DIGEST-MD5
+	ErrUnsupportedMechanism = errors.New("unsupported LDAP bind mechanism")
+)
+
+// LDAPUser is a directory user that authenticated successfully.
+type LDAPUser struct {
+	// DN is the user's distinguished name
+	DN string
+
+	// Username is the name the user signed in with
+	Username    string
+	DisplayName string
+	Email       string
+
+	// Groups lists the common names (CN) of the groups in the user's memberOf attribute
+	Groups []string
+}
+
+// Client authenticates users against an LDAP server. It finds the user's entry
+// with a service account, then binds as the user to check the password.
+type Client struct {
+	// Addr is the server URL, e.g. ldaps://ldap.example.com:636
+	Addr string
+
+	// BindDN and BindPassword identify the service account used to search for users;
+	// an empty BindDN searches anonymously
+	BindDN       string
+	BindPassword string
+
+	// UserBaseDN is where users are searched, e.g. ou=people,dc=example,dc=com
+	UserBaseDN string
+
+	// UserFilter finds a user's entry; every %s is replaced with the escaped
+	// username, e.g. (&(objectClass=user)(sAMAccountName=%s))
+	UserFilter string
+
+	// Mechanism is MechanismSimple (the default) or MechanismDigestMD5
+	Mechanism string
+
+	// Timeout bounds connecting and every request; zero uses 10 seconds
+	Timeout time.Duration
+}
+
+// NewClient creates an LDAP client using simple binds and DefaultUserFilter
+// when filter is empty.
+func NewClient(addr, bindDN, bindPassword, userBaseDN, filter string) *Client {
+	if filter == "" {
+		filter = DefaultUserFilter
+	}
+	return &Client{
+		Addr:         addr,
+		BindDN:       bindDN,
+		BindPassword: bindPassword,
+		UserBaseDN:   userBaseDN,
+		UserFilter:   filter,
+		Mechanism:    MechanismSimple,
+	}
+}
+
+// Authenticate checks username and password against the directory and returns
+// the user's profile. It returns ErrInvalidCredentials when the user is unknown
+// or the password is wrong, and other errors when the server cannot be used.
+func (c *Client) Authenticate(username, password string) (*LDAPUser, error) {
+	// An empty password would be an unauthenticated bind, which servers accept
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if c.BindDN != "" {
+		if err := c.bind(conn, c.BindDN, c.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind failed: %w", err)
+		}
+	}
+
+	entry, err := c.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// DIGEST-MD5 identifies the user by SASL username rather than DN
+	identity := entry.DN
+	if c.Mechanism == MechanismDigestMD5 {
+		identity = username
+	}
+	if err := c.bind(conn, identity, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	return &LDAPUser{
+		DN:          entry.DN,
+		Username:    username,
+		DisplayName: entry.GetAttributeValue("displayName"),
+		Email:       entry.GetAttributeValue("mail"),
+		Groups:      groupNames(entry.GetAttributeValues("memberOf")),
+	}, nil
+}
+
+// dial connects to the server.
+func (c *Client) dial() (*goldap.Conn, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	conn, err := goldap.DialURL(c.Addr, goldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connect failed: %w", err)
+	}
+	conn.SetTimeout(timeout)
+	return conn, nil
+}
+
+// bind authenticates the connection with the configured mechanism.
+func (c *Client) bind(conn *goldap.Conn, identity, password string) error {
+	switch c.Mechanism {
+	case "", MechanismSimple:
+		return conn.Bind(identity, password)
+	case MechanismDigestMD5:
+		host, err := c.host()
+		if err != nil {
+			return err
+		}
+		return conn.MD5Bind(host, identity, password)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedMechanism, c.Mechanism)
+	}
+}
+
+// findUser returns the single entry matching username.
+func (c *Client) findUser(conn *goldap.Conn, username string) (*goldap.Entry, error) {
+	filter := strings.ReplaceAll(c.UserFilter, "%s", goldap.EscapeFilter(username))
+	req := goldap.NewSearchRequest(
+		c.UserBaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, 0, false,
+		filter,
+		[]string{"dn", "displayName", "mail", "memberOf"},
+		nil,
+	)
+
+	result, err := conn.Search(req)
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap user search failed: %w", err)
+	}
+	// More than one match is ambiguous; refuse rather than pick one
+	if result == nil || len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+// host returns the server host name, which DIGEST-MD5 uses as its realm target.
+func (c *Client) host() (string, error) {
+	u, err := url.Parse(c.Addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid ldap address: %w", err)
+	}
+	return u.Hostname(), nil
+}
+
+// groupNames returns the CN of each group DN, skipping DNs without one.
+func groupNames(dns []string) []string {
+	groups := make([]string, 0, len(dns))
+	for _, raw := range dns {
+		dn, err := goldap.ParseDN(raw)
+		if err != nil || len(dn.RDNs) == 0 {
+			continue
+		}
+		for _, attr := range dn.RDNs[0].Attributes {
+			if strings.EqualFold(attr.Type, "cn") {
+				groups = append(groups, attr.Value)
+				break
+			}
+		}
+	}
+	return groups
+}
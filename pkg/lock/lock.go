@@ -0,0 +1,70 @@
+// Package lock provides short-lived distributed locks on Redis, so that an
+// operation such as redeeming a one-time code runs at most once at a time
+// across every instance of the service.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLockConflict is returned by Acquire while another holder has the lock
+var ErrLockConflict = errors.New("lock: already held")
+
+// releaseTimeout bounds the DEL of an unlock, which runs even after the
+// caller's context is done
+const releaseTimeout = 2 * time.Second
+
+// releaseScript deletes KEYS[1] only if it still holds ARGV[1], so an unlock
+// after the TTL expired never releases a lock someone else acquired since
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Locker acquires locks stored as Redis keys.
+type Locker struct {
+	rdb *redis.Client
+}
+
+// New creates a Locker on rdb.
+func New(rdb *redis.Client) *Locker {
+	return &Locker{rdb: rdb}
+}
+
+// Acquire takes the lock key for at most ttl with SET NX PX, and returns the
+// function that releases it. It returns ErrLockConflict without waiting when
+// the lock is held, and the Redis error when Redis cannot be reached. unlock
+// is safe to call more than once.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (unlock func(), err error) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	ok, err := l.rdb.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrLockConflict
+	}
+
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+		defer cancel()
+		// On failure the key still expires after ttl
+		_ = releaseScript.Run(ctx, l.rdb, []string{key}, token).Err()
+	}, nil
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// =============================================================================
+// Pluggable Logging Backends
+// =============================================================================
+
+// LogBackend is what the package-level Debug, Info, Warn, Error and Fatal
+// functions write to. keysAndValues are alternating keys and values, and may
+// also contain zap.Field values (e.g. zap.Error(err)); both backends below
+// accept either form.
+type LogBackend interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+	Fatal(msg string, keysAndValues ...interface{})
+}
+
+// backendHolder wraps the active backend so atomic.Value always stores the same concrete type.
+type backendHolder struct {
+	backend LogBackend
+	// explicit is true once SetBackend was called, so InitLogger leaves the choice alone
+	explicit bool
+}
+
+// current is the active backend. Until InitLogger or SetBackend run it is a
+// SlogBackend over slog.Default(), so early log lines still reach stderr.
+var current atomic.Value
+
+func init() {
+	current.Store(backendHolder{backend: NewSlogBackend(slog.Default())})
+}
+
+// SetBackend routes the package-level logging functions to backend, e.g.
+// logger.SetBackend(logger.NewZapBackend(logger.Sugar)) to skip the slog layer
+// for the sugared zap logger's lower per-call overhead. It is safe to call
+// concurrently with logging; a nil backend is ignored.
+func SetBackend(backend LogBackend) {
+	if backend == nil {
+		return
+	}
+	current.Store(backendHolder{backend: backend, explicit: true})
+}
+
+// Backend returns the backend the package-level logging functions write to.
+func Backend() LogBackend {
+	return current.Load().(backendHolder).backend
+}
+
+// useDefaultBackend installs backend unless SetBackend already chose one.
+func useDefaultBackend(backend LogBackend) {
+	if current.Load().(backendHolder).explicit {
+		return
+	}
+	current.Store(backendHolder{backend: backend})
+}
+
+// -----------------------------------------------------------------------------
+// Zap
+// -----------------------------------------------------------------------------
+
+// ZapBackend writes through a zap.SugaredLogger.
+type ZapBackend struct {
+	sugar *zap.SugaredLogger
+}
+
+// NewZapBackend creates a backend writing to sugar (typically logger.Sugar after
+// InitLogger); a nil sugar discards everything. The reported caller skips the
+// package-level function and the backend method, so it is the line that logged.
+func NewZapBackend(sugar *zap.SugaredLogger) *ZapBackend {
+	if sugar == nil {
+		sugar = zap.NewNop().Sugar()
+	}
+	return &ZapBackend{sugar: sugar.WithOptions(zap.AddCallerSkip(2))}
+}
+
+func (b *ZapBackend) Debug(msg string, keysAndValues ...interface{}) {
+	b.sugar.Debugw(msg, keysAndValues...)
+}
+
+func (b *ZapBackend) Info(msg string, keysAndValues ...interface{}) {
+	b.sugar.Infow(msg, keysAndValues...)
+}
+
+func (b *ZapBackend) Warn(msg string, keysAndValues ...interface{}) {
+	b.sugar.Warnw(msg, keysAndValues...)
+}
+
+func (b *ZapBackend) Error(msg string, keysAndValues ...interface{}) {
+	b.sugar.Errorw(msg, keysAndValues...)
+}
+
+// Fatal logs msg and exits the process with status 1.
+func (b *ZapBackend) Fatal(msg string, keysAndValues ...interface{}) {
+	b.sugar.Fatalw(msg, keysAndValues...)
+}
+
+// -----------------------------------------------------------------------------
+// slog
+// -----------------------------------------------------------------------------
+
+// SlogBackend writes through a log/slog Logger.
+type SlogBackend struct {
+	logger *slog.Logger
+}
+
+// NewSlogBackend creates a backend writing to logger. InitLogger installs one
+// over the configured zap core, so output format and level are the same as
+// with ZapBackend.
+func NewSlogBackend(logger *slog.Logger) *SlogBackend {
+	return &SlogBackend{logger: logger}
+}
+
+func (b *SlogBackend) Debug(msg string, keysAndValues ...interface{}) {
+	b.log(slog.LevelDebug, msg, keysAndValues)
+}
+
+func (b *SlogBackend) Info(msg string, keysAndValues ...interface{}) {
+	b.log(slog.LevelInfo, msg, keysAndValues)
+}
+
+func (b *SlogBackend) Warn(msg string, keysAndValues ...interface{}) {
+	b.log(slog.LevelWarn, msg, keysAndValues)
+}
+
+func (b *SlogBackend) Error(msg string, keysAndValues ...interface{}) {
+	b.log(slog.LevelError, msg, keysAndValues)
+}
+
+// Fatal logs msg at error level (slog has no fatal level) and exits the
+// process with status 1, flushing the zap logger first if there is one.
+func (b *SlogBackend) Fatal(msg string, keysAndValues ...interface{}) {
+	b.log(slog.LevelError, msg, keysAndValues)
+	_ = Sync()
+	os.Exit(1)
+}
+
+func (b *SlogBackend) log(level slog.Level, msg string, keysAndValues []interface{}) {
+	b.logger.Log(context.Background(), level, msg, slogArgs(keysAndValues)...)
+}
+
+// slogArgs converts zap.Field values, which call sites pass alongside plain
+// key/value pairs, into slog attributes; everything else is passed as-is.
+func slogArgs(keysAndValues []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(keysAndValues))
+	for _, kv := range keysAndValues {
+		field, ok := kv.(zapcore.Field)
+		if !ok {
+			args = append(args, kv)
+			continue
+		}
+		enc := zapcore.NewMapObjectEncoder()
+		field.AddTo(enc)
+		for key, value := range enc.Fields {
+			args = append(args, slog.Any(key, value))
+		}
+	}
+	return args
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/exp/zapslog"
+)
+
+// CorrelationIDHeader carries the correlation ID on requests and responses.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// correlationIDKey stores the correlation ID in the Gin context (c.Get) ...
+const correlationIDKey = "correlationID"
+
+// ... and correlationCtxKey stores it in the request context, for code that only sees c.Request.Context().
+type correlationCtxKey struct{}
+
+// requestIDCtxKey stores the client-supplied (or generated) X-Request-ID in the request context.
+type requestIDCtxKey struct{}
+
+// maxCorrelationIDLength caps incoming IDs so clients can't inflate every log line.
+const maxCorrelationIDLength = 128
+
+// CorrelationMiddleware assigns every request a correlation ID (reusing a well-formed
+// incoming X-Correlation-ID header, otherwise generating a UUID), echoes it in the
+// response header, and logs one structured line per request/response pair.
+func CorrelationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(CorrelationIDHeader)
+		if !validCorrelationID(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set(correlationIDKey, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), correlationCtxKey{}, id))
+		c.Header(CorrelationIDHeader, id)
+
+		c.Next()
+
+		requestSize := c.Request.ContentLength
+		if requestSize < 0 {
+			requestSize = 0
+		}
+
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int64("request_size", requestSize),
+			zap.Int("response_size", c.Writer.Size()),
+			zap.String("correlation_id", id),
+		}
+
+		// Set by middleware.RequestID, which runs before this middleware
+		if requestID := RequestIDFromContext(c.Request.Context()); requestID != "" {
+			fields = append(fields, zap.String("request_id", requestID))
+		}
+
+		// userID is set by the auth middleware on protected routes
+		if userID, ok := c.Get("userID"); ok {
+			fields = append(fields, zap.Any("user_id", userID))
+		}
+
+		if Logger == nil {
+			return
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.Strings("errors", c.Errors.Errors()))
+			Logger.Error("request completed with errors", fields...)
+		} else {
+			Logger.Info("request completed", fields...)
+		}
+	}
+}
+
+// CorrelationID returns the correlation ID stored in ctx, which may be a *gin.Context
+// or a request context derived from one. It returns "" if none is set.
+func CorrelationID(ctx context.Context) string {
+	if c, ok := ctx.(*gin.Context); ok {
+		return c.GetString(correlationIDKey)
+	}
+	id, _ := ctx.Value(correlationCtxKey{}).(string)
+	return id
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID, which FromContext
+// and the request log line include as request_id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID, or "" if none is set.
+// A *gin.Context is looked up through its request context.
+func RequestIDFromContext(ctx context.Context) string {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	id, _ := ctx.Value(requestIDCtxKey{}).(string)
+	return id
+}
+
+// FromContext returns a *slog.Logger that writes through the global zap logger and
+// is pre-seeded with the request's correlation ID and request ID.
+func FromContext(ctx context.Context) *slog.Logger {
+	var l *slog.Logger
+	if Logger != nil {
+		l = slog.New(zapslog.NewHandler(Logger.Core()))
+	} else {
+		l = slog.Default()
+	}
+
+	if id := CorrelationID(ctx); id != "" {
+		l = l.With("correlation_id", id)
+	}
+	if id := RequestIDFromContext(ctx); id != "" {
+		l = l.With("request_id", id)
+	}
+	return l
+}
+
+// validCorrelationID accepts non-empty, reasonably short IDs of printable ASCII.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"log/slog"
 	"sync"
 
 	"go.uber.org/zap"
+	"go.uber.org/zap/exp/zapslog"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -60,51 +62,45 @@ func InitLogger(isProduction bool) error {
 		
 		// Create the sugared wrapper and assign it to the global Sugar variable.
 		Sugar = Logger.Sugar()
+
+		// Route the package-level functions through slog over the zap core,
+		// unless SetBackend already picked a backend (e.g. ZapBackend)
+		useDefaultBackend(NewSlogBackend(slog.New(zapslog.NewHandler(Logger.Core()))))
 	})
 	
 	// Return the local err variable, which holds any error captured inside once.Do.
 	return err
 }
 
-// Debug logs a debug message using the sugared logger.
+// Debug logs a debug message using the active backend (see SetBackend).
 // Debug messages should be detailed and used primarily during development/troubleshooting.
 // It accepts alternating key-value pairs (e.g., "user_id", 42).
 func Debug(msg string, keysAndValues ...interface{}) {
-	if Sugar != nil {
-		Sugar.Debugw(msg, keysAndValues...)
-	}
+	Backend().Debug(msg, keysAndValues...)
 }
 
-// Info logs an info message using the sugared logger.
+// Info logs an info message using the active backend (see SetBackend).
 // Info messages represent normal, expected application events (e.g., server started, request processed).
 func Info(msg string, keysAndValues ...interface{}) {
-	if Sugar != nil {
-		Sugar.Infow(msg, keysAndValues...)
-	}
+	Backend().Info(msg, keysAndValues...)
 }
 
-// Warn logs a warning message using the sugared logger.
+// Warn logs a warning message using the active backend (see SetBackend).
 // Warnings indicate unusual events that might be non-critical but should be noted (e.g., deprecated API use).
 func Warn(msg string, keysAndValues ...interface{}) {
-	if Sugar != nil {
-		Sugar.Warnw(msg, keysAndValues...)
-	}
+	Backend().Warn(msg, keysAndValues...)
 }
 
-// Error logs an error message using the sugared logger.
+// Error logs an error message using the active backend (see SetBackend).
 // Errors indicate unexpected failures that should be investigated (e.g., database connection failure).
 func Error(msg string, keysAndValues ...interface{}) {
-	if Sugar != nil {
-		Sugar.Errorw(msg, keysAndValues...)
-	}
+	Backend().Error(msg, keysAndValues...)
 }
 
-// Fatal logs a fatal message then calls os.Exit(1), using the sugared logger.
+// Fatal logs a fatal message then calls os.Exit(1), using the active backend (see SetBackend).
 // Fatal errors mean the application cannot recover and must shut down immediately.
 func Fatal(msg string, keysAndValues ...interface{}) {
-	if Sugar != nil {
-		Sugar.Fatalw(msg, keysAndValues...)
-	}
+	Backend().Fatal(msg, keysAndValues...)
 }
 
 
@@ -0,0 +1,91 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const (
+	githubUserURL   = "https://api.github.com/user"
+	githubEmailsURL = "https://api.github.com/user/emails"
+)
+
+type githubProvider struct {
+	oauth2Provider
+}
+
+// NewGitHubProvider creates a GitHub OAuth2 provider requesting read access to the user's emails.
+func NewGitHubProvider(clientID, clientSecret, redirectURL string) Provider {
+	return &githubProvider{newOAuth2Provider(ProviderGitHub, &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"read:user", "user:email"},
+		Endpoint:     github.Endpoint,
+	})}
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*Identity, error) {
+	client, token, err := p.exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, client, githubUserURL, &user); err != nil {
+		return nil, err
+	}
+
+	// The public profile email may be hidden, so always read the primary address
+	email, verified, err := githubPrimaryEmail(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	// GitHub only has a single display name; fall back to the login
+	firstName, lastName := user.Login, ""
+	if name := strings.TrimSpace(user.Name); name != "" {
+		firstName, lastName, _ = strings.Cut(name, " ")
+	}
+
+	return &Identity{
+		Provider:      ProviderGitHub,
+		ProviderID:    strconv.FormatInt(user.ID, 10),
+		Email:         email,
+		EmailVerified: verified,
+		FirstName:     firstName,
+		LastName:      lastName,
+		AvatarURL:     user.AvatarURL,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+	}, nil
+}
+
+// githubPrimaryEmail returns the user's primary email address and whether GitHub has verified it.
+func githubPrimaryEmail(ctx context.Context, client *http.Client) (string, bool, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, githubEmailsURL, &emails); err != nil {
+		return "", false, err
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			return e.Email, e.Verified, nil
+		}
+	}
+	return "", false, ErrMissingEmail
+}
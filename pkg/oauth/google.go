@@ -0,0 +1,59 @@
+package oauth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const googleUserInfoURL = "https://openidconnect.googleapis.com/v1/userinfo"
+
+type googleProvider struct {
+	oauth2Provider
+}
+
+// NewGoogleProvider creates a Google OAuth2 provider requesting the email and profile scopes.
+func NewGoogleProvider(clientID, clientSecret, redirectURL string) Provider {
+	return &googleProvider{newOAuth2Provider(ProviderGoogle, &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Endpoint:     google.Endpoint,
+	})}
+}
+
+func (p *googleProvider) Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*Identity, error) {
+	client, token, err := p.exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		GivenName     string `json:"given_name"`
+		FamilyName    string `json:"family_name"`
+		Picture       string `json:"picture"`
+	}
+	if err := getJSON(ctx, client, googleUserInfoURL, &info); err != nil {
+		return nil, err
+	}
+	if info.Email == "" {
+		return nil, ErrMissingEmail
+	}
+
+	return &Identity{
+		Provider:      ProviderGoogle,
+		ProviderID:    info.Sub,
+		Email:         info.Email,
+		EmailVerified: info.EmailVerified,
+		FirstName:     info.GivenName,
+		LastName:      info.FamilyName,
+		AvatarURL:     info.Picture,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+	}, nil
+}
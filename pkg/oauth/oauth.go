@@ -0,0 +1,151 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"authentio/pkg/circuit"
+
+	"golang.org/x/oauth2"
+)
+
+// Supported provider names, as used in /auth/oauth/:provider routes.
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// Provider calls time out after HTTPTimeout. After breakerFailures consecutive
+// failures a provider's breaker opens for breakerTimeout, and breakerSuccesses
+// consecutive successes close it again.
+const (
+	HTTPTimeout = 10 * time.Second
+
+	breakerFailures  = 5
+	breakerSuccesses = 2
+	breakerTimeout   = 30 * time.Second
+)
+
+var (
+	// ErrUnknownProvider is returned when a provider name is not configured
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+
+	// ErrMissingEmail is returned when the provider does not disclose an email address
+	ErrMissingEmail = errors.New("oauth provider did not return an email address")
+)
+
+// Identity is the user profile returned by a provider after a successful code exchange.
+type Identity struct {
+	Provider      string
+	ProviderID    string // the provider's stable user ID (never the email, which can change)
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	AvatarURL     string
+
+	// AccessToken and RefreshToken are the tokens issued by the exchange, for
+	// calling the provider's API on the user's behalf (RefreshToken may be empty)
+	AccessToken  string
+	RefreshToken string
+}
+
+// Provider is an OAuth2 authorization-code provider.
+type Provider interface {
+	// Name returns the provider name, e.g. "google"
+	Name() string
+
+	// AuthCodeURL returns the consent screen URL the user is redirected to.
+	// Pass oauth2.S256ChallengeOption to start a PKCE flow.
+	AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string
+
+	// Exchange trades an authorization code for the user's identity.
+	// Pass oauth2.VerifierOption to complete a PKCE flow.
+	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*Identity, error)
+
+	// Breaker returns the circuit breaker guarding calls to the provider.
+	// While it is open, Exchange fails with circuit.ErrCircuitOpen.
+	Breaker() *circuit.Breaker
+}
+
+// NewBreaker creates the circuit breaker for calls to a provider, named after it (e.g. "oauth_google").
+func NewBreaker(provider string) *circuit.Breaker {
+	return circuit.New("oauth_"+provider, breakerFailures, breakerSuccesses, breakerTimeout)
+}
+
+// NewHTTPClient returns a client for provider calls that times out after
+// HTTPTimeout and sends every request through breaker.
+func NewHTTPClient(breaker *circuit.Breaker) *http.Client {
+	return &http.Client{
+		Timeout:   HTTPTimeout,
+		Transport: &circuit.Transport{Breaker: breaker},
+	}
+}
+
+// oauth2Provider holds what every authorization-code provider needs.
+type oauth2Provider struct {
+	name    string
+	config  *oauth2.Config
+	breaker *circuit.Breaker
+	client  *http.Client // used for the token exchange and the API calls made with the token
+}
+
+func newOAuth2Provider(name string, config *oauth2.Config) oauth2Provider {
+	breaker := NewBreaker(name)
+	return oauth2Provider{
+		name:    name,
+		config:  config,
+		breaker: breaker,
+		client:  NewHTTPClient(breaker),
+	}
+}
+
+func (p *oauth2Provider) Name() string {
+	return p.name
+}
+
+func (p *oauth2Provider) Breaker() *circuit.Breaker {
+	return p.breaker
+}
+
+func (p *oauth2Provider) AuthCodeURL(state string, opts ...oauth2.AuthCodeOption) string {
+	return p.config.AuthCodeURL(state, opts...)
+}
+
+// exchange trades the code for a token and returns it with an HTTP client that sends it.
+func (p *oauth2Provider) exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*http.Client, *oauth2.Token, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := p.config.Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	client := p.config.Client(ctx, token) // keeps p.client's transport, but not its timeout
+	client.Timeout = HTTPTimeout
+	return client, token, nil
+}
+
+// getJSON fetches url with the authenticated client and decodes the JSON body into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: unexpected status %d: %s", url, resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
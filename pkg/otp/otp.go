@@ -0,0 +1,83 @@
+// Package otp generates and checks the one-time codes sent by email or SMS
+// for 2FA and password resets. TOTP codes from authenticator apps live in
+// pkg/totp.
+package otp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Bounds on code length: shorter codes are too easy to guess, longer ones are
+// a pain to type and SMS templates are not laid out for them.
+const (
+	MinLength     = 4
+	MaxLength     = 8
+	DefaultLength = 6
+)
+
+var (
+	// ErrInvalidLength is returned by Generate for a length outside MinLength..MaxLength
+	ErrInvalidLength = fmt.Errorf("otp: length must be between %d and %d", MinLength, MaxLength)
+
+	// ErrExpired is returned by Verify once ttl has passed since the code was generated
+	ErrExpired = errors.New("otp: code expired")
+
+	// ErrMismatch is returned by Verify when the code does not match the hash
+	ErrMismatch = errors.New("otp: code does not match")
+)
+
+// Generate returns a random numeric code of the given length. Each digit is
+// drawn uniformly from crypto/rand, so leading zeros are as likely as any
+// other digit.
+func Generate(length int) (string, error) {
+	if length < MinLength || length > MaxLength {
+		return "", ErrInvalidLength
+	}
+
+	ten := big.NewInt(10)
+	code := make([]byte, length)
+	for i := range code {
+		d, err := rand.Int(rand.Reader, ten)
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + d.Int64())
+	}
+	return string(code), nil
+}
+
+// Hash returns the hex-encoded SHA-256 digest of code, the form in which codes
+// are stored when they are not encrypted.
+func Hash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsHash reports whether s looks like the output of Hash.
+func IsHash(s string) bool {
+	if len(s) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Verify checks code against hash, a value returned by Hash, and that the code
+// generated at generatedAt is still within ttl. The hashes are compared in
+// constant time. A code that is both wrong and expired reports ErrExpired.
+func Verify(code, hash string, generatedAt time.Time, ttl time.Duration) error {
+	if !time.Now().Before(generatedAt.Add(ttl)) {
+		return ErrExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(Hash(code)), []byte(hash)) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package otp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// FuzzVerifyOTP checks that Verify never panics and only ever fails with
+// ErrMismatch or ErrExpired, accepting exactly the unexpired codes of the hash.
+func FuzzVerifyOTP(f *testing.F) {
+	for _, code := range []string{
+		"", "0", "1234", "123456", "00000000", "123456789012",
+		"abcdef", "12 34", "12-34-56", "١٢٣٤٥٦", "１２３４５６", "\x00\xff", "<script>",
+	} {
+		f.Add(code, Hash(code), int64(0))
+		f.Add(code, Hash(code+"x"), int64(0))
+		f.Add(code, Hash(code), int64(10*time.Minute))
+	}
+	f.Add("123456", "", int64(0))
+	f.Add("123456", "not-a-hash", int64(-time.Minute))
+
+	const ttl = 5 * time.Minute
+	f.Fuzz(func(t *testing.T, code, hash string, age int64) {
+		generatedAt := time.Now().Add(-time.Duration(age))
+		err := Verify(code, hash, generatedAt, ttl)
+		if err != nil && !errors.Is(err, ErrMismatch) && !errors.Is(err, ErrExpired) {
+			t.Fatalf("Verify(%q, %q) returned non-sentinel error %v", code, hash, err)
+		}
+		if err == nil && (time.Duration(age) >= ttl || Hash(code) != hash) {
+			t.Fatalf("Verify(%q, %q) accepted an expired or wrong code", code, hash)
+		}
+	})
+}
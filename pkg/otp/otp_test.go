@@ -0,0 +1,85 @@
+package otp
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+const trials = 10000
+
+// TestGenerateFormat checks the property that every generated code has the
+// requested length and only ASCII digits, and that out-of-range lengths fail.
+func TestGenerateFormat(t *testing.T) {
+	property := func(n uint8) bool {
+		length := int(n % (MaxLength + 3))
+		code, err := Generate(length)
+		if length < MinLength || length > MaxLength {
+			return err == ErrInvalidLength && code == ""
+		}
+		if err != nil || len(code) != length {
+			return false
+		}
+		for _, c := range []byte(code) {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: trials}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGenerateUniqueness draws 10 000 codes per length and counts repeats.
+// With 10^length possible codes some repeats are expected (the birthday bound
+// gives about 50 for six digits), so the count must stay within six standard
+// deviations of that expectation. A biased or sequential generator falls
+// outside the band on one side or the other.
+func TestGenerateUniqueness(t *testing.T) {
+	for length := DefaultLength; length <= MaxLength; length++ {
+		seen := make(map[string]struct{}, trials)
+		for range trials {
+			code, err := Generate(length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen[code] = struct{}{}
+		}
+
+		expected := float64(trials) * (trials - 1) / 2 / math.Pow10(length)
+		spread := 6 * math.Sqrt(expected)
+		repeats := float64(trials - len(seen))
+		if repeats > expected+spread+3 || repeats < expected-spread {
+			t.Errorf("length %d: %v repeats in %d codes, expected about %.1f", length, repeats, trials, expected)
+		}
+	}
+}
+
+// TestGenerateDigitDistribution runs a chi-squared test on each digit position
+// over 10 000 codes. The threshold is far beyond the 0.1% critical value for
+// nine degrees of freedom (27.9), so a fair generator does not trip it.
+func TestGenerateDigitDistribution(t *testing.T) {
+	var counts [DefaultLength][10]int
+	for range trials {
+		code, err := Generate(DefaultLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, c := range []byte(code) {
+			counts[i][c-'0']++
+		}
+	}
+
+	const want = trials / 10.0
+	for pos, digits := range counts {
+		var chi float64
+		for _, n := range digits {
+			chi += (float64(n) - want) * (float64(n) - want) / want
+		}
+		if chi > 50 {
+			t.Errorf("position %d: chi-squared %.1f, digit counts %v", pos, chi, digits)
+		}
+	}
+}
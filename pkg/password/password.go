@@ -1,21 +1,33 @@
 package password
 
 import (
+	"fmt"
+
 	"golang.org/x/crypto/bcrypt"
-	"strconv"
-	"os"
 )
 
-// Hash hashes a password using bcrypt
-func Hash(password string) (string, error) {
-		cost := bcrypt.DefaultCost 
-	if c := os.Getenv("BCRYPT_COST"); c != "" {
-		if parsed, err := strconv.Atoi(c); err == nil {
-			cost = parsed
-		}
+// DefaultCost is the bcrypt cost used by Hash. Change it with SetDefaultCost.
+var DefaultCost = bcrypt.DefaultCost
+
+// SetDefaultCost sets the bcrypt cost used by Hash. The cost must be between
+// bcrypt.MinCost (4) and bcrypt.MaxCost (31). Call it once at startup, before
+// any passwords are hashed.
+func SetDefaultCost(cost int) error {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
 	}
+	DefaultCost = cost
+	return nil
+}
+
+// Hash hashes a password using bcrypt with DefaultCost
+func Hash(password string) (string, error) {
+	return HashWithCost(password, DefaultCost)
+}
 
-	 hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+// HashWithCost hashes a password using bcrypt with an explicit cost
+func HashWithCost(password string, cost int) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
 	if err != nil {
 		return "", err
 	}
@@ -26,4 +38,17 @@ func Hash(password string) (string, error) {
 func Check(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
-}
\ No newline at end of file
+}
+
+// NeedsRehash reports whether a bcrypt hash was produced with a lower cost than
+// targetCost, so it should be replaced with a fresh Hash of the password once the
+// user has proved they know it. Only the cost prefix of the hash is parsed, so the
+// check takes the same time whatever the password is. Hashes that are not bcrypt,
+// or cannot be parsed, never need a rehash.
+func NeedsRehash(hash string, targetCost int) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return cost < targetCost
+}
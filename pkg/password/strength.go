@@ -0,0 +1,257 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Strength labels returned in StrengthResult.Label, from weakest to strongest
+const (
+	StrengthVeryWeak   = "very_weak"
+	StrengthWeak       = "weak"
+	StrengthFair       = "fair"
+	StrengthStrong     = "strong"
+	StrengthVeryStrong = "very_strong"
+)
+
+// GuessesPerSecond is the offline attack rate CrackTimeSec assumes: a GPU rig
+// against a fast, unsalted hash. bcrypt makes real attacks far slower, so the
+// estimate is deliberately pessimistic.
+const GuessesPerSecond = 1e10
+
+// strengthBands are the entropy, in bits, below which each label applies
+var strengthBands = []struct {
+	below float64
+	label string
+}{
+	{28, StrengthVeryWeak},
+	{36, StrengthWeak},
+	{60, StrengthFair},
+	{128, StrengthStrong},
+}
+
+// keyboardRows are walked by patterns like "qwerty" or "0987"
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// leetSubstitutions maps look-alike characters back to the letters they replace
+var leetSubstitutions = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '@': 'a', '$': 's', '!': 'i',
+}
+
+// StrengthResult is the estimated strength of a password
+type StrengthResult struct {
+	Entropy      float64 `json:"entropy"`        // estimated entropy in bits
+	CrackTimeSec float64 `json:"crack_time_sec"` // average seconds to guess it at GuessesPerSecond
+	Label        string  `json:"label"`          // one of the Strength* labels
+}
+
+// EstimateStrength estimates how hard a password is to guess, in the spirit of
+// zxcvbn but much simpler. Every character is worth log2 of the size of the
+// character classes the password uses, except characters that follow a
+// predictable pattern, which are worth far less:
+//   - a common password, possibly capitalized, in leetspeak or with digits and
+//     symbols around it, is worth no more than picking one from the list
+//   - repeated characters ("aaaa"), sequences ("abcd", "9876") and keyboard
+//     walks ("qwerty") are worth about one bit per character after the first
+//   - a year between 1900 and 2099 is worth picking one of 200
+func EstimateStrength(password string) StrengthResult {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return StrengthResult{Label: StrengthVeryWeak}
+	}
+
+	bits := make([]float64, len(runes))
+	perChar := math.Log2(float64(charsetSize(runes)))
+	for i := range bits {
+		bits[i] = perChar
+	}
+
+	lower := []rune(strings.ToLower(password))
+	if len(lower) != len(runes) {
+		lower = runes // lowercasing changed the length; skip case folding
+	}
+	markRepeatsAndSequences(lower, bits)
+	markKeyboardWalks(lower, bits)
+	markYears(runes, bits)
+	markCommonPassword(runes, bits)
+
+	var entropy float64
+	for _, b := range bits {
+		entropy += b
+	}
+	return StrengthResult{
+		Entropy:      entropy,
+		CrackTimeSec: math.Pow(2, entropy) / 2 / GuessesPerSecond,
+		Label:        strengthLabel(entropy),
+	}
+}
+
+// strengthLabel returns the label of the band entropy falls in
+func strengthLabel(entropy float64) string {
+	for _, band := range strengthBands {
+		if entropy < band.below {
+			return band.label
+		}
+	}
+	return StrengthVeryStrong
+}
+
+// charsetSize returns the number of characters an attacker must try per
+// position, given the character classes the password uses.
+func charsetSize(runes []rune) int {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	size := 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{hasLower, 26}, {hasUpper, 26}, {hasDigit, 10}, {hasSymbol, 33}, {hasOther, 100}} {
+		if class.used {
+			size += class.size
+		}
+	}
+	return size
+}
+
+// markRepeatsAndSequences lowers the worth of characters that repeat the
+// previous one or continue a run of consecutive code points ("abc", "321").
+func markRepeatsAndSequences(runes []rune, bits []float64) {
+	for i := 1; i < len(runes); i++ {
+		delta := runes[i] - runes[i-1]
+		switch {
+		case delta == 0:
+			bits[i] = math.Min(bits[i], 1)
+		case (delta == 1 || delta == -1) && i >= 2 && runes[i-1]-runes[i-2] == delta:
+			bits[i-1] = math.Min(bits[i-1], 1)
+			bits[i] = math.Min(bits[i], 1)
+		}
+	}
+}
+
+// markKeyboardWalks lowers the worth of runs of three or more characters that
+// are adjacent on a keyboard row, in either direction.
+func markKeyboardWalks(runes []rune, bits []float64) {
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && keyboardAdjacent(runes[end-1], runes[end]) {
+			end++
+		}
+		if end-start >= 3 {
+			for i := start + 1; i < end; i++ {
+				bits[i] = math.Min(bits[i], 1)
+			}
+		}
+		start = end
+	}
+}
+
+// keyboardAdjacent reports whether b is next to a on the same keyboard row
+func keyboardAdjacent(a, b rune) bool {
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		if i < 0 {
+			continue
+		}
+		if (i > 0 && rune(row[i-1]) == b) || (i+1 < len(row) && rune(row[i+1]) == b) {
+			return true
+		}
+	}
+	return false
+}
+
+// markYears lowers four-digit runs from 1900 to 2099 to log2(200) bits in total.
+func markYears(runes []rune, bits []float64) {
+	perDigit := math.Log2(200) / 4
+	for i := 0; i+4 <= len(runes); i++ {
+		if !allDigits(runes[i:i+4]) || (i > 0 && unicode.IsDigit(runes[i-1])) || (i+4 < len(runes) && unicode.IsDigit(runes[i+4])) {
+			continue
+		}
+		year := string(runes[i : i+4])
+		if year >= "1900" && year <= "2099" {
+			for j := i; j < i+4; j++ {
+				bits[j] = math.Min(bits[j], perDigit)
+			}
+		}
+	}
+}
+
+// markCommonPassword finds common passwords in the password: the password as
+// a whole, or words in it once lowercased and decoded from leetspeak, so
+// "P@ssw0rd1!" and "Summer1999" are found too. Each match is worth log2 of the
+// list size, plus a bit for each substitution and capital letter.
+func markCommonPassword(runes []rune, bits []float64) {
+	listBits := math.Log2(float64(commonPasswordCount()))
+	if IsCommonPassword(string(runes)) {
+		spread(bits, 0, len(runes), listBits)
+		return
+	}
+
+	decoded := make([]rune, len(runes))
+	extra := make([]float64, len(runes))
+	for i, r := range runes {
+		if plain, ok := leetSubstitutions[r]; ok {
+			decoded[i], extra[i] = plain, 1
+		} else if unicode.IsUpper(r) {
+			decoded[i], extra[i] = unicode.ToLower(r), 1
+		} else {
+			decoded[i] = r
+		}
+	}
+
+	// Longest match first, left to right; words shorter than 4 are too
+	// ambiguous to count
+	for start := 0; start+4 <= len(decoded); start++ {
+		for end := len(decoded); end >= start+4; end-- {
+			if !IsCommonPassword(string(decoded[start:end])) {
+				continue
+			}
+			total := listBits
+			for _, e := range extra[start:end] {
+				total += e
+			}
+			spread(bits, start, end, total)
+			start = end - 1
+			break
+		}
+	}
+}
+
+// spread gives runes start to end a combined worth of total bits, unless they
+// are already worth less.
+func spread(bits []float64, start, end int, total float64) {
+	var current float64
+	for _, b := range bits[start:end] {
+		current += b
+	}
+	if current <= total {
+		return
+	}
+	for i := start; i < end; i++ {
+		bits[i] = total / float64(end-start)
+	}
+}
+
+// allDigits reports whether every rune is an ASCII digit
+func allDigits(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
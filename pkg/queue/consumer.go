@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"authentio/pkg/email"
+	"authentio/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler processes one job. Returning an error leaves the job pending, so it is
+// retried after the consumer's ClaimIdle.
+type Handler func(ctx context.Context, job EmailJob) error
+
+// Consumer reads jobs from a stream as part of a consumer group.
+type Consumer struct {
+	rdb    *redis.Client
+	stream string
+	group  string
+	name   string
+	handle Handler
+
+	// Block is how long a read waits for new entries
+	Block time.Duration
+
+	// ClaimIdle is how long a job may stay unacknowledged before another
+	// worker takes it over (failed jobs and jobs of crashed workers)
+	ClaimIdle time.Duration
+
+	// MaxAttempts is how many deliveries a job gets before it is dropped
+	MaxAttempts int64
+}
+
+// NewConsumer creates a consumer. name identifies this process within the group;
+// StartWorker derives a distinct name for each of its workers.
+func NewConsumer(rdb *redis.Client, stream, group, name string, handle Handler) *Consumer {
+	return &Consumer{
+		rdb:         rdb,
+		stream:      stream,
+		group:       group,
+		name:        name,
+		handle:      handle,
+		Block:       5 * time.Second,
+		ClaimIdle:   time.Minute,
+		MaxAttempts: 5,
+	}
+}
+
+// EmailHandler returns a Handler that delivers jobs with client. The client must
+// send directly (not through an Outbox), or jobs would be enqueued again.
+func EmailHandler(client *email.Client) Handler {
+	return func(ctx context.Context, job EmailJob) error {
+		if job.Template != "" {
+			return client.SendTemplate(ctx, job.To, job.Template, job.TemplateData)
+		}
+		return client.SendMessage(ctx, email.Message{To: job.To, Subject: job.Subject, HTML: job.Body, Text: job.Text})
+	}
+}
+
+// StartWorker creates the consumer group if needed and starts concurrency workers.
+// It returns once they are running; the workers stop when ctx is cancelled. Jobs
+// being processed at that moment are not acknowledged and will be redelivered.
+func StartWorker(ctx context.Context, c *Consumer, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	err := c.rdb.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create consumer group: %w", err)
+	}
+
+	for i := 0; i < concurrency; i++ {
+		go c.run(ctx, fmt.Sprintf("%s-%d", c.name, i))
+	}
+	logger.Info("queue workers started", "stream", c.stream, "group", c.group, "concurrency", concurrency)
+	return nil
+}
+
+// run reads and processes jobs until ctx is cancelled.
+func (c *Consumer) run(ctx context.Context, consumer string) {
+	var lastClaim time.Time
+	for ctx.Err() == nil {
+		// Periodically take over jobs that failed or whose worker died
+		if time.Since(lastClaim) >= c.ClaimIdle/2 {
+			c.reclaim(ctx, consumer)
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    1,
+			Block:    c.Block,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn("queue read failed", "stream", c.stream, "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, s := range streams {
+			for _, msg := range s.Messages {
+				c.process(ctx, msg)
+			}
+		}
+	}
+}
+
+// reclaim claims jobs pending longer than ClaimIdle, dropping those that have
+// exhausted MaxAttempts and retrying the rest.
+func (c *Consumer) reclaim(ctx context.Context, consumer string) {
+	msgs, _, err := c.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: consumer,
+		MinIdle:  c.ClaimIdle,
+		Start:    "0-0",
+		Count:    10,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("queue reclaim failed", "stream", c.stream, "error", err)
+		}
+		return
+	}
+
+	for _, msg := range msgs {
+		pending, err := c.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: c.stream,
+			Group:  c.group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err == nil && len(pending) == 1 && pending[0].RetryCount > c.MaxAttempts {
+			logger.Error("queue job dropped after too many attempts", "stream", c.stream, "id", msg.ID, "attempts", pending[0].RetryCount)
+			c.ack(ctx, msg.ID)
+			continue
+		}
+		c.process(ctx, msg)
+	}
+}
+
+// process decodes and handles one entry, acknowledging it on success.
+func (c *Consumer) process(ctx context.Context, msg redis.XMessage) {
+	raw, _ := msg.Values[jobField].(string)
+
+	var job EmailJob
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		// A malformed entry can never succeed; acknowledge it so it is not retried
+		logger.Error("queue job is malformed", "stream", c.stream, "id", msg.ID, "error", err)
+		c.ack(ctx, msg.ID)
+		return
+	}
+
+	if err := c.handle(ctx, job); err != nil {
+		logger.Warn("queue job failed, will retry", "stream", c.stream, "id", msg.ID, "error", err)
+		return
+	}
+	c.ack(ctx, msg.ID)
+}
+
+func (c *Consumer) ack(ctx context.Context, id string) {
+	if err := c.rdb.XAck(ctx, c.stream, c.group, id).Err(); err != nil {
+		logger.Warn("queue ack failed", "stream", c.stream, "id", id, "error", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package queue moves slow side effects such as email delivery out of the request
+// path. Jobs are appended to a Redis Stream and processed by a consumer group, so
+// every job is delivered at least once even if a worker dies mid-way.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"authentio/pkg/email"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// jobField is the stream entry field holding the JSON-encoded job
+const jobField = "job"
+
+// DefaultMaxLen caps the stream length; acknowledged entries beyond it are trimmed
+const DefaultMaxLen = 10000
+
+// enqueueTimeout bounds EnqueueEmail, which outlives the caller's cancellation
+const enqueueTimeout = 3 * time.Second
+
+// EmailJob is an email waiting to be sent. Either Body (already rendered HTML)
+// and Text (its optional plain-text alternative) or Template and TemplateData
+// (rendered by the worker) describe the content.
+type EmailJob struct {
+	To           []string       `json:"to"`
+	Subject      string         `json:"subject"`
+	Body         string         `json:"body,omitempty"`
+	Text         string         `json:"text,omitempty"`
+	Template     string         `json:"template,omitempty"`
+	TemplateData map[string]any `json:"template_data,omitempty"`
+}
+
+// Producer appends jobs to a Redis Stream.
+type Producer struct {
+	rdb    *redis.Client
+	stream string
+	maxLen int64
+}
+
+// NewProducer creates a producer for the given stream.
+func NewProducer(rdb *redis.Client, stream string) *Producer {
+	return &Producer{rdb: rdb, stream: stream, maxLen: DefaultMaxLen}
+}
+
+// Enqueue appends job to the stream.
+func (p *Producer) Enqueue(ctx context.Context, job EmailJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	err = p.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: p.stream,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]any{jobField: data},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("enqueue email: %w", err)
+	}
+	return nil
+}
+
+// EnqueueEmail enqueues an already rendered email. It implements email.Outbox, so
+// a queued email.Client hands its messages to the stream instead of SMTP.
+func (p *Producer) EnqueueEmail(ctx context.Context, msg email.Message) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), enqueueTimeout)
+	defer cancel()
+	return p.Enqueue(ctx, EmailJob{To: msg.To, Subject: msg.Subject, Body: msg.HTML, Text: msg.Text})
+}
@@ -0,0 +1,78 @@
+// Package sms sends text messages through the Twilio REST API.
+package sms
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// twilioAPIURL is the base URL of the Twilio REST API
+const twilioAPIURL = "https://api.twilio.com/2010-04-01"
+
+// Client sends SMS messages from a Twilio phone number.
+type Client struct {
+	// AccountSID and AuthToken authenticate against the Twilio API
+	AccountSID string
+	AuthToken  string
+
+	// From is the Twilio phone number (E.164, e.g. +15005550006) messages are sent from
+	From string
+
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a Twilio SMS client.
+func NewClient(accountSID, authToken, from string) *Client {
+	return &Client{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		baseURL:    twilioAPIURL,
+	}
+}
+
+// Send sends body as a text message to the E.164 phone number to.
+func (c *Client) Send(to, body string) error {
+	endpoint := fmt.Sprintf("%s/Accounts/%s/Messages.json", c.baseURL, url.PathEscape(c.AccountSID))
+	form := url.Values{"To": {to}, "From": {c.From}, "Body": {body}}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.AccountSID, c.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// Twilio reports failures as {"code": 21211, "message": "..."}
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("twilio error: %d - %s (code %d)", resp.StatusCode, apiErr.Message, apiErr.Code)
+		}
+		return fmt.Errorf("twilio error: %d - %s", resp.StatusCode, data)
+	}
+	return nil
+}
+
+// SendOTP is a convenience helper that formats and sends an OTP text message.
+func (c *Client) SendOTP(to, code string) error {
+	return c.Send(to, fmt.Sprintf("Your verification code is %s. It will expire in 10 minutes.", code))
+}
@@ -0,0 +1,170 @@
+//go:build integration
+
+// Package testserver runs the Authentio HTTP API in-process for end-to-end
+// tests: the real router, handlers, service and repositories, backed by
+// Postgres and Redis containers (see internal/testutil). Build the tests with
+// the integration tag:
+//
+//	go test -tags integration -p 1 ./...
+package testserver
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	dbpkg "authentio/internal/database"
+	"authentio/internal/handler"
+	"authentio/internal/router"
+	"authentio/internal/service"
+	"authentio/internal/testutil"
+	"authentio/pkg/crypto"
+	"authentio/pkg/email"
+	"authentio/pkg/jwt"
+	"authentio/pkg/lock"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// jwtSecret signs the access tokens of every test server
+const jwtSecret = "testserver-jwt-secret-at-least-32-bytes"
+
+var (
+	// The containers are started by the first New and shared by every test of
+	// the binary; the testcontainers reaper removes them when it exits
+	containersOnce sync.Once
+	containers     *testutil.Containers
+	containersErr  error
+)
+
+// TestServer is an Authentio API listening on a random local port.
+type TestServer struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:54321
+	URL string
+
+	// DB and Redis are the stores behind the server, emptied by New
+	DB    *sql.DB
+	Redis *redis.Client
+
+	// Auth is the service the handlers call
+	Auth *service.AuthService
+
+	// JWT signs and verifies the server's access tokens
+	JWT *jwt.Manager
+
+	// Emails records every email the server sends instead of delivering it
+	Emails *testutil.Outbox
+
+	server *httptest.Server
+	client *http.Client
+}
+
+// New starts a test server on an empty, migrated database and an empty Redis.
+// It is shut down when the test ends.
+func New(t *testing.T) *TestServer {
+	t.Helper()
+
+	containersOnce.Do(func() {
+		containers, containersErr = testutil.StartContainers(context.Background())
+	})
+	if containersErr != nil {
+		t.Fatalf("testserver: %v", containersErr)
+	}
+
+	db := containers.DB(t)
+	rdb := containers.Redis(t)
+
+	var encryptionKey [crypto.KeySize]byte
+	if _, err := rand.Read(encryptionKey[:]); err != nil {
+		t.Fatalf("testserver: encryption key: %v", err)
+	}
+
+	ts := &TestServer{
+		DB:     db,
+		Redis:  rdb,
+		JWT:    jwt.NewManager(jwtSecret).WithRevocationStore(rdb, true),
+		Emails: &testutil.Outbox{},
+	}
+	ts.JWT.WithDPoPReplayStore(rdb)
+
+	emailClient := email.NewClient("localhost", 25, "", "", "noreply@authentio.test", email.WithSender(ts.Emails))
+
+	// The same dependencies cmd/server wires without optional configuration
+	userRepo := dbpkg.NewUserRepository(db, nil)
+	ts.Auth = service.NewAuthService(
+		userRepo,
+		dbpkg.NewTwoFARepository(db, &encryptionKey, nil),
+		dbpkg.NewOTPRepository(db, &encryptionKey, nil),
+		dbpkg.NewTokenRepository(db, nil),
+		ts.JWT,
+		emailClient,
+		nil,
+		nil,
+	)
+	ts.Auth.WithEmailSuppression(dbpkg.NewEmailSuppressionRepository(db, nil))
+	ts.Auth.WithAuditLog(dbpkg.NewAuditRepository(db, nil))
+	ts.Auth.WithPasswordHistory(dbpkg.NewPasswordHistoryRepository(db, nil), 5)
+	ts.Auth.WithLockout(service.LockoutConfig{Redis: rdb, MaxFailedLogins: 5, Window: 15 * time.Minute})
+	ts.Auth.WithIPFilter(rdb)
+	ts.Auth.WithLocker(lock.New(rdb))
+	ts.Auth.WithRoles(dbpkg.NewRoleRepository(db, nil))
+	ts.Auth.WithPKCEStore(rdb, 10*time.Minute)
+	ts.Auth.WithTOTPLogin(rdb, 5*time.Minute)
+
+	gin.SetMode(gin.TestMode)
+	h := handler.NewHandler(*ts.Auth, handler.NewHealthHandler(db, rdb))
+	r := router.SetupRouter(h, rdb, ts.JWT, router.Options{
+		RateLimits: router.RouteRateLimits{
+			Login:    router.RateLimitConfig{Name: "login", Window: time.Minute, MaxRequests: 100},
+			Register: router.RateLimitConfig{Name: "register", Window: time.Minute, MaxRequests: 100},
+		},
+		IdempotencyTTL: time.Hour,
+		MaxBodyBytes:   1 << 20,
+	})
+
+	ts.server = httptest.NewServer(r)
+	ts.URL = ts.server.URL
+	base, _ := url.Parse(ts.URL)
+	ts.client = &http.Client{
+		Transport: &baseURLTransport{base: base, next: ts.server.Client().Transport},
+		Timeout:   10 * time.Second,
+	}
+
+	// Shut the server down, then let background emails finish, before the
+	// database and Redis are closed
+	t.Cleanup(func() {
+		ts.server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ts.Auth.FlushBackground(ctx)
+	})
+	return ts
+}
+
+// Client returns an HTTP client for the server. Requests with a relative URL,
+// e.g. "/api/v1/auth/login", are sent to the server's base URL.
+func (ts *TestServer) Client() *http.Client {
+	return ts.client
+}
+
+// baseURLTransport resolves relative request URLs against base
+type baseURLTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == "" {
+		req = req.Clone(req.Context())
+		req.URL = t.base.ResolveReference(req.URL)
+		req.Host = req.URL.Host
+	}
+	return t.next.RoundTrip(req)
+}
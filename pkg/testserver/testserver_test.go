@@ -0,0 +1,109 @@
+//go:build integration
+
+package testserver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"authentio/pkg/testserver"
+)
+
+// call sends body as JSON to path and decodes the response into out, failing
+// the test unless the status is want.
+func call(t *testing.T, client *http.Client, path, accessToken string, body any, want int, out any) {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&raw)
+	if resp.StatusCode != want {
+		t.Fatalf("POST %s: status %d, want %d: %s", path, resp.StatusCode, want, raw)
+	}
+	if out != nil {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("POST %s: decode response: %v", path, err)
+		}
+	}
+}
+
+// tokens is the token pair returned by login and refresh
+type tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func TestRegisterLoginVerifyOTPRefresh(t *testing.T) {
+	ts := testserver.New(t)
+	client := ts.Client()
+	const address = "jane.doe@example.com"
+
+	call(t, client, "/api/v1/auth/register", "", map[string]string{
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"email":      address,
+		"password":   "Str0ng!Passw0rd",
+	}, http.StatusCreated, nil)
+
+	var login tokens
+	call(t, client, "/api/v1/auth/login", "", map[string]string{
+		"email":    address,
+		"password": "Str0ng!Passw0rd",
+	}, http.StatusOK, &login)
+	if login.AccessToken == "" || login.RefreshToken == "" {
+		t.Fatalf("login returned %+v, want a token pair", login)
+	}
+
+	call(t, client, "/api/v1/2fa/sendOtp", login.AccessToken, map[string]string{"email": address}, http.StatusOK, nil)
+	code, ok := ts.Emails.LastOTP(address)
+	if !ok {
+		t.Fatal("no OTP email sent")
+	}
+	call(t, client, "/api/v1/auth/2fa/verify", "", map[string]string{"email": address, "code": code}, http.StatusOK, nil)
+
+	var refreshed tokens
+	call(t, client, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken}, http.StatusOK, &refreshed)
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == login.RefreshToken {
+		t.Fatalf("refresh returned %+v, want a new refresh token", refreshed)
+	}
+
+	// The rotated refresh token is single use
+	call(t, client, "/api/v1/auth/refresh", "", map[string]string{"refresh_token": login.RefreshToken}, http.StatusUnauthorized, nil)
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	ts := testserver.New(t)
+	client := ts.Client()
+
+	call(t, client, "/api/v1/auth/register", "", map[string]string{
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"email":      "jane.doe@example.com",
+		"password":   "Str0ng!Passw0rd",
+	}, http.StatusCreated, nil)
+
+	call(t, client, "/api/v1/auth/login", "", map[string]string{
+		"email":    "jane.doe@example.com",
+		"password": "Wr0ng!Passw0rd",
+	}, http.StatusUnauthorized, nil)
+}
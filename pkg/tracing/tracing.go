@@ -0,0 +1,78 @@
+// Package tracing configures OpenTelemetry distributed tracing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// Config configures the tracer provider.
+type Config struct {
+	// Enabled turns on span export; when false a no-op provider is returned
+	Enabled bool
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+
+	// SampleRatio is the fraction of new traces (0-1) that are recorded. Requests
+	// carrying a sampled traceparent are always recorded.
+	SampleRatio float64
+}
+
+// NewProvider builds a TracerProvider that exports spans over OTLP/HTTP.
+//
+// The exporter is configured through the standard OpenTelemetry environment
+// variables, chiefly OTEL_EXPORTER_OTLP_ENDPOINT (e.g. http://otel-collector:4318);
+// /v1/traces is appended to it. W3C TraceContext and Baggage are installed as the
+// global propagators so incoming traceparent headers continue the caller's trace.
+//
+// The returned shutdown function flushes buffered spans and must be called on exit.
+func NewProvider(ctx context.Context, cfg Config) (trace.TracerProvider, func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider, provider.Shutdown, nil
+}
+
+// Tracer returns the named tracer of tp, or a no-op tracer when tp is nil.
+// Constructors accepting an optional TracerProvider use it for their defaults.
+func Tracer(tp trace.TracerProvider, name string) trace.Tracer {
+	if tp == nil {
+		return noop.NewTracerProvider().Tracer(name)
+	}
+	return tp.Tracer(name)
+}